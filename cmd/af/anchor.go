@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/dbpool"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nats-io/nats.go"
+)
+
+// AuditAnchorResult represents the JSON output for one anchored tenant
+type AuditAnchorResult struct {
+	TenantID   string `json:"tenant_id"`
+	Status     string `json:"status"` // "anchored", "empty", "error"
+	Seq        int64  `json:"seq,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+	AnchoredAt string `json:"anchored_at,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// anchorSigner returns the signer for AF_AUDIT_ANCHOR_KEY, a hex Ed25519 seed,
+// or nil when it is unset
+func anchorSigner() (*audit.AnchorSigner, error) {
+	key := os.Getenv("AF_AUDIT_ANCHOR_KEY")
+	if key == "" {
+		return nil, nil
+	}
+	seed, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AF_AUDIT_ANCHOR_KEY: %w", err)
+	}
+	signer, err := audit.NewAnchorSigner(seed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AF_AUDIT_ANCHOR_KEY: %w", err)
+	}
+	return signer, nil
+}
+
+// anchorPublicKey returns the key anchors are checked with:
+// AF_AUDIT_ANCHOR_PUBLIC_KEY, or the public half of AF_AUDIT_ANCHOR_KEY
+func anchorPublicKey() (ed25519.PublicKey, error) {
+	if key := os.Getenv("AF_AUDIT_ANCHOR_PUBLIC_KEY"); key != "" {
+		publicKey, err := hex.DecodeString(key)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid AF_AUDIT_ANCHOR_PUBLIC_KEY: want %d hex-encoded bytes", ed25519.PublicKeySize)
+		}
+		return publicKey, nil
+	}
+	signer, err := anchorSigner()
+	if err != nil || signer == nil {
+		return nil, err
+	}
+	return signer.PublicKey(), nil
+}
+
+// anchorSinks opens the sinks configured by AF_AUDIT_ANCHOR_LOG,
+// AF_AUDIT_ANCHOR_DIR and AF_AUDIT_ANCHOR_NATS_URL. The returned func
+// releases them.
+func anchorSinks() ([]audit.AnchorSink, func(), error) {
+	var sinks []audit.AnchorSink
+	closeSinks := func() {}
+
+	if path := os.Getenv("AF_AUDIT_ANCHOR_LOG"); path != "" {
+		sinks = append(sinks, audit.NewFileAnchorLog(path))
+	}
+	if dir := os.Getenv("AF_AUDIT_ANCHOR_DIR"); dir != "" {
+		sinks = append(sinks, audit.NewDirAnchorSink(dir))
+	}
+	if url := os.Getenv("AF_AUDIT_ANCHOR_NATS_URL"); url != "" {
+		bucket := os.Getenv("AF_AUDIT_ANCHOR_NATS_BUCKET")
+		if bucket == "" {
+			bucket = audit.DefaultAnchorBucket
+		}
+		conn, err := nats.Connect(url, nats.Timeout(5*time.Second))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to NATS: %w", err)
+		}
+		js, err := conn.JetStream()
+		if err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("failed to create JetStream context: %w", err)
+		}
+		sink, err := audit.NewNATSAnchorSink(js, bucket)
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		sinks = append(sinks, sink)
+		closeSinks = conn.Close
+	}
+	return sinks, closeSinks, nil
+}
+
+// anchorChecker builds the checker used by audit verify, or nil when no
+// anchor sink is configured
+func anchorChecker(reader audit.VerifierQuerier) (*audit.AnchorChecker, func(), error) {
+	sinks, closeSinks, err := anchorSinks()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(sinks) == 0 {
+		return nil, closeSinks, nil
+	}
+	publicKey, err := anchorPublicKey()
+	if err == nil && publicKey == nil {
+		err = errors.New("anchor sinks are configured but neither AF_AUDIT_ANCHOR_PUBLIC_KEY nor AF_AUDIT_ANCHOR_KEY is set")
+	}
+	if err != nil {
+		closeSinks()
+		return nil, nil, err
+	}
+	return audit.NewAnchorChecker(reader, publicKey, sinks...), closeSinks, nil
+}
+
+// withAnchorCheck compares the chain with its anchors before verifying it, so
+// a consistently rewritten chain is reported as tampered
+func withAnchorCheck(verify verifyFunc, checker *audit.AnchorChecker) verifyFunc {
+	return func(ctx context.Context, tenantID pgtype.UUID) (audit.VerificationResult, error) {
+		check, err := checker.Check(ctx, tenantID)
+		if err != nil {
+			return audit.VerificationResult{}, err
+		}
+		if !check.Valid {
+			result := audit.VerificationResult{ErrorMessage: check.ErrorMessage, AnchorsChecked: check.AnchorsChecked}
+			if check.MismatchSeq > 0 {
+				index := int(check.MismatchSeq - 1)
+				result.FirstTamperedIndex = &index
+			}
+			return result, nil
+		}
+
+		result, err := verify(ctx, tenantID)
+		result.AnchorsChecked = check.AnchorsChecked
+		return result, err
+	}
+}
+
+// anchorAuditChains writes each tenant's chain head to the configured sinks
+func anchorAuditChains(args []string) error {
+	var tenantID *pgtype.UUID
+	jsonOutput := false
+	for _, arg := range args {
+		switch {
+		case arg == "--json":
+			jsonOutput = true
+		case strings.HasPrefix(arg, "--tenant-id="):
+			var uuid pgtype.UUID
+			if err := uuid.Scan(arg[len("--tenant-id="):]); err != nil {
+				return fmt.Errorf("invalid tenant ID format: %s", arg[len("--tenant-id="):])
+			}
+			tenantID = &uuid
+		}
+	}
+
+	signer, err := anchorSigner()
+	if err != nil {
+		return err
+	}
+	if signer == nil {
+		return fmt.Errorf("AF_AUDIT_ANCHOR_KEY must be set to anchor audit chains")
+	}
+	sinks, closeSinks, err := anchorSinks()
+	if err != nil {
+		return err
+	}
+	defer closeSinks()
+	if len(sinks) == 0 {
+		return fmt.Errorf("no anchor sink configured: set AF_AUDIT_ANCHOR_LOG, AF_AUDIT_ANCHOR_DIR or AF_AUDIT_ANCHOR_NATS_URL")
+	}
+
+	// Chain heads are read from the primary so anchors never trail a lagging replica
+	ctx := context.Background()
+	cluster, err := dbpool.Open(ctx, dbpool.LoadFromEnv(), logging.NewLoggerWithOutput(os.Stderr))
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer cluster.Close()
+
+	tenantIDs := []pgtype.UUID{}
+	if tenantID != nil {
+		tenantIDs = append(tenantIDs, *tenantID)
+	} else if tenantIDs, err = listTenantIDs(ctx, cluster.Primary()); err != nil {
+		return err
+	}
+
+	anchorer := audit.NewAnchorer(queries.New(cluster.Primary()), signer, sinks...)
+	results := make([]AuditAnchorResult, 0, len(tenantIDs))
+	failed := 0
+	for _, id := range tenantIDs {
+		result := AuditAnchorResult{TenantID: formatUUID(id), Status: "anchored"}
+		anchor, err := anchorer.AnchorTenant(ctx, id)
+		switch {
+		case errors.Is(err, audit.ErrEmptyChain):
+			result.Status = "empty"
+		case err != nil:
+			result.Status = "error"
+			result.Error = err.Error()
+			failed++
+		default:
+			result.Seq = anchor.Seq
+			result.Hash = anchor.Hash
+			result.AnchoredAt = anchor.AnchoredAt.Format(time.RFC3339)
+		}
+		results = append(results, result)
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, r := range results {
+			switch r.Status {
+			case "anchored":
+				fmt.Printf("%s  anchored seq %d  %s\n", r.TenantID, r.Seq, r.Hash)
+			case "empty":
+				fmt.Printf("%s  no audit records\n", r.TenantID)
+			default:
+				fmt.Printf("%s  error: %s\n", r.TenantID, r.Error)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to anchor %d of %d tenants", failed, len(tenantIDs))
+	}
+	return nil
+}
+
+// listTenantIDs returns the ID of every tenant
+func listTenantIDs(ctx context.Context, db queries.DBTX) ([]pgtype.UUID, error) {
+	rows, err := db.Query(ctx, "SELECT id FROM tenants ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAnchorKey = "0707070707070707070707070707070707070707070707070707070707070707"
+
+func clearAnchorEnv(t *testing.T) {
+	for _, name := range []string{
+		"AF_AUDIT_ANCHOR_KEY", "AF_AUDIT_ANCHOR_PUBLIC_KEY", "AF_AUDIT_ANCHOR_LOG",
+		"AF_AUDIT_ANCHOR_DIR", "AF_AUDIT_ANCHOR_NATS_URL", "AF_AUDIT_ANCHOR_NATS_BUCKET",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+// TestAnchorKeysFromEnv tests AF_AUDIT_ANCHOR_KEY and AF_AUDIT_ANCHOR_PUBLIC_KEY handling
+func TestAnchorKeysFromEnv(t *testing.T) {
+	clearAnchorEnv(t)
+
+	signer, err := anchorSigner()
+	require.NoError(t, err)
+	assert.Nil(t, signer)
+	publicKey, err := anchorPublicKey()
+	require.NoError(t, err)
+	assert.Nil(t, publicKey)
+
+	t.Setenv("AF_AUDIT_ANCHOR_KEY", "not-hex")
+	_, err = anchorSigner()
+	assert.Error(t, err)
+	t.Setenv("AF_AUDIT_ANCHOR_KEY", "0707")
+	_, err = anchorSigner()
+	assert.Error(t, err)
+
+	t.Setenv("AF_AUDIT_ANCHOR_KEY", testAnchorKey)
+	signer, err = anchorSigner()
+	require.NoError(t, err)
+	publicKey, err = anchorPublicKey()
+	require.NoError(t, err)
+	assert.Equal(t, signer.PublicKey(), publicKey)
+
+	// A verify-only host needs just the public key
+	t.Setenv("AF_AUDIT_ANCHOR_KEY", "")
+	t.Setenv("AF_AUDIT_ANCHOR_PUBLIC_KEY", hex.EncodeToString(signer.PublicKey()))
+	publicKey, err = anchorPublicKey()
+	require.NoError(t, err)
+	assert.Equal(t, signer.PublicKey(), publicKey)
+
+	t.Setenv("AF_AUDIT_ANCHOR_PUBLIC_KEY", "0707")
+	_, err = anchorPublicKey()
+	assert.Error(t, err)
+}
+
+// TestAnchorSinksFromEnv tests which sinks the environment enables
+func TestAnchorSinksFromEnv(t *testing.T) {
+	clearAnchorEnv(t)
+	dir := t.TempDir()
+
+	sinks, closeSinks, err := anchorSinks()
+	require.NoError(t, err)
+	closeSinks()
+	assert.Empty(t, sinks)
+
+	checker, closeSinks, err := anchorChecker(nil)
+	require.NoError(t, err)
+	closeSinks()
+	assert.Nil(t, checker)
+
+	t.Setenv("AF_AUDIT_ANCHOR_LOG", filepath.Join(dir, "anchors.log"))
+	t.Setenv("AF_AUDIT_ANCHOR_DIR", filepath.Join(dir, "documents"))
+	sinks, closeSinks, err = anchorSinks()
+	require.NoError(t, err)
+	closeSinks()
+	require.Len(t, sinks, 2)
+	assert.True(t, strings.HasPrefix(sinks[0].Name(), "file:"))
+	assert.True(t, strings.HasPrefix(sinks[1].Name(), "dir:"))
+
+	// Sinks without a key to check them with are a configuration error
+	_, _, err = anchorChecker(nil)
+	assert.Error(t, err)
+
+	t.Setenv("AF_AUDIT_ANCHOR_KEY", testAnchorKey)
+	checker, closeSinks, err = anchorChecker(nil)
+	require.NoError(t, err)
+	closeSinks()
+	require.NotNil(t, checker)
+
+	// With no anchors stored yet the chain verification result passes through
+	verify := withAnchorCheck(func(ctx context.Context, tenantID pgtype.UUID) (audit.VerificationResult, error) {
+		return audit.VerificationResult{Valid: true, TotalRecords: 3}, nil
+	}, checker)
+	result, err := verify(context.Background(), pgtype.UUID{Bytes: [16]byte{1}, Valid: true})
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, 3, result.TotalRecords)
+	assert.Zero(t, result.AnchorsChecked)
+}
+
+// TestAnchorCommandRequiresConfiguration tests af audit anchor without a key or sink
+func TestAnchorCommandRequiresConfiguration(t *testing.T) {
+	clearAnchorEnv(t)
+	err := anchorAuditChains(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AF_AUDIT_ANCHOR_KEY")
+
+	t.Setenv("AF_AUDIT_ANCHOR_KEY", testAnchorKey)
+	err = anchorAuditChains(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no anchor sink configured")
+}
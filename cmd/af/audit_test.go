@@ -61,6 +61,10 @@ func (m *MockAuditQuerier) GetLatestAudit(ctx context.Context, tenantID pgtype.U
 	return queries.Audit{}, fmt.Errorf("no rows in result set")
 }
 
+func (m *MockAuditQuerier) LockAuditChain(ctx context.Context, tenantID pgtype.UUID) error {
+	return nil
+}
+
 func (m *MockAuditQuerier) GetAuditChain(ctx context.Context, tenantID pgtype.UUID) ([]queries.Audit, error) {
 	if m.shouldError {
 		return nil, fmt.Errorf(m.errorMessage)
@@ -435,3 +439,50 @@ func TestAuditVerifyExitCodes(t *testing.T) {
 		})
 	}
 }
+
+// TestParseAuditVerifyArgs tests range, checkpoint and output flags
+func TestParseAuditVerifyArgs(t *testing.T) {
+	const tenant = "--tenant-id=123e4567-e89b-12d3-a456-426614174000"
+
+	opts, err := parseAuditVerifyArgs([]string{tenant, "--from=10", "--to=20", "--full", "--json"})
+	require.NoError(t, err)
+	require.NotNil(t, opts.tenantID)
+	assert.Equal(t, int64(10), opts.from)
+	assert.Equal(t, int64(20), opts.to)
+	assert.True(t, opts.full)
+	assert.True(t, opts.jsonOutput)
+
+	opts, err = parseAuditVerifyArgs([]string{"--forensic"})
+	require.NoError(t, err)
+	assert.True(t, opts.forensic)
+
+	invalid := [][]string{
+		{"--tenant-id=invalid-uuid"},
+		{"--from=10"},
+		{tenant, "--from=abc"},
+		{tenant, "--to=0"},
+		{tenant, "--from=20", "--to=10"},
+		{tenant, "--from=10", "--forensic"},
+	}
+	for _, args := range invalid {
+		_, err := parseAuditVerifyArgs(args)
+		assert.Error(t, err, "args %v", args)
+	}
+}
+
+// TestCheckpointSignerFromEnv tests AF_AUDIT_CHECKPOINT_KEY handling
+func TestCheckpointSignerFromEnv(t *testing.T) {
+	t.Setenv("AF_AUDIT_CHECKPOINT_KEY", "")
+	signer, err := checkpointSigner()
+	require.NoError(t, err)
+	assert.Nil(t, signer)
+
+	t.Setenv("AF_AUDIT_CHECKPOINT_KEY", "short")
+	_, err = checkpointSigner()
+	assert.Error(t, err)
+
+	t.Setenv("AF_AUDIT_CHECKPOINT_KEY", "0123456789abcdef0123456789abcdef")
+	signer, err = checkpointSigner()
+	require.NoError(t, err)
+	assert.NotNil(t, signer)
+}
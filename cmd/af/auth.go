@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/security/secrets"
+)
+
+// SigningKeyResult represents the JSON output for a token signing key
+type SigningKeyResult struct {
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Status    string `json:"status"` // active, retiring
+	CreatedAt string `json:"created_at"`
+	RetireAt  string `json:"retire_at,omitempty"`
+}
+
+// authOptions holds the parsed auth flags
+type authOptions struct {
+	alg        string
+	overlap    time.Duration
+	jsonOutput bool
+}
+
+// authCmd manages the keys control-plane tokens are signed with
+func authCmd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("auth command requires a subcommand: rotate-signing-key, signing-keys, hash-password")
+	}
+
+	subcommand := args[0]
+	if subcommand == "hash-password" {
+		if len(args) > 1 {
+			return fmt.Errorf("hash-password reads the password from stdin and takes no arguments")
+		}
+		return hashPassword(os.Stdin, os.Stdout)
+	}
+	opts, err := parseAuthArgs(args[1:])
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch subcommand {
+	case "rotate-signing-key":
+		// Keys written to the environment provider would only live as long as
+		// this process
+		if os.Getenv("AF_SECRETS_FILE") == "" {
+			return fmt.Errorf("rotate-signing-key requires AF_SECRETS_FILE to name the secrets file the control plane reads")
+		}
+		provider, err := secrets.NewProviderFromEnv()
+		if err != nil {
+			return err
+		}
+		key, err := security.RotateSigningKeys(ctx, provider, security.DefaultSigningKeysSecret, opts.alg, opts.overlap)
+		if err != nil {
+			return err
+		}
+		if opts.jsonOutput {
+			return outputSigningKeys(os.Stdout, []security.SigningKey{key}, true)
+		}
+		fmt.Printf("Created %s signing key %s; replaced keys keep verifying tokens for %s\n", key.Algorithm, key.ID, opts.overlap)
+		return nil
+	case "signing-keys":
+		provider, err := secrets.NewProviderFromEnv()
+		if err != nil {
+			return err
+		}
+		keys, err := security.LoadSigningKeys(ctx, provider, security.DefaultSigningKeysSecret)
+		if err != nil {
+			return err
+		}
+		return outputSigningKeys(os.Stdout, keys, opts.jsonOutput)
+	default:
+		return fmt.Errorf("unknown auth subcommand: %s", subcommand)
+	}
+}
+
+// hashPassword prints the argon2id hash of the first line of r, for
+// users.hashed_secret. The password is read from stdin so it stays out of
+// the shell history and process list.
+func hashPassword(r io.Reader, w io.Writer) error {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return fmt.Errorf("no password on stdin")
+	}
+	hash, err := security.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, hash)
+	return nil
+}
+
+// parseAuthArgs parses --alg=, --overlap= and --json
+func parseAuthArgs(args []string) (authOptions, error) {
+	opts := authOptions{alg: security.SigningAlgEdDSA, overlap: security.DefaultSigningKeyOverlap}
+	for _, arg := range args {
+		switch {
+		case arg == "--json":
+			opts.jsonOutput = true
+		case strings.HasPrefix(arg, "--alg="):
+			opts.alg = arg[len("--alg="):]
+			if opts.alg != security.SigningAlgEdDSA && opts.alg != security.SigningAlgRS256 {
+				return opts, fmt.Errorf("invalid algorithm: %s (want EdDSA or RS256)", opts.alg)
+			}
+		case strings.HasPrefix(arg, "--overlap="):
+			overlap, err := time.ParseDuration(arg[len("--overlap="):])
+			if err != nil || overlap < 0 {
+				return opts, fmt.Errorf("invalid overlap: %s", arg[len("--overlap="):])
+			}
+			opts.overlap = overlap
+		default:
+			return opts, fmt.Errorf("unknown auth flag: %s", arg)
+		}
+	}
+	return opts, nil
+}
+
+func outputSigningKeys(w io.Writer, keys []security.SigningKey, jsonOutput bool) error {
+	results := make([]SigningKeyResult, 0, len(keys))
+	for _, k := range keys {
+		result := SigningKeyResult{
+			KeyID:     k.ID,
+			Algorithm: k.Algorithm,
+			Status:    "active",
+			CreatedAt: k.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if !k.RetireAt.IsZero() {
+			result.Status = "retiring"
+			result.RetireAt = k.RetireAt.UTC().Format(time.RFC3339)
+		}
+		results = append(results, result)
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	for _, r := range results {
+		line := fmt.Sprintf("%-20s %-6s %-9s created %s", r.KeyID, r.Algorithm, r.Status, r.CreatedAt)
+		if r.RetireAt != "" {
+			line += ", retires " + r.RetireAt
+		}
+		fmt.Fprintln(w, line)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/security/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthCmdValidation(t *testing.T) {
+	t.Setenv("AF_SECRETS_FILE", "")
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{"No subcommand", []string{}, "auth command requires a subcommand: rotate-signing-key, signing-keys, hash-password"},
+		{"Invalid subcommand", []string{"rotate"}, "unknown auth subcommand: rotate"},
+		{"Unknown flag", []string{"signing-keys", "--all"}, "unknown auth flag: --all"},
+		{"Invalid algorithm", []string{"rotate-signing-key", "--alg=HS256"}, "invalid algorithm: HS256 (want EdDSA or RS256)"},
+		{"Invalid overlap", []string{"rotate-signing-key", "--overlap=2d"}, "invalid overlap: 2d"},
+		{"No secrets file", []string{"rotate-signing-key"}, "rotate-signing-key requires AF_SECRETS_FILE to name the secrets file the control plane reads"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := authCmd(tt.args)
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
+
+func TestRotateSigningKeyCmd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	t.Setenv("AF_SECRETS_FILE", path)
+
+	require.NoError(t, authCmd([]string{"rotate-signing-key", "--alg=RS256"}))
+	require.NoError(t, authCmd([]string{"rotate-signing-key", "--overlap=1h"}))
+	require.NoError(t, authCmd([]string{"signing-keys"}))
+
+	keys, err := security.LoadSigningKeys(context.Background(), secrets.NewFileProvider(path), security.DefaultSigningKeysSecret)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, security.SigningAlgRS256, keys[0].Algorithm)
+	assert.WithinDuration(t, keys[1].CreatedAt.Add(time.Hour), keys[0].RetireAt, time.Second)
+	assert.Equal(t, security.SigningAlgEdDSA, keys[1].Algorithm, "EdDSA is the default")
+	assert.True(t, keys[1].RetireAt.IsZero())
+}
+
+func TestOutputSigningKeys(t *testing.T) {
+	created := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	keys := []security.SigningKey{
+		{ID: "20250901-aaaa", Algorithm: "RS256", CreatedAt: created, RetireAt: created.Add(48 * time.Hour)},
+		{ID: "20250902-bbbb", Algorithm: "EdDSA", CreatedAt: created.Add(24 * time.Hour)},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, outputSigningKeys(&buf, keys, true))
+	var results []SigningKeyResult
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &results))
+	require.Len(t, results, 2)
+	assert.Equal(t, "retiring", results[0].Status)
+	assert.Equal(t, "2025-09-03T00:00:00Z", results[0].RetireAt)
+	assert.Equal(t, "active", results[1].Status)
+	assert.Empty(t, results[1].RetireAt)
+
+	buf.Reset()
+	require.NoError(t, outputSigningKeys(&buf, keys, false))
+	assert.Contains(t, buf.String(), "20250902-bbbb")
+	assert.Contains(t, buf.String(), "retires 2025-09-03T00:00:00Z")
+}
+
+func TestHashPassword(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, hashPassword(strings.NewReader("s3cret\n"), &out))
+	hash := strings.TrimSpace(out.String())
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$"))
+	ok, err := security.VerifyPassword(hash, "s3cret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.Error(t, hashPassword(strings.NewReader("\n"), &out))
+	assert.Error(t, authCmd([]string{"hash-password", "s3cret"}))
+}
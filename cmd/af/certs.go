@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/security/certs"
+)
+
+// defaultCertsDir is where af certs keeps the development CA unless --dir
+// or AF_CERTS_DIR says otherwise
+const defaultCertsDir = "certs"
+
+// certsOptions holds the parsed certs flags
+type certsOptions struct {
+	dir      string
+	kind     string
+	name     string
+	tenantID string
+	dnsNames []string
+	ips      []net.IP
+	validity time.Duration
+	force    bool
+}
+
+// certsCmd manages the development certificate authority for mutual TLS
+func certsCmd(args []string) error {
+	return runCerts(args, os.Stdout)
+}
+
+func runCerts(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("certs command requires a subcommand: init, issue")
+	}
+
+	subcommand := args[0]
+	opts, err := parseCertsArgs(args[1:])
+	if err != nil {
+		return err
+	}
+
+	switch subcommand {
+	case "init":
+		ca, err := certs.NewCA("AgentFlow Development CA", opts.validity)
+		if err != nil {
+			return err
+		}
+		if err := certs.WriteCA(opts.dir, ca); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Created CA %s (expires %s)\n", filepath.Join(opts.dir, certs.CACertFile), ca.Cert.NotAfter.Format(time.RFC3339))
+		return nil
+	case "issue":
+		if opts.kind == "" || opts.name == "" {
+			return fmt.Errorf("issue requires --kind=worker|service|server and --name=NAME")
+		}
+		ca, err := certs.LoadCA(opts.dir)
+		if err != nil {
+			return err
+		}
+		certPEM, keyPEM, err := ca.Issue(certs.IssueRequest{
+			Kind:     opts.kind,
+			Name:     opts.name,
+			TenantID: opts.tenantID,
+			DNSNames: opts.dnsNames,
+			IPs:      opts.ips,
+			Validity: opts.validity,
+		})
+		if err != nil {
+			return err
+		}
+		base := opts.kind + "-" + opts.name
+		certPath, keyPath := filepath.Join(opts.dir, base+".pem"), filepath.Join(opts.dir, base+"-key.pem")
+		if !opts.force {
+			for _, path := range []string{certPath, keyPath} {
+				if _, err := os.Stat(path); err == nil {
+					return fmt.Errorf("%s already exists (use --force to replace it)", path)
+				}
+			}
+		}
+		// The key is written first so a certificate never appears without it
+		if err := writeFileAtomic(keyPath, keyPEM, 0o600); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(certPath, certPEM, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Issued %s certificate %s\n  key: %s\n", opts.kind, certPath, keyPath)
+		return nil
+	default:
+		return fmt.Errorf("unknown certs subcommand: %s", subcommand)
+	}
+}
+
+// parseCertsArgs parses --dir=, --kind=, --name=, --tenant=, --dns=, --ip=,
+// --days= and --force
+func parseCertsArgs(args []string) (certsOptions, error) {
+	opts := certsOptions{dir: os.Getenv("AF_CERTS_DIR")}
+	if opts.dir == "" {
+		opts.dir = defaultCertsDir
+	}
+	for _, arg := range args {
+		switch {
+		case arg == "--force":
+			opts.force = true
+		case strings.HasPrefix(arg, "--dir="):
+			opts.dir = arg[len("--dir="):]
+		case strings.HasPrefix(arg, "--kind="):
+			opts.kind = arg[len("--kind="):]
+		case strings.HasPrefix(arg, "--name="):
+			opts.name = arg[len("--name="):]
+		case strings.HasPrefix(arg, "--tenant="):
+			opts.tenantID = arg[len("--tenant="):]
+		case strings.HasPrefix(arg, "--dns="):
+			opts.dnsNames = append(opts.dnsNames, strings.Split(arg[len("--dns="):], ",")...)
+		case strings.HasPrefix(arg, "--ip="):
+			for _, s := range strings.Split(arg[len("--ip="):], ",") {
+				ip := net.ParseIP(s)
+				if ip == nil {
+					return opts, fmt.Errorf("invalid IP address: %s", s)
+				}
+				opts.ips = append(opts.ips, ip)
+			}
+		case strings.HasPrefix(arg, "--days="):
+			days, err := strconv.Atoi(arg[len("--days="):])
+			if err != nil || days <= 0 {
+				return opts, fmt.Errorf("invalid --days: %s", arg[len("--days="):])
+			}
+			opts.validity = time.Duration(days) * 24 * time.Hour
+		default:
+			return opts, fmt.Errorf("unknown certs flag: %s", arg)
+		}
+	}
+	return opts, nil
+}
+
+// writeFileAtomic replaces path through a rename, so a reloading process
+// never reads a half-written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/security/certs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertsCmdValidation(t *testing.T) {
+	dir := "--dir=" + t.TempDir()
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{"No subcommand", []string{}, "certs command requires a subcommand: init, issue"},
+		{"Invalid subcommand", []string{"revoke"}, "unknown certs subcommand: revoke"},
+		{"Unknown flag", []string{"init", "--bits=4096"}, "unknown certs flag: --bits=4096"},
+		{"Invalid days", []string{"init", "--days=0"}, "invalid --days: 0"},
+		{"Invalid IP", []string{"issue", "--ip=localhost"}, "invalid IP address: localhost"},
+		{"Missing name", []string{"issue", "--kind=worker", dir}, "issue requires --kind=worker|service|server and --name=NAME"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runCerts(tt.args, &bytes.Buffer{})
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
+
+func TestCertsCmdInitAndIssue(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+
+	// Issuing needs a CA
+	require.Error(t, runCerts([]string{"issue", "--dir=" + dir, "--kind=worker", "--name=w1"}, &out))
+
+	require.NoError(t, runCerts([]string{"init", "--dir=" + dir, "--days=30"}, &out))
+	assert.Contains(t, out.String(), "Created CA")
+	err := runCerts([]string{"init", "--dir=" + dir}, &out)
+	assert.ErrorIs(t, err, certs.ErrCAExists)
+
+	require.NoError(t, runCerts([]string{"issue", "--dir=" + dir, "--kind=service", "--name=billing", "--tenant=t1"}, &out))
+	pair, err := tls.LoadX509KeyPair(filepath.Join(dir, "service-billing.pem"), filepath.Join(dir, "service-billing-key.pem"))
+	require.NoError(t, err)
+	id, err := certs.IdentityFromCertificate(pair.Leaf)
+	require.NoError(t, err)
+	assert.Equal(t, certs.Identity{Kind: certs.KindService, Name: "billing", TenantID: "t1"}, id)
+	info, err := os.Stat(filepath.Join(dir, "service-billing-key.pem"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Existing certificates are only replaced with --force
+	args := []string{"issue", "--dir=" + dir, "--kind=service", "--name=billing"}
+	assert.Error(t, runCerts(args, &out))
+	require.NoError(t, runCerts(append(args, "--force"), &out))
+
+	require.NoError(t, runCerts([]string{"issue", "--dir=" + dir, "--kind=server", "--name=control-plane", "--dns=localhost,cp.internal", "--ip=127.0.0.1"}, &out))
+	pair, err = tls.LoadX509KeyPair(filepath.Join(dir, "server-control-plane.pem"), filepath.Join(dir, "server-control-plane-key.pem"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost", "cp.internal"}, pair.Leaf.DNSNames)
+	assert.Len(t, pair.Leaf.IPAddresses, 1)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/dbpool"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+)
+
+// auditQueryOptions holds the parsed audit list and export flags
+type auditQueryOptions struct {
+	filter     audit.Filter
+	limit      int
+	jsonOutput bool
+	format     string
+	output     string
+}
+
+// parseAuditQueryArgs parses the filter flags shared by audit list and
+// export. list also accepts --after-seq=, --limit= and --json; export
+// accepts --format= and --output=.
+func parseAuditQueryArgs(args []string, export bool) (auditQueryOptions, error) {
+	opts := auditQueryOptions{limit: 100, format: audit.FormatJSONL}
+	for _, arg := range args {
+		name, value, _ := strings.Cut(arg, "=")
+		var err error
+		switch {
+		case name == "--tenant-id":
+			if err := opts.filter.TenantID.Scan(value); err != nil {
+				return opts, fmt.Errorf("invalid tenant ID format: %s", value)
+			}
+		case name == "--since":
+			opts.filter.Since, err = time.Parse(time.RFC3339, value)
+		case name == "--until":
+			opts.filter.Until, err = time.Parse(time.RFC3339, value)
+		case name == "--actor-type":
+			opts.filter.ActorType = value
+		case name == "--actor-id":
+			opts.filter.ActorID = value
+		case name == "--action":
+			opts.filter.Action = value
+		case name == "--resource-type":
+			opts.filter.ResourceType = value
+		case name == "--resource-id":
+			opts.filter.ResourceID = value
+		case name == "--after-seq" && !export:
+			opts.filter.AfterSeq, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && opts.filter.AfterSeq < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		case name == "--limit" && !export:
+			opts.limit, err = strconv.Atoi(value)
+			if err == nil && opts.limit < 1 {
+				err = fmt.Errorf("must be positive")
+			}
+		case arg == "--json" && !export:
+			opts.jsonOutput = true
+		case name == "--format" && export:
+			opts.format = value
+		case name == "--output" && export:
+			opts.output = value
+		default:
+			return opts, fmt.Errorf("unknown flag: %s", arg)
+		}
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %s", strings.TrimPrefix(name, "--"), value)
+		}
+	}
+	if !opts.filter.TenantID.Valid {
+		return opts, fmt.Errorf("--tenant-id is required")
+	}
+	if _, err := audit.NewExportWriter(opts.format, io.Discard); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// openAuditReader connects to the database and returns a querier that reads
+// through replicas when configured
+func openAuditReader(ctx context.Context) (*queries.Queries, func(), error) {
+	cluster, err := dbpool.Open(ctx, dbpool.LoadFromEnv(), logging.NewLoggerWithOutput(os.Stderr))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return queries.New(cluster.Reader()), cluster.Close, nil
+}
+
+// listAuditRecords prints one page of a tenant's audit records
+func listAuditRecords(args []string) error {
+	opts, err := parseAuditQueryArgs(args, false)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	q, closeDB, err := openAuditReader(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	records, next, err := audit.Search(ctx, q, opts.filter, opts.limit)
+	if err != nil {
+		return err
+	}
+
+	if opts.jsonOutput {
+		result := map[string]interface{}{"records": records}
+		if next > 0 {
+			result["next_after_seq"] = next
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, r := range records {
+		resource := r.ResourceType
+		if r.ResourceID != nil {
+			resource += "/" + *r.ResourceID
+		}
+		fmt.Printf("%6d  %s  %s:%s  %s  %s  %s\n", r.Seq, r.Timestamp.Format(time.RFC3339),
+			r.ActorType, r.ActorID, r.Action, resource, r.Outcome())
+	}
+	if len(records) == 0 {
+		fmt.Println("No audit records found")
+	}
+	if next > 0 {
+		fmt.Printf("More records: --after-seq=%d\n", next)
+	}
+	return nil
+}
+
+// exportAuditRecords writes every matching record in a SIEM-friendly format
+func exportAuditRecords(args []string) error {
+	opts, err := parseAuditQueryArgs(args, true)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if opts.output != "" {
+		if out, err = os.OpenFile(opts.output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600); err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer out.Close()
+	}
+	writer, err := audit.NewExportWriter(opts.format, out)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	q, closeDB, err := openAuditReader(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	written, err := audit.Export(ctx, q, opts.filter, 0, writer)
+	if err != nil {
+		return fmt.Errorf("export stopped after %d records: %w", written, err)
+	}
+	if opts.output != "" {
+		if err := out.Sync(); err != nil {
+			return fmt.Errorf("failed to write export file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d audit records to %s\n", written, opts.output)
+	}
+	return nil
+}
+
+// verifyAuditExport recomputes the hashes in a JSONL or CSV export offline
+func verifyAuditExport(args []string) error {
+	var path, format string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--format="):
+			format = arg[len("--format="):]
+		case strings.HasPrefix(arg, "--"):
+			return fmt.Errorf("unknown flag: %s", arg)
+		case path != "":
+			return fmt.Errorf("unexpected argument: %s", arg)
+		default:
+			path = arg
+		}
+	}
+	if path == "" {
+		return fmt.Errorf("audit verify-export requires an export file")
+	}
+	if format == "" {
+		format = audit.FormatJSONL
+		if strings.HasSuffix(path, ".csv") {
+			format = audit.FormatCSV
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+	defer f.Close()
+
+	checked, err := audit.VerifyExport(format, f)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ %d audit records match their chain hashes\n", checked)
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseAuditQueryArgs tests audit list and export argument parsing
+func TestParseAuditQueryArgs(t *testing.T) {
+	opts, err := parseAuditQueryArgs([]string{
+		"--tenant-id=123e4567-e89b-12d3-a456-426614174000",
+		"--since=2025-08-01T00:00:00Z",
+		"--actor-id=user-1",
+		"--action=api.delete",
+		"--after-seq=10",
+		"--limit=5",
+		"--json",
+	}, false)
+	require.NoError(t, err)
+	assert.True(t, opts.filter.TenantID.Valid)
+	assert.Equal(t, time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC), opts.filter.Since)
+	assert.Equal(t, "user-1", opts.filter.ActorID)
+	assert.Equal(t, "api.delete", opts.filter.Action)
+	assert.Equal(t, int64(10), opts.filter.AfterSeq)
+	assert.Equal(t, 5, opts.limit)
+	assert.True(t, opts.jsonOutput)
+
+	opts, err = parseAuditQueryArgs([]string{"--tenant-id=123e4567-e89b-12d3-a456-426614174000", "--format=cef", "--output=out.log"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, audit.FormatCEF, opts.format)
+	assert.Equal(t, "out.log", opts.output)
+
+	invalid := []struct {
+		args   []string
+		export bool
+	}{
+		{[]string{"--action=create"}, false},
+		{[]string{"--tenant-id=bad"}, false},
+		{[]string{"--tenant-id=123e4567-e89b-12d3-a456-426614174000", "--since=yesterday"}, false},
+		{[]string{"--tenant-id=123e4567-e89b-12d3-a456-426614174000", "--limit=0"}, false},
+		{[]string{"--tenant-id=123e4567-e89b-12d3-a456-426614174000", "--format=csv"}, false},
+		{[]string{"--tenant-id=123e4567-e89b-12d3-a456-426614174000", "--format=xml"}, true},
+		{[]string{"--tenant-id=123e4567-e89b-12d3-a456-426614174000", "--limit=5"}, true},
+	}
+	for _, tt := range invalid {
+		_, err := parseAuditQueryArgs(tt.args, tt.export)
+		assert.Error(t, err, "args %v", tt.args)
+	}
+}
+
+// TestVerifyAuditExportCommand tests offline export verification
+func TestVerifyAuditExportCommand(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := audit.NewExportWriter(audit.FormatJSONL, &buf)
+	require.NoError(t, err)
+
+	var prevHash []byte
+	for seq := int64(1); seq <= 3; seq++ {
+		record := audit.AuditRecord{
+			TenantID:     "123e4567-e89b-12d3-a456-426614174000",
+			ActorType:    "user",
+			ActorID:      "user-1",
+			Action:       "api.post",
+			ResourceType: "workflows",
+			Details:      json.RawMessage(`{"outcome":"success"}`),
+			Timestamp:    time.Date(2025, 8, 29, 12, 0, int(seq), 0, time.UTC),
+		}
+		hash, err := audit.ComputeHash(prevHash, record)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(audit.ExportRecord{
+			Seq:         seq,
+			AuditRecord: record,
+			PrevHash:    hex.EncodeToString(prevHash),
+			Hash:        hex.EncodeToString(hash),
+		}))
+		prevHash = hash
+	}
+	require.NoError(t, writer.Flush())
+
+	path := filepath.Join(t.TempDir(), "audits.jsonl")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+	assert.NoError(t, verifyAuditExport([]string{path}))
+
+	tampered := bytes.Replace(buf.Bytes(), []byte("user-1"), []byte("user-2"), 1)
+	require.NoError(t, os.WriteFile(path, tampered, 0o600))
+	assert.ErrorIs(t, verifyAuditExport([]string{path}), audit.ErrExportInvalid)
+
+	assert.Error(t, verifyAuditExport(nil))
+	assert.Error(t, verifyAuditExport([]string{path, "--format=cef"}))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPrintForensicResult tests the human-readable incident report
+func TestPrintForensicResult(t *testing.T) {
+	ts := time.Date(2025, 8, 29, 12, 0, 0, 0, time.UTC)
+	result := AuditForensicResult{
+		Status: "tampered",
+		Tenants: []audit.ForensicReport{
+			{TenantID: "tenant-a", TotalRecords: 10, Intact: true},
+			{
+				TenantID:     "tenant-b",
+				TotalRecords: 8,
+				FromSeq:      1,
+				ToSeq:        10,
+				Breaks: []audit.ChainBreak{{
+					Kind:        audit.BreakDeleted,
+					Description: "records with seq 4 to 5 are missing",
+					Records: []audit.AffectedRecord{
+						{ID: "record-3", Seq: 3, Timestamp: ts},
+						{ID: "record-6", Seq: 6, Timestamp: ts.Add(time.Minute)},
+					},
+					WindowStart: ts,
+					WindowEnd:   ts.Add(time.Minute),
+				}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	printForensicResult(&buf, result)
+	out := buf.String()
+	assert.Contains(t, out, "tenant-a  intact (10 records)")
+	assert.Contains(t, out, "tenant-b  1 breaks in 8 records (seq 1 to 10)")
+	assert.Contains(t, out, "deleted    2025-08-29T12:00:00Z to 2025-08-29T12:01:00Z  records with seq 4 to 5 are missing")
+	assert.Contains(t, out, "seq 6       record-6")
+	assert.Contains(t, out, "✗ Hash-chain integrity compromised")
+}
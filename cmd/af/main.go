@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/agentflow/agentflow/internal/health"
+	"github.com/agentflow/agentflow/internal/storage/migrate"
 )
 
 // ValidationResult represents the JSON output structure for af validate
@@ -62,8 +63,20 @@ func main() {
 		validateEnvironment()
 	case "audit":
 		err = auditCmd(args)
+	case "auth":
+		err = authCmd(args)
 	case "backup":
 		err = backupCmd(args)
+	case "certs":
+		err = certsCmd(args)
+	case "migrate":
+		err = migrateCmd(args)
+	case "policy":
+		err = policyCmd(args)
+	case "secrets":
+		err = secretsCmd(args)
+	case "tenant":
+		err = tenantCmd(args)
 	default:
 		printUsage()
 		return
@@ -79,11 +92,43 @@ func printUsage() {
 	fmt.Println("AgentFlow CLI")
 	fmt.Println("Usage:")
 	fmt.Println("  af validate                    Validate development environment")
-	fmt.Println("  af audit verify [tenant-id]    Verify audit hash-chain integrity")
+	fmt.Println("  af audit verify [--tenant-id=ID] [--from=N] [--to=N] [--full] [--json]  Verify audit hash-chain integrity")
+	fmt.Println("  af audit verify --forensic [--tenant-id=ID] [--json]  Report every break in the chain")
+	fmt.Println("  af audit anchor [--tenant-id=ID] [--json]    Anchor audit chain heads outside the database")
+	fmt.Println("  af audit prove <audit-id> [--tree-size=N] [--output=FILE]  Prove one record is in its tenant's log")
+	fmt.Println("  af audit verify-proof <proof-file>           Verify an inclusion proof offline")
+	fmt.Println("  af audit list --tenant-id=ID [filters] [--after-seq=N] [--limit=N] [--json]  List audit records")
+	fmt.Println("  af audit export --tenant-id=ID [filters] [--format=jsonl|csv|cef] [--output=FILE]  Export audit records")
+	fmt.Println("      filters: --since=TIME --until=TIME --actor-type= --actor-id= --action= --resource-type= --resource-id=")
+	fmt.Println("  af audit verify-export <file> [--format=jsonl|csv]  Recompute the chain hashes in an export")
+	fmt.Println("  af auth rotate-signing-key [--alg=EdDSA|RS256] [--overlap=48h] [--json]  Create the key tokens are signed with")
+	fmt.Println("  af auth signing-keys [--json]                List token signing keys")
+	fmt.Println("  af auth hash-password                        Hash the password on stdin for users.hashed_secret")
 	fmt.Println("  af backup create [backup-dir]  Create database backup")
 	fmt.Println("  af backup restore <backup-id> [backup-dir] [restore-type]")
 	fmt.Println("  af backup verify <backup-id> [backup-dir] [--json]")
 	fmt.Println("  af backup list [backup-dir] [--json]")
+	fmt.Println("  af certs init [--dir=DIR] [--days=N]         Create a development CA for mutual TLS")
+	fmt.Println("  af certs issue --kind=worker|service|server --name=NAME [--tenant=ID] [--dns=HOST,...] [--ip=ADDR,...] [--days=N] [--force]  Issue a certificate")
+	fmt.Println("  af migrate up [--to=VERSION] [--json]        Apply embedded migrations")
+	fmt.Println("  af migrate down [--to=VERSION] [--json]      Roll back migrations")
+	fmt.Println("  af migrate redo [--json]                     Roll back and reapply the latest migration")
+	fmt.Println("  af migrate status [--drift] [--json]         Show migration status and schema drift")
+	fmt.Println("  af policy validate <policy-file>...         Check policy documents")
+	fmt.Println("  af policy test --policy=FILE... --input=FILE [--expect=allow|deny] [--json]  Dry-run policies against a request")
+	fmt.Println("  af secrets init [--generate-key=PATH]        Encrypt AF_SECRETS_FILE with the master key")
+	fmt.Println("  af secrets get <key> [--version=N|previous]  Print a secret or one of its versions")
+	fmt.Println("  af secrets set <key> [value]                 Store a secret, read from stdin without a value")
+	fmt.Println("  af secrets list [--json]                     List secret keys")
+	fmt.Println("  af secrets versions <key> [--json]           List a secret's versions")
+	fmt.Println("  af secrets rollback <key> <version>          Make an earlier version current again")
+	fmt.Println("  af secrets rotate-secret <key> [--overlap=24h]  Replace a secret with a random value, keeping the old one valid")
+	fmt.Println("  af secrets rotate [--data-key]               Re-wrap the data key with AF_SECRETS_NEW_* or replace the data key")
+	fmt.Println("  af tenant status|suspend|resume <tenant-id> [--reason=TEXT] [--json]")
+	fmt.Println("  af tenant delete <tenant-id> [--grace=720h] [--reason=TEXT]   Schedule erasure after a grace period")
+	fmt.Println("  af tenant cancel-delete <tenant-id>          Cancel a scheduled erasure")
+	fmt.Println("  af tenant export <tenant-id> [--output=FILE] Export all tenant data as JSON")
+	fmt.Println("  af tenant erase-due [--json]                 Erase tenants whose grace period has ended")
 }
 
 func validateEnvironment() {
@@ -369,12 +414,13 @@ func validateGolangciLint(result *ValidationResult) {
 }
 
 func validateGoose(result *ValidationResult) {
+	// Migrations are embedded and applied with af migrate; goose is only
+	// needed to scaffold new migration files
 	if !commandExists("goose") {
 		result.Tools["goose"] = ToolStatus{
 			Status:  "warning",
-			Message: "goose is not installed",
+			Message: "goose is not installed (optional, used by migrate-create; " + embeddedMigrationsSummary() + ")",
 		}
-		result.Warnings = append(result.Warnings, "goose is not installed")
 		return
 	}
 
@@ -384,6 +430,18 @@ func validateGoose(result *ValidationResult) {
 	}
 }
 
+// embeddedMigrationsSummary describes the migrations compiled into af
+func embeddedMigrationsSummary() string {
+	embedded, err := migrate.Embedded()
+	if err != nil {
+		return fmt.Sprintf("embedded migrations invalid: %v", err)
+	}
+	if len(embedded) == 0 {
+		return "no embedded migrations"
+	}
+	return fmt.Sprintf("%d embedded migrations, latest %d", len(embedded), embedded[len(embedded)-1].Version)
+}
+
 func validateSqlc(result *ValidationResult) {
 	if !commandExists("sqlc") {
 		result.Tools["sqlc"] = ToolStatus{
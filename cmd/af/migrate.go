@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/dbpool"
+	"github.com/agentflow/agentflow/internal/storage/migrate"
+	"github.com/jackc/pgx/v5"
+)
+
+// MigrateResult represents the JSON output for migrate up, down and redo
+type MigrateResult struct {
+	Status       string           `json:"status"` // "success", "error"
+	Timestamp    string           `json:"timestamp"`
+	Applied      []migrate.Result `json:"applied"`
+	Version      int64            `json:"version"`
+	ErrorMessage string           `json:"error_message,omitempty"`
+}
+
+// migrateOptions holds the parsed migrate flags
+type migrateOptions struct {
+	target     int64
+	hasTarget  bool
+	jsonOutput bool
+	drift      bool
+	schema     string
+}
+
+// migrateCmd handles schema migration operations
+func migrateCmd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate command requires a subcommand: up, down, status, redo")
+	}
+
+	subcommand := args[0]
+	switch subcommand {
+	case "up", "down", "status", "redo":
+	default:
+		return fmt.Errorf("unknown migrate subcommand: %s", subcommand)
+	}
+
+	opts, err := parseMigrateArgs(args[1:])
+	if err != nil {
+		return err
+	}
+	if opts.drift && subcommand != "status" {
+		return fmt.Errorf("--drift is only supported by migrate status")
+	}
+	if opts.hasTarget && (subcommand == "status" || subcommand == "redo") {
+		return fmt.Errorf("--to is not supported by migrate %s", subcommand)
+	}
+
+	embedded, err := migrate.Embedded()
+	if err != nil {
+		return fmt.Errorf("failed to load embedded migrations: %w", err)
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, databaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	runner := migrate.NewRunner(conn, embedded, logging.NewLoggerWithOutput(os.Stderr))
+
+	switch subcommand {
+	case "status":
+		return migrateStatus(ctx, runner, opts)
+	case "up":
+		results, err := runner.Up(ctx, opts.target)
+		return outputMigrateResult(ctx, runner, results, err, opts.jsonOutput)
+	case "down":
+		target := int64(-1)
+		if opts.hasTarget {
+			target = opts.target
+		}
+		results, err := runner.Down(ctx, target)
+		return outputMigrateResult(ctx, runner, results, err, opts.jsonOutput)
+	default:
+		results, err := runner.Redo(ctx)
+		return outputMigrateResult(ctx, runner, results, err, opts.jsonOutput)
+	}
+}
+
+// parseMigrateArgs parses --to=VERSION, --json, --drift and --schema=NAME
+func parseMigrateArgs(args []string) (migrateOptions, error) {
+	opts := migrateOptions{schema: "public"}
+	for _, arg := range args {
+		switch {
+		case arg == "--json":
+			opts.jsonOutput = true
+		case arg == "--drift":
+			opts.drift = true
+		case strings.HasPrefix(arg, "--to="):
+			version, err := strconv.ParseInt(arg[len("--to="):], 10, 64)
+			if err != nil || version < 0 {
+				return opts, fmt.Errorf("invalid migration version: %s", arg[len("--to="):])
+			}
+			opts.target = version
+			opts.hasTarget = true
+		case strings.HasPrefix(arg, "--schema="):
+			opts.schema = arg[len("--schema="):]
+			if opts.schema == "" {
+				return opts, fmt.Errorf("--schema requires a value")
+			}
+		default:
+			return opts, fmt.Errorf("unknown migrate flag: %s", arg)
+		}
+	}
+	return opts, nil
+}
+
+// databaseURL returns AF_DATABASE_URL, then DATABASE_URL, then the development default
+func databaseURL() string {
+	return dbpool.URLFromEnv()
+}
+
+func migrateStatus(ctx context.Context, runner *migrate.Runner, opts migrateOptions) error {
+	status, err := runner.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read schema status: %w", err)
+	}
+
+	if opts.drift {
+		report, err := runner.Drift(ctx, opts.schema)
+		if err != nil {
+			return fmt.Errorf("failed to check schema drift: %w", err)
+		}
+		status.Drift = report
+	}
+
+	if opts.jsonOutput {
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal status: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printStatus(status)
+	}
+
+	if problem := status.Err(); problem != nil {
+		return problem
+	}
+	if status.Drift != nil && status.Drift.HasDrift() {
+		return fmt.Errorf("schema %s has drifted from migrations: %d differences", status.Drift.Schema, len(status.Drift.Items))
+	}
+	return nil
+}
+
+func printStatus(status *migrate.Status) {
+	fmt.Printf("Schema Migration Status\n")
+	fmt.Printf("Current Version: %d\n", status.CurrentVersion)
+	fmt.Printf("Latest Version: %d\n", status.LatestVersion)
+	fmt.Printf("Pending: %d\n\n", status.Pending)
+
+	for _, ms := range status.Migrations {
+		applied := "-"
+		if ms.AppliedAt != nil {
+			applied = ms.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Printf("  %-9s %d  %-40s %s\n", ms.State, ms.Version, ms.Name, applied)
+	}
+
+	if status.Drift != nil {
+		fmt.Printf("\nDrift (%s): ", status.Drift.Schema)
+		if !status.Drift.HasDrift() {
+			fmt.Printf("none\n")
+			return
+		}
+		fmt.Printf("%d differences\n", len(status.Drift.Items))
+		for _, item := range status.Drift.Items {
+			fmt.Printf("  %-10s %-10s %s\n", item.Problem, item.Kind, item.Object)
+			if item.Expected != "" {
+				fmt.Printf("      expected: %s\n", item.Expected)
+			}
+			if item.Actual != "" {
+				fmt.Printf("      actual:   %s\n", item.Actual)
+			}
+		}
+	}
+}
+
+func outputMigrateResult(ctx context.Context, runner *migrate.Runner, results []migrate.Result, runErr error, jsonOutput bool) error {
+	result := MigrateResult{
+		Status:    "success",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Applied:   results,
+	}
+	if result.Applied == nil {
+		result.Applied = []migrate.Result{}
+	}
+	if status, err := runner.Status(ctx); err == nil {
+		result.Version = status.CurrentVersion
+	}
+	if runErr != nil {
+		result.Status = "error"
+		result.ErrorMessage = runErr.Error()
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal result: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, r := range result.Applied {
+			fmt.Printf("%-4s %d_%s (%s)\n", r.Direction, r.Version, r.Name, r.Duration)
+		}
+		if len(result.Applied) == 0 && runErr == nil {
+			fmt.Printf("No migrations to run\n")
+		}
+		fmt.Printf("Schema version: %d\n", result.Version)
+	}
+
+	if runErr != nil {
+		return fmt.Errorf("migration failed: %w", runErr)
+	}
+	return nil
+}
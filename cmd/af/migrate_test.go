@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/storage/dbpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateCmdValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{"No subcommand", []string{}, "migrate command requires a subcommand: up, down, status, redo"},
+		{"Invalid subcommand", []string{"sideways"}, "unknown migrate subcommand: sideways"},
+		{"Unknown flag", []string{"up", "--force"}, "unknown migrate flag: --force"},
+		{"Invalid version", []string{"up", "--to=latest"}, "invalid migration version: latest"},
+		{"Drift outside status", []string{"up", "--drift"}, "--drift is only supported by migrate status"},
+		{"Target on redo", []string{"redo", "--to=1"}, "--to is not supported by migrate redo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := migrateCmd(tt.args)
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
+
+func TestParseMigrateArgs(t *testing.T) {
+	opts, err := parseMigrateArgs([]string{"--to=20250823000001", "--json"})
+	require.NoError(t, err)
+	assert.True(t, opts.hasTarget)
+	assert.Equal(t, int64(20250823000001), opts.target)
+	assert.True(t, opts.jsonOutput)
+	assert.Equal(t, "public", opts.schema)
+
+	opts, err = parseMigrateArgs([]string{"--drift", "--schema=tenant_data"})
+	require.NoError(t, err)
+	assert.True(t, opts.drift)
+	assert.False(t, opts.hasTarget)
+	assert.Equal(t, "tenant_data", opts.schema)
+}
+
+func TestDatabaseURL(t *testing.T) {
+	t.Setenv("AF_DATABASE_URL", "")
+	t.Setenv("DATABASE_URL", "")
+	assert.Equal(t, dbpool.DefaultURL, databaseURL())
+
+	t.Setenv("DATABASE_URL", "postgres://db/fallback")
+	assert.Equal(t, "postgres://db/fallback", databaseURL())
+
+	t.Setenv("AF_DATABASE_URL", "postgres://db/primary")
+	assert.Equal(t, "postgres://db/primary", databaseURL())
+}
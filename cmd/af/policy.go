@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/agentflow/agentflow/internal/security/policy"
+)
+
+// PolicyTestInput is a request read by af policy test
+type PolicyTestInput struct {
+	TenantID     string                 `json:"tenant_id"`
+	Action       string                 `json:"action"`
+	ActorType    string                 `json:"actor_type"`
+	ActorID      string                 `json:"actor_id"`
+	ResourceType string                 `json:"resource_type"`
+	ResourceID   string                 `json:"resource_id"`
+	Input        map[string]interface{} `json:"input"`
+}
+
+// policyOptions holds the parsed policy flags
+type policyOptions struct {
+	files      []string
+	policies   []string
+	input      string
+	expect     string
+	jsonOutput bool
+}
+
+// policyCmd checks policy documents offline
+func policyCmd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("policy command requires a subcommand: validate, test")
+	}
+
+	subcommand := args[0]
+	opts, err := parsePolicyArgs(args[1:])
+	if err != nil {
+		return err
+	}
+
+	switch subcommand {
+	case "validate":
+		if len(opts.files) == 0 {
+			return fmt.Errorf("validate requires at least one policy file")
+		}
+		for _, file := range opts.files {
+			p, err := loadPolicyFile(file)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s: %d rules, mode %s\n", file, len(p.Rules), p.Mode)
+		}
+		return nil
+	case "test":
+		if len(opts.policies) == 0 || opts.input == "" {
+			return fmt.Errorf("test requires --policy=FILE and --input=FILE")
+		}
+		var policies []*policy.Policy
+		for _, file := range opts.policies {
+			p, err := loadPolicyFile(file)
+			if err != nil {
+				return err
+			}
+			policies = append(policies, p)
+		}
+		req, err := loadPolicyInput(opts.input)
+		if err != nil {
+			return err
+		}
+		decision := policy.Evaluate(policies, req)
+		if err := outputDecision(os.Stdout, decision, opts.jsonOutput); err != nil {
+			return err
+		}
+		return checkExpectation(decision, opts.expect)
+	default:
+		return fmt.Errorf("unknown policy subcommand: %s", subcommand)
+	}
+}
+
+// parsePolicyArgs parses policy files, --policy=, --input=, --expect= and --json
+func parsePolicyArgs(args []string) (policyOptions, error) {
+	var opts policyOptions
+	for _, arg := range args {
+		switch {
+		case arg == "--json":
+			opts.jsonOutput = true
+		case strings.HasPrefix(arg, "--policy="):
+			opts.policies = append(opts.policies, arg[len("--policy="):])
+		case strings.HasPrefix(arg, "--input="):
+			opts.input = arg[len("--input="):]
+		case strings.HasPrefix(arg, "--expect="):
+			opts.expect = arg[len("--expect="):]
+			if opts.expect != policy.EffectAllow && opts.expect != policy.EffectDeny {
+				return opts, fmt.Errorf("invalid expectation: %s (want allow or deny)", opts.expect)
+			}
+		case strings.HasPrefix(arg, "--"):
+			return opts, fmt.Errorf("unknown policy flag: %s", arg)
+		default:
+			opts.files = append(opts.files, arg)
+		}
+	}
+	return opts, nil
+}
+
+// loadPolicyFile parses a policy document, named after its file unless it
+// names itself
+func loadPolicyFile(file string) (*policy.Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	p, err := policy.Parse(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)), data)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("%s holds no rules", file)
+	}
+	return p, nil
+}
+
+func loadPolicyInput(file string) (*policy.Request, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	var in PolicyTestInput
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("invalid input %s: %w", file, err)
+	}
+	switch in.Action {
+	case policy.ActionWorkflowRun, policy.ActionToolCall, policy.ActionMessageSend:
+	default:
+		return nil, fmt.Errorf("invalid input %s: unknown action %q", file, in.Action)
+	}
+	return &policy.Request{
+		TenantID:     in.TenantID,
+		Action:       in.Action,
+		ActorType:    in.ActorType,
+		ActorID:      in.ActorID,
+		ResourceType: in.ResourceType,
+		ResourceID:   in.ResourceID,
+		Input:        in.Input,
+	}, nil
+}
+
+func outputDecision(w io.Writer, decision *policy.Decision, jsonOutput bool) error {
+	if jsonOutput {
+		data, err := json.MarshalIndent(decision, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	status := "ALLOWED"
+	if !decision.Allowed {
+		status = "DENIED"
+	}
+	fmt.Fprintf(w, "%s %s: %s\n", status, decision.Action, decision.Reason)
+	for _, p := range decision.Policies {
+		effect := p.Effect
+		if effect == "" {
+			effect = "abstain"
+		}
+		line := fmt.Sprintf("  %-20s %-8s %-7s", p.Policy, p.Mode, effect)
+		if len(p.Rules) > 0 {
+			line += " rules " + strings.Join(p.Rules, ", ")
+		} else if p.Default {
+			line += " by default"
+		}
+		if p.Error != "" {
+			line += " (" + p.Error + ")"
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+	return nil
+}
+
+// checkExpectation fails when the enforced decision is not the expected one
+func checkExpectation(decision *policy.Decision, expect string) error {
+	if expect == "" {
+		return nil
+	}
+	got := policy.EffectAllow
+	if !decision.Allowed {
+		got = policy.EffectDeny
+	}
+	if got != expect {
+		return fmt.Errorf("expected %s, policies decided %s: %s", expect, got, decision.Reason)
+	}
+	return nil
+}
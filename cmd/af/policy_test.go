@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/security/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePolicyFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestPolicyCmdValidation(t *testing.T) {
+	empty := writePolicyFile(t, "empty.json", `{}`)
+	invalid := writePolicyFile(t, "invalid.json", `{"rules":[{"effect":"allow","when":"tool.name =="}]}`)
+	input := writePolicyFile(t, "input.json", `{"action":"agent.kill"}`)
+	valid := writePolicyFile(t, "valid.json", `{"rules":[{"effect":"allow"}]}`)
+
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{"No subcommand", []string{}, "policy command requires a subcommand: validate, test"},
+		{"Invalid subcommand", []string{"apply"}, "unknown policy subcommand: apply"},
+		{"Unknown flag", []string{"test", "--tenant=t"}, "unknown policy flag: --tenant=t"},
+		{"Invalid expectation", []string{"test", "--expect=maybe"}, "invalid expectation: maybe (want allow or deny)"},
+		{"No files", []string{"validate"}, "validate requires at least one policy file"},
+		{"No rules", []string{"validate", empty}, empty + " holds no rules"},
+		{"Invalid rule", []string{"validate", invalid}, "invalid policy invalid: rule rule-1: invalid policy expression: unexpected \"end of expression\" at offset 12"},
+		{"No input", []string{"test", "--policy=" + valid}, "test requires --policy=FILE and --input=FILE"},
+		{"Unknown action", []string{"test", "--policy=" + valid, "--input=" + input}, "invalid input " + input + ": unknown action \"agent.kill\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policyCmd(tt.args)
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
+
+func TestPolicyTestCmd(t *testing.T) {
+	tenant := writePolicyFile(t, "tenant.json", `{
+		"rules": [{"id": "no-shell", "effect": "deny", "actions": ["tool.call"], "when": "tool.name == \"shell\""}]
+	}`)
+	shadow := writePolicyFile(t, "shadow.json", `{"mode": "dry_run", "default": "deny", "rules": []}`)
+	shell := writePolicyFile(t, "shell.json", `{"action": "tool.call", "actor_type": "agent", "actor_id": "a1", "input": {"tool": {"name": "shell"}}}`)
+	search := writePolicyFile(t, "search.json", `{"action": "tool.call", "input": {"tool": {"name": "search"}}}`)
+
+	require.NoError(t, policyCmd([]string{"validate", tenant, shadow}))
+	require.NoError(t, policyCmd([]string{"test", "--policy=" + tenant, "--input=" + shell, "--expect=deny"}))
+	require.NoError(t, policyCmd([]string{"test", "--policy=" + tenant, "--policy=" + shadow, "--input=" + search, "--expect=allow"}))
+
+	err := policyCmd([]string{"test", "--policy=" + tenant, "--input=" + shell, "--expect=allow"})
+	assert.EqualError(t, err, "expected allow, policies decided deny: denied by tenant rule no-shell")
+}
+
+func TestOutputDecision(t *testing.T) {
+	decision := &policy.Decision{
+		Action:        policy.ActionToolCall,
+		Allowed:       false,
+		DryRunAllowed: false,
+		Reason:        "denied by tenant rule no-shell",
+		Policies: []policy.PolicyDecision{
+			{Policy: "tenant", Mode: policy.ModeEnforce, Effect: policy.EffectDeny, Rules: []string{"no-shell"}},
+			{Policy: "shadow", Mode: policy.ModeDryRun, Effect: policy.EffectDeny, Default: true},
+			{Policy: "agent:a1", Mode: policy.ModeEnforce},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, outputDecision(&buf, decision, false))
+	assert.Equal(t, "DENIED tool.call: denied by tenant rule no-shell\n"+
+		"  tenant               enforce  deny    rules no-shell\n"+
+		"  shadow               dry_run  deny    by default\n"+
+		"  agent:a1             enforce  abstain\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, outputDecision(&buf, decision, true))
+	var got policy.Decision
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, *decision, got)
+}
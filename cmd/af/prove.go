@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/dbpool"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// auditProveOptions holds the parsed audit prove flags
+type auditProveOptions struct {
+	auditID  pgtype.UUID
+	treeSize int64
+	output   string
+}
+
+// parseAuditProveArgs parses <audit-id>, --tree-size= and --output=
+func parseAuditProveArgs(args []string) (auditProveOptions, error) {
+	var opts auditProveOptions
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--tree-size="):
+			size, err := strconv.ParseInt(arg[len("--tree-size="):], 10, 64)
+			if err != nil || size < 1 {
+				return opts, fmt.Errorf("invalid tree size: %s", arg[len("--tree-size="):])
+			}
+			opts.treeSize = size
+		case strings.HasPrefix(arg, "--output="):
+			opts.output = arg[len("--output="):]
+		case strings.HasPrefix(arg, "--"):
+			return opts, fmt.Errorf("unknown flag: %s", arg)
+		default:
+			if opts.auditID.Valid {
+				return opts, fmt.Errorf("unexpected argument: %s", arg)
+			}
+			if err := opts.auditID.Scan(arg); err != nil {
+				return opts, fmt.Errorf("invalid audit ID format: %s", arg)
+			}
+		}
+	}
+	if !opts.auditID.Valid {
+		return opts, fmt.Errorf("audit prove requires an audit ID")
+	}
+	return opts, nil
+}
+
+// proveAuditRecord writes an inclusion proof for one audit record
+func proveAuditRecord(args []string) error {
+	opts, err := parseAuditProveArgs(args)
+	if err != nil {
+		return err
+	}
+	signer, err := anchorSigner()
+	if err != nil {
+		return err
+	}
+	if signer == nil {
+		return fmt.Errorf("AF_AUDIT_ANCHOR_KEY must be set to sign tree heads")
+	}
+
+	ctx := context.Background()
+	cluster, err := dbpool.Open(ctx, dbpool.LoadFromEnv(), logging.NewLoggerWithOutput(os.Stderr))
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer cluster.Close()
+
+	// Proofs read and sign on the primary so a new record is provable at once
+	q := queries.New(cluster.Primary())
+	record, err := q.GetAuditByID(ctx, opts.auditID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return audit.ErrAuditNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get audit record: %w", err)
+	}
+
+	proof, err := audit.NewProver(q, q, signer).Prove(ctx, record.TenantID, opts.auditID, opts.treeSize)
+	if err != nil {
+		return fmt.Errorf("failed to build inclusion proof: %w", err)
+	}
+
+	data, err := json.MarshalIndent(proof, "", "  ")
+	if err != nil {
+		return err
+	}
+	if opts.output == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(opts.output, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write proof: %w", err)
+	}
+	fmt.Printf("Inclusion proof for seq %d in a tree of %d records written to %s\n", proof.Seq, proof.TreeHead.TreeSize, opts.output)
+	return nil
+}
+
+// verifyInclusionProof checks a proof file without database access
+func verifyInclusionProof(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("audit verify-proof requires a proof file")
+	}
+	publicKey, err := anchorPublicKey()
+	if err != nil {
+		return err
+	}
+	if publicKey == nil {
+		return fmt.Errorf("AF_AUDIT_ANCHOR_PUBLIC_KEY or AF_AUDIT_ANCHOR_KEY must be set to verify proofs")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read proof: %w", err)
+	}
+	var proof audit.InclusionProof
+	if err := json.Unmarshal(data, &proof); err != nil {
+		return fmt.Errorf("failed to parse proof: %w", err)
+	}
+	if err := audit.VerifyInclusionProof(publicKey, proof); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Audit record %s (seq %d) is included in tenant %s's log of %d records\n",
+		proof.AuditID, proof.Seq, proof.TreeHead.TenantID, proof.TreeHead.TreeSize)
+	fmt.Printf("Root: %s (signed %s)\n", proof.TreeHead.Root, proof.TreeHead.SignedAt.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseAuditProveArgs tests audit prove argument parsing
+func TestParseAuditProveArgs(t *testing.T) {
+	opts, err := parseAuditProveArgs([]string{"123e4567-e89b-12d3-a456-426614174000", "--tree-size=10", "--output=proof.json"})
+	require.NoError(t, err)
+	assert.True(t, opts.auditID.Valid)
+	assert.Equal(t, int64(10), opts.treeSize)
+	assert.Equal(t, "proof.json", opts.output)
+
+	invalid := [][]string{
+		nil,
+		{"not-a-uuid"},
+		{"123e4567-e89b-12d3-a456-426614174000", "--tree-size=0"},
+		{"123e4567-e89b-12d3-a456-426614174000", "--verbose"},
+		{"123e4567-e89b-12d3-a456-426614174000", "123e4567-e89b-12d3-a456-426614174001"},
+	}
+	for _, args := range invalid {
+		_, err := parseAuditProveArgs(args)
+		assert.Error(t, err, "args %v", args)
+	}
+}
+
+// TestVerifyInclusionProofCommand tests offline proof verification
+func TestVerifyInclusionProofCommand(t *testing.T) {
+	clearAnchorEnv(t)
+	seed, err := hex.DecodeString(testAnchorKey)
+	require.NoError(t, err)
+	signer, err := audit.NewAnchorSigner(seed)
+	require.NoError(t, err)
+
+	// A tree of one record: the root is the record's leaf hash
+	record := audit.AuditRecord{
+		TenantID:     "123e4567-e89b-12d3-a456-426614174000",
+		ActorType:    "user",
+		ActorID:      "user-1",
+		Action:       "create",
+		ResourceType: "workflow",
+		Details:      json.RawMessage(`{"name":"demo"}`),
+		Timestamp:    time.Date(2025, 8, 29, 12, 0, 0, 0, time.UTC),
+	}
+	hash, err := audit.ComputeHash(nil, record)
+	require.NoError(t, err)
+	proof := audit.InclusionProof{
+		AuditID:    "123e4567-e89b-12d3-a456-426614174001",
+		Seq:        1,
+		Record:     record,
+		RecordHash: hex.EncodeToString(hash),
+		TreeHead:   signer.SignTreeHead(record.TenantID, 1, audit.MerkleLeafHash(hash)),
+	}
+	data, err := json.Marshal(proof)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "proof.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	err = verifyInclusionProof([]string{path})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AF_AUDIT_ANCHOR_PUBLIC_KEY")
+
+	t.Setenv("AF_AUDIT_ANCHOR_PUBLIC_KEY", hex.EncodeToString(signer.PublicKey()))
+	assert.NoError(t, verifyInclusionProof([]string{path}))
+
+	proof.Record.Action = "delete"
+	data, err = json.Marshal(proof)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	assert.ErrorIs(t, verifyInclusionProof([]string{path}), audit.ErrProofInvalid)
+
+	assert.Error(t, verifyInclusionProof(nil))
+	assert.Error(t, verifyInclusionProof([]string{filepath.Join(t.TempDir(), "missing.json")}))
+}
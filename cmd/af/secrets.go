@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/security/secrets"
+)
+
+// secretsOptions holds the parsed secrets flags
+type secretsOptions struct {
+	generateKey string
+	dataKey     bool
+	jsonOutput  bool
+	version     int
+	overlap     time.Duration
+	args        []string
+}
+
+// SecretVersionResult represents the JSON output for a secret version; the
+// value is left out
+type SecretVersionResult struct {
+	Version   int    `json:"version"`
+	Status    string `json:"status"` // current, valid, expired
+	CreatedAt string `json:"created_at"`
+	CreatedBy string `json:"created_by,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// secretsCmd manages the secrets file named by AF_SECRETS_FILE, encrypted
+// with the master key from AF_SECRETS_MASTER_KEY, AF_SECRETS_PASSPHRASE or
+// AF_SECRETS_KEY_FILE
+func secretsCmd(args []string) error {
+	return runSecrets(args, os.Stdin, os.Stdout)
+}
+
+func runSecrets(args []string, r io.Reader, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("secrets command requires a subcommand: init, get, set, list, versions, rollback, rotate-secret, rotate")
+	}
+
+	subcommand := args[0]
+	opts, err := parseSecretsArgs(args[1:])
+	if err != nil {
+		return err
+	}
+	path := os.Getenv("AF_SECRETS_FILE")
+	if path == "" {
+		return fmt.Errorf("secrets %s requires AF_SECRETS_FILE to name the secrets file", subcommand)
+	}
+
+	ctx := secrets.WithActor(context.Background(), cliActor().ID)
+	switch subcommand {
+	case "init":
+		key, err := secrets.MasterKeyFromEnv()
+		if err != nil {
+			return err
+		}
+		if opts.generateKey != "" {
+			if key != nil {
+				return fmt.Errorf("--generate-key cannot be combined with a master key from the environment")
+			}
+			generated, err := secrets.GenerateMasterKeyFile(opts.generateKey)
+			if err != nil {
+				return err
+			}
+			key = &generated
+			fmt.Fprintf(w, "Generated master key %s; set AF_SECRETS_KEY_FILE=%s to use it\n", opts.generateKey, opts.generateKey)
+		}
+		if key == nil {
+			return fmt.Errorf("init requires a master key: set AF_SECRETS_MASTER_KEY, AF_SECRETS_PASSPHRASE or AF_SECRETS_KEY_FILE, or pass --generate-key=PATH")
+		}
+		n, err := secrets.EncryptFile(path, *key)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Encrypted %d secrets in %s\n", n, path)
+		return nil
+	case "get":
+		if len(opts.args) != 1 {
+			return fmt.Errorf("get requires a key")
+		}
+		provider, err := openSecretsFile(path)
+		if err != nil {
+			return err
+		}
+		version, err := provider.GetSecretVersion(ctx, opts.args[0], opts.version)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, version.Value)
+		return nil
+	case "set":
+		if len(opts.args) != 1 && len(opts.args) != 2 {
+			return fmt.Errorf("set requires a key and an optional value")
+		}
+		var value string
+		if len(opts.args) == 2 {
+			value = opts.args[1]
+		} else {
+			// Reading the value from stdin keeps it out of the shell history
+			// and process list
+			line, err := bufio.NewReader(r).ReadString('\n')
+			if err != nil && err != io.EOF {
+				return fmt.Errorf("failed to read value: %w", err)
+			}
+			value = strings.TrimRight(line, "\r\n")
+		}
+		if value == "" {
+			return fmt.Errorf("no value for %s", opts.args[0])
+		}
+		provider, err := openSecretsFile(path)
+		if err != nil {
+			return err
+		}
+		return provider.SetSecret(ctx, opts.args[0], value)
+	case "list":
+		provider, err := openSecretsFile(path)
+		if err != nil {
+			return err
+		}
+		keys, err := provider.ListSecrets(ctx)
+		if err != nil {
+			return err
+		}
+		sort.Strings(keys)
+		if opts.jsonOutput {
+			data, err := json.MarshalIndent(keys, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, string(data))
+			return nil
+		}
+		for _, key := range keys {
+			fmt.Fprintln(w, key)
+		}
+		return nil
+	case "versions":
+		if len(opts.args) != 1 {
+			return fmt.Errorf("versions requires a key")
+		}
+		provider, err := openSecretsFile(path)
+		if err != nil {
+			return err
+		}
+		versions, err := provider.ListSecretVersions(ctx, opts.args[0])
+		if err != nil {
+			return err
+		}
+		return outputSecretVersions(w, versions, time.Now(), opts.jsonOutput)
+	case "rollback":
+		if len(opts.args) != 2 {
+			return fmt.Errorf("rollback requires a key and a version")
+		}
+		version, err := strconv.Atoi(opts.args[1])
+		if err != nil || version < 1 {
+			return fmt.Errorf("invalid version: %s", opts.args[1])
+		}
+		provider, err := openSecretsFile(path)
+		if err != nil {
+			return err
+		}
+		restored, err := provider.Rollback(ctx, opts.args[0], version)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Restored %s version %d as version %d\n", opts.args[0], version, restored.Version)
+		return nil
+	case "rotate-secret":
+		if len(opts.args) != 1 {
+			return fmt.Errorf("rotate-secret requires a key")
+		}
+		provider, err := openSecretsFile(path)
+		if err != nil {
+			return err
+		}
+		version, err := provider.RotateWithOverlap(ctx, opts.args[0], opts.overlap)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Rotated %s to version %d; the previous value stays valid for %s\n", opts.args[0], version.Version, opts.overlap)
+		return nil
+	case "rotate":
+		provider, err := openSecretsFile(path)
+		if err != nil {
+			return err
+		}
+		if !provider.Encrypted() {
+			return fmt.Errorf("rotate requires a master key for an encrypted secrets file")
+		}
+		if opts.dataKey {
+			if err := provider.RotateDataKey(ctx); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "Re-encrypted %s with a new data key\n", path)
+			return nil
+		}
+		newKey, err := newMasterKeyFromEnv()
+		if err != nil {
+			return err
+		}
+		if err := provider.RotateMasterKey(ctx, newKey); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Re-wrapped the data key of %s with the new %s\n", path, newKey.Kind())
+		return nil
+	default:
+		return fmt.Errorf("unknown secrets subcommand: %s", subcommand)
+	}
+}
+
+// openSecretsFile opens the secrets file at path, encrypted when a master key
+// is configured
+func openSecretsFile(path string) (*secrets.FileProvider, error) {
+	key, err := secrets.MasterKeyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return secrets.NewFileProvider(path), nil
+	}
+	return secrets.NewEncryptedFileProvider(path, *key)
+}
+
+// newMasterKeyFromEnv returns the master key to rotate to, from
+// AF_SECRETS_NEW_MASTER_KEY, AF_SECRETS_NEW_PASSPHRASE or
+// AF_SECRETS_NEW_KEY_FILE
+func newMasterKeyFromEnv() (secrets.MasterKey, error) {
+	key, err := secrets.MasterKeyFromEnvVars("AF_SECRETS_NEW_MASTER_KEY", "AF_SECRETS_NEW_PASSPHRASE", "AF_SECRETS_NEW_KEY_FILE")
+	if err != nil {
+		return secrets.MasterKey{}, err
+	}
+	if key == nil {
+		return secrets.MasterKey{}, fmt.Errorf("rotate requires the new master key in AF_SECRETS_NEW_MASTER_KEY, AF_SECRETS_NEW_PASSPHRASE or AF_SECRETS_NEW_KEY_FILE, or --data-key")
+	}
+	return *key, nil
+}
+
+func outputSecretVersions(w io.Writer, versions []secrets.Version, now time.Time, jsonOutput bool) error {
+	results := make([]SecretVersionResult, 0, len(versions))
+	for i, v := range versions {
+		result := SecretVersionResult{
+			Version:   v.Version,
+			Status:    "valid",
+			CreatedAt: v.CreatedAt.UTC().Format(time.RFC3339),
+			CreatedBy: v.CreatedBy,
+		}
+		switch {
+		case i == 0:
+			result.Status = "current"
+		case v.Expired(now):
+			result.Status = "expired"
+		}
+		if v.ExpiresAt != nil {
+			result.ExpiresAt = v.ExpiresAt.UTC().Format(time.RFC3339)
+		}
+		results = append(results, result)
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	for _, r := range results {
+		line := fmt.Sprintf("%-4d %-8s created %s", r.Version, r.Status, r.CreatedAt)
+		if r.CreatedBy != "" {
+			line += " by " + r.CreatedBy
+		}
+		if r.ExpiresAt != "" {
+			line += ", expires " + r.ExpiresAt
+		}
+		fmt.Fprintln(w, line)
+	}
+	return nil
+}
+
+// parseSecretsArgs parses --generate-key=, --data-key, --version=,
+// --overlap= and --json, and returns the other arguments in order
+func parseSecretsArgs(args []string) (secretsOptions, error) {
+	opts := secretsOptions{version: secrets.VersionCurrent, overlap: secrets.DefaultRotationOverlap}
+	if value := os.Getenv("AF_SECRETS_ROTATION_OVERLAP"); value != "" {
+		overlap, err := time.ParseDuration(value)
+		if err != nil || overlap < 0 {
+			return opts, fmt.Errorf("invalid AF_SECRETS_ROTATION_OVERLAP: %s", value)
+		}
+		opts.overlap = overlap
+	}
+	for _, arg := range args {
+		switch {
+		case arg == "--json":
+			opts.jsonOutput = true
+		case arg == "--data-key":
+			opts.dataKey = true
+		case arg == "--version=previous":
+			opts.version = secrets.VersionPrevious
+		case strings.HasPrefix(arg, "--version="):
+			version, err := strconv.Atoi(arg[len("--version="):])
+			if err != nil || version < 1 {
+				return opts, fmt.Errorf("invalid version: %s", arg[len("--version="):])
+			}
+			opts.version = version
+		case strings.HasPrefix(arg, "--overlap="):
+			overlap, err := time.ParseDuration(arg[len("--overlap="):])
+			if err != nil || overlap < 0 {
+				return opts, fmt.Errorf("invalid overlap: %s", arg[len("--overlap="):])
+			}
+			opts.overlap = overlap
+		case strings.HasPrefix(arg, "--generate-key="):
+			opts.generateKey = arg[len("--generate-key="):]
+		case strings.HasPrefix(arg, "--"):
+			return opts, fmt.Errorf("unknown secrets flag: %s", arg)
+		default:
+			opts.args = append(opts.args, arg)
+		}
+	}
+	return opts, nil
+}
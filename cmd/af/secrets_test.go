@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/security/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearSecretsEnv unsets the master key variables for the test
+func clearSecretsEnv(t *testing.T) {
+	for _, name := range []string{
+		"AF_SECRETS_MASTER_KEY", "AF_SECRETS_PASSPHRASE", "AF_SECRETS_KEY_FILE",
+		"AF_SECRETS_NEW_MASTER_KEY", "AF_SECRETS_NEW_PASSPHRASE", "AF_SECRETS_NEW_KEY_FILE",
+		"AF_SECRETS_ROTATION_OVERLAP",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestSecretsCmdValidation(t *testing.T) {
+	clearSecretsEnv(t)
+	t.Setenv("AF_SECRETS_FILE", filepath.Join(t.TempDir(), "secrets.json"))
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{"No subcommand", []string{}, "secrets command requires a subcommand: init, get, set, list, versions, rollback, rotate-secret, rotate"},
+		{"Invalid subcommand", []string{"delete"}, "unknown secrets subcommand: delete"},
+		{"Unknown flag", []string{"list", "--all"}, "unknown secrets flag: --all"},
+		{"Missing key", []string{"get"}, "get requires a key"},
+		{"Invalid version", []string{"get", "api_key", "--version=0"}, "invalid version: 0"},
+		{"Invalid overlap", []string{"rotate-secret", "api_key", "--overlap=2d"}, "invalid overlap: 2d"},
+		{"Missing rollback version", []string{"rollback", "api_key"}, "rollback requires a key and a version"},
+		{"No master key", []string{"init"}, "init requires a master key: set AF_SECRETS_MASTER_KEY, AF_SECRETS_PASSPHRASE or AF_SECRETS_KEY_FILE, or pass --generate-key=PATH"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runSecrets(tt.args, strings.NewReader(""), &bytes.Buffer{})
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+
+	t.Setenv("AF_SECRETS_FILE", "")
+	err := runSecrets([]string{"list"}, strings.NewReader(""), &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Equal(t, "secrets list requires AF_SECRETS_FILE to name the secrets file", err.Error())
+}
+
+func TestSecretsCmd(t *testing.T) {
+	clearSecretsEnv(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.json")
+	keyFile := filepath.Join(dir, "master.key")
+	t.Setenv("AF_SECRETS_FILE", path)
+	var out bytes.Buffer
+
+	// An existing plaintext file is encrypted by init
+	require.NoError(t, runSecrets([]string{"set", "api_key", "plain"}, strings.NewReader(""), &out))
+	require.NoError(t, runSecrets([]string{"init", "--generate-key=" + keyFile}, strings.NewReader(""), &out))
+	assert.Contains(t, out.String(), "Encrypted 1 secrets")
+
+	// Without the key the file is unreadable
+	err := runSecrets([]string{"get", "api_key"}, strings.NewReader(""), &out)
+	assert.ErrorIs(t, err, secrets.ErrEncrypted)
+
+	t.Setenv("AF_SECRETS_KEY_FILE", keyFile)
+	require.NoError(t, runSecrets([]string{"set", "db_password"}, strings.NewReader("from-stdin\n"), &out))
+	out.Reset()
+	require.NoError(t, runSecrets([]string{"get", "db_password"}, strings.NewReader(""), &out))
+	assert.Equal(t, "from-stdin\n", out.String())
+
+	out.Reset()
+	require.NoError(t, runSecrets([]string{"list", "--json"}, strings.NewReader(""), &out))
+	var keys []string
+	require.NoError(t, json.Unmarshal(out.Bytes(), &keys))
+	assert.Equal(t, []string{"api_key", "db_password"}, keys)
+
+	// Rotating needs the new key
+	err = runSecrets([]string{"rotate"}, strings.NewReader(""), &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AF_SECRETS_NEW_MASTER_KEY")
+
+	t.Setenv("AF_SECRETS_NEW_PASSPHRASE", "new passphrase")
+	require.NoError(t, runSecrets([]string{"rotate"}, strings.NewReader(""), &out))
+	assert.Error(t, runSecrets([]string{"get", "api_key"}, strings.NewReader(""), &out))
+
+	t.Setenv("AF_SECRETS_KEY_FILE", "")
+	t.Setenv("AF_SECRETS_PASSPHRASE", "new passphrase")
+	require.NoError(t, runSecrets([]string{"rotate", "--data-key"}, strings.NewReader(""), &out))
+	out.Reset()
+	require.NoError(t, runSecrets([]string{"get", "api_key"}, strings.NewReader(""), &out))
+	assert.Equal(t, "plain\n", out.String())
+}
+
+func TestSecretsCmdVersions(t *testing.T) {
+	clearSecretsEnv(t)
+	t.Setenv("AF_SECRETS_FILE", filepath.Join(t.TempDir(), "secrets.json"))
+	t.Setenv("USER", "alice")
+	var out bytes.Buffer
+	run := func(args ...string) error {
+		out.Reset()
+		return runSecrets(args, strings.NewReader(""), &out)
+	}
+
+	require.NoError(t, run("set", "db_password", "first"))
+	require.NoError(t, run("set", "db_password", "second"))
+	require.NoError(t, run("rotate-secret", "db_password", "--overlap=1h"))
+	assert.Contains(t, out.String(), "Rotated db_password to version 3")
+
+	require.NoError(t, run("get", "db_password", "--version=previous"))
+	assert.Equal(t, "second\n", out.String())
+	require.NoError(t, run("get", "db_password", "--version=1"))
+	assert.Equal(t, "first\n", out.String())
+
+	require.NoError(t, run("versions", "db_password", "--json"))
+	var versions []SecretVersionResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &versions))
+	require.Len(t, versions, 3)
+	assert.Equal(t, []string{"current", "valid", "expired"}, []string{versions[0].Status, versions[1].Status, versions[2].Status})
+	assert.Equal(t, "af-cli:alice", versions[0].CreatedBy)
+	assert.NotContains(t, out.String(), "second", "values are not listed")
+
+	require.NoError(t, run("rollback", "db_password", "1"))
+	assert.Equal(t, "Restored db_password version 1 as version 4\n", out.String())
+	require.NoError(t, run("get", "db_password"))
+	assert.Equal(t, "first\n", out.String())
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/agentflow/agentflow/internal/storage/tenant"
+	"github.com/agentflow/agentflow/pkg/messaging"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nats-io/nats.go"
+)
+
+// TenantStatusResult represents the JSON output for tenant lifecycle commands
+type TenantStatusResult struct {
+	TenantID            string `json:"tenant_id"`
+	Name                string `json:"name"`
+	Status              string `json:"status"`
+	Reason              string `json:"reason,omitempty"`
+	DeletionScheduledAt string `json:"deletion_scheduled_at,omitempty"`
+	ErasedAt            string `json:"erased_at,omitempty"`
+}
+
+// tenantOptions holds the parsed tenant flags
+type tenantOptions struct {
+	tenantID   pgtype.UUID
+	reason     string
+	grace      time.Duration
+	output     string
+	jsonOutput bool
+}
+
+// tenantSubcommands lists the subcommands and whether they take a tenant ID
+var tenantSubcommands = map[string]bool{
+	"status":        true,
+	"suspend":       true,
+	"resume":        true,
+	"delete":        true,
+	"cancel-delete": true,
+	"export":        true,
+	"erase-due":     false,
+}
+
+// tenantCmd handles tenant lifecycle operations
+func tenantCmd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("tenant command requires a subcommand: status, suspend, resume, delete, cancel-delete, export, erase-due")
+	}
+
+	subcommand := args[0]
+	needsID, ok := tenantSubcommands[subcommand]
+	if !ok {
+		return fmt.Errorf("unknown tenant subcommand: %s", subcommand)
+	}
+
+	opts, err := parseTenantArgs(args[1:], needsID)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, databaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	svc := tenant.NewService(conn)
+	actor := cliActor()
+
+	var t *queries.Tenant
+	switch subcommand {
+	case "status":
+		t, err = svc.Get(ctx, opts.tenantID)
+	case "suspend":
+		t, err = svc.Suspend(ctx, actor, opts.tenantID, opts.reason)
+	case "resume":
+		t, err = svc.Resume(ctx, actor, opts.tenantID)
+	case "delete":
+		t, err = svc.ScheduleDeletion(ctx, actor, opts.tenantID, opts.reason, opts.grace)
+	case "cancel-delete":
+		t, err = svc.CancelDeletion(ctx, actor, opts.tenantID)
+	case "export":
+		return exportTenant(ctx, svc, opts)
+	default:
+		return eraseDueTenants(ctx, svc, actor, opts.jsonOutput)
+	}
+	if err != nil {
+		return err
+	}
+	if subcommand != "status" {
+		announceTenantChanges(ctx, formatUUID(t.ID))
+	}
+	return outputTenantStatus(os.Stdout, t, opts.jsonOutput)
+}
+
+// announceTenantChanges tells running control planes to drop their cached
+// copies of the tenants when AF_BUS_URL is set. The change is already
+// stored, so a failed announcement only delays it until their caches expire.
+func announceTenantChanges(ctx context.Context, tenantIDs ...string) {
+	url := os.Getenv("AF_BUS_URL")
+	if url == "" || len(tenantIDs) == 0 {
+		return
+	}
+	if err := publishTenantChanges(ctx, url, tenantIDs); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; control planes apply the change within %s\n", err, security.DefaultTenantCacheTTL)
+	}
+}
+
+func publishTenantChanges(ctx context.Context, url string, tenantIDs []string) error {
+	busConfig, err := messaging.LoadBusConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	opts, err := busConfig.NATSOptions()
+	if err != nil {
+		return err
+	}
+	conn, err := nats.Connect(url, append(opts, nats.Timeout(5*time.Second))...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	defer conn.Close()
+
+	bus := security.NewNATSTenantInvalidationBus(conn, security.DefaultTenantInvalidationSubject)
+	for _, id := range tenantIDs {
+		if err := bus.Publish(ctx, id); err != nil {
+			return err
+		}
+	}
+	if err := conn.FlushTimeout(5 * time.Second); err != nil {
+		return fmt.Errorf("failed to announce tenant change: %w", err)
+	}
+	return nil
+}
+
+// parseTenantArgs parses the tenant ID and --reason=, --grace=, --output= and --json
+func parseTenantArgs(args []string, needsID bool) (tenantOptions, error) {
+	opts := tenantOptions{grace: tenant.DefaultGracePeriod}
+	var positional []string
+	for _, arg := range args {
+		switch {
+		case arg == "--json":
+			opts.jsonOutput = true
+		case strings.HasPrefix(arg, "--reason="):
+			opts.reason = arg[len("--reason="):]
+		case strings.HasPrefix(arg, "--output="):
+			opts.output = arg[len("--output="):]
+		case strings.HasPrefix(arg, "--grace="):
+			grace, err := time.ParseDuration(arg[len("--grace="):])
+			if err != nil || grace < 0 {
+				return opts, fmt.Errorf("invalid grace period: %s", arg[len("--grace="):])
+			}
+			opts.grace = grace
+		case strings.HasPrefix(arg, "--"):
+			return opts, fmt.Errorf("unknown tenant flag: %s", arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if !needsID {
+		if len(positional) > 0 {
+			return opts, fmt.Errorf("unexpected argument: %s", positional[0])
+		}
+		return opts, nil
+	}
+	if len(positional) != 1 {
+		return opts, fmt.Errorf("tenant ID is required")
+	}
+	if err := opts.tenantID.Scan(positional[0]); err != nil {
+		return opts, fmt.Errorf("invalid tenant ID format: %s", positional[0])
+	}
+	return opts, nil
+}
+
+// cliActor attributes CLI changes to the operator's login in the audit log
+func cliActor() tenant.Actor {
+	user := os.Getenv("USER")
+	if user == "" {
+		user = "unknown"
+	}
+	return tenant.Actor{Type: "user", ID: "af-cli:" + user}
+}
+
+func exportTenant(ctx context.Context, svc *tenant.Service, opts tenantOptions) error {
+	var w io.Writer = os.Stdout
+	if opts.output != "" {
+		f, err := os.OpenFile(opts.output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	manifest, err := svc.Export(ctx, opts.tenantID, w)
+	if err != nil {
+		if opts.output != "" {
+			_ = os.Remove(opts.output)
+		}
+		return err
+	}
+
+	// The archive itself goes to stdout when no file is given
+	if opts.output != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d agents, %d workflows, %d messages and %d audit records to %s\n",
+			manifest.Counts["agents"], manifest.Counts["workflows"], manifest.Counts["messages"], manifest.Counts["audits"], opts.output)
+	}
+	return nil
+}
+
+func eraseDueTenants(ctx context.Context, svc *tenant.Service, actor tenant.Actor, jsonOutput bool) error {
+	reports, err := svc.EraseDue(ctx, actor)
+	erased := make([]string, 0, len(reports))
+	for _, r := range reports {
+		erased = append(erased, r.TenantID)
+	}
+	announceTenantChanges(ctx, erased...)
+	if jsonOutput {
+		data, _ := json.MarshalIndent(reports, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		for _, r := range reports {
+			fmt.Printf("Erased tenant %s (tombstone %s)\n", r.TenantID, r.TombstoneID)
+		}
+		if len(reports) == 0 && err == nil {
+			fmt.Println("No tenants due for erasure")
+		}
+	}
+	return err
+}
+
+func outputTenantStatus(w io.Writer, t *queries.Tenant, jsonOutput bool) error {
+	result := TenantStatusResult{
+		TenantID: formatUUID(t.ID),
+		Name:     t.Name,
+		Status:   t.Status,
+		Reason:   t.StatusReason.String,
+	}
+	if t.DeletionScheduledAt.Valid {
+		result.DeletionScheduledAt = t.DeletionScheduledAt.Time.UTC().Format(time.RFC3339)
+	}
+	if t.ErasedAt.Valid {
+		result.ErasedAt = t.ErasedAt.Time.UTC().Format(time.RFC3339)
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	fmt.Fprintf(w, "Tenant:  %s (%s)\n", result.TenantID, result.Name)
+	fmt.Fprintf(w, "Status:  %s\n", result.Status)
+	if result.Reason != "" {
+		fmt.Fprintf(w, "Reason:  %s\n", result.Reason)
+	}
+	if result.DeletionScheduledAt != "" {
+		fmt.Fprintf(w, "Erasure: %s\n", result.DeletionScheduledAt)
+	}
+	if result.ErasedAt != "" {
+		fmt.Fprintf(w, "Erased:  %s\n", result.ErasedAt)
+	}
+	return nil
+}
+
+func formatUUID(id pgtype.UUID) string {
+	b := id.Bytes
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
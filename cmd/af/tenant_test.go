@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/agentflow/agentflow/internal/storage/tenant"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTenantID = "12345678-1234-1234-1234-123456789abc"
+
+func TestTenantCmdValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{"No subcommand", []string{}, "tenant command requires a subcommand: status, suspend, resume, delete, cancel-delete, export, erase-due"},
+		{"Invalid subcommand", []string{"archive"}, "unknown tenant subcommand: archive"},
+		{"Missing tenant ID", []string{"suspend"}, "tenant ID is required"},
+		{"Invalid tenant ID", []string{"status", "acme"}, "invalid tenant ID format: acme"},
+		{"Unknown flag", []string{"delete", testTenantID, "--now"}, "unknown tenant flag: --now"},
+		{"Invalid grace", []string{"delete", testTenantID, "--grace=30d"}, "invalid grace period: 30d"},
+		{"Negative grace", []string{"delete", testTenantID, "--grace=-1h"}, "invalid grace period: -1h"},
+		{"Tenant ID on erase-due", []string{"erase-due", testTenantID}, "unexpected argument: " + testTenantID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tenantCmd(tt.args)
+			require.Error(t, err)
+			assert.Equal(t, tt.errorMsg, err.Error())
+		})
+	}
+}
+
+func TestParseTenantArgs(t *testing.T) {
+	opts, err := parseTenantArgs([]string{testTenantID, "--grace=48h", "--reason=customer request", "--json"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, testTenantID, formatUUID(opts.tenantID))
+	assert.Equal(t, 48*time.Hour, opts.grace)
+	assert.Equal(t, "customer request", opts.reason)
+	assert.True(t, opts.jsonOutput)
+
+	opts, err = parseTenantArgs([]string{testTenantID}, true)
+	require.NoError(t, err)
+	assert.Equal(t, tenant.DefaultGracePeriod, opts.grace)
+}
+
+func TestOutputTenantStatus(t *testing.T) {
+	var id pgtype.UUID
+	require.NoError(t, id.Scan(testTenantID))
+	tn := &queries.Tenant{
+		ID:                  id,
+		Name:                "acme",
+		Status:              tenant.StatusPendingDeletion,
+		StatusReason:        pgtype.Text{String: "customer request", Valid: true},
+		DeletionScheduledAt: pgtype.Timestamptz{Time: time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC), Valid: true},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, outputTenantStatus(&buf, tn, true))
+	var result TenantStatusResult
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+	assert.Equal(t, TenantStatusResult{
+		TenantID:            testTenantID,
+		Name:                "acme",
+		Status:              "pending_deletion",
+		Reason:              "customer request",
+		DeletionScheduledAt: "2025-09-30T00:00:00Z",
+	}, result)
+
+	buf.Reset()
+	require.NoError(t, outputTenantStatus(&buf, tn, false))
+	assert.Contains(t, buf.String(), "Status:  pending_deletion")
+	assert.Contains(t, buf.String(), "Erasure: 2025-09-30T00:00:00Z")
+}
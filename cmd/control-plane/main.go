@@ -1,10 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/quota"
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/security/secrets"
 	"github.com/agentflow/agentflow/internal/server"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/dbpool"
+	"github.com/agentflow/agentflow/internal/storage/migrate"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/agentflow/agentflow/pkg/messaging"
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
 )
 
 // Progress: IN PROGRESS - Task 1: HTTP Server & Routing + Middleware Stack
@@ -19,6 +34,12 @@ func main() {
 	// Load server configuration from environment
 	config := server.LoadFromEnv()
 
+	// Verify the database schema before serving traffic
+	if err := checkSchema(config, logger); err != nil {
+		logger.Error("Database schema check failed", err)
+		os.Exit(1)
+	}
+
 	// Create and configure server
 	srv, err := server.New(config, logger)
 	if err != nil {
@@ -26,12 +47,259 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The database holds the audit trail, refresh tokens and RBAC roles;
+	// without one audit events are only logged, refresh tokens kept in memory
+	// and permissions taken from tokens
+	cluster, err := openDatabase(config, logger)
+	if err != nil {
+		logger.Error("Failed to connect to database", err)
+		os.Exit(1)
+	}
+	closeDB := func() {}
+	var tenants *security.TenantCache
+	if cluster != nil {
+		// Record API, auth and tenant isolation events in the audit trail and
+		// serve it under /api/v1/audits
+		var emitter *audit.Emitter
+		emitter, closeDB = openAuditTrail(cluster, config, logger)
+		srv.SetAuditRecorder(emitter)
+		srv.SetAuditReader(queries.New(cluster.Reader()))
+
+		// Rotation must see every use of a refresh token, so it runs on the primary
+		srv.SetRefreshTokenStore(security.NewDBRefreshTokenStore(queries.New(cluster.Primary())))
+
+		// Permissions come from rbac_roles and rbac_bindings rather than the
+		// token, so role changes apply to the next request
+		authorizer := security.NewRBACAuthorizer(queries.New(cluster.Primary()))
+		srv.SetAuthorizer(authorizer)
+
+		// Users sign in with the password in users.hashed_secret, and their
+		// tokens carry the roles bound to them
+		srv.SetLogin(security.NewLoginService(queries.New(cluster.Primary()), authorizer, nil))
+
+		// Machine clients authenticate with API keys issued to service accounts
+		srv.SetAPIKeys(security.NewAPIKeyService(queries.New(cluster.Primary())))
+
+		// Requests of unknown or erased tenants are refused, and suspended
+		// tenants and tenants pending deletion are read-only. Tenants are
+		// cached so requests do not each query the database.
+		tenants = security.NewTenantCache(queries.New(cluster.Reader()), security.DefaultTenantCacheTTL)
+		srv.SetTenants(tenants)
+
+		// Each tenant's request rate is limited by the tier and
+		// settings.resource_limits of its cached tenant, and its usage
+		// served at /api/v1/usage
+		srv.SetQuotas(quota.NewEnforcer(queries.New(cluster.Reader())))
+	}
+
+	// Replicas tell each other about revocations and tenant changes over the bus
+	busConn, err := connectBus(config)
+	if err != nil {
+		logger.Error("Failed to connect to the bus", err)
+		closeDB()
+		os.Exit(1)
+	}
+	closeBus := func() {
+		if busConn != nil {
+			busConn.Close()
+		}
+	}
+
+	// Tenants whose status, tier or settings change announce it on the bus,
+	// so their cached status and limits are read again
+	if tenants != nil && busConn != nil {
+		changes := security.NewNATSTenantInvalidationBus(busConn, security.DefaultTenantInvalidationSubject)
+		if _, err := tenants.Listen(changes); err != nil {
+			logger.Error("Failed to subscribe to tenant changes", err)
+			closeBus()
+			closeDB()
+			os.Exit(1)
+		}
+	} else if tenants != nil {
+		logger.Warn("No bus configured, tenant changes reach this replica once its cached tenants expire",
+			logging.String("cache_ttl", security.DefaultTenantCacheTTL.String()))
+	}
+
+	// Revoked tokens must be rejected by every replica
+	revocations, closeRevocations, err := openRevocationStore(cluster, config, busConn, logger)
+	if err != nil {
+		logger.Error("Failed to set up token revocation store", err)
+		closeBus()
+		closeDB()
+		os.Exit(1)
+	}
+	if revocations != nil {
+		srv.SetRevocationStore(revocations)
+	}
+
+	// Sign tokens with the rotating keys published at /.well-known/jwks.json
+	signingKeys, err := openSigningKeys(logger)
+	if err != nil {
+		logger.Error("Failed to load token signing keys", err)
+		closeRevocations()
+		closeBus()
+		closeDB()
+		os.Exit(1)
+	}
+	if signingKeys != nil {
+		srv.SetSigningKeys(signingKeys)
+	}
+
 	// Start server with graceful shutdown
 	logger.Info("Starting AgentFlow Control Plane API server")
-	if err := srv.StartWithGracefulShutdown(); err != nil {
+	err = srv.StartWithGracefulShutdown()
+	if signingKeys != nil {
+		_ = signingKeys.Close()
+	}
+	closeRevocations()
+	closeBus()
+	closeDB()
+	if err != nil {
 		logger.Error("Server error", err)
 		os.Exit(1)
 	}
 
 	logger.Info("AgentFlow Control Plane API server stopped")
 }
+
+// checkSchema compares the database schema with the embedded migrations.
+// It is skipped when no database is configured or the check is off.
+func checkSchema(config *server.Config, logger logging.Logger) error {
+	if config.DatabaseURL == "" || config.SchemaCheck == migrate.CheckOff {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, config.DatabaseURL)
+	if err != nil {
+		if config.SchemaCheck == migrate.CheckEnforce {
+			return err
+		}
+		logger.Warn("Skipping schema check, database unavailable", logging.String("error", err.Error()))
+		return nil
+	}
+	defer conn.Close(context.Background())
+
+	_, err = migrate.CheckSchema(ctx, conn, logger, config.SchemaCheck)
+	return err
+}
+
+// openDatabase connects to the configured database, or returns nil when no
+// database is configured
+func openDatabase(config *server.Config, logger logging.Logger) (*dbpool.Cluster, error) {
+	if config.DatabaseURL == "" {
+		logger.Warn("No database configured, audit events are only logged and refresh tokens kept in memory")
+		return nil, nil
+	}
+
+	poolConfig := dbpool.LoadFromEnv()
+	poolConfig.PrimaryURL = config.DatabaseURL
+	return dbpool.Open(context.Background(), poolConfig, logger)
+}
+
+// openAuditTrail creates the emitter audit events are written with. The
+// returned func flushes buffered events and closes the pools.
+func openAuditTrail(cluster *dbpool.Cluster, config *server.Config, logger logging.Logger) (*audit.Emitter, func()) {
+	emitter := audit.NewEmitter(audit.NewServiceWithDB(cluster.Primary()), logger, audit.DefaultEmitterConfig())
+	return emitter, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+		if err := emitter.Close(ctx); err != nil {
+			logger.Error("Failed to flush audit events", err)
+		}
+		cluster.Close()
+	}
+}
+
+// connectBus connects to the NATS server at AF_BUS_URL, or returns nil when
+// no bus is configured
+func connectBus(config *server.Config) (*nats.Conn, error) {
+	if config.BusURL == "" {
+		return nil, nil
+	}
+	busConfig, err := messaging.LoadBusConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := busConfig.NATSOptions()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := nats.Connect(config.BusURL, append(opts, nats.Timeout(5*time.Second))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return conn, nil
+}
+
+// openRevocationStore keeps token revocations in Redis when configured, or
+// else in the database, behind a local cache that replicas keep current over
+// the bus. It returns nil when neither store is available, leaving
+// revocations in memory.
+func openRevocationStore(cluster *dbpool.Cluster, config *server.Config, conn *nats.Conn, logger logging.Logger) (security.RevocationStore, func(), error) {
+	var store security.RevocationStore
+	closeStore := func() {}
+	switch {
+	case config.RevocationRedis != "":
+		opts, err := redis.ParseURL(config.RevocationRedis)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid AF_REVOCATION_REDIS_URL: %w", err)
+		}
+		client := redis.NewClient(opts)
+		store = security.NewRedisRevocationStore(client, security.DefaultRevocationKeyPrefix)
+		closeStore = func() { _ = client.Close() }
+	case cluster != nil:
+		// A revocation must be seen by the next lookup, so it runs on the primary
+		store = security.NewDBRevocationStore(queries.New(cluster.Primary()))
+	default:
+		logger.Warn("No database or Redis configured, token revocations are kept in memory")
+		return nil, func() {}, nil
+	}
+
+	cached := security.NewCachedRevocationStore(store, security.DefaultRevocationCacheSize, security.DefaultRevocationCacheTTL)
+	if conn == nil {
+		logger.Warn("No bus configured, revocations reach other replicas once their cached lookups expire",
+			logging.String("cache_ttl", security.DefaultRevocationCacheTTL.String()))
+		return cached, closeStore, nil
+	}
+
+	stop, err := cached.Listen(security.NewNATSRevocationBus(conn, security.DefaultRevocationSubject))
+	if err != nil {
+		closeStore()
+		return nil, nil, err
+	}
+	return cached, func() {
+		_ = stop()
+		closeStore()
+	}, nil
+}
+
+// openSigningKeys loads the token signing keys from the secrets provider and
+// reloads them periodically to pick up rotations. It returns nil when no keys
+// have been created, leaving tokens signed with AF_JWT_SECRET.
+func openSigningKeys(logger logging.Logger) (*security.SigningKeyRing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	provider, err := secrets.NewProviderFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open secrets provider: %w", err)
+	}
+	ring, err := security.NewSigningKeyRing(ctx, provider, security.DefaultSigningKeysSecret)
+	if errors.Is(err, secrets.ErrSecretNotFound) {
+		logger.Warn("No token signing keys found, signing tokens with HS256; run 'af auth rotate-signing-key' to create one")
+		if os.Getenv("AF_JWT_SECRET") == "" {
+			logger.Warn("AF_JWT_SECRET is not set, tokens are signed with a generated secret and will not survive a restart")
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ring.Start(security.DefaultSigningKeyReload)
+	logger.Info("Signing tokens with key ring", logging.String("kid", ring.Active().ID))
+	return ring, nil
+}
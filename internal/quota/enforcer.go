@@ -0,0 +1,179 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/agentflow/agentflow/pkg/messaging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrTenantNotFound is returned for tenants that do not exist
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// Querier defines the queries used by the Enforcer
+type Querier interface {
+	GetTenant(ctx context.Context, id pgtype.UUID) (queries.Tenant, error)
+	CountAgentsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	CountWorkflowsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+}
+
+// Usage is a tenant's limits with what it currently uses. Requests,
+// messages and executions are counted by the replica that answers.
+type Usage struct {
+	TenantID string         `json:"tenant_id"`
+	Tier     string         `json:"tier"`
+	Limits   Limits         `json:"limits"`
+	Used     map[string]int `json:"used"`
+}
+
+// Enforcer enforces the limits of every tenant. Request and message rates
+// and running executions are counted in memory; agents and workflows are
+// counted in the database.
+type Enforcer struct {
+	q          Querier
+	requests   *RateCounter
+	messages   *RateCounter
+	executions *ExecutionCounter
+}
+
+// NewEnforcer creates an enforcer that reads tenants through q. Tenants are
+// read on every call, so callers on hot paths should pass a q whose
+// GetTenant is cached, such as a security.TenantCache.
+func NewEnforcer(q Querier) *Enforcer {
+	return &Enforcer{
+		q:          q,
+		requests:   NewRateCounter(),
+		messages:   NewRateCounter(),
+		executions: NewExecutionCounter(),
+	}
+}
+
+// Limits returns the limits of a tenant, read from its tier and settings
+func (e *Enforcer) Limits(ctx context.Context, tenantID string) (Limits, error) {
+	_, limits, err := e.tenantLimits(ctx, tenantID)
+	return limits, err
+}
+
+func (e *Enforcer) tenantLimits(ctx context.Context, tenantID string) (string, Limits, error) {
+	id, err := parseTenantID(tenantID)
+	if err != nil {
+		return "", Limits{}, err
+	}
+	tenant, err := e.q.GetTenant(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", Limits{}, ErrTenantNotFound
+	}
+	if err != nil {
+		return "", Limits{}, fmt.Errorf("failed to load tenant: %w", err)
+	}
+	// Broken settings fall back to the tier's limits rather than none
+	limits, _ := LimitsFromSettings(tenant.Tier, tenant.Settings)
+	return tenant.Tier, limits, nil
+}
+
+// AllowRequest counts an API request against the tenant's requests per
+// minute. It returns a *QuotaError once the limit is reached.
+func (e *Enforcer) AllowRequest(tenantID string, limits Limits) error {
+	allowed, used, retryAfter := e.requests.Allow(tenantID, limits.RequestsPerMinute)
+	if !allowed {
+		return &QuotaError{TenantID: tenantID, Resource: ResourceRequestsPerMinute, Limit: limits.RequestsPerMinute, Used: used, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// AllowMessage counts a bus message against the tenant's messages per
+// minute. It returns a *QuotaError once the limit is reached.
+func (e *Enforcer) AllowMessage(ctx context.Context, tenantID string) error {
+	limits, err := e.Limits(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	allowed, used, retryAfter := e.messages.Allow(tenantID, limits.MessagesPerMinute)
+	if !allowed {
+		return &QuotaError{TenantID: tenantID, Resource: ResourceMessagesPerMinute, Limit: limits.MessagesPerMinute, Used: used, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// AuthorizeMessage limits the message rate of the tenant a message belongs
+// to, so an Enforcer can guard a bus wrapped with messaging.NewAuthorizedBus.
+// The tenant is taken from a tenant-scoped subject, or else from the
+// tenant_id metadata of the message. Messages of no tenant are not limited.
+func (e *Enforcer) AuthorizeMessage(ctx context.Context, subject string, msg *messaging.Message) error {
+	tenantID, err := messaging.NewTenantSubjectBuilder().ExtractTenantFromSubject(subject)
+	if err != nil {
+		tenantID, _ = msg.Metadata["tenant_id"].(string)
+	}
+	if tenantID == "" {
+		return nil
+	}
+	return e.AllowMessage(ctx, tenantID)
+}
+
+// StartExecution counts a workflow execution against the tenant's
+// concurrent executions. The returned function must be called when the
+// execution ends. It returns a *QuotaError once the limit is reached.
+func (e *Enforcer) StartExecution(ctx context.Context, tenantID string) (func(), error) {
+	limits, err := e.Limits(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	release, running, ok := e.executions.Acquire(tenantID, limits.MaxConcurrentExecutions)
+	if !ok {
+		return nil, &QuotaError{TenantID: tenantID, Resource: ResourceConcurrentExecutions, Limit: limits.MaxConcurrentExecutions, Used: running}
+	}
+	return release, nil
+}
+
+// Usage returns the limits of a tenant and what it uses of each
+func (e *Enforcer) Usage(ctx context.Context, tenantID string) (*Usage, error) {
+	tier, limits, err := e.tenantLimits(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := parseTenantID(tenantID)
+	agents, err := e.q.CountAgentsByTenant(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count agents: %w", err)
+	}
+	workflows, err := e.q.CountWorkflowsByTenant(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count workflows: %w", err)
+	}
+	return &Usage{
+		TenantID: tenantID,
+		Tier:     tier,
+		Limits:   limits,
+		Used: map[string]int{
+			ResourceAgents:               int(agents),
+			ResourceWorkflows:            int(workflows),
+			ResourceConcurrentExecutions: e.executions.Running(tenantID),
+			ResourceRequestsPerMinute:    e.requests.Used(tenantID),
+			ResourceMessagesPerMinute:    e.messages.Used(tenantID),
+		},
+	}, nil
+}
+
+// CheckCount returns a *QuotaError if a tenant that has used of resource
+// may not add another under limit
+func CheckCount(tenantID, resource string, limit int, used int64) error {
+	if limit > 0 && used >= int64(limit) {
+		return &QuotaError{TenantID: tenantID, Resource: resource, Limit: limit, Used: int(used)}
+	}
+	return nil
+}
+
+func parseTenantID(tenantID string) (pgtype.UUID, error) {
+	id, err := uuid.Parse(tenantID)
+	if err != nil {
+		return pgtype.UUID{}, ErrTenantNotFound
+	}
+	return pgtype.UUID{Bytes: id, Valid: true}, nil
+}
+
+var _ messaging.MessageAuthorizer = (*Enforcer)(nil)
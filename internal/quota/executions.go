@@ -0,0 +1,47 @@
+package quota
+
+import "sync"
+
+// ExecutionCounter counts the workflow executions each tenant is running.
+// Counts are kept per process.
+type ExecutionCounter struct {
+	mu      sync.Mutex
+	running map[string]int
+}
+
+// NewExecutionCounter creates an empty execution counter
+func NewExecutionCounter() *ExecutionCounter {
+	return &ExecutionCounter{running: make(map[string]int)}
+}
+
+// Acquire counts an execution for tenantID if fewer than limit are running.
+// A limit of 0 or less allows every execution. The returned function ends
+// the execution; calling it more than once has no further effect.
+func (c *ExecutionCounter) Acquire(tenantID string, limit int) (release func(), running int, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	running = c.running[tenantID]
+	if limit > 0 && running >= limit {
+		return nil, running, false
+	}
+	c.running[tenantID] = running + 1
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.running[tenantID]--; c.running[tenantID] <= 0 {
+				delete(c.running, tenantID)
+			}
+		})
+	}, running + 1, true
+}
+
+// Running returns how many executions tenantID is running
+func (c *ExecutionCounter) Running(tenantID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.running[tenantID]
+}
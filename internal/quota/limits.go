@@ -0,0 +1,163 @@
+// Package quota enforces per-tenant resource limits: how many agents and
+// workflows a tenant may keep, how many workflow executions it may run at
+// once, and how many API requests and bus messages it may send per minute.
+// Limits default from the tenant's tier and can be overridden per tenant in
+// settings.resource_limits.
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Tiers stored in tenants.tier
+const (
+	TierFree       = "free"
+	TierStandard   = "standard"
+	TierPremium    = "premium"
+	TierEnterprise = "enterprise"
+)
+
+// Resources limits apply to, also the keys of settings.resource_limits
+const (
+	ResourceAgents               = "max_agents"
+	ResourceWorkflows            = "max_workflows"
+	ResourceConcurrentExecutions = "max_concurrent_executions"
+	ResourceRequestsPerMinute    = "requests_per_minute"
+	ResourceMessagesPerMinute    = "messages_per_minute"
+)
+
+// ErrQuotaExceeded is matched by every QuotaError
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Limits are the resource limits of a tenant. Zero means unlimited.
+type Limits struct {
+	MaxAgents               int `json:"max_agents"`
+	MaxWorkflows            int `json:"max_workflows"`
+	MaxConcurrentExecutions int `json:"max_concurrent_executions"`
+	RequestsPerMinute       int `json:"requests_per_minute"`
+	MessagesPerMinute       int `json:"messages_per_minute"`
+}
+
+// tierLimits are the defaults of each tier. Unknown tiers get the free
+// tier's limits, so a typo never lifts a tenant's limits.
+var tierLimits = map[string]Limits{
+	TierFree: {
+		MaxAgents:               10,
+		MaxWorkflows:            25,
+		MaxConcurrentExecutions: 2,
+		RequestsPerMinute:       120,
+		MessagesPerMinute:       600,
+	},
+	TierStandard: {
+		MaxAgents:               100,
+		MaxWorkflows:            250,
+		MaxConcurrentExecutions: 10,
+		RequestsPerMinute:       1200,
+		MessagesPerMinute:       6000,
+	},
+	TierPremium: {
+		MaxAgents:               1000,
+		MaxWorkflows:            2500,
+		MaxConcurrentExecutions: 50,
+		RequestsPerMinute:       6000,
+		MessagesPerMinute:       60000,
+	},
+	TierEnterprise: {},
+}
+
+// TierLimits returns the default limits of tier
+func TierLimits(tier string) Limits {
+	if limits, ok := tierLimits[tier]; ok {
+		return limits
+	}
+	return tierLimits[TierFree]
+}
+
+// LimitsFor returns the limits of a tenant of tier whose
+// settings.resource_limits are overrides. Keys that are absent keep the
+// tier's default; an override of 0 removes the limit.
+func LimitsFor(tier string, overrides map[string]interface{}) (Limits, error) {
+	limits := TierLimits(tier)
+	fields := map[string]*int{
+		ResourceAgents:               &limits.MaxAgents,
+		ResourceWorkflows:            &limits.MaxWorkflows,
+		ResourceConcurrentExecutions: &limits.MaxConcurrentExecutions,
+		ResourceRequestsPerMinute:    &limits.RequestsPerMinute,
+		ResourceMessagesPerMinute:    &limits.MessagesPerMinute,
+	}
+	for key, value := range overrides {
+		field, ok := fields[key]
+		if !ok {
+			continue // other limits, such as tool sandbox limits, live here too
+		}
+		n, err := limitValue(value)
+		if err != nil {
+			return TierLimits(tier), fmt.Errorf("invalid resource limit %s: %w", key, err)
+		}
+		*field = n
+	}
+	return limits, nil
+}
+
+// LimitsFromSettings returns the limits of a tenant from its tier and the
+// tenants.settings JSON document
+func LimitsFromSettings(tier string, settings []byte) (Limits, error) {
+	var doc struct {
+		ResourceLimits map[string]interface{} `json:"resource_limits"`
+	}
+	if len(settings) > 0 {
+		if err := json.Unmarshal(settings, &doc); err != nil {
+			return TierLimits(tier), fmt.Errorf("invalid tenant settings: %w", err)
+		}
+	}
+	return LimitsFor(tier, doc.ResourceLimits)
+}
+
+// limitValue converts a JSON number to a limit
+func limitValue(value interface{}) (int, error) {
+	var f float64
+	switch v := value.(type) {
+	case float64:
+		f = v
+	case int:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case json.Number:
+		parsed, err := v.Float64()
+		if err != nil {
+			return 0, err
+		}
+		f = parsed
+	default:
+		return 0, fmt.Errorf("want a number, got %T", value)
+	}
+	if f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+		return 0, fmt.Errorf("want a whole number of at least 0, got %v", value)
+	}
+	return int(f), nil
+}
+
+// QuotaError reports a request refused because a tenant reached a limit
+type QuotaError struct {
+	TenantID string
+	Resource string // one of the Resource constants
+	Limit    int
+	Used     int
+	// RetryAfter is when rate limits reset; zero for count limits, which
+	// only free up when resources are removed
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("quota exceeded: tenant %s has used %d of %d %s", e.TenantID, e.Used, e.Limit, e.Resource)
+}
+
+// Is makes errors.Is(err, ErrQuotaExceeded) match
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
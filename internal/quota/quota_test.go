@@ -0,0 +1,200 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/agentflow/agentflow/pkg/messaging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTenant = "00000000-0000-0000-0000-000000000001"
+
+// fakeQuerier serves tenants and resource counts from memory
+type fakeQuerier struct {
+	tenants   map[string]queries.Tenant
+	agents    int64
+	workflows int64
+	reads     int
+}
+
+func (q *fakeQuerier) GetTenant(ctx context.Context, id pgtype.UUID) (queries.Tenant, error) {
+	q.reads++
+	tenant, ok := q.tenants[uuid.UUID(id.Bytes).String()]
+	if !ok {
+		return queries.Tenant{}, pgx.ErrNoRows
+	}
+	return tenant, nil
+}
+
+func (q *fakeQuerier) CountAgentsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return q.agents, nil
+}
+
+func (q *fakeQuerier) CountWorkflowsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return q.workflows, nil
+}
+
+func newTestEnforcer(tier, settings string) (*Enforcer, *fakeQuerier) {
+	q := &fakeQuerier{tenants: map[string]queries.Tenant{
+		testTenant: {Tier: tier, Settings: []byte(settings)},
+	}}
+	return NewEnforcer(q), q
+}
+
+func TestLimitsFromSettings(t *testing.T) {
+	limits, err := LimitsFromSettings(TierStandard, []byte(`{"resource_limits":{"max_agents":5,"requests_per_minute":0,"sandbox_memory_mb":256}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 5, limits.MaxAgents)
+	assert.Equal(t, 0, limits.RequestsPerMinute)
+	assert.Equal(t, TierLimits(TierStandard).MaxWorkflows, limits.MaxWorkflows)
+
+	// Unknown tiers get the free tier's limits, enterprise none
+	assert.Equal(t, TierLimits(TierFree), TierLimits("platinum"))
+	assert.Equal(t, Limits{}, TierLimits(TierEnterprise))
+
+	for _, settings := range []string{
+		`{"resource_limits":{"max_agents":-1}}`,
+		`{"resource_limits":{"max_agents":1.5}}`,
+		`{"resource_limits":{"max_agents":"ten"}}`,
+		`not json`,
+	} {
+		limits, err := LimitsFromSettings(TierPremium, []byte(settings))
+		assert.Error(t, err, settings)
+		assert.Equal(t, TierLimits(TierPremium), limits, settings)
+	}
+}
+
+func TestRateCounter(t *testing.T) {
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	c := NewRateCounter()
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		allowed, _, _ := c.Allow("t1", 3)
+		require.True(t, allowed)
+	}
+	allowed, used, retryAfter := c.Allow("t1", 3)
+	assert.False(t, allowed)
+	assert.Equal(t, 3, used)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	// Tenants are counted apart, and a limit of 0 allows everything
+	allowed, _, _ = c.Allow("t2", 3)
+	assert.True(t, allowed)
+	allowed, _, _ = c.Allow("t1", 0)
+	assert.True(t, allowed)
+
+	// Half way into the next minute, half of the previous minute still counts
+	now = now.Add(90 * time.Second)
+	assert.Equal(t, 2, c.Used("t1"))
+	allowed, _, _ = c.Allow("t1", 3)
+	assert.True(t, allowed)
+	allowed, _, retryAfter = c.Allow("t1", 3)
+	assert.False(t, allowed)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	// Two minutes later nothing counts any more
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, 0, c.Used("t1"))
+}
+
+func TestExecutionCounter(t *testing.T) {
+	c := NewExecutionCounter()
+	release, running, ok := c.Acquire("t1", 2)
+	require.True(t, ok)
+	assert.Equal(t, 1, running)
+	_, _, ok = c.Acquire("t1", 2)
+	require.True(t, ok)
+	_, running, ok = c.Acquire("t1", 2)
+	assert.False(t, ok)
+	assert.Equal(t, 2, running)
+
+	release()
+	release()
+	assert.Equal(t, 1, c.Running("t1"))
+	_, _, ok = c.Acquire("t1", 2)
+	assert.True(t, ok)
+}
+
+func TestEnforcerLimits(t *testing.T) {
+	e, q := newTestEnforcer(TierFree, `{"resource_limits":{"requests_per_minute":2}}`)
+
+	limits, err := e.Limits(context.Background(), testTenant)
+	require.NoError(t, err)
+	assert.Equal(t, 2, limits.RequestsPerMinute)
+
+	// Limits are read again every time, so changes apply at once
+	q.tenants[testTenant] = queries.Tenant{Tier: TierFree, Settings: []byte(`{"resource_limits":{"requests_per_minute":5}}`)}
+	limits, err = e.Limits(context.Background(), testTenant)
+	require.NoError(t, err)
+	assert.Equal(t, 5, limits.RequestsPerMinute)
+	assert.Equal(t, 2, q.reads)
+
+	_, err = e.Limits(context.Background(), "00000000-0000-0000-0000-000000000002")
+	assert.ErrorIs(t, err, ErrTenantNotFound)
+	_, err = e.Limits(context.Background(), "not-a-uuid")
+	assert.ErrorIs(t, err, ErrTenantNotFound)
+}
+
+func TestEnforcerRequestsAndMessages(t *testing.T) {
+	e, _ := newTestEnforcer(TierFree, `{"resource_limits":{"requests_per_minute":1,"messages_per_minute":1}}`)
+	ctx := context.Background()
+
+	limits, err := e.Limits(ctx, testTenant)
+	require.NoError(t, err)
+	require.NoError(t, e.AllowRequest(testTenant, limits))
+	err = e.AllowRequest(testTenant, limits)
+	var quotaErr *QuotaError
+	require.True(t, errors.As(err, &quotaErr))
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, ResourceRequestsPerMinute, quotaErr.Resource)
+	assert.Positive(t, quotaErr.RetryAfter)
+
+	subject := messaging.NewTenantSubjectBuilder().TenantWorkflowIn(testTenant, "wf-1")
+	require.NoError(t, e.AuthorizeMessage(ctx, subject, &messaging.Message{}))
+	err = e.AuthorizeMessage(ctx, subject, &messaging.Message{})
+	require.True(t, errors.As(err, &quotaErr))
+	assert.Equal(t, ResourceMessagesPerMinute, quotaErr.Resource)
+
+	// Messages of no tenant are not limited
+	assert.NoError(t, e.AuthorizeMessage(ctx, "workflows.wf-1.in", &messaging.Message{}))
+}
+
+func TestEnforcerExecutionsAndUsage(t *testing.T) {
+	e, q := newTestEnforcer(TierStandard, `{"resource_limits":{"max_concurrent_executions":1}}`)
+	q.agents, q.workflows = 4, 7
+	ctx := context.Background()
+
+	release, err := e.StartExecution(ctx, testTenant)
+	require.NoError(t, err)
+	_, err = e.StartExecution(ctx, testTenant)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	usage, err := e.Usage(ctx, testTenant)
+	require.NoError(t, err)
+	assert.Equal(t, TierStandard, usage.Tier)
+	assert.Equal(t, 1, usage.Limits.MaxConcurrentExecutions)
+	assert.Equal(t, 4, usage.Used[ResourceAgents])
+	assert.Equal(t, 7, usage.Used[ResourceWorkflows])
+	assert.Equal(t, 1, usage.Used[ResourceConcurrentExecutions])
+
+	release()
+	_, err = e.StartExecution(ctx, testTenant)
+	assert.NoError(t, err)
+}
+
+func TestCheckCount(t *testing.T) {
+	assert.NoError(t, CheckCount(testTenant, ResourceAgents, 0, 100))
+	assert.NoError(t, CheckCount(testTenant, ResourceAgents, 10, 9))
+	err := CheckCount(testTenant, ResourceAgents, 10, 10)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Contains(t, err.Error(), "10 of 10 max_agents")
+}
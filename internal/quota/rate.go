@@ -0,0 +1,113 @@
+package quota
+
+import (
+	"sync"
+	"time"
+)
+
+// rateWindow is the length of the windows rates are counted in
+const rateWindow = time.Minute
+
+// window counts events of one tenant in the current and previous minute
+type window struct {
+	start    time.Time
+	current  int
+	previous int
+}
+
+// RateCounter counts events per tenant over a sliding one-minute window.
+// The previous minute's count is weighted by how much of it the window
+// still covers, which smooths the burst a fixed window allows at its edge.
+// Counts are kept per process.
+type RateCounter struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastPrune time.Time
+}
+
+// NewRateCounter creates an empty rate counter
+func NewRateCounter() *RateCounter {
+	return &RateCounter{now: time.Now, windows: make(map[string]*window)}
+}
+
+// Allow counts an event for tenantID if fewer than limit events were counted
+// in the last minute. A limit of 0 or less allows every event. When the
+// event is refused, it returns how many were counted and how long until one
+// would be allowed.
+func (c *RateCounter) Allow(tenantID string, limit int) (allowed bool, used int, retryAfter time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.prune(now)
+	w := c.window(tenantID, now)
+	used = w.estimate(now)
+	if limit > 0 && used >= limit {
+		return false, used, w.start.Add(rateWindow).Sub(now)
+	}
+	w.current++
+	return true, used + 1, 0
+}
+
+// Used returns how many events were counted for tenantID in the last minute
+func (c *RateCounter) Used(tenantID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w, ok := c.windows[tenantID]
+	if !ok {
+		return 0
+	}
+	now := c.now()
+	w.advance(now)
+	return w.estimate(now)
+}
+
+// window returns the window of tenantID, advanced to now; c.mu must be held
+func (c *RateCounter) window(tenantID string, now time.Time) *window {
+	w, ok := c.windows[tenantID]
+	if !ok {
+		w = &window{start: now.Truncate(rateWindow)}
+		c.windows[tenantID] = w
+	}
+	w.advance(now)
+	return w
+}
+
+// prune drops tenants without events in the last two minutes, at most once
+// a minute; c.mu must be held
+func (c *RateCounter) prune(now time.Time) {
+	if now.Sub(c.lastPrune) < rateWindow {
+		return
+	}
+	c.lastPrune = now
+	for tenantID, w := range c.windows {
+		if now.Sub(w.start) >= 2*rateWindow {
+			delete(c.windows, tenantID)
+		}
+	}
+}
+
+// advance moves the window forward so it contains now
+func (w *window) advance(now time.Time) {
+	start := now.Truncate(rateWindow)
+	switch elapsed := start.Sub(w.start); {
+	case elapsed <= 0:
+		return
+	case elapsed == rateWindow:
+		w.previous = w.current
+	default:
+		w.previous = 0
+	}
+	w.current = 0
+	w.start = start
+}
+
+// estimate returns the events in the minute before now
+func (w *window) estimate(now time.Time) int {
+	remaining := rateWindow - now.Sub(w.start)
+	weight := float64(remaining) / float64(rateWindow)
+	return w.current + int(float64(w.previous)*weight)
+}
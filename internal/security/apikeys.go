@@ -0,0 +1,521 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// API key errors
+var (
+	ErrAPIKeyInvalid          = errors.New("invalid API key")
+	ErrAPIKeyNotFound         = errors.New("API key not found")
+	ErrServiceAccountNotFound = errors.New("service account not found")
+	ErrInvalidScope           = errors.New("invalid scope")
+)
+
+const (
+	// APIKeyPrefix starts every API key, so keys are told apart from JWTs
+	// and found by secret scanners
+	APIKeyPrefix = "afk_"
+
+	// APIKeyIssuer is the issuer of the claims an API key authenticates as
+	APIKeyIssuer = "agentflow-api-key"
+
+	// ServiceAccountRole is the role of every service account
+	ServiceAccountRole = "service_account"
+
+	// apiKeyIDBytes is the length of the random key ID that keys are looked
+	// up by, and apiKeySecretBytes that of the secret after it
+	apiKeyIDBytes     = 6
+	apiKeySecretBytes = 32
+
+	// apiKeyTouchInterval limits how often a replica writes last_used_at
+	apiKeyTouchInterval = time.Minute
+)
+
+// ServiceAccount is a tenant-scoped principal for machine clients
+type ServiceAccount struct {
+	ID          string     `json:"id"`
+	TenantID    string     `json:"tenant_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+}
+
+// APIKey is a stored API key. The key itself is only returned when issued.
+type APIKey struct {
+	ID               string     `json:"id"`
+	TenantID         string     `json:"tenant_id"`
+	ServiceAccountID string     `json:"service_account_id"`
+	Prefix           string     `json:"prefix"`
+	Scopes           []string   `json:"scopes"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt       *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyQuerier is the subset of queries.Querier used by the API key service
+type APIKeyQuerier interface {
+	CreateServiceAccount(ctx context.Context, arg queries.CreateServiceAccountParams) (queries.ServiceAccount, error)
+	GetServiceAccount(ctx context.Context, arg queries.GetServiceAccountParams) (queries.ServiceAccount, error)
+	ListServiceAccounts(ctx context.Context, tenantID pgtype.UUID) ([]queries.ServiceAccount, error)
+	DisableServiceAccount(ctx context.Context, arg queries.DisableServiceAccountParams) (queries.ServiceAccount, error)
+	CreateAPIKey(ctx context.Context, arg queries.CreateAPIKeyParams) (queries.ApiKey, error)
+	GetAPIKey(ctx context.Context, arg queries.GetAPIKeyParams) (queries.ApiKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (queries.ApiKey, error)
+	ListAPIKeys(ctx context.Context, arg queries.ListAPIKeysParams) ([]queries.ApiKey, error)
+	SetAPIKeyExpiry(ctx context.Context, arg queries.SetAPIKeyExpiryParams) error
+	RevokeAPIKey(ctx context.Context, arg queries.RevokeAPIKeyParams) (int64, error)
+	TouchAPIKey(ctx context.Context, arg queries.TouchAPIKeyParams) error
+}
+
+// APIKeyService manages service accounts and their API keys, and
+// authenticates requests made with the keys
+type APIKeyService struct {
+	q   APIKeyQuerier
+	now func() time.Time
+
+	mu      sync.Mutex
+	touched map[[16]byte]time.Time
+}
+
+// NewAPIKeyService creates an API key service backed by the service_accounts
+// and api_keys tables
+func NewAPIKeyService(q APIKeyQuerier) *APIKeyService {
+	return &APIKeyService{q: q, now: time.Now, touched: make(map[[16]byte]time.Time)}
+}
+
+// IsAPIKey reports whether a bearer token is an API key rather than a JWT
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// generateAPIKey returns a new key and the prefix it is looked up by. Keys
+// take the form afk_<key ID>_<secret>.
+func generateAPIKey() (key, prefix string, err error) {
+	id := make([]byte, apiKeyIDBytes)
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	prefix = APIKeyPrefix + hex.EncodeToString(id)
+	return prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), prefix, nil
+}
+
+// hashAPIKey returns the hash a key is stored as. Keys carry 256 random
+// bits, so a fast hash is enough.
+func hashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// apiKeyPrefix returns the lookup prefix of a well-formed key
+func apiKeyPrefix(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, APIKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 2*apiKeyIDBytes || secret == "" {
+		return "", false
+	}
+	return APIKeyPrefix + id, true
+}
+
+// ValidateScopes checks that every scope is a permission: "*",
+// "resource:action" or "resource:<id>:action"
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if scope == "*" {
+			continue
+		}
+		parts := strings.Split(scope, ":")
+		if len(parts) < 2 || len(parts) > 3 || strings.ContainsAny(scope, " \t\n") {
+			return fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		for _, part := range parts {
+			if part == "" {
+				return fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+			}
+		}
+	}
+	return nil
+}
+
+// GrantsScope reports whether permissions grant everything scope does, so a
+// caller can only issue keys with permissions it holds itself
+func GrantsScope(permissions []string, scope string) bool {
+	if scope == "*" {
+		return permitted(permissions, "*", "", "*")
+	}
+	parts := strings.Split(scope, ":")
+	switch len(parts) {
+	case 2:
+		return permitted(permissions, parts[0], "", parts[1])
+	case 3:
+		return permitted(permissions, parts[0], parts[1], parts[2])
+	}
+	return false
+}
+
+// CreateServiceAccount creates a service account in a tenant
+func (s *APIKeyService) CreateServiceAccount(ctx context.Context, tenantID, name, description string) (*ServiceAccount, error) {
+	tenant, err := parseUUID(tenantID, "tenant ID")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("service account name is required")
+	}
+	row, err := s.q.CreateServiceAccount(ctx, queries.CreateServiceAccountParams{TenantID: tenant, Name: name, Description: description})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account: %w", err)
+	}
+	return serviceAccountFromRow(row), nil
+}
+
+// ListServiceAccounts lists the service accounts of a tenant
+func (s *APIKeyService) ListServiceAccounts(ctx context.Context, tenantID string) ([]*ServiceAccount, error) {
+	tenant, err := parseUUID(tenantID, "tenant ID")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.q.ListServiceAccounts(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service accounts: %w", err)
+	}
+	accounts := make([]*ServiceAccount, 0, len(rows))
+	for _, row := range rows {
+		accounts = append(accounts, serviceAccountFromRow(row))
+	}
+	return accounts, nil
+}
+
+// DisableServiceAccount disables a service account. Its keys stop working
+// at once.
+func (s *APIKeyService) DisableServiceAccount(ctx context.Context, tenantID, accountID string) (*ServiceAccount, error) {
+	tenant, account, err := parseAccountIDs(tenantID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	row, err := s.q.DisableServiceAccount(ctx, queries.DisableServiceAccountParams{ID: account, TenantID: tenant})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrServiceAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to disable service account: %w", err)
+	}
+	return serviceAccountFromRow(row), nil
+}
+
+// IssueAPIKey creates a key for an enabled service account. The key is only
+// returned here; it is stored as a hash. A ttl of zero issues a key that
+// does not expire.
+func (s *APIKeyService) IssueAPIKey(ctx context.Context, tenantID, accountID string, scopes []string, ttl time.Duration) (string, *APIKey, error) {
+	if err := ValidateScopes(scopes); err != nil {
+		return "", nil, err
+	}
+	if ttl < 0 {
+		return "", nil, errors.New("API key lifetime cannot be negative")
+	}
+	tenant, account, err := parseAccountIDs(tenantID, accountID)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := s.enabledAccount(ctx, tenant, account); err != nil {
+		return "", nil, err
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if ttl > 0 {
+		expiresAt = pgtype.Timestamptz{Time: s.now().Add(ttl), Valid: true}
+	}
+	return s.createKey(ctx, tenant, account, scopes, expiresAt)
+}
+
+func (s *APIKeyService) createKey(ctx context.Context, tenant, account pgtype.UUID, scopes []string, expiresAt pgtype.Timestamptz) (string, *APIKey, error) {
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+	if scopes == nil {
+		scopes = []string{}
+	}
+	row, err := s.q.CreateAPIKey(ctx, queries.CreateAPIKeyParams{
+		TenantID:         tenant,
+		ServiceAccountID: account,
+		Prefix:           prefix,
+		KeyHash:          hashAPIKey(key),
+		Scopes:           scopes,
+		ExpiresAt:        expiresAt,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to store API key: %w", err)
+	}
+	return key, apiKeyFromRow(row), nil
+}
+
+// ListAPIKeys lists the keys of a service account, including revoked and
+// expired ones
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, tenantID, accountID string) ([]*APIKey, error) {
+	tenant, account, err := parseAccountIDs(tenantID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.q.ListAPIKeys(ctx, queries.ListAPIKeysParams{TenantID: tenant, ServiceAccountID: account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	keys := make([]*APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, apiKeyFromRow(row))
+	}
+	return keys, nil
+}
+
+// RotateAPIKey replaces a key with a new one with the same scopes and
+// lifetime. The replaced key keeps working for overlap, so clients can
+// switch without downtime; with no overlap it is revoked at once.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, tenantID, accountID, keyID string, overlap time.Duration) (string, *APIKey, error) {
+	if overlap < 0 {
+		return "", nil, errors.New("rotation overlap cannot be negative")
+	}
+	old, err := s.accountKey(ctx, tenantID, accountID, keyID)
+	if err != nil {
+		return "", nil, err
+	}
+	now := s.now()
+	if old.RevokedAt.Valid || (old.ExpiresAt.Valid && !now.Before(old.ExpiresAt.Time)) {
+		return "", nil, fmt.Errorf("%w: the key is revoked or expired", ErrAPIKeyInvalid)
+	}
+	if _, err := s.enabledAccount(ctx, old.TenantID, old.ServiceAccountID); err != nil {
+		return "", nil, err
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if old.ExpiresAt.Valid {
+		expiresAt = pgtype.Timestamptz{Time: now.Add(old.ExpiresAt.Time.Sub(old.CreatedAt)), Valid: true}
+	}
+	key, replacement, err := s.createKey(ctx, old.TenantID, old.ServiceAccountID, old.Scopes, expiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if overlap == 0 {
+		_, err = s.q.RevokeAPIKey(ctx, queries.RevokeAPIKeyParams{ID: old.ID, TenantID: old.TenantID})
+	} else if retireAt := now.Add(overlap); !old.ExpiresAt.Valid || retireAt.Before(old.ExpiresAt.Time) {
+		err = s.q.SetAPIKeyExpiry(ctx, queries.SetAPIKeyExpiryParams{
+			ID:        old.ID,
+			TenantID:  old.TenantID,
+			ExpiresAt: pgtype.Timestamptz{Time: retireAt, Valid: true},
+		})
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to retire replaced API key: %w", err)
+	}
+	return key, replacement, nil
+}
+
+// RevokeAPIKey revokes a key of a service account
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, tenantID, accountID, keyID string) error {
+	key, err := s.accountKey(ctx, tenantID, accountID, keyID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.q.RevokeAPIKey(ctx, queries.RevokeAPIKeyParams{ID: key.ID, TenantID: key.TenantID}); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return nil
+}
+
+// Authenticate returns the claims of the service account key belongs to, with
+// the key's scopes as permissions. Unknown, revoked and expired keys, and
+// keys of disabled accounts, return ErrAPIKeyInvalid.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*AgentFlowClaims, error) {
+	prefix, ok := apiKeyPrefix(key)
+	if !ok {
+		return nil, ErrAPIKeyInvalid
+	}
+	row, err := s.q.GetAPIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	if subtle.ConstantTimeCompare(hashAPIKey(key), row.KeyHash) != 1 {
+		return nil, ErrAPIKeyInvalid
+	}
+	now := s.now()
+	if row.RevokedAt.Valid || (row.ExpiresAt.Valid && !now.Before(row.ExpiresAt.Time)) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if _, err := s.enabledAccount(ctx, row.TenantID, row.ServiceAccountID); err != nil {
+		if errors.Is(err, ErrServiceAccountNotFound) {
+			return nil, ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+
+	// Last use is tracked to the minute; a failed write must not fail the
+	// request, and the next one retries it
+	if s.shouldTouch(row.ID.Bytes, now) {
+		if err := s.q.TouchAPIKey(ctx, queries.TouchAPIKeyParams{ID: row.ID, LastUsedAt: pgtype.Timestamptz{Time: now, Valid: true}}); err != nil {
+			s.untouch(row.ID.Bytes)
+		}
+	}
+
+	accountID := uuid.UUID(row.ServiceAccountID.Bytes).String()
+	claims := &AgentFlowClaims{
+		TenantID:    uuid.UUID(row.TenantID.Bytes).String(),
+		UserID:      accountID,
+		Roles:       []string{ServiceAccountRole},
+		Permissions: row.Scopes,
+	}
+	claims.ID = uuid.UUID(row.ID.Bytes).String()
+	claims.Issuer = APIKeyIssuer
+	claims.Subject = accountID
+	if row.ExpiresAt.Valid {
+		claims.ExpiresAt = jwt.NewNumericDate(row.ExpiresAt.Time)
+	}
+	return claims, nil
+}
+
+// shouldTouch reports whether last use of a key is due to be written, and
+// notes that it is being written
+func (s *APIKeyService) shouldTouch(id [16]byte, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.touched[id]; ok && now.Sub(last) < apiKeyTouchInterval {
+		return false
+	}
+	// Forget keys not used for a while, so the map does not grow unbounded
+	for k, last := range s.touched {
+		if now.Sub(last) >= apiKeyTouchInterval {
+			delete(s.touched, k)
+		}
+	}
+	s.touched[id] = now
+	return true
+}
+
+func (s *APIKeyService) untouch(id [16]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.touched, id)
+}
+
+// enabledAccount loads a service account that has not been disabled
+func (s *APIKeyService) enabledAccount(ctx context.Context, tenant, account pgtype.UUID) (queries.ServiceAccount, error) {
+	row, err := s.q.GetServiceAccount(ctx, queries.GetServiceAccountParams{ID: account, TenantID: tenant})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return row, ErrServiceAccountNotFound
+	}
+	if err != nil {
+		return row, fmt.Errorf("failed to load service account: %w", err)
+	}
+	if row.DisabledAt.Valid {
+		return row, ErrServiceAccountNotFound
+	}
+	return row, nil
+}
+
+// accountKey loads a key that belongs to the given service account
+func (s *APIKeyService) accountKey(ctx context.Context, tenantID, accountID, keyID string) (queries.ApiKey, error) {
+	tenant, account, err := parseAccountIDs(tenantID, accountID)
+	if err != nil {
+		return queries.ApiKey{}, err
+	}
+	var id pgtype.UUID
+	if id.Scan(keyID) != nil {
+		return queries.ApiKey{}, ErrAPIKeyNotFound
+	}
+	row, err := s.q.GetAPIKey(ctx, queries.GetAPIKeyParams{ID: id, TenantID: tenant})
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && row.ServiceAccountID != account) {
+		return queries.ApiKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return queries.ApiKey{}, fmt.Errorf("failed to load API key: %w", err)
+	}
+	return row, nil
+}
+
+func parseUUID(s, what string) (pgtype.UUID, error) {
+	var id pgtype.UUID
+	if err := id.Scan(s); err != nil {
+		return id, fmt.Errorf("invalid %s: %w", what, err)
+	}
+	return id, nil
+}
+
+// parseAccountIDs parses a tenant ID and the ID of one of its service
+// accounts; an account ID that is not a UUID names no account
+func parseAccountIDs(tenantID, accountID string) (pgtype.UUID, pgtype.UUID, error) {
+	tenant, err := parseUUID(tenantID, "tenant ID")
+	if err != nil {
+		return tenant, pgtype.UUID{}, err
+	}
+	var account pgtype.UUID
+	if account.Scan(accountID) != nil {
+		return tenant, account, ErrServiceAccountNotFound
+	}
+	return tenant, account, nil
+}
+
+func serviceAccountFromRow(row queries.ServiceAccount) *ServiceAccount {
+	return &ServiceAccount{
+		ID:          uuid.UUID(row.ID.Bytes).String(),
+		TenantID:    uuid.UUID(row.TenantID.Bytes).String(),
+		Name:        row.Name,
+		Description: row.Description,
+		CreatedAt:   row.CreatedAt,
+		DisabledAt:  optionalTime(row.DisabledAt),
+	}
+}
+
+func apiKeyFromRow(row queries.ApiKey) *APIKey {
+	scopes := row.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return &APIKey{
+		ID:               uuid.UUID(row.ID.Bytes).String(),
+		TenantID:         uuid.UUID(row.TenantID.Bytes).String(),
+		ServiceAccountID: uuid.UUID(row.ServiceAccountID.Bytes).String(),
+		Prefix:           row.Prefix,
+		Scopes:           scopes,
+		CreatedAt:        row.CreatedAt,
+		ExpiresAt:        optionalTime(row.ExpiresAt),
+		LastUsedAt:       optionalTime(row.LastUsedAt),
+		RevokedAt:        optionalTime(row.RevokedAt),
+	}
+}
+
+func optionalTime(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
@@ -0,0 +1,349 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiKeyQueries keeps service_accounts and api_keys rows in memory
+type apiKeyQueries struct {
+	accounts []queries.ServiceAccount
+	keys     []queries.ApiKey
+	touches  int
+	err      error
+}
+
+func newUUID() pgtype.UUID {
+	return pgtype.UUID{Bytes: uuid.New(), Valid: true}
+}
+
+func (q *apiKeyQueries) CreateServiceAccount(ctx context.Context, arg queries.CreateServiceAccountParams) (queries.ServiceAccount, error) {
+	row := queries.ServiceAccount{ID: newUUID(), TenantID: arg.TenantID, Name: arg.Name, Description: arg.Description, CreatedAt: time.Now()}
+	q.accounts = append(q.accounts, row)
+	return row, nil
+}
+
+func (q *apiKeyQueries) GetServiceAccount(ctx context.Context, arg queries.GetServiceAccountParams) (queries.ServiceAccount, error) {
+	if q.err != nil {
+		return queries.ServiceAccount{}, q.err
+	}
+	for _, a := range q.accounts {
+		if a.ID == arg.ID && a.TenantID == arg.TenantID {
+			return a, nil
+		}
+	}
+	return queries.ServiceAccount{}, pgx.ErrNoRows
+}
+
+func (q *apiKeyQueries) ListServiceAccounts(ctx context.Context, tenantID pgtype.UUID) ([]queries.ServiceAccount, error) {
+	var rows []queries.ServiceAccount
+	for _, a := range q.accounts {
+		if a.TenantID == tenantID {
+			rows = append(rows, a)
+		}
+	}
+	return rows, nil
+}
+
+func (q *apiKeyQueries) DisableServiceAccount(ctx context.Context, arg queries.DisableServiceAccountParams) (queries.ServiceAccount, error) {
+	for i, a := range q.accounts {
+		if a.ID == arg.ID && a.TenantID == arg.TenantID {
+			q.accounts[i].DisabledAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			return q.accounts[i], nil
+		}
+	}
+	return queries.ServiceAccount{}, pgx.ErrNoRows
+}
+
+func (q *apiKeyQueries) CreateAPIKey(ctx context.Context, arg queries.CreateAPIKeyParams) (queries.ApiKey, error) {
+	row := queries.ApiKey{
+		ID:               newUUID(),
+		TenantID:         arg.TenantID,
+		ServiceAccountID: arg.ServiceAccountID,
+		Prefix:           arg.Prefix,
+		KeyHash:          arg.KeyHash,
+		Scopes:           arg.Scopes,
+		ExpiresAt:        arg.ExpiresAt,
+		CreatedAt:        time.Now(),
+	}
+	q.keys = append(q.keys, row)
+	return row, nil
+}
+
+func (q *apiKeyQueries) key(id pgtype.UUID) *queries.ApiKey {
+	for i := range q.keys {
+		if q.keys[i].ID == id {
+			return &q.keys[i]
+		}
+	}
+	return nil
+}
+
+func (q *apiKeyQueries) GetAPIKey(ctx context.Context, arg queries.GetAPIKeyParams) (queries.ApiKey, error) {
+	if k := q.key(arg.ID); k != nil && k.TenantID == arg.TenantID {
+		return *k, nil
+	}
+	return queries.ApiKey{}, pgx.ErrNoRows
+}
+
+func (q *apiKeyQueries) GetAPIKeyByPrefix(ctx context.Context, prefix string) (queries.ApiKey, error) {
+	if q.err != nil {
+		return queries.ApiKey{}, q.err
+	}
+	for _, k := range q.keys {
+		if k.Prefix == prefix {
+			return k, nil
+		}
+	}
+	return queries.ApiKey{}, pgx.ErrNoRows
+}
+
+func (q *apiKeyQueries) ListAPIKeys(ctx context.Context, arg queries.ListAPIKeysParams) ([]queries.ApiKey, error) {
+	var rows []queries.ApiKey
+	for _, k := range q.keys {
+		if k.TenantID == arg.TenantID && k.ServiceAccountID == arg.ServiceAccountID {
+			rows = append(rows, k)
+		}
+	}
+	return rows, nil
+}
+
+func (q *apiKeyQueries) SetAPIKeyExpiry(ctx context.Context, arg queries.SetAPIKeyExpiryParams) error {
+	if k := q.key(arg.ID); k != nil {
+		k.ExpiresAt = arg.ExpiresAt
+	}
+	return nil
+}
+
+func (q *apiKeyQueries) RevokeAPIKey(ctx context.Context, arg queries.RevokeAPIKeyParams) (int64, error) {
+	if k := q.key(arg.ID); k != nil && !k.RevokedAt.Valid {
+		k.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (q *apiKeyQueries) TouchAPIKey(ctx context.Context, arg queries.TouchAPIKeyParams) error {
+	q.touches++
+	if k := q.key(arg.ID); k != nil {
+		k.LastUsedAt = arg.LastUsedAt
+	}
+	return nil
+}
+
+func newTestServiceAccount(t *testing.T) (*APIKeyService, *apiKeyQueries, *ServiceAccount) {
+	t.Helper()
+	q := &apiKeyQueries{}
+	service := NewAPIKeyService(q)
+	account, err := service.CreateServiceAccount(context.Background(), rbacTestTenant, "ci", "deploys from CI")
+	require.NoError(t, err)
+	return service, q, account
+}
+
+func TestAPIKeyService_IssueAndAuthenticate(t *testing.T) {
+	service, q, account := newTestServiceAccount(t)
+	ctx := context.Background()
+
+	key, apiKey, err := service.IssueAPIKey(ctx, rbacTestTenant, account.ID, []string{"workflows:read", "workflows:wf-1:execute"}, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, IsAPIKey(key))
+	assert.True(t, strings.HasPrefix(key, apiKey.Prefix+"_"))
+	assert.NotContains(t, string(q.keys[0].KeyHash), key, "keys are stored hashed")
+	require.NotNil(t, apiKey.ExpiresAt)
+
+	claims, err := service.Authenticate(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, claims.IsServiceAccount())
+	assert.Equal(t, "service_account", claims.ActorType())
+	assert.Equal(t, rbacTestTenant, claims.TenantID)
+	assert.Equal(t, account.ID, claims.UserID)
+	assert.Equal(t, apiKey.ID, claims.ID)
+	assert.Equal(t, []string{ServiceAccountRole}, claims.Roles)
+	assert.Equal(t, []string{"workflows:read", "workflows:wf-1:execute"}, claims.Permissions)
+
+	// A key with the right prefix but the wrong secret
+	_, err = service.Authenticate(ctx, apiKey.Prefix+"_guessed")
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+	_, err = service.Authenticate(ctx, "afk_malformed")
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+
+	// Lookups that fail are not reported as bad keys
+	q.err = errors.New("database down")
+	_, err = service.Authenticate(ctx, key)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrAPIKeyInvalid)
+}
+
+func TestAPIKeyService_Rejections(t *testing.T) {
+	service, _, account := newTestServiceAccount(t)
+	ctx := context.Background()
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	expiring, _, err := service.IssueAPIKey(ctx, rbacTestTenant, account.ID, []string{"workflows:read"}, time.Hour)
+	require.NoError(t, err)
+	revoked, revokedKey, err := service.IssueAPIKey(ctx, rbacTestTenant, account.ID, []string{"workflows:read"}, 0)
+	require.NoError(t, err)
+	assert.Nil(t, revokedKey.ExpiresAt)
+
+	require.NoError(t, service.RevokeAPIKey(ctx, rbacTestTenant, account.ID, revokedKey.ID))
+	_, err = service.Authenticate(ctx, revoked)
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+
+	now = now.Add(time.Hour)
+	_, err = service.Authenticate(ctx, expiring)
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+
+	// Invalid scopes and unknown accounts
+	_, _, err = service.IssueAPIKey(ctx, rbacTestTenant, account.ID, []string{"workflows"}, 0)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+	_, _, err = service.IssueAPIKey(ctx, rbacTestTenant, uuid.NewString(), nil, 0)
+	assert.ErrorIs(t, err, ErrServiceAccountNotFound)
+	assert.ErrorIs(t, service.RevokeAPIKey(ctx, rbacTestTenant, account.ID, uuid.NewString()), ErrAPIKeyNotFound)
+}
+
+func TestAPIKeyService_DisabledAccount(t *testing.T) {
+	service, _, account := newTestServiceAccount(t)
+	ctx := context.Background()
+
+	key, _, err := service.IssueAPIKey(ctx, rbacTestTenant, account.ID, []string{"workflows:read"}, 0)
+	require.NoError(t, err)
+	disabled, err := service.DisableServiceAccount(ctx, rbacTestTenant, account.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, disabled.DisabledAt)
+
+	_, err = service.Authenticate(ctx, key)
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+	_, _, err = service.IssueAPIKey(ctx, rbacTestTenant, account.ID, nil, 0)
+	assert.ErrorIs(t, err, ErrServiceAccountNotFound)
+}
+
+func TestAPIKeyService_Rotate(t *testing.T) {
+	service, _, account := newTestServiceAccount(t)
+	ctx := context.Background()
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	old, oldKey, err := service.IssueAPIKey(ctx, rbacTestTenant, account.ID, []string{"tools:call"}, 0)
+	require.NoError(t, err)
+	replacement, newKey, err := service.RotateAPIKey(ctx, rbacTestTenant, account.ID, oldKey.ID, time.Hour)
+	require.NoError(t, err)
+	assert.NotEqual(t, old, replacement)
+	assert.Equal(t, oldKey.Scopes, newKey.Scopes)
+	assert.Nil(t, newKey.ExpiresAt)
+
+	// Both keys work during the overlap, then only the new one
+	_, err = service.Authenticate(ctx, old)
+	assert.NoError(t, err)
+	now = now.Add(time.Hour)
+	_, err = service.Authenticate(ctx, old)
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+	_, err = service.Authenticate(ctx, replacement)
+	assert.NoError(t, err)
+
+	// Without overlap the replaced key is revoked at once, and it cannot be
+	// rotated again
+	next, _, err := service.RotateAPIKey(ctx, rbacTestTenant, account.ID, newKey.ID, 0)
+	require.NoError(t, err)
+	_, err = service.Authenticate(ctx, replacement)
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+	_, err = service.Authenticate(ctx, next)
+	assert.NoError(t, err)
+	_, _, err = service.RotateAPIKey(ctx, rbacTestTenant, account.ID, newKey.ID, 0)
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+
+	keys, err := service.ListAPIKeys(ctx, rbacTestTenant, account.ID)
+	require.NoError(t, err)
+	assert.Len(t, keys, 3)
+}
+
+func TestAPIKeyService_LastUsed(t *testing.T) {
+	service, q, account := newTestServiceAccount(t)
+	ctx := context.Background()
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	key, _, err := service.IssueAPIKey(ctx, rbacTestTenant, account.ID, nil, 0)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = service.Authenticate(ctx, key)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, q.touches, "last use is written once a minute")
+	assert.True(t, q.keys[0].LastUsedAt.Valid)
+
+	now = now.Add(apiKeyTouchInterval)
+	_, err = service.Authenticate(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, 2, q.touches)
+}
+
+func TestGrantsScope(t *testing.T) {
+	tests := []struct {
+		permissions []string
+		scope       string
+		want        bool
+	}{
+		{[]string{"*"}, "*", true},
+		{[]string{"*"}, "workflows:wf-1:execute", true},
+		{[]string{"workflows:*"}, "workflows:read", true},
+		{[]string{"workflows:read"}, "workflows:wf-1:read", true},
+		{[]string{"workflows:wf-1:read"}, "workflows:read", false},
+		{[]string{"workflows:read"}, "workflows:write", false},
+		{[]string{"workflows:*"}, "*", false},
+		{nil, "workflows:read", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, GrantsScope(tt.permissions, tt.scope), "%v grants %s", tt.permissions, tt.scope)
+	}
+}
+
+func TestAuthMiddleware_APIKeys(t *testing.T) {
+	service, q, account := newTestServiceAccount(t)
+	ctx := context.Background()
+	key, _, err := service.IssueAPIKey(ctx, rbacTestTenant, account.ID, []string{"workflows:read"}, 0)
+	require.NoError(t, err)
+
+	// Service accounts cannot hold role bindings, so the authorizer must not
+	// replace the key's scopes
+	middleware := NewAuthMiddleware(newRefreshTestAuthenticator(), logging.NewLogger(), nil).
+		WithAuthorizer(NewRBACAuthorizer(&rbacQueries{})).
+		WithAPIKeys(service)
+
+	var seen *AgentFlowClaims
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetClaimsFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(handler http.Handler, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/workflows", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		middleware.Middleware()(handler).ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve(middleware.RequirePermission("workflows", "read")(ok), key).Code)
+	require.NotNil(t, seen)
+	assert.Equal(t, account.ID, seen.UserID)
+	assert.Equal(t, http.StatusForbidden, serve(middleware.RequirePermission("workflows", "write")(ok), key).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(ok, "afk_000000000000_wrong").Code)
+
+	q.err = errors.New("database down")
+	w := serve(ok, key)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "authentication_unavailable")
+}
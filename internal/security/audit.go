@@ -0,0 +1,55 @@
+// Package security provides audit hooks for authentication and tenant isolation
+package security
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/agentflow/agentflow/internal/storage/audit"
+)
+
+// Audit actions recorded by the security hooks
+const (
+	AuditActionTokenIssue        = "auth.token_issue"
+	AuditActionTokenValidate     = "auth.token_validate"
+	AuditActionTokenRevoke       = "auth.token_revoke"
+	AuditActionTokenRefresh      = "auth.token_refresh"
+	AuditActionLogin             = "auth.login"
+	AuditActionAccessDenied      = "auth.access_denied"
+	AuditActionCrossTenantAccess = "cross_tenant_access_attempt"
+)
+
+// recordAudit passes event to recorder. Events without a tenant are only
+// logged by the caller: every audit chain belongs to a tenant, and a tenant
+// taken from an unverified token would let anyone write to its chain.
+func recordAudit(ctx context.Context, recorder audit.Recorder, event audit.Event) error {
+	if recorder == nil || event.TenantID == "" {
+		return nil
+	}
+	return recorder.Record(ctx, event)
+}
+
+// claimsAuditEvent starts an event for an action taken by the claims' user
+func claimsAuditEvent(r *http.Request, claims *AgentFlowClaims, action, resourceType, outcome string) audit.Event {
+	return audit.Event{
+		TenantID:     claims.TenantID,
+		ActorType:    claims.ActorType(),
+		ActorID:      claims.UserID,
+		Action:       action,
+		ResourceType: resourceType,
+		Outcome:      outcome,
+		Details: map[string]interface{}{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"remote_addr": r.RemoteAddr,
+			"roles":       claims.Roles,
+		},
+	}
+}
+
+// tokenAuditEvent starts an event for an action on the token claims was read from
+func tokenAuditEvent(r *http.Request, claims *AgentFlowClaims, action, outcome string) audit.Event {
+	event := claimsAuditEvent(r, claims, action, "token", outcome)
+	event.ResourceID = claims.ID
+	return event
+}
@@ -0,0 +1,138 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const auditTestTenant = "00000000-0000-0000-0000-000000000001"
+
+func TestAuthHandlers_Audit(t *testing.T) {
+	config := &AuthConfig{
+		JWTSecret:   "test-secret-32-characters-long",
+		TokenExpiry: time.Hour,
+	}
+	auth := NewAuthenticator(config)
+	recorder := audit.NewMemoryRecorder()
+	handlers := NewAuthHandlers(auth, logging.NewLogger()).WithRecorder(recorder)
+
+	issue := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(TokenIssueRequest{TenantID: auditTestTenant, UserID: "user456", Roles: []string{"admin"}})
+		w := httptest.NewRecorder()
+		handlers.HandleTokenIssue(w, asCaller(httptest.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader(body)), auditTestTenant, "admin1", AdminRole))
+		return w
+	}
+
+	w := issue()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, recorder.Events(), 1)
+	event := recorder.Events()[0]
+	assert.Equal(t, AuditActionTokenIssue, event.Action)
+	assert.Equal(t, auditTestTenant, event.TenantID)
+	assert.Equal(t, "admin1", event.ActorID)
+	assert.Equal(t, "user456", event.ResourceID)
+	assert.Equal(t, audit.OutcomeSuccess, event.Outcome)
+	assert.True(t, event.Critical)
+
+	var response struct {
+		Data TokenResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	claims, err := auth.ValidateToken(context.Background(), response.Data.AccessToken)
+	require.NoError(t, err)
+
+	t.Run("Validate", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/auth/validate", nil)
+		req.Header.Set("Authorization", "Bearer "+response.Data.AccessToken)
+		w := httptest.NewRecorder()
+		handlers.HandleTokenValidate(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		event := recorder.Last()
+		assert.Equal(t, AuditActionTokenValidate, event.Action)
+		assert.Equal(t, claims.ID, event.ResourceID)
+		assert.False(t, event.Critical)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/auth/revoke", nil)
+		req.Header.Set("Authorization", "Bearer "+response.Data.AccessToken)
+		w := httptest.NewRecorder()
+		handlers.HandleTokenRevoke(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		event := recorder.Last()
+		assert.Equal(t, AuditActionTokenRevoke, event.Action)
+		assert.Equal(t, claims.ID, event.ResourceID)
+		assert.Equal(t, audit.OutcomeSuccess, event.Outcome)
+		assert.True(t, event.Critical)
+	})
+
+	t.Run("IssueWithoutAuditRecord", func(t *testing.T) {
+		recorder.SetError(errors.New("audit store down"))
+		defer recorder.SetError(nil)
+
+		w := issue()
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.NotContains(t, w.Body.String(), "access_token")
+	})
+}
+
+func TestAuthMiddleware_AuditsDenials(t *testing.T) {
+	recorder := audit.NewMemoryRecorder()
+	middleware := NewAuthMiddleware(NewAuthenticator(DefaultAuthConfig()), logging.NewLogger(), DefaultAuthConfig()).WithRecorder(recorder)
+	claims := &AgentFlowClaims{TenantID: auditTestTenant, UserID: "user456", Roles: []string{"viewer"}}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	handlers := map[string]http.Handler{
+		"role":       middleware.RequireRole("admin")(ok),
+		"permission": middleware.RequirePermission("workflows", "delete")(ok),
+	}
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("DELETE", "/api/v1/workflows/wf-1", nil)
+			req = req.WithContext(context.WithValue(req.Context(), "auth_claims", claims))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusForbidden, w.Code)
+			event := recorder.Last()
+			assert.Equal(t, AuditActionAccessDenied, event.Action)
+			assert.Equal(t, audit.OutcomeDenied, event.Outcome)
+			assert.Equal(t, "/api/v1/workflows/wf-1", event.Details["path"])
+			assert.True(t, event.Critical)
+		})
+	}
+	assert.Len(t, recorder.Events(), 2)
+}
+
+func TestTenantIsolationMiddleware_AuditCrossTenantAttempt(t *testing.T) {
+	recorder := audit.NewMemoryRecorder()
+	middleware := NewTenantIsolationMiddleware(logging.NewLogger(), nil).WithRecorder(recorder)
+	claims := &AgentFlowClaims{TenantID: auditTestTenant, UserID: "user456"}
+
+	req := httptest.NewRequest("GET", "/api/v1/workflows?tenant_id=other", nil)
+	middleware.auditCrossTenantAttempt(req, claims, "cross_tenant_access", "query parameter tenant_id does not match")
+
+	require.Len(t, recorder.Events(), 1)
+	event := recorder.Events()[0]
+	assert.Equal(t, AuditActionCrossTenantAccess, event.Action)
+	assert.Equal(t, "security_violation", event.ResourceType)
+	assert.Equal(t, audit.OutcomeDenied, event.Outcome)
+	assert.Equal(t, "cross_tenant_access", event.Details["attempt_type"])
+	assert.True(t, event.Critical)
+
+	// Without a recorder the attempt is only logged
+	NewTenantIsolationMiddleware(logging.NewLogger(), nil).auditCrossTenantAttempt(req, claims, "cross_tenant_access", "")
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,6 +33,23 @@ type AgentFlowClaims struct {
 	jwt.RegisteredClaims
 }
 
+// IsServiceAccount reports whether the claims were authenticated with a
+// service account API key
+func (c *AgentFlowClaims) IsServiceAccount() bool {
+	return c.Issuer == APIKeyIssuer
+}
+
+// ActorType returns the audit actor type of the claims' principal
+func (c *AgentFlowClaims) ActorType() string {
+	if c.IsServiceAccount() {
+		return "service_account"
+	}
+	if c.IsClientCertificate() && len(c.Roles) > 0 {
+		return c.Roles[0]
+	}
+	return "user"
+}
+
 // TokenRequest represents a token issuance request
 type TokenRequest struct {
 	TenantID    string   `json:"tenant_id"`
@@ -59,15 +77,38 @@ type AuthConfig struct {
 	OIDCIssuer       string        `env:"AF_OIDC_ISSUER"`
 	OIDCClientID     string        `env:"AF_OIDC_CLIENT_ID"`
 	OIDCClientSecret string        `env:"AF_OIDC_CLIENT_SECRET"`
+	OIDCAudience     string        `env:"AF_OIDC_AUDIENCE"` // defaults to the client ID
+	OIDCClockSkew    time.Duration `env:"AF_OIDC_CLOCK_SKEW"`
+	OIDCJWKSRefresh  time.Duration `env:"AF_OIDC_JWKS_REFRESH_INTERVAL"`
+	// AcceptLegacyHS256 keeps accepting tokens signed with JWTSecret once
+	// signing keys are configured, while those tokens expire
+	AcceptLegacyHS256 bool `env:"AF_JWT_ACCEPT_HS256"`
+	// LoginMaxAttempts failed password logins in a row lock an account for
+	// LoginLockout
+	LoginMaxAttempts int           `env:"AF_LOGIN_MAX_ATTEMPTS"`
+	LoginLockout     time.Duration `env:"AF_LOGIN_LOCKOUT"`
 }
 
+// Issuer and audience of the tokens the control plane issues
+const (
+	TokenIssuer   = "agentflow-control-plane"
+	TokenAudience = "agentflow"
+)
+
+// defaultRefreshExpiry is how long a refresh token lasts unless configured
+const defaultRefreshExpiry = 7 * 24 * time.Hour
+
 // DefaultAuthConfig returns default authentication configuration
 func DefaultAuthConfig() *AuthConfig {
 	return &AuthConfig{
-		JWTSecret:     generateDefaultSecret(),
-		TokenExpiry:   24 * time.Hour,
-		RefreshExpiry: 7 * 24 * time.Hour,
-		OIDCEnabled:   false,
+		JWTSecret:        generateDefaultSecret(),
+		TokenExpiry:      24 * time.Hour,
+		RefreshExpiry:    defaultRefreshExpiry,
+		OIDCEnabled:      false,
+		OIDCClockSkew:    time.Minute,
+		OIDCJWKSRefresh:  defaultJWKSRefreshInterval,
+		LoginMaxAttempts: defaultLoginMaxAttempts,
+		LoginLockout:     defaultLoginLockout,
 	}
 }
 
@@ -107,13 +148,47 @@ func LoadAuthConfigFromEnv() *AuthConfig {
 		config.OIDCClientSecret = val
 	}
 
+	if val := os.Getenv("AF_OIDC_AUDIENCE"); val != "" {
+		config.OIDCAudience = val
+	}
+
+	if val := os.Getenv("AF_OIDC_CLOCK_SKEW"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			config.OIDCClockSkew = duration
+		}
+	}
+
+	if val := os.Getenv("AF_OIDC_JWKS_REFRESH_INTERVAL"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil {
+			config.OIDCJWKSRefresh = duration
+		}
+	}
+
+	if val := os.Getenv("AF_JWT_ACCEPT_HS256"); val != "" {
+		config.AcceptLegacyHS256 = val == "true" || val == "1"
+	}
+
+	if val := os.Getenv("AF_LOGIN_MAX_ATTEMPTS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			config.LoginMaxAttempts = n
+		}
+	}
+
+	if val := os.Getenv("AF_LOGIN_LOCKOUT"); val != "" {
+		if duration, err := time.ParseDuration(val); err == nil && duration > 0 {
+			config.LoginLockout = duration
+		}
+	}
+
 	return config
 }
 
 // jwtAuthenticator implements JWT-based authentication
 type jwtAuthenticator struct {
-	config        *AuthConfig
-	revokedTokens map[string]time.Time // Simple in-memory revocation store
+	config       *AuthConfig
+	revocations  RevocationStore
+	refreshStore RefreshTokenStore
+	signingKeys  *SigningKeyRing
 }
 
 // NewAuthenticator creates a new JWT authenticator
@@ -123,22 +198,34 @@ func NewAuthenticator(config *AuthConfig) Authenticator {
 	}
 
 	return &jwtAuthenticator{
-		config:        config,
-		revokedTokens: make(map[string]time.Time),
+		config:       config,
+		revocations:  NewMemoryRevocationStore(),
+		refreshStore: NewMemoryRefreshTokenStore(),
 	}
 }
 
+// SetRevocationStore keeps token revocations in store instead of memory
+func (a *jwtAuthenticator) SetRevocationStore(store RevocationStore) {
+	a.revocations = store
+}
+
+// SetSigningKeys signs tokens with the active key of ring instead of
+// JWTSecret
+func (a *jwtAuthenticator) SetSigningKeys(ring *SigningKeyRing) {
+	a.signingKeys = ring
+}
+
+// SetRefreshTokenStore keeps issued refresh tokens in store instead of memory
+func (a *jwtAuthenticator) SetRefreshTokenStore(store RefreshTokenStore) {
+	a.refreshStore = store
+}
+
 // ValidateToken validates a JWT token and returns claims
 func (a *jwtAuthenticator) ValidateToken(ctx context.Context, tokenString string) (*AgentFlowClaims, error) {
-	// Parse and validate the token
-	token, err := jwt.ParseWithClaims(tokenString, &AgentFlowClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(a.config.JWTSecret), nil
-	})
-
+	// Parse and validate the token. Only tokens issued by the control plane
+	// for AgentFlow are accepted.
+	token, err := jwt.ParseWithClaims(tokenString, &AgentFlowClaims{}, a.keyFunc(ctx),
+		jwt.WithIssuer(TokenIssuer), jwt.WithAudience(TokenAudience))
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
@@ -149,10 +236,15 @@ func (a *jwtAuthenticator) ValidateToken(ctx context.Context, tokenString string
 		return nil, errors.New("invalid token claims")
 	}
 
-	// Check if token is revoked
-	if revokedAt, exists := a.revokedTokens[tokenString]; exists {
-		if claims.IssuedAt.Before(revokedAt) {
-			return nil, errors.New("token has been revoked")
+	// Check if token is revoked. A token whose revocation cannot be checked
+	// is rejected.
+	if claims.ID != "" {
+		revoked, err := a.revocations.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to check whether token has been revoked: %w", err)
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
 		}
 	}
 
@@ -168,7 +260,12 @@ func (a *jwtAuthenticator) ValidateToken(ctx context.Context, tokenString string
 	return claims, nil
 }
 
-// IssueToken issues a new JWT token
+// ErrTokenLifetime is returned for a requested token lifetime longer than
+// AuthConfig.TokenExpiry
+var ErrTokenLifetime = errors.New("token lifetime exceeds the configured token expiry")
+
+// IssueToken issues a new JWT token with a refresh token that starts a new
+// token family
 func (a *jwtAuthenticator) IssueToken(ctx context.Context, req *TokenRequest) (*TokenResponse, error) {
 	if req.TenantID == "" {
 		return nil, errors.New("tenant_id is required")
@@ -178,6 +275,15 @@ func (a *jwtAuthenticator) IssueToken(ctx context.Context, req *TokenRequest) (*
 		return nil, errors.New("user_id is required")
 	}
 
+	if a.config.TokenExpiry > 0 && time.Duration(req.ExpiresIn)*time.Second > a.config.TokenExpiry {
+		return nil, fmt.Errorf("%w (%s)", ErrTokenLifetime, a.config.TokenExpiry)
+	}
+
+	return a.issue(ctx, req, uuid.New().String())
+}
+
+// issue signs an access token for req and stores a refresh token in familyID
+func (a *jwtAuthenticator) issue(ctx context.Context, req *TokenRequest, familyID string) (*TokenResponse, error) {
 	now := time.Now()
 	expiry := req.ExpiresIn
 	if expiry == 0 {
@@ -193,23 +299,37 @@ func (a *jwtAuthenticator) IssueToken(ctx context.Context, req *TokenRequest) (*
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        uuid.New().String(),
 			Subject:   req.UserID,
-			Audience:  []string{"agentflow"},
-			Issuer:    "agentflow-control-plane",
+			Audience:  []string{TokenAudience},
+			Issuer:    TokenIssuer,
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(expiry) * time.Second)),
 			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(a.config.JWTSecret))
+	tokenString, err := a.sign(claims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign token: %w", err)
 	}
 
-	// Generate refresh token
+	// Only the hash of the refresh token is stored
+	refreshExpiry := a.config.RefreshExpiry
+	if refreshExpiry <= 0 {
+		refreshExpiry = defaultRefreshExpiry
+	}
 	refreshToken := generateRefreshToken()
+	err = a.refreshStore.Create(ctx, RefreshToken{
+		Hash:        hashRefreshToken(refreshToken),
+		FamilyID:    familyID,
+		TenantID:    req.TenantID,
+		UserID:      req.UserID,
+		Roles:       req.Roles,
+		Permissions: req.Permissions,
+		ExpiresAt:   now.Add(refreshExpiry),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
 
 	return &TokenResponse{
 		AccessToken:  tokenString,
@@ -220,22 +340,84 @@ func (a *jwtAuthenticator) IssueToken(ctx context.Context, req *TokenRequest) (*
 	}, nil
 }
 
-// RefreshToken refreshes an access token using a refresh token
+// RefreshToken exchanges a refresh token for a new access token and a new
+// refresh token in the same family. Each refresh token works once; using one
+// again revokes its whole family and returns a *RefreshReuseError.
 func (a *jwtAuthenticator) RefreshToken(ctx context.Context, refreshToken string) (*TokenResponse, error) {
-	// In a real implementation, you would validate the refresh token
-	// against a database or cache. For now, we'll return an error.
-	return nil, errors.New("refresh token functionality not implemented")
+	stored, err := a.refreshStore.Use(ctx, hashRefreshToken(refreshToken))
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if err := a.refreshStore.RevokeFamily(ctx, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, &RefreshReuseError{TenantID: stored.TenantID, UserID: stored.UserID, FamilyID: stored.FamilyID}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a.issue(ctx, &TokenRequest{
+		TenantID:    stored.TenantID,
+		UserID:      stored.UserID,
+		Roles:       stored.Roles,
+		Permissions: stored.Permissions,
+	}, stored.FamilyID)
 }
 
-// RevokeToken revokes a token
+// RevokeToken revokes a token by its JWT ID until it expires. The token must
+// carry a valid signature; an expired token needs no revocation.
 func (a *jwtAuthenticator) RevokeToken(ctx context.Context, token string) error {
-	// Add token to revocation list with current timestamp
-	a.revokedTokens[token] = time.Now()
+	parsed, err := jwt.ParseWithClaims(token, &AgentFlowClaims{}, a.keyFunc(ctx), jwt.WithoutClaimsValidation())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTokenNotRevocable, err)
+	}
+	claims, ok := parsed.Claims.(*AgentFlowClaims)
+	if !ok || claims.ID == "" || claims.ExpiresAt == nil {
+		return fmt.Errorf("%w: missing jti or exp claim", ErrTokenNotRevocable)
+	}
+
+	if !time.Now().Before(claims.ExpiresAt.Time) {
+		return nil
+	}
+	return a.revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+// sign signs claims with the active signing key, or with JWTSecret when no
+// signing keys are configured
+func (a *jwtAuthenticator) sign(claims *AgentFlowClaims) (string, error) {
+	if a.signingKeys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(a.config.JWTSecret))
+	}
 
-	// In a production system, you would persist this to a database
-	// and implement cleanup for expired tokens
+	key := a.signingKeys.Active()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Key)
+}
 
-	return nil
+// keyFunc returns the key a token was signed with. Once signing keys are
+// configured, HMAC tokens are only accepted with AcceptLegacyHS256.
+func (a *jwtAuthenticator) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			if a.signingKeys != nil && !a.config.AcceptLegacyHS256 {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return []byte(a.config.JWTSecret), nil
+		}
+		if a.signingKeys == nil {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, err := a.signingKeys.verificationKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.Algorithm != token.Method.Alg() {
+			return nil, fmt.Errorf("algorithm %s does not match signing key %s", token.Method.Alg(), kid)
+		}
+		return key.Key.Public(), nil
+	}
 }
 
 // generateDefaultSecret generates a default JWT secret for development
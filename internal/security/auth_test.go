@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -85,6 +86,25 @@ func TestJWTAuthenticator(t *testing.T) {
 		assert.Contains(t, err.Error(), "invalid token")
 	})
 
+	t.Run("ValidateToken_WrongIssuerOrAudience", func(t *testing.T) {
+		now := time.Now()
+		for name, registered := range map[string]jwt.RegisteredClaims{
+			"no issuer":      {Audience: []string{TokenAudience}},
+			"other issuer":   {Issuer: "someone-else", Audience: []string{TokenAudience}},
+			"no audience":    {Issuer: TokenIssuer},
+			"other audience": {Issuer: TokenIssuer, Audience: []string{"other-service"}},
+		} {
+			registered.IssuedAt = jwt.NewNumericDate(now)
+			registered.ExpiresAt = jwt.NewNumericDate(now.Add(time.Hour))
+			claims := &AgentFlowClaims{TenantID: "tenant123", UserID: "user456", RegisteredClaims: registered}
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.JWTSecret))
+			require.NoError(t, err)
+
+			_, err = auth.ValidateToken(ctx, token)
+			assert.Error(t, err, name)
+		}
+	})
+
 	t.Run("ValidateToken_ExpiredToken", func(t *testing.T) {
 		// Create config with very short expiry
 		shortConfig := &AuthConfig{
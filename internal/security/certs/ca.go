@@ -0,0 +1,249 @@
+// Package certs issues and loads the X.509 certificates used for mutual TLS
+// between workers, services, the control plane and NATS. It includes a
+// small certificate authority for development clusters; production
+// deployments can use any CA that issues certificates with the same
+// identities.
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files written by WriteCA and read by LoadCA
+const (
+	CACertFile = "ca.pem"
+	CAKeyFile  = "ca-key.pem"
+)
+
+// Default lifetimes of development certificates
+const (
+	DefaultCAValidity   = 10 * 365 * 24 * time.Hour
+	DefaultCertValidity = 90 * 24 * time.Hour
+)
+
+// ErrCAExists is returned by WriteCA when the directory already holds a CA
+var ErrCAExists = errors.New("certificate authority already exists")
+
+// CA is a certificate authority that issues certificates
+type CA struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+}
+
+// IssueRequest describes a certificate to issue. Server certificates name
+// the hosts they serve; worker and service certificates carry an identity.
+type IssueRequest struct {
+	Kind     string // KindWorker, KindService or KindServer
+	Name     string
+	TenantID string // optional, for services scoped to one tenant
+	DNSNames []string
+	IPs      []net.IP
+	Validity time.Duration
+}
+
+// NewCA creates a self-signed certificate authority
+func NewCA(commonName string, validity time.Duration) (*CA, error) {
+	if validity <= 0 {
+		validity = DefaultCAValidity
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName, Organization: []string{"AgentFlow"}},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{Cert: cert, Key: key}, nil
+}
+
+// WriteCA writes the CA certificate and key to dir. It refuses to replace
+// an existing CA, since every certificate it issued would stop verifying.
+func WriteCA(dir string, ca *CA) error {
+	certPath, keyPath := filepath.Join(dir, CACertFile), filepath.Join(dir, CAKeyFile)
+	for _, path := range []string{certPath, keyPath} {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%w: %s", ErrCAExists, path)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	keyPEM, err := encodeKey(ca.Key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return fmt.Errorf("failed to write CA key: %w", err)
+	}
+	if err := os.WriteFile(certPath, encodeCert(ca.Cert.Raw), 0o644); err != nil {
+		return fmt.Errorf("failed to write CA certificate: %w", err)
+	}
+	return nil
+}
+
+// LoadCA reads the CA written to dir by WriteCA
+func LoadCA(dir string) (*CA, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, CACertFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(dir, CAKeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA key: %w", err)
+	}
+	certs, err := parseCertificates(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("CA key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA key: %w", err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok || !certs[0].IsCA {
+		return nil, errors.New("not a certificate authority")
+	}
+	return &CA{Cert: certs[0], Key: key}, nil
+}
+
+// Issue creates a certificate and key signed by the CA, both PEM encoded.
+// Worker and service certificates are for client authentication and carry
+// their identity as a URI SAN; server certificates are for the hosts named.
+func (ca *CA) Issue(req IssueRequest) (certPEM, keyPEM []byte, err error) {
+	if req.Name == "" {
+		return nil, nil, errors.New("certificate name is required")
+	}
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: req.Name, OrganizationalUnit: []string{req.Kind}},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		DNSNames:    req.DNSNames,
+		IPAddresses: req.IPs,
+	}
+	switch req.Kind {
+	case KindServer:
+		if len(req.DNSNames) == 0 && len(req.IPs) == 0 {
+			template.DNSNames = []string{req.Name}
+		}
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	case KindWorker, KindService:
+		id := Identity{Kind: req.Kind, Name: req.Name, TenantID: req.TenantID}
+		uri, err := id.URI()
+		if err != nil {
+			return nil, nil, err
+		}
+		template.URIs = append(template.URIs, uri)
+		// NATS and other peers may also need to serve with the same key
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+	default:
+		return nil, nil, fmt.Errorf("unknown certificate kind %q (want %s, %s or %s)", req.Kind, KindWorker, KindService, KindServer)
+	}
+
+	validity := req.Validity
+	if validity <= 0 {
+		validity = DefaultCertValidity
+	}
+	now := time.Now()
+	template.NotBefore = now.Add(-time.Minute)
+	template.NotAfter = now.Add(validity)
+	if template.NotAfter.After(ca.Cert.NotAfter) {
+		template.NotAfter = ca.Cert.NotAfter
+	}
+	if template.SerialNumber, err = newSerial(); err != nil {
+		return nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, key.Public(), ca.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyPEM, err = encodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeCert(der), keyPEM, nil
+}
+
+func newSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	return serial, nil
+}
+
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func encodeKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// parseCertificates parses every certificate in a PEM bundle
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
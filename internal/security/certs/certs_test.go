@@ -0,0 +1,226 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA creates a CA written to a temporary directory
+func testCA(t *testing.T) (*CA, string) {
+	t.Helper()
+	ca, err := NewCA("test CA", time.Hour)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, WriteCA(dir, ca))
+	return ca, dir
+}
+
+// issueFiles issues a certificate into dir and returns its paths
+func issueFiles(t *testing.T, ca *CA, dir string, req IssueRequest) (certPath, keyPath string) {
+	t.Helper()
+	certPEM, keyPEM, err := ca.Issue(req)
+	require.NoError(t, err)
+	certPath, keyPath = filepath.Join(dir, req.Name+".pem"), filepath.Join(dir, req.Name+"-key.pem")
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0o644))
+	require.NoError(t, os.WriteFile(keyPath, keyPEM, 0o600))
+	return certPath, keyPath
+}
+
+func parsePEM(t *testing.T, data []byte) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode(data)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestCA_WriteAndLoad(t *testing.T) {
+	ca, dir := testCA(t)
+
+	loaded, err := LoadCA(dir)
+	require.NoError(t, err)
+	assert.True(t, loaded.Cert.Equal(ca.Cert))
+
+	info, err := os.Stat(filepath.Join(dir, CAKeyFile))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	err = WriteCA(dir, ca)
+	assert.ErrorIs(t, err, ErrCAExists)
+
+	_, err = LoadCA(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestCA_Issue(t *testing.T) {
+	ca, _ := testCA(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+
+	certPEM, keyPEM, err := ca.Issue(IssueRequest{Kind: KindWorker, Name: "worker-1", Validity: 24 * time.Hour})
+	require.NoError(t, err)
+	_, err = tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	cert := parsePEM(t, certPEM)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	require.NoError(t, err)
+	assert.False(t, cert.NotAfter.After(ca.Cert.NotAfter), "certificates never outlive their CA")
+
+	id, err := IdentityFromCertificate(cert)
+	require.NoError(t, err)
+	assert.Equal(t, Identity{Kind: KindWorker, Name: "worker-1"}, id)
+	assert.Equal(t, "worker:worker-1", id.String())
+
+	certPEM, _, err = ca.Issue(IssueRequest{Kind: KindService, Name: "billing", TenantID: "tenant-a"})
+	require.NoError(t, err)
+	cert = parsePEM(t, certPEM)
+	assert.Equal(t, "spiffe://agentflow/tenant/tenant-a/service/billing", cert.URIs[0].String())
+	id, err = IdentityFromCertificate(cert)
+	require.NoError(t, err)
+	assert.Equal(t, Identity{Kind: KindService, Name: "billing", TenantID: "tenant-a"}, id)
+
+	certPEM, _, err = ca.Issue(IssueRequest{Kind: KindServer, Name: "control-plane", IPs: []net.IP{net.ParseIP("127.0.0.1")}})
+	require.NoError(t, err)
+	cert = parsePEM(t, certPEM)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "127.0.0.1"})
+	require.NoError(t, err)
+	_, err = IdentityFromCertificate(cert)
+	assert.ErrorIs(t, err, ErrNoIdentity)
+
+	for _, req := range []IssueRequest{
+		{Kind: KindWorker},
+		{Kind: "admin", Name: "x"},
+		{Kind: KindWorker, Name: "bad/name"},
+		{Kind: KindService, Name: "svc", TenantID: "a b"},
+	} {
+		_, _, err := ca.Issue(req)
+		assert.Error(t, err, "%+v", req)
+	}
+}
+
+func TestParseIdentityPath(t *testing.T) {
+	for _, path := range []string{
+		"",
+		"/worker",
+		"/server/api",
+		"/worker/a/b",
+		"/tenant/t1/worker",
+		"/tenant//worker/w",
+		"/worker/",
+	} {
+		_, err := parseIdentityPath(path)
+		assert.ErrorIs(t, err, ErrNoIdentity, path)
+	}
+}
+
+func TestReloader_KeepsCertificatesOnFailure(t *testing.T) {
+	ca, dir := testCA(t)
+	certPath, keyPath := issueFiles(t, ca, dir, IssueRequest{Kind: KindWorker, Name: "w1"})
+
+	r, err := NewReloader(certPath, keyPath, filepath.Join(dir, CACertFile))
+	require.NoError(t, err)
+	first := r.Certificate()
+	require.NotNil(t, first)
+	require.NotNil(t, r.Pool())
+
+	// A half-written renewal leaves the loaded certificate in place
+	require.NoError(t, os.WriteFile(certPath, []byte("garbage"), 0o644))
+	assert.Error(t, r.Reload())
+	assert.Same(t, first, r.Certificate())
+
+	issueFiles(t, ca, dir, IssueRequest{Kind: KindWorker, Name: "w1"})
+	require.NoError(t, r.Reload())
+	assert.NotEqual(t, first.Leaf.SerialNumber, r.Certificate().Leaf.SerialNumber)
+
+	_, err = NewReloader(certPath, "", "")
+	assert.Error(t, err)
+}
+
+func TestReloader_Start(t *testing.T) {
+	ca, dir := testCA(t)
+	certPath, keyPath := issueFiles(t, ca, dir, IssueRequest{Kind: KindWorker, Name: "w1"})
+	r, err := NewReloader(certPath, keyPath, "")
+	require.NoError(t, err)
+	first := r.Certificate().Leaf.SerialNumber
+
+	r.Start(10 * time.Millisecond)
+	issueFiles(t, ca, dir, IssueRequest{Kind: KindWorker, Name: "w1"})
+	assert.Eventually(t, func() bool {
+		return r.Certificate().Leaf.SerialNumber.Cmp(first) != 0
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, r.Close())
+	require.NoError(t, r.Close())
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca, dir := testCA(t)
+	caPath := filepath.Join(dir, CACertFile)
+	serverCert, serverKey := issueFiles(t, ca, dir, IssueRequest{Kind: KindServer, Name: "server", IPs: []net.IP{net.ParseIP("127.0.0.1")}})
+	workerCert, workerKey := issueFiles(t, ca, dir, IssueRequest{Kind: KindWorker, Name: "w1"})
+
+	serverReloader, err := NewReloader(serverCert, serverKey, caPath)
+	require.NoError(t, err)
+	serverConfig, err := serverReloader.ServerConfig(tls.RequireAndVerifyClientCert)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := PeerIdentity(r.TLS)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.WriteString(w, id.String())
+	}))
+	srv.TLS = serverConfig
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func(r *Reloader) (string, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: r.ClientConfig("127.0.0.1")}}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	worker, err := NewReloader(workerCert, workerKey, caPath)
+	require.NoError(t, err)
+	body, err := get(worker)
+	require.NoError(t, err)
+	assert.Equal(t, "worker:w1", body)
+
+	// Without a client certificate the handshake fails
+	anonymous, err := NewReloader("", "", caPath)
+	require.NoError(t, err)
+	_, err = get(anonymous)
+	assert.Error(t, err)
+
+	// A certificate from another CA is refused, and the client refuses a
+	// server its CA did not issue
+	other, otherDir := testCA(t)
+	strangerCert, strangerKey := issueFiles(t, other, otherDir, IssueRequest{Kind: KindWorker, Name: "w2"})
+	stranger, err := NewReloader(strangerCert, strangerKey, caPath)
+	require.NoError(t, err)
+	_, err = get(stranger)
+	assert.Error(t, err)
+	distrustful, err := NewReloader(workerCert, workerKey, filepath.Join(otherDir, CACertFile))
+	require.NoError(t, err)
+	_, err = get(distrustful)
+	assert.Error(t, err)
+}
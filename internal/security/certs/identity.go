@@ -0,0 +1,106 @@
+package certs
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Certificate kinds. Workers and services authenticate with their
+// certificates; servers only present them.
+const (
+	KindWorker  = "worker"
+	KindService = "service"
+	KindServer  = "server"
+)
+
+// TrustDomain is the SPIFFE trust domain of identity URIs
+const TrustDomain = "agentflow"
+
+// ErrNoIdentity is returned for certificates that carry no AgentFlow
+// identity, such as server certificates
+var ErrNoIdentity = errors.New("certificate carries no AgentFlow identity")
+
+// Identity is the worker or service a client certificate was issued to. It
+// is carried as a SPIFFE-style URI SAN:
+//
+//	spiffe://agentflow/worker/<name>
+//	spiffe://agentflow/service/<name>
+//	spiffe://agentflow/tenant/<tenant id>/service/<name>
+type Identity struct {
+	Kind     string
+	Name     string
+	TenantID string
+}
+
+// String returns the identity as "<kind>:<name>"
+func (id Identity) String() string {
+	return id.Kind + ":" + id.Name
+}
+
+// URI returns the URI SAN the identity is carried as
+func (id Identity) URI() (*url.URL, error) {
+	if id.Kind != KindWorker && id.Kind != KindService {
+		return nil, fmt.Errorf("identity kind must be %s or %s, not %q", KindWorker, KindService, id.Kind)
+	}
+	if !validSegment(id.Name) {
+		return nil, fmt.Errorf("invalid identity name %q", id.Name)
+	}
+	path := "/" + id.Kind + "/" + id.Name
+	if id.TenantID != "" {
+		if !validSegment(id.TenantID) {
+			return nil, fmt.Errorf("invalid tenant ID %q", id.TenantID)
+		}
+		path = "/tenant/" + id.TenantID + path
+	}
+	return &url.URL{Scheme: "spiffe", Host: TrustDomain, Path: path}, nil
+}
+
+// IdentityFromCertificate returns the identity of a verified client
+// certificate
+func IdentityFromCertificate(cert *x509.Certificate) (Identity, error) {
+	for _, uri := range cert.URIs {
+		if uri.Scheme != "spiffe" || uri.Host != TrustDomain {
+			continue
+		}
+		return parseIdentityPath(uri.Path)
+	}
+	return Identity{}, ErrNoIdentity
+}
+
+func parseIdentityPath(path string) (Identity, error) {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	var id Identity
+	if len(segments) == 4 && segments[0] == "tenant" {
+		if !validSegment(segments[1]) {
+			return Identity{}, fmt.Errorf("%w: unrecognized identity path %q", ErrNoIdentity, path)
+		}
+		id.TenantID = segments[1]
+		segments = segments[2:]
+	}
+	if len(segments) != 2 || (segments[0] != KindWorker && segments[0] != KindService) {
+		return Identity{}, fmt.Errorf("%w: unrecognized identity path %q", ErrNoIdentity, path)
+	}
+	id.Kind, id.Name = segments[0], segments[1]
+	if !validSegment(id.Name) {
+		return Identity{}, fmt.Errorf("%w: unrecognized identity path %q", ErrNoIdentity, path)
+	}
+	return id, nil
+}
+
+// validSegment reports whether s can be a path segment of an identity URI
+func validSegment(s string) bool {
+	if s == "" || len(s) > 128 {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,210 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultReloadInterval is how often Start re-reads certificate files
+const DefaultReloadInterval = time.Minute
+
+// Reloader keeps a certificate, its key and a CA bundle loaded from files
+// and re-reads them so renewed certificates are used without a restart.
+// Connections already established keep the certificate they started with.
+type Reloader struct {
+	certPath string
+	keyPath  string
+	caPath   string
+
+	reloadMu sync.Mutex // serializes reloads
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	pool *x509.CertPool
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{} // nil until Start is called
+}
+
+// NewReloader loads the certificate and key at certPath and keyPath and
+// the CA bundle at caPath. caPath may be empty when peers are not verified,
+// and certPath and keyPath may be empty for a client without a certificate.
+func NewReloader(certPath, keyPath, caPath string) (*Reloader, error) {
+	if (certPath == "") != (keyPath == "") {
+		return nil, errors.New("certificate and key paths must be set together")
+	}
+	r := &Reloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+		caPath:   caPath,
+		stop:     make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again. On failure the current certificates are
+// kept, so a half-written renewal never takes a listener down.
+func (r *Reloader) Reload() error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	var cert *tls.Certificate
+	if r.certPath != "" {
+		loaded, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+		if err != nil {
+			return fmt.Errorf("failed to load certificate: %w", err)
+		}
+		if loaded.Leaf, err = x509.ParseCertificate(loaded.Certificate[0]); err != nil {
+			return fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		cert = &loaded
+	}
+	var pool *x509.CertPool
+	if r.caPath != "" {
+		data, err := os.ReadFile(r.caPath)
+		if err != nil {
+			return fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		cas, err := parseCertificates(data)
+		if err != nil {
+			return fmt.Errorf("failed to load CA bundle %s: %w", r.caPath, err)
+		}
+		pool = x509.NewCertPool()
+		for _, ca := range cas {
+			pool.AddCert(ca)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.pool = cert, pool
+	r.mu.Unlock()
+	return nil
+}
+
+// Certificate returns the current certificate, or nil if none is configured
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+// Pool returns the current CA pool, or nil if none is configured
+func (r *Reloader) Pool() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pool
+}
+
+// ServerConfig returns a TLS configuration for a listener that serves the
+// current certificate and verifies client certificates against the current
+// CA bundle according to clientAuth
+func (r *Reloader) ServerConfig(clientAuth tls.ClientAuthType) (*tls.Config, error) {
+	if r.certPath == "" {
+		return nil, errors.New("a server certificate is required")
+	}
+	if clientAuth >= tls.VerifyClientCertIfGiven && r.caPath == "" {
+		return nil, errors.New("a client CA bundle is required to verify client certificates")
+	}
+	base := &tls.Config{MinVersion: tls.VersionTLS12}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		cfg := &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ClientAuth: clientAuth,
+			ClientCAs:  r.Pool(),
+		}
+		if cert := r.Certificate(); cert != nil {
+			cfg.Certificates = []tls.Certificate{*cert}
+		}
+		return cfg, nil
+	}
+	return base, nil
+}
+
+// ClientConfig returns a TLS configuration for dialing serverName. It
+// presents the current certificate, if any, and verifies the server against
+// the current CA bundle, or the system roots when none is configured.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert := r.Certificate(); cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+	}
+	if r.caPath == "" {
+		return cfg
+	}
+	// The CA pool can change between handshakes, so verification happens
+	// in VerifyConnection against the pool current at that moment
+	cfg.InsecureSkipVerify = true
+	cfg.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return errors.New("server presented no certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         r.Pool(),
+			DNSName:       state.ServerName,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range state.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := state.PeerCertificates[0].Verify(opts)
+		return err
+	}
+	return cfg
+}
+
+// Start reloads the files every interval until Close is called. A failed
+// reload keeps the current certificates and is retried on the next tick.
+func (r *Reloader) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				_ = r.Reload()
+			}
+		}
+	}()
+}
+
+// Close stops periodic reloads
+func (r *Reloader) Close() error {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+		if r.done != nil {
+			<-r.done
+		}
+	})
+	return nil
+}
+
+// PeerIdentity returns the identity of the verified client certificate of
+// a TLS connection
+func PeerIdentity(state *tls.ConnectionState) (Identity, error) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return Identity{}, ErrNoIdentity
+	}
+	return IdentityFromCertificate(state.VerifiedChains[0][0])
+}
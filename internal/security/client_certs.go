@@ -0,0 +1,61 @@
+package security
+
+import (
+	"net/http"
+
+	"github.com/agentflow/agentflow/internal/security/certs"
+)
+
+// ClientCertIssuer is the issuer of claims built from a client certificate
+const ClientCertIssuer = "agentflow-mtls"
+
+// DefaultClientCertPermissions are the permissions granted to each kind of
+// client certificate identity unless configured otherwise
+var DefaultClientCertPermissions = map[string][]string{
+	certs.KindWorker:  {"workflows:read", "workflows:execute", "agents:read", "tools:execute"},
+	certs.KindService: {"workflows:read", "agents:read"},
+}
+
+// IsClientCertificate reports whether the claims were authenticated with a
+// TLS client certificate
+func (c *AgentFlowClaims) IsClientCertificate() bool {
+	return c.Issuer == ClientCertIssuer
+}
+
+// WithClientCertificates authenticates requests that carry no Authorization
+// header with the verified TLS client certificate of the connection. The
+// identity's kind is its role, and permissions maps each kind to the
+// permissions it is granted; a nil map uses DefaultClientCertPermissions.
+// Like API key scopes, these are not resolved through the authorizer.
+func (am *AuthMiddleware) WithClientCertificates(permissions map[string][]string) *AuthMiddleware {
+	if permissions == nil {
+		permissions = DefaultClientCertPermissions
+	}
+	am.clientCerts = permissions
+	return am
+}
+
+// clientCertClaims returns claims for the verified client certificate of r,
+// or nil if client certificates are not accepted or r has none
+func (am *AuthMiddleware) clientCertClaims(r *http.Request) *AgentFlowClaims {
+	if am.clientCerts == nil {
+		return nil
+	}
+	id, err := certs.PeerIdentity(r.TLS)
+	if err != nil {
+		return nil
+	}
+	permissions, ok := am.clientCerts[id.Kind]
+	if !ok {
+		return nil
+	}
+	claims := &AgentFlowClaims{
+		TenantID:    id.TenantID,
+		UserID:      id.String(),
+		Roles:       []string{id.Kind},
+		Permissions: append([]string(nil), permissions...),
+	}
+	claims.Issuer = ClientCertIssuer
+	claims.Subject = id.String()
+	return claims
+}
@@ -0,0 +1,82 @@
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/security/certs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// verifiedConnection returns the state of a TLS connection whose client
+// presented a verified certificate issued for req
+func verifiedConnection(t *testing.T, ca *certs.CA, req certs.IssueRequest) *tls.ConnectionState {
+	t.Helper()
+	certPEM, _, err := ca.Issue(req)
+	require.NoError(t, err)
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert, ca.Cert}},
+	}
+}
+
+func TestAuthMiddleware_ClientCertificates(t *testing.T) {
+	ca, err := certs.NewCA("test CA", time.Hour)
+	require.NoError(t, err)
+	worker := verifiedConnection(t, ca, certs.IssueRequest{Kind: certs.KindWorker, Name: "w1"})
+	service := verifiedConnection(t, ca, certs.IssueRequest{Kind: certs.KindService, Name: "billing", TenantID: rbacTestTenant})
+
+	// Certificate identities cannot hold role bindings either, so the
+	// authorizer must not replace their permissions
+	middleware := NewAuthMiddleware(newRefreshTestAuthenticator(), logging.NewLogger(), nil).
+		WithAuthorizer(NewRBACAuthorizer(&rbacQueries{})).
+		WithClientCertificates(nil)
+
+	var seen *AgentFlowClaims
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetClaimsFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(m *AuthMiddleware, handler http.Handler, state *tls.ConnectionState) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/workflows", nil)
+		req.TLS = state
+		w := httptest.NewRecorder()
+		m.Middleware()(handler).ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(middleware, middleware.RequirePermission("workflows", "execute")(ok), worker)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "worker:w1", seen.UserID)
+	assert.Empty(t, seen.TenantID)
+	assert.True(t, seen.IsClientCertificate())
+	assert.Equal(t, certs.KindWorker, seen.ActorType())
+
+	assert.Equal(t, http.StatusOK, serve(middleware, middleware.RequirePermission("workflows", "read")(ok), service).Code)
+	assert.Equal(t, rbacTestTenant, seen.TenantID)
+	assert.Equal(t, http.StatusForbidden, serve(middleware, middleware.RequirePermission("workflows", "execute")(ok), service).Code)
+
+	// Unverified certificates and plain connections do not authenticate
+	unverified := &tls.ConnectionState{PeerCertificates: worker.PeerCertificates}
+	assert.Equal(t, http.StatusUnauthorized, serve(middleware, ok, unverified).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(middleware, ok, nil).Code)
+
+	// Kinds without configured permissions are refused
+	workersOnly := NewAuthMiddleware(newRefreshTestAuthenticator(), logging.NewLogger(), nil).
+		WithClientCertificates(map[string][]string{certs.KindWorker: {"tools:execute"}})
+	assert.Equal(t, http.StatusUnauthorized, serve(workersOnly, ok, service).Code)
+
+	// Without WithClientCertificates certificates are ignored
+	plain := NewAuthMiddleware(newRefreshTestAuthenticator(), logging.NewLogger(), nil)
+	assert.Equal(t, http.StatusUnauthorized, serve(plain, ok, worker).Code)
+}
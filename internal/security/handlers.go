@@ -3,16 +3,21 @@ package security
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/audit"
 )
 
 // AuthHandlers provides HTTP handlers for authentication endpoints
 type AuthHandlers struct {
 	authenticator Authenticator
 	logger        logging.Logger
+	recorder      audit.Recorder
+	login         *LoginService
 }
 
 // NewAuthHandlers creates new authentication handlers
@@ -23,6 +28,20 @@ func NewAuthHandlers(authenticator Authenticator, logger logging.Logger) *AuthHa
 	}
 }
 
+// WithRecorder records token issue, validation and revocation in the audit
+// trail. Issued tokens are only returned once their audit record is written.
+func (ah *AuthHandlers) WithRecorder(recorder audit.Recorder) *AuthHandlers {
+	ah.recorder = recorder
+	return ah
+}
+
+// WithLogin serves password logins through login. Without it, logins are
+// refused with 503.
+func (ah *AuthHandlers) WithLogin(login *LoginService) *AuthHandlers {
+	ah.login = login
+	return ah
+}
+
 // TokenRequest represents the request body for token issuance
 type TokenIssueRequest struct {
 	TenantID    string   `json:"tenant_id"`
@@ -32,13 +51,22 @@ type TokenIssueRequest struct {
 	ExpiresIn   string   `json:"expires_in,omitempty"` // Duration string like "24h"
 }
 
-// HandleTokenIssue handles POST /api/v1/auth/token
+// HandleTokenIssue handles POST /api/v1/auth/token. The caller must be
+// authenticated: admins may issue tokens for users of their own tenant, and
+// other users only for themselves, with roles and permissions they hold.
+// Users sign in with HandleLogin.
 func (ah *AuthHandlers) HandleTokenIssue(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		ah.writeError(w, "method_not_allowed", "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	caller := GetClaimsFromContext(r.Context())
+	if caller == nil {
+		ah.writeError(w, "unauthenticated", "Authentication required; sign in with /api/v1/auth/login", http.StatusUnauthorized)
+		return
+	}
+
 	var req TokenIssueRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		ah.writeError(w, "invalid_request", "Invalid JSON request body", http.StatusBadRequest)
@@ -56,22 +84,33 @@ func (ah *AuthHandlers) HandleTokenIssue(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Default roles are checked like requested ones
+	if len(req.Roles) == 0 {
+		req.Roles = []string{"viewer"}
+	}
+
+	if reason := ah.issueDenial(r, caller, &req); reason != "" {
+		event := claimsAuditEvent(r, caller, AuditActionTokenIssue, "token", audit.OutcomeDenied)
+		event.Details["reason"] = reason
+		event.Details["subject_tenant_id"] = req.TenantID
+		event.Details["subject_user_id"] = req.UserID
+		event.Critical = true
+		_ = recordAudit(r.Context(), ah.recorder, event)
+		ah.writeError(w, "insufficient_permissions", reason, http.StatusForbidden)
+		return
+	}
+
 	// Parse expires_in duration
 	var expiresIn int64
 	if req.ExpiresIn != "" {
 		duration, err := time.ParseDuration(req.ExpiresIn)
-		if err != nil {
+		if err != nil || duration < 0 {
 			ah.writeError(w, "invalid_expires_in", "Invalid expires_in duration format", http.StatusBadRequest)
 			return
 		}
 		expiresIn = int64(duration.Seconds())
 	}
 
-	// Set default roles if none provided
-	if len(req.Roles) == 0 {
-		req.Roles = []string{"viewer"}
-	}
-
 	// Create token request
 	tokenReq := &TokenRequest{
 		TenantID:    req.TenantID,
@@ -81,21 +120,44 @@ func (ah *AuthHandlers) HandleTokenIssue(w http.ResponseWriter, r *http.Request)
 		ExpiresIn:   expiresIn,
 	}
 
+	event := claimsAuditEvent(r, caller, AuditActionTokenIssue, "token", "")
+	event.ResourceID = req.UserID
+	event.Details["subject_user_id"] = req.UserID
+	event.Details["roles"] = req.Roles
+	event.Details["permissions"] = req.Permissions
+	event.Critical = true
+
 	// Issue token
 	tokenResp, err := ah.authenticator.IssueToken(r.Context(), tokenReq)
+	if errors.Is(err, ErrTokenLifetime) {
+		ah.writeError(w, "invalid_expires_in", err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		ah.logger.Error("Failed to issue token", err,
 			logging.String("tenant_id", req.TenantID),
 			logging.String("user_id", req.UserID),
 		)
+		event.Outcome = audit.OutcomeFailure
+		event.Details["error"] = err.Error()
+		_ = recordAudit(r.Context(), ah.recorder, event)
 		ah.writeError(w, "token_issuance_failed", "Failed to issue token", http.StatusInternalServerError)
 		return
 	}
 
+	// A token without an audit record is never handed out
+	event.Outcome = audit.OutcomeSuccess
+	event.Details["expires_in"] = tokenResp.ExpiresIn
+	if err := recordAudit(r.Context(), ah.recorder, event); err != nil {
+		ah.writeError(w, "audit_unavailable", "Failed to record token issuance", http.StatusServiceUnavailable)
+		return
+	}
+
 	// Log successful token issuance
 	ah.logger.Info("Token issued successfully",
 		logging.String("tenant_id", req.TenantID),
 		logging.String("user_id", req.UserID),
+		logging.String("issued_by", caller.UserID),
 		logging.Any("roles", req.Roles),
 		logging.Any("expires_in", tokenResp.ExpiresIn),
 	)
@@ -104,6 +166,209 @@ func (ah *AuthHandlers) HandleTokenIssue(w http.ResponseWriter, r *http.Request)
 	ah.writeSuccess(w, tokenResp)
 }
 
+// issueDenial returns why caller may not issue the requested token, or ""
+// if it may. Tokens are never issued across tenants or by service accounts
+// and certificate identities, which have credentials of their own.
+func (ah *AuthHandlers) issueDenial(r *http.Request, caller *AgentFlowClaims, req *TokenIssueRequest) string {
+	if caller.IsServiceAccount() {
+		return "Service accounts cannot issue tokens"
+	}
+	if caller.IsClientCertificate() {
+		return "Certificate identities cannot issue tokens"
+	}
+	if req.TenantID != caller.TenantID {
+		return "Tokens can only be issued within the caller's tenant"
+	}
+	roles := requestRoles(r.Context(), caller)
+	if slices.Contains(roles, AdminRole) {
+		return ""
+	}
+	if req.UserID != caller.UserID {
+		return "Only admins can issue tokens for other users"
+	}
+	for _, role := range req.Roles {
+		if !slices.Contains(roles, role) {
+			return "Cannot issue a token with role " + role
+		}
+	}
+	permissions := requestPermissions(r.Context(), caller)
+	for _, permission := range req.Permissions {
+		if !GrantsScope(permissions, permission) {
+			return "Cannot issue a token with permission " + permission
+		}
+	}
+	return ""
+}
+
+// LoginRequest represents the request body for password login
+type LoginRequest struct {
+	TenantID string `json:"tenant_id"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// HandleLogin handles POST /api/v1/auth/login. The user's password is
+// checked against users.hashed_secret, and the token carries the roles and
+// permissions bound to the user.
+func (ah *AuthHandlers) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		ah.writeError(w, "method_not_allowed", "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if ah.login == nil {
+		ah.writeError(w, "login_unavailable", "Password login requires a database", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ah.writeError(w, "invalid_request", "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+	if req.TenantID == "" || req.Email == "" || req.Password == "" {
+		ah.writeError(w, "invalid_request", "tenant_id, email and password are required", http.StatusBadRequest)
+		return
+	}
+
+	// Failed logins are recorded against the email, since there may be no
+	// user to name
+	event := audit.Event{
+		TenantID:     req.TenantID,
+		ActorType:    "user",
+		ActorID:      req.Email,
+		Action:       AuditActionLogin,
+		ResourceType: "user",
+		Details:      map[string]interface{}{"remote_addr": r.RemoteAddr},
+		Critical:     true,
+	}
+
+	result, err := ah.login.Login(r.Context(), req.TenantID, req.Email, req.Password)
+	var locked *AccountLockedError
+	switch {
+	case errors.As(err, &locked), errors.Is(err, ErrInvalidCredentials):
+		// A locked account gets the same response as a wrong password, so
+		// the response does not reveal which emails have accounts; only the
+		// audit trail records the lock
+		event.Outcome = audit.OutcomeDenied
+		event.Details["reason"] = "invalid_credentials"
+		if locked != nil {
+			event.Details["reason"] = "account_locked"
+			event.Details["locked_until"] = locked.Until.UTC().Format(time.RFC3339)
+		}
+		_ = recordAudit(r.Context(), ah.recorder, event)
+		ah.writeError(w, "invalid_credentials", "Invalid email or password", http.StatusUnauthorized)
+		return
+	case err != nil:
+		ah.logger.Error("Failed to log in", err, logging.String("tenant_id", req.TenantID))
+		ah.writeError(w, "login_failed", "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	tokenResp, err := ah.authenticator.IssueToken(r.Context(), &TokenRequest{
+		TenantID:    result.TenantID,
+		UserID:      result.UserID,
+		Roles:       result.Roles,
+		Permissions: result.Permissions,
+	})
+	if err != nil {
+		ah.logger.Error("Failed to issue token", err, logging.String("user_id", result.UserID))
+		ah.writeError(w, "token_issuance_failed", "Failed to issue token", http.StatusInternalServerError)
+		return
+	}
+
+	// As with issuance, tokens are only handed out once recorded
+	event.TenantID = result.TenantID
+	event.ActorID = result.UserID
+	event.ResourceID = result.UserID
+	event.Outcome = audit.OutcomeSuccess
+	event.Details["roles"] = result.Roles
+	if err := recordAudit(r.Context(), ah.recorder, event); err != nil {
+		ah.writeError(w, "audit_unavailable", "Failed to record login", http.StatusServiceUnavailable)
+		return
+	}
+
+	ah.logger.Info("User logged in",
+		logging.String("tenant_id", result.TenantID),
+		logging.String("user_id", result.UserID),
+		logging.Any("roles", result.Roles),
+	)
+	ah.writeSuccess(w, tokenResp)
+}
+
+// TokenRefreshRequest represents the request body for token refresh
+type TokenRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// HandleTokenRefresh handles POST /api/v1/auth/refresh. The refresh token is
+// rotated: the response carries a new one and the old one stops working.
+func (ah *AuthHandlers) HandleTokenRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		ah.writeError(w, "method_not_allowed", "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TokenRefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ah.writeError(w, "invalid_request", "Invalid JSON request body", http.StatusBadRequest)
+		return
+	}
+	if req.RefreshToken == "" {
+		ah.writeError(w, "missing_refresh_token", "refresh_token is required", http.StatusBadRequest)
+		return
+	}
+
+	tokenResp, err := ah.authenticator.RefreshToken(r.Context(), req.RefreshToken)
+	var reuse *RefreshReuseError
+	switch {
+	case errors.As(err, &reuse):
+		ah.logger.Warn("Refresh token reused, token family revoked",
+			logging.String("tenant_id", reuse.TenantID),
+			logging.String("user_id", reuse.UserID),
+			logging.String("family_id", reuse.FamilyID),
+		)
+		_ = recordAudit(r.Context(), ah.recorder, audit.Event{
+			TenantID:     reuse.TenantID,
+			ActorType:    "user",
+			ActorID:      reuse.UserID,
+			Action:       AuditActionTokenRefresh,
+			ResourceType: "token_family",
+			ResourceID:   reuse.FamilyID,
+			Outcome:      audit.OutcomeDenied,
+			Details: map[string]interface{}{
+				"reason":      "refresh_token_reused",
+				"remote_addr": r.RemoteAddr,
+			},
+			Critical: true,
+		})
+		ah.writeError(w, "refresh_token_reused", "Refresh token was already used; sign in again", http.StatusUnauthorized)
+		return
+	case errors.Is(err, ErrRefreshTokenInvalid):
+		ah.writeError(w, "invalid_refresh_token", "Refresh token is invalid or expired", http.StatusUnauthorized)
+		return
+	case err != nil:
+		ah.logger.Error("Failed to refresh token", err)
+		ah.writeError(w, "token_refresh_failed", "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	// As with issuance, new tokens are only handed out once recorded
+	if ah.recorder != nil {
+		claims, err := ah.authenticator.ValidateToken(r.Context(), tokenResp.AccessToken)
+		if err == nil {
+			event := tokenAuditEvent(r, claims, AuditActionTokenRefresh, audit.OutcomeSuccess)
+			event.Critical = true
+			err = recordAudit(r.Context(), ah.recorder, event)
+		}
+		if err != nil {
+			ah.writeError(w, "audit_unavailable", "Failed to record token refresh", http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	ah.writeSuccess(w, tokenResp)
+}
+
 // HandleTokenValidate handles POST /api/v1/auth/validate
 func (ah *AuthHandlers) HandleTokenValidate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -134,6 +399,8 @@ func (ah *AuthHandlers) HandleTokenValidate(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	_ = recordAudit(r.Context(), ah.recorder, tokenAuditEvent(r, claims, AuditActionTokenValidate, audit.OutcomeSuccess))
+
 	// Return validation result
 	validationResult := map[string]interface{}{
 		"valid":       true,
@@ -168,14 +435,37 @@ func (ah *AuthHandlers) HandleTokenRevoke(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// The token's claims name the tenant whose audit trail records the
+	// revocation; a token that no longer validates is revoked unrecorded
+	var claims *AgentFlowClaims
+	if ah.recorder != nil {
+		claims, _ = ah.authenticator.ValidateToken(r.Context(), token)
+	}
+
 	// Revoke token
-	if err := ah.authenticator.RevokeToken(r.Context(), token); err != nil {
+	err = ah.authenticator.RevokeToken(r.Context(), token)
+	if errors.Is(err, ErrTokenNotRevocable) {
+		ah.writeError(w, "invalid_token", err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
 		ah.logger.Error("Failed to revoke token", err)
+		if claims != nil {
+			event := tokenAuditEvent(r, claims, AuditActionTokenRevoke, audit.OutcomeFailure)
+			event.Details["error"] = err.Error()
+			event.Critical = true
+			_ = recordAudit(r.Context(), ah.recorder, event)
+		}
 		ah.writeError(w, "revocation_failed", "Failed to revoke token", http.StatusInternalServerError)
 		return
 	}
 
 	ah.logger.Info("Token revoked successfully")
+	if claims != nil {
+		event := tokenAuditEvent(r, claims, AuditActionTokenRevoke, audit.OutcomeSuccess)
+		event.Critical = true
+		_ = recordAudit(r.Context(), ah.recorder, event)
+	}
 
 	// Return success response
 	ah.writeSuccess(w, map[string]interface{}{
@@ -250,7 +540,9 @@ func RegisterAuthRoutes(router interface{}, handlers *AuthHandlers, authMiddlewa
 // GetAuthEndpoints returns a map of authentication endpoints and their handlers
 func GetAuthEndpoints(handlers *AuthHandlers, authMiddleware *AuthMiddleware) map[string]http.HandlerFunc {
 	return map[string]http.HandlerFunc{
-		"POST /api/v1/auth/token":    handlers.HandleTokenIssue,
+		"POST /api/v1/auth/login":    handlers.HandleLogin,
+		"POST /api/v1/auth/token":    authMiddleware.Middleware()(http.HandlerFunc(handlers.HandleTokenIssue)).ServeHTTP,
+		"POST /api/v1/auth/refresh":  handlers.HandleTokenRefresh,
 		"POST /api/v1/auth/validate": handlers.HandleTokenValidate,
 		"POST /api/v1/auth/revoke":   handlers.HandleTokenRevoke,
 		"GET /api/v1/auth/userinfo":  authMiddleware.Middleware()(http.HandlerFunc(handlers.HandleUserInfo)).ServeHTTP,
@@ -40,7 +40,7 @@ func TestAuthHandlers(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 
-		handlers.HandleTokenIssue(w, req)
+		handlers.HandleTokenIssue(w, asCaller(req, "tenant123", "admin1", AdminRole))
 
 		assert.Equal(t, http.StatusOK, w.Code)
 
@@ -68,7 +68,7 @@ func TestAuthHandlers(t *testing.T) {
 		req := httptest.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader([]byte("invalid json")))
 		w := httptest.NewRecorder()
 
-		handlers.HandleTokenIssue(w, req)
+		handlers.HandleTokenIssue(w, asCaller(req, "tenant123", "admin1", AdminRole))
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 		assert.Contains(t, w.Body.String(), "invalid_request")
@@ -83,7 +83,7 @@ func TestAuthHandlers(t *testing.T) {
 		req := httptest.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader(body))
 		w := httptest.NewRecorder()
 
-		handlers.HandleTokenIssue(w, req)
+		handlers.HandleTokenIssue(w, asCaller(req, "tenant123", "admin1", AdminRole))
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 		assert.Contains(t, w.Body.String(), "missing_tenant_id")
@@ -98,7 +98,7 @@ func TestAuthHandlers(t *testing.T) {
 		req := httptest.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader(body))
 		w := httptest.NewRecorder()
 
-		handlers.HandleTokenIssue(w, req)
+		handlers.HandleTokenIssue(w, asCaller(req, "tenant123", "admin1", AdminRole))
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 		assert.Contains(t, w.Body.String(), "missing_user_id")
@@ -115,12 +115,72 @@ func TestAuthHandlers(t *testing.T) {
 		req := httptest.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader(body))
 		w := httptest.NewRecorder()
 
-		handlers.HandleTokenIssue(w, req)
+		handlers.HandleTokenIssue(w, asCaller(req, "tenant123", "admin1", AdminRole))
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 		assert.Contains(t, w.Body.String(), "invalid_expires_in")
 	})
 
+	t.Run("HandleTokenIssue_ExpiresInAboveTokenExpiry", func(t *testing.T) {
+		for _, expiresIn := range []string{"8760h", "-1h"} {
+			body, _ := json.Marshal(TokenIssueRequest{TenantID: "tenant123", UserID: "admin1", Roles: []string{AdminRole}, ExpiresIn: expiresIn})
+			req := httptest.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handlers.HandleTokenIssue(w, asCaller(req, "tenant123", "admin1", AdminRole))
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, expiresIn)
+			assert.Contains(t, w.Body.String(), "invalid_expires_in")
+		}
+	})
+
+	t.Run("HandleTokenIssue_RequiresCaller", func(t *testing.T) {
+		issue := func(req TokenIssueRequest, caller func(*http.Request) *http.Request) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(req)
+			w := httptest.NewRecorder()
+			handlers.HandleTokenIssue(w, caller(httptest.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader(body))))
+			return w
+		}
+		anonymous := func(r *http.Request) *http.Request { return r }
+		developer := func(r *http.Request) *http.Request {
+			r = asCaller(r, "tenant123", "user456", "developer")
+			return r.WithContext(context.WithValue(r.Context(), "user_permissions", []string{"workflows:read"}))
+		}
+		admin := func(r *http.Request) *http.Request { return asCaller(r, "tenant123", "admin1", AdminRole) }
+
+		w := issue(TokenIssueRequest{TenantID: "tenant123", UserID: "user456"}, anonymous)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		// Users can only issue tokens for themselves, with what they hold
+		w = issue(TokenIssueRequest{TenantID: "tenant123", UserID: "user456", Roles: []string{"developer"}, Permissions: []string{"workflows:wf-1:read"}}, developer)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		for _, req := range []TokenIssueRequest{
+			{TenantID: "tenant123", UserID: "other"},
+			{TenantID: "tenant123", UserID: "user456", Roles: []string{AdminRole}},
+			{TenantID: "tenant123", UserID: "user456", Permissions: []string{"workflows:write"}},
+			// The default viewer role is checked like a requested one
+			{TenantID: "tenant123", UserID: "user456"},
+		} {
+			w = issue(req, developer)
+			assert.Equal(t, http.StatusForbidden, w.Code, "%+v", req)
+		}
+
+		// Admins issue for anyone in their tenant, but not across tenants
+		w = issue(TokenIssueRequest{TenantID: "tenant123", UserID: "other", Roles: []string{AdminRole}}, admin)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = issue(TokenIssueRequest{TenantID: "tenant999", UserID: "other"}, admin)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// Certificate identities have no use for tokens, even as their own user
+		worker := func(r *http.Request) *http.Request {
+			claims := &AgentFlowClaims{TenantID: "tenant123", UserID: "worker:w1", Roles: []string{AdminRole}}
+			claims.Issuer = ClientCertIssuer
+			return r.WithContext(context.WithValue(r.Context(), "auth_claims", claims))
+		}
+		w = issue(TokenIssueRequest{TenantID: "tenant123", UserID: "worker:w1"}, worker)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
 	t.Run("HandleTokenValidate_Success", func(t *testing.T) {
 		// First issue a token
 		tokenReq := &TokenRequest{
@@ -263,6 +323,12 @@ func TestAuthHandlers(t *testing.T) {
 	})
 }
 
+// asCaller returns req as sent by an authenticated user with roles
+func asCaller(req *http.Request, tenantID, userID string, roles ...string) *http.Request {
+	claims := &AgentFlowClaims{TenantID: tenantID, UserID: userID, Roles: roles}
+	return req.WithContext(context.WithValue(req.Context(), "auth_claims", claims))
+}
+
 func TestGetAuthEndpoints(t *testing.T) {
 	config := &AuthConfig{
 		JWTSecret:   "test-secret-32-characters-long",
@@ -278,7 +344,9 @@ func TestGetAuthEndpoints(t *testing.T) {
 	endpoints := GetAuthEndpoints(handlers, middleware)
 
 	expectedEndpoints := []string{
+		"POST /api/v1/auth/login",
 		"POST /api/v1/auth/token",
+		"POST /api/v1/auth/refresh",
 		"POST /api/v1/auth/validate",
 		"POST /api/v1/auth/revoke",
 		"GET /api/v1/auth/userinfo",
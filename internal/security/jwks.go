@@ -0,0 +1,422 @@
+package security
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JSONWebKey is a public key in JSON Web Key form (RFC 7517). RSA, EC and
+// Ed25519 OKP keys are supported.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA modulus and exponent
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP curve and coordinates
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is a JWK set as served from a jwks_uri
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// PublicKey decodes the key into an *rsa.PublicKey, *ecdsa.PublicKey or
+// ed25519.PublicKey
+func (k JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		if n.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA key of %d bits is too short", n.BitLen())
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %q", k.Crv)
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %w", err)
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Ed25519 key: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Ed25519 key is %d bytes, want %d", len(x), ed25519.PublicKeySize)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// NewJSONWebKey encodes an *rsa.PublicKey, *ecdsa.PublicKey or
+// ed25519.PublicKey as a signing JWK. alg may be empty.
+func NewJSONWebKey(kid, alg string, key crypto.PublicKey) (JSONWebKey, error) {
+	jwk := JSONWebKey{Kid: kid, Use: "sig", Alg: alg}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.Kty = "EC"
+		jwk.Crv = k.Curve.Params().Name
+		// Coordinates are padded to the size of the curve (RFC 7518 6.2.1.2)
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk.X = base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(k)
+	default:
+		return JSONWebKey{}, fmt.Errorf("unsupported key type %T", key)
+	}
+	return jwk, nil
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing value")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// asymmetricSigningMethods are the algorithms accepted for tokens verified
+// against a JWK set. HMAC is excluded so a public key can never be used as a
+// shared secret.
+var asymmetricSigningMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// checkKeyAlgorithm verifies that a token signed with alg may be verified
+// with key
+func checkKeyAlgorithm(key crypto.PublicKey, alg string) error {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS") {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		want := map[string]string{"P-256": "ES256", "P-384": "ES384", "P-521": "ES512"}[k.Curve.Params().Name]
+		if alg == want {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if alg == jwt.SigningMethodEdDSA.Alg() {
+			return nil
+		}
+	}
+	return fmt.Errorf("algorithm %s does not match the key", alg)
+}
+
+// jwksKey is a verification key from a JWK set
+type jwksKey struct {
+	key crypto.PublicKey
+	alg string // empty if the JWK does not restrict its algorithm
+}
+
+// Timing defaults for the JWKS cache
+const (
+	defaultJWKSRefreshInterval = time.Hour
+	// jwksMinRefetch limits how often an unknown key ID triggers a fetch, so
+	// tokens with made-up key IDs cannot flood the provider
+	jwksMinRefetch = 30 * time.Second
+)
+
+// jwksCache keeps the signing keys published at a jwks_uri. Keys are
+// refreshed in the background and when a token names an unknown key, which
+// picks up rotated keys as soon as they are used.
+type jwksCache struct {
+	uri    string
+	client *http.Client
+	now    func() time.Time
+
+	fetchMu sync.Mutex // serializes fetches
+
+	mu          sync.RWMutex
+	keys        map[string]jwksKey
+	attemptedAt time.Time // last fetch, successful or not
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{} // nil until start is called
+}
+
+func newJWKSCache(uri string, client *http.Client) *jwksCache {
+	return &jwksCache{
+		uri:    uri,
+		client: client,
+		now:    time.Now,
+		keys:   make(map[string]jwksKey),
+		stop:   make(chan struct{}),
+	}
+}
+
+// key returns the key with kid. A token without a key ID may only be
+// verified when the set holds a single key.
+func (c *jwksCache) key(ctx context.Context, kid string) (jwksKey, error) {
+	if key, ok := c.lookup(kid); ok {
+		return key, nil
+	}
+
+	// Callers waiting on the same unknown key share the fetch made by the
+	// first of them
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+	if key, ok := c.lookup(kid); ok {
+		return key, nil
+	}
+	c.mu.RLock()
+	attemptedAt := c.attemptedAt
+	c.mu.RUnlock()
+	if attemptedAt.IsZero() || c.now().Sub(attemptedAt) >= jwksMinRefetch {
+		if err := c.fetch(ctx); err != nil {
+			return jwksKey{}, err
+		}
+		if key, ok := c.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return jwksKey{}, fmt.Errorf("unknown key ID %q", kid)
+}
+
+func (c *jwksCache) lookup(kid string) (jwksKey, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if kid == "" {
+		if len(c.keys) == 1 {
+			for _, key := range c.keys {
+				return key, true
+			}
+		}
+		return jwksKey{}, false
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+// refresh fetches the key set and replaces the cached keys. On failure the
+// cached keys are kept.
+func (c *jwksCache) refresh(ctx context.Context) error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+	return c.fetch(ctx)
+}
+
+// fetch does the work of refresh; fetchMu must be held
+func (c *jwksCache) fetch(ctx context.Context) error {
+	c.mu.Lock()
+	c.attemptedAt = c.now()
+	c.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS request failed with status: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	var set JSONWebKeySet
+	if err := json.Unmarshal(body, &set); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	// Keys for encryption or of unsupported types are skipped rather than
+	// failing the whole set
+	keys := make(map[string]jwksKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = jwksKey{key: pub, alg: k.Alg}
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS contains no usable signing keys")
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+	return nil
+}
+
+// start fetches the keys, then refreshes them every interval until close is
+// called. A failed refresh keeps the current keys and is retried on the next
+// tick.
+func (c *jwksCache) start(interval time.Duration, timeout time.Duration) {
+	if interval <= 0 {
+		interval = defaultJWKSRefreshInterval
+	}
+	refresh := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		_ = c.refresh(ctx)
+	}
+
+	// The first fetch completes before start returns, so the keys are ready
+	// for the first token
+	refresh()
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+}
+
+// close stops background refreshes
+func (c *jwksCache) close() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+		if c.done != nil {
+			<-c.done
+		}
+	})
+}
+
+// JWKSValidator verifies control-plane tokens offline against the keys the
+// control plane publishes at /.well-known/jwks.json. It does not consult the
+// revocation store, so a revoked token is accepted until it expires.
+type JWKSValidator struct {
+	keys *jwksCache
+}
+
+// NewJWKSValidator creates a validator for the keys published at jwksURL,
+// refreshed every refresh
+func NewJWKSValidator(jwksURL string, refresh time.Duration) *JWKSValidator {
+	client := &http.Client{Timeout: 10 * time.Second}
+	keys := newJWKSCache(jwksURL, client)
+	keys.start(refresh, client.Timeout)
+	return &JWKSValidator{keys: keys}
+}
+
+// ValidateToken verifies the signature and claims of a control-plane token
+func (v *JWKSValidator) ValidateToken(ctx context.Context, tokenString string) (*AgentFlowClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &AgentFlowClaims{},
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			key, err := v.keys.key(ctx, kid)
+			if err != nil {
+				return nil, err
+			}
+			if key.alg != "" && key.alg != token.Method.Alg() {
+				return nil, fmt.Errorf("algorithm %s does not match key %s", token.Method.Alg(), kid)
+			}
+			if err := checkKeyAlgorithm(key.key, token.Method.Alg()); err != nil {
+				return nil, err
+			}
+			return key.key, nil
+		},
+		jwt.WithValidMethods(asymmetricSigningMethods),
+		jwt.WithIssuer(TokenIssuer),
+		jwt.WithAudience(TokenAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	claims, ok := token.Claims.(*AgentFlowClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token claims")
+	}
+	if claims.TenantID == "" {
+		return nil, errors.New("missing tenant_id claim")
+	}
+	if claims.UserID == "" {
+		return nil, errors.New("missing user_id claim")
+	}
+	return claims, nil
+}
+
+// Close stops background key refreshes
+func (v *JWKSValidator) Close() error {
+	v.keys.close()
+	return nil
+}
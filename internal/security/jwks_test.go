@@ -0,0 +1,88 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONWebKey_RoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for name, key := range map[string]crypto.PublicKey{
+		"RSA":     &rsaKey.PublicKey,
+		"P-256":   &p256.PublicKey,
+		"P-521":   &p521.PublicKey,
+		"Ed25519": edPublic,
+	} {
+		t.Run(name, func(t *testing.T) {
+			jwk, err := NewJSONWebKey("key-1", "", key)
+			require.NoError(t, err)
+			assert.Equal(t, "sig", jwk.Use)
+
+			// Survives serialization as served from a jwks_uri
+			data, err := json.Marshal(JSONWebKeySet{Keys: []JSONWebKey{jwk}})
+			require.NoError(t, err)
+			var set JSONWebKeySet
+			require.NoError(t, json.Unmarshal(data, &set))
+
+			decoded, err := set.Keys[0].PublicKey()
+			require.NoError(t, err)
+			assert.True(t, decoded.(interface{ Equal(crypto.PublicKey) bool }).Equal(key))
+		})
+	}
+}
+
+func TestJSONWebKey_Invalid(t *testing.T) {
+	shortRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	short, err := NewJSONWebKey("short", "", &shortRSA.PublicKey)
+	require.NoError(t, err)
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	offCurve, err := NewJSONWebKey("ec", "", &p256.PublicKey)
+	require.NoError(t, err)
+	offCurve.Y = offCurve.X
+
+	tests := map[string]JSONWebKey{
+		"short RSA key":        short,
+		"point not on curve":   offCurve,
+		"unknown key type":     {Kty: "oct", X: "c2VjcmV0"},
+		"unsupported curve":    {Kty: "EC", Crv: "secp256k1", X: offCurve.X, Y: offCurve.X},
+		"unsupported OKP":      {Kty: "OKP", Crv: "X25519", X: "AAAA"},
+		"truncated Ed25519":    {Kty: "OKP", Crv: "Ed25519", X: "AAAA"},
+		"missing RSA exponent": {Kty: "RSA", N: short.N},
+	}
+	for name, jwk := range tests {
+		_, err := jwk.PublicKey()
+		assert.Error(t, err, name)
+	}
+}
+
+func TestCheckKeyAlgorithm(t *testing.T) {
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	assert.NoError(t, checkKeyAlgorithm(&p384.PublicKey, "ES384"))
+	assert.Error(t, checkKeyAlgorithm(&p384.PublicKey, "ES256"), "curve must match the algorithm")
+	assert.NoError(t, checkKeyAlgorithm(edPublic, "EdDSA"))
+	assert.Error(t, checkKeyAlgorithm(edPublic, "RS256"))
+	assert.Error(t, checkKeyAlgorithm(&rsa.PublicKey{}, "HS256"))
+}
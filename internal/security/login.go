@@ -0,0 +1,171 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Login errors
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountLocked      = errors.New("account is locked")
+)
+
+// AdminRole is the role allowed to issue tokens for other users
+const AdminRole = "admin"
+
+const (
+	defaultLoginMaxAttempts = 5
+	defaultLoginLockout     = 15 * time.Minute
+)
+
+// AccountLockedError reports a login refused because of repeated failures
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("account is locked until %s", e.Until.UTC().Format(time.RFC3339))
+}
+
+// Is matches ErrAccountLocked
+func (e *AccountLockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// LoginQuerier is the subset of queries.Querier used by the login service
+type LoginQuerier interface {
+	GetUserByEmail(ctx context.Context, arg queries.GetUserByEmailParams) (queries.User, error)
+	RecordFailedLogin(ctx context.Context, arg queries.RecordFailedLoginParams) (pgtype.Timestamptz, error)
+	ResetFailedLogins(ctx context.Context, arg queries.ResetFailedLoginsParams) error
+}
+
+// LoginResult is a user whose credentials were verified, with the roles and
+// permissions bound to them
+type LoginResult struct {
+	TenantID    string
+	UserID      string
+	Roles       []string
+	Permissions []string
+}
+
+// LoginService verifies user passwords against users.hashed_secret and
+// locks accounts after repeated failures
+type LoginService struct {
+	q           LoginQuerier
+	authorizer  Authorizer
+	maxAttempts int
+	lockout     time.Duration
+	now         func() time.Time
+}
+
+// NewLoginService creates a login service. Roles and permissions of users
+// who log in are resolved through authorizer, never taken from the request.
+func NewLoginService(q LoginQuerier, authorizer Authorizer, config *AuthConfig) *LoginService {
+	if config == nil {
+		config = LoadAuthConfigFromEnv()
+	}
+	s := &LoginService{
+		q:           q,
+		authorizer:  authorizer,
+		maxAttempts: config.LoginMaxAttempts,
+		lockout:     config.LoginLockout,
+		now:         time.Now,
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = defaultLoginMaxAttempts
+	}
+	if s.lockout <= 0 {
+		s.lockout = defaultLoginLockout
+	}
+	return s
+}
+
+// dummyPasswordHash is verified against when there is no user to check, so
+// unknown emails take as long to reject as wrong passwords
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("agentflow-dummy-password")
+	return hash
+})
+
+// Login verifies a user's password. Unknown users, users without a password
+// and wrong passwords all return ErrInvalidCredentials; a locked account
+// returns an *AccountLockedError, which callers must not tell apart from
+// ErrInvalidCredentials, so the response does not reveal that it exists.
+func (s *LoginService) Login(ctx context.Context, tenantID, email, password string) (*LoginResult, error) {
+	var tenant pgtype.UUID
+	if tenant.Scan(tenantID) != nil {
+		_, _ = VerifyPassword(dummyPasswordHash(), password)
+		return nil, ErrInvalidCredentials
+	}
+	user, err := s.q.GetUserByEmail(ctx, queries.GetUserByEmailParams{TenantID: tenant, Email: email})
+	if errors.Is(err, pgx.ErrNoRows) {
+		_, _ = VerifyPassword(dummyPasswordHash(), password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	// The password is verified before the lock is checked, so a locked
+	// account takes as long to refuse as an unknown one
+	hasPassword := user.HashedSecret.Valid && user.HashedSecret.String != ""
+	hash := dummyPasswordHash()
+	if hasPassword {
+		hash = user.HashedSecret.String
+	}
+	ok, err := VerifyPassword(hash, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify password of user %s: %w", uuid.UUID(user.ID.Bytes), err)
+	}
+
+	now := s.now()
+	if user.LockedUntil.Valid && now.Before(user.LockedUntil.Time) {
+		return nil, &AccountLockedError{Until: user.LockedUntil.Time}
+	}
+	if !hasPassword {
+		return nil, ErrInvalidCredentials
+	}
+	if !ok {
+		lockedUntil, err := s.q.RecordFailedLogin(ctx, queries.RecordFailedLoginParams{
+			ID:          user.ID,
+			TenantID:    user.TenantID,
+			MaxAttempts: int32(s.maxAttempts),
+			LockUntil:   pgtype.Timestamptz{Time: now.Add(s.lockout), Valid: true},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record failed login: %w", err)
+		}
+		if lockedUntil.Valid && now.Before(lockedUntil.Time) {
+			return nil, &AccountLockedError{Until: lockedUntil.Time}
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.FailedLoginAttempts != 0 || user.LockedUntil.Valid {
+		if err := s.q.ResetFailedLogins(ctx, queries.ResetFailedLoginsParams{ID: user.ID, TenantID: user.TenantID}); err != nil {
+			return nil, fmt.Errorf("failed to reset failed logins: %w", err)
+		}
+	}
+
+	tenantID = uuid.UUID(user.TenantID.Bytes).String()
+	userID := uuid.UUID(user.ID.Bytes).String()
+	effective, err := s.authorizer.EffectivePermissions(ctx, tenantID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve permissions: %w", err)
+	}
+	return &LoginResult{
+		TenantID:    tenantID,
+		UserID:      userID,
+		Roles:       effective.Roles,
+		Permissions: effective.Permissions,
+	}, nil
+}
@@ -0,0 +1,215 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const loginTestEmail = "ada@example.com"
+
+// loginQueries keeps users rows in memory, updating failed logins the way
+// RecordFailedLogin does
+type loginQueries struct {
+	users []queries.User
+	err   error
+}
+
+func (q *loginQueries) user(id, tenantID pgtype.UUID) *queries.User {
+	for i := range q.users {
+		if q.users[i].ID == id && q.users[i].TenantID == tenantID {
+			return &q.users[i]
+		}
+	}
+	return nil
+}
+
+func (q *loginQueries) GetUserByEmail(ctx context.Context, arg queries.GetUserByEmailParams) (queries.User, error) {
+	if q.err != nil {
+		return queries.User{}, q.err
+	}
+	for _, u := range q.users {
+		if u.TenantID == arg.TenantID && u.Email == arg.Email {
+			return u, nil
+		}
+	}
+	return queries.User{}, pgx.ErrNoRows
+}
+
+func (q *loginQueries) RecordFailedLogin(ctx context.Context, arg queries.RecordFailedLoginParams) (pgtype.Timestamptz, error) {
+	u := q.user(arg.ID, arg.TenantID)
+	if u == nil {
+		return pgtype.Timestamptz{}, pgx.ErrNoRows
+	}
+	if u.FailedLoginAttempts+1 >= arg.MaxAttempts {
+		u.FailedLoginAttempts = 0
+		u.LockedUntil = arg.LockUntil
+	} else {
+		u.FailedLoginAttempts++
+	}
+	return u.LockedUntil, nil
+}
+
+func (q *loginQueries) ResetFailedLogins(ctx context.Context, arg queries.ResetFailedLoginsParams) error {
+	if u := q.user(arg.ID, arg.TenantID); u != nil {
+		u.FailedLoginAttempts = 0
+		u.LockedUntil = pgtype.Timestamptz{}
+	}
+	return nil
+}
+
+// newTestLogin returns a login service for a user of the RBAC test tenant
+// with password "s3cret" who is bound to a viewer role
+func newTestLogin(t *testing.T) (*LoginService, *loginQueries) {
+	t.Helper()
+	hash, err := HashPassword("s3cret")
+	require.NoError(t, err)
+	q := &loginQueries{users: []queries.User{{
+		ID:           rbacUUID(rbacTestUser),
+		TenantID:     rbacUUID(rbacTestTenant),
+		Email:        loginTestEmail,
+		Role:         "admin", // ignored: roles come from bindings
+		HashedSecret: pgtype.Text{String: hash, Valid: true},
+	}}}
+	rbac := &rbacQueries{}
+	rbac.addRole(1, "viewer", 0, `["workflows:read"]`)
+	rbac.bind(rbacTestUser, 1)
+	login := NewLoginService(q, NewRBACAuthorizer(rbac), &AuthConfig{LoginMaxAttempts: 3, LoginLockout: time.Minute})
+	return login, q
+}
+
+func TestLoginService_Login(t *testing.T) {
+	login, q := newTestLogin(t)
+	ctx := context.Background()
+
+	result, err := login.Login(ctx, rbacTestTenant, loginTestEmail, "s3cret")
+	require.NoError(t, err)
+	assert.Equal(t, rbacTestUser, result.UserID)
+	assert.Equal(t, rbacTestTenant, result.TenantID)
+	assert.Equal(t, []string{"viewer"}, result.Roles)
+	assert.Equal(t, []string{"workflows:read"}, result.Permissions)
+
+	for _, attempt := range []struct{ tenant, email, password string }{
+		{rbacTestTenant, loginTestEmail, "wrong"},
+		{rbacTestTenant, "nobody@example.com", "s3cret"},
+		{"not-a-uuid", loginTestEmail, "s3cret"},
+	} {
+		_, err := login.Login(ctx, attempt.tenant, attempt.email, attempt.password)
+		assert.ErrorIs(t, err, ErrInvalidCredentials, "%+v", attempt)
+	}
+
+	// Users without a password cannot log in with one
+	q.users[0].HashedSecret = pgtype.Text{}
+	_, err = login.Login(ctx, rbacTestTenant, loginTestEmail, "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	q.err = errors.New("database down")
+	_, err = login.Login(ctx, rbacTestTenant, loginTestEmail, "s3cret")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestLoginService_Lockout(t *testing.T) {
+	login, q := newTestLogin(t)
+	ctx := context.Background()
+	now := time.Now()
+	login.now = func() time.Time { return now }
+
+	// A success resets the count of failures
+	_, err := login.Login(ctx, rbacTestTenant, loginTestEmail, "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = login.Login(ctx, rbacTestTenant, loginTestEmail, "s3cret")
+	require.NoError(t, err)
+	assert.Zero(t, q.users[0].FailedLoginAttempts)
+
+	for i := 0; i < 2; i++ {
+		_, err = login.Login(ctx, rbacTestTenant, loginTestEmail, "wrong")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+	_, err = login.Login(ctx, rbacTestTenant, loginTestEmail, "wrong")
+	var locked *AccountLockedError
+	require.ErrorAs(t, err, &locked)
+	assert.Equal(t, now.Add(time.Minute), locked.Until)
+
+	// While locked even the right password is refused
+	_, err = login.Login(ctx, rbacTestTenant, loginTestEmail, "s3cret")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+
+	now = now.Add(time.Minute)
+	_, err = login.Login(ctx, rbacTestTenant, loginTestEmail, "s3cret")
+	require.NoError(t, err)
+	assert.False(t, q.users[0].LockedUntil.Valid)
+}
+
+func TestAuthHandlers_Login(t *testing.T) {
+	login, _ := newTestLogin(t)
+	auth := newRefreshTestAuthenticator()
+	recorder := audit.NewMemoryRecorder()
+	handlers := NewAuthHandlers(auth, logging.NewLogger()).WithRecorder(recorder)
+
+	post := func(req LoginRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		handlers.HandleLogin(w, httptest.NewRequest("POST", "/api/v1/auth/login", bytes.NewReader(body)))
+		return w
+	}
+	good := LoginRequest{TenantID: rbacTestTenant, Email: loginTestEmail, Password: "s3cret"}
+
+	w := post(good)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "login_unavailable")
+
+	handlers.WithLogin(login)
+	w = post(good)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Data TokenResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	claims, err := auth.ValidateToken(context.Background(), response.Data.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, rbacTestUser, claims.UserID)
+	assert.Equal(t, []string{"viewer"}, claims.Roles, "roles come from RBAC, not the users row")
+
+	event := recorder.Last()
+	assert.Equal(t, AuditActionLogin, event.Action)
+	assert.Equal(t, audit.OutcomeSuccess, event.Outcome)
+	assert.Equal(t, rbacTestUser, event.ActorID)
+
+	bad := good
+	bad.Password = "wrong"
+	w = post(bad)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_credentials")
+	event = recorder.Last()
+	assert.Equal(t, audit.OutcomeDenied, event.Outcome)
+	assert.Equal(t, loginTestEmail, event.ActorID)
+
+	post(bad)
+	post(bad)
+	// A locked account gets the response of a wrong password, even with the
+	// right one; only the audit trail records the lock
+	for _, req := range []LoginRequest{bad, good} {
+		w = post(req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_credentials")
+		assert.Empty(t, w.Header().Get("Retry-After"))
+		assert.Equal(t, "account_locked", recorder.Last().Details["reason"])
+	}
+
+	w = post(LoginRequest{TenantID: rbacTestTenant, Email: loginTestEmail})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -46,7 +46,7 @@ func TestManualAuthenticationFlow(t *testing.T) {
 	}).Methods("GET")
 
 	// Auth endpoints
-	router.HandleFunc("/api/v1/auth/token", handlers.HandleTokenIssue).Methods("POST")
+	router.Handle("/api/v1/auth/token", middleware.Middleware()(http.HandlerFunc(handlers.HandleTokenIssue))).Methods("POST")
 	router.HandleFunc("/api/v1/auth/validate", handlers.HandleTokenValidate).Methods("POST")
 
 	// Protected auth endpoints
@@ -90,8 +90,10 @@ func TestManualAuthenticationFlow(t *testing.T) {
 	fmt.Printf("   Protected endpoint without token status: %d\n", resp.StatusCode)
 	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
 
-	// Test 3: Issue a token
+	// Test 3: Issue a token, as an admin of the tenant
 	fmt.Println("\n3. Issuing authentication token...")
+	admin, err := auth.IssueToken(context.Background(), &TokenRequest{TenantID: "demo-tenant", UserID: "demo-admin", Roles: []string{"admin"}})
+	require.NoError(t, err)
 	tokenRequest := TokenIssueRequest{
 		TenantID:    "demo-tenant",
 		UserID:      "demo-user",
@@ -101,7 +103,10 @@ func TestManualAuthenticationFlow(t *testing.T) {
 	}
 
 	tokenBody, _ := json.Marshal(tokenRequest)
-	resp, err = http.Post(server.URL+"/api/v1/auth/token", "application/json", bytes.NewReader(tokenBody))
+	issueReq, _ := http.NewRequest("POST", server.URL+"/api/v1/auth/token", bytes.NewReader(tokenBody))
+	issueReq.Header.Set("Content-Type", "application/json")
+	issueReq.Header.Set("Authorization", "Bearer "+admin.AccessToken)
+	resp, err = http.DefaultClient.Do(issueReq)
 	require.NoError(t, err)
 	defer resp.Body.Close()
 
@@ -4,9 +4,11 @@ package security
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/audit"
 )
 
 // AuthMiddleware provides JWT authentication middleware
@@ -14,6 +16,10 @@ type AuthMiddleware struct {
 	authenticator Authenticator
 	logger        logging.Logger
 	config        *AuthConfig
+	recorder      audit.Recorder
+	authorizer    Authorizer
+	apiKeys       *APIKeyService
+	clientCerts   map[string][]string // permissions by identity kind
 }
 
 // NewAuthMiddleware creates a new authentication middleware
@@ -29,6 +35,28 @@ func NewAuthMiddleware(authenticator Authenticator, logger logging.Logger, confi
 	}
 }
 
+// WithRecorder records role and permission denials in the audit trail
+func (am *AuthMiddleware) WithRecorder(recorder audit.Recorder) *AuthMiddleware {
+	am.recorder = recorder
+	return am
+}
+
+// WithAuthorizer resolves roles and permissions through authorizer on every
+// request instead of taking them from the token, so changes take effect at
+// once
+func (am *AuthMiddleware) WithAuthorizer(authorizer Authorizer) *AuthMiddleware {
+	am.authorizer = authorizer
+	return am
+}
+
+// WithAPIKeys accepts service account API keys as bearer tokens alongside
+// JWTs. A key's scopes are its permissions; they are not resolved through
+// the authorizer.
+func (am *AuthMiddleware) WithAPIKeys(apiKeys *APIKeyService) *AuthMiddleware {
+	am.apiKeys = apiKeys
+	return am
+}
+
 // Middleware returns the HTTP middleware function
 func (am *AuthMiddleware) Middleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -39,10 +67,16 @@ func (am *AuthMiddleware) Middleware() func(http.Handler) http.Handler {
 				return
 			}
 
-			// Extract token from Authorization header
+			// Extract token from Authorization header, falling back to the
+			// client certificate when there is none
 			authHeader := r.Header.Get("Authorization")
+			var claims *AgentFlowClaims
 			if authHeader == "" {
-				am.writeAuthError(w, "missing_authorization_header", "Authorization header is required", http.StatusUnauthorized)
+				if claims = am.clientCertClaims(r); claims == nil {
+					am.writeAuthError(w, "missing_authorization_header", "Authorization header is required", http.StatusUnauthorized)
+					return
+				}
+				am.serveAuthenticated(w, r, next, claims, claims.Roles, claims.Permissions)
 				return
 			}
 
@@ -53,7 +87,18 @@ func (am *AuthMiddleware) Middleware() func(http.Handler) http.Handler {
 			}
 
 			// Validate token
-			claims, err := am.authenticator.ValidateToken(r.Context(), token)
+			if am.apiKeys != nil && IsAPIKey(token) {
+				claims, err = am.apiKeys.Authenticate(r.Context(), token)
+				if err != nil && !errors.Is(err, ErrAPIKeyInvalid) {
+					am.logger.Error("Failed to authenticate API key", err,
+						logging.String("path", r.URL.Path),
+					)
+					am.writeAuthError(w, "authentication_unavailable", "API key could not be checked", http.StatusServiceUnavailable)
+					return
+				}
+			} else {
+				claims, err = am.authenticator.ValidateToken(r.Context(), token)
+			}
 			if err != nil {
 				am.logger.Warn("Token validation failed",
 					logging.String("error", err.Error()),
@@ -65,28 +110,48 @@ func (am *AuthMiddleware) Middleware() func(http.Handler) http.Handler {
 				return
 			}
 
-			// Add claims to request context
-			ctx := context.WithValue(r.Context(), "auth_claims", claims)
-			ctx = context.WithValue(ctx, "tenant_id", claims.TenantID)
-			ctx = context.WithValue(ctx, "user_id", claims.UserID)
-			ctx = context.WithValue(ctx, "user_roles", claims.Roles)
-			ctx = context.WithValue(ctx, "user_permissions", claims.Permissions)
-
-			// Log successful authentication
-			am.logger.Info("Request authenticated",
-				logging.String("user_id", claims.UserID),
-				logging.String("tenant_id", claims.TenantID),
-				logging.Any("roles", claims.Roles),
-				logging.String("path", r.URL.Path),
-				logging.String("method", r.Method),
-			)
-
-			// Continue with authenticated request
-			next.ServeHTTP(w, r.WithContext(ctx))
+			roles, permissions := claims.Roles, claims.Permissions
+			if am.authorizer != nil && !claims.IsServiceAccount() {
+				effective, err := am.authorizer.EffectivePermissions(r.Context(), claims.TenantID, claims.UserID)
+				if err != nil {
+					am.logger.Error("Failed to resolve permissions", err,
+						logging.String("user_id", claims.UserID),
+						logging.String("tenant_id", claims.TenantID),
+					)
+					am.writeAuthError(w, "authorization_unavailable", "Permissions could not be resolved", http.StatusServiceUnavailable)
+					return
+				}
+				roles, permissions = effective.Roles, effective.Permissions
+			}
+
+			am.serveAuthenticated(w, r, next, claims, roles, permissions)
 		})
 	}
 }
 
+// serveAuthenticated adds the caller's claims, roles and permissions to the
+// request context and passes it on
+func (am *AuthMiddleware) serveAuthenticated(w http.ResponseWriter, r *http.Request, next http.Handler, claims *AgentFlowClaims, roles, permissions []string) {
+	// Add claims to request context
+	ctx := context.WithValue(r.Context(), "auth_claims", claims)
+	ctx = context.WithValue(ctx, "tenant_id", claims.TenantID)
+	ctx = context.WithValue(ctx, "user_id", claims.UserID)
+	ctx = context.WithValue(ctx, "user_roles", roles)
+	ctx = context.WithValue(ctx, "user_permissions", permissions)
+
+	// Log successful authentication
+	am.logger.Info("Request authenticated",
+		logging.String("user_id", claims.UserID),
+		logging.String("tenant_id", claims.TenantID),
+		logging.Any("roles", roles),
+		logging.String("path", r.URL.Path),
+		logging.String("method", r.Method),
+	)
+
+	// Continue with authenticated request
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // RequireRole creates middleware that requires specific roles
 func (am *AuthMiddleware) RequireRole(requiredRoles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -98,8 +163,9 @@ func (am *AuthMiddleware) RequireRole(requiredRoles ...string) func(http.Handler
 			}
 
 			// Check if user has any of the required roles
+			userRoles := requestRoles(r.Context(), claims)
 			hasRole := false
-			for _, userRole := range claims.Roles {
+			for _, userRole := range userRoles {
 				for _, requiredRole := range requiredRoles {
 					if userRole == requiredRole {
 						hasRole = true
@@ -115,10 +181,14 @@ func (am *AuthMiddleware) RequireRole(requiredRoles ...string) func(http.Handler
 				am.logger.Warn("Insufficient role permissions",
 					logging.String("user_id", claims.UserID),
 					logging.String("tenant_id", claims.TenantID),
-					logging.Any("user_roles", claims.Roles),
+					logging.Any("user_roles", userRoles),
 					logging.Any("required_roles", requiredRoles),
 					logging.String("path", r.URL.Path),
 				)
+				event := claimsAuditEvent(r, claims, AuditActionAccessDenied, "endpoint", audit.OutcomeDenied)
+				event.Details["required_roles"] = requiredRoles
+				event.Critical = true
+				_ = recordAudit(r.Context(), am.recorder, event)
 				am.writeAuthError(w, "insufficient_permissions", "Insufficient role permissions", http.StatusForbidden)
 				return
 			}
@@ -130,8 +200,13 @@ func (am *AuthMiddleware) RequireRole(requiredRoles ...string) func(http.Handler
 
 // RequirePermission creates middleware that requires specific permissions
 func (am *AuthMiddleware) RequirePermission(resource, action string) func(http.Handler) http.Handler {
-	requiredPermission := resource + ":" + action
+	return am.RequireResourcePermission(resource, action, nil)
+}
 
+// RequireResourcePermission creates middleware that requires action on the
+// resource instance named by resourceID, such as "workflows:<id>:execute".
+// Permissions on every instance of the resource also satisfy it.
+func (am *AuthMiddleware) RequireResourcePermission(resource, action string, resourceID func(*http.Request) string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			claims := GetClaimsFromContext(r.Context())
@@ -140,25 +215,28 @@ func (am *AuthMiddleware) RequirePermission(resource, action string) func(http.H
 				return
 			}
 
-			// Check if user has the required permission or wildcard permission
-			hasPermission := false
-			resourceWildcard := resource + ":*"
-
-			for _, permission := range claims.Permissions {
-				if permission == requiredPermission || permission == resourceWildcard || permission == "*" {
-					hasPermission = true
-					break
+			id := ""
+			requiredPermission := resource + ":" + action
+			if resourceID != nil {
+				if id = resourceID(r); id != "" {
+					requiredPermission = resource + ":" + id + ":" + action
 				}
 			}
 
-			if !hasPermission {
+			// Check if user has the required permission or a wildcard permission
+			permissions := requestPermissions(r.Context(), claims)
+			if !permitted(permissions, resource, id, action) {
 				am.logger.Warn("Insufficient permissions",
 					logging.String("user_id", claims.UserID),
 					logging.String("tenant_id", claims.TenantID),
-					logging.Any("user_permissions", claims.Permissions),
+					logging.Any("user_permissions", permissions),
 					logging.String("required_permission", requiredPermission),
 					logging.String("path", r.URL.Path),
 				)
+				event := claimsAuditEvent(r, claims, AuditActionAccessDenied, "endpoint", audit.OutcomeDenied)
+				event.Details["required_permission"] = requiredPermission
+				event.Critical = true
+				_ = recordAudit(r.Context(), am.recorder, event)
 				am.writeAuthError(w, "insufficient_permissions",
 					"User lacks required permission: "+requiredPermission, http.StatusForbidden)
 				return
@@ -169,6 +247,24 @@ func (am *AuthMiddleware) RequirePermission(resource, action string) func(http.H
 	}
 }
 
+// requestRoles returns the roles resolved by Middleware, or those in the
+// token when the request did not pass through it
+func requestRoles(ctx context.Context, claims *AgentFlowClaims) []string {
+	if roles, ok := ctx.Value("user_roles").([]string); ok {
+		return roles
+	}
+	return claims.Roles
+}
+
+// requestPermissions returns the permissions resolved by Middleware, or
+// those in the token when the request did not pass through it
+func requestPermissions(ctx context.Context, claims *AgentFlowClaims) []string {
+	if permissions, ok := ctx.Value("user_permissions").([]string); ok {
+		return permissions
+	}
+	return claims.Permissions
+}
+
 // isPublicEndpoint checks if an endpoint should skip authentication
 func (am *AuthMiddleware) isPublicEndpoint(path string) bool {
 	publicEndpoints := []string{
@@ -176,7 +272,9 @@ func (am *AuthMiddleware) isPublicEndpoint(path string) bool {
 		"/api/v1/health",
 		"/",
 		"/api",
-		"/api/v1/auth/token", // Token issuance endpoint
+		"/api/v1/auth/login",   // Credentials authenticate themselves
+		"/api/v1/auth/refresh", // Refresh tokens authenticate themselves
+		"/.well-known/jwks.json",
 	}
 
 	for _, endpoint := range publicEndpoints {
@@ -259,14 +357,11 @@ func HasRole(ctx context.Context, role string) bool {
 
 // HasPermission checks if the current user has a specific permission
 func HasPermission(ctx context.Context, resource, action string) bool {
-	permissions := GetUserPermissionsFromContext(ctx)
-	requiredPermission := resource + ":" + action
-	resourceWildcard := resource + ":*"
+	return permitted(GetUserPermissionsFromContext(ctx), resource, "", action)
+}
 
-	for _, permission := range permissions {
-		if permission == requiredPermission || permission == resourceWildcard || permission == "*" {
-			return true
-		}
-	}
-	return false
+// HasResourcePermission checks if the current user may perform action on
+// the resource instance resourceID
+func HasResourcePermission(ctx context.Context, resource, resourceID, action string) bool {
+	return permitted(GetUserPermissionsFromContext(ctx), resource, resourceID, action)
 }
@@ -18,6 +18,8 @@ import (
 type OIDCProvider interface {
 	ValidateToken(ctx context.Context, token string) (*AgentFlowClaims, error)
 	GetProviderInfo(ctx context.Context) (*OIDCProviderInfo, error)
+	// Close stops background refreshes of the provider's signing keys
+	Close() error
 }
 
 // OIDCProviderInfo contains OIDC provider metadata
@@ -48,9 +50,12 @@ type oidcProvider struct {
 	config       *AuthConfig
 	providerInfo *OIDCProviderInfo
 	httpClient   *http.Client
+	keys         *jwksCache
 }
 
-// NewOIDCProvider creates a new OIDC provider
+// NewOIDCProvider creates a new OIDC provider. The provider's signing keys
+// are fetched from its jwks_uri and refreshed every OIDCJWKSRefresh until
+// Close is called.
 func NewOIDCProvider(config *AuthConfig) (OIDCProvider, error) {
 	if !config.OIDCEnabled {
 		return nil, errors.New("OIDC is not enabled")
@@ -75,36 +80,66 @@ func NewOIDCProvider(config *AuthConfig) (OIDCProvider, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
 	}
+	if providerInfo.JWKSUri == "" {
+		return nil, errors.New("failed to discover OIDC provider: configuration has no jwks_uri")
+	}
 
 	provider.providerInfo = providerInfo
+	provider.keys = newJWKSCache(providerInfo.JWKSUri, provider.httpClient)
+	provider.keys.start(config.OIDCJWKSRefresh, provider.httpClient.Timeout)
 	return provider, nil
 }
 
-// ValidateToken validates an OIDC token
-func (p *oidcProvider) ValidateToken(ctx context.Context, tokenString string) (*AgentFlowClaims, error) {
-	// Parse token without verification first to get the header
-	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, &OIDCClaims{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
+// Close stops background key refreshes
+func (p *oidcProvider) Close() error {
+	if p.keys != nil {
+		p.keys.close()
 	}
+	return nil
+}
 
-	// Get the key ID from token header
-	keyID, ok := token.Header["kid"].(string)
-	if !ok {
-		return nil, errors.New("token missing key ID")
+// audience returns the audience tokens must be issued for
+func (p *oidcProvider) audience() string {
+	if p.config.OIDCAudience != "" {
+		return p.config.OIDCAudience
 	}
+	return p.config.OIDCClientID
+}
 
-	// Get the public key for verification
-	publicKey, err := p.getPublicKey(ctx, keyID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get public key: %w", err)
+// ValidateToken verifies an OIDC token's signature against the provider's
+// published keys, selected by the token's kid, and checks its issuer,
+// audience and validity period, allowing OIDCClockSkew of clock drift
+func (p *oidcProvider) ValidateToken(ctx context.Context, tokenString string) (*AgentFlowClaims, error) {
+	audience := p.audience()
+	if audience == "" {
+		return nil, errors.New("token validation failed: no OIDC audience or client ID is configured")
 	}
 
-	// Parse and validate token with public key
-	validatedToken, err := jwt.ParseWithClaims(tokenString, &OIDCClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return publicKey, nil
-	})
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(asymmetricSigningMethods),
+		jwt.WithIssuer(p.config.OIDCIssuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if p.config.OIDCClockSkew > 0 {
+		options = append(options, jwt.WithLeeway(p.config.OIDCClockSkew))
+	}
 
+	validatedToken, err := jwt.ParseWithClaims(tokenString, &OIDCClaims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.getPublicKey(ctx, kid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get public key: %w", err)
+		}
+		if key.alg != "" && key.alg != token.Method.Alg() {
+			return nil, fmt.Errorf("key %q is for %s, token is signed with %s", kid, key.alg, token.Method.Alg())
+		}
+		if err := checkKeyAlgorithm(key.key, token.Method.Alg()); err != nil {
+			return nil, err
+		}
+		return key.key, nil
+	}, options...)
 	if err != nil {
 		return nil, fmt.Errorf("token validation failed: %w", err)
 	}
@@ -115,11 +150,6 @@ func (p *oidcProvider) ValidateToken(ctx context.Context, tokenString string) (*
 		return nil, errors.New("invalid token claims")
 	}
 
-	// Validate issuer
-	if oidcClaims.Issuer != p.config.OIDCIssuer {
-		return nil, fmt.Errorf("invalid issuer: expected %s, got %s", p.config.OIDCIssuer, oidcClaims.Issuer)
-	}
-
 	// Convert OIDC claims to AgentFlow claims
 	agentFlowClaims := &AgentFlowClaims{
 		TenantID:    oidcClaims.TenantID,
@@ -161,7 +191,7 @@ func (p *oidcProvider) GetProviderInfo(ctx context.Context) (*OIDCProviderInfo,
 // discoverProvider discovers OIDC provider configuration
 func (p *oidcProvider) discoverProvider(ctx context.Context) (*OIDCProviderInfo, error) {
 	// Construct well-known configuration URL
-	configURL := strings.TrimSuffix(p.config.OIDCIssuer, "/") + "/.well-known/openid_configuration"
+	configURL := strings.TrimSuffix(p.config.OIDCIssuer, "/") + "/.well-known/openid-configuration"
 
 	req, err := http.NewRequestWithContext(ctx, "GET", configURL, nil)
 	if err != nil {
@@ -188,19 +218,22 @@ func (p *oidcProvider) discoverProvider(ctx context.Context) (*OIDCProviderInfo,
 		return nil, fmt.Errorf("failed to parse provider configuration: %w", err)
 	}
 
+	// The configuration must be for the issuer it was fetched from, or its
+	// keys could sign tokens for another issuer
+	if providerInfo.Issuer != p.config.OIDCIssuer {
+		return nil, fmt.Errorf("provider configuration is for issuer %s, expected %s", providerInfo.Issuer, p.config.OIDCIssuer)
+	}
+
 	return &providerInfo, nil
 }
 
-// getPublicKey retrieves the public key for token verification
-func (p *oidcProvider) getPublicKey(ctx context.Context, keyID string) (interface{}, error) {
-	// In a real implementation, you would:
-	// 1. Fetch JWKS from the provider's JWKS URI
-	// 2. Parse the keys and find the one with matching key ID
-	// 3. Convert to appropriate format for verification
-	// 4. Cache keys for performance
-
-	// For now, return an error indicating this needs implementation
-	return nil, errors.New("OIDC public key retrieval not implemented - requires JWKS integration")
+// getPublicKey returns the provider's signing key with keyID, fetching the
+// key set again if the key is not cached
+func (p *oidcProvider) getPublicKey(ctx context.Context, keyID string) (jwksKey, error) {
+	if p.keys == nil {
+		return jwksKey{}, errors.New("provider has no JWKS")
+	}
+	return p.keys.key(ctx, keyID)
 }
 
 // deriveTenantFromEmail derives tenant ID from email domain
@@ -234,8 +267,9 @@ func NewHybridAuthenticator(config *AuthConfig) (Authenticator, error) {
 	}
 
 	jwtAuth := &jwtAuthenticator{
-		config:        config,
-		revokedTokens: make(map[string]time.Time),
+		config:       config,
+		revocations:  NewMemoryRevocationStore(),
+		refreshStore: NewMemoryRefreshTokenStore(),
 	}
 
 	hybrid := &hybridAuthenticator{
@@ -283,6 +317,21 @@ func (h *hybridAuthenticator) RefreshToken(ctx context.Context, refreshToken str
 	return h.jwtAuth.RefreshToken(ctx, refreshToken)
 }
 
+// SetRefreshTokenStore keeps issued refresh tokens in store instead of memory
+func (h *hybridAuthenticator) SetRefreshTokenStore(store RefreshTokenStore) {
+	h.jwtAuth.SetRefreshTokenStore(store)
+}
+
+// SetRevocationStore keeps token revocations in store instead of memory
+func (h *hybridAuthenticator) SetRevocationStore(store RevocationStore) {
+	h.jwtAuth.SetRevocationStore(store)
+}
+
+// SetSigningKeys signs issued tokens with the active key of ring
+func (h *hybridAuthenticator) SetSigningKeys(ring *SigningKeyRing) {
+	h.jwtAuth.SetSigningKeys(ring)
+}
+
 // RevokeToken revokes a token
 func (h *hybridAuthenticator) RevokeToken(ctx context.Context, token string) error {
 	return h.jwtAuth.RevokeToken(ctx, token)
@@ -2,12 +2,20 @@ package security
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -48,7 +56,7 @@ func TestOIDCProvider(t *testing.T) {
 		// Create a mock OIDC discovery server
 		var serverURL string
 		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.URL.Path == "/.well-known/openid_configuration" {
+			if r.URL.Path == "/.well-known/openid-configuration" {
 				providerInfo := OIDCProviderInfo{
 					Issuer:                 serverURL,
 					AuthorizationEndpoint:  serverURL + "/auth",
@@ -73,6 +81,7 @@ func TestOIDCProvider(t *testing.T) {
 
 		provider, err := NewOIDCProvider(config)
 		require.NoError(t, err)
+		defer provider.Close()
 
 		info, err := provider.GetProviderInfo(context.Background())
 		require.NoError(t, err)
@@ -82,11 +91,22 @@ func TestOIDCProvider(t *testing.T) {
 		assert.Equal(t, mockServer.URL+"/jwks", info.JWKSUri)
 	})
 
-	t.Run("ValidateToken_NotImplemented", func(t *testing.T) {
+	t.Run("DiscoverProvider_IssuerMismatch", func(t *testing.T) {
+		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(OIDCProviderInfo{Issuer: "https://other.example.com", JWKSUri: "https://other.example.com/jwks"})
+		}))
+		defer mockServer.Close()
+
+		_, err := NewOIDCProvider(&AuthConfig{OIDCEnabled: true, OIDCIssuer: mockServer.URL})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "expected "+mockServer.URL)
+	})
+
+	t.Run("ValidateToken_Malformed", func(t *testing.T) {
 		// Create a mock OIDC discovery server
 		var serverURL string
 		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.URL.Path == "/.well-known/openid_configuration" {
+			if r.URL.Path == "/.well-known/openid-configuration" {
 				providerInfo := OIDCProviderInfo{
 					Issuer:  serverURL,
 					JWKSUri: serverURL + "/jwks",
@@ -99,17 +119,17 @@ func TestOIDCProvider(t *testing.T) {
 		serverURL = mockServer.URL
 
 		config := &AuthConfig{
-			OIDCEnabled: true,
-			OIDCIssuer:  mockServer.URL,
+			OIDCEnabled:  true,
+			OIDCIssuer:   mockServer.URL,
+			OIDCClientID: "agentflow-client",
 		}
 
 		provider, err := NewOIDCProvider(config)
 		require.NoError(t, err)
+		defer provider.Close()
 
-		// This should fail because JWKS integration is not implemented
 		_, err = provider.ValidateToken(context.Background(), "fake-jwt-token")
 		assert.Error(t, err)
-		// The error could be from JWT parsing or JWKS retrieval - just check that it fails
 		assert.Contains(t, err.Error(), "token")
 	})
 }
@@ -196,3 +216,237 @@ func TestHybridAuthenticator_OIDC(t *testing.T) {
 		assert.Equal(t, "user456", claims.UserID)
 	})
 }
+
+// testIdentityProvider is a local OIDC provider that publishes the public
+// halves of its signing keys
+type testIdentityProvider struct {
+	server *httptest.Server
+
+	mu           sync.Mutex
+	keys         map[string]crypto.Signer
+	jwksRequests int
+}
+
+func newTestIdentityProvider(t *testing.T) *testIdentityProvider {
+	idp := &testIdentityProvider{keys: make(map[string]crypto.Signer)}
+	idp.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(OIDCProviderInfo{Issuer: idp.server.URL, JWKSUri: idp.server.URL + "/jwks"})
+		case "/jwks":
+			idp.mu.Lock()
+			defer idp.mu.Unlock()
+			idp.jwksRequests++
+			set := JSONWebKeySet{}
+			for kid, key := range idp.keys {
+				jwk, err := NewJSONWebKey(kid, "", key.Public())
+				require.NoError(t, err)
+				set.Keys = append(set.Keys, jwk)
+			}
+			json.NewEncoder(w).Encode(set)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdentityProvider) setKey(kid string, key crypto.Signer) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	if key == nil {
+		delete(idp.keys, kid)
+		return
+	}
+	idp.keys[kid] = key
+}
+
+func (idp *testIdentityProvider) requests() int {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.jwksRequests
+}
+
+// claims returns valid claims for a token issued to agentflow-client
+func (idp *testIdentityProvider) claims() *OIDCClaims {
+	now := time.Now()
+	return &OIDCClaims{
+		Subject:  "user456",
+		Email:    "user@example.com",
+		Roles:    []string{"developer"},
+		TenantID: "tenant123",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    idp.server.URL,
+			Audience:  jwt.ClaimStrings{"agentflow-client"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+}
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func (idp *testIdentityProvider) provider(t *testing.T) *oidcProvider {
+	t.Helper()
+	provider, err := NewOIDCProvider(&AuthConfig{
+		OIDCEnabled:   true,
+		OIDCIssuer:    idp.server.URL,
+		OIDCClientID:  "agentflow-client",
+		OIDCClockSkew: time.Minute,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { provider.Close() })
+	return provider.(*oidcProvider)
+}
+
+func TestOIDCProvider_ValidateToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	idp := newTestIdentityProvider(t)
+	idp.setKey("rsa", rsaKey)
+	idp.setKey("ec", ecKey)
+	idp.setKey("ed", edKey)
+	provider := idp.provider(t)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		method jwt.SigningMethod
+		kid    string
+		key    crypto.Signer
+	}{
+		{jwt.SigningMethodRS256, "rsa", rsaKey},
+		{jwt.SigningMethodPS256, "rsa", rsaKey},
+		{jwt.SigningMethodES256, "ec", ecKey},
+		{jwt.SigningMethodEdDSA, "ed", edKey},
+	} {
+		t.Run(tc.method.Alg(), func(t *testing.T) {
+			claims, err := provider.ValidateToken(ctx, signTestToken(t, tc.method, tc.kid, tc.key, idp.claims()))
+			require.NoError(t, err)
+			assert.Equal(t, "tenant123", claims.TenantID)
+			assert.Equal(t, "user456", claims.UserID)
+			assert.Equal(t, []string{"developer"}, claims.Roles)
+		})
+	}
+
+	t.Run("Rejected", func(t *testing.T) {
+		expired := idp.claims()
+		expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Minute))
+		noExpiry := idp.claims()
+		noExpiry.ExpiresAt = nil
+		wrongAudience := idp.claims()
+		wrongAudience.Audience = jwt.ClaimStrings{"another-client"}
+		wrongIssuer := idp.claims()
+		wrongIssuer.Issuer = "https://other.example.com"
+		notYetValid := idp.claims()
+		notYetValid.NotBefore = jwt.NewNumericDate(time.Now().Add(5 * time.Minute))
+
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		rsaJWK, err := NewJSONWebKey("rsa", "", &rsaKey.PublicKey)
+		require.NoError(t, err)
+
+		tokens := map[string]string{
+			"expired beyond skew":         signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, expired),
+			"missing exp":                 signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, noExpiry),
+			"wrong audience":              signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, wrongAudience),
+			"wrong issuer":                signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, wrongIssuer),
+			"not yet valid beyond skew":   signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, notYetValid),
+			"signed by another key":       signTestToken(t, jwt.SigningMethodRS256, "rsa", otherKey, idp.claims()),
+			"key of another type":         signTestToken(t, jwt.SigningMethodES256, "rsa", ecKey, idp.claims()),
+			"HMAC with the public key":    signTestToken(t, jwt.SigningMethodHS256, "rsa", []byte(rsaJWK.N), idp.claims()),
+			"unknown key ID":              signTestToken(t, jwt.SigningMethodRS256, "unknown", rsaKey, idp.claims()),
+			"no key ID with several keys": signTestToken(t, jwt.SigningMethodRS256, "", rsaKey, idp.claims()),
+		}
+		for name, token := range tokens {
+			_, err := provider.ValidateToken(ctx, token)
+			assert.Error(t, err, name)
+		}
+	})
+
+	t.Run("ClockSkew", func(t *testing.T) {
+		claims := idp.claims()
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-30 * time.Second))
+		claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(30 * time.Second))
+		_, err := provider.ValidateToken(ctx, signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims))
+		assert.NoError(t, err)
+	})
+
+	t.Run("AudienceRequired", func(t *testing.T) {
+		unconfigured := &oidcProvider{config: &AuthConfig{OIDCIssuer: idp.server.URL}, keys: provider.keys}
+		_, err := unconfigured.ValidateToken(ctx, signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, idp.claims()))
+		assert.ErrorContains(t, err, "no OIDC audience")
+	})
+}
+
+func TestOIDCProvider_KeyRotation(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	idp := newTestIdentityProvider(t)
+	idp.setKey("2024", oldKey)
+	provider := idp.provider(t)
+	ctx := context.Background()
+
+	_, err = provider.ValidateToken(ctx, signTestToken(t, jwt.SigningMethodES256, "2024", oldKey, idp.claims()))
+	require.NoError(t, err)
+
+	// A token signed with a newly published key triggers a fetch
+	idp.setKey("2025", newKey)
+	now := time.Now().Add(time.Minute)
+	provider.keys.now = func() time.Time { return now }
+	_, err = provider.ValidateToken(ctx, signTestToken(t, jwt.SigningMethodES384, "2025", newKey, idp.claims()))
+	require.NoError(t, err)
+
+	// Unknown key IDs do not trigger a fetch again straight away
+	requests := idp.requests()
+	for i := 0; i < 5; i++ {
+		_, err = provider.ValidateToken(ctx, signTestToken(t, jwt.SigningMethodES384, "made-up", newKey, idp.claims()))
+		assert.ErrorContains(t, err, "unknown key ID")
+	}
+	assert.Equal(t, requests, idp.requests())
+
+	// Keys withdrawn by the provider are dropped on the next refresh
+	idp.setKey("2024", nil)
+	require.NoError(t, provider.keys.refresh(ctx))
+	_, err = provider.ValidateToken(ctx, signTestToken(t, jwt.SigningMethodES256, "2024", oldKey, idp.claims()))
+	assert.Error(t, err)
+}
+
+func TestOIDCProvider_BackgroundRefresh(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	idp := newTestIdentityProvider(t)
+	idp.setKey("ed", key)
+
+	provider, err := NewOIDCProvider(&AuthConfig{
+		OIDCEnabled:     true,
+		OIDCIssuer:      idp.server.URL,
+		OIDCClientID:    "agentflow-client",
+		OIDCJWKSRefresh: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return idp.requests() >= 3 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, provider.Close())
+	stopped := idp.requests()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, stopped, idp.requests(), "no refreshes after Close")
+}
@@ -0,0 +1,97 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnsupportedPasswordHash is returned for stored hashes in a format other
+// than argon2id or bcrypt
+var ErrUnsupportedPasswordHash = errors.New("unsupported password hash")
+
+// argon2id parameters for new hashes, following the OWASP recommendation of
+// 64 MiB memory and 3 passes
+const (
+	argon2Memory  = 64 * 1024
+	argon2Time    = 3
+	argon2Threads = 2
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+
+	// maxPasswordLength bounds the work an attacker can cause per attempt
+	maxPasswordLength = 1024
+)
+
+// HashPassword returns an argon2id hash of password in the PHC string
+// format, for users.hashed_secret
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("password is required")
+	}
+	if len(password) > maxPasswordLength {
+		return "", fmt.Errorf("password is longer than %d bytes", maxPasswordLength)
+	}
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword reports whether password matches a stored argon2id or
+// bcrypt hash. A malformed or unknown hash is an error, not a mismatch.
+func VerifyPassword(hash, password string) (bool, error) {
+	if len(password) > maxPasswordLength {
+		return false, nil
+	}
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2id(hash, password)
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("%w: %v", ErrUnsupportedPasswordHash, err)
+		}
+		return true, nil
+	}
+	return false, ErrUnsupportedPasswordHash
+}
+
+func verifyArgon2id(hash, password string) (bool, error) {
+	// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, ErrUnsupportedPasswordHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrUnsupportedPasswordHash
+	}
+	var memory, passes uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &passes, &threads); err != nil {
+		return false, ErrUnsupportedPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrUnsupportedPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 || passes == 0 || threads == 0 {
+		return false, ErrUnsupportedPasswordHash
+	}
+	computed := argon2.IDKey([]byte(password), salt, passes, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
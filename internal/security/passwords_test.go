@@ -0,0 +1,59 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$"))
+
+	other, err := HashPassword("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "hashes are salted")
+
+	ok, err := VerifyPassword(hash, "correct horse")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = VerifyPassword(hash, "wrong horse")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = HashPassword("")
+	assert.Error(t, err)
+	_, err = HashPassword(strings.Repeat("x", maxPasswordLength+1))
+	assert.Error(t, err)
+}
+
+func TestVerifyPassword_Bcrypt(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	ok, err := VerifyPassword(string(hash), "s3cret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = VerifyPassword(string(hash), "S3cret")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifyPassword_Unsupported(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plaintext",
+		"$argon2i$v=19$m=65536,t=3,p=2$c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=3$c2FsdA$a2V5",
+		"$argon2id$v=18$m=65536,t=3,p=2$c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=2$!!$a2V5",
+		"$2b$10$short",
+	} {
+		_, err := VerifyPassword(hash, "password")
+		assert.ErrorIs(t, err, ErrUnsupportedPasswordHash, hash)
+	}
+}
@@ -0,0 +1,320 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/agentflow/agentflow/internal/storage/audit"
+)
+
+// AuditActionDecision is the audit action recorded for a policy decision
+const AuditActionDecision = "policy.decision"
+
+// ErrDenied is returned by Authorize when a policy denies the request
+var ErrDenied = errors.New("denied by policy")
+
+// Request is an action to decide on. Input holds the values rule conditions
+// refer to; the request constructors document its shape for each action.
+type Request struct {
+	TenantID     string
+	Action       string
+	ActorType    string
+	ActorID      string
+	ResourceType string
+	ResourceID   string
+	Input        map[string]interface{}
+}
+
+// Principal is the user or service a workflow runs for
+type Principal struct {
+	Type  string
+	ID    string
+	Roles []string
+}
+
+// WorkflowRun requests that principal run a workflow. Conditions see
+// principal.type, principal.id, principal.roles, workflow.id and
+// workflow.name.
+func WorkflowRun(tenantID string, principal Principal, workflowID, workflowName string) *Request {
+	roles := principal.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return &Request{
+		TenantID:     tenantID,
+		Action:       ActionWorkflowRun,
+		ActorType:    principal.Type,
+		ActorID:      principal.ID,
+		ResourceType: "workflow",
+		ResourceID:   workflowID,
+		Input: map[string]interface{}{
+			"principal": map[string]interface{}{"type": principal.Type, "id": principal.ID, "roles": roles},
+			"workflow":  map[string]interface{}{"id": workflowID, "name": workflowName},
+		},
+	}
+}
+
+// ToolCall requests that an agent call a tool with params. Conditions see
+// agent.id, tool.name and params.
+func ToolCall(tenantID, agentID, toolName string, params map[string]interface{}) *Request {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return &Request{
+		TenantID:     tenantID,
+		Action:       ActionToolCall,
+		ActorType:    "agent",
+		ActorID:      agentID,
+		ResourceType: "tool",
+		ResourceID:   toolName,
+		Input: map[string]interface{}{
+			"agent":  map[string]interface{}{"id": agentID},
+			"tool":   map[string]interface{}{"name": toolName},
+			"params": params,
+		},
+	}
+}
+
+// MessageSend requests that a message pass from one agent to another.
+// Conditions see from.id, to.id, subject, and message.id, message.type,
+// message.metadata and message.payload.
+func MessageSend(tenantID, from, to, subject, messageID, messageType string, metadata map[string]interface{}, payload interface{}) *Request {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	return &Request{
+		TenantID:     tenantID,
+		Action:       ActionMessageSend,
+		ActorType:    "agent",
+		ActorID:      from,
+		ResourceType: "agent",
+		ResourceID:   to,
+		Input: map[string]interface{}{
+			"from":    map[string]interface{}{"id": from},
+			"to":      map[string]interface{}{"id": to},
+			"subject": subject,
+			"message": map[string]interface{}{
+				"id":       messageID,
+				"type":     messageType,
+				"metadata": metadata,
+				"payload":  payload,
+			},
+		},
+	}
+}
+
+// PolicyDecision explains how one policy decided a request
+type PolicyDecision struct {
+	Policy string `json:"policy"`
+	Mode   string `json:"mode"`
+	// Effect is allow or deny, or empty when the policy abstains
+	Effect string `json:"effect,omitempty"`
+	// Rules are the matching rules that decided the effect
+	Rules []string `json:"rules,omitempty"`
+	// Default is set when no rule matched and the policy default decided
+	Default bool   `json:"default,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Decision is the outcome of evaluating every policy that covers a request.
+// Dry-run policies are reported but only change DryRunAllowed.
+type Decision struct {
+	Action        string           `json:"action"`
+	Allowed       bool             `json:"allowed"`
+	DryRunAllowed bool             `json:"dry_run_allowed"`
+	Reason        string           `json:"reason"`
+	Policies      []PolicyDecision `json:"policies"`
+}
+
+// Applied reports whether any policy decided the request
+func (d *Decision) Applied() bool {
+	for _, p := range d.Policies {
+		if p.Effect != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate decides req against policies. A request no policy decides is
+// allowed. A condition that fails to evaluate denies, so a broken rule
+// cannot open access.
+func Evaluate(policies []*Policy, req *Request) *Decision {
+	input := evaluationInput(req)
+	decision := &Decision{Action: req.Action, Allowed: true, DryRunAllowed: true, Policies: []PolicyDecision{}}
+
+	var allowedBy, deniedBy, dryRunDeniedBy []string
+	for _, p := range policies {
+		if p == nil {
+			continue
+		}
+		pd := evaluatePolicy(p, req.Action, input)
+		decision.Policies = append(decision.Policies, pd)
+
+		explanation := pd.Policy
+		if len(pd.Rules) > 0 {
+			explanation += " rule " + strings.Join(pd.Rules, ", ")
+		} else if pd.Default {
+			explanation += " default"
+		}
+		switch {
+		case pd.Effect == EffectDeny && pd.Mode == ModeDryRun:
+			decision.DryRunAllowed = false
+			dryRunDeniedBy = append(dryRunDeniedBy, explanation)
+		case pd.Effect == EffectDeny:
+			decision.Allowed = false
+			decision.DryRunAllowed = false
+			deniedBy = append(deniedBy, explanation)
+		case pd.Effect == EffectAllow && pd.Mode == ModeEnforce:
+			allowedBy = append(allowedBy, explanation)
+		}
+	}
+
+	switch {
+	case !decision.Allowed:
+		decision.Reason = "denied by " + strings.Join(deniedBy, "; ")
+	case len(allowedBy) > 0:
+		decision.Reason = "allowed by " + strings.Join(allowedBy, "; ")
+	default:
+		decision.Reason = "no policy applies"
+	}
+	if len(dryRunDeniedBy) > 0 {
+		decision.Reason += "; dry run would deny by " + strings.Join(dryRunDeniedBy, "; ")
+	}
+	return decision
+}
+
+func evaluatePolicy(p *Policy, action string, input map[string]interface{}) PolicyDecision {
+	pd := PolicyDecision{Policy: p.Name, Mode: p.Mode}
+	var allows, denies []string
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.appliesTo(action) {
+			continue
+		}
+		if rule.condition != nil {
+			holds, err := rule.condition.Eval(input)
+			if err != nil {
+				pd.Effect = EffectDeny
+				pd.Rules = []string{rule.ID}
+				pd.Error = fmt.Sprintf("rule %s: %v", rule.ID, err)
+				return pd
+			}
+			if !holds {
+				continue
+			}
+		}
+		if rule.Effect == EffectDeny {
+			denies = append(denies, rule.ID)
+		} else {
+			allows = append(allows, rule.ID)
+		}
+	}
+
+	switch {
+	case len(denies) > 0:
+		pd.Effect, pd.Rules = EffectDeny, denies
+	case len(allows) > 0:
+		pd.Effect, pd.Rules = EffectAllow, allows
+	case p.Default != "":
+		pd.Effect, pd.Default = p.Default, true
+	}
+	return pd
+}
+
+// evaluationInput returns the request input as JSON would decode it, so
+// conditions see the same types whether the input came from Go values or a
+// test file, together with action and tenant_id
+func evaluationInput(req *Request) map[string]interface{} {
+	input := map[string]interface{}{}
+	if data, err := json.Marshal(req.Input); err == nil {
+		_ = json.Unmarshal(data, &input)
+	}
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	input["action"] = req.Action
+	input["tenant_id"] = req.TenantID
+	return input
+}
+
+// Source returns the policies that cover a request
+type Source interface {
+	Policies(ctx context.Context, req *Request) ([]*Policy, error)
+}
+
+// StaticSource is a fixed set of policies that covers every request
+type StaticSource []*Policy
+
+// Policies returns the policies of the set
+func (s StaticSource) Policies(ctx context.Context, req *Request) ([]*Policy, error) {
+	return s, nil
+}
+
+// Engine decides requests against the policies of a source and records each
+// decision a policy took part in
+type Engine struct {
+	source   Source
+	recorder audit.Recorder
+}
+
+// NewEngine creates an engine. recorder may be nil to skip auditing.
+func NewEngine(source Source, recorder audit.Recorder) *Engine {
+	return &Engine{source: source, recorder: recorder}
+}
+
+// Decide evaluates req. Policies that cannot be loaded are an error; callers
+// should refuse the request rather than run it unchecked.
+func (e *Engine) Decide(ctx context.Context, req *Request) (*Decision, error) {
+	policies, err := e.source.Policies(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policies: %w", err)
+	}
+	decision := Evaluate(policies, req)
+	if decision.Applied() {
+		e.record(ctx, req, decision)
+	}
+	return decision, nil
+}
+
+// Authorize decides req and returns an error wrapping ErrDenied with the
+// reason when it is not allowed
+func (e *Engine) Authorize(ctx context.Context, req *Request) error {
+	decision, err := e.Decide(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !decision.Allowed {
+		return fmt.Errorf("%w: %s", ErrDenied, decision.Reason)
+	}
+	return nil
+}
+
+func (e *Engine) record(ctx context.Context, req *Request, decision *Decision) {
+	if e.recorder == nil || req.TenantID == "" {
+		return
+	}
+	event := audit.Event{
+		TenantID:     req.TenantID,
+		ActorType:    req.ActorType,
+		ActorID:      req.ActorID,
+		Action:       AuditActionDecision,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Outcome:      audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"action":          decision.Action,
+			"reason":          decision.Reason,
+			"dry_run_allowed": decision.DryRunAllowed,
+			"policies":        decision.Policies,
+		},
+	}
+	if !decision.Allowed {
+		event.Outcome = audit.OutcomeDenied
+		event.Critical = true
+	}
+	_ = e.recorder.Record(ctx, event)
+}
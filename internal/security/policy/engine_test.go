@@ -0,0 +1,251 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTenant = "00000000-0000-0000-0000-00000000000a"
+
+func mustParse(t *testing.T, name, doc string) *Policy {
+	t.Helper()
+	p, err := Parse(name, []byte(doc))
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	return p
+}
+
+func TestParse(t *testing.T) {
+	for _, doc := range []string{``, `null`, `{}`, `{"max_tokens": 100}`} {
+		p, err := Parse("agent:a", []byte(doc))
+		require.NoError(t, err, doc)
+		assert.Nil(t, p, "%q holds no policy", doc)
+	}
+
+	p := mustParse(t, "agent:a", `{"rules":[{"effect":"allow"},{"id":"x","effect":"deny","actions":["tool.call"]}]}`)
+	assert.Equal(t, "agent:a", p.Name)
+	assert.Equal(t, ModeEnforce, p.Mode)
+	assert.Equal(t, "rule-1", p.Rules[0].ID)
+
+	for doc, msg := range map[string]string{
+		`[]`:                              "cannot unmarshal",
+		`{"mode":"audit","rules":[]}`:     `unknown mode "audit"`,
+		`{"default":"maybe","rules":[]}`:  `unknown default "maybe"`,
+		`{"rules":[{"effect":"permit"}]}`: `rule rule-1: unknown effect "permit"`,
+		`{"rules":[{"effect":"allow","actions":["agent.kill"]}]}`:            `unknown action "agent.kill"`,
+		`{"rules":[{"effect":"allow","when":"x =="}]}`:                       "rule rule-1: invalid policy expression",
+		`{"rules":[{"id":"a","effect":"allow"},{"id":"a","effect":"deny"}]}`: "duplicate rule a",
+	} {
+		_, err := Parse("p", []byte(doc))
+		assert.ErrorIs(t, err, ErrInvalidPolicy, doc)
+		assert.ErrorContains(t, err, msg, doc)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tenantWide := mustParse(t, "tenant", `{
+		"rules": [
+			{"id": "no-shell", "effect": "deny", "actions": ["tool.call"], "when": "tool.name == \"shell\""},
+			{"id": "operators-run", "effect": "allow", "actions": ["workflow.run"], "when": "\"operator\" in principal.roles"}
+		]
+	}`)
+	httpTool := mustParse(t, "tool:http_get", `{
+		"default": "deny",
+		"rules": [{"id": "internal-only", "effect": "allow", "when": "startsWith(params.url, \"https://internal.\")"}]
+	}`)
+	operator := Principal{Type: "user", ID: "u1", Roles: []string{"operator"}}
+
+	d := Evaluate([]*Policy{tenantWide}, WorkflowRun(testTenant, operator, "wf-1", "nightly"))
+	assert.True(t, d.Allowed)
+	assert.Equal(t, "allowed by tenant rule operators-run", d.Reason)
+
+	// A request no policy decides is allowed
+	d = Evaluate([]*Policy{tenantWide}, WorkflowRun(testTenant, Principal{Type: "user", ID: "u2"}, "wf-1", "nightly"))
+	assert.True(t, d.Allowed)
+	assert.False(t, d.Applied())
+	assert.Equal(t, "no policy applies", d.Reason)
+
+	d = Evaluate([]*Policy{tenantWide, httpTool}, ToolCall(testTenant, "agent-1", "http_get", map[string]interface{}{"url": "https://internal.example.com"}))
+	assert.True(t, d.Allowed)
+	assert.Equal(t, []PolicyDecision{
+		{Policy: "tenant", Mode: ModeEnforce},
+		{Policy: "tool:http_get", Mode: ModeEnforce, Effect: EffectAllow, Rules: []string{"internal-only"}},
+	}, d.Policies)
+
+	d = Evaluate([]*Policy{tenantWide, httpTool}, ToolCall(testTenant, "agent-1", "http_get", map[string]interface{}{"url": "https://example.com"}))
+	assert.False(t, d.Allowed)
+	assert.Equal(t, "denied by tool:http_get default", d.Reason)
+
+	// Deny overrides allow, across policies and within one
+	both := mustParse(t, "both", `{"rules":[{"id":"a","effect":"allow"},{"id":"d","effect":"deny","when":"tool.name == \"shell\""}]}`)
+	d = Evaluate([]*Policy{both}, ToolCall(testTenant, "agent-1", "shell", nil))
+	assert.False(t, d.Allowed)
+	assert.Equal(t, "denied by both rule d", d.Reason)
+	d = Evaluate([]*Policy{both, tenantWide}, ToolCall(testTenant, "agent-1", "shell", nil))
+	assert.Equal(t, "denied by both rule d; tenant rule no-shell", d.Reason)
+
+	// A condition that cannot be evaluated denies
+	broken := mustParse(t, "broken", `{"rules":[{"id":"r","effect":"allow","when":"params.n > 1"}]}`)
+	d = Evaluate([]*Policy{broken}, ToolCall(testTenant, "agent-1", "calc", map[string]interface{}{"n": "many"}))
+	assert.False(t, d.Allowed)
+	assert.Contains(t, d.Policies[0].Error, "rule r: cannot compare string with number")
+
+	// Input built from Go values compares like JSON
+	d = Evaluate([]*Policy{broken}, ToolCall(testTenant, "agent-1", "calc", map[string]interface{}{"n": 2}))
+	assert.True(t, d.Allowed)
+}
+
+func TestEvaluate_DryRun(t *testing.T) {
+	shadow := mustParse(t, "shadow", `{"mode":"dry_run","rules":[{"id":"no-external","effect":"deny","when":"!startsWith(to.id, \"internal-\")"}]}`)
+
+	d := Evaluate([]*Policy{shadow}, MessageSend(testTenant, "planner", "external-bot", "", "m1", "request", nil, nil))
+	assert.True(t, d.Allowed, "dry-run policies are not enforced")
+	assert.False(t, d.DryRunAllowed)
+	assert.True(t, d.Applied())
+	assert.Equal(t, "no policy applies; dry run would deny by shadow rule no-external", d.Reason)
+
+	d = Evaluate([]*Policy{shadow}, MessageSend(testTenant, "planner", "internal-db", "", "m2", "request", nil, nil))
+	assert.True(t, d.Allowed)
+	assert.True(t, d.DryRunAllowed)
+}
+
+func TestEngine_Audit(t *testing.T) {
+	recorder := audit.NewMemoryRecorder()
+	source := StaticSource{mustParse(t, "tenant", `{"rules":[{"id":"no-shell","effect":"deny","actions":["tool.call"],"when":"tool.name == \"shell\""}]}`)}
+	engine := NewEngine(source, recorder)
+	ctx := context.Background()
+
+	require.NoError(t, engine.Authorize(ctx, ToolCall(testTenant, "agent-1", "http_get", nil)))
+	assert.Empty(t, recorder.Events(), "decisions no policy took part in are not recorded")
+
+	err := engine.Authorize(ctx, ToolCall(testTenant, "agent-1", "shell", map[string]interface{}{"cmd": "ls"}))
+	assert.ErrorIs(t, err, ErrDenied)
+	assert.EqualError(t, err, "denied by policy: denied by tenant rule no-shell")
+
+	require.Len(t, recorder.Events(), 1)
+	event := recorder.Events()[0]
+	assert.Equal(t, AuditActionDecision, event.Action)
+	assert.Equal(t, "agent", event.ActorType)
+	assert.Equal(t, "agent-1", event.ActorID)
+	assert.Equal(t, "tool", event.ResourceType)
+	assert.Equal(t, "shell", event.ResourceID)
+	assert.Equal(t, audit.OutcomeDenied, event.Outcome)
+	assert.True(t, event.Critical)
+	assert.Equal(t, ActionToolCall, event.Details["action"])
+	assert.NotContains(t, event.Details, "params", "parameter values may hold credentials")
+
+	failing := NewEngine(failingSource{}, recorder)
+	assert.ErrorContains(t, failing.Authorize(ctx, ToolCall(testTenant, "agent-1", "shell", nil)), "failed to load policies")
+}
+
+type failingSource struct{}
+
+func (failingSource) Policies(ctx context.Context, req *Request) ([]*Policy, error) {
+	return nil, errors.New("database down")
+}
+
+// policyQueries keeps policies, agents and tools in memory
+type policyQueries struct {
+	policies []queries.Policy
+	agents   []queries.Agent
+	tools    []queries.Tool
+}
+
+func (q *policyQueries) ListEnabledPolicies(ctx context.Context, tenantID pgtype.UUID) ([]queries.Policy, error) {
+	var rows []queries.Policy
+	for _, p := range q.policies {
+		if p.TenantID == tenantID && p.Enabled {
+			rows = append(rows, p)
+		}
+	}
+	return rows, nil
+}
+
+func (q *policyQueries) GetAgent(ctx context.Context, arg queries.GetAgentParams) (queries.Agent, error) {
+	for _, a := range q.agents {
+		if a.TenantID == arg.TenantID && a.ID == arg.ID {
+			return a, nil
+		}
+	}
+	return queries.Agent{}, pgx.ErrNoRows
+}
+
+func (q *policyQueries) GetAgentByName(ctx context.Context, arg queries.GetAgentByNameParams) (queries.Agent, error) {
+	for _, a := range q.agents {
+		if a.TenantID == arg.TenantID && a.Name == arg.Name {
+			return a, nil
+		}
+	}
+	return queries.Agent{}, pgx.ErrNoRows
+}
+
+func (q *policyQueries) GetToolByName(ctx context.Context, arg queries.GetToolByNameParams) (queries.Tool, error) {
+	for _, tool := range q.tools {
+		if tool.TenantID == arg.TenantID && tool.Name == arg.Name {
+			return tool, nil
+		}
+	}
+	return queries.Tool{}, pgx.ErrNoRows
+}
+
+func testUUID(s string) pgtype.UUID {
+	var id pgtype.UUID
+	_ = id.Scan(s)
+	return id
+}
+
+func newPolicyQueries() *policyQueries {
+	tenant := testUUID(testTenant)
+	return &policyQueries{
+		policies: []queries.Policy{
+			{TenantID: tenant, Name: "tenant", Enabled: true, Document: []byte(`{"rules":[{"id":"no-secrets","effect":"deny","actions":["message.send"],"when":"message.type == \"secret\""}]}`)},
+			{TenantID: tenant, Name: "disabled", Enabled: false, Document: []byte(`{"default":"deny","rules":[]}`)},
+		},
+		agents: []queries.Agent{
+			{ID: testUUID("00000000-0000-0000-0000-000000000001"), TenantID: tenant, Name: "planner", PoliciesJson: []byte(`{"rules":[{"id":"only-search","effect":"deny","actions":["tool.call"],"when":"tool.name != \"search\""}]}`)},
+			{ID: testUUID("00000000-0000-0000-0000-000000000002"), TenantID: tenant, Name: "worker", PoliciesJson: []byte(`{}`)},
+		},
+		tools: []queries.Tool{
+			{TenantID: tenant, Name: "search", Permissions: []byte(`{"rules":[{"id":"shallow-only","effect":"deny","when":"params.depth > 2"}]}`)},
+		},
+	}
+}
+
+func TestDBSource(t *testing.T) {
+	source := NewDBSource(newPolicyQueries())
+	ctx := context.Background()
+	names := func(policies []*Policy) []string {
+		var n []string
+		for _, p := range policies {
+			if p != nil {
+				n = append(n, p.Name)
+			}
+		}
+		return n
+	}
+
+	policies, err := source.Policies(ctx, ToolCall(testTenant, "planner", "search", nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant", "agent:planner", "tool:search"}, names(policies))
+
+	// Agents are looked up by ID as well as by name
+	policies, err = source.Policies(ctx, ToolCall(testTenant, "00000000-0000-0000-0000-000000000001", "unregistered", nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant", "agent:planner"}, names(policies))
+
+	policies, err = source.Policies(ctx, MessageSend(testTenant, "planner", "worker", "", "m1", "request", nil, nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant", "agent:planner"}, names(policies), "the worker has no rules")
+
+	policies, err = source.Policies(ctx, WorkflowRun("tenant123", Principal{ID: "u1"}, "wf-1", "nightly"))
+	require.NoError(t, err)
+	assert.Empty(t, policies)
+}
@@ -0,0 +1,678 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidExpression is returned for conditions that do not compile
+var ErrInvalidExpression = errors.New("invalid policy expression")
+
+// maxExpressionLength bounds the conditions a policy may hold
+const maxExpressionLength = 4096
+
+// Expression is a compiled rule condition. Conditions are side-effect free
+// and always terminate: they have no loops, assignments or user functions.
+//
+//	tool.name == "http_get" && startsWith(params.url, "https://internal.")
+//	principal.id in ["u1", "u2"] || "admin" in principal.roles
+//	!(message.type in ["tool_call", "tool_result"])
+type Expression struct {
+	src  string
+	root node
+}
+
+// Compile parses a condition
+func Compile(src string) (*Expression, error) {
+	if len(src) > maxExpressionLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrInvalidExpression, maxExpressionLength)
+	}
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("%w: unexpected %q at offset %d", ErrInvalidExpression, tok.text, tok.pos)
+	}
+	return &Expression{src: src, root: root}, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression against input. Paths that are not present
+// in input evaluate to null. The expression must produce a boolean.
+func (e *Expression) Eval(input map[string]interface{}) (bool, error) {
+	v, err := e.root.eval(input)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition produced %s, not a boolean", typeName(v))
+	}
+	return b, nil
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var punctuation = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, src[start:i], start})
+		case isDigit(c) || (c == '-' && i+1 < len(src) && isDigit(src[i+1]) && numberAllowed(tokens)):
+			start := i
+			i++
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, src[start:i], start})
+		case c == '"' || c == '\'':
+			start := i
+			var b strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) {
+					return nil, fmt.Errorf("%w: unterminated string at offset %d", ErrInvalidExpression, start)
+				}
+				if src[i] == c {
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				b.WriteByte(src[i])
+			}
+			tokens = append(tokens, token{tokString, b.String(), start})
+		default:
+			matched := ""
+			for _, p := range punctuation {
+				if strings.HasPrefix(src[i:], p) {
+					matched = p
+					break
+				}
+			}
+			if matched == "" {
+				return nil, fmt.Errorf("%w: unexpected %q at offset %d", ErrInvalidExpression, c, i)
+			}
+			tokens = append(tokens, token{tokPunct, matched, i})
+			i += len(matched)
+		}
+	}
+	return append(tokens, token{tokEOF, "end of expression", len(src)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// numberAllowed reports whether a '-' starts a negative number rather than
+// following an operand
+func numberAllowed(tokens []token) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	last := tokens[len(tokens)-1]
+	return last.kind == tokPunct && last.text != ")" && last.text != "]"
+}
+
+// Parser
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) accept(text string) bool {
+	if tok := p.peek(); tok.kind == tokPunct && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		tok := p.peek()
+		return fmt.Errorf("%w: expected %q at offset %d, found %q", ErrInvalidExpression, text, tok.pos, tok.text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+var comparisons = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	op := ""
+	switch {
+	case tok.kind == tokPunct && comparisons[tok.text]:
+		op = tok.text
+	case tok.kind == tokIdent && tok.text == "in":
+		op = "in"
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return &compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %q at offset %d", ErrInvalidExpression, tok.text, tok.pos)
+		}
+		return &literalNode{value: n}, nil
+	case tokString:
+		return &literalNode{value: tok.text}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if p.accept("(") {
+			return p.parseCall(tok)
+		}
+		path := []string{tok.text}
+		for p.accept(".") {
+			field := p.next()
+			if field.kind != tokIdent {
+				return nil, fmt.Errorf("%w: expected field name at offset %d", ErrInvalidExpression, field.pos)
+			}
+			path = append(path, field.text)
+		}
+		return &pathNode{path: path}, nil
+	case tokPunct:
+		switch tok.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			list := &listNode{}
+			if p.accept("]") {
+				return list, nil
+			}
+			for {
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if p.accept("]") {
+					return list, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: unexpected %q at offset %d", ErrInvalidExpression, tok.text, tok.pos)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown function %s at offset %d", ErrInvalidExpression, name.text, name.pos)
+	}
+	call := &callNode{name: name.text, fn: fn.call}
+	if !p.accept(")") {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.accept(")") {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(call.args) != fn.arity {
+		return nil, fmt.Errorf("%w: %s takes %d arguments, got %d", ErrInvalidExpression, name.text, fn.arity, len(call.args))
+	}
+	// Patterns given as literals are checked when the policy is loaded
+	if name.text == "matches" {
+		if lit, ok := call.args[1].(*literalNode); ok {
+			pattern, isString := lit.value.(string)
+			if !isString {
+				return nil, fmt.Errorf("%w: matches takes a string pattern", ErrInvalidExpression)
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidExpression, err)
+			}
+			call.args[1] = &literalNode{value: re}
+		}
+	}
+	return call, nil
+}
+
+// Evaluation
+
+type node interface {
+	eval(input map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(input map[string]interface{}) (interface{}, error) {
+	values := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+type pathNode struct {
+	path []string
+}
+
+func (n *pathNode) eval(input map[string]interface{}) (interface{}, error) {
+	var v interface{} = input
+	for _, field := range n.path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		v = m[field]
+	}
+	return normalize(v), nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(input map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("cannot negate %s", typeName(v))
+	}
+	return !b, nil
+}
+
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (n *logicalNode) eval(input map[string]interface{}) (interface{}, error) {
+	left, err := n.operand(n.left, input)
+	if err != nil {
+		return nil, err
+	}
+	// && stops at false and || at true
+	if left != n.and {
+		return left, nil
+	}
+	return n.operand(n.right, input)
+}
+
+func (n *logicalNode) operand(operand node, input map[string]interface{}) (bool, error) {
+	v, err := operand.eval(input)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		op := "||"
+		if n.and {
+			op = "&&"
+		}
+		return false, fmt.Errorf("operands of %s must be booleans, got %s", op, typeName(v))
+	}
+	return b, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(input map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		switch r := right.(type) {
+		case []interface{}:
+			for _, item := range r {
+				if equal(left, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			key, ok := left.(string)
+			if !ok {
+				return nil, fmt.Errorf("cannot look up %s in an object", typeName(left))
+			}
+			_, found := r[key]
+			return found, nil
+		case nil:
+			return false, nil
+		}
+		return nil, fmt.Errorf("right operand of in must be a list or object, got %s", typeName(right))
+	}
+
+	// Ordering compares numbers with numbers and strings with strings
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number with %s", typeName(right))
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string with %s", typeName(right))
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, fmt.Errorf("cannot order %s", typeName(left))
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+type callNode struct {
+	name string
+	fn   func(args []interface{}) (interface{}, error)
+	args []node
+}
+
+func (n *callNode) eval(input map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+type function struct {
+	arity int
+	call  func(args []interface{}) (interface{}, error)
+}
+
+// functions are the built-ins conditions may call
+var functions = map[string]function{
+	"startsWith": {2, stringPredicate(strings.HasPrefix)},
+	"endsWith":   {2, stringPredicate(strings.HasSuffix)},
+	"contains": {2, func(args []interface{}) (interface{}, error) {
+		if list, ok := args[0].([]interface{}); ok {
+			for _, item := range list {
+				if equal(item, args[1]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return stringPredicate(strings.Contains)(args)
+	}},
+	"matches": {2, func(args []interface{}) (interface{}, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return false, nil
+		}
+		switch pattern := args[1].(type) {
+		case *regexp.Regexp:
+			return pattern.MatchString(s), nil
+		case string:
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			return re.MatchString(s), nil
+		}
+		return nil, fmt.Errorf("pattern must be a string, got %s", typeName(args[1]))
+	}},
+	"size": {1, func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case string:
+			return float64(len(v)), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		case nil:
+			return float64(0), nil
+		}
+		return nil, fmt.Errorf("cannot take the size of %s", typeName(args[0]))
+	}},
+}
+
+// stringPredicate adapts f so that a non-string first argument, such as a
+// missing parameter, is false rather than an error
+func stringPredicate(f func(s, substr string) bool) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return false, nil
+		}
+		substr, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("second argument must be a string, got %s", typeName(args[1]))
+		}
+		return f(s, substr), nil
+	}
+}
+
+// normalize converts input values to the types expressions operate on:
+// float64 for numbers, []interface{} for lists and map[string]interface{}
+// for objects
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, bool, string, float64, []interface{}, map[string]interface{}:
+		return v
+	case int:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case float32:
+		return float64(x)
+	case []string:
+		list := make([]interface{}, len(x))
+		for i, s := range x {
+			list[i] = s
+		}
+		return list
+	case map[string]string:
+		m := make(map[string]interface{}, len(x))
+		for k, s := range x {
+			m[k] = s
+		}
+		return m
+	}
+	return fmt.Sprint(v)
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,92 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpression_Eval(t *testing.T) {
+	input := map[string]interface{}{
+		"principal": map[string]interface{}{"id": "u1", "roles": []interface{}{"operator", "viewer"}},
+		"tool":      map[string]interface{}{"name": "http_get"},
+		"params": map[string]interface{}{
+			"url":     "https://internal.example.com/a",
+			"retries": float64(3),
+			"headers": map[string]interface{}{"x-trace": "1"},
+		},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`tool.name == "http_get"`, true},
+		{`tool.name != 'http_get'`, false},
+		{`"operator" in principal.roles`, true},
+		{`principal.id in ["u2", "u3"]`, false},
+		{`"x-trace" in params.headers`, true},
+		{`startsWith(params.url, "https://internal.")`, true},
+		{`endsWith(params.url, "/b")`, false},
+		{`contains(params.url, "example")`, true},
+		{`contains(principal.roles, "admin")`, false},
+		{`matches(params.url, "^https://[a-z]+\\.example\\.com/")`, true},
+		{`params.retries <= 3 && params.retries > -1`, true},
+		{`size(principal.roles) == 2`, true},
+		{`params.missing == null`, true},
+		{`startsWith(params.missing, "x")`, false},
+		{`"a" in params.missing`, false},
+		{`!(tool.name == "shell") || false`, true},
+		{`true && false || true`, true},
+		{`"b" > "a"`, true},
+		// The right operand is not evaluated once the left decides
+		{`false && params.retries > "x"`, false},
+		{`true || params.retries > "x"`, true},
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.expr)
+		require.NoError(t, err, tt.expr)
+		got, err := e.Eval(input)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, got, tt.expr)
+	}
+}
+
+func TestExpression_EvalErrors(t *testing.T) {
+	input := map[string]interface{}{"params": map[string]interface{}{"n": float64(1)}}
+	for _, expr := range []string{
+		`params.n`,
+		`params.n > "1"`,
+		`!params.n`,
+		`params.n && true`,
+		`params < 2`,
+		`"a" in "abc"`,
+		`matches("a", params.n)`,
+	} {
+		e, err := Compile(expr)
+		require.NoError(t, err, expr)
+		_, err = e.Eval(input)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`tool.name ==`,
+		`tool.name = "x"`,
+		`exec("rm -rf /")`,
+		`startsWith(tool.name)`,
+		`matches(tool.name, "(")`,
+		`"unterminated`,
+		`tool.`,
+		`(true`,
+		`[1, 2`,
+		`true true`,
+		strings.Repeat("(", maxExpressionLength) + "true",
+	} {
+		_, err := Compile(expr)
+		assert.ErrorIs(t, err, ErrInvalidExpression, expr)
+	}
+}
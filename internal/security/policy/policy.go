@@ -0,0 +1,149 @@
+// Package policy evaluates the policies that decide whether principals may
+// run workflows, agents may call tools and messages may pass between agents
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Actions a policy decides on
+const (
+	ActionWorkflowRun = "workflow.run"
+	ActionToolCall    = "tool.call"
+	ActionMessageSend = "message.send"
+)
+
+// Rule effects
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Policy modes. A dry-run policy is evaluated and its decision audited, but
+// it is not enforced.
+const (
+	ModeEnforce = "enforce"
+	ModeDryRun  = "dry_run"
+)
+
+// ErrInvalidPolicy is returned for policy documents that do not load
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// Policy is a named set of rules. Rules whose condition holds decide the
+// action they apply to; a matching deny rule overrides any allow rule. When
+// no rule matches, Default decides, or the policy abstains if it is empty.
+//
+//	{
+//	  "mode": "enforce",
+//	  "default": "deny",
+//	  "rules": [
+//	    {"id": "internal-only", "effect": "allow", "actions": ["tool.call"],
+//	     "when": "startsWith(params.url, \"https://internal.\")"}
+//	  ]
+//	}
+type Policy struct {
+	Name    string `json:"name,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+	Default string `json:"default,omitempty"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Rule allows or denies the actions it lists when its condition holds. A rule
+// without actions applies to every action, and one without a condition
+// always holds.
+type Rule struct {
+	ID          string   `json:"id"`
+	Effect      string   `json:"effect"`
+	Description string   `json:"description,omitempty"`
+	Actions     []string `json:"actions,omitempty"`
+	When        string   `json:"when,omitempty"`
+
+	condition *Expression
+}
+
+// Parse loads a policy document and compiles its conditions. Documents
+// without rules, such as the empty objects agents and tools are created
+// with, hold no policy and return nil.
+func Parse(name string, data []byte) (*Policy, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrInvalidPolicy, name, err)
+	}
+	if _, ok := fields["rules"]; !ok {
+		return nil, nil
+	}
+
+	p := &Policy{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrInvalidPolicy, name, err)
+	}
+	if p.Name == "" {
+		p.Name = name
+	}
+	if err := p.compile(); err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrInvalidPolicy, p.Name, err)
+	}
+	return p, nil
+}
+
+// compile checks the policy and compiles its rule conditions
+func (p *Policy) compile() error {
+	switch p.Mode {
+	case "":
+		p.Mode = ModeEnforce
+	case ModeEnforce, ModeDryRun:
+	default:
+		return fmt.Errorf("unknown mode %q", p.Mode)
+	}
+	if p.Default != "" && p.Default != EffectAllow && p.Default != EffectDeny {
+		return fmt.Errorf("unknown default %q", p.Default)
+	}
+
+	seen := make(map[string]bool, len(p.Rules))
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule-%d", i+1)
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("duplicate rule %s", rule.ID)
+		}
+		seen[rule.ID] = true
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("rule %s: unknown effect %q", rule.ID, rule.Effect)
+		}
+		for _, action := range rule.Actions {
+			if action != ActionWorkflowRun && action != ActionToolCall && action != ActionMessageSend && action != "*" {
+				return fmt.Errorf("rule %s: unknown action %q", rule.ID, action)
+			}
+		}
+		if rule.When != "" {
+			condition, err := Compile(rule.When)
+			if err != nil {
+				return fmt.Errorf("rule %s: %w", rule.ID, err)
+			}
+			rule.condition = condition
+		}
+	}
+	return nil
+}
+
+// appliesTo reports whether the rule decides action
+func (r *Rule) appliesTo(action string) bool {
+	if len(r.Actions) == 0 {
+		return true
+	}
+	for _, a := range r.Actions {
+		if a == action || a == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Querier is the subset of queries.Querier the database source reads
+type Querier interface {
+	ListEnabledPolicies(ctx context.Context, tenantID pgtype.UUID) ([]queries.Policy, error)
+	GetAgent(ctx context.Context, arg queries.GetAgentParams) (queries.Agent, error)
+	GetAgentByName(ctx context.Context, arg queries.GetAgentByNameParams) (queries.Agent, error)
+	GetToolByName(ctx context.Context, arg queries.GetToolByNameParams) (queries.Tool, error)
+}
+
+// dbSource loads policies from the policies table, agents.policies_json and
+// tools.permissions
+type dbSource struct {
+	q Querier
+}
+
+// NewDBSource creates a source that covers every request with the tenant's
+// enabled policies. Tool calls are also covered by the calling agent's
+// policies and the tool's permissions, and messages by the policies of the
+// sending and receiving agents.
+func NewDBSource(q Querier) Source {
+	return &dbSource{q: q}
+}
+
+// Policies returns the policies covering req. Tenants whose ID is not a UUID
+// have no stored policies.
+func (s *dbSource) Policies(ctx context.Context, req *Request) ([]*Policy, error) {
+	var tenant pgtype.UUID
+	if tenant.Scan(req.TenantID) != nil {
+		return nil, nil
+	}
+
+	rows, err := s.q.ListEnabledPolicies(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant policies: %w", err)
+	}
+	var policies []*Policy
+	for _, row := range rows {
+		p, err := Parse(row.Name, row.Document)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+
+	switch req.Action {
+	case ActionToolCall:
+		if req.ActorType == "agent" {
+			if policies, err = s.appendAgent(ctx, policies, tenant, req.ActorID); err != nil {
+				return nil, err
+			}
+		}
+		tool, err := s.q.GetToolByName(ctx, queries.GetToolByNameParams{TenantID: tenant, Name: req.ResourceID})
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+		case err != nil:
+			return nil, fmt.Errorf("failed to load tool %s: %w", req.ResourceID, err)
+		default:
+			p, err := Parse("tool:"+tool.Name, tool.Permissions)
+			if err != nil {
+				return nil, err
+			}
+			policies = append(policies, p)
+		}
+	case ActionMessageSend:
+		for _, agent := range []string{req.ActorID, req.ResourceID} {
+			if policies, err = s.appendAgent(ctx, policies, tenant, agent); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return policies, nil
+}
+
+// appendAgent appends the policy of the agent with the given ID or name, if
+// it is registered and has one
+func (s *dbSource) appendAgent(ctx context.Context, policies []*Policy, tenant pgtype.UUID, agentID string) ([]*Policy, error) {
+	if agentID == "" {
+		return policies, nil
+	}
+	var agent queries.Agent
+	var id pgtype.UUID
+	var err error
+	if id.Scan(agentID) == nil {
+		agent, err = s.q.GetAgent(ctx, queries.GetAgentParams{ID: id, TenantID: tenant})
+	} else {
+		agent, err = s.q.GetAgentByName(ctx, queries.GetAgentByNameParams{TenantID: tenant, Name: agentID})
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return policies, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load agent %s: %w", agentID, err)
+	}
+	p, err := Parse("agent:"+agent.Name, agent.PoliciesJson)
+	if err != nil {
+		return nil, err
+	}
+	return append(policies, p), nil
+}
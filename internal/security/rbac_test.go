@@ -0,0 +1,209 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	rbacTestTenant = "00000000-0000-0000-0000-00000000000a"
+	rbacTestUser   = "00000000-0000-0000-0000-00000000000b"
+)
+
+// rbacQueries keeps rbac_roles and rbac_bindings rows in memory
+type rbacQueries struct {
+	roles    []queries.RbacRole
+	bindings []queries.RbacBinding
+	err      error
+}
+
+func rbacUUID(s string) pgtype.UUID {
+	var id pgtype.UUID
+	_ = id.Scan(s)
+	return id
+}
+
+// addRole adds a role of the test tenant whose ID ends in n
+func (q *rbacQueries) addRole(n int, name string, parent int, permissions string) {
+	role := queries.RbacRole{
+		ID:          pgtype.UUID{Bytes: [16]byte{15: byte(n)}, Valid: true},
+		TenantID:    rbacUUID(rbacTestTenant),
+		Name:        name,
+		Permissions: []byte(permissions),
+	}
+	if parent != 0 {
+		role.ParentID = pgtype.UUID{Bytes: [16]byte{15: byte(parent)}, Valid: true}
+	}
+	q.roles = append(q.roles, role)
+}
+
+func (q *rbacQueries) bind(user string, role int) {
+	q.bindings = append(q.bindings, queries.RbacBinding{
+		TenantID: rbacUUID(rbacTestTenant),
+		UserID:   rbacUUID(user),
+		RoleID:   pgtype.UUID{Bytes: [16]byte{15: byte(role)}, Valid: true},
+	})
+}
+
+func (q *rbacQueries) ListTenantRoles(ctx context.Context, tenantID pgtype.UUID) ([]queries.RbacRole, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	var roles []queries.RbacRole
+	for _, r := range q.roles {
+		if r.TenantID == tenantID {
+			roles = append(roles, r)
+		}
+	}
+	return roles, nil
+}
+
+func (q *rbacQueries) ListUserRoleIDs(ctx context.Context, arg queries.ListUserRoleIDsParams) ([]pgtype.UUID, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	var ids []pgtype.UUID
+	for _, b := range q.bindings {
+		if b.TenantID == arg.TenantID && b.UserID == arg.UserID {
+			ids = append(ids, b.RoleID)
+		}
+	}
+	return ids, nil
+}
+
+func TestRBACAuthorizer_Inheritance(t *testing.T) {
+	q := &rbacQueries{}
+	q.addRole(1, "viewer", 0, `["workflows:read","agents:read"]`)
+	q.addRole(2, "operator", 1, `["workflows:execute"]`)
+	q.addRole(3, "admin", 2, `["*"]`)
+	q.addRole(4, "auditor", 0, `["audits:read"]`)
+	q.bind(rbacTestUser, 2)
+	q.bind(rbacTestUser, 4)
+	authz := NewRBACAuthorizer(q)
+	ctx := context.Background()
+
+	effective, err := authz.EffectivePermissions(ctx, rbacTestTenant, rbacTestUser)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"auditor", "operator"}, effective.Roles, "only bound roles are listed")
+	assert.Equal(t, []string{"agents:read", "audits:read", "workflows:execute", "workflows:read"}, effective.Permissions)
+
+	// Inheritance cycles end where a role repeats
+	q.roles[0].ParentID = q.roles[1].ID
+	effective, err = authz.EffectivePermissions(ctx, rbacTestTenant, rbacTestUser)
+	require.NoError(t, err)
+	assert.Len(t, effective.Permissions, 4)
+
+	// Users without bindings, and IDs that are not UUIDs, have no permissions
+	for _, user := range []string{"00000000-0000-0000-0000-0000000000ff", "user456"} {
+		effective, err = authz.EffectivePermissions(ctx, rbacTestTenant, user)
+		require.NoError(t, err)
+		assert.Empty(t, effective.Roles)
+		assert.Empty(t, effective.Permissions)
+	}
+
+	q.roles[3].Permissions = []byte(`"audits:read"`)
+	_, err = authz.EffectivePermissions(ctx, rbacTestTenant, rbacTestUser)
+	assert.ErrorContains(t, err, "invalid permissions on role auditor")
+
+	q.err = errors.New("database down")
+	_, err = authz.EffectivePermissions(ctx, rbacTestTenant, rbacTestUser)
+	assert.Error(t, err)
+}
+
+func TestPermitted(t *testing.T) {
+	tests := []struct {
+		granted    string
+		resourceID string
+		action     string
+		want       bool
+	}{
+		{"*", "", "delete", true},
+		{"workflows:execute", "", "execute", true},
+		{"workflows:execute", "", "read", false},
+		{"workflows:*", "", "read", true},
+		{"agents:read", "", "read", false},
+		// Type-wide grants cover every instance
+		{"workflows:execute", "wf-1", "execute", true},
+		{"workflows:*", "wf-1", "delete", true},
+		// Instance grants cover only their instance
+		{"workflows:wf-1:execute", "wf-1", "execute", true},
+		{"workflows:wf-1:execute", "wf-2", "execute", false},
+		{"workflows:wf-1:execute", "wf-1", "delete", false},
+		{"workflows:wf-1:execute", "", "execute", false},
+		{"workflows:wf-1:*", "wf-1", "delete", true},
+		{"workflows:*:execute", "wf-2", "execute", true},
+		{"workflows", "", "execute", false},
+	}
+	for _, tt := range tests {
+		got := permitted([]string{tt.granted}, "workflows", tt.resourceID, tt.action)
+		assert.Equal(t, tt.want, got, "%s on %q for %s", tt.granted, tt.resourceID, tt.action)
+	}
+}
+
+func TestAuthMiddleware_Authorizer(t *testing.T) {
+	q := &rbacQueries{}
+	q.addRole(1, "viewer", 0, `["workflows:read"]`)
+	auth := newRefreshTestAuthenticator()
+	middleware := NewAuthMiddleware(auth, logging.NewLogger(), nil).WithAuthorizer(NewRBACAuthorizer(q))
+	ctx := context.Background()
+
+	// Permissions in the token are not trusted
+	issued, err := auth.IssueToken(ctx, &TokenRequest{
+		TenantID:    rbacTestTenant,
+		UserID:      rbacTestUser,
+		Roles:       []string{"admin"},
+		Permissions: []string{"*"},
+	})
+	require.NoError(t, err)
+
+	var seenPermissions []string
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenPermissions = GetUserPermissionsFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+issued.AccessToken)
+		w := httptest.NewRecorder()
+		middleware.Middleware()(handler).ServeHTTP(w, req)
+		return w
+	}
+	readWorkflows := middleware.RequirePermission("workflows", "read")(ok)
+	admins := middleware.RequireRole("admin")(ok)
+
+	assert.Equal(t, http.StatusForbidden, serve(readWorkflows, "/api/v1/workflows").Code)
+	assert.Equal(t, http.StatusForbidden, serve(admins, "/api/v1/workflows").Code)
+
+	// A binding applies to the next request with the same token
+	q.bind(rbacTestUser, 1)
+	assert.Equal(t, http.StatusOK, serve(readWorkflows, "/api/v1/workflows").Code)
+	assert.Equal(t, []string{"workflows:read"}, seenPermissions)
+	assert.Equal(t, http.StatusForbidden, serve(admins, "/api/v1/workflows").Code)
+
+	// Resource-scoped grants
+	q.roles[0].Permissions = []byte(`["workflows:wf-1:execute"]`)
+	lastSegment := func(r *http.Request) string {
+		return r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	}
+	execute := middleware.RequireResourcePermission("workflows", "execute", lastSegment)(ok)
+	assert.Equal(t, http.StatusOK, serve(execute, "/api/v1/workflows/wf-1").Code)
+	w := serve(execute, "/api/v1/workflows/wf-2")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "workflows:wf-2:execute")
+
+	// Permissions that cannot be resolved fail the request
+	q.err = errors.New("database down")
+	w = serve(ok, "/api/v1/workflows")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "authorization_unavailable")
+}
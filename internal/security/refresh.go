@@ -0,0 +1,234 @@
+package security
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Refresh token errors
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid, expired or revoked")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used")
+)
+
+// RefreshReuseError reports a rotated refresh token presented again. The
+// token or its replacement has leaked, so the whole family is revoked.
+type RefreshReuseError struct {
+	TenantID string
+	UserID   string
+	FamilyID string
+}
+
+func (e *RefreshReuseError) Error() string {
+	return fmt.Sprintf("refresh token of family %s was already used; the family has been revoked", e.FamilyID)
+}
+
+// Is matches ErrRefreshTokenReused
+func (e *RefreshReuseError) Is(target error) bool {
+	return target == ErrRefreshTokenReused
+}
+
+// RefreshToken is a stored refresh token. Only the hash of the token is kept.
+type RefreshToken struct {
+	Hash        []byte
+	FamilyID    string
+	TenantID    string
+	UserID      string
+	Roles       []string
+	Permissions []string
+	ExpiresAt   time.Time
+}
+
+// RefreshTokenStore persists refresh tokens for rotation
+type RefreshTokenStore interface {
+	// Create stores a new, unused token
+	Create(ctx context.Context, token RefreshToken) error
+	// Use marks the token with hash used and returns it. A token used before
+	// is returned with ErrRefreshTokenReused; an unknown, expired or revoked
+	// token gives ErrRefreshTokenInvalid.
+	Use(ctx context.Context, hash []byte) (RefreshToken, error)
+	// RevokeFamily revokes every token of a family
+	RevokeFamily(ctx context.Context, familyID string) error
+}
+
+// RefreshTokenPersister is implemented by authenticators whose refresh
+// tokens can be kept in a RefreshTokenStore
+type RefreshTokenPersister interface {
+	SetRefreshTokenStore(store RefreshTokenStore)
+}
+
+// hashRefreshToken returns the hash a refresh token is stored under. Tokens
+// carry 256 random bits, so an unsalted hash cannot be reversed.
+func hashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// memoryRefreshStore keeps refresh tokens in memory, so they do not survive
+// a restart or work across replicas
+type memoryRefreshStore struct {
+	mu     sync.Mutex
+	tokens map[string]*memoryRefreshToken
+	now    func() time.Time
+}
+
+type memoryRefreshToken struct {
+	RefreshToken
+	used    bool
+	revoked bool
+}
+
+// NewMemoryRefreshTokenStore creates a process-local refresh token store
+func NewMemoryRefreshTokenStore() RefreshTokenStore {
+	return &memoryRefreshStore{tokens: make(map[string]*memoryRefreshToken), now: time.Now}
+}
+
+func (s *memoryRefreshStore) Create(ctx context.Context, token RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Expired tokens can no longer be used or reused, so drop them
+	now := s.now()
+	for key, t := range s.tokens {
+		if !now.Before(t.ExpiresAt) {
+			delete(s.tokens, key)
+		}
+	}
+	s.tokens[string(token.Hash)] = &memoryRefreshToken{RefreshToken: token}
+	return nil
+}
+
+func (s *memoryRefreshStore) Use(ctx context.Context, hash []byte) (RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[string(hash)]
+	switch {
+	case !ok || t.revoked || !s.now().Before(t.ExpiresAt):
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	case t.used:
+		return t.RefreshToken, ErrRefreshTokenReused
+	}
+	t.used = true
+	return t.RefreshToken, nil
+}
+
+func (s *memoryRefreshStore) RevokeFamily(ctx context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tokens {
+		if t.FamilyID == familyID {
+			t.revoked = true
+		}
+	}
+	return nil
+}
+
+// RefreshTokenQuerier is the subset of queries.Querier used by the database
+// refresh token store
+type RefreshTokenQuerier interface {
+	CreateRefreshToken(ctx context.Context, arg queries.CreateRefreshTokenParams) error
+	UseRefreshToken(ctx context.Context, tokenHash []byte) (queries.RefreshToken, error)
+	GetRefreshToken(ctx context.Context, tokenHash []byte) (queries.RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID pgtype.UUID) (int64, error)
+}
+
+// dbRefreshStore keeps refresh tokens in the refresh_tokens table
+type dbRefreshStore struct {
+	q RefreshTokenQuerier
+}
+
+// NewDBRefreshTokenStore creates a refresh token store backed by the
+// refresh_tokens table. Tokens must belong to a tenant stored in tenants.
+func NewDBRefreshTokenStore(q RefreshTokenQuerier) RefreshTokenStore {
+	return &dbRefreshStore{q: q}
+}
+
+func (s *dbRefreshStore) Create(ctx context.Context, token RefreshToken) error {
+	var familyID, tenantID pgtype.UUID
+	if err := familyID.Scan(token.FamilyID); err != nil {
+		return fmt.Errorf("invalid token family ID: %w", err)
+	}
+	if err := tenantID.Scan(token.TenantID); err != nil {
+		return fmt.Errorf("invalid tenant ID: %w", err)
+	}
+	roles, permissions := token.Roles, token.Permissions
+	if roles == nil {
+		roles = []string{}
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	err := s.q.CreateRefreshToken(ctx, queries.CreateRefreshTokenParams{
+		TokenHash:   token.Hash,
+		FamilyID:    familyID,
+		TenantID:    tenantID,
+		UserID:      token.UserID,
+		Roles:       roles,
+		Permissions: permissions,
+		ExpiresAt:   pgtype.Timestamptz{Time: token.ExpiresAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+func (s *dbRefreshStore) Use(ctx context.Context, hash []byte) (RefreshToken, error) {
+	// The conditional update lets only one of several concurrent uses succeed
+	row, err := s.q.UseRefreshToken(ctx, hash)
+	if err == nil {
+		return refreshTokenFromRow(row), nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return RefreshToken{}, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+
+	row, err = s.q.GetRefreshToken(ctx, hash)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	case err != nil:
+		return RefreshToken{}, fmt.Errorf("failed to read refresh token: %w", err)
+	case row.RevokedAt.Valid || !time.Now().Before(row.ExpiresAt.Time):
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	case row.UsedAt.Valid:
+		return refreshTokenFromRow(row), ErrRefreshTokenReused
+	}
+	// Expired between the two queries by the database clock
+	return RefreshToken{}, ErrRefreshTokenInvalid
+}
+
+func (s *dbRefreshStore) RevokeFamily(ctx context.Context, familyID string) error {
+	var id pgtype.UUID
+	if err := id.Scan(familyID); err != nil {
+		return fmt.Errorf("invalid token family ID: %w", err)
+	}
+	if _, err := s.q.RevokeRefreshTokenFamily(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return nil
+}
+
+func refreshTokenFromRow(row queries.RefreshToken) RefreshToken {
+	return RefreshToken{
+		Hash:        row.TokenHash,
+		FamilyID:    uuid.UUID(row.FamilyID.Bytes).String(),
+		TenantID:    uuid.UUID(row.TenantID.Bytes).String(),
+		UserID:      row.UserID,
+		Roles:       row.Roles,
+		Permissions: row.Permissions,
+		ExpiresAt:   row.ExpiresAt.Time,
+	}
+}
@@ -0,0 +1,222 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRefreshTestAuthenticator() Authenticator {
+	return NewAuthenticator(&AuthConfig{
+		JWTSecret:     "test-secret-32-characters-long",
+		TokenExpiry:   time.Hour,
+		RefreshExpiry: time.Hour,
+	})
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	auth := newRefreshTestAuthenticator()
+	ctx := context.Background()
+
+	issued, err := auth.IssueToken(ctx, &TokenRequest{
+		TenantID:    auditTestTenant,
+		UserID:      "user456",
+		Roles:       []string{"developer"},
+		Permissions: []string{"workflows:read"},
+	})
+	require.NoError(t, err)
+
+	refreshed, err := auth.RefreshToken(ctx, issued.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, issued.RefreshToken, refreshed.RefreshToken)
+	claims, err := auth.ValidateToken(ctx, refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, auditTestTenant, claims.TenantID)
+	assert.Equal(t, []string{"developer"}, claims.Roles)
+	assert.Equal(t, []string{"workflows:read"}, claims.Permissions)
+
+	// The rotated token chains on
+	latest, err := auth.RefreshToken(ctx, refreshed.RefreshToken)
+	require.NoError(t, err)
+
+	// Replaying the first token revokes the family, including its newest token
+	_, err = auth.RefreshToken(ctx, issued.RefreshToken)
+	var reuse *RefreshReuseError
+	require.ErrorAs(t, err, &reuse)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	assert.Equal(t, auditTestTenant, reuse.TenantID)
+	assert.Equal(t, "user456", reuse.UserID)
+
+	_, err = auth.RefreshToken(ctx, latest.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+
+	// Other families are unaffected
+	other, err := auth.IssueToken(ctx, &TokenRequest{TenantID: auditTestTenant, UserID: "user456"})
+	require.NoError(t, err)
+	_, err = auth.RefreshToken(ctx, other.RefreshToken)
+	assert.NoError(t, err)
+
+	_, err = auth.RefreshToken(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+}
+
+func TestMemoryRefreshStore_Expiry(t *testing.T) {
+	store := NewMemoryRefreshTokenStore().(*memoryRefreshStore)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	token := RefreshToken{Hash: hashRefreshToken("token"), FamilyID: "family", ExpiresAt: now.Add(time.Minute)}
+	require.NoError(t, store.Create(ctx, token))
+
+	now = now.Add(2 * time.Minute)
+	_, err := store.Use(ctx, token.Hash)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+
+	// Expired tokens are dropped when the next one is stored
+	require.NoError(t, store.Create(ctx, RefreshToken{Hash: hashRefreshToken("next"), ExpiresAt: now.Add(time.Minute)}))
+	assert.Len(t, store.tokens, 1)
+}
+
+// refreshQueries keeps refresh_tokens rows in memory
+type refreshQueries struct {
+	rows map[string]*queries.RefreshToken
+}
+
+func (q *refreshQueries) CreateRefreshToken(ctx context.Context, arg queries.CreateRefreshTokenParams) error {
+	q.rows[string(arg.TokenHash)] = &queries.RefreshToken{
+		TokenHash:   arg.TokenHash,
+		FamilyID:    arg.FamilyID,
+		TenantID:    arg.TenantID,
+		UserID:      arg.UserID,
+		Roles:       arg.Roles,
+		Permissions: arg.Permissions,
+		ExpiresAt:   arg.ExpiresAt,
+	}
+	return nil
+}
+
+func (q *refreshQueries) UseRefreshToken(ctx context.Context, tokenHash []byte) (queries.RefreshToken, error) {
+	row, ok := q.rows[string(tokenHash)]
+	if !ok || row.UsedAt.Valid || row.RevokedAt.Valid || !time.Now().Before(row.ExpiresAt.Time) {
+		return queries.RefreshToken{}, pgx.ErrNoRows
+	}
+	row.UsedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return *row, nil
+}
+
+func (q *refreshQueries) GetRefreshToken(ctx context.Context, tokenHash []byte) (queries.RefreshToken, error) {
+	row, ok := q.rows[string(tokenHash)]
+	if !ok {
+		return queries.RefreshToken{}, pgx.ErrNoRows
+	}
+	return *row, nil
+}
+
+func (q *refreshQueries) RevokeRefreshTokenFamily(ctx context.Context, familyID pgtype.UUID) (int64, error) {
+	var n int64
+	for _, row := range q.rows {
+		if row.FamilyID == familyID && !row.RevokedAt.Valid {
+			row.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestDBRefreshStore(t *testing.T) {
+	q := &refreshQueries{rows: make(map[string]*queries.RefreshToken)}
+	auth := newRefreshTestAuthenticator()
+	auth.(RefreshTokenPersister).SetRefreshTokenStore(NewDBRefreshTokenStore(q))
+	ctx := context.Background()
+
+	issued, err := auth.IssueToken(ctx, &TokenRequest{TenantID: auditTestTenant, UserID: "user456", Roles: []string{"viewer"}})
+	require.NoError(t, err)
+	require.Len(t, q.rows, 1)
+	for hash := range q.rows {
+		assert.NotContains(t, hash, issued.RefreshToken, "only the hash is stored")
+	}
+
+	refreshed, err := auth.RefreshToken(ctx, issued.RefreshToken)
+	require.NoError(t, err)
+	claims, err := auth.ValidateToken(ctx, refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"viewer"}, claims.Roles)
+
+	_, err = auth.RefreshToken(ctx, issued.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	for _, row := range q.rows {
+		assert.True(t, row.RevokedAt.Valid, "every token of the family is revoked")
+	}
+	_, err = auth.RefreshToken(ctx, refreshed.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+
+	// Stored tokens need a tenant row, so the tenant must be a UUID
+	_, err = auth.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+	assert.Error(t, err)
+}
+
+func TestHandleTokenRefresh(t *testing.T) {
+	auth := newRefreshTestAuthenticator()
+	recorder := audit.NewMemoryRecorder()
+	handlers := NewAuthHandlers(auth, logging.NewLogger()).WithRecorder(recorder)
+
+	issued, err := auth.IssueToken(context.Background(), &TokenRequest{TenantID: auditTestTenant, UserID: "user456"})
+	require.NoError(t, err)
+
+	refresh := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handlers.HandleTokenRefresh(w, httptest.NewRequest("POST", "/api/v1/auth/refresh", bytes.NewReader([]byte(body))))
+		return w
+	}
+	body := func(token string) string {
+		data, _ := json.Marshal(TokenRefreshRequest{RefreshToken: token})
+		return string(data)
+	}
+
+	w := refresh(body(issued.RefreshToken))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Data TokenResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.Data.AccessToken)
+	assert.NotEqual(t, issued.RefreshToken, response.Data.RefreshToken)
+	require.Len(t, recorder.Events(), 1)
+	assert.Equal(t, AuditActionTokenRefresh, recorder.Events()[0].Action)
+	assert.Equal(t, audit.OutcomeSuccess, recorder.Events()[0].Outcome)
+
+	w = refresh(body(issued.RefreshToken))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "refresh_token_reused")
+	require.Len(t, recorder.Events(), 2)
+	assert.Equal(t, audit.OutcomeDenied, recorder.Events()[1].Outcome)
+	assert.Equal(t, "token_family", recorder.Events()[1].ResourceType)
+	assert.True(t, recorder.Events()[1].Critical)
+
+	w = refresh(body(response.Data.RefreshToken))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_refresh_token")
+
+	assert.Equal(t, http.StatusBadRequest, refresh(`{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, refresh(`not json`).Code)
+
+	// A refreshed token is withheld when its audit record cannot be written
+	next, err := auth.IssueToken(context.Background(), &TokenRequest{TenantID: auditTestTenant, UserID: "user456"})
+	require.NoError(t, err)
+	recorder.SetError(errors.New("audit store down"))
+	assert.Equal(t, http.StatusServiceUnavailable, refresh(body(next.RefreshToken)).Code)
+}
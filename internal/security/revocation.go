@@ -0,0 +1,356 @@
+package security
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nats-io/nats.go"
+)
+
+// Revocation errors
+var (
+	ErrTokenRevoked      = errors.New("token has been revoked")
+	ErrTokenNotRevocable = errors.New("token cannot be revoked")
+)
+
+// RevocationStore records revoked access tokens by JWT ID. A revocation only
+// needs to be kept until the token expires.
+type RevocationStore interface {
+	// Revoke records jti as revoked until expiresAt
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	// IsRevoked reports whether jti is revoked
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// RevocationPersister is implemented by authenticators whose revocations can
+// be kept in a RevocationStore
+type RevocationPersister interface {
+	SetRevocationStore(store RevocationStore)
+}
+
+// memoryRevocationStore keeps revocations in memory, so they do not survive
+// a restart or work across replicas
+type memoryRevocationStore struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+	now     func() time.Time
+}
+
+// NewMemoryRevocationStore creates a process-local revocation store
+func NewMemoryRevocationStore() RevocationStore {
+	return &memoryRevocationStore{revoked: make(map[string]time.Time), now: time.Now}
+}
+
+func (s *memoryRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Expired tokens fail validation anyway, so drop their revocations
+	now := s.now()
+	for id, exp := range s.revoked {
+		if !now.Before(exp) {
+			delete(s.revoked, id)
+		}
+	}
+	s.revoked[jti] = expiresAt
+	return nil
+}
+
+func (s *memoryRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exp, ok := s.revoked[jti]
+	return ok && s.now().Before(exp), nil
+}
+
+// RevocationQuerier is the subset of queries.Querier used by the database
+// revocation store
+type RevocationQuerier interface {
+	RevokeToken(ctx context.Context, arg queries.RevokeTokenParams) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpiredRevokedTokens(ctx context.Context) (int64, error)
+}
+
+// dbRevocationStore keeps revocations in the revoked_tokens table
+type dbRevocationStore struct {
+	q RevocationQuerier
+}
+
+// NewDBRevocationStore creates a revocation store backed by the
+// revoked_tokens table
+func NewDBRevocationStore(q RevocationQuerier) RevocationStore {
+	return &dbRevocationStore{q: q}
+}
+
+func (s *dbRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	err := s.q.RevokeToken(ctx, queries.RevokeTokenParams{
+		Jti:       jti,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store token revocation: %w", err)
+	}
+
+	// Expired rows are ignored by lookups, so a failed cleanup is retried on
+	// the next revocation
+	_, _ = s.q.DeleteExpiredRevokedTokens(ctx)
+	return nil
+}
+
+func (s *dbRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	revoked, err := s.q.IsTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return revoked, nil
+}
+
+// DefaultRevocationKeyPrefix prefixes the Redis keys revocations are kept under
+const DefaultRevocationKeyPrefix = "af:revoked:"
+
+// redisRevocationStore keeps each revocation in a Redis key that expires
+// with the token
+type redisRevocationStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisRevocationStore creates a revocation store that keeps revocations
+// under prefix+jti in Redis
+func NewRedisRevocationStore(client redis.Cmdable, prefix string) RevocationStore {
+	if prefix == "" {
+		prefix = DefaultRevocationKeyPrefix
+	}
+	return &redisRevocationStore{client: client, prefix: prefix}
+}
+
+func (s *redisRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := s.client.Set(ctx, s.prefix+jti, expiresAt.Unix(), ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store token revocation: %w", err)
+	}
+	return nil
+}
+
+func (s *redisRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := s.client.Exists(ctx, s.prefix+jti).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RevocationBus tells the other replicas about revocations so they can
+// update their caches
+type RevocationBus interface {
+	// Publish announces that jti is revoked until expiresAt
+	Publish(ctx context.Context, jti string, expiresAt time.Time) error
+	// Subscribe calls handler for every revocation announced, and returns a
+	// function that stops the subscription
+	Subscribe(handler func(jti string, expiresAt time.Time)) (func() error, error)
+}
+
+// DefaultRevocationSubject is the NATS subject revocations are announced on
+const DefaultRevocationSubject = "system.auth.revocations"
+
+// revocationMessage is a revocation announced on the bus
+type revocationMessage struct {
+	JTI       string    `json:"jti"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// natsRevocationBus announces revocations with core NATS publish/subscribe.
+// Announcements are not persisted; a replica that misses one falls back to
+// the store once its cached answer expires.
+type natsRevocationBus struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSRevocationBus creates a revocation bus on subject
+func NewNATSRevocationBus(conn *nats.Conn, subject string) RevocationBus {
+	if subject == "" {
+		subject = DefaultRevocationSubject
+	}
+	return &natsRevocationBus{conn: conn, subject: subject}
+}
+
+func (b *natsRevocationBus) Publish(ctx context.Context, jti string, expiresAt time.Time) error {
+	data, err := json.Marshal(revocationMessage{JTI: jti, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+	if err := b.conn.Publish(b.subject, data); err != nil {
+		return fmt.Errorf("failed to announce token revocation: %w", err)
+	}
+	return nil
+}
+
+func (b *natsRevocationBus) Subscribe(handler func(jti string, expiresAt time.Time)) (func() error, error) {
+	sub, err := b.conn.Subscribe(b.subject, func(msg *nats.Msg) {
+		var m revocationMessage
+		if err := json.Unmarshal(msg.Data, &m); err != nil || m.JTI == "" {
+			return
+		}
+		handler(m.JTI, m.ExpiresAt)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to token revocations: %w", err)
+	}
+	return sub.Unsubscribe, nil
+}
+
+// Defaults for the revocation cache
+const (
+	DefaultRevocationCacheSize = 10000
+	DefaultRevocationCacheTTL  = 30 * time.Second
+)
+
+// CachedRevocationStore answers lookups from a local LRU cache in front of a
+// shared store. Revoked tokens stay revoked, so those answers are kept until
+// the token expires; answers that a token is not revoked are kept for the
+// cache TTL, which bounds how long a replica that missed an announcement on
+// the bus keeps accepting a revoked token.
+type CachedRevocationStore struct {
+	store RevocationStore
+	bus   RevocationBus
+	size  int
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}
+
+// revocationEntry is a cached lookup; a zero until never expires
+type revocationEntry struct {
+	jti     string
+	revoked bool
+	until   time.Time
+}
+
+// NewCachedRevocationStore caches up to size lookups of store, keeping
+// answers that a token is not revoked for ttl
+func NewCachedRevocationStore(store RevocationStore, size int, ttl time.Duration) *CachedRevocationStore {
+	if size <= 0 {
+		size = DefaultRevocationCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultRevocationCacheTTL
+	}
+	return &CachedRevocationStore{
+		store:   store,
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Listen announces revocations on bus and applies the ones announced by
+// other replicas to the cache. The returned function stops listening.
+func (s *CachedRevocationStore) Listen(bus RevocationBus) (func() error, error) {
+	stop, err := bus.Subscribe(func(jti string, expiresAt time.Time) {
+		s.set(jti, true, expiresAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.bus = bus
+	s.mu.Unlock()
+	return stop, nil
+}
+
+// Revoke records the revocation in the store and announces it on the bus
+func (s *CachedRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := s.store.Revoke(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	s.set(jti, true, expiresAt)
+
+	s.mu.Lock()
+	bus := s.bus
+	s.mu.Unlock()
+	if bus != nil {
+		// The revocation is stored, so replicas that miss the announcement
+		// still see it once their cached answer expires
+		_ = bus.Publish(ctx, jti, expiresAt)
+	}
+	return nil
+}
+
+// IsRevoked answers from the cache, or from the store on a miss. Store
+// errors are not cached.
+func (s *CachedRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	if revoked, ok := s.get(jti); ok {
+		return revoked, nil
+	}
+
+	revoked, err := s.store.IsRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+	if revoked {
+		s.set(jti, true, time.Time{})
+	} else {
+		s.set(jti, false, s.now().Add(s.ttl))
+	}
+	return revoked, nil
+}
+
+func (s *CachedRevocationStore) get(jti string) (revoked, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[jti]
+	if !ok {
+		return false, false
+	}
+	entry := elem.Value.(*revocationEntry)
+	if !entry.until.IsZero() && !s.now().Before(entry.until) {
+		s.order.Remove(elem)
+		delete(s.entries, jti)
+		return false, false
+	}
+	s.order.MoveToFront(elem)
+	return entry.revoked, true
+}
+
+func (s *CachedRevocationStore) set(jti string, revoked bool, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[jti]; ok {
+		entry := elem.Value.(*revocationEntry)
+		// A revocation is never replaced by an older answer that the token
+		// was not revoked
+		if entry.revoked && !revoked {
+			return
+		}
+		entry.revoked, entry.until = revoked, until
+		s.order.MoveToFront(elem)
+		return
+	}
+
+	s.entries[jti] = s.order.PushFront(&revocationEntry{jti: jti, revoked: revoked, until: until})
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*revocationEntry).jti)
+	}
+}
@@ -0,0 +1,303 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRevocationTestAuthenticator(store RevocationStore) Authenticator {
+	auth := newRefreshTestAuthenticator()
+	auth.(RevocationPersister).SetRevocationStore(store)
+	return auth
+}
+
+func TestRevokeToken_SharedStore(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	replicaA := newRevocationTestAuthenticator(store)
+	replicaB := newRevocationTestAuthenticator(store)
+	ctx := context.Background()
+
+	revoked, err := replicaA.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+	require.NoError(t, err)
+	other, err := replicaA.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+	require.NoError(t, err)
+
+	require.NoError(t, replicaA.RevokeToken(ctx, revoked.AccessToken))
+
+	// Revocation is keyed by jti, so it holds on every replica and only for
+	// the revoked token
+	_, err = replicaB.ValidateToken(ctx, revoked.AccessToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = replicaB.ValidateToken(ctx, other.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestRevokeToken_Rejected(t *testing.T) {
+	store := NewMemoryRevocationStore().(*memoryRevocationStore)
+	auth := newRevocationTestAuthenticator(store)
+	ctx := context.Background()
+
+	err := auth.RevokeToken(ctx, "not-a-token")
+	assert.ErrorIs(t, err, ErrTokenNotRevocable)
+
+	// A token signed with another key cannot be revoked
+	foreign, err := NewAuthenticator(&AuthConfig{JWTSecret: "another-secret-32-characters-long", TokenExpiry: time.Hour}).
+		IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, auth.RevokeToken(ctx, foreign.AccessToken), ErrTokenNotRevocable)
+
+	// An expired token needs no revocation
+	expired, err := auth.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456", ExpiresIn: -60})
+	require.NoError(t, err)
+	assert.NoError(t, auth.RevokeToken(ctx, expired.AccessToken))
+	assert.Empty(t, store.revoked)
+}
+
+// failingRevocationStore fails every operation
+type failingRevocationStore struct{}
+
+func (failingRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	return errors.New("store down")
+}
+
+func (failingRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return false, errors.New("store down")
+}
+
+func TestValidateToken_RevocationStoreUnavailable(t *testing.T) {
+	auth := newRefreshTestAuthenticator()
+	ctx := context.Background()
+	issued, err := auth.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+	require.NoError(t, err)
+
+	auth.(RevocationPersister).SetRevocationStore(failingRevocationStore{})
+	_, err = auth.ValidateToken(ctx, issued.AccessToken)
+	assert.Error(t, err, "a token whose revocation cannot be checked is rejected")
+	assert.Error(t, auth.RevokeToken(ctx, issued.AccessToken))
+}
+
+func TestMemoryRevocationStore_Expiry(t *testing.T) {
+	store := NewMemoryRevocationStore().(*memoryRevocationStore)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, store.Revoke(ctx, "jti-1", now.Add(time.Minute)))
+	revoked, err := store.IsRevoked(ctx, "jti-1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	now = now.Add(2 * time.Minute)
+	revoked, err = store.IsRevoked(ctx, "jti-1")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Expired revocations are dropped when the next one is stored
+	require.NoError(t, store.Revoke(ctx, "jti-2", now.Add(time.Minute)))
+	assert.Len(t, store.revoked, 1)
+}
+
+// revocationQueries keeps revoked_tokens rows in memory
+type revocationQueries struct {
+	rows    map[string]time.Time
+	deletes int
+}
+
+func (q *revocationQueries) RevokeToken(ctx context.Context, arg queries.RevokeTokenParams) error {
+	if _, ok := q.rows[arg.Jti]; !ok {
+		q.rows[arg.Jti] = arg.ExpiresAt.Time
+	}
+	return nil
+}
+
+func (q *revocationQueries) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	exp, ok := q.rows[jti]
+	return ok && time.Now().Before(exp), nil
+}
+
+func (q *revocationQueries) DeleteExpiredRevokedTokens(ctx context.Context) (int64, error) {
+	q.deletes++
+	var n int64
+	for jti, exp := range q.rows {
+		if !time.Now().Before(exp) {
+			delete(q.rows, jti)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestDBRevocationStore(t *testing.T) {
+	q := &revocationQueries{rows: map[string]time.Time{"old": time.Now().Add(-time.Minute)}}
+	auth := newRevocationTestAuthenticator(NewDBRevocationStore(q))
+	ctx := context.Background()
+
+	issued, err := auth.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+	require.NoError(t, err)
+	claims, err := auth.ValidateToken(ctx, issued.AccessToken)
+	require.NoError(t, err)
+	require.NoError(t, auth.RevokeToken(ctx, issued.AccessToken))
+
+	assert.Contains(t, q.rows, claims.ID)
+	assert.WithinDuration(t, claims.ExpiresAt.Time, q.rows[claims.ID], time.Second, "the row expires with the token")
+	assert.NotContains(t, q.rows, "old", "expired rows are cleaned up")
+	assert.Equal(t, 1, q.deletes)
+
+	_, err = auth.ValidateToken(ctx, issued.AccessToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestRedisRevocationStore(t *testing.T) {
+	url := os.Getenv("AF_TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("AF_TEST_REDIS_URL not set")
+	}
+	opts, err := redis.ParseURL(url)
+	require.NoError(t, err)
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	ctx := context.Background()
+	prefix := "af:test:revoked:" + uuid.New().String() + ":"
+	store := NewRedisRevocationStore(client, prefix)
+
+	require.NoError(t, store.Revoke(ctx, "jti-1", time.Now().Add(time.Minute)))
+	revoked, err := store.IsRevoked(ctx, "jti-1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	ttl, err := client.TTL(ctx, prefix+"jti-1").Result()
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 2, "the key expires with the token")
+
+	revoked, err = store.IsRevoked(ctx, "jti-2")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+// countingRevocationStore counts lookups that reach the shared store
+type countingRevocationStore struct {
+	RevocationStore
+	mu      sync.Mutex
+	lookups int
+}
+
+func (s *countingRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.Lock()
+	s.lookups++
+	s.mu.Unlock()
+	return s.RevocationStore.IsRevoked(ctx, jti)
+}
+
+// memoryRevocationBus delivers announcements to every subscriber in process
+type memoryRevocationBus struct {
+	mu       sync.Mutex
+	handlers []func(string, time.Time)
+}
+
+func (b *memoryRevocationBus) Publish(ctx context.Context, jti string, expiresAt time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, h := range b.handlers {
+		h(jti, expiresAt)
+	}
+	return nil
+}
+
+func (b *memoryRevocationBus) Subscribe(handler func(string, time.Time)) (func() error, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return func() error { return nil }, nil
+}
+
+func TestCachedRevocationStore(t *testing.T) {
+	backend := &countingRevocationStore{RevocationStore: NewMemoryRevocationStore()}
+	cache := NewCachedRevocationStore(backend, 2, time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	isRevoked := func(jti string) bool {
+		t.Helper()
+		revoked, err := cache.IsRevoked(ctx, jti)
+		require.NoError(t, err)
+		return revoked
+	}
+
+	assert.False(t, isRevoked("jti-1"))
+	assert.False(t, isRevoked("jti-1"))
+	assert.Equal(t, 1, backend.lookups, "the second lookup is answered from the cache")
+
+	// Answers that a token is not revoked expire
+	now = now.Add(2 * time.Minute)
+	assert.False(t, isRevoked("jti-1"))
+	assert.Equal(t, 2, backend.lookups)
+
+	// Revoking through the cache updates it at once
+	require.NoError(t, cache.Revoke(ctx, "jti-1", now.Add(time.Hour)))
+	assert.True(t, isRevoked("jti-1"))
+	assert.Equal(t, 2, backend.lookups)
+
+	// The least recently used entry is evicted
+	assert.False(t, isRevoked("jti-2"))
+	assert.False(t, isRevoked("jti-3"))
+	assert.True(t, isRevoked("jti-1"))
+	assert.Equal(t, 5, backend.lookups, "jti-1 was evicted and looked up again")
+
+	// Store errors are returned and not cached
+	failing := NewCachedRevocationStore(failingRevocationStore{}, 0, 0)
+	_, err := failing.IsRevoked(ctx, "jti-1")
+	assert.Error(t, err)
+	assert.Empty(t, failing.entries)
+}
+
+func TestCachedRevocationStore_Bus(t *testing.T) {
+	shared := NewMemoryRevocationStore()
+	bus := &memoryRevocationBus{}
+	ctx := context.Background()
+
+	replicaA := NewCachedRevocationStore(shared, 0, time.Hour)
+	replicaB := NewCachedRevocationStore(shared, 0, time.Hour)
+	for _, replica := range []*CachedRevocationStore{replicaA, replicaB} {
+		_, err := replica.Listen(bus)
+		require.NoError(t, err)
+	}
+
+	// Replica B has cached that the token is not revoked
+	revoked, err := replicaB.IsRevoked(ctx, "jti-1")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, replicaA.Revoke(ctx, "jti-1", time.Now().Add(time.Hour)))
+
+	// The announcement updates B's cache before its answer expires
+	revoked, err = replicaB.IsRevoked(ctx, "jti-1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestHandleTokenRevoke_InvalidToken(t *testing.T) {
+	handlers := NewAuthHandlers(newRefreshTestAuthenticator(), logging.NewLogger())
+
+	req := httptest.NewRequest("POST", "/api/v1/auth/revoke", nil)
+	req.Header.Set("Authorization", "Bearer not-a-token")
+	w := httptest.NewRecorder()
+	handlers.HandleTokenRevoke(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_token")
+}
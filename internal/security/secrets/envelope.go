@@ -0,0 +1,390 @@
+// Copyright 2025 AgentFlow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// EnvelopeFormat identifies encrypted secrets files
+const EnvelopeFormat = "agentflow.secrets.encrypted/v1"
+
+// Kinds of master key
+const (
+	MasterKeyKindKey        = "key"
+	MasterKeyKindPassphrase = "passphrase"
+)
+
+// Encryption errors
+var (
+	ErrEncrypted        = errors.New("secrets file is encrypted and no master key is configured")
+	ErrNotEncrypted     = errors.New("secrets file is not encrypted")
+	ErrAlreadyEncrypted = errors.New("secrets file is already encrypted")
+	ErrDecryptionFailed = errors.New("failed to decrypt secrets file: wrong master key or tampered file")
+	ErrInvalidMasterKey = errors.New("invalid master key")
+)
+
+// masterKeySize is the size of raw master keys and data keys (AES-256)
+const masterKeySize = 32
+
+// argon2id parameters for passphrases; stored in each file, so they can be
+// raised without breaking existing files
+const (
+	kdfArgon2id = "argon2id"
+	kdfTime     = 3
+	kdfMemory   = 64 * 1024
+	kdfThreads  = 2
+	kdfSaltLen  = 16
+)
+
+// Upper bounds on the argon2id parameters read from a file, so a tampered
+// file cannot make opening it exhaust memory or CPU
+const (
+	kdfMaxTime    = 16
+	kdfMaxMemory  = 1024 * 1024
+	kdfMaxThreads = 16
+)
+
+// MasterKey wraps the data key an encrypted secrets file is encrypted with.
+// It is either a raw 256-bit key or a passphrase the wrapping key is derived
+// from with argon2id.
+type MasterKey struct {
+	key        []byte
+	passphrase []byte
+}
+
+// NewMasterKey returns a master key of 32 raw bytes
+func NewMasterKey(key []byte) (MasterKey, error) {
+	if len(key) != masterKeySize {
+		return MasterKey{}, fmt.Errorf("%w: want %d bytes, got %d", ErrInvalidMasterKey, masterKeySize, len(key))
+	}
+	return MasterKey{key: append([]byte(nil), key...)}, nil
+}
+
+// PassphraseMasterKey returns a master key derived from passphrase
+func PassphraseMasterKey(passphrase string) (MasterKey, error) {
+	if passphrase == "" {
+		return MasterKey{}, fmt.Errorf("%w: passphrase is empty", ErrInvalidMasterKey)
+	}
+	return MasterKey{passphrase: []byte(passphrase)}, nil
+}
+
+// ParseMasterKey returns the master key encoded as base64 or hex
+func ParseMasterKey(encoded string) (MasterKey, error) {
+	encoded = strings.TrimSpace(encoded)
+	for _, decode := range []func(string) ([]byte, error){
+		base64.StdEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+		hex.DecodeString,
+	} {
+		if key, err := decode(encoded); err == nil && len(key) == masterKeySize {
+			return NewMasterKey(key)
+		}
+	}
+	return MasterKey{}, fmt.Errorf("%w: want %d bytes encoded as base64 or hex", ErrInvalidMasterKey, masterKeySize)
+}
+
+// LoadMasterKeyFile reads a master key file holding 32 raw bytes or their
+// base64 or hex encoding
+func LoadMasterKeyFile(path string) (MasterKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return MasterKey{}, fmt.Errorf("failed to read master key file: %w", err)
+	}
+	if len(data) == masterKeySize {
+		return NewMasterKey(data)
+	}
+	key, err := ParseMasterKey(string(data))
+	if err != nil {
+		return MasterKey{}, fmt.Errorf("master key file %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// GenerateMasterKeyFile writes a new random master key, base64 encoded, to
+// path. It fails if path exists.
+func GenerateMasterKeyFile(path string) (MasterKey, error) {
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return MasterKey{}, fmt.Errorf("failed to generate master key: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return MasterKey{}, fmt.Errorf("failed to create master key file: %w", err)
+	}
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+		f.Close()
+		return MasterKey{}, fmt.Errorf("failed to write master key file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return MasterKey{}, fmt.Errorf("failed to write master key file: %w", err)
+	}
+	return NewMasterKey(key)
+}
+
+// MasterKeyFromEnv returns the master key configured by one of
+// AF_SECRETS_MASTER_KEY (base64 or hex), AF_SECRETS_PASSPHRASE or
+// AF_SECRETS_KEY_FILE, or nil when none is set
+func MasterKeyFromEnv() (*MasterKey, error) {
+	return MasterKeyFromEnvVars("AF_SECRETS_MASTER_KEY", "AF_SECRETS_PASSPHRASE", "AF_SECRETS_KEY_FILE")
+}
+
+// MasterKeyFromEnvVars returns the master key configured by one of the
+// named variables, or nil when none is set
+func MasterKeyFromEnvVars(keyVar, passphraseVar, fileVar string) (*MasterKey, error) {
+	var set []string
+	for _, name := range []string{keyVar, passphraseVar, fileVar} {
+		if os.Getenv(name) != "" {
+			set = append(set, name)
+		}
+	}
+	if len(set) == 0 {
+		return nil, nil
+	}
+	if len(set) > 1 {
+		return nil, fmt.Errorf("%w: set only one of %s", ErrInvalidMasterKey, strings.Join(set, ", "))
+	}
+
+	var key MasterKey
+	var err error
+	switch set[0] {
+	case keyVar:
+		key, err = ParseMasterKey(os.Getenv(keyVar))
+	case passphraseVar:
+		key, err = PassphraseMasterKey(os.Getenv(passphraseVar))
+	default:
+		key, err = LoadMasterKeyFile(os.Getenv(fileVar))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", set[0], err)
+	}
+	return &key, nil
+}
+
+// Kind returns MasterKeyKindKey or MasterKeyKindPassphrase
+func (k MasterKey) Kind() string {
+	if k.passphrase != nil {
+		return MasterKeyKindPassphrase
+	}
+	return MasterKeyKindKey
+}
+
+// envelope is an encrypted secrets file. The secrets document is encrypted
+// with a random data key, and the data key with a key-encryption key (KEK)
+// from the master key, both with AES-256-GCM. Rotating the master key only
+// re-wraps the data key.
+type envelope struct {
+	Format     string    `json:"format"`
+	KEK        kekParams `json:"kek"`
+	WrappedKey string    `json:"wrapped_key"` // base64 of nonce || sealed data key
+	Payload    string    `json:"payload"`     // base64 of nonce || sealed document
+}
+
+// kekParams say how the KEK is obtained from the master key
+type kekParams struct {
+	Kind    string `json:"kind"`
+	KDF     string `json:"kdf,omitempty"`
+	Salt    string `json:"salt,omitempty"`
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+// newKEKParams returns the parameters for a new KEK from key
+func newKEKParams(key MasterKey) (kekParams, error) {
+	if key.Kind() == MasterKeyKindKey {
+		return kekParams{Kind: MasterKeyKindKey}, nil
+	}
+	salt := make([]byte, kdfSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return kekParams{}, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return kekParams{
+		Kind:    MasterKeyKindPassphrase,
+		KDF:     kdfArgon2id,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Time:    kdfTime,
+		Memory:  kdfMemory,
+		Threads: kdfThreads,
+	}, nil
+}
+
+// deriveKEK returns the KEK of key under params
+func deriveKEK(key MasterKey, params kekParams) ([]byte, error) {
+	if params.Kind != key.Kind() {
+		return nil, fmt.Errorf("%w: file is encrypted with a %s, not a %s", ErrInvalidMasterKey, params.Kind, key.Kind())
+	}
+	if params.Kind == MasterKeyKindKey {
+		return key.key, nil
+	}
+	if params.KDF != kdfArgon2id || params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+		return nil, fmt.Errorf("%w: unsupported key derivation %q", ErrDecryptionFailed, params.KDF)
+	}
+	if params.Time > kdfMaxTime || params.Memory > kdfMaxMemory || params.Threads > kdfMaxThreads {
+		return nil, fmt.Errorf("%w: key derivation parameters exceed time %d, memory %d KiB, threads %d",
+			ErrDecryptionFailed, kdfMaxTime, kdfMaxMemory, kdfMaxThreads)
+	}
+	salt, err := base64.StdEncoding.DecodeString(params.Salt)
+	if err != nil || len(salt) < kdfSaltLen {
+		return nil, fmt.Errorf("%w: invalid salt", ErrDecryptionFailed)
+	}
+	return argon2.IDKey(key.passphrase, salt, params.Time, params.Memory, params.Threads, masterKeySize), nil
+}
+
+// wrapAAD binds the wrapped data key to the KEK parameters, so they cannot
+// be changed without detection
+func (e *envelope) wrapAAD() []byte {
+	params, _ := json.Marshal(e.KEK)
+	return append([]byte(e.Format+"\n"), params...)
+}
+
+// payloadAAD binds the payload to the file format; it leaves out the KEK so
+// re-wrapping the data key keeps the payload valid
+func (e *envelope) payloadAAD() []byte {
+	return []byte(e.Format)
+}
+
+// isEnvelope reports whether data is an encrypted secrets file
+func isEnvelope(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return false
+	}
+	var probe struct {
+		Format string `json:"format"`
+	}
+	return json.Unmarshal(data, &probe) == nil && probe.Format == EnvelopeFormat
+}
+
+// sealEnvelope encrypts document with dataKey, and wraps dataKey with kek
+func sealEnvelope(params kekParams, kek, dataKey, document []byte) (*envelope, error) {
+	e := &envelope{Format: EnvelopeFormat, KEK: params}
+	wrapped, err := seal(kek, dataKey, e.wrapAAD())
+	if err != nil {
+		return nil, err
+	}
+	payload, err := seal(dataKey, document, e.payloadAAD())
+	if err != nil {
+		return nil, err
+	}
+	e.WrappedKey = base64.StdEncoding.EncodeToString(wrapped)
+	e.Payload = base64.StdEncoding.EncodeToString(payload)
+	return e, nil
+}
+
+// parseEnvelope parses an encrypted secrets file
+func parseEnvelope(data []byte) (*envelope, error) {
+	var e envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted secrets file: %w", err)
+	}
+	if e.Format != EnvelopeFormat {
+		return nil, fmt.Errorf("unsupported secrets file format %q", e.Format)
+	}
+	return &e, nil
+}
+
+// unwrap returns the data key of the envelope
+func (e *envelope) unwrap(kek []byte) ([]byte, error) {
+	wrapped, err := base64.StdEncoding.DecodeString(e.WrappedKey)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	dataKey, err := open(kek, wrapped, e.wrapAAD())
+	if err != nil || len(dataKey) != masterKeySize {
+		return nil, ErrDecryptionFailed
+	}
+	return dataKey, nil
+}
+
+// decrypt returns the secrets document of the envelope
+func (e *envelope) decrypt(dataKey []byte) ([]byte, error) {
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	document, err := open(dataKey, payload, e.payloadAAD())
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return document, nil
+}
+
+// rewrap wraps dataKey with a new KEK, keeping the payload as it is
+func (e *envelope) rewrap(params kekParams, kek, dataKey []byte) (*envelope, error) {
+	rewrapped := &envelope{Format: e.Format, KEK: params, Payload: e.Payload}
+	wrapped, err := seal(kek, dataKey, rewrapped.wrapAAD())
+	if err != nil {
+		return nil, err
+	}
+	rewrapped.WrappedKey = base64.StdEncoding.EncodeToString(wrapped)
+	return rewrapped, nil
+}
+
+// seal encrypts plaintext with AES-256-GCM under a random nonce, returning
+// nonce || ciphertext
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open decrypts nonce || ciphertext sealed by seal
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrDecryptionFailed
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// newDataKey returns a random data key
+func newDataKey() ([]byte, error) {
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	return key, nil
+}
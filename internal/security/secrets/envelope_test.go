@@ -0,0 +1,304 @@
+// Copyright 2025 AgentFlow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testMasterKey(t *testing.T, b byte) MasterKey {
+	t.Helper()
+	key, err := NewMasterKey(bytes.Repeat([]byte{b}, masterKeySize))
+	if err != nil {
+		t.Fatalf("Failed to create master key: %v", err)
+	}
+	return key
+}
+
+func readEnvelope(t *testing.T, path string) *envelope {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read secrets file: %v", err)
+	}
+	e, err := parseEnvelope(data)
+	if err != nil {
+		t.Fatalf("Failed to parse secrets file: %v", err)
+	}
+	return e
+}
+
+func TestEncryptedFileProvider_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	key := testMasterKey(t, 1)
+	ctx := context.Background()
+
+	provider, err := NewEncryptedFileProvider(path, key)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if err := provider.SetSecret(ctx, "db_password", "hunter2"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read secrets file: %v", err)
+	}
+	if bytes.Contains(data, []byte("hunter2")) || bytes.Contains(data, []byte("db_password")) {
+		t.Error("Secrets file holds plaintext")
+	}
+
+	reopened, err := NewEncryptedFileProvider(path, key)
+	if err != nil {
+		t.Fatalf("Failed to reopen provider: %v", err)
+	}
+	if value, err := reopened.GetSecret(ctx, "db_password"); err != nil || value != "hunter2" {
+		t.Errorf("GetSecret = %q, %v; want hunter2", value, err)
+	}
+
+	// Without the key, or with another, the file cannot be read
+	if _, err := NewEncryptedFileProvider(path, testMasterKey(t, 2)); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Expected ErrDecryptionFailed with the wrong key, got: %v", err)
+	}
+	plain := NewFileProvider(path)
+	if _, err := plain.GetSecret(ctx, "db_password"); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted without a key, got: %v", err)
+	}
+	if err := plain.SetSecret(ctx, "other", "value"); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted writing without a key, got: %v", err)
+	}
+	if value, err := reopened.GetSecret(ctx, "db_password"); err != nil || value != "hunter2" {
+		t.Errorf("File was overwritten without a key: %q, %v", value, err)
+	}
+}
+
+func TestEncryptedFileProvider_DetectsTampering(t *testing.T) {
+	key := testMasterKey(t, 1)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		tamper func(e *envelope)
+	}{
+		{"payload", func(e *envelope) {
+			payload, _ := base64.StdEncoding.DecodeString(e.Payload)
+			payload[len(payload)-1] ^= 1
+			e.Payload = base64.StdEncoding.EncodeToString(payload)
+		}},
+		{"wrapped key", func(e *envelope) {
+			wrapped, _ := base64.StdEncoding.DecodeString(e.WrappedKey)
+			wrapped[len(wrapped)-1] ^= 1
+			e.WrappedKey = base64.StdEncoding.EncodeToString(wrapped)
+		}},
+		{"kek parameters", func(e *envelope) {
+			e.KEK.KDF = "none"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secrets.json")
+			provider, err := NewEncryptedFileProvider(path, key)
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+			if err := provider.SetSecret(ctx, "api_key", "secret"); err != nil {
+				t.Fatalf("Failed to set secret: %v", err)
+			}
+
+			e := readEnvelope(t, path)
+			tt.tamper(e)
+			data, _ := json.Marshal(e)
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				t.Fatalf("Failed to write secrets file: %v", err)
+			}
+
+			if _, err := NewEncryptedFileProvider(path, key); !errors.Is(err, ErrDecryptionFailed) {
+				t.Errorf("Expected ErrDecryptionFailed, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedFileProvider_RejectsCostlyKDFParameters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	key, err := PassphraseMasterKey("passphrase")
+	if err != nil {
+		t.Fatalf("Failed to create passphrase key: %v", err)
+	}
+	provider, err := NewEncryptedFileProvider(path, key)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if err := provider.SetSecret(context.Background(), "api_key", "secret"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+
+	e := readEnvelope(t, path)
+	e.KEK.Memory = 1 << 31
+	data, _ := json.Marshal(e)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write secrets file: %v", err)
+	}
+	if _, err := NewEncryptedFileProvider(path, key); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Expected ErrDecryptionFailed, got: %v", err)
+	}
+}
+
+func TestEncryptedFileProvider_RotateMasterKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	oldKey := testMasterKey(t, 1)
+	ctx := context.Background()
+
+	provider, err := NewEncryptedFileProvider(path, oldKey)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if err := provider.SetSecret(ctx, "api_key", "secret"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	before := readEnvelope(t, path)
+
+	newKey, err := PassphraseMasterKey("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Failed to create passphrase key: %v", err)
+	}
+	if err := provider.RotateMasterKey(ctx, newKey); err != nil {
+		t.Fatalf("Failed to rotate master key: %v", err)
+	}
+
+	// Only the data key was re-wrapped
+	after := readEnvelope(t, path)
+	if after.Payload != before.Payload {
+		t.Error("Rotating the master key re-encrypted the secrets")
+	}
+	if after.WrappedKey == before.WrappedKey || after.KEK.Kind != MasterKeyKindPassphrase {
+		t.Error("Data key was not re-wrapped with the passphrase")
+	}
+
+	if _, err := NewEncryptedFileProvider(path, oldKey); err == nil {
+		t.Error("Old master key still opens the file")
+	}
+	reopened, err := NewEncryptedFileProvider(path, newKey)
+	if err != nil {
+		t.Fatalf("Failed to open with the new key: %v", err)
+	}
+	if value, err := reopened.GetSecret(ctx, "api_key"); err != nil || value != "secret" {
+		t.Errorf("GetSecret = %q, %v; want secret", value, err)
+	}
+
+	if err := reopened.RotateDataKey(ctx); err != nil {
+		t.Fatalf("Failed to rotate data key: %v", err)
+	}
+	if readEnvelope(t, path).Payload == after.Payload {
+		t.Error("Rotating the data key kept the payload")
+	}
+	reopened, err = NewEncryptedFileProvider(path, newKey)
+	if err != nil {
+		t.Fatalf("Failed to open after data key rotation: %v", err)
+	}
+	if value, err := reopened.GetSecret(ctx, "api_key"); err != nil || value != "secret" {
+		t.Errorf("GetSecret = %q, %v; want secret", value, err)
+	}
+}
+
+func TestEncryptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	key := testMasterKey(t, 1)
+	ctx := context.Background()
+
+	if err := NewFileProvider(path).SetSecret(ctx, "api_key", "secret"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if _, err := NewEncryptedFileProvider(path, key); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("Expected ErrNotEncrypted for a plaintext file, got: %v", err)
+	}
+
+	n, err := EncryptFile(path, key)
+	if err != nil || n != 1 {
+		t.Fatalf("EncryptFile = %d, %v; want 1", n, err)
+	}
+	if _, err := EncryptFile(path, key); !errors.Is(err, ErrAlreadyEncrypted) {
+		t.Errorf("Expected ErrAlreadyEncrypted, got: %v", err)
+	}
+
+	provider, err := NewEncryptedFileProvider(path, key)
+	if err != nil {
+		t.Fatalf("Failed to open encrypted file: %v", err)
+	}
+	if value, err := provider.GetSecret(ctx, "api_key"); err != nil || value != "secret" {
+		t.Errorf("GetSecret = %q, %v; want secret", value, err)
+	}
+}
+
+func TestMasterKeyFromEnv(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, masterKeySize))
+	keyFile := filepath.Join(t.TempDir(), "master.key")
+	if _, err := GenerateMasterKeyFile(keyFile); err != nil {
+		t.Fatalf("Failed to generate key file: %v", err)
+	}
+	if _, err := GenerateMasterKeyFile(keyFile); err == nil {
+		t.Error("GenerateMasterKeyFile replaced an existing file")
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		kind    string
+		wantErr error
+	}{
+		{"none", nil, "", nil},
+		{"raw key", map[string]string{"AF_SECRETS_MASTER_KEY": encoded}, MasterKeyKindKey, nil},
+		{"passphrase", map[string]string{"AF_SECRETS_PASSPHRASE": "pass"}, MasterKeyKindPassphrase, nil},
+		{"key file", map[string]string{"AF_SECRETS_KEY_FILE": keyFile}, MasterKeyKindKey, nil},
+		{"short key", map[string]string{"AF_SECRETS_MASTER_KEY": "c2hvcnQ="}, "", ErrInvalidMasterKey},
+		{"two sources", map[string]string{"AF_SECRETS_MASTER_KEY": encoded, "AF_SECRETS_PASSPHRASE": "pass"}, "", ErrInvalidMasterKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"AF_SECRETS_MASTER_KEY", "AF_SECRETS_PASSPHRASE", "AF_SECRETS_KEY_FILE"} {
+				t.Setenv(name, tt.env[name])
+			}
+			key, err := MasterKeyFromEnv()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MasterKeyFromEnv failed: %v", err)
+			}
+			if tt.kind == "" {
+				if key != nil {
+					t.Error("Expected no master key")
+				}
+				return
+			}
+			if key == nil || key.Kind() != tt.kind {
+				t.Errorf("Expected a %s master key, got %v", tt.kind, key)
+			}
+		})
+	}
+}
@@ -15,10 +15,12 @@
 package secrets
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -28,37 +30,115 @@ import (
 	"github.com/agentflow/agentflow/internal/logging"
 )
 
-// FileProvider implements SecretsProvider using a JSON file
+// FileProvider implements VersionedProvider using a JSON file, optionally
+// encrypted with a master key
 type FileProvider struct {
 	configPath string
 	secrets    map[string]string
 	lastMod    time.Time
 	mu         sync.RWMutex
 	logger     logging.Logger
+
+	// Version history of each secret, oldest first; the last is current
+	versions      map[string][]Version
+	overlap       time.Duration
+	now           func() time.Time
+	watchInterval time.Duration
+	watchers      map[*secretWatcher]struct{}
+	// seen is the current version of each secret watchers were told about
+	seen map[string]int
+
+	// Encryption state; master is nil for plaintext files
+	master   *MasterKey
+	envelope *envelope
+	kek      []byte
+	dataKey  []byte
 }
 
+var _ VersionedProvider = (*FileProvider)(nil)
+
 // NewFileProvider creates a new file-based secrets provider
 func NewFileProvider(configPath string) *FileProvider {
-	if configPath == "" {
-		configPath = "secrets.json"
-	}
-
-	provider := &FileProvider{
-		configPath: configPath,
-		secrets:    make(map[string]string),
-		logger:     logging.NewLogger().WithFields(logging.String("component", "secrets.file")),
-	}
+	provider := newFileProvider(configPath, nil)
 
 	// Load initial secrets
 	if err := provider.loadSecrets(); err != nil {
 		provider.logger.Warn("Failed to load initial secrets file",
-			logging.String("path", configPath),
+			logging.String("path", provider.configPath),
 			logging.Any("error", err))
 	}
 
 	return provider
 }
 
+// NewEncryptedFileProvider creates a file-based secrets provider whose file
+// is encrypted with key. A missing file is created encrypted. Unlike
+// NewFileProvider it fails when the file cannot be read, such as when it is
+// not encrypted or key does not decrypt it.
+func NewEncryptedFileProvider(configPath string, key MasterKey) (*FileProvider, error) {
+	provider := newFileProvider(configPath, &key)
+	if err := provider.loadSecrets(); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+func newFileProvider(configPath string, key *MasterKey) *FileProvider {
+	if configPath == "" {
+		configPath = "secrets.json"
+	}
+	return &FileProvider{
+		configPath:    configPath,
+		secrets:       make(map[string]string),
+		logger:        logging.NewLogger().WithFields(logging.String("component", "secrets.file")),
+		master:        key,
+		versions:      make(map[string][]Version),
+		overlap:       DefaultRotationOverlap,
+		now:           time.Now,
+		watchInterval: DefaultWatchInterval,
+		watchers:      make(map[*secretWatcher]struct{}),
+		seen:          make(map[string]int),
+	}
+}
+
+// EncryptFile encrypts the plaintext secrets file at path with key, or
+// creates an empty encrypted file when there is none. It returns how many
+// secrets were encrypted.
+func EncryptFile(path string, key MasterKey) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	if isEnvelope(data) {
+		return 0, fmt.Errorf("%w: %s", ErrAlreadyEncrypted, path)
+	}
+
+	provider := newFileProvider(path, &key)
+	if provider.secrets, provider.versions, err = decodeDocument(bytes.TrimSpace(data)); err != nil {
+		return 0, err
+	}
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if err := provider.saveSecretsLocked(); err != nil {
+		return 0, err
+	}
+	return len(provider.secrets), nil
+}
+
+// Encrypted reports whether the provider's file is encrypted
+func (p *FileProvider) Encrypted() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.master != nil
+}
+
+// SetRotationOverlap sets how long Rotate keeps the replaced value valid
+func (p *FileProvider) SetRotationOverlap(overlap time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.overlap = overlap
+}
+
 // loadSecrets loads secrets from the file and checks for modifications
 func (p *FileProvider) loadSecrets() error {
 	p.mu.Lock()
@@ -73,6 +153,7 @@ func (p *FileProvider) loadSecretsLocked() error {
 	if os.IsNotExist(err) {
 		// Create empty secrets file
 		p.secrets = make(map[string]string)
+		p.versions = make(map[string][]Version)
 		return p.saveSecretsLocked()
 	}
 	if err != nil {
@@ -90,17 +171,37 @@ func (p *FileProvider) loadSecretsLocked() error {
 		return fmt.Errorf("failed to read secrets file: %w", err)
 	}
 
-	var secrets map[string]string
-	if len(data) > 0 {
-		if err := json.Unmarshal(data, &secrets); err != nil {
-			return fmt.Errorf("failed to parse secrets file: %w", err)
+	if isEnvelope(data) {
+		if data, err = p.decryptLocked(data); err != nil {
+			return err
+		}
+	} else if p.master != nil && len(bytes.TrimSpace(data)) > 0 {
+		return fmt.Errorf("%w: run 'af secrets init' to encrypt it", ErrNotEncrypted)
+	}
+
+	secrets, versions, err := decodeDocument(data)
+	if err != nil {
+		return err
+	}
+
+	// Values edited outside the provider become new versions; they are
+	// recorded in the file when the provider next writes it
+	for key, value := range secrets {
+		history := versions[key]
+		if n := len(history); n == 0 || history[n-1].Value != value {
+			versions[key] = appendVersion(history, Version{Value: value, CreatedAt: info.ModTime().UTC()}, info.ModTime(), 0)
+		}
+	}
+	for key := range versions {
+		if _, ok := secrets[key]; !ok {
+			delete(versions, key)
 		}
-	} else {
-		secrets = make(map[string]string)
 	}
 
 	p.secrets = secrets
+	p.versions = versions
 	p.lastMod = info.ModTime()
+	p.notifyLocked()
 
 	p.logger.Info("Loaded secrets from file",
 		logging.String("path", p.configPath),
@@ -110,20 +211,99 @@ func (p *FileProvider) loadSecretsLocked() error {
 	return nil
 }
 
+// unreadable reports whether err means the file cannot be read with the
+// configured key. Cached secrets are not served then, and the file is not
+// overwritten.
+func unreadable(err error) bool {
+	return errors.Is(err, ErrEncrypted) || errors.Is(err, ErrNotEncrypted) ||
+		errors.Is(err, ErrDecryptionFailed) || errors.Is(err, ErrInvalidMasterKey)
+}
+
+// decryptLocked returns the secrets document of an encrypted file (must be
+// called with lock held)
+func (p *FileProvider) decryptLocked(data []byte) ([]byte, error) {
+	if p.master == nil {
+		return nil, ErrEncrypted
+	}
+	e, err := parseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	kek := p.kek
+	if p.envelope == nil || e.KEK != p.envelope.KEK {
+		// Deriving a KEK from a passphrase is slow, so it is reused while
+		// the file's KEK parameters stay the same
+		if kek, err = deriveKEK(*p.master, e.KEK); err != nil {
+			return nil, err
+		}
+	}
+	dataKey, err := e.unwrap(kek)
+	if err != nil {
+		return nil, err
+	}
+	document, err := e.decrypt(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	p.envelope, p.kek, p.dataKey = e, kek, dataKey
+	return document, nil
+}
+
+// encryptLocked seals the secrets document, creating the data key and KEK
+// of a new file (must be called with lock held)
+func (p *FileProvider) encryptLocked(document []byte) ([]byte, error) {
+	params := kekParams{}
+	if p.envelope != nil {
+		params = p.envelope.KEK
+	}
+	if p.dataKey == nil {
+		var err error
+		if params, err = newKEKParams(*p.master); err != nil {
+			return nil, err
+		}
+		if p.kek, err = deriveKEK(*p.master, params); err != nil {
+			return nil, err
+		}
+		if p.dataKey, err = newDataKey(); err != nil {
+			return nil, err
+		}
+	}
+	e, err := sealEnvelope(params, p.kek, p.dataKey, document)
+	if err != nil {
+		return nil, err
+	}
+	p.envelope = e
+	return json.MarshalIndent(e, "", "  ")
+}
+
 // saveSecretsLocked saves secrets to file (must be called with lock held)
 func (p *FileProvider) saveSecretsLocked() error {
+	// Marshal secrets to JSON
+	data, err := encodeDocument(p.secrets, p.versions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+	if p.master != nil {
+		if data, err = p.encryptLocked(data); err != nil {
+			return fmt.Errorf("failed to encrypt secrets: %w", err)
+		}
+	}
+	if err := p.writeFileLocked(data); err != nil {
+		return err
+	}
+	p.notifyLocked()
+	return nil
+}
+
+// writeFileLocked atomically replaces the secrets file with data (must be
+// called with lock held)
+func (p *FileProvider) writeFileLocked(data []byte) error {
 	// Ensure directory exists
 	dir := filepath.Dir(p.configPath)
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("failed to create secrets directory: %w", err)
 	}
 
-	// Marshal secrets to JSON
-	data, err := json.MarshalIndent(p.secrets, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal secrets: %w", err)
-	}
-
 	// Write to temporary file first
 	tempPath := p.configPath + ".tmp"
 	if err := os.WriteFile(tempPath, data, 0600); err != nil {
@@ -155,6 +335,9 @@ func (p *FileProvider) GetSecret(ctx context.Context, key string) (string, error
 	// Reload secrets to check for updates
 	if err := p.loadSecrets(); err != nil {
 		p.logger.WithTrace(ctx).Error("Failed to reload secrets", err)
+		if unreadable(err) {
+			return "", err
+		}
 		// Continue with cached secrets
 	}
 
@@ -191,9 +374,12 @@ func (p *FileProvider) SetSecret(ctx context.Context, key, value string) error {
 	// Reload to get latest state first (with lock held)
 	if err := p.loadSecretsLocked(); err != nil {
 		p.logger.WithTrace(ctx).Error("Failed to reload secrets before set", err)
+		if unreadable(err) {
+			return err
+		}
 	}
 
-	p.secrets[key] = value
+	version := p.setLocked(ctx, key, value, 0)
 
 	if err := p.saveSecretsLocked(); err != nil {
 		p.logger.WithTrace(ctx).Error("Failed to save secret to file", err,
@@ -204,6 +390,7 @@ func (p *FileProvider) SetSecret(ctx context.Context, key, value string) error {
 	p.logger.WithTrace(ctx).Info("Secret set in file",
 		logging.String("key", key),
 		logging.String("path", p.configPath),
+		logging.Int("version", version.Version),
 		logging.Int("value_length", len(value)),
 		logging.Any("timestamp", time.Now().UTC()))
 
@@ -224,6 +411,9 @@ func (p *FileProvider) DeleteSecret(ctx context.Context, key string) error {
 	// Reload to get latest state first (with lock held)
 	if err := p.loadSecretsLocked(); err != nil {
 		p.logger.WithTrace(ctx).Error("Failed to reload secrets before delete", err)
+		if unreadable(err) {
+			return err
+		}
 	}
 
 	if _, exists := p.secrets[key]; !exists {
@@ -234,6 +424,7 @@ func (p *FileProvider) DeleteSecret(ctx context.Context, key string) error {
 	}
 
 	delete(p.secrets, key)
+	delete(p.versions, key)
 
 	if err := p.saveSecretsLocked(); err != nil {
 		p.logger.WithTrace(ctx).Error("Failed to save secrets after delete", err,
@@ -254,6 +445,9 @@ func (p *FileProvider) ListSecrets(ctx context.Context) ([]string, error) {
 	// Reload secrets to check for updates
 	if err := p.loadSecrets(); err != nil {
 		p.logger.WithTrace(ctx).Error("Failed to reload secrets for list", err)
+		if unreadable(err) {
+			return nil, err
+		}
 		// Continue with cached secrets
 	}
 
@@ -271,12 +465,27 @@ func (p *FileProvider) ListSecrets(ctx context.Context) ([]string, error) {
 	return keys, nil
 }
 
-// Rotate generates a new random value for an existing secret
+// Rotate generates a new random value for an existing secret. The replaced
+// value stays valid for the rotation overlap (see SetRotationOverlap).
 func (p *FileProvider) Rotate(ctx context.Context, key string) error {
+	p.mu.RLock()
+	overlap := p.overlap
+	p.mu.RUnlock()
+
+	_, err := p.RotateWithOverlap(ctx, key, overlap)
+	return err
+}
+
+// RotateWithOverlap generates a new random value for an existing secret and
+// keeps the replaced value valid for overlap
+func (p *FileProvider) RotateWithOverlap(ctx context.Context, key string, overlap time.Duration) (Version, error) {
 	if err := ValidateKey(key); err != nil {
 		p.logger.WithTrace(ctx).Error("Invalid secret key for rotation", err,
 			logging.String("key", MaskSecret(key)))
-		return err
+		return Version{}, err
+	}
+	if overlap < 0 {
+		return Version{}, fmt.Errorf("invalid rotation overlap: %s", overlap)
 	}
 
 	p.mu.Lock()
@@ -285,13 +494,16 @@ func (p *FileProvider) Rotate(ctx context.Context, key string) error {
 	// Reload to get latest state first (with lock held)
 	if err := p.loadSecretsLocked(); err != nil {
 		p.logger.WithTrace(ctx).Error("Failed to reload secrets before rotation", err)
+		if unreadable(err) {
+			return Version{}, err
+		}
 	}
 
 	if _, exists := p.secrets[key]; !exists {
 		p.logger.WithTrace(ctx).Warn("Attempted to rotate non-existent secret",
 			logging.String("key", key),
 			logging.String("path", p.configPath))
-		return fmt.Errorf("%w: %s", ErrSecretNotFound, key)
+		return Version{}, fmt.Errorf("%w: %s", ErrSecretNotFound, key)
 	}
 
 	// Generate new random value (32 bytes = 64 hex characters)
@@ -299,23 +511,314 @@ func (p *FileProvider) Rotate(ctx context.Context, key string) error {
 	if _, err := rand.Read(randomBytes); err != nil {
 		p.logger.WithTrace(ctx).Error("Failed to generate random value for rotation", err,
 			logging.String("key", key))
-		return fmt.Errorf("failed to generate random value: %w", err)
+		return Version{}, fmt.Errorf("failed to generate random value: %w", err)
 	}
 
 	newValue := hex.EncodeToString(randomBytes)
-	p.secrets[key] = newValue
+	version := p.setLocked(ctx, key, newValue, overlap)
 
 	if err := p.saveSecretsLocked(); err != nil {
 		p.logger.WithTrace(ctx).Error("Failed to save rotated secret", err,
 			logging.String("key", key))
-		return fmt.Errorf("failed to save rotated secret: %w", err)
+		return Version{}, fmt.Errorf("failed to save rotated secret: %w", err)
 	}
 
 	p.logger.WithTrace(ctx).Info("Secret rotated in file",
 		logging.String("key", key),
 		logging.String("path", p.configPath),
+		logging.Int("version", version.Version),
 		logging.Int("new_value_length", len(newValue)),
+		logging.Any("overlap", overlap.String()),
 		logging.Any("timestamp", time.Now().UTC()))
 
+	return version, nil
+}
+
+// Rollback makes the value of an earlier version current again, as a new
+// version. The replaced value expires at once.
+func (p *FileProvider) Rollback(ctx context.Context, key string, version int) (Version, error) {
+	if err := ValidateKey(key); err != nil {
+		return Version{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.loadSecretsLocked(); err != nil {
+		p.logger.WithTrace(ctx).Error("Failed to reload secrets before rollback", err)
+		if unreadable(err) {
+			return Version{}, err
+		}
+	}
+
+	history, exists := p.versions[key]
+	if !exists {
+		return Version{}, fmt.Errorf("%w: %s", ErrSecretNotFound, key)
+	}
+	target, ok := findVersion(history, version)
+	if !ok {
+		return Version{}, fmt.Errorf("%w: %s version %d", ErrVersionNotFound, key, version)
+	}
+	current := history[len(history)-1]
+	if target.Version == current.Version {
+		return Version{}, fmt.Errorf("%s version %d is already current", key, target.Version)
+	}
+
+	restored := p.setLocked(ctx, key, target.Value, 0)
+	if err := p.saveSecretsLocked(); err != nil {
+		p.logger.WithTrace(ctx).Error("Failed to save rolled back secret", err,
+			logging.String("key", key))
+		return Version{}, fmt.Errorf("failed to save rolled back secret: %w", err)
+	}
+
+	p.logger.WithTrace(ctx).Info("Secret rolled back in file",
+		logging.String("key", key),
+		logging.String("path", p.configPath),
+		logging.Int("from_version", current.Version),
+		logging.Int("restored_version", target.Version),
+		logging.Int("version", restored.Version))
+
+	return restored, nil
+}
+
+// GetSecretVersion returns a version of a secret by number, or
+// VersionCurrent or VersionPrevious. Expired versions are returned too.
+func (p *FileProvider) GetSecretVersion(ctx context.Context, key string, version int) (Version, error) {
+	history, err := p.history(ctx, key)
+	if err != nil {
+		return Version{}, err
+	}
+	v, ok := findVersion(history, version)
+	if !ok {
+		return Version{}, fmt.Errorf("%w: %s version %d", ErrVersionNotFound, key, version)
+	}
+	return v, nil
+}
+
+// ListSecretVersions returns the kept versions of a secret, newest first
+func (p *FileProvider) ListSecretVersions(ctx context.Context, key string) ([]Version, error) {
+	history, err := p.history(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]Version, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		versions = append(versions, history[i])
+	}
+	return versions, nil
+}
+
+// GetValidSecrets returns the current value of a secret followed by the
+// earlier values that have not expired, newest first. Consumers verifying a
+// value, such as a token signature or a password, accept any of them.
+func (p *FileProvider) GetValidSecrets(ctx context.Context, key string) ([]string, error) {
+	history, err := p.history(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	now := p.now()
+	values := []string{history[len(history)-1].Value}
+	for i := len(history) - 2; i >= 0; i-- {
+		if !history[i].Expired(now) {
+			values = append(values, history[i].Value)
+		}
+	}
+	return values, nil
+}
+
+// history returns a copy of the versions of key, oldest first
+func (p *FileProvider) history(ctx context.Context, key string) ([]Version, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	// Reload secrets to check for updates
+	if err := p.loadSecrets(); err != nil {
+		p.logger.WithTrace(ctx).Error("Failed to reload secrets for versions", err)
+		if unreadable(err) {
+			return nil, err
+		}
+		// Continue with cached secrets
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	history, exists := p.versions[key]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, key)
+	}
+	return append([]Version(nil), history...), nil
+}
+
+// setLocked makes value the current version of key, keeping the replaced
+// value valid for overlap (must be called with lock held)
+func (p *FileProvider) setLocked(ctx context.Context, key, value string, overlap time.Duration) Version {
+	now := p.now()
+	history := appendVersion(p.versions[key], Version{
+		Value:     value,
+		CreatedAt: now.UTC(),
+		CreatedBy: actorFromContext(ctx),
+	}, now, overlap)
+	p.versions[key] = history
+	p.secrets[key] = value
+	return history[len(history)-1]
+}
+
+// secretWatcher receives the changes to one secret, or to all when key is
+// empty
+type secretWatcher struct {
+	key string
+	ch  chan SecretEvent
+}
+
+// watchBuffer is how many events a watcher can fall behind by before
+// further events are dropped
+const watchBuffer = 16
+
+// Watch reports changes to key, or to every secret when key is empty, until
+// ctx is done, when the channel is closed. Changes made through this
+// provider are reported at once; changes made by other processes when the
+// file is next checked, every DefaultWatchInterval. A watcher that falls
+// behind misses events, so consumers should read the secret again on each
+// event rather than count on seeing every version.
+func (p *FileProvider) Watch(ctx context.Context, key string) (<-chan SecretEvent, error) {
+	if key != "" {
+		if err := ValidateKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	w := &secretWatcher{key: key, ch: make(chan SecretEvent, watchBuffer)}
+	p.mu.Lock()
+	p.watchers[w] = struct{}{}
+	interval := p.watchInterval
+	p.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				p.mu.Lock()
+				delete(p.watchers, w)
+				close(w.ch)
+				p.mu.Unlock()
+				return
+			case <-ticker.C:
+				if err := p.loadSecrets(); err != nil {
+					p.logger.Warn("Failed to reload watched secrets file",
+						logging.String("path", p.configPath),
+						logging.Any("error", err))
+				}
+			}
+		}
+	}()
+	return w.ch, nil
+}
+
+// notifyLocked tells watchers about secrets whose current version changed
+// since they were last told (must be called with lock held)
+func (p *FileProvider) notifyLocked() {
+	var events []SecretEvent
+	for key, history := range p.versions {
+		current := history[len(history)-1].Version
+		if p.seen[key] != current {
+			p.seen[key] = current
+			events = append(events, SecretEvent{Key: key, Type: SecretUpdated, Version: current})
+		}
+	}
+	for key := range p.seen {
+		if _, exists := p.versions[key]; !exists {
+			delete(p.seen, key)
+			events = append(events, SecretEvent{Key: key, Type: SecretDeleted})
+		}
+	}
+
+	for w := range p.watchers {
+		for _, event := range events {
+			if w.key != "" && w.key != event.Key {
+				continue
+			}
+			select {
+			case w.ch <- event:
+			default:
+				p.logger.Warn("Dropped secret event for slow watcher",
+					logging.String("key", event.Key))
+			}
+		}
+	}
+}
+
+// RotateMasterKey re-wraps the file's data key with newKey. The encrypted
+// secrets are left as they are, so rotating does not rewrite them.
+func (p *FileProvider) RotateMasterKey(ctx context.Context, newKey MasterKey) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.master == nil {
+		return ErrNotEncrypted
+	}
+	if err := p.loadSecretsLocked(); err != nil {
+		return err
+	}
+	if p.envelope == nil {
+		return fmt.Errorf("%w: %s", ErrNotEncrypted, p.configPath)
+	}
+
+	params, err := newKEKParams(newKey)
+	if err != nil {
+		return err
+	}
+	kek, err := deriveKEK(newKey, params)
+	if err != nil {
+		return err
+	}
+	rewrapped, err := p.envelope.rewrap(params, kek, p.dataKey)
+	if err != nil {
+		return fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	data, err := json.MarshalIndent(rewrapped, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+	if err := p.writeFileLocked(data); err != nil {
+		return err
+	}
+	p.master, p.envelope, p.kek = &newKey, rewrapped, kek
+
+	p.logger.WithTrace(ctx).Info("Secrets file master key rotated",
+		logging.String("path", p.configPath),
+		logging.String("kind", newKey.Kind()))
+
+	return nil
+}
+
+// RotateDataKey re-encrypts the secrets under a new data key, wrapped with
+// the current master key
+func (p *FileProvider) RotateDataKey(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.master == nil {
+		return ErrNotEncrypted
+	}
+	if err := p.loadSecretsLocked(); err != nil {
+		return err
+	}
+	dataKey, err := newDataKey()
+	if err != nil {
+		return err
+	}
+	previous := p.dataKey
+	p.dataKey = dataKey
+	if err := p.saveSecretsLocked(); err != nil {
+		p.dataKey = previous
+		return err
+	}
+
+	p.logger.WithTrace(ctx).Info("Secrets file data key rotated",
+		logging.String("path", p.configPath))
+
 	return nil
 }
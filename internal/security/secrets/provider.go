@@ -18,7 +18,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 )
 
 // SecretsProvider defines the interface for managing secrets across different providers
@@ -39,6 +41,35 @@ type SecretsProvider interface {
 	Rotate(ctx context.Context, key string) error
 }
 
+// VersionedProvider is a SecretsProvider that keeps earlier values of each
+// secret, so a rotated value stays valid while consumers switch over
+type VersionedProvider interface {
+	SecretsProvider
+
+	// GetSecretVersion returns a version of a secret by number, or
+	// VersionCurrent or VersionPrevious. Expired versions are returned too.
+	GetSecretVersion(ctx context.Context, key string, version int) (Version, error)
+
+	// ListSecretVersions returns the kept versions of a secret, newest first
+	ListSecretVersions(ctx context.Context, key string) ([]Version, error)
+
+	// GetValidSecrets returns the current value of a secret followed by the
+	// earlier values that have not expired
+	GetValidSecrets(ctx context.Context, key string) ([]string, error)
+
+	// RotateWithOverlap generates a new value for an existing secret and
+	// keeps the replaced value valid for overlap
+	RotateWithOverlap(ctx context.Context, key string, overlap time.Duration) (Version, error)
+
+	// Rollback makes the value of an earlier version current again, as a
+	// new version. The replaced value expires at once.
+	Rollback(ctx context.Context, key string, version int) (Version, error)
+
+	// Watch reports changes to key, or to every secret when key is empty,
+	// until ctx is done
+	Watch(ctx context.Context, key string) (<-chan SecretEvent, error)
+}
+
 // Common errors
 var (
 	ErrSecretNotFound      = errors.New("secret not found")
@@ -46,8 +77,39 @@ var (
 	ErrInvalidKey          = errors.New("invalid secret key")
 	ErrPermissionDenied    = errors.New("permission denied")
 	ErrProviderUnavailable = errors.New("secrets provider unavailable")
+	ErrVersionNotFound     = errors.New("secret version not found")
 )
 
+// NewProviderFromEnv returns a FileProvider for the file named by
+// AF_SECRETS_FILE, or else an EnvironmentProvider reading AF_SECRET_* variables.
+// The file is encrypted when a master key is configured (see MasterKeyFromEnv),
+// and AF_SECRETS_ROTATION_OVERLAP sets how long rotated values stay valid.
+func NewProviderFromEnv() (SecretsProvider, error) {
+	path := os.Getenv("AF_SECRETS_FILE")
+	if path == "" {
+		return NewEnvironmentProvider("AF_SECRET_"), nil
+	}
+	key, err := MasterKeyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var provider *FileProvider
+	if key == nil {
+		provider = NewFileProvider(path)
+	} else if provider, err = NewEncryptedFileProvider(path, *key); err != nil {
+		return nil, err
+	}
+	if value := os.Getenv("AF_SECRETS_ROTATION_OVERLAP"); value != "" {
+		overlap, err := time.ParseDuration(value)
+		if err != nil || overlap < 0 {
+			return nil, fmt.Errorf("invalid AF_SECRETS_ROTATION_OVERLAP: %s", value)
+		}
+		provider.SetRotationOverlap(overlap)
+	}
+	return provider, nil
+}
+
 // MaskSecret masks sensitive values for logging and debug output
 func MaskSecret(value string) string {
 	if len(value) == 0 {
@@ -0,0 +1,168 @@
+// Copyright 2025 AgentFlow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Version selectors for GetSecretVersion
+const (
+	VersionCurrent  = 0
+	VersionPrevious = -1
+)
+
+// DefaultRotationOverlap is how long Rotate keeps the replaced value valid
+const DefaultRotationOverlap = 24 * time.Hour
+
+// MaxSecretVersions is how many versions of each secret are kept; older
+// versions are dropped
+const MaxSecretVersions = 10
+
+// DefaultWatchInterval is how often a watched file is checked for changes
+// made outside the provider
+const DefaultWatchInterval = 2 * time.Second
+
+// versionsKey holds the version history in a secrets file. It is not a
+// valid secret key, so it cannot clash with one.
+const versionsKey = ".versions"
+
+// Version is one value a secret has held
+type Version struct {
+	Version   int        `json:"version"`
+	Value     string     `json:"value"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the version is no longer valid at now
+func (v Version) Expired(now time.Time) bool {
+	return v.ExpiresAt != nil && !now.Before(*v.ExpiresAt)
+}
+
+// SecretEventType says how a watched secret changed
+type SecretEventType string
+
+// Secret event types
+const (
+	SecretUpdated SecretEventType = "updated"
+	SecretDeleted SecretEventType = "deleted"
+)
+
+// SecretEvent tells a watcher that a secret has a new current version or
+// was deleted
+type SecretEvent struct {
+	Key     string          `json:"key"`
+	Type    SecretEventType `json:"type"`
+	Version int             `json:"version,omitempty"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a context recording actor as the creator of the secret
+// versions written with it
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// decodeDocument splits a secrets document into the current values and
+// their version history. The current values stay a flat map, so files
+// written by older releases and edited by hand still load.
+func decodeDocument(data []byte) (map[string]string, map[string][]Version, error) {
+	var current map[string]string
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &current); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse secrets file: %w", err)
+		}
+	}
+	if current == nil {
+		current = make(map[string]string)
+	}
+
+	versions := make(map[string][]Version)
+	if encoded, ok := current[versionsKey]; ok {
+		delete(current, versionsKey)
+		if err := json.Unmarshal([]byte(encoded), &versions); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse secret versions: %w", err)
+		}
+	}
+	return current, versions, nil
+}
+
+// encodeDocument is the inverse of decodeDocument
+func encodeDocument(current map[string]string, versions map[string][]Version) ([]byte, error) {
+	doc := make(map[string]string, len(current)+1)
+	for key, value := range current {
+		doc[key] = value
+	}
+	if len(versions) > 0 {
+		encoded, err := json.Marshal(versions)
+		if err != nil {
+			return nil, err
+		}
+		doc[versionsKey] = string(encoded)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// appendVersion adds v as the current version of history. The replaced
+// version stays valid for overlap after now.
+func appendVersion(history []Version, v Version, now time.Time, overlap time.Duration) []Version {
+	v.Version = 1
+	if n := len(history); n > 0 {
+		v.Version = history[n-1].Version + 1
+		expiresAt := now.Add(overlap)
+		if previous := &history[n-1]; previous.ExpiresAt == nil || previous.ExpiresAt.After(expiresAt) {
+			previous.ExpiresAt = &expiresAt
+		}
+	}
+	history = append(history, v)
+	if len(history) > MaxSecretVersions {
+		history = append([]Version(nil), history[len(history)-MaxSecretVersions:]...)
+	}
+	return history
+}
+
+// findVersion returns the version selected by version in history, oldest
+// first: a version number, VersionCurrent or VersionPrevious
+func findVersion(history []Version, version int) (Version, bool) {
+	n := len(history)
+	switch {
+	case n == 0:
+		return Version{}, false
+	case version == VersionCurrent:
+		return history[n-1], true
+	case version == VersionPrevious:
+		if n < 2 {
+			return Version{}, false
+		}
+		return history[n-2], true
+	}
+	for _, v := range history {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return Version{}, false
+}
@@ -0,0 +1,277 @@
+// Copyright 2025 AgentFlow
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileProvider_Versions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	provider := NewFileProvider(path)
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	provider.now = func() time.Time { return now }
+	ctx := WithActor(context.Background(), "alice")
+
+	if err := provider.SetSecret(ctx, "db_password", "first"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	now = now.Add(time.Hour)
+	if err := provider.SetSecret(ctx, "db_password", "second"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+
+	current, err := provider.GetSecretVersion(ctx, "db_password", VersionCurrent)
+	if err != nil {
+		t.Fatalf("Failed to get current version: %v", err)
+	}
+	if current.Version != 2 || current.Value != "second" || current.CreatedBy != "alice" || !current.CreatedAt.Equal(now) {
+		t.Errorf("Unexpected current version: %+v", current)
+	}
+	previous, err := provider.GetSecretVersion(ctx, "db_password", VersionPrevious)
+	if err != nil {
+		t.Fatalf("Failed to get previous version: %v", err)
+	}
+	if previous.Version != 1 || previous.Value != "first" {
+		t.Errorf("Unexpected previous version: %+v", previous)
+	}
+	// Setting a value replaces the old one at once
+	if previous.ExpiresAt == nil || !previous.ExpiresAt.Equal(now) {
+		t.Errorf("Expected the replaced version to expire at %s, got %v", now, previous.ExpiresAt)
+	}
+	if _, err := provider.GetSecretVersion(ctx, "db_password", 7); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("Expected ErrVersionNotFound, got: %v", err)
+	}
+
+	// The history survives a reload, and the current values stay a flat map
+	reopened := NewFileProvider(path)
+	versions, err := reopened.ListSecretVersions(ctx, "db_password")
+	if err != nil {
+		t.Fatalf("Failed to list versions: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 2 || versions[1].Version != 1 {
+		t.Errorf("Expected versions 2 and 1, got %+v", versions)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read secrets file: %v", err)
+	}
+	var flat map[string]string
+	if err := json.Unmarshal(data, &flat); err != nil {
+		t.Fatalf("Secrets file is not a flat map: %v", err)
+	}
+	if flat["db_password"] != "second" {
+		t.Errorf("Expected current value in the file, got %q", flat["db_password"])
+	}
+	keys, err := reopened.ListSecrets(ctx)
+	if err != nil || !reflect.DeepEqual(keys, []string{"db_password"}) {
+		t.Errorf("ListSecrets = %v, %v; want [db_password]", keys, err)
+	}
+}
+
+func TestFileProvider_RotateWithOverlap(t *testing.T) {
+	provider := NewFileProvider(filepath.Join(t.TempDir(), "secrets.json"))
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	provider.now = func() time.Time { return now }
+	provider.SetRotationOverlap(time.Hour)
+	ctx := context.Background()
+
+	if err := provider.SetSecret(ctx, "jwt_secret", "original"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if err := provider.Rotate(ctx, "jwt_secret"); err != nil {
+		t.Fatalf("Failed to rotate secret: %v", err)
+	}
+
+	rotated, err := provider.GetSecret(ctx, "jwt_secret")
+	if err != nil || rotated == "original" || len(rotated) != 64 {
+		t.Fatalf("GetSecret = %q, %v; want a new 64 character value", rotated, err)
+	}
+	// The old value stays valid for the overlap
+	values, err := provider.GetValidSecrets(ctx, "jwt_secret")
+	if err != nil || !reflect.DeepEqual(values, []string{rotated, "original"}) {
+		t.Errorf("GetValidSecrets = %v, %v; want the new and original values", values, err)
+	}
+	now = now.Add(time.Hour)
+	values, err = provider.GetValidSecrets(ctx, "jwt_secret")
+	if err != nil || !reflect.DeepEqual(values, []string{rotated}) {
+		t.Errorf("GetValidSecrets = %v, %v; want only the new value", values, err)
+	}
+
+	// A shorter overlap does not extend one already running
+	version, err := provider.RotateWithOverlap(ctx, "jwt_secret", 10*time.Minute)
+	if err != nil || version.Version != 3 {
+		t.Fatalf("RotateWithOverlap = %+v, %v; want version 3", version, err)
+	}
+	previous, err := provider.GetSecretVersion(ctx, "jwt_secret", VersionPrevious)
+	if err != nil || !previous.ExpiresAt.Equal(now.Add(10*time.Minute)) {
+		t.Errorf("Previous version = %+v, %v; want it to expire in 10m", previous, err)
+	}
+	if _, err := provider.RotateWithOverlap(ctx, "missing", time.Minute); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Expected ErrSecretNotFound, got: %v", err)
+	}
+}
+
+func TestFileProvider_Rollback(t *testing.T) {
+	provider := NewFileProvider(filepath.Join(t.TempDir(), "secrets.json"))
+	ctx := context.Background()
+
+	for _, value := range []string{"v1", "v2", "v3"} {
+		if err := provider.SetSecret(ctx, "api_key", value); err != nil {
+			t.Fatalf("Failed to set secret: %v", err)
+		}
+	}
+
+	restored, err := provider.Rollback(ctx, "api_key", 1)
+	if err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if restored.Version != 4 || restored.Value != "v1" {
+		t.Errorf("Unexpected restored version: %+v", restored)
+	}
+	if value, _ := provider.GetSecret(ctx, "api_key"); value != "v1" {
+		t.Errorf("Expected v1 after rollback, got %q", value)
+	}
+	values, err := provider.GetValidSecrets(ctx, "api_key")
+	if err != nil || !reflect.DeepEqual(values, []string{"v1"}) {
+		t.Errorf("GetValidSecrets = %v, %v; want only v1", values, err)
+	}
+
+	if _, err := provider.Rollback(ctx, "api_key", VersionCurrent); err == nil {
+		t.Error("Expected an error rolling back to the current version")
+	}
+	if _, err := provider.Rollback(ctx, "api_key", 9); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("Expected ErrVersionNotFound, got: %v", err)
+	}
+
+	// Deleting a secret drops its history
+	if err := provider.DeleteSecret(ctx, "api_key"); err != nil {
+		t.Fatalf("Failed to delete secret: %v", err)
+	}
+	if _, err := provider.ListSecretVersions(ctx, "api_key"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Expected ErrSecretNotFound, got: %v", err)
+	}
+}
+
+func TestFileProvider_VersionLimitAndExternalEdits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	provider := NewFileProvider(path)
+	ctx := context.Background()
+
+	for i := 0; i < MaxSecretVersions+3; i++ {
+		if err := provider.SetSecret(ctx, "token", string(rune('a'+i))); err != nil {
+			t.Fatalf("Failed to set secret: %v", err)
+		}
+	}
+	versions, err := provider.ListSecretVersions(ctx, "token")
+	if err != nil {
+		t.Fatalf("Failed to list versions: %v", err)
+	}
+	if len(versions) != MaxSecretVersions || versions[0].Version != MaxSecretVersions+3 {
+		t.Errorf("Expected the newest %d versions, got %d starting at %d", MaxSecretVersions, len(versions), versions[0].Version)
+	}
+
+	// A value edited by hand becomes a new version
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read secrets file: %v", err)
+	}
+	var doc map[string]string
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Failed to parse secrets file: %v", err)
+	}
+	doc["token"] = "edited"
+	data, _ = json.Marshal(doc)
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write secrets file: %v", err)
+	}
+
+	current, err := provider.GetSecretVersion(ctx, "token", VersionCurrent)
+	if err != nil {
+		t.Fatalf("Failed to get current version: %v", err)
+	}
+	if current.Version != MaxSecretVersions+4 || current.Value != "edited" {
+		t.Errorf("Unexpected version after external edit: %+v", current)
+	}
+}
+
+func TestFileProvider_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	provider := NewFileProvider(path)
+	provider.watchInterval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := provider.SetSecret(ctx, "api_key", "v1"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	events, err := provider.Watch(ctx, "api_key")
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	all, err := provider.Watch(ctx, "")
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+
+	next := func(ch <-chan SecretEvent) SecretEvent {
+		t.Helper()
+		select {
+		case event := <-ch:
+			return event
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for a secret event")
+			return SecretEvent{}
+		}
+	}
+
+	if err := provider.SetSecret(ctx, "other", "x"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if err := provider.Rotate(ctx, "api_key"); err != nil {
+		t.Fatalf("Failed to rotate secret: %v", err)
+	}
+	if event := next(events); event != (SecretEvent{Key: "api_key", Type: SecretUpdated, Version: 2}) {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event := next(all); event.Key != "other" {
+		t.Errorf("Expected an event for other, got %+v", event)
+	}
+	if event := next(all); event.Key != "api_key" {
+		t.Errorf("Expected an event for api_key, got %+v", event)
+	}
+
+	// Changes made by another process are picked up by polling
+	other := NewFileProvider(path)
+	time.Sleep(10 * time.Millisecond)
+	if err := other.DeleteSecret(context.Background(), "api_key"); err != nil {
+		t.Fatalf("Failed to delete secret: %v", err)
+	}
+	if event := next(events); event != (SecretEvent{Key: "api_key", Type: SecretDeleted}) {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	cancel()
+	for range events {
+	}
+}
@@ -0,0 +1,398 @@
+package security
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/security/secrets"
+)
+
+// Algorithms AgentFlow-issued tokens can be signed with
+const (
+	SigningAlgRS256 = "RS256"
+	SigningAlgEdDSA = "EdDSA"
+)
+
+// DefaultSigningKeysSecret is the secret the signing key ring is kept in
+const DefaultSigningKeysSecret = "jwt_signing_keys"
+
+// Timing defaults for the signing key ring
+const (
+	// DefaultSigningKeyReload is how often replicas reload the key ring
+	DefaultSigningKeyReload = time.Minute
+	// DefaultSigningKeyOverlap is how long a replaced key keeps verifying
+	// tokens. It must be at least the token lifetime.
+	DefaultSigningKeyOverlap = 48 * time.Hour
+	// signingKeyMinReload limits how often an unknown key ID triggers a reload
+	signingKeyMinReload = 10 * time.Second
+)
+
+// SigningKeyPersister is implemented by authenticators that can sign tokens
+// with the keys of a SigningKeyRing
+type SigningKeyPersister interface {
+	SetSigningKeys(ring *SigningKeyRing)
+}
+
+// SigningKey is a private key that AgentFlow tokens are signed with
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Key       crypto.Signer
+	CreatedAt time.Time
+	RetireAt  time.Time // zero for the key new tokens are signed with
+}
+
+// storedSigningKey is a signing key as kept in the secrets provider
+type storedSigningKey struct {
+	ID         string     `json:"kid"`
+	Algorithm  string     `json:"alg"`
+	PrivateKey string     `json:"private_key"` // PKCS #8 PEM
+	CreatedAt  time.Time  `json:"created_at"`
+	RetireAt   *time.Time `json:"retire_at,omitempty"`
+}
+
+type storedSigningKeys struct {
+	Keys []storedSigningKey `json:"keys"`
+}
+
+// GenerateSigningKey creates a key for alg, identified by its creation date
+// and a random suffix
+func GenerateSigningKey(alg string) (SigningKey, error) {
+	var key crypto.Signer
+	var err error
+	switch alg {
+	case SigningAlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case SigningAlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return SigningKey{}, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return SigningKey{}, fmt.Errorf("failed to generate key ID: %w", err)
+	}
+	now := time.Now().UTC()
+	return SigningKey{
+		ID:        now.Format("20060102") + "-" + hex.EncodeToString(suffix),
+		Algorithm: alg,
+		Key:       key,
+		CreatedAt: now,
+	}, nil
+}
+
+// encodeSigningKeys serializes keys for the secrets provider
+func encodeSigningKeys(keys []SigningKey) (string, error) {
+	stored := storedSigningKeys{Keys: make([]storedSigningKey, 0, len(keys))}
+	for _, k := range keys {
+		der, err := x509.MarshalPKCS8PrivateKey(k.Key)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode signing key %s: %w", k.ID, err)
+		}
+		s := storedSigningKey{
+			ID:         k.ID,
+			Algorithm:  k.Algorithm,
+			PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+			CreatedAt:  k.CreatedAt,
+		}
+		if !k.RetireAt.IsZero() {
+			retireAt := k.RetireAt
+			s.RetireAt = &retireAt
+		}
+		stored.Keys = append(stored.Keys, s)
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeSigningKeys parses keys kept in the secrets provider
+func decodeSigningKeys(value string) ([]SigningKey, error) {
+	var stored storedSigningKeys
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse signing keys: %w", err)
+	}
+
+	keys := make([]SigningKey, 0, len(stored.Keys))
+	for _, s := range stored.Keys {
+		block, _ := pem.Decode([]byte(s.PrivateKey))
+		if block == nil {
+			return nil, fmt.Errorf("signing key %s is not PEM encoded", s.ID)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %s: %w", s.ID, err)
+		}
+		key := SigningKey{ID: s.ID, Algorithm: s.Algorithm, CreatedAt: s.CreatedAt}
+		switch k := parsed.(type) {
+		case *rsa.PrivateKey:
+			if s.Algorithm != SigningAlgRS256 {
+				return nil, fmt.Errorf("signing key %s is an RSA key, not %s", s.ID, s.Algorithm)
+			}
+			key.Key = k
+		case ed25519.PrivateKey:
+			if s.Algorithm != SigningAlgEdDSA {
+				return nil, fmt.Errorf("signing key %s is an Ed25519 key, not %s", s.ID, s.Algorithm)
+			}
+			key.Key = k
+		default:
+			return nil, fmt.Errorf("signing key %s has unsupported type %T", s.ID, parsed)
+		}
+		if s.ID == "" {
+			return nil, errors.New("signing key without kid")
+		}
+		if s.RetireAt != nil {
+			key.RetireAt = *s.RetireAt
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// LoadSigningKeys reads the signing keys kept in secret
+func LoadSigningKeys(ctx context.Context, provider secrets.SecretsProvider, secret string) ([]SigningKey, error) {
+	value, err := provider.GetSecret(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSigningKeys(value)
+}
+
+// RotateSigningKeys generates a key for alg that new tokens are signed with.
+// The key it replaces keeps verifying tokens for overlap, which must be at
+// least the token lifetime; keys past their overlap are removed.
+func RotateSigningKeys(ctx context.Context, provider secrets.SecretsProvider, secret, alg string, overlap time.Duration) (SigningKey, error) {
+	keys, err := LoadSigningKeys(ctx, provider, secret)
+	if err != nil && !errors.Is(err, secrets.ErrSecretNotFound) {
+		return SigningKey{}, err
+	}
+
+	next, err := GenerateSigningKey(alg)
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	kept := make([]SigningKey, 0, len(keys)+1)
+	for _, k := range keys {
+		if k.RetireAt.IsZero() {
+			k.RetireAt = next.CreatedAt.Add(overlap)
+		}
+		if k.RetireAt.After(next.CreatedAt) {
+			kept = append(kept, k)
+		}
+	}
+	kept = append(kept, next)
+
+	value, err := encodeSigningKeys(kept)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	if err := provider.SetSecret(ctx, secret, value); err != nil {
+		return SigningKey{}, fmt.Errorf("failed to store signing keys: %w", err)
+	}
+	return next, nil
+}
+
+// SigningKeyRing holds the signing keys kept in a secrets provider. New
+// tokens are signed with the newest key that is not retiring; tokens signed
+// with any key are verified until the key retires. The ring is reloaded
+// periodically and when a token names an unknown key, so replicas pick up
+// rotations.
+type SigningKeyRing struct {
+	provider secrets.SecretsProvider
+	secret   string
+	now      func() time.Time
+
+	reloadMu sync.Mutex // serializes reloads
+
+	mu         sync.RWMutex
+	active     SigningKey
+	keys       map[string]SigningKey
+	reloadedAt time.Time // last reload attempt
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{} // nil until Start is called
+}
+
+// NewSigningKeyRing loads the key ring kept in secret. It fails if the
+// secret holds no key to sign with.
+func NewSigningKeyRing(ctx context.Context, provider secrets.SecretsProvider, secret string) (*SigningKeyRing, error) {
+	r := &SigningKeyRing{
+		provider: provider,
+		secret:   secret,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+	if err := r.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the key ring again. On failure the current keys are kept.
+func (r *SigningKeyRing) Reload(ctx context.Context) error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	return r.reload(ctx)
+}
+
+// reload does the work of Reload; reloadMu must be held
+func (r *SigningKeyRing) reload(ctx context.Context) error {
+	r.mu.Lock()
+	r.reloadedAt = r.now()
+	r.mu.Unlock()
+
+	keys, err := LoadSigningKeys(ctx, r.provider, r.secret)
+	if err != nil {
+		return err
+	}
+
+	var active SigningKey
+	byID := make(map[string]SigningKey, len(keys))
+	for _, k := range keys {
+		byID[k.ID] = k
+		if k.RetireAt.IsZero() && (active.Key == nil || k.CreatedAt.After(active.CreatedAt)) {
+			active = k
+		}
+	}
+	if active.Key == nil {
+		return errors.New("no active signing key: every key is retiring")
+	}
+
+	r.mu.Lock()
+	r.active = active
+	r.keys = byID
+	r.mu.Unlock()
+	return nil
+}
+
+// Active returns the key new tokens are signed with
+func (r *SigningKeyRing) Active() SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active
+}
+
+// verificationKey returns the unretired key with kid, reloading the ring
+// once if the key is unknown
+func (r *SigningKeyRing) verificationKey(ctx context.Context, kid string) (SigningKey, error) {
+	if key, ok := r.lookup(kid); ok {
+		return key, nil
+	}
+
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	if key, ok := r.lookup(kid); ok {
+		return key, nil
+	}
+	r.mu.RLock()
+	reloadedAt := r.reloadedAt
+	r.mu.RUnlock()
+	if r.now().Sub(reloadedAt) >= signingKeyMinReload {
+		if err := r.reload(ctx); err != nil {
+			return SigningKey{}, fmt.Errorf("failed to reload signing keys: %w", err)
+		}
+		if key, ok := r.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return SigningKey{}, fmt.Errorf("unknown or retired signing key %q", kid)
+}
+
+func (r *SigningKeyRing) lookup(kid string) (SigningKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[kid]
+	if !ok || (!key.RetireAt.IsZero() && !r.now().Before(key.RetireAt)) {
+		return SigningKey{}, false
+	}
+	return key, true
+}
+
+// JWKS returns the public halves of the unretired keys
+func (r *SigningKeyRing) JWKS() JSONWebKeySet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	now := r.now()
+	for _, k := range r.keys {
+		if !k.RetireAt.IsZero() && !now.Before(k.RetireAt) {
+			continue
+		}
+		jwk, err := NewJSONWebKey(k.ID, k.Algorithm, k.Key.Public())
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// Start reloads the ring every interval until Close is called. A failed
+// reload keeps the current keys and is retried on the next tick. When the
+// provider is versioned, the ring also reloads as soon as it reports a
+// change to the secret.
+func (r *SigningKeyRing) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSigningKeyReload
+	}
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	var changes <-chan secrets.SecretEvent
+	if versioned, ok := r.provider.(secrets.VersionedProvider); ok {
+		// Without a watch the ring still reloads every interval
+		changes, _ = versioned.Watch(watchCtx, r.secret)
+	}
+
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		defer stopWatch()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case _, ok := <-changes:
+				if !ok {
+					changes = nil
+					continue
+				}
+			case <-ticker.C:
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			_ = r.Reload(ctx)
+			cancel()
+		}
+	}()
+}
+
+// Close stops periodic reloads
+func (r *SigningKeyRing) Close() error {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+		if r.done != nil {
+			<-r.done
+		}
+	})
+	return nil
+}
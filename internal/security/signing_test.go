@@ -0,0 +1,218 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/security/secrets"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSigningTestProvider returns a secrets provider holding one signing key
+func newSigningTestProvider(t *testing.T, alg string) secrets.SecretsProvider {
+	t.Helper()
+	provider := secrets.NewFileProvider(filepath.Join(t.TempDir(), "secrets.json"))
+	_, err := RotateSigningKeys(context.Background(), provider, DefaultSigningKeysSecret, alg, time.Hour)
+	require.NoError(t, err)
+	return provider
+}
+
+func newSigningTestAuthenticator(t *testing.T, ring *SigningKeyRing) Authenticator {
+	t.Helper()
+	auth := newRefreshTestAuthenticator()
+	auth.(SigningKeyPersister).SetSigningKeys(ring)
+	return auth
+}
+
+func tokenHeader(t *testing.T, token string) map[string]interface{} {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &AgentFlowClaims{})
+	require.NoError(t, err)
+	return parsed.Header
+}
+
+func TestRotateSigningKeys(t *testing.T) {
+	provider := newSigningTestProvider(t, SigningAlgEdDSA)
+	ctx := context.Background()
+
+	keys, err := LoadSigningKeys(ctx, provider, DefaultSigningKeysSecret)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	first := keys[0]
+	assert.True(t, first.RetireAt.IsZero())
+
+	second, err := RotateSigningKeys(ctx, provider, DefaultSigningKeysSecret, SigningAlgRS256, 2*time.Hour)
+	require.NoError(t, err)
+	keys, err = LoadSigningKeys(ctx, provider, DefaultSigningKeysSecret)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, first.ID, keys[0].ID)
+	assert.WithinDuration(t, second.CreatedAt.Add(2*time.Hour), keys[0].RetireAt, time.Second, "the replaced key retires after the overlap")
+	assert.Equal(t, second.ID, keys[1].ID)
+	assert.True(t, keys[1].RetireAt.IsZero())
+
+	// Without overlap the replaced key is dropped at once; the key retiring
+	// from the earlier rotation keeps its own overlap
+	third, err := RotateSigningKeys(ctx, provider, DefaultSigningKeysSecret, SigningAlgEdDSA, 0)
+	require.NoError(t, err)
+	keys, err = LoadSigningKeys(ctx, provider, DefaultSigningKeysSecret)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, first.ID, keys[0].ID)
+	assert.Equal(t, third.ID, keys[1].ID)
+
+	_, err = RotateSigningKeys(ctx, provider, DefaultSigningKeysSecret, "HS256", time.Hour)
+	assert.Error(t, err)
+}
+
+func TestSigningKeyRing_MissingSecret(t *testing.T) {
+	provider := secrets.NewFileProvider(filepath.Join(t.TempDir(), "secrets.json"))
+	_, err := NewSigningKeyRing(context.Background(), provider, DefaultSigningKeysSecret)
+	assert.ErrorIs(t, err, secrets.ErrSecretNotFound)
+}
+
+func TestSigningKeys_IssueAndValidate(t *testing.T) {
+	for _, alg := range []string{SigningAlgEdDSA, SigningAlgRS256} {
+		t.Run(alg, func(t *testing.T) {
+			ctx := context.Background()
+			ring, err := NewSigningKeyRing(ctx, newSigningTestProvider(t, alg), DefaultSigningKeysSecret)
+			require.NoError(t, err)
+			auth := newSigningTestAuthenticator(t, ring)
+
+			issued, err := auth.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+			require.NoError(t, err)
+			header := tokenHeader(t, issued.AccessToken)
+			assert.Equal(t, alg, header["alg"])
+			assert.Equal(t, ring.Active().ID, header["kid"])
+
+			claims, err := auth.ValidateToken(ctx, issued.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, "tenant123", claims.TenantID)
+			require.NoError(t, auth.RevokeToken(ctx, issued.AccessToken))
+			_, err = auth.ValidateToken(ctx, issued.AccessToken)
+			assert.ErrorIs(t, err, ErrTokenRevoked)
+		})
+	}
+}
+
+func TestSigningKeys_LegacyHS256(t *testing.T) {
+	ctx := context.Background()
+	ring, err := NewSigningKeyRing(ctx, newSigningTestProvider(t, SigningAlgEdDSA), DefaultSigningKeysSecret)
+	require.NoError(t, err)
+
+	// Issued with the shared secret before signing keys were configured
+	legacy, err := newRefreshTestAuthenticator().IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+	require.NoError(t, err)
+
+	auth := newSigningTestAuthenticator(t, ring)
+	_, err = auth.ValidateToken(ctx, legacy.AccessToken)
+	assert.Error(t, err, "HS256 tokens are rejected once signing keys are configured")
+
+	auth.(*jwtAuthenticator).config.AcceptLegacyHS256 = true
+	_, err = auth.ValidateToken(ctx, legacy.AccessToken)
+	assert.NoError(t, err)
+
+	// Without signing keys, asymmetric tokens are rejected
+	signed, err := auth.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+	require.NoError(t, err)
+	_, err = newRefreshTestAuthenticator().ValidateToken(ctx, signed.AccessToken)
+	assert.Error(t, err)
+}
+
+func TestSigningKeys_Rotation(t *testing.T) {
+	ctx := context.Background()
+	provider := newSigningTestProvider(t, SigningAlgEdDSA)
+
+	// Two replicas share the key ring
+	replicaA, err := NewSigningKeyRing(ctx, provider, DefaultSigningKeysSecret)
+	require.NoError(t, err)
+	replicaB, err := NewSigningKeyRing(ctx, provider, DefaultSigningKeysSecret)
+	require.NoError(t, err)
+	now := time.Now()
+	replicaB.now = func() time.Time { return now }
+	authA := newSigningTestAuthenticator(t, replicaA)
+	authB := newSigningTestAuthenticator(t, replicaB)
+
+	before, err := authA.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+	require.NoError(t, err)
+
+	_, err = RotateSigningKeys(ctx, provider, DefaultSigningKeysSecret, SigningAlgEdDSA, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, replicaA.Reload(ctx))
+	after, err := authA.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+	require.NoError(t, err)
+	assert.NotEqual(t, tokenHeader(t, before.AccessToken)["kid"], tokenHeader(t, after.AccessToken)["kid"])
+
+	// Replica B has not reloaded yet; a token naming an unknown key triggers
+	// a reload once the last one is old enough
+	_, err = authB.ValidateToken(ctx, before.AccessToken)
+	assert.NoError(t, err, "the replaced key verifies during the overlap")
+	_, err = authB.ValidateToken(ctx, after.AccessToken)
+	assert.Error(t, err, "reloads are rate limited")
+	now = now.Add(signingKeyMinReload)
+	_, err = authB.ValidateToken(ctx, after.AccessToken)
+	assert.NoError(t, err)
+
+	// Once retired the replaced key no longer verifies, nor is it published
+	now = now.Add(time.Hour)
+	_, err = authB.ValidateToken(ctx, before.AccessToken)
+	assert.Error(t, err)
+	_, err = authB.ValidateToken(ctx, after.AccessToken)
+	assert.NoError(t, err)
+	assert.Len(t, replicaB.JWKS().Keys, 1)
+}
+
+func TestSigningKeyRing_ReloadsOnChange(t *testing.T) {
+	ctx := context.Background()
+	provider := newSigningTestProvider(t, SigningAlgEdDSA)
+	ring, err := NewSigningKeyRing(ctx, provider, DefaultSigningKeysSecret)
+	require.NoError(t, err)
+	ring.Start(time.Hour)
+	defer ring.Close()
+
+	// The rotation is reported by the provider, not picked up by the hourly
+	// reload
+	key, err := RotateSigningKeys(ctx, provider, DefaultSigningKeysSecret, SigningAlgEdDSA, time.Hour)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return ring.Active().ID == key.ID }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestJWKSValidator(t *testing.T) {
+	ctx := context.Background()
+	ring, err := NewSigningKeyRing(ctx, newSigningTestProvider(t, SigningAlgEdDSA), DefaultSigningKeysSecret)
+	require.NoError(t, err)
+	auth := newSigningTestAuthenticator(t, ring)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(ring.JWKS())
+	}))
+	defer server.Close()
+
+	validator := NewJWKSValidator(server.URL, time.Hour)
+	defer validator.Close()
+
+	issued, err := auth.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456", Roles: []string{"admin"}})
+	require.NoError(t, err)
+	claims, err := validator.ValidateToken(ctx, issued.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user456", claims.UserID)
+	assert.Equal(t, []string{"admin"}, claims.Roles)
+
+	// Tokens signed with the shared secret cannot be checked offline
+	legacy, err := newRefreshTestAuthenticator().IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456"})
+	require.NoError(t, err)
+	_, err = validator.ValidateToken(ctx, legacy.AccessToken)
+	assert.Error(t, err)
+
+	expired, err := auth.IssueToken(ctx, &TokenRequest{TenantID: "tenant123", UserID: "user456", ExpiresIn: -60})
+	require.NoError(t, err)
+	_, err = validator.ValidateToken(ctx, expired.AccessToken)
+	assert.Error(t, err)
+}
@@ -3,37 +3,56 @@ package security
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/quota"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	lifecycle "github.com/agentflow/agentflow/internal/storage/tenant"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // TenantContext represents tenant-specific context information
 type TenantContext struct {
 	TenantID       string                 `json:"tenant_id"`
 	TenantName     string                 `json:"tenant_name"`
+	Tier           string                 `json:"tier"`
+	Status         string                 `json:"status"`
 	Permissions    []string               `json:"permissions"`
 	ResourceLimits map[string]interface{} `json:"resource_limits"`
+	// Limits are the tier's limits with ResourceLimits applied
+	Limits quota.Limits `json:"limits"`
 }
 
 // TenantIsolationMiddleware provides tenant isolation and cross-tenant access prevention
 type TenantIsolationMiddleware struct {
-	logger logging.Logger
-	db     *sql.DB // Database connection for tenant validation
+	logger   logging.Logger
+	tenants  TenantQuerier
+	recorder audit.Recorder
 }
 
-// NewTenantIsolationMiddleware creates a new tenant isolation middleware
-func NewTenantIsolationMiddleware(logger logging.Logger, db *sql.DB) *TenantIsolationMiddleware {
+// NewTenantIsolationMiddleware creates a new tenant isolation middleware that
+// looks tenants up through tenants, usually a TenantCache in front of the
+// queries on the database pool
+func NewTenantIsolationMiddleware(logger logging.Logger, tenants TenantQuerier) *TenantIsolationMiddleware {
 	return &TenantIsolationMiddleware{
-		logger: logger,
-		db:     db,
+		logger:  logger,
+		tenants: tenants,
 	}
 }
 
+// WithRecorder records rejected tenants and cross-tenant access attempts in
+// the audit trail
+func (tim *TenantIsolationMiddleware) WithRecorder(recorder audit.Recorder) *TenantIsolationMiddleware {
+	tim.recorder = recorder
+	return tim
+}
+
 // Middleware returns the HTTP middleware function for tenant isolation
 func (tim *TenantIsolationMiddleware) Middleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -51,6 +70,14 @@ func (tim *TenantIsolationMiddleware) Middleware() func(http.Handler) http.Handl
 				return
 			}
 
+			// Worker and service certificates issued without a tenant act
+			// for the platform rather than a tenant, so they carry no
+			// tenant context. Tenant-scoped certificates are checked below.
+			if claims.IsClientCertificate() && claims.TenantID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			// Validate tenant exists and is active
 			tenantContext, err := tim.validateAndLoadTenant(r.Context(), claims.TenantID)
 			if err != nil {
@@ -59,10 +86,30 @@ func (tim *TenantIsolationMiddleware) Middleware() func(http.Handler) http.Handl
 					logging.String("user_id", claims.UserID),
 					logging.String("path", r.URL.Path))
 
-				tim.auditCrossTenantAttempt(r.Context(), claims, "tenant_validation_failed", err.Error())
+				tim.auditCrossTenantAttempt(r, claims, "tenant_validation_failed", err.Error())
 				tim.writeError(w, "invalid_tenant", "Tenant validation failed", http.StatusForbidden)
 				return
 			}
+			tenantContext.Permissions = requestPermissions(r.Context(), claims)
+
+			// Suspended and pending-deletion tenants are read-only, erased
+			// tenants are closed. Signing in, refreshing and revoking tokens
+			// do not change tenant data, so they count as reads.
+			method := r.Method
+			if strings.HasPrefix(r.URL.Path, "/api/v1/auth/") {
+				method = http.MethodGet
+			}
+			if err := lifecycle.CheckAccess(tenantContext.Status, method); err != nil {
+				tim.logger.Warn("Request rejected by tenant status",
+					logging.String("tenant_id", claims.TenantID),
+					logging.String("status", tenantContext.Status),
+					logging.String("path", r.URL.Path),
+					logging.String("method", r.Method))
+
+				code, message := tenantStatusError(err)
+				tim.writeError(w, code, message, http.StatusForbidden)
+				return
+			}
 
 			// Check for cross-tenant access attempts in request parameters
 			if err := tim.validateRequestTenantScope(r, claims.TenantID); err != nil {
@@ -73,7 +120,7 @@ func (tim *TenantIsolationMiddleware) Middleware() func(http.Handler) http.Handl
 					logging.String("method", r.Method),
 					logging.String("error", err.Error()))
 
-				tim.auditCrossTenantAttempt(r.Context(), claims, "cross_tenant_access", err.Error())
+				tim.auditCrossTenantAttempt(r, claims, "cross_tenant_access", err.Error())
 				tim.writeError(w, "cross_tenant_access_denied", "Cross-tenant access is not permitted", http.StatusForbidden)
 				return
 			}
@@ -100,14 +147,13 @@ func (tim *TenantIsolationMiddleware) validateAndLoadTenant(ctx context.Context,
 		return nil, fmt.Errorf("tenant_id is required")
 	}
 
-	// Query tenant from database
-	query := `SELECT id, name, tier, settings FROM tenants WHERE id = $1`
-	var id, name, tier string
-	var settings []byte
-
-	err := tim.db.QueryRowContext(ctx, query, tenantID).Scan(&id, &name, &tier, &settings)
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		return nil, fmt.Errorf("tenant not found: %s", tenantID)
+	}
+	tenant, err := tim.tenants.GetTenant(ctx, tenantUUID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("tenant not found: %s", tenantID)
 		}
 		return nil, fmt.Errorf("failed to query tenant: %w", err)
@@ -115,8 +161,8 @@ func (tim *TenantIsolationMiddleware) validateAndLoadTenant(ctx context.Context,
 
 	// Parse tenant settings
 	var settingsMap map[string]interface{}
-	if len(settings) > 0 {
-		if err := json.Unmarshal(settings, &settingsMap); err != nil {
+	if len(tenant.Settings) > 0 {
+		if err := json.Unmarshal(tenant.Settings, &settingsMap); err != nil {
 			tim.logger.Warn("Failed to parse tenant settings",
 				logging.String("tenant_id", tenantID),
 				logging.String("error", err.Error()))
@@ -131,15 +177,37 @@ func (tim *TenantIsolationMiddleware) validateAndLoadTenant(ctx context.Context,
 	if limits, ok := settingsMap["resource_limits"].(map[string]interface{}); ok {
 		resourceLimits = limits
 	}
+	limits, err := quota.LimitsFor(tenant.Tier, resourceLimits)
+	if err != nil {
+		tim.logger.Warn("Invalid tenant resource limits",
+			logging.String("tenant_id", tenantID),
+			logging.String("error", err.Error()))
+	}
 
 	return &TenantContext{
-		TenantID:       id,
-		TenantName:     name,
-		Permissions:    []string{}, // Will be populated from RBAC
+		TenantID:       tenantID,
+		TenantName:     tenant.Name,
+		Tier:           tenant.Tier,
+		Status:         tenant.Status,
+		Permissions:    []string{},
 		ResourceLimits: resourceLimits,
+		Limits:         limits,
 	}, nil
 }
 
+// tenantStatusError maps a lifecycle access error to an API error code and message.
+// Erased tenants are reported like unknown ones.
+func tenantStatusError(err error) (string, string) {
+	switch {
+	case errors.Is(err, lifecycle.ErrSuspended):
+		return "tenant_suspended", "Tenant is suspended; only read access is permitted"
+	case errors.Is(err, lifecycle.ErrPendingDeletion):
+		return "tenant_pending_deletion", "Tenant is scheduled for deletion; only read access is permitted"
+	default:
+		return "invalid_tenant", "Tenant validation failed"
+	}
+}
+
 // validateRequestTenantScope checks for cross-tenant access attempts in request
 func (tim *TenantIsolationMiddleware) validateRequestTenantScope(r *http.Request, userTenantID string) error {
 	// Check query parameters for tenant_id
@@ -173,39 +241,15 @@ func (tim *TenantIsolationMiddleware) validateRequestTenantScope(r *http.Request
 	return nil
 }
 
-// auditCrossTenantAttempt logs cross-tenant access attempts for security monitoring
-func (tim *TenantIsolationMiddleware) auditCrossTenantAttempt(ctx context.Context, claims *AgentFlowClaims, attemptType, details string) {
-	// Create audit entry in database
-	auditQuery := `
-		INSERT INTO audits (tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, prev_hash, hash)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-
-	auditDetails := map[string]interface{}{
-		"attempt_type": attemptType,
-		"details":      details,
-		"user_id":      claims.UserID,
-		"roles":        claims.Roles,
-		"timestamp":    "now", // Will be replaced by database timestamp
-	}
-
-	detailsJSON, _ := json.Marshal(auditDetails)
+// auditCrossTenantAttempt records a denied request in the audit trail of the
+// caller's tenant
+func (tim *TenantIsolationMiddleware) auditCrossTenantAttempt(r *http.Request, claims *AgentFlowClaims, attemptType, details string) {
+	event := claimsAuditEvent(r, claims, AuditActionCrossTenantAccess, "security_violation", audit.OutcomeDenied)
+	event.Details["attempt_type"] = attemptType
+	event.Details["details"] = details
+	event.Critical = true
 
-	// For now, use placeholder hash values - in production this would use the hash chain
-	prevHash := []byte{}
-	hash := []byte("placeholder_hash")
-
-	_, err := tim.db.ExecContext(ctx, auditQuery,
-		claims.TenantID,
-		"user",
-		claims.UserID,
-		"cross_tenant_access_attempt",
-		"security_violation",
-		"",
-		detailsJSON,
-		prevHash,
-		hash)
-
-	if err != nil {
+	if err := recordAudit(r.Context(), tim.recorder, event); err != nil {
 		tim.logger.Error("Failed to audit cross-tenant access attempt", err,
 			logging.String("tenant_id", claims.TenantID),
 			logging.String("user_id", claims.UserID),
@@ -220,8 +264,10 @@ func (tim *TenantIsolationMiddleware) isPublicEndpoint(path string) bool {
 		"/api/v1/health",
 		"/",
 		"/api",
-		"/api/v1/auth/token",
+		"/api/v1/auth/login",
+		"/api/v1/auth/refresh",
 		"/api/v1/auth/validate",
+		"/.well-known/jwks.json",
 	}
 
 	for _, endpoint := range publicEndpoints {
@@ -0,0 +1,195 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nats-io/nats.go"
+)
+
+// TenantQuerier looks up tenants, as the sqlc queries on the pgx pool and
+// TenantCache do
+type TenantQuerier interface {
+	GetTenant(ctx context.Context, id pgtype.UUID) (queries.Tenant, error)
+}
+
+// TenantInvalidationBus tells the other replicas which tenants changed so
+// they can drop them from their caches
+type TenantInvalidationBus interface {
+	// Publish announces that tenantID changed
+	Publish(ctx context.Context, tenantID string) error
+	// Subscribe calls handler for every change announced, and returns a
+	// function that stops the subscription
+	Subscribe(handler func(tenantID string)) (func() error, error)
+}
+
+// DefaultTenantInvalidationSubject is the NATS subject tenant changes are
+// announced on
+const DefaultTenantInvalidationSubject = "system.tenants.invalidations"
+
+// tenantInvalidationMessage is a tenant change announced on the bus
+type tenantInvalidationMessage struct {
+	TenantID string `json:"tenant_id"`
+}
+
+// natsTenantInvalidationBus announces tenant changes with core NATS
+// publish/subscribe. Announcements are not persisted; a replica that misses
+// one serves the old tenant until its cache entry expires.
+type natsTenantInvalidationBus struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSTenantInvalidationBus creates a tenant invalidation bus on subject
+func NewNATSTenantInvalidationBus(conn *nats.Conn, subject string) TenantInvalidationBus {
+	if subject == "" {
+		subject = DefaultTenantInvalidationSubject
+	}
+	return &natsTenantInvalidationBus{conn: conn, subject: subject}
+}
+
+func (b *natsTenantInvalidationBus) Publish(ctx context.Context, tenantID string) error {
+	data, err := json.Marshal(tenantInvalidationMessage{TenantID: tenantID})
+	if err != nil {
+		return err
+	}
+	if err := b.conn.Publish(b.subject, data); err != nil {
+		return fmt.Errorf("failed to announce tenant change: %w", err)
+	}
+	return nil
+}
+
+func (b *natsTenantInvalidationBus) Subscribe(handler func(tenantID string)) (func() error, error) {
+	sub, err := b.conn.Subscribe(b.subject, func(msg *nats.Msg) {
+		var m tenantInvalidationMessage
+		if err := json.Unmarshal(msg.Data, &m); err != nil || m.TenantID == "" {
+			return
+		}
+		handler(m.TenantID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to tenant changes: %w", err)
+	}
+	return sub.Unsubscribe, nil
+}
+
+// DefaultTenantCacheTTL is how long a cached tenant is served before it is
+// read again
+const DefaultTenantCacheTTL = 30 * time.Second
+
+// TenantCache answers tenant lookups from memory in front of a TenantQuerier.
+// Tenants are kept for the cache TTL, which bounds how long a replica that
+// missed an announcement on the bus serves a tenant's old status. Lookups
+// that fail, including of unknown tenants, are not cached.
+type TenantCache struct {
+	q   TenantQuerier
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]tenantEntry
+	lastPrune time.Time
+	bus       TenantInvalidationBus
+	// drops counts invalidations, so a lookup that raced with one does not
+	// cache what it read
+	drops uint64
+}
+
+// tenantEntry is a cached tenant
+type tenantEntry struct {
+	tenant queries.Tenant
+	until  time.Time
+}
+
+// NewTenantCache caches the tenants looked up through q for ttl
+func NewTenantCache(q TenantQuerier, ttl time.Duration) *TenantCache {
+	if ttl <= 0 {
+		ttl = DefaultTenantCacheTTL
+	}
+	return &TenantCache{
+		q:       q,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]tenantEntry),
+	}
+}
+
+// Listen drops the tenants other replicas announce on bus, and announces
+// the ones invalidated here. The returned function stops listening.
+func (c *TenantCache) Listen(bus TenantInvalidationBus) (func() error, error) {
+	stop, err := bus.Subscribe(c.drop)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.bus = bus
+	c.mu.Unlock()
+	return stop, nil
+}
+
+// GetTenant returns the tenant from the cache, or from the querier on a miss
+func (c *TenantCache) GetTenant(ctx context.Context, id pgtype.UUID) (queries.Tenant, error) {
+	key := uuid.UUID(id.Bytes).String()
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	drops := c.drops
+	c.mu.Unlock()
+	if ok && now.Before(entry.until) {
+		return entry.tenant, nil
+	}
+
+	tenant, err := c.q.GetTenant(ctx, id)
+	if err != nil {
+		return queries.Tenant{}, err
+	}
+
+	c.mu.Lock()
+	if c.drops == drops {
+		c.prune(now)
+		c.entries[key] = tenantEntry{tenant: tenant, until: now.Add(c.ttl)}
+	}
+	c.mu.Unlock()
+	return tenant, nil
+}
+
+// Invalidate drops a tenant after its status, tier or settings change and
+// announces the change to the other replicas
+func (c *TenantCache) Invalidate(ctx context.Context, tenantID string) error {
+	c.drop(tenantID)
+
+	c.mu.Lock()
+	bus := c.bus
+	c.mu.Unlock()
+	if bus == nil {
+		return nil
+	}
+	return bus.Publish(ctx, tenantID)
+}
+
+func (c *TenantCache) drop(tenantID string) {
+	c.mu.Lock()
+	delete(c.entries, tenantID)
+	c.drops++
+	c.mu.Unlock()
+}
+
+// prune drops expired tenants, at most once per TTL; c.mu must be held
+func (c *TenantCache) prune(now time.Time) {
+	if now.Sub(c.lastPrune) < c.ttl {
+		return
+	}
+	c.lastPrune = now
+	for key, entry := range c.entries {
+		if !now.Before(entry.until) {
+			delete(c.entries, key)
+		}
+	}
+}
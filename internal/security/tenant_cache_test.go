@@ -0,0 +1,220 @@
+package security
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	lifecycle "github.com/agentflow/agentflow/internal/storage/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingTenantQuerier serves tenants from memory and counts lookups
+type countingTenantQuerier struct {
+	mu      sync.Mutex
+	tenants map[string]queries.Tenant
+	lookups int
+}
+
+func (q *countingTenantQuerier) GetTenant(ctx context.Context, id pgtype.UUID) (queries.Tenant, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.lookups++
+	tenant, ok := q.tenants[uuid.UUID(id.Bytes).String()]
+	if !ok {
+		return queries.Tenant{}, pgx.ErrNoRows
+	}
+	return tenant, nil
+}
+
+func (q *countingTenantQuerier) setStatus(tenantID, status string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tenant := q.tenants[tenantID]
+	tenant.Status = status
+	q.tenants[tenantID] = tenant
+}
+
+// memoryTenantBus delivers tenant changes to every subscriber at once
+type memoryTenantBus struct {
+	mu       sync.Mutex
+	handlers []func(string)
+}
+
+func (b *memoryTenantBus) Publish(ctx context.Context, tenantID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, h := range b.handlers {
+		h(tenantID)
+	}
+	return nil
+}
+
+func (b *memoryTenantBus) Subscribe(handler func(string)) (func() error, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return func() error { return nil }, nil
+}
+
+func newTenantQuerier() *countingTenantQuerier {
+	return &countingTenantQuerier{tenants: map[string]queries.Tenant{
+		auditTestTenant: {
+			Name:     "acme",
+			Tier:     "standard",
+			Status:   lifecycle.StatusActive,
+			Settings: []byte(`{"resource_limits":{"max_agents":5}}`),
+		},
+	}}
+}
+
+func tenantUUID(t *testing.T, s string) pgtype.UUID {
+	t.Helper()
+	var id pgtype.UUID
+	require.NoError(t, id.Scan(s))
+	return id
+}
+
+func TestTenantCache(t *testing.T) {
+	q := newTenantQuerier()
+	cache := NewTenantCache(q, time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+	id := tenantUUID(t, auditTestTenant)
+
+	tenant, err := cache.GetTenant(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenant.Name)
+	_, err = cache.GetTenant(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 1, q.lookups)
+
+	// Entries expire after the TTL
+	now = now.Add(time.Minute)
+	_, err = cache.GetTenant(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 2, q.lookups)
+
+	// Unknown tenants are not cached
+	unknown := tenantUUID(t, "00000000-0000-0000-0000-000000000009")
+	_, err = cache.GetTenant(ctx, unknown)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	_, err = cache.GetTenant(ctx, unknown)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	assert.Equal(t, 4, q.lookups)
+}
+
+func TestTenantCacheInvalidationOverBus(t *testing.T) {
+	q := newTenantQuerier()
+	bus := &memoryTenantBus{}
+	local := NewTenantCache(q, time.Hour)
+	remote := NewTenantCache(q, time.Hour)
+	_, err := local.Listen(bus)
+	require.NoError(t, err)
+	_, err = remote.Listen(bus)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	id := tenantUUID(t, auditTestTenant)
+	for _, cache := range []*TenantCache{local, remote} {
+		tenant, err := cache.GetTenant(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, lifecycle.StatusActive, tenant.Status)
+	}
+
+	q.setStatus(auditTestTenant, lifecycle.StatusSuspended)
+	require.NoError(t, local.Invalidate(ctx, auditTestTenant))
+
+	// Both replicas read the tenant again
+	for _, cache := range []*TenantCache{local, remote} {
+		tenant, err := cache.GetTenant(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, lifecycle.StatusSuspended, tenant.Status)
+	}
+	assert.Equal(t, 4, q.lookups)
+}
+
+// blockingTenantQuerier returns a tenant only once released
+type blockingTenantQuerier struct {
+	*countingTenantQuerier
+	started, release chan struct{}
+}
+
+func (q *blockingTenantQuerier) GetTenant(ctx context.Context, id pgtype.UUID) (queries.Tenant, error) {
+	tenant, err := q.countingTenantQuerier.GetTenant(ctx, id)
+	q.started <- struct{}{}
+	<-q.release
+	return tenant, err
+}
+
+func TestTenantCacheDropsLookupsRacingInvalidation(t *testing.T) {
+	q := &blockingTenantQuerier{countingTenantQuerier: newTenantQuerier(), started: make(chan struct{}), release: make(chan struct{})}
+	cache := NewTenantCache(q, time.Hour)
+	ctx := context.Background()
+	id := tenantUUID(t, auditTestTenant)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = cache.GetTenant(ctx, id)
+	}()
+	<-q.started
+	require.NoError(t, cache.Invalidate(ctx, auditTestTenant))
+	close(q.release)
+	<-done
+
+	// The lookup read the tenant before the change, so it was not cached
+	go func() { <-q.started }()
+	_, err := cache.GetTenant(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 2, q.lookups)
+}
+
+func TestTenantIsolationMiddleware_LoadsTenant(t *testing.T) {
+	q := newTenantQuerier()
+	cache := NewTenantCache(q, time.Hour)
+	middleware := NewTenantIsolationMiddleware(logging.NewLogger(), cache)
+
+	var tenant *TenantContext
+	handler := middleware.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, _ = GetTenantContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(method, tenantID string) int {
+		req := httptest.NewRequest(method, "/api/v1/workflows", nil)
+		claims := &AgentFlowClaims{TenantID: tenantID, UserID: "user456"}
+		req = req.WithContext(context.WithValue(req.Context(), "auth_claims", claims))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, serve("POST", auditTestTenant))
+	require.NotNil(t, tenant)
+	assert.Equal(t, "acme", tenant.TenantName)
+	assert.Equal(t, "standard", tenant.Tier)
+	assert.Equal(t, 5, tenant.Limits.MaxAgents)
+	assert.Equal(t, 250, tenant.Limits.MaxWorkflows)
+
+	// A suspension applies once the tenant is invalidated
+	q.setStatus(auditTestTenant, lifecycle.StatusSuspended)
+	assert.Equal(t, http.StatusOK, serve("POST", auditTestTenant))
+	require.NoError(t, cache.Invalidate(context.Background(), auditTestTenant))
+	assert.Equal(t, http.StatusForbidden, serve("POST", auditTestTenant))
+	assert.Equal(t, http.StatusOK, serve("GET", auditTestTenant))
+
+	assert.Equal(t, http.StatusForbidden, serve("GET", "00000000-0000-0000-0000-000000000009"))
+	assert.Equal(t, http.StatusForbidden, serve("GET", "not-a-uuid"))
+	// IDs that are not UUIDs are rejected without a lookup
+	assert.Equal(t, 3, q.lookups)
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/agentflow/agentflow/internal/logging"
+	lifecycle "github.com/agentflow/agentflow/internal/storage/tenant"
 )
 
 func TestTenantContext_ContextHelpers(t *testing.T) {
@@ -91,7 +92,7 @@ func TestTenantIsolationMiddleware_PublicEndpoints(t *testing.T) {
 		"/api/v1/health",
 		"/",
 		"/api",
-		"/api/v1/auth/token",
+		"/api/v1/auth/refresh",
 	}
 
 	for _, endpoint := range publicEndpoints {
@@ -101,6 +102,7 @@ func TestTenantIsolationMiddleware_PublicEndpoints(t *testing.T) {
 	}
 
 	privateEndpoints := []string{
+		"/api/v1/auth/token",
 		"/api/v1/workflows",
 		"/api/v1/agents",
 		"/api/v1/tools",
@@ -236,3 +238,34 @@ func TestTenantIsolationMiddleware_WriteError(t *testing.T) {
 		t.Errorf("Expected error message 'Test error message', got '%s'", errorObj["message"])
 	}
 }
+
+func TestTenantStatusError(t *testing.T) {
+	tests := []struct {
+		status string
+		method string
+		code   string
+	}{
+		{"suspended", "POST", "tenant_suspended"},
+		{"pending_deletion", "DELETE", "tenant_pending_deletion"},
+		{"erased", "GET", "invalid_tenant"},
+	}
+
+	for _, tt := range tests {
+		err := lifecycle.CheckAccess(tt.status, tt.method)
+		if err == nil {
+			t.Fatalf("Expected %s %s to be rejected", tt.status, tt.method)
+		}
+
+		code, message := tenantStatusError(err)
+		if code != tt.code {
+			t.Errorf("Expected code '%s' for status '%s', got '%s'", tt.code, tt.status, code)
+		}
+		if message == "" {
+			t.Errorf("Expected a message for status '%s'", tt.status)
+		}
+	}
+
+	if err := lifecycle.CheckAccess("suspended", "GET"); err != nil {
+		t.Errorf("Expected reads to be allowed for a suspended tenant, got: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+)
+
+// SetAuditRecorder sets the recorder mutating API calls are audited with
+func (ms *MiddlewareStack) SetAuditRecorder(recorder audit.Recorder) {
+	ms.auditRecorder = recorder
+}
+
+// AuditMiddleware records every authenticated mutating request with its
+// actor, resource and outcome. It must run inside the authentication
+// middleware. Auth endpoints are skipped because their handlers record their
+// own events.
+func (ms *MiddlewareStack) AuditMiddleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := ms.auditRecorder
+			claims := security.GetClaimsFromContext(r.Context())
+			if recorder == nil || claims == nil || !isMutatingMethod(r.Method) ||
+				strings.HasPrefix(r.URL.Path, "/api/v1/auth/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			resourceType, resourceID := resourceFromPath(r.URL.Path)
+			event := audit.Event{
+				TenantID:     claims.TenantID,
+				ActorType:    claims.ActorType(),
+				ActorID:      claims.UserID,
+				Action:       "api." + strings.ToLower(r.Method),
+				ResourceType: resourceType,
+				ResourceID:   resourceID,
+				Outcome:      statusOutcome(rw.statusCode),
+				Details: map[string]interface{}{
+					"method":         r.Method,
+					"path":           r.URL.Path,
+					"status_code":    rw.statusCode,
+					"duration_ms":    time.Since(start).Milliseconds(),
+					"correlation_id": rw.Header().Get("X-Correlation-ID"),
+					"remote_addr":    r.RemoteAddr,
+				},
+			}
+
+			// Failed writes are logged by the recorder; the response is already sent
+			_ = recorder.Record(r.Context(), event)
+		})
+	}
+}
+
+// isMutatingMethod reports whether requests with method change state
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// resourceFromPath takes the resource type and ID from an API path such as
+// /api/v1/workflows/{id}
+func resourceFromPath(path string) (string, string) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(path, "/api/v1"), "/"), "/")
+	if parts[0] == "" {
+		return "api", ""
+	}
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// statusOutcome maps a response status to an audit outcome
+func statusOutcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return audit.OutcomeDenied
+	case status >= 400:
+		return audit.OutcomeFailure
+	default:
+		return audit.OutcomeSuccess
+	}
+}
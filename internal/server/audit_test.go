@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	recorder := audit.NewMemoryRecorder()
+	stack := NewMiddlewareStack(logging.NewLogger())
+	stack.SetAuditRecorder(recorder)
+
+	handler := stack.AuditMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	claims := &security.AgentFlowClaims{TenantID: "tenant123", UserID: "user456"}
+
+	serve := func(method, path string, claims *security.AgentFlowClaims) int {
+		req := httptest.NewRequest(method, path, nil)
+		if claims != nil {
+			req = req.WithContext(context.WithValue(req.Context(), "auth_claims", claims))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, serve("POST", "/api/v1/workflows", claims))
+	serve("DELETE", "/api/v1/workflows/wf-1", claims)
+	require.Len(t, recorder.Events(), 2)
+
+	created := recorder.Events()[0]
+	assert.Equal(t, "tenant123", created.TenantID)
+	assert.Equal(t, "user456", created.ActorID)
+	assert.Equal(t, "api.post", created.Action)
+	assert.Equal(t, "workflows", created.ResourceType)
+	assert.Equal(t, audit.OutcomeSuccess, created.Outcome)
+	assert.Equal(t, http.StatusCreated, created.Details["status_code"])
+	assert.False(t, created.Critical)
+
+	deleted := recorder.Events()[1]
+	assert.Equal(t, "wf-1", deleted.ResourceID)
+	assert.Equal(t, audit.OutcomeDenied, deleted.Outcome)
+
+	// Reads, anonymous calls and auth endpoints are not recorded here
+	serve("GET", "/api/v1/workflows", claims)
+	serve("POST", "/api/v1/workflows", nil)
+	serve("POST", "/api/v1/auth/revoke", claims)
+	assert.Len(t, recorder.Events(), 2)
+}
+
+func TestResourceFromPath(t *testing.T) {
+	tests := []struct {
+		path         string
+		resourceType string
+		resourceID   string
+	}{
+		{"/api/v1/workflows", "workflows", ""},
+		{"/api/v1/agents/agent-1", "agents", "agent-1"},
+		{"/api/v1/tools/tool-1/versions", "tools", "tool-1"},
+		{"/api/v1", "api", ""},
+		{"/", "api", ""},
+	}
+	for _, tt := range tests {
+		resourceType, resourceID := resourceFromPath(tt.path)
+		assert.Equal(t, tt.resourceType, resourceType, tt.path)
+		assert.Equal(t, tt.resourceID, resourceID, tt.path)
+	}
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+)
+
+// Audit listing page sizes
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+)
+
+// SetAuditReader serves the caller's audit records under /api/v1/audits from
+// reader. It must be called before the server starts.
+func (s *Server) SetAuditReader(reader audit.SearchQuerier) {
+	s.auditReader = reader
+}
+
+// handleListAudits handles GET /api/v1/audits
+func (s *Server) handleListAudits(w http.ResponseWriter, r *http.Request) {
+	if s.auditReader == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "audit_unavailable", "Audit storage is not configured")
+		return
+	}
+	filter, err := auditFilterFromRequest(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_filter", err.Error())
+		return
+	}
+	limit := defaultAuditPageSize
+	if val := r.URL.Query().Get("limit"); val != "" {
+		if limit, err = strconv.Atoi(val); err != nil || limit < 1 || limit > maxAuditPageSize {
+			s.writeError(w, http.StatusBadRequest, "invalid_filter", fmt.Sprintf("limit must be between 1 and %d", maxAuditPageSize))
+			return
+		}
+	}
+
+	records, next, err := audit.Search(r.Context(), s.auditReader, filter, limit)
+	if err != nil {
+		GetLoggerFromContext(r.Context()).Error("Failed to list audit records", err)
+		s.writeError(w, http.StatusInternalServerError, "audit_query_failed", "Failed to list audit records")
+		return
+	}
+
+	response := map[string]interface{}{
+		"records": records,
+	}
+	if next > 0 {
+		response["next_after_seq"] = next
+	}
+	s.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// handleExportAudits handles GET /api/v1/audits/export, streaming every
+// matching record as JSONL, CSV or CEF
+func (s *Server) handleExportAudits(w http.ResponseWriter, r *http.Request) {
+	if s.auditReader == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "audit_unavailable", "Audit storage is not configured")
+		return
+	}
+	filter, err := auditFilterFromRequest(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_filter", err.Error())
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = audit.FormatJSONL
+	}
+	writer, err := audit.NewExportWriter(format, w)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_format", err.Error())
+		return
+	}
+
+	claims := security.GetClaimsFromContext(r.Context())
+	w.Header().Set("Content-Type", audit.ExportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audits-%s.%s"`, claims.TenantID, format))
+
+	// The status is sent with the first record, so a failure part way
+	// through can only end the stream early
+	written, err := audit.Export(r.Context(), s.auditReader, filter, 0, writer)
+	outcome := audit.OutcomeSuccess
+	if err != nil {
+		outcome = audit.OutcomeFailure
+		GetLoggerFromContext(r.Context()).Error("Audit export ended early", err,
+			logging.Int("records_written", written))
+	}
+
+	// Exports leave the system, so they are recorded like any other change
+	if s.auditRecorder != nil {
+		_ = s.auditRecorder.Record(r.Context(), audit.Event{
+			TenantID:     claims.TenantID,
+			ActorType:    "user",
+			ActorID:      claims.UserID,
+			Action:       "audit.export",
+			ResourceType: "audits",
+			Outcome:      outcome,
+			Details: map[string]interface{}{
+				"format":  format,
+				"filter":  r.URL.Query().Encode(),
+				"records": written,
+			},
+		})
+	}
+}
+
+// auditFilterFromRequest reads the filter query parameters. Records are
+// always scoped to the caller's tenant.
+func auditFilterFromRequest(r *http.Request) (audit.Filter, error) {
+	var filter audit.Filter
+	claims := security.GetClaimsFromContext(r.Context())
+	if claims == nil {
+		return filter, fmt.Errorf("authentication required")
+	}
+	if err := filter.TenantID.Scan(claims.TenantID); err != nil {
+		return filter, fmt.Errorf("tenant ID %q is not a UUID", claims.TenantID)
+	}
+
+	query := r.URL.Query()
+	var err error
+	if filter.Since, err = parseFilterTime(query, "since"); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = parseFilterTime(query, "until"); err != nil {
+		return filter, err
+	}
+	if val := query.Get("after_seq"); val != "" {
+		if filter.AfterSeq, err = strconv.ParseInt(val, 10, 64); err != nil || filter.AfterSeq < 0 {
+			return filter, fmt.Errorf("invalid after_seq: %s", val)
+		}
+	}
+	filter.ActorType = query.Get("actor_type")
+	filter.ActorID = query.Get("actor_id")
+	filter.Action = query.Get("action")
+	filter.ResourceType = query.Get("resource_type")
+	filter.ResourceID = query.Get("resource_id")
+	return filter, nil
+}
+
+// parseFilterTime parses an RFC 3339 query parameter, or returns the zero time
+func parseFilterTime(query url.Values, name string) (time.Time, error) {
+	val := query.Get(name)
+	if val == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: want an RFC 3339 time", name)
+	}
+	return t, nil
+}
+
+func (s *Server) writeError(w http.ResponseWriter, statusCode int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const auditsTestTenant = "00000000-0000-0000-0000-000000000001"
+
+// searchQuerier returns stored records after the cursor and remembers the
+// last query
+type searchQuerier struct {
+	audits []queries.Audit
+	last   queries.SearchAuditsParams
+}
+
+func (q *searchQuerier) SearchAudits(ctx context.Context, arg queries.SearchAuditsParams) ([]queries.Audit, error) {
+	q.last = arg
+	var result []queries.Audit
+	for _, a := range q.audits {
+		if a.TenantID == arg.TenantID && a.Seq > arg.AfterSeq && len(result) < int(arg.MaxResults) {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+func newAuditsTestServer(t *testing.T, n int) (*Server, *searchQuerier, *audit.MemoryRecorder) {
+	t.Helper()
+	var tenantID pgtype.UUID
+	require.NoError(t, tenantID.Scan(auditsTestTenant))
+
+	q := &searchQuerier{}
+	for i := 1; i <= n; i++ {
+		q.audits = append(q.audits, queries.Audit{
+			ID:           pgtype.UUID{Bytes: [16]byte{byte(i)}, Valid: true},
+			TenantID:     tenantID,
+			Seq:          int64(i),
+			ActorType:    "user",
+			ActorID:      "user456",
+			Action:       "api.post",
+			ResourceType: "workflows",
+			Details:      []byte(`{"outcome":"success"}`),
+			Ts:           pgtype.Timestamptz{Time: time.Date(2025, 8, 1, 0, 0, i, 0, time.UTC), Valid: true},
+			Hash:         []byte{byte(i)},
+		})
+	}
+
+	srv, err := New(DefaultConfig(), logging.NewLogger())
+	require.NoError(t, err)
+	recorder := audit.NewMemoryRecorder()
+	srv.SetAuditRecorder(recorder)
+	srv.SetAuditReader(q)
+	return srv, q, recorder
+}
+
+func auditsRequest(path string, permissions ...string) *http.Request {
+	req := httptest.NewRequest("GET", path, nil)
+	claims := &security.AgentFlowClaims{TenantID: auditsTestTenant, UserID: "auditor", Permissions: permissions}
+	return req.WithContext(context.WithValue(req.Context(), "auth_claims", claims))
+}
+
+func TestHandleListAudits(t *testing.T) {
+	srv, q, _ := newAuditsTestServer(t, 5)
+
+	w := httptest.NewRecorder()
+	srv.handleListAudits(w, auditsRequest("/api/v1/audits?limit=2&actor_id=user456&since=2025-08-01T00:00:00Z"))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response struct {
+		Data struct {
+			Records      []audit.ExportRecord `json:"records"`
+			NextAfterSeq int64                `json:"next_after_seq"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Data.Records, 2)
+	assert.Equal(t, int64(2), response.Data.NextAfterSeq)
+	assert.Equal(t, "user456", q.last.ActorID)
+	assert.True(t, q.last.Since.Valid)
+	assert.Equal(t, auditsTestTenant, response.Data.Records[0].TenantID)
+	assert.NotEmpty(t, response.Data.Records[0].Hash)
+
+	for _, query := range []string{"limit=0", "since=yesterday", "after_seq=-1"} {
+		w := httptest.NewRecorder()
+		srv.handleListAudits(w, auditsRequest("/api/v1/audits?"+query))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestHandleExportAudits(t *testing.T) {
+	srv, _, recorder := newAuditsTestServer(t, 3)
+
+	w := httptest.NewRecorder()
+	srv.handleExportAudits(w, auditsRequest("/api/v1/audits/export?format=csv"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Len(t, strings.Split(strings.TrimSpace(w.Body.String()), "\n"), 4)
+
+	require.Len(t, recorder.Events(), 1)
+	assert.Equal(t, "audit.export", recorder.Events()[0].Action)
+	assert.Equal(t, 3, recorder.Events()[0].Details["records"])
+
+	w = httptest.NewRecorder()
+	srv.handleExportAudits(w, auditsRequest("/api/v1/audits/export?format=xml"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAuditRoutesRequirePermission(t *testing.T) {
+	srv, _, _ := newAuditsTestServer(t, 1)
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, auditsRequest("/api/v1/audits"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, auditsRequest("/api/v1/audits/export", "audits:read"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+}
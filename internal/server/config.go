@@ -9,35 +9,51 @@ import (
 
 // Config holds HTTP server configuration
 type Config struct {
-	Port            int           `env:"AF_API_PORT"`
-	ReadTimeout     time.Duration `env:"AF_API_READ_TIMEOUT"`
-	WriteTimeout    time.Duration `env:"AF_API_WRITE_TIMEOUT"`
-	IdleTimeout     time.Duration `env:"AF_API_IDLE_TIMEOUT"`
-	MaxHeaderBytes  int           `env:"AF_API_MAX_HEADER_BYTES"`
-	EnableTLS       bool          `env:"AF_API_TLS_ENABLED"`
-	TLSCertPath     string        `env:"AF_API_TLS_CERT_PATH"`
-	TLSKeyPath      string        `env:"AF_API_TLS_KEY_PATH"`
-	ShutdownTimeout time.Duration `env:"AF_API_SHUTDOWN_TIMEOUT"`
-	EnableTracing   bool          `env:"AF_TRACING_ENABLED"`
-	TracingEndpoint string        `env:"AF_OTEL_EXPORTER_OTLP_ENDPOINT"`
-	ServiceName     string        `env:"AF_SERVICE_NAME"`
+	Port           int           `env:"AF_API_PORT"`
+	ReadTimeout    time.Duration `env:"AF_API_READ_TIMEOUT"`
+	WriteTimeout   time.Duration `env:"AF_API_WRITE_TIMEOUT"`
+	IdleTimeout    time.Duration `env:"AF_API_IDLE_TIMEOUT"`
+	MaxHeaderBytes int           `env:"AF_API_MAX_HEADER_BYTES"`
+	EnableTLS      bool          `env:"AF_API_TLS_ENABLED"`
+	TLSCertPath    string        `env:"AF_API_TLS_CERT_PATH"`
+	TLSKeyPath     string        `env:"AF_API_TLS_KEY_PATH"`
+	// TLSClientCAPath is the CA bundle client certificates are verified
+	// against, and TLSClientAuth is off, optional or require
+	TLSClientCAPath   string        `env:"AF_API_TLS_CLIENT_CA_PATH"`
+	TLSClientAuth     string        `env:"AF_API_TLS_CLIENT_AUTH"`
+	TLSReloadInterval time.Duration `env:"AF_API_TLS_RELOAD_INTERVAL"`
+	ShutdownTimeout   time.Duration `env:"AF_API_SHUTDOWN_TIMEOUT"`
+	EnableTracing     bool          `env:"AF_TRACING_ENABLED"`
+	TracingEndpoint   string        `env:"AF_OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName       string        `env:"AF_SERVICE_NAME"`
+	DatabaseURL       string        `env:"AF_DATABASE_URL"`
+	SchemaCheck       string        `env:"AF_SCHEMA_CHECK"` // off, warn or enforce
+	BusURL            string        `env:"AF_BUS_URL"`
+	RevocationRedis   string        `env:"AF_REVOCATION_REDIS_URL"`
 }
 
 // DefaultConfig returns default server configuration
 func DefaultConfig() *Config {
 	return &Config{
-		Port:            8080,
-		ReadTimeout:     30 * time.Second,
-		WriteTimeout:    30 * time.Second,
-		IdleTimeout:     120 * time.Second,
-		MaxHeaderBytes:  1048576, // 1MB
-		EnableTLS:       false,
-		TLSCertPath:     "",
-		TLSKeyPath:      "",
-		ShutdownTimeout: 30 * time.Second,
-		EnableTracing:   true,
-		TracingEndpoint: "http://localhost:4318",
-		ServiceName:     "agentflow-control-plane",
+		Port:              8080,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    1048576, // 1MB
+		EnableTLS:         false,
+		TLSCertPath:       "",
+		TLSKeyPath:        "",
+		TLSClientCAPath:   "",
+		TLSClientAuth:     "off",
+		TLSReloadInterval: time.Minute,
+		ShutdownTimeout:   30 * time.Second,
+		EnableTracing:     true,
+		TracingEndpoint:   "http://localhost:4318",
+		ServiceName:       "agentflow-control-plane",
+		DatabaseURL:       "",
+		SchemaCheck:       "warn",
+		BusURL:            "",
+		RevocationRedis:   "",
 	}
 }
 
@@ -87,6 +103,20 @@ func LoadFromEnv() *Config {
 		config.TLSKeyPath = val
 	}
 
+	if val := os.Getenv("AF_API_TLS_CLIENT_CA_PATH"); val != "" {
+		config.TLSClientCAPath = val
+	}
+
+	if val := os.Getenv("AF_API_TLS_CLIENT_AUTH"); val != "" {
+		config.TLSClientAuth = val
+	}
+
+	if val := os.Getenv("AF_API_TLS_RELOAD_INTERVAL"); val != "" {
+		if interval, err := time.ParseDuration(val); err == nil {
+			config.TLSReloadInterval = interval
+		}
+	}
+
 	if val := os.Getenv("AF_API_SHUTDOWN_TIMEOUT"); val != "" {
 		if timeout, err := time.ParseDuration(val); err == nil {
 			config.ShutdownTimeout = timeout
@@ -105,5 +135,21 @@ func LoadFromEnv() *Config {
 		config.ServiceName = val
 	}
 
+	if val := os.Getenv("AF_DATABASE_URL"); val != "" {
+		config.DatabaseURL = val
+	}
+
+	if val := os.Getenv("AF_SCHEMA_CHECK"); val != "" {
+		config.SchemaCheck = val
+	}
+
+	if val := os.Getenv("AF_BUS_URL"); val != "" {
+		config.BusURL = val
+	}
+
+	if val := os.Getenv("AF_REVOCATION_REDIS_URL"); val != "" {
+		config.RevocationRedis = val
+	}
+
 	return config
 }
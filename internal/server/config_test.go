@@ -23,6 +23,8 @@ func TestDefaultConfig(t *testing.T) {
 	assert.True(t, config.EnableTracing)
 	assert.Equal(t, "http://localhost:4318", config.TracingEndpoint)
 	assert.Equal(t, "agentflow-control-plane", config.ServiceName)
+	assert.Equal(t, "", config.DatabaseURL)
+	assert.Equal(t, "warn", config.SchemaCheck)
 }
 
 func TestLoadFromEnv(t *testing.T) {
@@ -37,10 +39,15 @@ func TestLoadFromEnv(t *testing.T) {
 		"AF_API_TLS_ENABLED",
 		"AF_API_TLS_CERT_PATH",
 		"AF_API_TLS_KEY_PATH",
+		"AF_API_TLS_CLIENT_CA_PATH",
+		"AF_API_TLS_CLIENT_AUTH",
+		"AF_API_TLS_RELOAD_INTERVAL",
 		"AF_API_SHUTDOWN_TIMEOUT",
 		"AF_TRACING_ENABLED",
 		"AF_OTEL_EXPORTER_OTLP_ENDPOINT",
 		"AF_SERVICE_NAME",
+		"AF_DATABASE_URL",
+		"AF_SCHEMA_CHECK",
 	}
 
 	for _, envVar := range envVars {
@@ -67,10 +74,15 @@ func TestLoadFromEnv(t *testing.T) {
 	os.Setenv("AF_API_TLS_ENABLED", "true")
 	os.Setenv("AF_API_TLS_CERT_PATH", "/path/to/cert.pem")
 	os.Setenv("AF_API_TLS_KEY_PATH", "/path/to/key.pem")
+	os.Setenv("AF_API_TLS_CLIENT_CA_PATH", "/path/to/ca.pem")
+	os.Setenv("AF_API_TLS_CLIENT_AUTH", "require")
+	os.Setenv("AF_API_TLS_RELOAD_INTERVAL", "5m")
 	os.Setenv("AF_API_SHUTDOWN_TIMEOUT", "45s")
 	os.Setenv("AF_TRACING_ENABLED", "false")
 	os.Setenv("AF_OTEL_EXPORTER_OTLP_ENDPOINT", "http://jaeger:4318")
 	os.Setenv("AF_SERVICE_NAME", "test-service")
+	os.Setenv("AF_DATABASE_URL", "postgres://db:5432/agentflow")
+	os.Setenv("AF_SCHEMA_CHECK", "enforce")
 
 	config := LoadFromEnv()
 
@@ -82,10 +94,15 @@ func TestLoadFromEnv(t *testing.T) {
 	assert.True(t, config.EnableTLS)
 	assert.Equal(t, "/path/to/cert.pem", config.TLSCertPath)
 	assert.Equal(t, "/path/to/key.pem", config.TLSKeyPath)
+	assert.Equal(t, "/path/to/ca.pem", config.TLSClientCAPath)
+	assert.Equal(t, ClientAuthRequire, config.TLSClientAuth)
+	assert.Equal(t, 5*time.Minute, config.TLSReloadInterval)
 	assert.Equal(t, 45*time.Second, config.ShutdownTimeout)
 	assert.False(t, config.EnableTracing)
 	assert.Equal(t, "http://jaeger:4318", config.TracingEndpoint)
 	assert.Equal(t, "test-service", config.ServiceName)
+	assert.Equal(t, "postgres://db:5432/agentflow", config.DatabaseURL)
+	assert.Equal(t, "enforce", config.SchemaCheck)
 }
 
 func TestLoadFromEnvWithInvalidValues(t *testing.T) {
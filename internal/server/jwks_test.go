@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/security/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleJWKS(t *testing.T) {
+	srv, err := New(DefaultConfig(), logging.NewLogger())
+	require.NoError(t, err)
+
+	fetch := func() security.JSONWebKeySet {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Cache-Control"))
+		var set security.JSONWebKeySet
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &set), "the set is served without the API envelope")
+		return set
+	}
+
+	// Tokens signed with a shared secret have no public keys
+	assert.Empty(t, fetch().Keys)
+
+	ctx := context.Background()
+	provider := secrets.NewFileProvider(filepath.Join(t.TempDir(), "secrets.json"))
+	key, err := security.RotateSigningKeys(ctx, provider, security.DefaultSigningKeysSecret, security.SigningAlgEdDSA, time.Hour)
+	require.NoError(t, err)
+	ring, err := security.NewSigningKeyRing(ctx, provider, security.DefaultSigningKeysSecret)
+	require.NoError(t, err)
+	srv.SetSigningKeys(ring)
+
+	set := fetch()
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, key.ID, set.Keys[0].Kid)
+	assert.Equal(t, "EdDSA", set.Keys[0].Alg)
+	assert.Empty(t, set.Keys[0].Y)
+}
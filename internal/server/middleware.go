@@ -8,6 +8,9 @@ import (
 	"time"
 
 	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/quota"
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/storage/audit"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -23,6 +26,9 @@ type MiddlewareStack struct {
 	tracer            trace.Tracer
 	tracingMiddleware interface{} // Will hold *messaging.TracingMiddleware
 	authMiddleware    interface{} // Will hold *security.AuthMiddleware
+	auditRecorder     audit.Recorder
+	quotas            *quota.Enforcer
+	tenantIsolation   *security.TenantIsolationMiddleware
 }
 
 // NewMiddlewareStack creates a new middleware stack
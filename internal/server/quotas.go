@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agentflow/agentflow/internal/quota"
+	"github.com/agentflow/agentflow/internal/security"
+)
+
+// SetQuotas sets the enforcer request rates are limited with
+func (ms *MiddlewareStack) SetQuotas(enforcer *quota.Enforcer) {
+	ms.quotas = enforcer
+}
+
+// SetQuotas limits each tenant's request rate with enforcer and serves its
+// usage at /api/v1/usage. Rates are limited by the tenant limits loaded by
+// SetTenants. It must be called before the server starts.
+func (s *Server) SetQuotas(enforcer *quota.Enforcer) {
+	s.quotas = enforcer
+	s.middleware.SetQuotas(enforcer)
+}
+
+// QuotaMiddleware counts every tenant request against the
+// requests_per_minute limit the tenant middleware loaded for it and answers
+// 429 once it is reached. It must run inside the tenant middleware; requests
+// without a tenant context are not limited. Auth endpoints are skipped so a
+// limited tenant can still log in and refresh.
+func (ms *MiddlewareStack) QuotaMiddleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			enforcer := ms.quotas
+			if enforcer == nil || strings.HasPrefix(r.URL.Path, "/api/v1/auth/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			tenant, err := security.GetTenantContext(r.Context())
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := enforcer.AllowRequest(tenant.TenantID, tenant.Limits); err != nil {
+				var quotaErr *quota.QuotaError
+				if errors.As(err, &quotaErr) {
+					writeQuotaError(w, quotaErr)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// handleGetUsage handles GET /api/v1/usage, returning the limits of the
+// caller's tenant and what it uses of each
+func (s *Server) handleGetUsage(w http.ResponseWriter, r *http.Request) {
+	if s.quotas == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "quotas_unavailable", "Resource limits are not configured")
+		return
+	}
+	claims := security.GetClaimsFromContext(r.Context())
+	if claims == nil {
+		s.writeError(w, http.StatusUnauthorized, "unauthenticated", "Authentication required")
+		return
+	}
+	usage, err := s.quotas.Usage(r.Context(), claims.TenantID)
+	if errors.Is(err, quota.ErrTenantNotFound) {
+		s.writeError(w, http.StatusNotFound, "tenant_not_found", "Tenant not found")
+		return
+	}
+	if err != nil {
+		GetLoggerFromContext(r.Context()).Error("Failed to read tenant usage", err)
+		s.writeError(w, http.StatusInternalServerError, "usage_query_failed", "Failed to read tenant usage")
+		return
+	}
+	s.writeJSONResponse(w, http.StatusOK, usage)
+}
+
+// writeQuotaError answers 429 with the limit that was reached. Rate limits
+// also say when to retry.
+func writeQuotaError(w http.ResponseWriter, err *quota.QuotaError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(err.Limit))
+	if err.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	}
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error": map[string]interface{}{
+			"code":     "quota_exceeded",
+			"message":  err.Error(),
+			"resource": err.Resource,
+			"limit":    err.Limit,
+			"used":     err.Used,
+		},
+	})
+}
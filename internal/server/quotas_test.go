@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/quota"
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	lifecycle "github.com/agentflow/agentflow/internal/storage/tenant"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tenantQuerier serves a single tenant of tier with settings
+type tenantQuerier struct {
+	tier     string
+	settings string
+}
+
+func (q *tenantQuerier) GetTenant(ctx context.Context, id pgtype.UUID) (queries.Tenant, error) {
+	return queries.Tenant{ID: id, Tier: q.tier, Status: lifecycle.StatusActive, Settings: []byte(q.settings)}, nil
+}
+
+func (q *tenantQuerier) CountAgentsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return 3, nil
+}
+
+func (q *tenantQuerier) CountWorkflowsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return 5, nil
+}
+
+func TestQuotaMiddleware(t *testing.T) {
+	stack := NewMiddlewareStack(logging.NewLogger())
+	stack.SetQuotas(quota.NewEnforcer(&tenantQuerier{tier: quota.TierFree}))
+	handler := stack.QuotaMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	limits, err := quota.LimitsFor(quota.TierFree, map[string]interface{}{"requests_per_minute": 2.0})
+	require.NoError(t, err)
+	tenant := &security.TenantContext{TenantID: auditsTestTenant, Limits: limits}
+
+	serve := func(path string, tenant *security.TenantContext) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if tenant != nil {
+			req = req.WithContext(security.WithTenantContext(req.Context(), tenant))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/api/v1/workflows", tenant).Code)
+	assert.Equal(t, http.StatusOK, serve("/api/v1/workflows", tenant).Code)
+
+	w := serve("/api/v1/workflows", tenant)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	var body struct {
+		Error struct {
+			Code     string `json:"code"`
+			Resource string `json:"resource"`
+			Limit    int    `json:"limit"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, "quota_exceeded", body.Error.Code)
+	assert.Equal(t, quota.ResourceRequestsPerMinute, body.Error.Resource)
+	assert.Equal(t, 2, body.Error.Limit)
+
+	// Auth endpoints and requests without a tenant context are not limited
+	assert.Equal(t, http.StatusOK, serve("/api/v1/auth/refresh", tenant).Code)
+	assert.Equal(t, http.StatusOK, serve("/api/v1/workflows", nil).Code)
+}
+
+func TestServerLimitsRequestsByTenantSettings(t *testing.T) {
+	srv, err := New(DefaultConfig(), logging.NewLogger())
+	require.NoError(t, err)
+	tenants := &tenantQuerier{tier: quota.TierFree, settings: `{"resource_limits":{"requests_per_minute":1}}`}
+	srv.SetTenants(tenants)
+	srv.SetQuotas(quota.NewEnforcer(tenants))
+
+	token, err := srv.authenticator.IssueToken(context.Background(), &security.TokenRequest{
+		TenantID:    auditsTestTenant,
+		UserID:      "user456",
+		Permissions: []string{"workflows:*"},
+	})
+	require.NoError(t, err)
+	serve := func() int {
+		req := httptest.NewRequest("GET", "/api/v1/workflows", nil)
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		w := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNotImplemented, serve())
+	assert.Equal(t, http.StatusTooManyRequests, serve())
+}
+
+func TestHandleGetUsage(t *testing.T) {
+	srv, err := New(DefaultConfig(), logging.NewLogger())
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	srv.handleGetUsage(w, auditsRequest("/api/v1/usage"))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	srv.SetQuotas(quota.NewEnforcer(&tenantQuerier{tier: quota.TierStandard}))
+	w = httptest.NewRecorder()
+	srv.handleGetUsage(w, auditsRequest("/api/v1/usage"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data quota.Usage `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	usage := body.Data
+	assert.Equal(t, auditsTestTenant, usage.TenantID)
+	assert.Equal(t, quota.TierStandard, usage.Tier)
+	assert.Equal(t, quota.TierLimits(quota.TierStandard), usage.Limits)
+	assert.Equal(t, 3, usage.Used[quota.ResourceAgents])
+	assert.Equal(t, 5, usage.Used[quota.ResourceWorkflows])
+}
+
+func TestUsageRouteRequiresPermission(t *testing.T) {
+	srv, err := New(DefaultConfig(), logging.NewLogger())
+	require.NoError(t, err)
+	srv.SetQuotas(quota.NewEnforcer(&tenantQuerier{tier: quota.TierFree}))
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, auditsRequest("/api/v1/usage"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, auditsRequest("/api/v1/usage", "usage:read"))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,7 +12,9 @@ import (
 	"time"
 
 	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/quota"
 	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/storage/audit"
 	"github.com/agentflow/agentflow/pkg/messaging"
 	"github.com/gorilla/mux"
 )
@@ -27,6 +30,11 @@ type Server struct {
 	authenticator  security.Authenticator
 	authMiddleware *security.AuthMiddleware
 	authHandlers   *security.AuthHandlers
+	auditRecorder  audit.Recorder
+	auditReader    audit.SearchQuerier
+	signingKeys    *security.SigningKeyRing
+	apiKeys        *security.APIKeyService
+	quotas         *quota.Enforcer
 }
 
 // New creates a new HTTP server instance
@@ -59,6 +67,13 @@ func New(config *Config, logger logging.Logger) (*Server, error) {
 	}
 
 	authMiddleware := security.NewAuthMiddleware(authenticator, logger, authConfig)
+	clientAuth, err := clientAuthType(config.TLSClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	if config.EnableTLS && clientAuth != tls.NoClientCert {
+		authMiddleware.WithClientCertificates(nil)
+	}
 	authHandlers := security.NewAuthHandlers(authenticator, logger)
 
 	// Create middleware stack
@@ -116,10 +131,70 @@ func (s *Server) setupMiddleware() {
 	// 4. Authentication middleware (validates JWT tokens)
 	s.middleware.Use(s.authMiddleware.Middleware())
 
-	// 5. CORS middleware (handles cross-origin requests)
+	// 5. Tenant middleware (enforces tenant status and isolation)
+	s.middleware.Use(s.middleware.TenantMiddleware())
+
+	// 6. Quota middleware (limits each tenant's request rate)
+	s.middleware.Use(s.middleware.QuotaMiddleware())
+
+	// 7. Audit middleware (records mutating calls of authenticated users)
+	s.middleware.Use(s.middleware.AuditMiddleware())
+
+	// 8. CORS middleware (handles cross-origin requests)
 	s.middleware.Use(s.middleware.CORSMiddleware())
 }
 
+// SetAuditRecorder records mutating API calls, auth events and access
+// denials with recorder. It must be called before the server starts.
+func (s *Server) SetAuditRecorder(recorder audit.Recorder) {
+	s.auditRecorder = recorder
+	s.middleware.SetAuditRecorder(recorder)
+	s.authHandlers.WithRecorder(recorder)
+	s.authMiddleware.WithRecorder(recorder)
+	if s.middleware.tenantIsolation != nil {
+		s.middleware.tenantIsolation.WithRecorder(recorder)
+	}
+}
+
+// SetRefreshTokenStore keeps issued refresh tokens in store, so they survive
+// restarts and work across replicas. It must be called before the server starts.
+func (s *Server) SetRefreshTokenStore(store security.RefreshTokenStore) {
+	if persister, ok := s.authenticator.(security.RefreshTokenPersister); ok {
+		persister.SetRefreshTokenStore(store)
+	}
+}
+
+// SetRevocationStore keeps token revocations in store, so they survive
+// restarts and work across replicas. It must be called before the server starts.
+func (s *Server) SetRevocationStore(store security.RevocationStore) {
+	if persister, ok := s.authenticator.(security.RevocationPersister); ok {
+		persister.SetRevocationStore(store)
+	}
+}
+
+// SetAuthorizer resolves roles and permissions through authorizer on every
+// request instead of trusting those in the token. It must be called before
+// the server starts.
+func (s *Server) SetAuthorizer(authorizer security.Authorizer) {
+	s.authMiddleware.WithAuthorizer(authorizer)
+}
+
+// SetLogin serves password logins at /api/v1/auth/login. It must be called
+// before the server starts.
+func (s *Server) SetLogin(login *security.LoginService) {
+	s.authHandlers.WithLogin(login)
+}
+
+// SetSigningKeys signs issued tokens with the active key of ring and
+// publishes its public keys at /.well-known/jwks.json. It must be called
+// before the server starts.
+func (s *Server) SetSigningKeys(ring *security.SigningKeyRing) {
+	s.signingKeys = ring
+	if persister, ok := s.authenticator.(security.SigningKeyPersister); ok {
+		persister.SetSigningKeys(ring)
+	}
+}
+
 // setupRoutes configures the API routes
 func (s *Server) setupRoutes() {
 	// API v1 routes
@@ -128,8 +203,11 @@ func (s *Server) setupRoutes() {
 	// Health check endpoint (public)
 	v1.HandleFunc("/health", s.handleHealth).Methods("GET")
 
-	// Authentication endpoints (public)
+	// Authentication endpoints. Login and refresh are public; issuing a
+	// token requires an authenticated caller.
+	v1.HandleFunc("/auth/login", s.authHandlers.HandleLogin).Methods("POST")
 	v1.HandleFunc("/auth/token", s.authHandlers.HandleTokenIssue).Methods("POST")
+	v1.HandleFunc("/auth/refresh", s.authHandlers.HandleTokenRefresh).Methods("POST")
 	v1.HandleFunc("/auth/validate", s.authHandlers.HandleTokenValidate).Methods("POST")
 	v1.HandleFunc("/auth/revoke", s.authHandlers.HandleTokenRevoke).Methods("POST")
 	v1.HandleFunc("/auth/userinfo", s.authHandlers.HandleUserInfo).Methods("GET")
@@ -142,6 +220,29 @@ func (s *Server) setupRoutes() {
 	v1.HandleFunc("/tools", s.handleTools).Methods("GET", "POST")
 	v1.HandleFunc("/tools/{id}", s.handleTool).Methods("GET", "PUT", "DELETE")
 
+	// Audit trail of the caller's tenant
+	readAudits := s.authMiddleware.RequirePermission("audits", "read")
+	v1.Handle("/audits", readAudits(http.HandlerFunc(s.handleListAudits))).Methods("GET")
+	v1.Handle("/audits/export", readAudits(http.HandlerFunc(s.handleExportAudits))).Methods("GET")
+
+	// Service accounts and their API keys, managed within the caller's tenant
+	readAccounts := s.authMiddleware.RequirePermission("service_accounts", "read")
+	writeAccounts := s.authMiddleware.RequirePermission("service_accounts", "write")
+	v1.Handle("/service-accounts", readAccounts(http.HandlerFunc(s.handleListServiceAccounts))).Methods("GET")
+	v1.Handle("/service-accounts", writeAccounts(http.HandlerFunc(s.handleCreateServiceAccount))).Methods("POST")
+	v1.Handle("/service-accounts/{id}", writeAccounts(http.HandlerFunc(s.handleDisableServiceAccount))).Methods("DELETE")
+	v1.Handle("/service-accounts/{id}/keys", readAccounts(http.HandlerFunc(s.handleListAPIKeys))).Methods("GET")
+	v1.Handle("/service-accounts/{id}/keys", writeAccounts(http.HandlerFunc(s.handleIssueAPIKey))).Methods("POST")
+	v1.Handle("/service-accounts/{id}/keys/{key_id}/rotate", writeAccounts(http.HandlerFunc(s.handleRotateAPIKey))).Methods("POST")
+	v1.Handle("/service-accounts/{id}/keys/{key_id}", writeAccounts(http.HandlerFunc(s.handleRevokeAPIKey))).Methods("DELETE")
+
+	// Resource limits of the caller's tenant and its usage of them
+	readUsage := s.authMiddleware.RequirePermission("usage", "read")
+	v1.Handle("/usage", readUsage(http.HandlerFunc(s.handleGetUsage))).Methods("GET")
+
+	// Token signing keys for offline validation (public)
+	s.router.HandleFunc("/.well-known/jwks.json", s.handleJWKS).Methods("GET")
+
 	// Root handler for API discovery (public)
 	s.router.HandleFunc("/", s.handleRoot).Methods("GET")
 	s.router.HandleFunc("/api", s.handleAPIRoot).Methods("GET")
@@ -152,6 +253,7 @@ func (s *Server) Start(ctx context.Context) error {
 	s.logger.Info("Starting AgentFlow Control Plane API server",
 		logging.Int("port", s.config.Port),
 		logging.Bool("tls_enabled", s.config.EnableTLS),
+		logging.String("tls_client_auth", s.config.TLSClientAuth),
 		logging.String("service_name", s.config.ServiceName),
 	)
 
@@ -159,11 +261,14 @@ func (s *Server) Start(ctx context.Context) error {
 	serverErr := make(chan error, 1)
 	go func() {
 		if s.config.EnableTLS {
-			if s.config.TLSCertPath == "" || s.config.TLSKeyPath == "" {
-				serverErr <- fmt.Errorf("TLS enabled but cert or key path not provided")
+			config, reloader, err := s.tlsConfig()
+			if err != nil {
+				serverErr <- err
 				return
 			}
-			serverErr <- s.httpServer.ListenAndServeTLS(s.config.TLSCertPath, s.config.TLSKeyPath)
+			defer reloader.Close()
+			s.httpServer.TLSConfig = config
+			serverErr <- s.httpServer.ListenAndServeTLS("", "")
 		} else {
 			serverErr <- s.httpServer.ListenAndServe()
 		}
@@ -234,11 +339,27 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 		"endpoints": map[string]string{
 			"health": "/health",
 			"api":    "/api/v1",
+			"jwks":   "/.well-known/jwks.json",
 		},
 	}
 	s.writeJSONResponse(w, http.StatusOK, response)
 }
 
+// handleJWKS publishes the public keys tokens are signed with. Without
+// signing keys, tokens are signed with a shared secret and the set is empty.
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	set := security.JSONWebKeySet{Keys: []security.JSONWebKey{}}
+	if s.signingKeys != nil {
+		set = s.signingKeys.JWKS()
+	}
+	// Served bare rather than in the API envelope, as JWT libraries expect
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(set); err != nil {
+		s.logger.Error("Failed to encode JWKS", err)
+	}
+}
+
 func (s *Server) handleAPIRoot(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"version": "v1",
@@ -246,11 +367,15 @@ func (s *Server) handleAPIRoot(w http.ResponseWriter, r *http.Request) {
 			"workflows": "/api/v1/workflows",
 			"agents":    "/api/v1/agents",
 			"tools":     "/api/v1/tools",
+			"audits":    "/api/v1/audits",
+			"usage":     "/api/v1/usage",
 			"health":    "/api/v1/health",
 			"auth":      "/api/v1/auth",
 		},
 		"auth_endpoints": map[string]string{
+			"login":    "/api/v1/auth/login",
 			"token":    "/api/v1/auth/token",
+			"refresh":  "/api/v1/auth/refresh",
 			"validate": "/api/v1/auth/validate",
 			"revoke":   "/api/v1/auth/revoke",
 			"userinfo": "/api/v1/auth/userinfo",
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/gorilla/mux"
+)
+
+// serviceAccountRequest is the body of POST /api/v1/service-accounts
+type serviceAccountRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// apiKeyRequest is the body of POST /api/v1/service-accounts/{id}/keys
+type apiKeyRequest struct {
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int64    `json:"expires_in,omitempty"` // seconds, optional
+}
+
+// rotateKeyRequest is the body of POST .../keys/{key_id}/rotate
+type rotateKeyRequest struct {
+	Overlap *int64 `json:"overlap,omitempty"` // seconds, defaults to a day
+}
+
+// defaultKeyRotationOverlap is how long a rotated key keeps working when the
+// request does not say
+const defaultKeyRotationOverlap = 24 * time.Hour
+
+// SetAPIKeys serves service accounts under /api/v1/service-accounts and
+// accepts their API keys as bearer tokens. It must be called before the
+// server starts.
+func (s *Server) SetAPIKeys(apiKeys *security.APIKeyService) {
+	s.apiKeys = apiKeys
+	s.authMiddleware.WithAPIKeys(apiKeys)
+}
+
+// handleCreateServiceAccount handles POST /api/v1/service-accounts
+func (s *Server) handleCreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAPIKeys(w) {
+		return
+	}
+	var req serviceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON in request body")
+		return
+	}
+	if req.Name == "" {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "name is required")
+		return
+	}
+	account, err := s.apiKeys.CreateServiceAccount(r.Context(), security.GetTenantIDFromContext(r.Context()), req.Name, req.Description)
+	if err != nil {
+		s.writeServiceAccountError(w, r, "Failed to create service account", err)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusCreated, account)
+}
+
+// handleListServiceAccounts handles GET /api/v1/service-accounts
+func (s *Server) handleListServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAPIKeys(w) {
+		return
+	}
+	accounts, err := s.apiKeys.ListServiceAccounts(r.Context(), security.GetTenantIDFromContext(r.Context()))
+	if err != nil {
+		s.writeServiceAccountError(w, r, "Failed to list service accounts", err)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusOK, map[string]interface{}{"service_accounts": accounts})
+}
+
+// handleDisableServiceAccount handles DELETE /api/v1/service-accounts/{id}
+func (s *Server) handleDisableServiceAccount(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAPIKeys(w) {
+		return
+	}
+	account, err := s.apiKeys.DisableServiceAccount(r.Context(), security.GetTenantIDFromContext(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		s.writeServiceAccountError(w, r, "Failed to disable service account", err)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusOK, account)
+}
+
+// handleIssueAPIKey handles POST /api/v1/service-accounts/{id}/keys. The key
+// is only ever returned in this response.
+func (s *Server) handleIssueAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAPIKeys(w) {
+		return
+	}
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON in request body")
+		return
+	}
+	if req.ExpiresIn < 0 {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "expires_in cannot be negative")
+		return
+	}
+	if err := security.ValidateScopes(req.Scopes); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_scope", err.Error())
+		return
+	}
+	// Keys cannot carry permissions their issuer does not hold
+	granted := security.GetUserPermissionsFromContext(r.Context())
+	for _, scope := range req.Scopes {
+		if !security.GrantsScope(granted, scope) {
+			s.writeError(w, http.StatusForbidden, "scope_not_granted", fmt.Sprintf("Cannot issue a key with scope %s", scope))
+			return
+		}
+	}
+
+	key, apiKey, err := s.apiKeys.IssueAPIKey(r.Context(), security.GetTenantIDFromContext(r.Context()), mux.Vars(r)["id"],
+		req.Scopes, time.Duration(req.ExpiresIn)*time.Second)
+	if err != nil {
+		s.writeServiceAccountError(w, r, "Failed to issue API key", err)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusCreated, map[string]interface{}{"key": key, "api_key": apiKey})
+}
+
+// handleListAPIKeys handles GET /api/v1/service-accounts/{id}/keys
+func (s *Server) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAPIKeys(w) {
+		return
+	}
+	keys, err := s.apiKeys.ListAPIKeys(r.Context(), security.GetTenantIDFromContext(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		s.writeServiceAccountError(w, r, "Failed to list API keys", err)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusOK, map[string]interface{}{"api_keys": keys})
+}
+
+// handleRotateAPIKey handles POST /api/v1/service-accounts/{id}/keys/{key_id}/rotate
+func (s *Server) handleRotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAPIKeys(w) {
+		return
+	}
+	var req rotateKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON in request body")
+			return
+		}
+	}
+	overlap := defaultKeyRotationOverlap
+	if req.Overlap != nil {
+		if *req.Overlap < 0 {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "overlap cannot be negative")
+			return
+		}
+		overlap = time.Duration(*req.Overlap) * time.Second
+	}
+
+	vars := mux.Vars(r)
+	key, apiKey, err := s.apiKeys.RotateAPIKey(r.Context(), security.GetTenantIDFromContext(r.Context()), vars["id"], vars["key_id"], overlap)
+	if err != nil {
+		s.writeServiceAccountError(w, r, "Failed to rotate API key", err)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusCreated, map[string]interface{}{"key": key, "api_key": apiKey, "replaces": vars["key_id"]})
+}
+
+// handleRevokeAPIKey handles DELETE /api/v1/service-accounts/{id}/keys/{key_id}
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAPIKeys(w) {
+		return
+	}
+	vars := mux.Vars(r)
+	if err := s.apiKeys.RevokeAPIKey(r.Context(), security.GetTenantIDFromContext(r.Context()), vars["id"], vars["key_id"]); err != nil {
+		s.writeServiceAccountError(w, r, "Failed to revoke API key", err)
+		return
+	}
+	s.writeJSONResponse(w, http.StatusOK, map[string]interface{}{"revoked": vars["key_id"]})
+}
+
+// requireAPIKeys writes an error when service accounts are not configured
+func (s *Server) requireAPIKeys(w http.ResponseWriter) bool {
+	if s.apiKeys == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "service_accounts_unavailable", "Service accounts require a database")
+		return false
+	}
+	return true
+}
+
+func (s *Server) writeServiceAccountError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, security.ErrServiceAccountNotFound):
+		s.writeError(w, http.StatusNotFound, "service_account_not_found", "Service account not found or disabled")
+	case errors.Is(err, security.ErrAPIKeyNotFound):
+		s.writeError(w, http.StatusNotFound, "api_key_not_found", "API key not found")
+	case errors.Is(err, security.ErrAPIKeyInvalid):
+		s.writeError(w, http.StatusConflict, "api_key_inactive", "API key is revoked or expired")
+	default:
+		GetLoggerFromContext(r.Context()).Error(message, err)
+		s.writeError(w, http.StatusInternalServerError, "service_account_failed", message)
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serviceAccountsRequest(method, path, body string, permissions ...string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	claims := &security.AgentFlowClaims{TenantID: auditsTestTenant, UserID: "admin", Permissions: permissions}
+	return req.WithContext(context.WithValue(req.Context(), "auth_claims", claims))
+}
+
+func TestServiceAccountRoutes(t *testing.T) {
+	srv, err := New(DefaultConfig(), logging.NewLogger())
+	require.NoError(t, err)
+
+	// Without a database the routes exist but are unavailable
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, serviceAccountsRequest("GET", "/api/v1/service-accounts", "", "service_accounts:read"))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "service_accounts_unavailable")
+
+	// The checks below are made before the service touches storage
+	srv.SetAPIKeys(security.NewAPIKeyService(nil))
+	keysPath := "/api/v1/service-accounts/00000000-0000-0000-0000-0000000000aa/keys"
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, serviceAccountsRequest("POST", keysPath, `{"scopes":["workflows:read"]}`, "service_accounts:read"))
+	assert.Equal(t, http.StatusForbidden, w.Code, "issuing keys needs write permission")
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, serviceAccountsRequest("POST", keysPath, `{"scopes":["workflows:write"]}`,
+		"service_accounts:write", "workflows:read"))
+	assert.Equal(t, http.StatusForbidden, w.Code, "keys cannot exceed the issuer's permissions")
+	assert.Contains(t, w.Body.String(), "scope_not_granted")
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, serviceAccountsRequest("POST", keysPath, `{"scopes":["workflows"]}`, "*"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_scope")
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, serviceAccountsRequest("POST", "/api/v1/service-accounts", `{"description":"no name"}`, "*"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
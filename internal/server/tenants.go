@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/agentflow/agentflow/internal/security"
+)
+
+// SetTenantIsolation sets the middleware callers' tenants are checked with
+func (ms *MiddlewareStack) SetTenantIsolation(tenants *security.TenantIsolationMiddleware) {
+	ms.tenantIsolation = tenants
+}
+
+// SetTenants checks on every authenticated request that the caller's tenant
+// exists and that its lifecycle status allows the request, looking tenants
+// up through tenants. It must be called before the server starts.
+func (s *Server) SetTenants(tenants security.TenantQuerier) {
+	isolation := security.NewTenantIsolationMiddleware(s.logger, tenants)
+	if s.auditRecorder != nil {
+		isolation.WithRecorder(s.auditRecorder)
+	}
+	s.middleware.SetTenantIsolation(isolation)
+}
+
+// TenantMiddleware rejects requests of unknown tenants, writes by suspended
+// tenants and tenants pending deletion, and cross-tenant access, and puts
+// the caller's tenant in the request context. It must run inside the
+// authentication middleware. Without tenant lookups requests are let through.
+func (ms *MiddlewareStack) TenantMiddleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ms.tenantIsolation == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ms.tenantIsolation.Middleware()(next).ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/security"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	lifecycle "github.com/agentflow/agentflow/internal/storage/tenant"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusQuerier serves every tenant with status
+type statusQuerier struct {
+	status string
+}
+
+func (q *statusQuerier) GetTenant(ctx context.Context, id pgtype.UUID) (queries.Tenant, error) {
+	return queries.Tenant{ID: id, Name: "acme", Tier: "standard", Status: q.status}, nil
+}
+
+func TestServerEnforcesTenantStatus(t *testing.T) {
+	srv, err := New(DefaultConfig(), logging.NewLogger())
+	require.NoError(t, err)
+	tenants := &statusQuerier{status: lifecycle.StatusActive}
+	recorder := audit.NewMemoryRecorder()
+	srv.SetAuditRecorder(recorder)
+	srv.SetTenants(tenants)
+
+	token, err := srv.authenticator.IssueToken(context.Background(), &security.TokenRequest{
+		TenantID:    auditsTestTenant,
+		UserID:      "user456",
+		Permissions: []string{"workflows:*"},
+	})
+	require.NoError(t, err)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		w := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(w, req)
+		return w
+	}
+	errorCode := func(w *httptest.ResponseRecorder) string {
+		var body struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		return body.Error.Code
+	}
+
+	assert.Equal(t, http.StatusNotImplemented, serve("POST", "/api/v1/workflows").Code)
+
+	// Suspended tenants can read but not write
+	tenants.status = lifecycle.StatusSuspended
+	w := serve("POST", "/api/v1/workflows")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "tenant_suspended", errorCode(w))
+	assert.Equal(t, http.StatusNotImplemented, serve("GET", "/api/v1/workflows").Code)
+
+	tenants.status = lifecycle.StatusPendingDeletion
+	w = serve("DELETE", "/api/v1/agents/a1")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "tenant_pending_deletion", errorCode(w))
+
+	// Erased tenants are closed
+	tenants.status = lifecycle.StatusErased
+	w = serve("GET", "/api/v1/workflows")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "invalid_tenant", errorCode(w))
+	assert.Equal(t, http.StatusForbidden, serve("POST", "/api/v1/auth/token").Code, "erased tenants cannot mint tokens")
+
+	// Cross-tenant access is refused and audited
+	tenants.status = lifecycle.StatusActive
+	w = serve("GET", "/api/v1/workflows?tenant_id=00000000-0000-0000-0000-0000000000ff")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, security.AuditActionCrossTenantAccess, recorder.Last().Action)
+
+	// Revoking a token is not a write to the tenant's data
+	tenants.status = lifecycle.StatusSuspended
+	assert.Equal(t, http.StatusOK, serve("POST", "/api/v1/auth/revoke").Code)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/agentflow/agentflow/internal/security/certs"
+)
+
+// Client certificate modes of Config.TLSClientAuth
+const (
+	ClientAuthOff      = "off"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+// clientAuthType maps a TLSClientAuth mode to how the listener treats
+// client certificates
+func clientAuthType(mode string) (tls.ClientAuthType, error) {
+	switch mode {
+	case "", ClientAuthOff:
+		return tls.NoClientCert, nil
+	case ClientAuthOptional:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("invalid TLS client auth mode %q (want %s, %s or %s)", mode, ClientAuthOff, ClientAuthOptional, ClientAuthRequire)
+	}
+}
+
+// tlsConfig loads the server certificate, and the client CA bundle when
+// client certificates are verified, and keeps both reloaded from disk until
+// the returned reloader is closed
+func (s *Server) tlsConfig() (*tls.Config, *certs.Reloader, error) {
+	if s.config.TLSCertPath == "" || s.config.TLSKeyPath == "" {
+		return nil, nil, fmt.Errorf("TLS enabled but cert or key path not provided")
+	}
+	clientAuth, err := clientAuthType(s.config.TLSClientAuth)
+	if err != nil {
+		return nil, nil, err
+	}
+	caPath := ""
+	if clientAuth != tls.NoClientCert {
+		caPath = s.config.TLSClientCAPath
+	}
+	reloader, err := certs.NewReloader(s.config.TLSCertPath, s.config.TLSKeyPath, caPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := reloader.ServerConfig(clientAuth)
+	if err != nil {
+		return nil, nil, err
+	}
+	reloader.Start(s.config.TLSReloadInterval)
+	return config, reloader, nil
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/security/certs"
+	lifecycle "github.com/agentflow/agentflow/internal/storage/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientAuthType(t *testing.T) {
+	for mode, want := range map[string]tls.ClientAuthType{
+		"":                 tls.NoClientCert,
+		ClientAuthOff:      tls.NoClientCert,
+		ClientAuthOptional: tls.VerifyClientCertIfGiven,
+		ClientAuthRequire:  tls.RequireAndVerifyClientCert,
+	} {
+		got, err := clientAuthType(mode)
+		require.NoError(t, err, mode)
+		assert.Equal(t, want, got, mode)
+	}
+
+	_, err := clientAuthType("mandatory")
+	assert.Error(t, err)
+
+	config := DefaultConfig()
+	config.EnableTracing = false
+	config.TLSClientAuth = "mandatory"
+	_, err = New(config, logging.NewLogger())
+	assert.Error(t, err)
+}
+
+// writeCert issues a certificate from ca into dir and returns its paths
+func writeCert(t *testing.T, ca *certs.CA, dir string, req certs.IssueRequest) (string, string) {
+	t.Helper()
+	certPEM, keyPEM, err := ca.Issue(req)
+	require.NoError(t, err)
+	certPath, keyPath := filepath.Join(dir, req.Name+".pem"), filepath.Join(dir, req.Name+"-key.pem")
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0o644))
+	require.NoError(t, os.WriteFile(keyPath, keyPEM, 0o600))
+	return certPath, keyPath
+}
+
+func TestServerMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := certs.NewCA("test CA", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, certs.WriteCA(dir, ca))
+	caPath := filepath.Join(dir, certs.CACertFile)
+	serverCert, serverKey := writeCert(t, ca, dir, certs.IssueRequest{Kind: certs.KindServer, Name: "control-plane", IPs: []net.IP{net.ParseIP("127.0.0.1")}})
+	workerCert, workerKey := writeCert(t, ca, dir, certs.IssueRequest{Kind: certs.KindWorker, Name: "w1"})
+	serviceCert, serviceKey := writeCert(t, ca, dir, certs.IssueRequest{Kind: certs.KindService, Name: "billing", TenantID: auditsTestTenant})
+
+	config := DefaultConfig()
+	config.EnableTracing = false
+	config.EnableTLS = true
+	config.TLSCertPath, config.TLSKeyPath = serverCert, serverKey
+	config.TLSClientCAPath = caPath
+	config.TLSClientAuth = ClientAuthOptional
+	srv, err := New(config, logging.NewLogger())
+	require.NoError(t, err)
+
+	tlsConfig, reloader, err := srv.tlsConfig()
+	require.NoError(t, err)
+	defer reloader.Close()
+	ts := httptest.NewUnstartedServer(srv.httpServer.Handler)
+	ts.TLS = tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	get := func(certPath, keyPath, path string) int {
+		client, err := certs.NewReloader(certPath, keyPath, caPath)
+		require.NoError(t, err)
+		resp, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: client.ClientConfig("127.0.0.1")}}).Get(ts.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Workers authenticate with their certificate but hold no audit access
+	assert.Equal(t, http.StatusForbidden, get(workerCert, workerKey, "/api/v1/audits"))
+	assert.Equal(t, http.StatusUnauthorized, get("", "", "/api/v1/audits"))
+
+	// With tenants enforced, workers act for no tenant and pass, while
+	// tenant-scoped certificates follow their tenant's status
+	tenants := &statusQuerier{status: lifecycle.StatusActive}
+	srv.SetTenants(tenants)
+	assert.Equal(t, http.StatusNotImplemented, get(workerCert, workerKey, "/api/v1/workflows"))
+	assert.Equal(t, http.StatusNotImplemented, get(serviceCert, serviceKey, "/api/v1/workflows"))
+	tenants.status = lifecycle.StatusErased
+	assert.Equal(t, http.StatusForbidden, get(serviceCert, serviceKey, "/api/v1/workflows"))
+	assert.Equal(t, http.StatusNotImplemented, get(workerCert, workerKey, "/api/v1/workflows"))
+
+	config.TLSClientCAPath = ""
+	_, _, err = srv.tlsConfig()
+	assert.Error(t, err, "verifying client certificates needs a CA bundle")
+}
@@ -0,0 +1,226 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// anchorVersion prefixes the signed anchor payload so the format can change
+const anchorVersion = "agentflow.audit-anchor/v1"
+
+var (
+	// ErrEmptyChain is returned when anchoring a tenant without audit records
+	ErrEmptyChain = errors.New("audit chain is empty")
+	// ErrAnchorSignature is returned for an anchor whose signature does not match its contents
+	ErrAnchorSignature = errors.New("anchor signature invalid")
+	// ErrAnchorLogCorrupt is returned when a sink's own integrity checks fail
+	ErrAnchorLogCorrupt = errors.New("anchor log corrupt")
+)
+
+// Anchor records a tenant's chain head at a point in time. Anchors are kept
+// outside the database, so rewriting the chain and recomputing its hashes
+// no longer goes unnoticed.
+type Anchor struct {
+	TenantID   string    `json:"tenant_id"`
+	Seq        int64     `json:"seq"`
+	Hash       string    `json:"hash"`
+	RecordTs   time.Time `json:"record_ts"`
+	AnchoredAt time.Time `json:"anchored_at"`
+	KeyID      string    `json:"key_id"`
+	Signature  string    `json:"signature"`
+}
+
+// AnchorSink stores anchors in append-only storage independent of the
+// database. Appending an anchor for a seq already stored is a no-op.
+type AnchorSink interface {
+	Name() string
+	Append(ctx context.Context, anchor Anchor) error
+	Anchors(ctx context.Context, tenantID string) ([]Anchor, error)
+}
+
+// AnchorSigner signs anchors with Ed25519, so they can be checked with the
+// public key alone
+type AnchorSigner struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewAnchorSigner creates a signer from a 32-byte Ed25519 seed
+func NewAnchorSigner(seed []byte) (*AnchorSigner, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("anchor key must be a %d-byte Ed25519 seed", ed25519.SeedSize)
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+	return &AnchorSigner{key: key, keyID: AnchorKeyID(key.Public().(ed25519.PublicKey))}, nil
+}
+
+// PublicKey returns the key that verifies this signer's anchors
+func (s *AnchorSigner) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// KeyID identifies the signing key
+func (s *AnchorSigner) KeyID() string {
+	return s.keyID
+}
+
+// Sign creates an anchor for record, stamped with the current time
+func (s *AnchorSigner) Sign(record queries.Audit) Anchor {
+	anchor := Anchor{
+		TenantID:   uuidToString(record.TenantID),
+		Seq:        record.Seq,
+		Hash:       hex.EncodeToString(record.Hash),
+		RecordTs:   record.Ts.Time.UTC(),
+		AnchoredAt: time.Now().UTC(),
+		KeyID:      s.keyID,
+	}
+	anchor.Signature = hex.EncodeToString(ed25519.Sign(s.key, anchorPayload(anchor)))
+	return anchor
+}
+
+// AnchorKeyID derives the key ID recorded with anchors from the public key
+func AnchorKeyID(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// VerifyAnchor checks that anchor was signed by publicKey and has not been altered
+func VerifyAnchor(publicKey ed25519.PublicKey, anchor Anchor) error {
+	if anchor.KeyID != AnchorKeyID(publicKey) {
+		return fmt.Errorf("%w: signed with key %s", ErrAnchorSignature, anchor.KeyID)
+	}
+	signature, err := hex.DecodeString(anchor.Signature)
+	if err != nil || !ed25519.Verify(publicKey, anchorPayload(anchor), signature) {
+		return ErrAnchorSignature
+	}
+	return nil
+}
+
+func anchorPayload(a Anchor) []byte {
+	return []byte(anchorVersion + "\n" +
+		a.TenantID + "\n" +
+		strconv.FormatInt(a.Seq, 10) + "\n" +
+		a.Hash + "\n" +
+		a.RecordTs.UTC().Format(time.RFC3339Nano) + "\n" +
+		a.AnchoredAt.UTC().Format(time.RFC3339Nano))
+}
+
+// AnchorQuerier reads the chain head to anchor
+type AnchorQuerier interface {
+	GetLatestAudit(ctx context.Context, tenantID pgtype.UUID) (queries.Audit, error)
+}
+
+// Anchorer writes signed chain heads to every configured sink
+type Anchorer struct {
+	reader AnchorQuerier
+	signer *AnchorSigner
+	sinks  []AnchorSink
+}
+
+// NewAnchorer creates an anchorer reading chain heads through reader
+func NewAnchorer(reader AnchorQuerier, signer *AnchorSigner, sinks ...AnchorSink) *Anchorer {
+	return &Anchorer{reader: reader, signer: signer, sinks: sinks}
+}
+
+// AnchorTenant anchors the tenant's current chain head. It stops at the first
+// sink that fails, so a retry appends to the others again, which is harmless.
+func (a *Anchorer) AnchorTenant(ctx context.Context, tenantID pgtype.UUID) (Anchor, error) {
+	head, err := a.reader.GetLatestAudit(ctx, tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Anchor{}, ErrEmptyChain
+	}
+	if err != nil {
+		return Anchor{}, fmt.Errorf("failed to get chain head: %w", err)
+	}
+
+	anchor := a.signer.Sign(head)
+	for _, sink := range a.sinks {
+		if err := sink.Append(ctx, anchor); err != nil {
+			return anchor, fmt.Errorf("failed to append anchor to %s: %w", sink.Name(), err)
+		}
+	}
+	return anchor, nil
+}
+
+// AnchorResult reports how the live chain compares with stored anchors
+type AnchorResult struct {
+	Valid          bool
+	AnchorsChecked int
+	LatestSeq      int64
+	MismatchSeq    int64
+	ErrorMessage   string
+}
+
+// AnchorChecker compares the live chain with the anchors in each sink
+type AnchorChecker struct {
+	reader    VerifierQuerier
+	publicKey ed25519.PublicKey
+	sinks     []AnchorSink
+}
+
+// NewAnchorChecker creates a checker that accepts anchors signed by publicKey
+func NewAnchorChecker(reader VerifierQuerier, publicKey ed25519.PublicKey, sinks ...AnchorSink) *AnchorChecker {
+	return &AnchorChecker{reader: reader, publicKey: publicKey, sinks: sinks}
+}
+
+// Check verifies that every anchored record is still in the chain with the
+// anchored hash. Combined with chain verification this shows the records up
+// to the latest anchor are unchanged since they were anchored.
+func (c *AnchorChecker) Check(ctx context.Context, tenantID pgtype.UUID) (AnchorResult, error) {
+	result := AnchorResult{Valid: true}
+	tenant := uuidToString(tenantID)
+
+	for _, sink := range c.sinks {
+		anchors, err := sink.Anchors(ctx, tenant)
+		if errors.Is(err, ErrAnchorLogCorrupt) {
+			return anchorMismatch(result, 0, fmt.Sprintf("%s: %v", sink.Name(), err)), nil
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to read anchors from %s: %w", sink.Name(), err)
+		}
+
+		for _, anchor := range anchors {
+			if anchor.TenantID != tenant {
+				return anchorMismatch(result, anchor.Seq, fmt.Sprintf("%s: anchor at seq %d belongs to tenant %s", sink.Name(), anchor.Seq, anchor.TenantID)), nil
+			}
+			if err := VerifyAnchor(c.publicKey, anchor); err != nil {
+				return anchorMismatch(result, anchor.Seq, fmt.Sprintf("%s: anchor at seq %d: %v", sink.Name(), anchor.Seq, err)), nil
+			}
+
+			record, err := c.reader.GetAuditBySeq(ctx, queries.GetAuditBySeqParams{TenantID: tenantID, Seq: anchor.Seq})
+			if errors.Is(err, pgx.ErrNoRows) {
+				return anchorMismatch(result, anchor.Seq, fmt.Sprintf("%s: anchored record at seq %d is missing", sink.Name(), anchor.Seq)), nil
+			}
+			if err != nil {
+				return result, fmt.Errorf("failed to get audit record %d: %w", anchor.Seq, err)
+			}
+			if hex.EncodeToString(record.Hash) != anchor.Hash {
+				return anchorMismatch(result, anchor.Seq, fmt.Sprintf("%s: record at seq %d does not match its anchor from %s",
+					sink.Name(), anchor.Seq, anchor.AnchoredAt.Format(time.RFC3339))), nil
+			}
+
+			result.AnchorsChecked++
+			if anchor.Seq > result.LatestSeq {
+				result.LatestSeq = anchor.Seq
+			}
+		}
+	}
+	return result, nil
+}
+
+func anchorMismatch(result AnchorResult, seq int64, message string) AnchorResult {
+	result.Valid = false
+	result.MismatchSeq = seq
+	result.ErrorMessage = message
+	return result
+}
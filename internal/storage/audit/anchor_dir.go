@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DirAnchorSink stores each anchor as a signed timestamp document,
+// <dir>/<tenant-id>/<seq>.json. Documents are created once and never
+// rewritten, so the directory suits write-once storage such as an object
+// store bucket with retention locks mounted as a file system.
+type DirAnchorSink struct {
+	dir string
+}
+
+// NewDirAnchorSink creates a sink writing documents under dir
+func NewDirAnchorSink(dir string) *DirAnchorSink {
+	return &DirAnchorSink{dir: dir}
+}
+
+// Name identifies the sink in reports
+func (s *DirAnchorSink) Name() string {
+	return "dir:" + s.dir
+}
+
+// Append writes the anchor's document unless one exists for its seq
+func (s *DirAnchorSink) Append(ctx context.Context, anchor Anchor) error {
+	tenantDir := filepath.Join(s.dir, anchor.TenantID)
+	if err := os.MkdirAll(tenantDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create anchor directory: %w", err)
+	}
+	data, err := json.MarshalIndent(anchor, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode anchor: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(tenantDir, anchorFileName(anchor.Seq)), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o400)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create anchor document: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write anchor document: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync anchor document: %w", err)
+	}
+	return f.Close()
+}
+
+// Anchors returns the tenant's documents in seq order
+func (s *DirAnchorSink) Anchors(ctx context.Context, tenantID string) ([]Anchor, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, tenantID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list anchor documents: %w", err)
+	}
+
+	var anchors []Anchor
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		seq, err := strconv.ParseInt(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unexpected document %s", ErrAnchorLogCorrupt, name)
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, tenantID, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read anchor document: %w", err)
+		}
+		var anchor Anchor
+		if err := json.Unmarshal(data, &anchor); err != nil {
+			return nil, fmt.Errorf("%w: document %s: %v", ErrAnchorLogCorrupt, name, err)
+		}
+		if anchor.Seq != seq {
+			return nil, fmt.Errorf("%w: document %s holds seq %d", ErrAnchorLogCorrupt, name, anchor.Seq)
+		}
+		anchors = append(anchors, anchor)
+	}
+
+	sort.Slice(anchors, func(i, j int) bool { return anchors[i].Seq < anchors[j].Seq })
+	return anchors, nil
+}
+
+// anchorFileName zero-pads seq so documents list in order
+func anchorFileName(seq int64) string {
+	return fmt.Sprintf("%020d.json", seq)
+}
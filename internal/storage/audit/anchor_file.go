@@ -0,0 +1,228 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// AnchorLogEntry is one line of a FileAnchorLog. Root is the Merkle root over
+// every entry up to and including this one.
+type AnchorLogEntry struct {
+	Index    int64  `json:"index"`
+	Anchor   Anchor `json:"anchor"`
+	TreeSize int64  `json:"tree_size"`
+	Root     string `json:"root"`
+}
+
+// AnchorLogProof shows that an entry is included in the log at a given size
+type AnchorLogProof struct {
+	Index    int64    `json:"index"`
+	TreeSize int64    `json:"tree_size"`
+	LeafHash string   `json:"leaf_hash"`
+	Root     string   `json:"root"`
+	Path     []string `json:"path"`
+}
+
+// FileAnchorLog is an append-only transparency log of anchors for all tenants,
+// stored as JSON lines. Each entry carries the Merkle root of the log so far,
+// so a root published or copied elsewhere pins every entry before it.
+// A log has a single writer; concurrent appends from several processes are
+// not supported.
+type FileAnchorLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileAnchorLog creates a log at path. The file is created on first append.
+func NewFileAnchorLog(path string) *FileAnchorLog {
+	return &FileAnchorLog{path: path}
+}
+
+// Name identifies the sink in reports
+func (l *FileAnchorLog) Name() string {
+	return "file:" + l.path
+}
+
+// Append adds anchor to the log unless an entry for its tenant and seq exists
+func (l *FileAnchorLog) Append(ctx context.Context, anchor Anchor) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, frontier, err := l.read()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Anchor.TenantID == anchor.TenantID && e.Anchor.Seq == anchor.Seq {
+			return nil
+		}
+	}
+
+	leaf, err := anchorLeafHash(anchor)
+	if err != nil {
+		return err
+	}
+	frontier.push(leaf)
+	entry := AnchorLogEntry{
+		Index:    int64(len(entries)),
+		Anchor:   anchor,
+		TreeSize: int64(len(entries)) + 1,
+		Root:     hex.EncodeToString(frontier.root()),
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode anchor log entry: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open anchor log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write anchor log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync anchor log: %w", err)
+	}
+	return f.Close()
+}
+
+// Anchors returns the tenant's anchors after checking every root in the log
+func (l *FileAnchorLog) Anchors(ctx context.Context, tenantID string) ([]Anchor, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, _, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	var anchors []Anchor
+	for _, e := range entries {
+		if e.Anchor.TenantID == tenantID {
+			anchors = append(anchors, e.Anchor)
+		}
+	}
+	return anchors, nil
+}
+
+// Head returns the size and root of the log
+func (l *FileAnchorLog) Head() (int64, string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, frontier, err := l.read()
+	if err != nil {
+		return 0, "", err
+	}
+	return int64(len(entries)), hex.EncodeToString(frontier.root()), nil
+}
+
+// Prove returns an inclusion proof for the entry at index against the
+// current log head
+func (l *FileAnchorLog) Prove(index int64) (AnchorLogProof, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, frontier, err := l.read()
+	if err != nil {
+		return AnchorLogProof{}, err
+	}
+	leaves := make([][]byte, len(entries))
+	for i, e := range entries {
+		if leaves[i], err = anchorLeafHash(e.Anchor); err != nil {
+			return AnchorLogProof{}, err
+		}
+	}
+	path, err := MerkleInclusionProof(leaves, int(index))
+	if err != nil {
+		return AnchorLogProof{}, err
+	}
+
+	proof := AnchorLogProof{
+		Index:    index,
+		TreeSize: int64(len(entries)),
+		LeafHash: hex.EncodeToString(leaves[index]),
+		Root:     hex.EncodeToString(frontier.root()),
+	}
+	for _, p := range path {
+		proof.Path = append(proof.Path, hex.EncodeToString(p))
+	}
+	return proof, nil
+}
+
+// VerifyAnchorLogProof reports whether proof shows anchor in a log with the proof's root
+func VerifyAnchorLogProof(anchor Anchor, proof AnchorLogProof) bool {
+	leaf, err := anchorLeafHash(anchor)
+	if err != nil || hex.EncodeToString(leaf) != proof.LeafHash {
+		return false
+	}
+	root, err := hex.DecodeString(proof.Root)
+	if err != nil {
+		return false
+	}
+	path := make([][]byte, len(proof.Path))
+	for i, p := range proof.Path {
+		if path[i], err = hex.DecodeString(p); err != nil {
+			return false
+		}
+	}
+	return VerifyMerkleInclusion(leaf, proof.Index, proof.TreeSize, path, root)
+}
+
+// read loads every entry, recomputing the root after each one
+func (l *FileAnchorLog) read() ([]AnchorLogEntry, *merkleFrontier, error) {
+	frontier := &merkleFrontier{}
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, frontier, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open anchor log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []AnchorLogEntry
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(line) == 0 {
+			break
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("failed to read anchor log: %w", err)
+		}
+
+		var entry AnchorLogEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, nil, fmt.Errorf("%w: entry %d: %v", ErrAnchorLogCorrupt, len(entries), err)
+		}
+		leaf, err := anchorLeafHash(entry.Anchor)
+		if err != nil {
+			return nil, nil, err
+		}
+		frontier.push(leaf)
+		index := int64(len(entries))
+		if entry.Index != index || entry.TreeSize != index+1 || entry.Root != hex.EncodeToString(frontier.root()) {
+			return nil, nil, fmt.Errorf("%w: entry %d does not match the log before it", ErrAnchorLogCorrupt, index)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, frontier, nil
+}
+
+func anchorLeafHash(anchor Anchor) ([]byte, error) {
+	data, err := json.Marshal(anchor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode anchor: %w", err)
+	}
+	return MerkleLeafHash(data), nil
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileAnchorLog(t *testing.T) {
+	signer := newTestAnchorSigner(t)
+	q, tenantID := newTestChain(t, 6)
+	other, otherTenant := newTestChain(t, 2)
+	otherTenant.Bytes[0] = 2
+	for i := range other.audits {
+		other.audits[i].TenantID = otherTenant
+	}
+
+	log := NewFileAnchorLog(filepath.Join(t.TempDir(), "anchors.log"))
+	ctx := context.Background()
+
+	if size, _, err := log.Head(); err != nil || size != 0 {
+		t.Fatalf("Head() of a new log = %d, %v, want 0", size, err)
+	}
+
+	anchors := []Anchor{
+		signer.Sign(q.audits[2]),
+		signer.Sign(other.audits[1]),
+		signer.Sign(q.audits[5]),
+	}
+	for _, a := range append(anchors, anchors[0]) {
+		if err := log.Append(ctx, a); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+
+	size, root, err := log.Head()
+	if err != nil || size != 3 {
+		t.Fatalf("Head() = %d, %v, want 3 entries", size, err)
+	}
+
+	got, err := log.Anchors(ctx, uuidToString(tenantID))
+	if err != nil {
+		t.Fatalf("Anchors() unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].Seq != 3 || got[1].Seq != 6 {
+		t.Errorf("Anchors() = %+v, want seq 3 and 6", got)
+	}
+
+	for i, a := range anchors {
+		proof, err := log.Prove(int64(i))
+		if err != nil {
+			t.Fatalf("Prove(%d) unexpected error: %v", i, err)
+		}
+		if proof.Root != root || !VerifyAnchorLogProof(a, proof) {
+			t.Errorf("proof for entry %d did not verify against the log head", i)
+		}
+		if VerifyAnchorLogProof(anchors[(i+1)%len(anchors)], proof) {
+			t.Errorf("proof for entry %d verified another anchor", i)
+		}
+	}
+	if _, err := log.Prove(3); err == nil {
+		t.Error("Prove() expected error past the end of the log")
+	}
+}
+
+func TestFileAnchorLogDetectsRewrite(t *testing.T) {
+	signer := newTestAnchorSigner(t)
+	q, tenantID := newTestChain(t, 3)
+	path := filepath.Join(t.TempDir(), "anchors.log")
+	log := NewFileAnchorLog(path)
+	ctx := context.Background()
+
+	for _, a := range q.audits {
+		if err := log.Append(ctx, signer.Sign(a)); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+
+	// Dropping an entry breaks the index and root of every entry after it
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if err := os.WriteFile(path, append(lines[0], lines[2]...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := log.Anchors(ctx, uuidToString(tenantID)); !errors.Is(err, ErrAnchorLogCorrupt) {
+		t.Errorf("Anchors() error = %v, want %v", err, ErrAnchorLogCorrupt)
+	}
+	if err := log.Append(ctx, signer.Sign(q.audits[2])); !errors.Is(err, ErrAnchorLogCorrupt) {
+		t.Errorf("Append() to a corrupt log error = %v, want %v", err, ErrAnchorLogCorrupt)
+	}
+}
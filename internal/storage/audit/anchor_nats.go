@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultAnchorBucket is the JetStream key-value bucket anchors are kept in
+const DefaultAnchorBucket = "AF_AUDIT_ANCHORS"
+
+// NATSAnchorSink stores anchors in a JetStream key-value bucket under
+// <tenant-id>.<seq>. Keys are only ever created, and a deleted or purged key
+// is reported as corruption.
+type NATSAnchorSink struct {
+	kv     nats.KeyValue
+	bucket string
+}
+
+// NewNATSAnchorSink binds to bucket, creating it when it does not exist
+func NewNATSAnchorSink(js nats.JetStreamContext, bucket string) (*NATSAnchorSink, error) {
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:      bucket,
+			Description: "AgentFlow audit chain anchors",
+			History:     1,
+			Storage:     nats.FileStorage,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open anchor bucket %s: %w", bucket, err)
+	}
+	return &NATSAnchorSink{kv: kv, bucket: bucket}, nil
+}
+
+// Name identifies the sink in reports
+func (s *NATSAnchorSink) Name() string {
+	return "nats:" + s.bucket
+}
+
+// Append creates the anchor's key unless it exists
+func (s *NATSAnchorSink) Append(ctx context.Context, anchor Anchor) error {
+	data, err := json.Marshal(anchor)
+	if err != nil {
+		return fmt.Errorf("failed to encode anchor: %w", err)
+	}
+	_, err = s.kv.Create(anchorKey(anchor.TenantID, anchor.Seq), data)
+	if errors.Is(err, nats.ErrKeyExists) {
+		return nil
+	}
+	return err
+}
+
+// Anchors returns the tenant's anchors in seq order
+func (s *NATSAnchorSink) Anchors(ctx context.Context, tenantID string) ([]Anchor, error) {
+	watcher, err := s.kv.Watch(tenantID+".*", nats.Context(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch anchor bucket: %w", err)
+	}
+	defer watcher.Stop()
+
+	var anchors []Anchor
+	for entry := range watcher.Updates() {
+		// A nil entry marks the end of the stored values
+		if entry == nil {
+			break
+		}
+		if op := entry.Operation(); op != nats.KeyValuePut {
+			return nil, fmt.Errorf("%w: key %s was removed (%s)", ErrAnchorLogCorrupt, entry.Key(), op)
+		}
+		var anchor Anchor
+		if err := json.Unmarshal(entry.Value(), &anchor); err != nil {
+			return nil, fmt.Errorf("%w: key %s: %v", ErrAnchorLogCorrupt, entry.Key(), err)
+		}
+		if entry.Key() != anchorKey(anchor.TenantID, anchor.Seq) {
+			return nil, fmt.Errorf("%w: key %s holds seq %d", ErrAnchorLogCorrupt, entry.Key(), anchor.Seq)
+		}
+		anchors = append(anchors, anchor)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(anchors, func(i, j int) bool { return anchors[i].Seq < anchors[j].Seq })
+	return anchors, nil
+}
+
+func anchorKey(tenantID string, seq int64) string {
+	return tenantID + "." + strconv.FormatInt(seq, 10)
+}
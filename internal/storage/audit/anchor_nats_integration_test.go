@@ -0,0 +1,59 @@
+//go:build integration
+// +build integration
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNATSAnchorSinkIntegration(t *testing.T) {
+	natsURL := os.Getenv("NATS_URL")
+	if natsURL == "" {
+		t.Skip("NATS_URL not set")
+	}
+
+	conn, err := nats.Connect(natsURL)
+	require.NoError(t, err)
+	defer conn.Close()
+	js, err := conn.JetStream()
+	require.NoError(t, err)
+
+	bucket := fmt.Sprintf("AF_AUDIT_ANCHORS_TEST_%d", time.Now().UnixNano())
+	sink, err := NewNATSAnchorSink(js, bucket)
+	require.NoError(t, err)
+	defer js.DeleteKeyValue(bucket)
+
+	signer := newTestAnchorSigner(t)
+	q, tenantID := newTestChain(t, 5)
+	ctx := context.Background()
+	for _, i := range []int{4, 1, 1} {
+		require.NoError(t, sink.Append(ctx, signer.Sign(q.audits[i])))
+	}
+
+	anchors, err := sink.Anchors(ctx, uuidToString(tenantID))
+	require.NoError(t, err)
+	require.Len(t, anchors, 2)
+	assert.Equal(t, int64(2), anchors[0].Seq)
+	assert.Equal(t, int64(5), anchors[1].Seq)
+
+	result, err := NewAnchorChecker(q, signer.PublicKey(), sink).Check(ctx, tenantID)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, 2, result.AnchorsChecked)
+
+	// Deleting a key leaves a marker that is reported as corruption
+	kv, err := js.KeyValue(bucket)
+	require.NoError(t, err)
+	require.NoError(t, kv.Delete(anchorKey(uuidToString(tenantID), 2)))
+	_, err = sink.Anchors(ctx, uuidToString(tenantID))
+	assert.ErrorIs(t, err, ErrAnchorLogCorrupt)
+}
@@ -0,0 +1,227 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var testAnchorSeed = bytes.Repeat([]byte{7}, 32)
+
+// memoryAnchorSink keeps anchors in memory
+type memoryAnchorSink struct {
+	anchors []Anchor
+	err     error
+}
+
+func (s *memoryAnchorSink) Name() string { return "memory" }
+
+func (s *memoryAnchorSink) Append(ctx context.Context, anchor Anchor) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, a := range s.anchors {
+		if a.TenantID == anchor.TenantID && a.Seq == anchor.Seq {
+			return nil
+		}
+	}
+	s.anchors = append(s.anchors, anchor)
+	return nil
+}
+
+func (s *memoryAnchorSink) Anchors(ctx context.Context, tenantID string) ([]Anchor, error) {
+	var anchors []Anchor
+	for _, a := range s.anchors {
+		if a.TenantID == tenantID {
+			anchors = append(anchors, a)
+		}
+	}
+	return anchors, nil
+}
+
+func newTestAnchorSigner(t *testing.T) *AnchorSigner {
+	t.Helper()
+	signer, err := NewAnchorSigner(testAnchorSeed)
+	if err != nil {
+		t.Fatalf("NewAnchorSigner() unexpected error: %v", err)
+	}
+	return signer
+}
+
+func TestAnchorSigner(t *testing.T) {
+	if _, err := NewAnchorSigner([]byte("short")); err == nil {
+		t.Error("NewAnchorSigner() expected error for a short seed")
+	}
+
+	signer := newTestAnchorSigner(t)
+	q, _ := newTestChain(t, 3)
+	anchor := signer.Sign(q.audits[2])
+	if anchor.Seq != 3 || anchor.KeyID != signer.KeyID() {
+		t.Errorf("Sign() = seq %d key %s, want seq 3 key %s", anchor.Seq, anchor.KeyID, signer.KeyID())
+	}
+	if err := VerifyAnchor(signer.PublicKey(), anchor); err != nil {
+		t.Fatalf("VerifyAnchor() unexpected error: %v", err)
+	}
+
+	altered := anchor
+	altered.Seq = 2
+	if err := VerifyAnchor(signer.PublicKey(), altered); !errors.Is(err, ErrAnchorSignature) {
+		t.Errorf("VerifyAnchor() of altered anchor error = %v, want %v", err, ErrAnchorSignature)
+	}
+
+	other, err := NewAnchorSigner(bytes.Repeat([]byte{8}, 32))
+	if err != nil {
+		t.Fatalf("NewAnchorSigner() unexpected error: %v", err)
+	}
+	if err := VerifyAnchor(other.PublicKey(), anchor); !errors.Is(err, ErrAnchorSignature) {
+		t.Errorf("VerifyAnchor() with another key error = %v, want %v", err, ErrAnchorSignature)
+	}
+}
+
+func TestAnchorer_AnchorTenant(t *testing.T) {
+	signer := newTestAnchorSigner(t)
+	q, tenantID := newTestChain(t, 5)
+	sink := &memoryAnchorSink{}
+	anchorer := NewAnchorer(q, signer, sink)
+
+	anchor, err := anchorer.AnchorTenant(context.Background(), tenantID)
+	if err != nil {
+		t.Fatalf("AnchorTenant() unexpected error: %v", err)
+	}
+	if anchor.Seq != 5 || len(sink.anchors) != 1 {
+		t.Errorf("AnchorTenant() anchored seq %d with %d stored, want seq 5 with 1", anchor.Seq, len(sink.anchors))
+	}
+
+	// Anchoring an unchanged head again stores nothing new
+	if _, err := anchorer.AnchorTenant(context.Background(), tenantID); err != nil {
+		t.Fatalf("AnchorTenant() unexpected error: %v", err)
+	}
+	if len(sink.anchors) != 1 {
+		t.Errorf("sink holds %d anchors after re-anchoring, want 1", len(sink.anchors))
+	}
+
+	sink.err = errors.New("sink down")
+	appendTestRecords(t, q, tenantID, 1)
+	if _, err := anchorer.AnchorTenant(context.Background(), tenantID); err == nil || !strings.Contains(err.Error(), "memory") {
+		t.Errorf("AnchorTenant() error = %v, want an error naming the sink", err)
+	}
+
+	empty, emptyTenant := newTestChain(t, 0)
+	if _, err := NewAnchorer(empty, signer, sink).AnchorTenant(context.Background(), emptyTenant); !errors.Is(err, ErrEmptyChain) {
+		t.Errorf("AnchorTenant() on empty chain error = %v, want %v", err, ErrEmptyChain)
+	}
+}
+
+func TestAnchorChecker_Check(t *testing.T) {
+	signer := newTestAnchorSigner(t)
+
+	setup := func(t *testing.T) (*rangeQueries, pgtype.UUID, *memoryAnchorSink) {
+		q, tenantID := newTestChain(t, 4)
+		sink := &memoryAnchorSink{}
+		anchorer := NewAnchorer(q, signer, sink)
+		if _, err := anchorer.AnchorTenant(context.Background(), tenantID); err != nil {
+			t.Fatalf("AnchorTenant() unexpected error: %v", err)
+		}
+		appendTestRecords(t, q, tenantID, 4)
+		if _, err := anchorer.AnchorTenant(context.Background(), tenantID); err != nil {
+			t.Fatalf("AnchorTenant() unexpected error: %v", err)
+		}
+		return q, tenantID, sink
+	}
+
+	t.Run("intact", func(t *testing.T) {
+		q, tenantID, sink := setup(t)
+		result, err := NewAnchorChecker(q, signer.PublicKey(), sink).Check(context.Background(), tenantID)
+		if err != nil {
+			t.Fatalf("Check() unexpected error: %v", err)
+		}
+		if !result.Valid || result.AnchorsChecked != 2 || result.LatestSeq != 8 {
+			t.Errorf("Check() = %+v, want valid with 2 anchors up to seq 8", result)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		modify  func(q *rangeQueries, sink *memoryAnchorSink)
+		wantSeq int64
+		wantMsg string
+	}{
+		{
+			name: "rewritten chain",
+			modify: func(q *rangeQueries, sink *memoryAnchorSink) {
+				q.audits[3].Hash = []byte("recomputed")
+			},
+			wantSeq: 4,
+			wantMsg: "does not match its anchor",
+		},
+		{
+			name: "truncated chain",
+			modify: func(q *rangeQueries, sink *memoryAnchorSink) {
+				q.audits = q.audits[:6]
+			},
+			wantSeq: 8,
+			wantMsg: "is missing",
+		},
+		{
+			name: "forged anchor",
+			modify: func(q *rangeQueries, sink *memoryAnchorSink) {
+				sink.anchors[1].Hash = "00"
+			},
+			wantSeq: 8,
+			wantMsg: "signature invalid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, tenantID, sink := setup(t)
+			tt.modify(q, sink)
+			result, err := NewAnchorChecker(q, signer.PublicKey(), sink).Check(context.Background(), tenantID)
+			if err != nil {
+				t.Fatalf("Check() unexpected error: %v", err)
+			}
+			if result.Valid || result.MismatchSeq != tt.wantSeq || !strings.Contains(result.ErrorMessage, tt.wantMsg) {
+				t.Errorf("Check() = %+v, want mismatch at seq %d containing %q", result, tt.wantSeq, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestDirAnchorSink(t *testing.T) {
+	signer := newTestAnchorSigner(t)
+	q, tenantID := newTestChain(t, 12)
+	dir := t.TempDir()
+	sink := NewDirAnchorSink(dir)
+	ctx := context.Background()
+
+	for _, i := range []int{11, 1, 1} {
+		if err := sink.Append(ctx, signer.Sign(q.audits[i])); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+
+	anchors, err := sink.Anchors(ctx, uuidToString(tenantID))
+	if err != nil {
+		t.Fatalf("Anchors() unexpected error: %v", err)
+	}
+	if len(anchors) != 2 || anchors[0].Seq != 2 || anchors[1].Seq != 12 {
+		t.Fatalf("Anchors() = %+v, want seq 2 and 12 in order", anchors)
+	}
+	if others, err := sink.Anchors(ctx, "other-tenant"); err != nil || len(others) != 0 {
+		t.Errorf("Anchors() for another tenant = %v, %v, want none", others, err)
+	}
+
+	// A document moved to another seq is caught
+	tenantDir := filepath.Join(dir, uuidToString(tenantID))
+	if err := os.Rename(filepath.Join(tenantDir, anchorFileName(2)), filepath.Join(tenantDir, anchorFileName(3))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sink.Anchors(ctx, uuidToString(tenantID)); !errors.Is(err, ErrAnchorLogCorrupt) {
+		t.Errorf("Anchors() error = %v, want %v", err, ErrAnchorLogCorrupt)
+	}
+}
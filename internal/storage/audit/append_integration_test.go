@@ -0,0 +1,78 @@
+//go:build integration
+// +build integration
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+)
+
+func TestConcurrentAppendsIntegration(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	config, err := pgxpool.ParseConfig(dbURL)
+	require.NoError(t, err)
+	config.MaxConns = 50
+	db, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Skip("Database not available")
+	}
+	defer db.Close()
+
+	q := queries.New(db)
+	tenant, err := q.CreateTenant(ctx, queries.CreateTenantParams{Name: "audit-stress-" + uuid.NewString(), Tier: "free", Settings: []byte(`{}`)})
+	require.NoError(t, err)
+	defer func() { _ = q.DeleteTenant(ctx, tenant.ID) }()
+
+	const writers = 500
+	svc := NewServiceWithDB(db)
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := svc.CreateAudit(ctx, CreateAuditParams{
+				TenantID:     tenant.ID,
+				ActorType:    "agent",
+				ActorID:      fmt.Sprintf("agent-%d", i),
+				Action:       "invoke",
+				ResourceType: "tool",
+				Details:      map[string]interface{}{"writer": i, "tool_name": "search"},
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	chain, err := q.GetAuditChain(ctx, tenant.ID)
+	require.NoError(t, err)
+	require.Len(t, chain, writers)
+	for i, a := range chain {
+		assert.Equal(t, int64(i+1), a.Seq)
+	}
+
+	result, err := svc.VerifyChainIntegrity(ctx, tenant.ID)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.ErrorMessage)
+	assert.Equal(t, writers, result.TotalRecords)
+}
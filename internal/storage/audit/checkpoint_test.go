@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestNewCheckpointSigner(t *testing.T) {
+	if _, err := NewCheckpointSigner([]byte("too-short")); err == nil {
+		t.Error("NewCheckpointSigner() expected error for a short key")
+	}
+
+	a := newTestSigner(t, testCheckpointKey)
+	b := newTestSigner(t, testCheckpointKey)
+	if a.KeyID() != b.KeyID() || len(a.KeyID()) != 16 {
+		t.Errorf("KeyID() = %q and %q, want the same 16 hex characters", a.KeyID(), b.KeyID())
+	}
+}
+
+func TestCheckpointSigner_Verify(t *testing.T) {
+	signer := newTestSigner(t, testCheckpointKey)
+	tenantID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	ts := time.Date(2025, 8, 28, 12, 0, 0, 123456000, time.FixedZone("CEST", 2*60*60))
+	params := signer.Sign(tenantID, 42, []byte("head-hash"), ts)
+
+	checkpoint := func() queries.AuditCheckpoint {
+		return queries.AuditCheckpoint{
+			TenantID:  params.TenantID,
+			Seq:       params.Seq,
+			Hash:      params.Hash,
+			RecordTs:  pgtype.Timestamptz{Time: params.RecordTs.Time.Local(), Valid: true},
+			KeyID:     params.KeyID,
+			Signature: params.Signature,
+		}
+	}
+
+	if err := signer.Verify(checkpoint()); err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *queries.AuditCheckpoint)
+		want   error
+	}{
+		{"seq", func(c *queries.AuditCheckpoint) { c.Seq = 43 }, ErrCheckpointSignature},
+		{"hash", func(c *queries.AuditCheckpoint) { c.Hash = []byte("other-hash") }, ErrCheckpointSignature},
+		{"timestamp", func(c *queries.AuditCheckpoint) { c.RecordTs.Time = c.RecordTs.Time.Add(time.Microsecond) }, ErrCheckpointSignature},
+		{"tenant", func(c *queries.AuditCheckpoint) { c.TenantID.Bytes[0] = 2 }, ErrCheckpointSignature},
+		{"key", func(c *queries.AuditCheckpoint) { c.KeyID = "0000000000000000" }, ErrCheckpointKeyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := checkpoint()
+			tt.modify(&c)
+			if err := signer.Verify(c); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Event outcomes, stored in the details of the audit record
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// ErrInvalidEvent is returned for events that cannot be written to a chain
+var ErrInvalidEvent = errors.New("invalid audit event")
+
+// Event is an auditable action taken by an actor on a resource
+type Event struct {
+	TenantID     string
+	ActorType    string
+	ActorID      string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Outcome      string
+	Details      map[string]interface{}
+
+	// Critical events are written before Record returns
+	Critical bool
+}
+
+// Recorder records audit events
+type Recorder interface {
+	Record(ctx context.Context, event Event) error
+}
+
+// Appender appends records to a tenant's audit chain, as Service does
+type Appender interface {
+	CreateAudit(ctx context.Context, params CreateAuditParams) (*queries.Audit, error)
+}
+
+// EmitterConfig configures an Emitter
+type EmitterConfig struct {
+	// BufferSize is the number of non-critical events held for the writer
+	BufferSize int
+	// WriteTimeout bounds each append
+	WriteTimeout time.Duration
+}
+
+// DefaultEmitterConfig returns the default emitter configuration
+func DefaultEmitterConfig() EmitterConfig {
+	return EmitterConfig{
+		BufferSize:   1024,
+		WriteTimeout: 5 * time.Second,
+	}
+}
+
+// EmitterStats counts the events an Emitter has handled
+type EmitterStats struct {
+	Written int64
+	Failed  int64
+	// Overflowed counts non-critical events written synchronously because
+	// the buffer was full
+	Overflowed int64
+}
+
+// Emitter is a Recorder that writes critical events synchronously and
+// buffers the rest for a background writer. Events are never dropped: when
+// the buffer is full or the emitter is closed they are written in the
+// caller's goroutine instead.
+type Emitter struct {
+	appender Appender
+	logger   logging.Logger
+	config   EmitterConfig
+
+	mu     sync.RWMutex
+	closed bool
+	events chan Event
+	done   chan struct{}
+
+	written    atomic.Int64
+	failed     atomic.Int64
+	overflowed atomic.Int64
+}
+
+// NewEmitter creates an emitter on appender and starts its writer. Close
+// flushes buffered events and stops the writer.
+func NewEmitter(appender Appender, logger logging.Logger, config EmitterConfig) *Emitter {
+	defaults := DefaultEmitterConfig()
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaults.BufferSize
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = defaults.WriteTimeout
+	}
+
+	e := &Emitter{
+		appender: appender,
+		logger:   logger,
+		config:   config,
+		events:   make(chan Event, config.BufferSize),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Record writes a critical event and returns its error, or queues any other
+// event and returns once it is buffered. Failed asynchronous writes are
+// logged and counted.
+func (e *Emitter) Record(ctx context.Context, event Event) error {
+	tenantID, err := parseEventTenant(event)
+	if err != nil {
+		e.failed.Add(1)
+		e.logger.Error("Rejected audit event", err,
+			logging.String("action", event.Action),
+			logging.String("actor_id", event.ActorID))
+		return err
+	}
+
+	if event.Critical {
+		// The write must outlive a request that is cancelled once it responds
+		return e.write(context.WithoutCancel(ctx), tenantID, event)
+	}
+
+	e.mu.RLock()
+	if !e.closed {
+		select {
+		case e.events <- event:
+			e.mu.RUnlock()
+			return nil
+		default:
+			e.overflowed.Add(1)
+		}
+	}
+	e.mu.RUnlock()
+	return e.write(context.WithoutCancel(ctx), tenantID, event)
+}
+
+// Close stops accepting buffered events and waits until those already
+// queued are written or ctx is done
+func (e *Emitter) Close(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.events)
+	}
+	e.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("audit emitter closed with %d events unwritten: %w", len(e.events), ctx.Err())
+	}
+}
+
+// Stats returns the emitter's counters
+func (e *Emitter) Stats() EmitterStats {
+	return EmitterStats{
+		Written:    e.written.Load(),
+		Failed:     e.failed.Load(),
+		Overflowed: e.overflowed.Load(),
+	}
+}
+
+// run writes buffered events in the order they were queued
+func (e *Emitter) run() {
+	defer close(e.done)
+	for event := range e.events {
+		tenantID, _ := parseEventTenant(event)
+		_ = e.write(context.Background(), tenantID, event)
+	}
+}
+
+// write appends event to its tenant's chain
+func (e *Emitter) write(ctx context.Context, tenantID pgtype.UUID, event Event) error {
+	ctx, cancel := context.WithTimeout(ctx, e.config.WriteTimeout)
+	defer cancel()
+
+	details := make(map[string]interface{}, len(event.Details)+1)
+	for k, v := range event.Details {
+		details[k] = v
+	}
+	if event.Outcome != "" {
+		details["outcome"] = event.Outcome
+	}
+
+	var resourceID *string
+	if event.ResourceID != "" {
+		resourceID = &event.ResourceID
+	}
+
+	_, err := e.appender.CreateAudit(ctx, CreateAuditParams{
+		TenantID:     tenantID,
+		ActorType:    event.ActorType,
+		ActorID:      event.ActorID,
+		Action:       event.Action,
+		ResourceType: event.ResourceType,
+		ResourceID:   resourceID,
+		Details:      details,
+	})
+	if err != nil {
+		e.failed.Add(1)
+		e.logger.Error("Failed to write audit event", err,
+			logging.String("tenant_id", event.TenantID),
+			logging.String("action", event.Action),
+			logging.String("actor_id", event.ActorID),
+			logging.Bool("critical", event.Critical))
+		return err
+	}
+	e.written.Add(1)
+	return nil
+}
+
+// parseEventTenant checks the fields every audit record requires and parses
+// the tenant ID
+func parseEventTenant(event Event) (pgtype.UUID, error) {
+	var tenantID pgtype.UUID
+	if err := tenantID.Scan(event.TenantID); err != nil || !tenantID.Valid {
+		return tenantID, fmt.Errorf("%w: tenant ID %q is not a UUID", ErrInvalidEvent, event.TenantID)
+	}
+	if event.ActorType == "" || event.ActorID == "" || event.Action == "" || event.ResourceType == "" {
+		return tenantID, fmt.Errorf("%w: actor, action and resource type are required", ErrInvalidEvent)
+	}
+	return tenantID, nil
+}
@@ -0,0 +1,186 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+)
+
+const testEventTenant = "00000000-0000-0000-0000-000000000001"
+
+// memoryAppender records appended params. Appends of the action "slow" wait
+// until release is closed.
+type memoryAppender struct {
+	mu      sync.Mutex
+	params  []CreateAuditParams
+	err     error
+	release chan struct{}
+}
+
+func (a *memoryAppender) CreateAudit(ctx context.Context, params CreateAuditParams) (*queries.Audit, error) {
+	if params.Action == "slow" {
+		select {
+		case <-a.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil {
+		return nil, a.err
+	}
+	a.params = append(a.params, params)
+	return &queries.Audit{}, nil
+}
+
+func (a *memoryAppender) actions() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	actions := make([]string, len(a.params))
+	for i, p := range a.params {
+		actions[i] = p.Action
+	}
+	return actions
+}
+
+func testEvent(action string, critical bool) Event {
+	return Event{
+		TenantID:     testEventTenant,
+		ActorType:    "user",
+		ActorID:      "user-1",
+		Action:       action,
+		ResourceType: "workflows",
+		Outcome:      OutcomeSuccess,
+		Details:      map[string]interface{}{"path": "/api/v1/workflows"},
+		Critical:     critical,
+	}
+}
+
+func TestEmitter_Record(t *testing.T) {
+	appender := &memoryAppender{}
+	emitter := NewEmitter(appender, logging.NewLoggerWithWriter(&bytes.Buffer{}), EmitterConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := emitter.Record(ctx, testEvent("critical", true)); err != nil {
+		t.Fatalf("Record() critical unexpected error: %v", err)
+	}
+	if got := appender.actions(); len(got) != 1 || got[0] != "critical" {
+		t.Fatalf("critical event not written before Record returned: %v", got)
+	}
+
+	// Buffered events outlive the request they were recorded in
+	for _, action := range []string{"a", "b", "c"} {
+		if err := emitter.Record(ctx, testEvent(action, false)); err != nil {
+			t.Fatalf("Record() unexpected error: %v", err)
+		}
+	}
+	cancel()
+	if err := emitter.Close(context.Background()); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	got := appender.actions()
+	if len(got) != 4 || got[1] != "a" || got[2] != "b" || got[3] != "c" {
+		t.Errorf("written actions = %v, want critical, a, b, c", got)
+	}
+	p := appender.params[0]
+	if p.ResourceID != nil || p.Details["outcome"] != OutcomeSuccess || p.Details["path"] != "/api/v1/workflows" {
+		t.Errorf("written params = %+v, want outcome merged into details and no resource ID", p)
+	}
+	if stats := emitter.Stats(); stats.Written != 4 || stats.Failed != 0 {
+		t.Errorf("Stats() = %+v, want 4 written", stats)
+	}
+
+	// After Close events are written synchronously
+	if err := emitter.Record(context.Background(), testEvent("late", false)); err != nil {
+		t.Fatalf("Record() after Close unexpected error: %v", err)
+	}
+	if got := appender.actions(); got[len(got)-1] != "late" {
+		t.Errorf("event recorded after Close not written: %v", got)
+	}
+}
+
+func TestEmitter_Overflow(t *testing.T) {
+	appender := &memoryAppender{release: make(chan struct{})}
+	emitter := NewEmitter(appender, logging.NewLoggerWithWriter(&bytes.Buffer{}), EmitterConfig{BufferSize: 1})
+	ctx := context.Background()
+
+	// The writer blocks on the first event and the second fills the buffer
+	if err := emitter.Record(ctx, testEvent("slow", false)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(emitter.events) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := emitter.Record(ctx, testEvent("queued", false)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := emitter.Record(ctx, testEvent("overflow", false)); err != nil {
+		t.Fatalf("Record() on a full buffer unexpected error: %v", err)
+	}
+	if got := appender.actions(); len(got) != 1 || got[0] != "overflow" {
+		t.Errorf("overflowing event not written synchronously: %v", got)
+	}
+
+	close(appender.release)
+	if err := emitter.Close(ctx); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	if stats := emitter.Stats(); stats.Written != 3 || stats.Overflowed != 1 {
+		t.Errorf("Stats() = %+v, want 3 written and 1 overflowed", stats)
+	}
+}
+
+func TestEmitter_Errors(t *testing.T) {
+	appender := &memoryAppender{err: errors.New("database down")}
+	emitter := NewEmitter(appender, logging.NewLoggerWithWriter(&bytes.Buffer{}), EmitterConfig{})
+	ctx := context.Background()
+
+	invalid := testEvent("create", true)
+	invalid.TenantID = "tenant123"
+	if err := emitter.Record(ctx, invalid); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Record() with invalid tenant error = %v, want %v", err, ErrInvalidEvent)
+	}
+	anonymous := testEvent("create", false)
+	anonymous.ActorID = ""
+	if err := emitter.Record(ctx, anonymous); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Record() without actor error = %v, want %v", err, ErrInvalidEvent)
+	}
+
+	if err := emitter.Record(ctx, testEvent("create", true)); err == nil {
+		t.Error("Record() critical expected the append error")
+	}
+	if err := emitter.Record(ctx, testEvent("update", false)); err != nil {
+		t.Errorf("Record() buffered unexpected error: %v", err)
+	}
+	if err := emitter.Close(ctx); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	if stats := emitter.Stats(); stats.Failed != 4 || stats.Written != 0 {
+		t.Errorf("Stats() = %+v, want 4 failed", stats)
+	}
+}
+
+func TestEmitter_CloseTimeout(t *testing.T) {
+	appender := &memoryAppender{release: make(chan struct{})}
+	defer close(appender.release)
+	emitter := NewEmitter(appender, logging.NewLoggerWithWriter(&bytes.Buffer{}), EmitterConfig{})
+
+	if err := emitter.Record(context.Background(), testEvent("slow", false)); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := emitter.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,429 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Export formats
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+	FormatCEF   = "cef" // CEF in RFC 5424 syslog framing
+)
+
+// ErrExportInvalid is returned by VerifyExport for an export that does not
+// match its own hashes
+var ErrExportInvalid = errors.New("audit export invalid")
+
+// Filter selects a tenant's audit records. Empty fields match any record.
+type Filter struct {
+	TenantID     pgtype.UUID
+	Since        time.Time // inclusive
+	Until        time.Time // exclusive
+	ActorType    string
+	ActorID      string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	AfterSeq     int64 // cursor: only records after this seq
+}
+
+// SearchQuerier reads filtered audit records
+type SearchQuerier interface {
+	SearchAudits(ctx context.Context, arg queries.SearchAuditsParams) ([]queries.Audit, error)
+}
+
+// ExportRecord is an audit record with the chain hashes it was stored with.
+// Hash is SHA256(prev_hash || canonical record), so a receiver can recompute
+// it from the exported fields and check that consecutive records link.
+type ExportRecord struct {
+	ID  string `json:"id"`
+	Seq int64  `json:"seq"`
+	AuditRecord
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// NewExportRecord converts a stored audit record for export
+func NewExportRecord(a queries.Audit) (ExportRecord, error) {
+	record, err := convertDBAuditToRecord(a)
+	if err != nil {
+		return ExportRecord{}, err
+	}
+	return ExportRecord{
+		ID:          uuidToString(a.ID),
+		Seq:         a.Seq,
+		AuditRecord: record,
+		PrevHash:    hex.EncodeToString(a.PrevHash),
+		Hash:        hex.EncodeToString(a.Hash),
+	}, nil
+}
+
+// Outcome returns the outcome recorded in the details, if any
+func (r ExportRecord) Outcome() string {
+	var details struct {
+		Outcome string `json:"outcome"`
+	}
+	_ = json.Unmarshal(r.Details, &details)
+	return details.Outcome
+}
+
+// Verify recomputes the record's hash from its fields and prev_hash
+func (r ExportRecord) Verify() error {
+	prevHash, err := hex.DecodeString(r.PrevHash)
+	if err != nil {
+		return fmt.Errorf("%w: seq %d: prev_hash: %v", ErrExportInvalid, r.Seq, err)
+	}
+	hash, err := ComputeHash(prevHash, r.AuditRecord)
+	if err != nil {
+		return fmt.Errorf("%w: seq %d: %v", ErrExportInvalid, r.Seq, err)
+	}
+	if hex.EncodeToString(hash) != r.Hash {
+		return fmt.Errorf("%w: seq %d does not match its hash", ErrExportInvalid, r.Seq)
+	}
+	return nil
+}
+
+// Search returns up to limit records matching filter in seq order, and the
+// cursor for the next page, or 0 when there is none
+func Search(ctx context.Context, q SearchQuerier, filter Filter, limit int) ([]ExportRecord, int64, error) {
+	audits, err := q.SearchAudits(ctx, searchParams(filter, limit+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search audit records: %w", err)
+	}
+
+	var next int64
+	if len(audits) > limit {
+		audits = audits[:limit]
+		next = audits[limit-1].Seq
+	}
+	records := make([]ExportRecord, len(audits))
+	for i, a := range audits {
+		if records[i], err = NewExportRecord(a); err != nil {
+			return nil, 0, fmt.Errorf("failed to convert audit record %d: %w", a.Seq, err)
+		}
+	}
+	return records, next, nil
+}
+
+// Export streams every record matching filter to w in batches of batchSize
+// and returns the number written
+func Export(ctx context.Context, q SearchQuerier, filter Filter, batchSize int, w ExportWriter) (int, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	written := 0
+	for {
+		records, next, err := Search(ctx, q, filter, batchSize)
+		if err != nil {
+			return written, err
+		}
+		for _, r := range records {
+			if err := w.Write(r); err != nil {
+				return written, fmt.Errorf("failed to write audit record %d: %w", r.Seq, err)
+			}
+			written++
+		}
+		if next == 0 {
+			return written, w.Flush()
+		}
+		filter.AfterSeq = next
+	}
+}
+
+func searchParams(filter Filter, limit int) queries.SearchAuditsParams {
+	params := queries.SearchAuditsParams{
+		TenantID:     filter.TenantID,
+		AfterSeq:     filter.AfterSeq,
+		ActorType:    filter.ActorType,
+		ActorID:      filter.ActorID,
+		Action:       filter.Action,
+		ResourceType: filter.ResourceType,
+		ResourceID:   filter.ResourceID,
+		MaxResults:   int32(limit),
+	}
+	if !filter.Since.IsZero() {
+		params.Since = pgtype.Timestamptz{Time: filter.Since, Valid: true}
+	}
+	if !filter.Until.IsZero() {
+		params.Until = pgtype.Timestamptz{Time: filter.Until, Valid: true}
+	}
+	return params
+}
+
+// ExportWriter encodes exported records in one format
+type ExportWriter interface {
+	Write(record ExportRecord) error
+	Flush() error
+}
+
+// NewExportWriter returns a writer for format, one of FormatJSONL, FormatCSV
+// and FormatCEF
+func NewExportWriter(format string, w io.Writer) (ExportWriter, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonlWriter{w: bufio.NewWriter(w)}, nil
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatCEF:
+		hostname, err := os.Hostname()
+		if err != nil || hostname == "" {
+			hostname = "-"
+		}
+		return &cefWriter{w: bufio.NewWriter(w), hostname: hostname}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q: want %s, %s or %s", format, FormatJSONL, FormatCSV, FormatCEF)
+	}
+}
+
+// ExportContentType returns the MIME type of format
+func ExportContentType(format string) string {
+	switch format {
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatCSV:
+		return "text/csv"
+	default:
+		return "text/plain"
+	}
+}
+
+// jsonlWriter writes one JSON record per line
+type jsonlWriter struct {
+	w *bufio.Writer
+}
+
+func (j *jsonlWriter) Write(record ExportRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := j.w.Write(data); err != nil {
+		return err
+	}
+	return j.w.WriteByte('\n')
+}
+
+func (j *jsonlWriter) Flush() error { return j.w.Flush() }
+
+// csvColumns are the CSV export columns. An empty resource_id is exported
+// for a record without one.
+var csvColumns = []string{
+	"id", "seq", "tenant_id", "ts", "actor_type", "actor_id", "action",
+	"resource_type", "resource_id", "details", "prev_hash", "hash",
+}
+
+// csvWriter writes a header row and one row per record
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (c *csvWriter) Write(record ExportRecord) error {
+	if !c.headerWritten {
+		if err := c.w.Write(csvColumns); err != nil {
+			return err
+		}
+		c.headerWritten = true
+	}
+	resourceID := ""
+	if record.ResourceID != nil {
+		resourceID = *record.ResourceID
+	}
+	return c.w.Write([]string{
+		record.ID,
+		strconv.FormatInt(record.Seq, 10),
+		record.TenantID,
+		record.Timestamp.UTC().Format(time.RFC3339Nano),
+		record.ActorType,
+		record.ActorID,
+		record.Action,
+		record.ResourceType,
+		resourceID,
+		string(record.Details),
+		record.PrevHash,
+		record.Hash,
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	if !c.headerWritten {
+		if err := c.w.Write(csvColumns); err != nil {
+			return err
+		}
+		c.headerWritten = true
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// cefWriter writes CEF events in RFC 5424 syslog framing, facility log audit
+type cefWriter struct {
+	w        *bufio.Writer
+	hostname string
+}
+
+// syslogLogAudit is the RFC 5424 "log audit" facility
+const syslogLogAudit = 13
+
+func (c *cefWriter) Write(record ExportRecord) error {
+	outcome := record.Outcome()
+	// CEF severity 0-10 and the matching syslog severity
+	cefSeverity, syslogSeverity := 3, 6 // informational
+	switch outcome {
+	case OutcomeDenied:
+		cefSeverity, syslogSeverity = 7, 4 // warning
+	case OutcomeFailure:
+		cefSeverity, syslogSeverity = 5, 5 // notice
+	}
+
+	resourceID := ""
+	if record.ResourceID != nil {
+		resourceID = *record.ResourceID
+	}
+	extension := []string{
+		"rt=" + strconv.FormatInt(record.Timestamp.UnixMilli(), 10),
+		"externalId=" + cefValue(record.ID),
+		"suser=" + cefValue(record.ActorID),
+		"act=" + cefValue(record.Action),
+		"cs1Label=tenantId cs1=" + cefValue(record.TenantID),
+		"cs2Label=actorType cs2=" + cefValue(record.ActorType),
+		"cs3Label=resourceType cs3=" + cefValue(record.ResourceType),
+		"cs4Label=resourceId cs4=" + cefValue(resourceID),
+		"cs5Label=prevHash cs5=" + record.PrevHash,
+		"cs6Label=hash cs6=" + record.Hash,
+		"cn1Label=seq cn1=" + strconv.FormatInt(record.Seq, 10),
+		"msg=" + cefValue(string(record.Details)),
+	}
+	if outcome != "" {
+		extension = append(extension, "outcome="+cefValue(outcome))
+	}
+
+	_, err := fmt.Fprintf(c.w, "<%d>1 %s %s agentflow - - - CEF:0|AgentFlow|AgentFlow|1.0|%s|%s|%d|%s\n",
+		syslogLogAudit*8+syslogSeverity,
+		record.Timestamp.UTC().Format(time.RFC3339Nano),
+		c.hostname,
+		cefHeader(record.Action),
+		cefHeader(record.ResourceType+" "+record.Action),
+		cefSeverity,
+		strings.Join(extension, " "))
+	return err
+}
+
+func (c *cefWriter) Flush() error { return c.w.Flush() }
+
+// cefHeader escapes a CEF header field
+func cefHeader(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ").Replace(s)
+}
+
+// cefValue escapes a CEF extension value
+func cefValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
+
+// VerifyExport checks a JSONL or CSV export: every record must match its
+// hash, and a record that follows its predecessor by seq must link to it.
+// Filtered exports skip seqs, so gaps are allowed. It returns the number of
+// records checked.
+func VerifyExport(format string, r io.Reader) (int, error) {
+	var next func() (ExportRecord, error)
+	switch format {
+	case FormatJSONL:
+		decoder := json.NewDecoder(r)
+		next = func() (ExportRecord, error) {
+			var record ExportRecord
+			err := decoder.Decode(&record)
+			return record, err
+		}
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		header, err := reader.Read()
+		if err != nil {
+			return 0, fmt.Errorf("%w: missing CSV header: %v", ErrExportInvalid, err)
+		}
+		if strings.Join(header, ",") != strings.Join(csvColumns, ",") {
+			return 0, fmt.Errorf("%w: unexpected CSV columns", ErrExportInvalid)
+		}
+		next = func() (ExportRecord, error) {
+			row, err := reader.Read()
+			if err != nil {
+				return ExportRecord{}, err
+			}
+			return parseCSVRecord(row)
+		}
+	default:
+		return 0, fmt.Errorf("cannot verify %q exports: want %s or %s", format, FormatJSONL, FormatCSV)
+	}
+
+	checked := 0
+	var prev ExportRecord
+	for {
+		record, err := next()
+		if errors.Is(err, io.EOF) {
+			return checked, nil
+		}
+		if err != nil {
+			return checked, fmt.Errorf("%w: record %d: %v", ErrExportInvalid, checked+1, err)
+		}
+		if err := record.Verify(); err != nil {
+			return checked, err
+		}
+		if checked > 0 {
+			if record.Seq <= prev.Seq || record.TenantID != prev.TenantID {
+				return checked, fmt.Errorf("%w: seq %d follows seq %d", ErrExportInvalid, record.Seq, prev.Seq)
+			}
+			if record.Seq == prev.Seq+1 && record.PrevHash != prev.Hash {
+				return checked, fmt.Errorf("%w: seq %d does not link to seq %d", ErrExportInvalid, record.Seq, prev.Seq)
+			}
+		}
+		prev = record
+		checked++
+	}
+}
+
+// parseCSVRecord reads a row written by csvWriter
+func parseCSVRecord(row []string) (ExportRecord, error) {
+	seq, err := strconv.ParseInt(row[1], 10, 64)
+	if err != nil {
+		return ExportRecord{}, fmt.Errorf("invalid seq %q", row[1])
+	}
+	ts, err := time.Parse(time.RFC3339Nano, row[3])
+	if err != nil {
+		return ExportRecord{}, fmt.Errorf("invalid ts %q", row[3])
+	}
+	var resourceID *string
+	if row[8] != "" {
+		resourceID = &row[8]
+	}
+	return ExportRecord{
+		ID:  row[0],
+		Seq: seq,
+		AuditRecord: AuditRecord{
+			TenantID:     row[2],
+			ActorType:    row[4],
+			ActorID:      row[5],
+			Action:       row[6],
+			ResourceType: row[7],
+			ResourceID:   resourceID,
+			Details:      json.RawMessage(row[9]),
+			Timestamp:    ts,
+		},
+		PrevHash: row[10],
+		Hash:     row[11],
+	}, nil
+}
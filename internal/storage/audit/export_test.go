@@ -0,0 +1,198 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// searchQueries filters the mock chain as SearchAudits does
+type searchQueries struct {
+	*MockQueries
+}
+
+func (q *searchQueries) SearchAudits(ctx context.Context, arg queries.SearchAuditsParams) ([]queries.Audit, error) {
+	var result []queries.Audit
+	for _, a := range q.audits {
+		switch {
+		case a.TenantID != arg.TenantID || a.Seq <= arg.AfterSeq,
+			arg.Since.Valid && a.Ts.Time.Before(arg.Since.Time),
+			arg.Until.Valid && !a.Ts.Time.Before(arg.Until.Time),
+			arg.ActorID != "" && a.ActorID != arg.ActorID,
+			arg.Action != "" && a.Action != arg.Action,
+			arg.ResourceID != "" && a.ResourceID.String != arg.ResourceID:
+			continue
+		}
+		if len(result) == int(arg.MaxResults) {
+			break
+		}
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+// newSearchChain appends n records alternating between two actors and actions
+func newSearchChain(t *testing.T, n int) (*searchQueries, pgtype.UUID) {
+	t.Helper()
+	q := &searchQueries{MockQueries: &MockQueries{}}
+	tenantID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	service := NewService(q.MockQueries)
+	for i := 0; i < n; i++ {
+		resourceID := "wf-1"
+		params := CreateAuditParams{
+			TenantID:     tenantID,
+			ActorType:    "user",
+			ActorID:      []string{"alice", "bob"}[i%2],
+			Action:       []string{"api.post", "api.delete"}[i%2],
+			ResourceType: "workflows",
+			Details:      map[string]interface{}{"outcome": []string{OutcomeSuccess, OutcomeDenied}[i%2], "note": "a=b|c\nd"},
+		}
+		if i%3 == 0 {
+			params.ResourceID = &resourceID
+		}
+		if _, err := service.CreateAudit(context.Background(), params); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return q, tenantID
+}
+
+func TestSearch(t *testing.T) {
+	q, tenantID := newSearchChain(t, 7)
+	ctx := context.Background()
+
+	records, next, err := Search(ctx, q, Filter{TenantID: tenantID, ActorID: "alice"}, 3)
+	if err != nil {
+		t.Fatalf("Search() unexpected error: %v", err)
+	}
+	if len(records) != 3 || records[0].Seq != 1 || records[2].Seq != 5 || next != 5 {
+		t.Fatalf("Search() = %d records to seq %d, next %d, want seq 1, 3, 5 and next 5", len(records), records[len(records)-1].Seq, next)
+	}
+	for _, r := range records {
+		if err := r.Verify(); err != nil {
+			t.Errorf("record %d Verify() unexpected error: %v", r.Seq, err)
+		}
+	}
+
+	records, next, err = Search(ctx, q, Filter{TenantID: tenantID, ActorID: "alice", AfterSeq: next}, 3)
+	if err != nil || len(records) != 1 || records[0].Seq != 7 || next != 0 {
+		t.Errorf("Search() second page = %d records, next %d, %v, want seq 7 and no next page", len(records), next, err)
+	}
+
+	future := Filter{TenantID: tenantID, Since: time.Now().Add(time.Hour)}
+	if records, _, err := Search(ctx, q, future, 10); err != nil || len(records) != 0 {
+		t.Errorf("Search() since the future = %d records, %v, want none", len(records), err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	q, tenantID := newSearchChain(t, 9)
+	ctx := context.Background()
+
+	for _, format := range []string{FormatJSONL, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewExportWriter(format, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n, err := Export(ctx, q, Filter{TenantID: tenantID}, 4, w)
+			if err != nil || n != 9 {
+				t.Fatalf("Export() = %d, %v, want 9 records", n, err)
+			}
+
+			checked, err := VerifyExport(format, bytes.NewReader(buf.Bytes()))
+			if err != nil || checked != 9 {
+				t.Fatalf("VerifyExport() = %d, %v, want 9 records verified", checked, err)
+			}
+
+			// Altering any exported field breaks the record's hash
+			tampered := strings.Replace(buf.String(), "bob", "eve", 1)
+			if _, err := VerifyExport(format, strings.NewReader(tampered)); !errors.Is(err, ErrExportInvalid) {
+				t.Errorf("VerifyExport() of altered export error = %v, want %v", err, ErrExportInvalid)
+			}
+		})
+	}
+
+	// A filtered export skips seqs but still verifies record by record
+	var buf bytes.Buffer
+	w, _ := NewExportWriter(FormatJSONL, &buf)
+	if n, err := Export(ctx, q, Filter{TenantID: tenantID, Action: "api.delete"}, 0, w); err != nil || n != 4 {
+		t.Fatalf("Export() filtered = %d, %v, want 4 records", n, err)
+	}
+	if checked, err := VerifyExport(FormatJSONL, &buf); err != nil || checked != 4 {
+		t.Errorf("VerifyExport() filtered = %d, %v, want 4 records verified", checked, err)
+	}
+
+	// Renumbering records to hide a dropped one breaks the link between them
+	buf.Reset()
+	w, _ = NewExportWriter(FormatJSONL, &buf)
+	if _, err := Export(ctx, q, Filter{TenantID: tenantID}, 0, w); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+	dropped := lines[0] + lines[1] + strings.Join(lines[3:], "")
+	if _, err := VerifyExport(FormatJSONL, strings.NewReader(strings.Replace(dropped, `"seq":4`, `"seq":3`, 1))); !errors.Is(err, ErrExportInvalid) {
+		t.Errorf("VerifyExport() with a record dropped error = %v, want %v", err, ErrExportInvalid)
+	}
+}
+
+func TestCEFWriter(t *testing.T) {
+	q, tenantID := newSearchChain(t, 2)
+	var buf bytes.Buffer
+	w := &cefWriter{w: bufio.NewWriter(&buf), hostname: "host-1"}
+	if _, err := Export(context.Background(), q, Filter{TenantID: tenantID}, 0, w); err != nil {
+		t.Fatalf("Export() unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("CEF export has %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	hash := q.audits[1].Hash
+	for _, want := range []string{
+		"<108>1 ", // log audit, warning
+		" host-1 agentflow - - - CEF:0|AgentFlow|AgentFlow|1.0|api.delete|workflows api.delete|7|",
+		"suser=bob",
+		"outcome=denied",
+		"cn1Label=seq cn1=2",
+		`"note":"a\=b|c\\nd"`, // JSON escapes the newline, CEF the backslash and =
+		"cs6Label=hash cs6=" + hex.EncodeToString(hash),
+	} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("CEF line missing %q:\n%s", want, lines[1])
+		}
+	}
+	if !strings.HasPrefix(lines[0], "<110>1 ") {
+		t.Errorf("CEF line for a successful event = %s, want informational priority 110", lines[0])
+	}
+}
+
+func TestNewExportWriter(t *testing.T) {
+	if _, err := NewExportWriter("xml", &bytes.Buffer{}); err == nil {
+		t.Error("NewExportWriter() expected error for an unknown format")
+	}
+
+	// An empty CSV export still has its header
+	var buf bytes.Buffer
+	w, _ := NewExportWriter(FormatCSV, &buf)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != 1 || rows[0][0] != "id" {
+		t.Errorf("empty CSV export = %v, %v, want the header only", rows, err)
+	}
+	if _, err := VerifyExport(FormatCEF, &buf); err == nil {
+		t.Error("VerifyExport() expected error for CEF")
+	}
+}
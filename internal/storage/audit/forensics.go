@@ -0,0 +1,268 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Kinds of chain break reported by forensic analysis
+const (
+	BreakModified  = "modified"
+	BreakDeleted   = "deleted"
+	BreakInserted  = "inserted"
+	BreakReordered = "reordered"
+)
+
+// AffectedRecord identifies a stored record involved in a chain break
+type AffectedRecord struct {
+	ID        string    `json:"id"`
+	Seq       int64     `json:"seq"`
+	Timestamp time.Time `json:"ts"`
+}
+
+// ChainBreak is one incident found by forensic analysis. For deletions the
+// records are the ones either side of the gap and the window runs between
+// them; otherwise the window spans the affected records' timestamps.
+type ChainBreak struct {
+	Kind           string           `json:"kind"`
+	Description    string           `json:"description"`
+	Records        []AffectedRecord `json:"records"`
+	MissingFromSeq int64            `json:"missing_from_seq,omitempty"`
+	MissingToSeq   int64            `json:"missing_to_seq,omitempty"`
+	WindowStart    time.Time        `json:"window_start"`
+	WindowEnd      time.Time        `json:"window_end"`
+}
+
+// ForensicReport is the incident report for one tenant's chain
+type ForensicReport struct {
+	TenantID     string       `json:"tenant_id"`
+	GeneratedAt  time.Time    `json:"generated_at"`
+	TotalRecords int          `json:"total_records"`
+	FromSeq      int64        `json:"from_seq,omitempty"`
+	ToSeq        int64        `json:"to_seq,omitempty"`
+	Intact       bool         `json:"intact"`
+	Breaks       []ChainBreak `json:"breaks"`
+}
+
+// Forensics scans a tenant's whole chain without stopping at the first break
+// and classifies every break it finds. A record may link to any other, so
+// the hashes of the whole chain are held in memory.
+func (v *Verifier) Forensics(ctx context.Context, tenantID pgtype.UUID) (ForensicReport, error) {
+	var entries []chainEntry
+	var after int64
+	for {
+		batch, err := v.reader.ListAuditRange(ctx, queries.ListAuditRangeParams{
+			TenantID:  tenantID,
+			AfterSeq:  after,
+			ToSeq:     math.MaxInt64,
+			BatchSize: v.batchSize,
+		})
+		if err != nil {
+			return ForensicReport{}, fmt.Errorf("failed to retrieve audit chain: %w", err)
+		}
+		for _, a := range batch {
+			entries = append(entries, newChainEntry(a))
+		}
+		if len(batch) < int(v.batchSize) {
+			break
+		}
+		after = batch[len(batch)-1].Seq
+	}
+
+	report := analyzeChain(entries)
+	report.TenantID = uuidToString(tenantID)
+	return report, nil
+}
+
+// AnalyzeChain classifies every break in audits, which must be one tenant's
+// records in seq order
+func AnalyzeChain(audits []queries.Audit) ForensicReport {
+	entries := make([]chainEntry, len(audits))
+	for i, a := range audits {
+		entries[i] = newChainEntry(a)
+	}
+	report := analyzeChain(entries)
+	if len(audits) > 0 {
+		report.TenantID = uuidToString(audits[0].TenantID)
+	}
+	return report
+}
+
+// chainEntry is the part of a record forensic analysis keeps
+type chainEntry struct {
+	record    AffectedRecord
+	prevHash  []byte
+	hash      []byte
+	contentOK bool // hash matches the record's content and stored prev hash
+}
+
+func newChainEntry(a queries.Audit) chainEntry {
+	e := chainEntry{
+		record:   AffectedRecord{ID: uuidToString(a.ID), Seq: a.Seq, Timestamp: a.Ts.Time.UTC()},
+		prevHash: a.PrevHash,
+		hash:     a.Hash,
+	}
+	if record, err := convertDBAuditToRecord(a); err == nil {
+		hash, err := ComputeHash(a.PrevHash, record)
+		e.contentOK = err == nil && equalBytes(hash, a.Hash)
+	}
+	return e
+}
+
+// pendingBreak is a break located by position in the chain
+type pendingBreak struct {
+	kind        string
+	description string
+	positions   []int
+	missingFrom int64
+	missingTo   int64
+}
+
+// analyzeChain checks each record's own hash and its link to the record
+// before it. A broken link is classified by where the stored prev hash
+// points: past records nothing links to (inserted), to another stored record
+// (reordered), or to no stored record (deleted, or a rehashed record).
+func analyzeChain(entries []chainEntry) ForensicReport {
+	report := ForensicReport{
+		GeneratedAt:  time.Now().UTC(),
+		TotalRecords: len(entries),
+		Breaks:       []ChainBreak{},
+	}
+	if len(entries) == 0 {
+		report.Intact = true
+		return report
+	}
+	report.FromSeq = entries[0].record.Seq
+	report.ToSeq = entries[len(entries)-1].record.Seq
+
+	owner := make(map[string]int, len(entries))
+	linked := make(map[string]int, len(entries))
+	for i, e := range entries {
+		if _, ok := owner[string(e.hash)]; !ok {
+			owner[string(e.hash)] = i
+		}
+		linked[string(e.prevHash)]++
+	}
+	hasSuccessor := func(i int) bool { return linked[string(entries[i].hash)] > 0 }
+
+	var pending []pendingBreak
+	add := func(kind, description string, positions ...int) {
+		pending = append(pending, pendingBreak{kind: kind, description: description, positions: positions})
+	}
+
+	for i, e := range entries {
+		if !e.contentOK {
+			add(BreakModified, "record content no longer matches its stored hash", i)
+		}
+
+		expectedSeq := int64(1)
+		var prevHash []byte
+		if i > 0 {
+			expectedSeq = entries[i-1].record.Seq + 1
+			prevHash = entries[i-1].hash
+		}
+		if e.record.Seq > expectedSeq {
+			positions := []int{i}
+			if i > 0 {
+				positions = []int{i - 1, i}
+			}
+			pending = append(pending, pendingBreak{
+				kind:        BreakDeleted,
+				description: fmt.Sprintf("records with seq %d to %d are missing", expectedSeq, e.record.Seq-1),
+				positions:   positions,
+				missingFrom: expectedSeq,
+				missingTo:   e.record.Seq - 1,
+			})
+			continue
+		}
+		if equalBytes(e.prevHash, prevHash) {
+			continue
+		}
+
+		j, known := owner[string(e.prevHash)]
+		switch {
+		case known && j < i-1:
+			var between []int
+			inserted := true
+			for k := j + 1; k < i; k++ {
+				between = append(between, k)
+				inserted = inserted && !hasSuccessor(k)
+			}
+			if inserted {
+				add(BreakInserted, "record is not part of the chain: no record links to it and the next record links past it", between...)
+			} else {
+				add(BreakReordered, "record is out of chain order: the next record links past it", between...)
+			}
+		case known && j > i:
+			add(BreakReordered, "record links to a record stored after it", i)
+		case known:
+			add(BreakModified, "record links to itself", i)
+		case i > 0 && linked[string(prevHash)] > 0:
+			add(BreakInserted, "record does not link to the record before it, which the chain continues from elsewhere", i)
+		case i > 0:
+			add(BreakModified, "the next record links to a different hash: the record was rewritten and rehashed, or records after it were deleted and the rest renumbered", i-1)
+		default:
+			add(BreakDeleted, "the first record links to a record that is not stored: earlier records were deleted and the rest renumbered", i)
+		}
+	}
+
+	report.Breaks = mergeBreaks(entries, pending)
+	report.Intact = len(report.Breaks) == 0
+	return report
+}
+
+// mergeBreaks orders breaks by position and joins adjacent breaks of the
+// same kind, except deletions, into one incident
+func mergeBreaks(entries []chainEntry, pending []pendingBreak) []ChainBreak {
+	sort.SliceStable(pending, func(a, b int) bool {
+		return pending[a].positions[0] < pending[b].positions[0]
+	})
+
+	var merged []pendingBreak
+	for _, p := range pending {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			end := last.positions[len(last.positions)-1]
+			if p.kind == last.kind && p.kind != BreakDeleted && p.positions[0] <= end+1 {
+				last.positions = append(last.positions, p.positions...)
+				continue
+			}
+		}
+		merged = append(merged, p)
+	}
+
+	breaks := make([]ChainBreak, 0, len(merged))
+	for _, p := range merged {
+		sort.Ints(p.positions)
+		b := ChainBreak{
+			Kind:           p.kind,
+			Description:    p.description,
+			MissingFromSeq: p.missingFrom,
+			MissingToSeq:   p.missingTo,
+		}
+		for k, pos := range p.positions {
+			if k > 0 && pos == p.positions[k-1] {
+				continue
+			}
+			b.Records = append(b.Records, entries[pos].record)
+		}
+		b.WindowStart = b.Records[0].Timestamp
+		b.WindowEnd = b.Records[0].Timestamp
+		for _, r := range b.Records[1:] {
+			if r.Timestamp.Before(b.WindowStart) {
+				b.WindowStart = r.Timestamp
+			}
+			if r.Timestamp.After(b.WindowEnd) {
+				b.WindowEnd = r.Timestamp
+			}
+		}
+		breaks = append(breaks, b)
+	}
+	return breaks
+}
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// rehash recomputes a record's hash from its stored prev hash, as someone
+// rewriting it would
+func rehash(t *testing.T, a *queries.Audit) {
+	t.Helper()
+	record, err := convertDBAuditToRecord(*a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Hash, err = ComputeHash(a.PrevHash, record); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// breakSummary is the kind and affected seqs of a break
+type breakSummary struct {
+	kind string
+	seqs []int64
+}
+
+func summarize(breaks []ChainBreak) []breakSummary {
+	var result []breakSummary
+	for _, b := range breaks {
+		s := breakSummary{kind: b.Kind}
+		for _, r := range b.Records {
+			s.seqs = append(s.seqs, r.Seq)
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func TestAnalyzeChain(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, q *rangeQueries)
+		want   []breakSummary
+	}{
+		{name: "intact chain"},
+		{
+			name: "every modified record is reported",
+			tamper: func(t *testing.T, q *rangeQueries) {
+				q.audits[2].ActorID = "intruder"
+				q.audits[8].Action = "delete"
+			},
+			want: []breakSummary{{BreakModified, []int64{3}}, {BreakModified, []int64{9}}},
+		},
+		{
+			name: "modified and rehashed record",
+			tamper: func(t *testing.T, q *rangeQueries) {
+				q.audits[4].ActorID = "intruder"
+				rehash(t, &q.audits[4])
+			},
+			want: []breakSummary{{BreakModified, []int64{5}}},
+		},
+		{
+			name: "deleted records",
+			tamper: func(t *testing.T, q *rangeQueries) {
+				q.audits = append(q.audits[:5], q.audits[7:]...)
+			},
+			want: []breakSummary{{BreakDeleted, []int64{5, 8}}},
+		},
+		{
+			name: "inserted record",
+			tamper: func(t *testing.T, q *rangeQueries) {
+				forged := q.audits[3]
+				forged.ID = pgtype.UUID{Bytes: [16]byte{99}, Valid: true}
+				forged.Seq = 5
+				forged.PrevHash = q.audits[3].Hash
+				forged.ActorID = "intruder"
+				rehash(t, &forged)
+				for i := 4; i < len(q.audits); i++ {
+					q.audits[i].Seq++
+				}
+				q.audits = append(q.audits[:4], append([]queries.Audit{forged}, q.audits[4:]...)...)
+			},
+			want: []breakSummary{{BreakInserted, []int64{5}}},
+		},
+		{
+			name: "reordered records",
+			tamper: func(t *testing.T, q *rangeQueries) {
+				q.audits[6], q.audits[7] = q.audits[7], q.audits[6]
+				q.audits[6].Seq, q.audits[7].Seq = 7, 8
+			},
+			want: []breakSummary{{BreakReordered, []int64{7, 8}}},
+		},
+		{
+			name: "breaks of different kinds",
+			tamper: func(t *testing.T, q *rangeQueries) {
+				q.audits[1].Details = []byte(`{"step": 100}`)
+				q.audits = append(q.audits[:9], q.audits[10:]...)
+			},
+			want: []breakSummary{{BreakModified, []int64{2}}, {BreakDeleted, []int64{9, 11}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, tenantID := newTestChain(t, 12)
+			if tt.tamper != nil {
+				tt.tamper(t, q)
+			}
+
+			report, err := NewVerifier(q).WithBatchSize(5).Forensics(context.Background(), tenantID)
+			if err != nil {
+				t.Fatalf("Forensics() unexpected error: %v", err)
+			}
+			if report.Intact != (tt.want == nil) {
+				t.Errorf("Forensics() Intact = %v, want %v", report.Intact, tt.want == nil)
+			}
+			if got := summarize(report.Breaks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Forensics() breaks = %v, want %v", got, tt.want)
+			}
+			if report.TotalRecords != len(q.audits) || report.TenantID != uuidToString(tenantID) {
+				t.Errorf("Forensics() report covers %d records of %s, want %d of %s",
+					report.TotalRecords, report.TenantID, len(q.audits), uuidToString(tenantID))
+			}
+		})
+	}
+}
+
+func TestAnalyzeChain_DeletionWindow(t *testing.T) {
+	q, _ := newTestChain(t, 6)
+	before, after := q.audits[1], q.audits[4]
+	report := AnalyzeChain(append(q.audits[:2], q.audits[4:]...))
+
+	if len(report.Breaks) != 1 {
+		t.Fatalf("AnalyzeChain() found %d breaks, want 1", len(report.Breaks))
+	}
+	b := report.Breaks[0]
+	if b.MissingFromSeq != 3 || b.MissingToSeq != 4 {
+		t.Errorf("missing seqs = %d to %d, want 3 to 4", b.MissingFromSeq, b.MissingToSeq)
+	}
+	if !b.WindowStart.Equal(before.Ts.Time) || !b.WindowEnd.Equal(after.Ts.Time) {
+		t.Errorf("window = %v to %v, want %v to %v", b.WindowStart, b.WindowEnd, before.Ts.Time, after.Ts.Time)
+	}
+	if b.Records[0].ID != uuidToString(before.ID) || b.Records[1].ID != uuidToString(after.ID) {
+		t.Errorf("records = %+v, want the records either side of the gap", b.Records)
+	}
+}
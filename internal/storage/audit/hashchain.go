@@ -44,6 +44,22 @@ type VerificationResult struct {
 	TotalRecords       int
 	FirstTamperedIndex *int
 	ErrorMessage       string
+
+	// Set by streaming verification
+	FromSeq          int64         // first record verified
+	ToSeq            int64         // last record verified
+	CheckpointSeq    int64         // checkpoint verification resumed after, 0 for none
+	NewCheckpointSeq int64         // checkpoint recorded by this run, 0 for none
+	Duration         time.Duration // time spent verifying
+	AnchorsChecked   int           // external anchors the chain was compared with
+}
+
+// Throughput returns the records verified per second
+func (r VerificationResult) Throughput() int {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return int(float64(r.TotalRecords) / r.Duration.Seconds())
 }
 
 // VerifyHashChain validates the integrity of an entire audit chain
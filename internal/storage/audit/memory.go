@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryRecorder is a Recorder that keeps events in memory, for tests and
+// local development
+type MemoryRecorder struct {
+	mu     sync.Mutex
+	events []Event
+	err    error
+}
+
+// NewMemoryRecorder creates an empty in-memory recorder
+func NewMemoryRecorder() *MemoryRecorder {
+	return &MemoryRecorder{}
+}
+
+// Record keeps event, or returns the error set with SetError
+func (m *MemoryRecorder) Record(ctx context.Context, event Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.events = append(m.events, event)
+	return nil
+}
+
+// SetError makes Record fail with err; nil makes it record again
+func (m *MemoryRecorder) SetError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+// Events returns the recorded events, oldest first
+func (m *MemoryRecorder) Events() []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Event(nil), m.events...)
+}
+
+// Last returns the most recently recorded event, or the zero Event
+func (m *MemoryRecorder) Last() Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.events) == 0 {
+		return Event{}
+	}
+	return m.events[len(m.events)-1]
+}
@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// Merkle trees follow RFC 6962: leaves and interior nodes are hashed with
+// distinct prefixes so a leaf can never be passed off as a node
+
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// MerkleLeafHash hashes data as a tree leaf
+func MerkleLeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// MerkleRoot returns the root over leaf hashes. The root of an empty tree is
+// the hash of no data.
+func MerkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	}
+	return merkleSubtree(leaves)
+}
+
+func merkleSubtree(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := merkleSplit(len(leaves))
+	return merkleNodeHash(merkleSubtree(leaves[:k]), merkleSubtree(leaves[k:]))
+}
+
+// merkleSplit returns the largest power of two smaller than n
+func merkleSplit(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// MerkleInclusionProof returns the audit path proving leaves[index] is in the
+// tree over leaves, ordered from the leaf up
+func MerkleInclusionProof(leaves [][]byte, index int) ([][]byte, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("leaf index %d out of range for tree size %d", index, len(leaves))
+	}
+	return merklePath(leaves, index), nil
+}
+
+func merklePath(leaves [][]byte, index int) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := merkleSplit(len(leaves))
+	if index < k {
+		return append(merklePath(leaves[:k], index), merkleSubtree(leaves[k:]))
+	}
+	return append(merklePath(leaves[k:], index-k), merkleSubtree(leaves[:k]))
+}
+
+// VerifyMerkleInclusion reports whether proof shows leafHash at index in a
+// tree of size leaves with the given root
+func VerifyMerkleInclusion(leafHash []byte, index, size int64, proof [][]byte, root []byte) bool {
+	if index < 0 || index >= size {
+		return false
+	}
+	// Walk up from the leaf as in RFC 9162 section 2.1.3.2
+	fn, sn := index, size-1
+	hash := leafHash
+	for _, sibling := range proof {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			hash = merkleNodeHash(sibling, hash)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			hash = merkleNodeHash(hash, sibling)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && equalBytes(hash, root)
+}
+
+// merkleFrontier tracks the perfect subtrees on the right edge of a growing
+// tree, giving the root after each append without rehashing every leaf
+type merkleFrontier struct {
+	hashes  [][]byte
+	heights []int
+	leaves  int64
+}
+
+func (f *merkleFrontier) size() int64 {
+	return f.leaves
+}
+
+func (f *merkleFrontier) push(leafHash []byte) {
+	f.leaves++
+	f.hashes = append(f.hashes, leafHash)
+	f.heights = append(f.heights, 0)
+	for n := len(f.hashes); n > 1 && f.heights[n-2] == f.heights[n-1]; n = len(f.hashes) {
+		f.hashes = append(f.hashes[:n-2], merkleNodeHash(f.hashes[n-2], f.hashes[n-1]))
+		f.heights = append(f.heights[:n-2], f.heights[n-2]+1)
+	}
+}
+
+func (f *merkleFrontier) root() []byte {
+	if len(f.hashes) == 0 {
+		return MerkleRoot(nil)
+	}
+	root := f.hashes[len(f.hashes)-1]
+	for i := len(f.hashes) - 2; i >= 0; i-- {
+		root = merkleNodeHash(f.hashes[i], root)
+	}
+	return root
+}
+
+// merkleProofRanges returns the leaf ranges [lo, hi) whose subtree hashes
+// make up the inclusion proof for index, in the order MerkleInclusionProof
+// returns them. Proofs can then be built while streaming the leaves.
+func merkleProofRanges(index, size int64) [][2]int64 {
+	var ranges [][2]int64
+	lo, hi := int64(0), size
+	for hi-lo > 1 {
+		k := int64(merkleSplit(int(hi - lo)))
+		if index < lo+k {
+			ranges = append(ranges, [2]int64{lo + k, hi})
+			hi = lo + k
+		} else {
+			ranges = append(ranges, [2]int64{lo, lo + k})
+			lo += k
+		}
+	}
+	// Collected from the root down; proofs run from the leaf up
+	for i, j := 0, len(ranges)-1; i < j; i, j = i+1, j-1 {
+		ranges[i], ranges[j] = ranges[j], ranges[i]
+	}
+	return ranges
+}
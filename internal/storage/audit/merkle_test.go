@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func testLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		leaves[i] = MerkleLeafHash([]byte(fmt.Sprintf("leaf-%d", i)))
+	}
+	return leaves
+}
+
+func TestMerkleRoot(t *testing.T) {
+	leaves := testLeaves(3)
+	want := merkleNodeHash(merkleNodeHash(leaves[0], leaves[1]), leaves[2])
+	if got := MerkleRoot(leaves); !bytes.Equal(got, want) {
+		t.Errorf("MerkleRoot() = %x, want %x", got, want)
+	}
+	if got := MerkleRoot(leaves[:1]); !bytes.Equal(got, leaves[0]) {
+		t.Errorf("MerkleRoot() of one leaf = %x, want the leaf", got)
+	}
+	if bytes.Equal(MerkleLeafHash([]byte("x")), merkleNodeHash(nil, []byte("x"))) {
+		t.Error("leaf and node hashes must be domain separated")
+	}
+}
+
+func TestMerkleFrontier(t *testing.T) {
+	leaves := testLeaves(33)
+	f := &merkleFrontier{}
+	if !bytes.Equal(f.root(), MerkleRoot(nil)) {
+		t.Error("empty frontier root differs from MerkleRoot(nil)")
+	}
+	for i, leaf := range leaves {
+		f.push(leaf)
+		if got, want := f.root(), MerkleRoot(leaves[:i+1]); !bytes.Equal(got, want) {
+			t.Fatalf("root after %d leaves = %x, want %x", i+1, got, want)
+		}
+	}
+}
+
+func TestMerkleInclusionProof(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		leaves := testLeaves(size)
+		root := MerkleRoot(leaves)
+		for i := 0; i < size; i++ {
+			proof, err := MerkleInclusionProof(leaves, i)
+			if err != nil {
+				t.Fatalf("MerkleInclusionProof(%d, %d) unexpected error: %v", size, i, err)
+			}
+			if !VerifyMerkleInclusion(leaves[i], int64(i), int64(size), proof, root) {
+				t.Errorf("proof for leaf %d of %d did not verify", i, size)
+			}
+			if size > 1 && VerifyMerkleInclusion(leaves[(i+1)%size], int64(i), int64(size), proof, root) {
+				t.Errorf("proof for leaf %d of %d verified another leaf", i, size)
+			}
+			if VerifyMerkleInclusion(leaves[i], int64(i), int64(size), proof, MerkleLeafHash(root)) {
+				t.Errorf("proof for leaf %d of %d verified against another root", i, size)
+			}
+		}
+	}
+
+	if _, err := MerkleInclusionProof(testLeaves(2), 2); err == nil {
+		t.Error("MerkleInclusionProof() expected error for an index out of range")
+	}
+}
+
+func TestMerkleProofRanges(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		leaves := testLeaves(size)
+		for i := 0; i < size; i++ {
+			want, _ := MerkleInclusionProof(leaves, i)
+			ranges := merkleProofRanges(int64(i), int64(size))
+			if len(ranges) != len(want) {
+				t.Fatalf("merkleProofRanges(%d, %d) returned %d ranges, want %d", i, size, len(ranges), len(want))
+			}
+			for j, r := range ranges {
+				if got := MerkleRoot(leaves[r[0]:r[1]]); !bytes.Equal(got, want[j]) {
+					t.Errorf("range %d of proof for leaf %d of %d = %v, does not hash to the proof node", j, i, size, r)
+				}
+			}
+		}
+	}
+}
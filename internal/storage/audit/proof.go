@@ -0,0 +1,318 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// treeHeadVersion prefixes the signed tree head payload so the format can change
+const treeHeadVersion = "agentflow.audit-tree-head/v1"
+
+var (
+	// ErrAuditNotFound is returned when proving a record that does not exist
+	ErrAuditNotFound = errors.New("audit record not found")
+	// ErrTreeHeadMismatch is returned when the chain no longer hashes to a tree head signed earlier
+	ErrTreeHeadMismatch = errors.New("audit records no longer match the signed tree head")
+	// ErrProofInvalid is returned by VerifyInclusionProof for a proof that does not hold
+	ErrProofInvalid = errors.New("inclusion proof invalid")
+)
+
+// TreeHead is a signed Merkle root over a tenant's first TreeSize audit
+// records. Leaf i is the hash of the record with seq i+1.
+type TreeHead struct {
+	TenantID  string    `json:"tenant_id"`
+	TreeSize  int64     `json:"tree_size"`
+	Root      string    `json:"root"`
+	SignedAt  time.Time `json:"signed_at"`
+	KeyID     string    `json:"key_id"`
+	Signature string    `json:"signature"`
+}
+
+// InclusionProof shows that one audit record is in a tenant's log without
+// revealing any other record
+type InclusionProof struct {
+	AuditID    string      `json:"audit_id"`
+	Seq        int64       `json:"seq"`
+	Record     AuditRecord `json:"record"`
+	PrevHash   string      `json:"prev_hash"`
+	RecordHash string      `json:"record_hash"`
+	Path       []string    `json:"path"`
+	TreeHead   TreeHead    `json:"tree_head"`
+}
+
+// SignTreeHead signs root as the tree head over size records of tenantID
+func (s *AnchorSigner) SignTreeHead(tenantID string, size int64, root []byte) TreeHead {
+	head := TreeHead{
+		TenantID: tenantID,
+		TreeSize: size,
+		Root:     hex.EncodeToString(root),
+		SignedAt: time.Now().UTC().Truncate(time.Microsecond), // stored as a timestamptz
+		KeyID:    s.keyID,
+	}
+	head.Signature = hex.EncodeToString(ed25519.Sign(s.key, treeHeadPayload(head)))
+	return head
+}
+
+// VerifyTreeHead checks that head was signed by publicKey and has not been altered
+func VerifyTreeHead(publicKey ed25519.PublicKey, head TreeHead) error {
+	if head.KeyID != AnchorKeyID(publicKey) {
+		return fmt.Errorf("%w: signed with key %s", ErrAnchorSignature, head.KeyID)
+	}
+	signature, err := hex.DecodeString(head.Signature)
+	if err != nil || !ed25519.Verify(publicKey, treeHeadPayload(head), signature) {
+		return ErrAnchorSignature
+	}
+	return nil
+}
+
+func treeHeadPayload(h TreeHead) []byte {
+	return []byte(treeHeadVersion + "\n" +
+		h.TenantID + "\n" +
+		strconv.FormatInt(h.TreeSize, 10) + "\n" +
+		h.Root + "\n" +
+		h.SignedAt.UTC().Format(time.RFC3339Nano))
+}
+
+// VerifyInclusionProof checks proof on its own: the tree head signature, the
+// record against its hash, and the Merkle path from the record to the root.
+// It needs nothing but the proof and the public key.
+func VerifyInclusionProof(publicKey ed25519.PublicKey, proof InclusionProof) error {
+	if err := VerifyTreeHead(publicKey, proof.TreeHead); err != nil {
+		return fmt.Errorf("%w: tree head: %v", ErrProofInvalid, err)
+	}
+	if proof.Record.TenantID != proof.TreeHead.TenantID {
+		return fmt.Errorf("%w: record belongs to tenant %s", ErrProofInvalid, proof.Record.TenantID)
+	}
+	if proof.Seq < 1 || proof.Seq > proof.TreeHead.TreeSize {
+		return fmt.Errorf("%w: seq %d outside tree of size %d", ErrProofInvalid, proof.Seq, proof.TreeHead.TreeSize)
+	}
+
+	prevHash, err := hex.DecodeString(proof.PrevHash)
+	if err != nil {
+		return fmt.Errorf("%w: prev_hash: %v", ErrProofInvalid, err)
+	}
+	if len(prevHash) == 0 {
+		prevHash = nil
+	}
+	hash, err := ComputeHash(prevHash, proof.Record)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProofInvalid, err)
+	}
+	if hex.EncodeToString(hash) != proof.RecordHash {
+		return fmt.Errorf("%w: record does not match record_hash", ErrProofInvalid)
+	}
+
+	root, err := hex.DecodeString(proof.TreeHead.Root)
+	if err != nil {
+		return fmt.Errorf("%w: root: %v", ErrProofInvalid, err)
+	}
+	path := make([][]byte, len(proof.Path))
+	for i, p := range proof.Path {
+		if path[i], err = hex.DecodeString(p); err != nil {
+			return fmt.Errorf("%w: path: %v", ErrProofInvalid, err)
+		}
+	}
+	if !VerifyMerkleInclusion(MerkleLeafHash(hash), proof.Seq-1, proof.TreeHead.TreeSize, path, root) {
+		return fmt.Errorf("%w: path does not lead to the signed root", ErrProofInvalid)
+	}
+	return nil
+}
+
+// ProofQuerier reads the records and tree heads proofs are built from
+type ProofQuerier interface {
+	GetAudit(ctx context.Context, arg queries.GetAuditParams) (queries.Audit, error)
+	GetLatestAudit(ctx context.Context, tenantID pgtype.UUID) (queries.Audit, error)
+	ListAuditRange(ctx context.Context, arg queries.ListAuditRangeParams) ([]queries.Audit, error)
+	GetAuditTreeHead(ctx context.Context, arg queries.GetAuditTreeHeadParams) (queries.AuditTreeHead, error)
+}
+
+// TreeHeadWriter stores signed tree heads
+type TreeHeadWriter interface {
+	CreateAuditTreeHead(ctx context.Context, arg queries.CreateAuditTreeHeadParams) error
+}
+
+// Prover builds inclusion proofs over a tenant's audit records. Each tree
+// head it signs is stored, and later proofs at the same size must hash to
+// the stored root, so a rewritten chain cannot be proven against.
+type Prover struct {
+	reader    ProofQuerier
+	writer    TreeHeadWriter
+	signer    *AnchorSigner
+	batchSize int32
+}
+
+// NewProver creates a prover reading through reader and storing tree heads through writer
+func NewProver(reader ProofQuerier, writer TreeHeadWriter, signer *AnchorSigner) *Prover {
+	return &Prover{reader: reader, writer: writer, signer: signer, batchSize: DefaultBatchSize}
+}
+
+// WithBatchSize sets how many records are read per query
+func (p *Prover) WithBatchSize(n int) *Prover {
+	if n > 0 && n <= math.MaxInt32 {
+		p.batchSize = int32(n)
+	}
+	return p
+}
+
+// Prove returns an inclusion proof for the audit record against a tree over
+// the tenant's first treeSize records. A treeSize of 0 uses the whole chain.
+func (p *Prover) Prove(ctx context.Context, tenantID, auditID pgtype.UUID, treeSize int64) (InclusionProof, error) {
+	record, err := p.reader.GetAudit(ctx, queries.GetAuditParams{ID: auditID, TenantID: tenantID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return InclusionProof{}, ErrAuditNotFound
+	}
+	if err != nil {
+		return InclusionProof{}, fmt.Errorf("failed to get audit record: %w", err)
+	}
+
+	if treeSize == 0 {
+		head, err := p.reader.GetLatestAudit(ctx, tenantID)
+		if err != nil {
+			return InclusionProof{}, fmt.Errorf("failed to get chain head: %w", err)
+		}
+		treeSize = head.Seq
+	}
+	if record.Seq > treeSize {
+		return InclusionProof{}, fmt.Errorf("record seq %d is outside a tree of size %d", record.Seq, treeSize)
+	}
+
+	root, path, prevHash, err := p.hashTree(ctx, tenantID, record.Seq-1, treeSize)
+	if err != nil {
+		return InclusionProof{}, err
+	}
+
+	canonical, err := convertDBAuditToRecord(record)
+	if err != nil {
+		return InclusionProof{}, err
+	}
+	hash, err := ComputeHash(prevHash, canonical)
+	if err != nil {
+		return InclusionProof{}, err
+	}
+	if !equalBytes(hash, record.Hash) {
+		return InclusionProof{}, fmt.Errorf("record at seq %d does not match its stored hash", record.Seq)
+	}
+
+	head, err := p.treeHead(ctx, tenantID, treeSize, root)
+	if err != nil {
+		return InclusionProof{}, err
+	}
+
+	proof := InclusionProof{
+		AuditID:    uuidToString(record.ID),
+		Seq:        record.Seq,
+		Record:     canonical,
+		PrevHash:   hex.EncodeToString(prevHash),
+		RecordHash: hex.EncodeToString(record.Hash),
+		TreeHead:   head,
+	}
+	for _, node := range path {
+		proof.Path = append(proof.Path, hex.EncodeToString(node))
+	}
+	return proof, nil
+}
+
+// hashTree streams the first size records and returns the root, the proof
+// path for leaf index and the hash of the record before it
+func (p *Prover) hashTree(ctx context.Context, tenantID pgtype.UUID, index, size int64) ([]byte, [][]byte, []byte, error) {
+	ranges := merkleProofRanges(index, size)
+	frontiers := make([]merkleFrontier, len(ranges))
+	var tree merkleFrontier
+	var prevHash []byte
+
+	for next := int64(1); next <= size; {
+		batch, err := p.reader.ListAuditRange(ctx, queries.ListAuditRangeParams{
+			TenantID:  tenantID,
+			AfterSeq:  next - 1,
+			ToSeq:     size,
+			BatchSize: p.batchSize,
+		})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to retrieve audit chain: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, a := range batch {
+			if a.Seq != next {
+				return nil, nil, nil, fmt.Errorf("missing record at seq %d", next)
+			}
+			leaf := MerkleLeafHash(a.Hash)
+			tree.push(leaf)
+			i := a.Seq - 1
+			for r := range ranges {
+				if i >= ranges[r][0] && i < ranges[r][1] {
+					frontiers[r].push(leaf)
+				}
+			}
+			if i == index-1 {
+				prevHash = a.Hash
+			}
+			next++
+		}
+	}
+	if tree.size() != size {
+		return nil, nil, nil, fmt.Errorf("missing record at seq %d", tree.size()+1)
+	}
+
+	path := make([][]byte, len(frontiers))
+	for i := range frontiers {
+		path[i] = frontiers[i].root()
+	}
+	return tree.root(), path, prevHash, nil
+}
+
+// treeHead returns the stored head for size, or signs and stores a new one
+func (p *Prover) treeHead(ctx context.Context, tenantID pgtype.UUID, size int64, root []byte) (TreeHead, error) {
+	stored, err := p.reader.GetAuditTreeHead(ctx, queries.GetAuditTreeHeadParams{TenantID: tenantID, TreeSize: size})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return TreeHead{}, fmt.Errorf("failed to get tree head: %w", err)
+	default:
+		if !equalBytes(stored.Root, root) {
+			return TreeHead{}, fmt.Errorf("%w at tree size %d", ErrTreeHeadMismatch, size)
+		}
+		head := TreeHead{
+			TenantID:  uuidToString(tenantID),
+			TreeSize:  stored.TreeSize,
+			Root:      hex.EncodeToString(stored.Root),
+			SignedAt:  stored.SignedAt.Time.UTC(),
+			KeyID:     stored.KeyID,
+			Signature: hex.EncodeToString(stored.Signature),
+		}
+		// A head signed with a rotated key is re-signed but not replaced
+		if VerifyTreeHead(p.signer.PublicKey(), head) == nil {
+			return head, nil
+		}
+		return p.signer.SignTreeHead(head.TenantID, size, root), nil
+	}
+
+	head := p.signer.SignTreeHead(uuidToString(tenantID), size, root)
+	if p.writer != nil {
+		signature, _ := hex.DecodeString(head.Signature)
+		err := p.writer.CreateAuditTreeHead(ctx, queries.CreateAuditTreeHeadParams{
+			TenantID:  tenantID,
+			TreeSize:  size,
+			Root:      root,
+			SignedAt:  pgtype.Timestamptz{Time: head.SignedAt, Valid: true},
+			KeyID:     head.KeyID,
+			Signature: signature,
+		})
+		if err != nil {
+			return TreeHead{}, fmt.Errorf("failed to store tree head: %w", err)
+		}
+	}
+	return head, nil
+}
@@ -0,0 +1,191 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// proofQueries adds record lookup by ID and tree head storage to rangeQueries
+type proofQueries struct {
+	*rangeQueries
+	heads map[int64]queries.AuditTreeHead
+}
+
+func newProofChain(t *testing.T, n int) (*proofQueries, pgtype.UUID) {
+	t.Helper()
+	q, tenantID := newTestChain(t, n)
+	return &proofQueries{rangeQueries: q, heads: map[int64]queries.AuditTreeHead{}}, tenantID
+}
+
+func (q *proofQueries) GetAudit(ctx context.Context, arg queries.GetAuditParams) (queries.Audit, error) {
+	for _, a := range q.audits {
+		if a.ID == arg.ID && a.TenantID == arg.TenantID {
+			return a, nil
+		}
+	}
+	return queries.Audit{}, pgx.ErrNoRows
+}
+
+func (q *proofQueries) GetAuditTreeHead(ctx context.Context, arg queries.GetAuditTreeHeadParams) (queries.AuditTreeHead, error) {
+	head, ok := q.heads[arg.TreeSize]
+	if !ok {
+		return queries.AuditTreeHead{}, pgx.ErrNoRows
+	}
+	return head, nil
+}
+
+func (q *proofQueries) CreateAuditTreeHead(ctx context.Context, arg queries.CreateAuditTreeHeadParams) error {
+	if _, ok := q.heads[arg.TreeSize]; !ok {
+		q.heads[arg.TreeSize] = queries.AuditTreeHead{
+			TenantID:  arg.TenantID,
+			TreeSize:  arg.TreeSize,
+			Root:      arg.Root,
+			SignedAt:  arg.SignedAt,
+			KeyID:     arg.KeyID,
+			Signature: arg.Signature,
+		}
+	}
+	return nil
+}
+
+// roundTrip sends proof through JSON as an auditor would receive it
+func roundTrip(t *testing.T, proof InclusionProof) InclusionProof {
+	t.Helper()
+	data, err := json.MarshalIndent(proof, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out InclusionProof
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestProver_Prove(t *testing.T) {
+	signer := newTestAnchorSigner(t)
+	q, tenantID := newProofChain(t, 13)
+	prover := NewProver(q, q, signer).WithBatchSize(4)
+	ctx := context.Background()
+
+	var root string
+	for _, a := range q.audits {
+		proof, err := prover.Prove(ctx, tenantID, a.ID, 0)
+		if err != nil {
+			t.Fatalf("Prove(seq %d) unexpected error: %v", a.Seq, err)
+		}
+		if proof.Seq != a.Seq || proof.TreeHead.TreeSize != 13 {
+			t.Errorf("Prove(seq %d) = seq %d in tree of %d, want tree of 13", a.Seq, proof.Seq, proof.TreeHead.TreeSize)
+		}
+		if err := VerifyInclusionProof(signer.PublicKey(), roundTrip(t, proof)); err != nil {
+			t.Errorf("VerifyInclusionProof(seq %d) unexpected error: %v", a.Seq, err)
+		}
+
+		// Every proof at one size is issued against the same stored head
+		if root == "" {
+			root = proof.TreeHead.Root
+		} else if proof.TreeHead.Root != root {
+			t.Errorf("Prove(seq %d) root %s, want %s", a.Seq, proof.TreeHead.Root, root)
+		}
+	}
+	if len(q.heads) != 1 {
+		t.Errorf("stored %d tree heads, want 1", len(q.heads))
+	}
+
+	leaves := make([][]byte, len(q.audits))
+	for i, a := range q.audits {
+		leaves[i] = MerkleLeafHash(a.Hash)
+	}
+	if want := MerkleRoot(leaves); !bytes.Equal(q.heads[13].Root, want) {
+		t.Errorf("stored root %x, want %x", q.heads[13].Root, want)
+	}
+
+	// An earlier tree size proves against a smaller tree
+	proof, err := prover.Prove(ctx, tenantID, q.audits[2].ID, 5)
+	if err != nil {
+		t.Fatalf("Prove() at size 5 unexpected error: %v", err)
+	}
+	if proof.TreeHead.TreeSize != 5 || VerifyInclusionProof(signer.PublicKey(), proof) != nil {
+		t.Errorf("Prove() at size 5 = %+v, want a valid proof in a tree of 5", proof.TreeHead)
+	}
+	if _, err := prover.Prove(ctx, tenantID, q.audits[7].ID, 5); err == nil {
+		t.Error("Prove() expected error for a record outside the tree")
+	}
+	if _, err := prover.Prove(ctx, tenantID, pgtype.UUID{Bytes: [16]byte{0xff}, Valid: true}, 0); !errors.Is(err, ErrAuditNotFound) {
+		t.Errorf("Prove() of unknown record error = %v, want %v", err, ErrAuditNotFound)
+	}
+}
+
+func TestProver_ProveDetectsRewrite(t *testing.T) {
+	signer := newTestAnchorSigner(t)
+	q, tenantID := newProofChain(t, 6)
+	prover := NewProver(q, q, signer)
+	ctx := context.Background()
+
+	if _, err := prover.Prove(ctx, tenantID, q.audits[0].ID, 0); err != nil {
+		t.Fatalf("Prove() unexpected error: %v", err)
+	}
+
+	// Rewrite the chain from seq 4 on with valid hashes
+	q.audits[3].Details = []byte(`{"step":99}`)
+	prevHash := q.audits[2].Hash
+	for i := 3; i < len(q.audits); i++ {
+		record, err := convertDBAuditToRecord(q.audits[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.audits[i].Hash, err = ComputeHash(prevHash, record); err != nil {
+			t.Fatal(err)
+		}
+		q.audits[i].PrevHash = prevHash
+		prevHash = q.audits[i].Hash
+	}
+
+	if _, err := prover.Prove(ctx, tenantID, q.audits[0].ID, 0); !errors.Is(err, ErrTreeHeadMismatch) {
+		t.Errorf("Prove() after rewrite error = %v, want %v", err, ErrTreeHeadMismatch)
+	}
+}
+
+func TestVerifyInclusionProof(t *testing.T) {
+	signer := newTestAnchorSigner(t)
+	q, tenantID := newProofChain(t, 7)
+	proof, err := NewProver(q, q, signer).Prove(context.Background(), tenantID, q.audits[4].ID, 0)
+	if err != nil {
+		t.Fatalf("Prove() unexpected error: %v", err)
+	}
+
+	other, err := NewAnchorSigner(bytes.Repeat([]byte{9}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(p *InclusionProof)
+		key    *AnchorSigner
+	}{
+		{"altered record", func(p *InclusionProof) { p.Record.Action = "delete" }, signer},
+		{"altered seq", func(p *InclusionProof) { p.Seq = 4 }, signer},
+		{"altered path", func(p *InclusionProof) { p.Path[0] = p.Path[1] }, signer},
+		{"altered root", func(p *InclusionProof) { p.TreeHead.Root = p.RecordHash }, signer},
+		{"other tenant", func(p *InclusionProof) { p.Record.TenantID = "other" }, signer},
+		{"other key", func(p *InclusionProof) {}, other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := roundTrip(t, proof)
+			p.Path = append([]string(nil), p.Path...)
+			tt.modify(&p)
+			if err := VerifyInclusionProof(tt.key.PublicKey(), p); !errors.Is(err, ErrProofInvalid) {
+				t.Errorf("VerifyInclusionProof() error = %v, want %v", err, ErrProofInvalid)
+			}
+		})
+	}
+}
@@ -1,12 +1,15 @@
 package audit
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -15,17 +18,53 @@ type AuditQuerier interface {
 	CreateAudit(ctx context.Context, arg queries.CreateAuditParams) (queries.Audit, error)
 	GetLatestAudit(ctx context.Context, tenantID pgtype.UUID) (queries.Audit, error)
 	GetAuditChain(ctx context.Context, tenantID pgtype.UUID) ([]queries.Audit, error)
+	LockAuditChain(ctx context.Context, tenantID pgtype.UUID) error
+}
+
+// DB is a connection or pool that can begin the transaction an append runs in
+type DB interface {
+	queries.DBTX
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
 }
 
 // Service provides audit operations with hash-chain integrity
 type Service struct {
-	queries AuditQuerier
+	queries    AuditQuerier
+	reader     AuditQuerier
+	db         DB
+	newQuerier func(queries.DBTX) AuditQuerier
 }
 
-// NewService creates a new audit service
+// NewService creates an audit service on queries. Appends hold the tenant's
+// chain lock only until the surrounding transaction ends, so queries must be
+// bound to a transaction for concurrent appends to be serialized; callers
+// without one use NewServiceWithDB.
 func NewService(queries AuditQuerier) *Service {
 	return &Service{
 		queries: queries,
+		reader:  queries,
+	}
+}
+
+// NewServiceWithReader creates an audit service that verifies chains through
+// reader, typically a read replica. Appends always use queries because the
+// next hash depends on the latest record.
+func NewServiceWithReader(queries, reader AuditQuerier) *Service {
+	return &Service{
+		queries: queries,
+		reader:  reader,
+	}
+}
+
+// NewServiceWithDB creates an audit service that runs each append in its own
+// transaction on db
+func NewServiceWithDB(db DB) *Service {
+	q := queries.New(db)
+	return &Service{
+		queries:    q,
+		reader:     q,
+		db:         db,
+		newQuerier: func(tx queries.DBTX) AuditQuerier { return queries.New(tx) },
 	}
 }
 
@@ -40,18 +79,48 @@ type CreateAuditParams struct {
 	Details      map[string]interface{}
 }
 
-// CreateAudit creates a new audit record with proper hash-chain maintenance
+// CreateAudit appends an audit record to the tenant's hash chain. Appends for
+// the same tenant are serialized, so each record links to the one before it
+// and takes the next sequence number.
 func (s *Service) CreateAudit(ctx context.Context, params CreateAuditParams) (*queries.Audit, error) {
+	if s.db == nil {
+		return appendAudit(ctx, s.queries, params)
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin audit transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	audit, err := appendAudit(ctx, s.newQuerier(tx), params)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit audit record: %w", err)
+	}
+	return audit, nil
+}
+
+// appendAudit takes the tenant's chain lock, then links the new record to the chain head
+func appendAudit(ctx context.Context, q AuditQuerier, params CreateAuditParams) (*queries.Audit, error) {
+	if err := q.LockAuditChain(ctx, params.TenantID); err != nil {
+		return nil, fmt.Errorf("failed to lock audit chain: %w", err)
+	}
+
 	// Get the latest audit record for this tenant to get prev_hash
 	var prevHash []byte
-	latestAudit, err := s.queries.GetLatestAudit(ctx, params.TenantID)
+	var seq int64 = 1
+	latestAudit, err := q.GetLatestAudit(ctx, params.TenantID)
 	if err != nil {
 		// If no previous audit exists, prevHash remains nil (genesis record)
-		if err.Error() != "no rows in result set" {
+		if !errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("failed to get latest audit: %w", err)
 		}
 	} else {
 		prevHash = latestAudit.Hash
+		seq = latestAudit.Seq + 1
 	}
 
 	// Marshal details to JSON
@@ -60,8 +129,10 @@ func (s *Service) CreateAudit(ctx context.Context, params CreateAuditParams) (*q
 		return nil, fmt.Errorf("failed to marshal audit details: %w", err)
 	}
 
-	// Use consistent timestamp for hash computation and database insertion
-	timestamp := time.Now()
+	// The timestamp is taken under the lock so ts follows seq, and stored at
+	// the microsecond precision PostgreSQL returns so verification recomputes
+	// the same hash
+	timestamp := time.Now().UTC().Truncate(time.Microsecond)
 
 	// Create audit record for hash computation
 	auditRecord := AuditRecord{
@@ -90,17 +161,19 @@ func (s *Service) CreateAudit(ctx context.Context, params CreateAuditParams) (*q
 	// Insert audit record with computed hash
 	createParams := queries.CreateAuditParams{
 		TenantID:     params.TenantID,
+		Seq:          seq,
 		ActorType:    params.ActorType,
 		ActorID:      params.ActorID,
 		Action:       params.Action,
 		ResourceType: params.ResourceType,
 		ResourceID:   resourceIDPG,
 		Details:      detailsJSON,
+		Ts:           pgtype.Timestamptz{Time: timestamp, Valid: true},
 		PrevHash:     prevHash,
 		Hash:         hash,
 	}
 
-	audit, err := s.queries.CreateAudit(ctx, createParams)
+	audit, err := q.CreateAudit(ctx, createParams)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create audit record: %w", err)
 	}
@@ -111,7 +184,7 @@ func (s *Service) CreateAudit(ctx context.Context, params CreateAuditParams) (*q
 // VerifyChainIntegrity verifies the hash-chain integrity for a tenant
 func (s *Service) VerifyChainIntegrity(ctx context.Context, tenantID pgtype.UUID) (VerificationResult, error) {
 	// Get all audit records for the tenant in chronological order
-	audits, err := s.queries.GetAuditChain(ctx, tenantID)
+	audits, err := s.reader.GetAuditChain(ctx, tenantID)
 	if err != nil {
 		return VerificationResult{
 			Valid:        false,
@@ -189,6 +262,12 @@ func convertDBAuditToRecord(audit queries.Audit) (AuditRecord, error) {
 		resourceID = &audit.ResourceID.String
 	}
 
+	// JSONB does not keep the key order details were hashed with
+	details, err := canonicalJSON(audit.Details)
+	if err != nil {
+		return AuditRecord{}, err
+	}
+
 	return AuditRecord{
 		TenantID:     uuidToString(audit.TenantID),
 		ActorType:    audit.ActorType,
@@ -196,11 +275,26 @@ func convertDBAuditToRecord(audit queries.Audit) (AuditRecord, error) {
 		Action:       audit.Action,
 		ResourceType: audit.ResourceType,
 		ResourceID:   resourceID,
-		Details:      audit.Details,
-		Timestamp:    audit.Ts.Time,
+		Details:      details,
+		Timestamp:    audit.Ts.Time.UTC(),
 	}, nil
 }
 
+// canonicalJSON re-encodes data with object keys sorted, as json.Marshal
+// writes the details maps audits are created from
+func canonicalJSON(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid details JSON: %w", err)
+	}
+	return json.Marshal(v)
+}
+
 // equalBytes compares two byte slices for equality
 func equalBytes(a, b []byte) bool {
 	if len(a) != len(b) {
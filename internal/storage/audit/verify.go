@@ -0,0 +1,224 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultBatchSize is how many records streaming verification holds in memory
+const DefaultBatchSize = 1000
+
+// ErrCheckpointsDisabled is returned by VerifyIncremental without a signer
+var ErrCheckpointsDisabled = errors.New("audit checkpoints are not configured")
+
+// VerifierQuerier reads chain ranges and checkpoints for streaming verification
+type VerifierQuerier interface {
+	GetAuditBySeq(ctx context.Context, arg queries.GetAuditBySeqParams) (queries.Audit, error)
+	ListAuditRange(ctx context.Context, arg queries.ListAuditRangeParams) ([]queries.Audit, error)
+	GetLatestAuditCheckpoint(ctx context.Context, tenantID pgtype.UUID) (queries.AuditCheckpoint, error)
+}
+
+// CheckpointWriter stores verification checkpoints
+type CheckpointWriter interface {
+	CreateAuditCheckpoint(ctx context.Context, arg queries.CreateAuditCheckpointParams) error
+}
+
+// Verifier verifies audit chains in seq order a batch at a time, so memory
+// use does not grow with the chain
+type Verifier struct {
+	reader    VerifierQuerier
+	writer    CheckpointWriter
+	signer    *CheckpointSigner
+	batchSize int32
+}
+
+// NewVerifier creates a verifier reading through reader, typically a read replica
+func NewVerifier(reader VerifierQuerier) *Verifier {
+	return &Verifier{reader: reader, batchSize: DefaultBatchSize}
+}
+
+// WithCheckpoints enables VerifyIncremental. Checkpoints are signed with
+// signer and written through writer, which should be the primary.
+func (v *Verifier) WithCheckpoints(writer CheckpointWriter, signer *CheckpointSigner) *Verifier {
+	v.writer = writer
+	v.signer = signer
+	return v
+}
+
+// WithBatchSize sets how many records are read per query
+func (v *Verifier) WithBatchSize(n int) *Verifier {
+	if n > 0 && n <= math.MaxInt32 {
+		v.batchSize = int32(n)
+	}
+	return v
+}
+
+// VerifyRange verifies the records with seq from through to. A from of 0 or 1
+// starts at the genesis record and a to of 0 runs to the chain head. A range
+// starting later links to the stored hash of the record before it.
+func (v *Verifier) VerifyRange(ctx context.Context, tenantID pgtype.UUID, from, to int64) (VerificationResult, error) {
+	start := time.Now()
+	if from < 1 {
+		from = 1
+	}
+	if to == 0 {
+		to = math.MaxInt64
+	}
+	if to < from {
+		return VerificationResult{}, fmt.Errorf("invalid range: %d to %d", from, to)
+	}
+
+	var prevHash []byte
+	if from > 1 {
+		prev, err := v.reader.GetAuditBySeq(ctx, queries.GetAuditBySeqParams{TenantID: tenantID, Seq: from - 1})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tampered(from-1, fmt.Sprintf("missing record at seq %d", from-1), start), nil
+		}
+		if err != nil {
+			return retrievalFailed(err), err
+		}
+		prevHash = prev.Hash
+	}
+
+	result, _, err := v.stream(ctx, tenantID, prevHash, from, to)
+	result.Duration = time.Since(start)
+	return result, err
+}
+
+// VerifyIncremental verifies the records after the tenant's latest checkpoint
+// and records a new checkpoint at the chain head when they are intact. The
+// checkpoint itself must carry a valid signature and still match its record.
+// Without a usable checkpoint, including one signed with a rotated key, the
+// whole chain is verified.
+func (v *Verifier) VerifyIncremental(ctx context.Context, tenantID pgtype.UUID) (VerificationResult, error) {
+	if v.signer == nil {
+		return VerificationResult{}, ErrCheckpointsDisabled
+	}
+	start := time.Now()
+
+	var prevHash []byte
+	from := int64(1)
+	checkpoint, err := v.reader.GetLatestAuditCheckpoint(ctx, tenantID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return retrievalFailed(err), err
+	default:
+		err := v.signer.Verify(checkpoint)
+		if errors.Is(err, ErrCheckpointSignature) {
+			return tampered(checkpoint.Seq, fmt.Sprintf("checkpoint at seq %d: %v", checkpoint.Seq, err), start), nil
+		}
+		if err == nil {
+			record, err := v.reader.GetAuditBySeq(ctx, queries.GetAuditBySeqParams{TenantID: tenantID, Seq: checkpoint.Seq})
+			if errors.Is(err, pgx.ErrNoRows) {
+				return tampered(checkpoint.Seq, fmt.Sprintf("missing record at seq %d", checkpoint.Seq), start), nil
+			}
+			if err != nil {
+				return retrievalFailed(err), err
+			}
+			if !equalBytes(record.Hash, checkpoint.Hash) {
+				return tampered(checkpoint.Seq, fmt.Sprintf("record at seq %d no longer matches its checkpoint", checkpoint.Seq), start), nil
+			}
+			prevHash = checkpoint.Hash
+			from = checkpoint.Seq + 1
+		}
+	}
+
+	result, last, err := v.stream(ctx, tenantID, prevHash, from, math.MaxInt64)
+	if from > 1 {
+		result.CheckpointSeq = from - 1
+	}
+	if err == nil && result.Valid && last != nil && v.writer != nil {
+		params := v.signer.Sign(tenantID, last.Seq, last.Hash, last.Ts.Time)
+		if err := v.writer.CreateAuditCheckpoint(ctx, params); err != nil {
+			result.Duration = time.Since(start)
+			return result, fmt.Errorf("failed to record checkpoint: %w", err)
+		}
+		result.NewCheckpointSeq = last.Seq
+	}
+	result.Duration = time.Since(start)
+	return result, err
+}
+
+// stream verifies records from through to, linking the first to prevHash,
+// and returns the last record verified
+func (v *Verifier) stream(ctx context.Context, tenantID pgtype.UUID, prevHash []byte, from, to int64) (VerificationResult, *queries.Audit, error) {
+	result := VerificationResult{Valid: true}
+	var last *queries.Audit
+	expected := from
+
+	for expected <= to {
+		batch, err := v.reader.ListAuditRange(ctx, queries.ListAuditRangeParams{
+			TenantID:  tenantID,
+			AfterSeq:  expected - 1,
+			ToSeq:     to,
+			BatchSize: v.batchSize,
+		})
+		if err != nil {
+			return retrievalFailed(err), nil, err
+		}
+
+		for i := range batch {
+			a := batch[i]
+			if a.Seq != expected {
+				return tamperedAt(result, expected, fmt.Sprintf("missing record at seq %d", expected)), last, nil
+			}
+
+			record, err := convertDBAuditToRecord(a)
+			if err != nil {
+				return tamperedAt(result, a.Seq, fmt.Sprintf("failed to convert audit record %d: %v", a.Seq-1, err)), last, err
+			}
+			hash, err := ComputeHash(prevHash, record)
+			if err != nil {
+				return tamperedAt(result, a.Seq, fmt.Sprintf("failed to compute hash for record %d: %v", a.Seq-1, err)), last, nil
+			}
+			if !equalBytes(hash, a.Hash) {
+				return tamperedAt(result, a.Seq, fmt.Sprintf("hash mismatch at record %d", a.Seq-1)), last, nil
+			}
+
+			if result.FromSeq == 0 {
+				result.FromSeq = a.Seq
+			}
+			result.ToSeq = a.Seq
+			result.TotalRecords++
+			prevHash = a.Hash
+			last = &a
+			expected++
+		}
+
+		if len(batch) < int(v.batchSize) {
+			break
+		}
+	}
+
+	return result, last, nil
+}
+
+// tamperedAt marks result invalid at seq, keeping the records verified so far
+func tamperedAt(result VerificationResult, seq int64, message string) VerificationResult {
+	index := int(seq - 1)
+	result.Valid = false
+	result.FirstTamperedIndex = &index
+	result.ErrorMessage = message
+	return result
+}
+
+func tampered(seq int64, message string, start time.Time) VerificationResult {
+	result := tamperedAt(VerificationResult{}, seq, message)
+	result.Duration = time.Since(start)
+	return result
+}
+
+func retrievalFailed(err error) VerificationResult {
+	return VerificationResult{
+		Valid:        false,
+		ErrorMessage: fmt.Sprintf("failed to retrieve audit chain: %v", err),
+	}
+}
@@ -0,0 +1,305 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var testCheckpointKey = []byte("0123456789abcdef0123456789abcdef")
+
+// rangeQueries serves a chain built through MockQueries to the verifier and
+// records the checkpoints it writes
+type rangeQueries struct {
+	*MockQueries
+	checkpoints []queries.AuditCheckpoint
+	batches     []int
+}
+
+func (q *rangeQueries) GetAuditBySeq(ctx context.Context, arg queries.GetAuditBySeqParams) (queries.Audit, error) {
+	for _, a := range q.audits {
+		if a.Seq == arg.Seq {
+			return a, nil
+		}
+	}
+	return queries.Audit{}, pgx.ErrNoRows
+}
+
+func (q *rangeQueries) ListAuditRange(ctx context.Context, arg queries.ListAuditRangeParams) ([]queries.Audit, error) {
+	if q.getErr != nil {
+		return nil, q.getErr
+	}
+	var batch []queries.Audit
+	for _, a := range q.audits {
+		if a.Seq > arg.AfterSeq && a.Seq <= arg.ToSeq && len(batch) < int(arg.BatchSize) {
+			batch = append(batch, a)
+		}
+	}
+	q.batches = append(q.batches, len(batch))
+	return batch, nil
+}
+
+func (q *rangeQueries) GetLatestAuditCheckpoint(ctx context.Context, tenantID pgtype.UUID) (queries.AuditCheckpoint, error) {
+	if len(q.checkpoints) == 0 {
+		return queries.AuditCheckpoint{}, pgx.ErrNoRows
+	}
+	return q.checkpoints[len(q.checkpoints)-1], nil
+}
+
+func (q *rangeQueries) CreateAuditCheckpoint(ctx context.Context, arg queries.CreateAuditCheckpointParams) error {
+	q.checkpoints = append(q.checkpoints, queries.AuditCheckpoint{
+		TenantID:  arg.TenantID,
+		Seq:       arg.Seq,
+		Hash:      arg.Hash,
+		RecordTs:  arg.RecordTs,
+		KeyID:     arg.KeyID,
+		Signature: arg.Signature,
+	})
+	return nil
+}
+
+func newTestChain(t *testing.T, n int) (*rangeQueries, pgtype.UUID) {
+	t.Helper()
+	q := &rangeQueries{MockQueries: &MockQueries{}}
+	tenantID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	appendTestRecords(t, q, tenantID, n)
+	return q, tenantID
+}
+
+func appendTestRecords(t *testing.T, q *rangeQueries, tenantID pgtype.UUID, n int) {
+	t.Helper()
+	service := NewService(q.MockQueries)
+	for i := 0; i < n; i++ {
+		_, err := service.CreateAudit(context.Background(), CreateAuditParams{
+			TenantID:     tenantID,
+			ActorType:    "user",
+			ActorID:      "user-123",
+			Action:       "update",
+			ResourceType: "workflow",
+			Details:      map[string]interface{}{"step": i},
+		})
+		if err != nil {
+			t.Fatalf("CreateAudit() unexpected error: %v", err)
+		}
+	}
+}
+
+func newTestSigner(t *testing.T, key []byte) *CheckpointSigner {
+	t.Helper()
+	signer, err := NewCheckpointSigner(key)
+	if err != nil {
+		t.Fatalf("NewCheckpointSigner() unexpected error: %v", err)
+	}
+	return signer
+}
+
+func TestVerifier_VerifyRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		from, to  int64
+		tamper    func(q *rangeQueries)
+		wantValid bool
+		wantTotal int
+		wantFrom  int64
+		wantTo    int64
+		wantIndex int
+		wantError string
+	}{
+		{name: "whole chain", wantValid: true, wantTotal: 25, wantFrom: 1, wantTo: 25},
+		{name: "bounded range", from: 5, to: 12, wantValid: true, wantTotal: 8, wantFrom: 5, wantTo: 12},
+		{name: "range past the head", from: 20, to: 40, wantValid: true, wantTotal: 6, wantFrom: 20, wantTo: 25},
+		{
+			name:      "modified record",
+			tamper:    func(q *rangeQueries) { q.audits[6].ActorID = "intruder" },
+			wantIndex: 6, wantTotal: 6, wantError: "hash mismatch at record 6",
+		},
+		{
+			name:      "deleted record",
+			tamper:    func(q *rangeQueries) { q.audits = append(q.audits[:8:8], q.audits[9:]...) },
+			wantIndex: 8, wantTotal: 8, wantError: "missing record at seq 9",
+		},
+		{
+			name:      "deleted record before range",
+			from:      10,
+			tamper:    func(q *rangeQueries) { q.audits = append(q.audits[:8:8], q.audits[9:]...) },
+			wantIndex: 8, wantError: "missing record at seq 9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, tenantID := newTestChain(t, 25)
+			if tt.tamper != nil {
+				tt.tamper(q)
+			}
+
+			result, err := NewVerifier(q).WithBatchSize(10).VerifyRange(context.Background(), tenantID, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("VerifyRange() unexpected error: %v", err)
+			}
+			if result.Valid != tt.wantValid {
+				t.Fatalf("VerifyRange() Valid = %v, want %v (%s)", result.Valid, tt.wantValid, result.ErrorMessage)
+			}
+			if result.TotalRecords != tt.wantTotal {
+				t.Errorf("VerifyRange() TotalRecords = %d, want %d", result.TotalRecords, tt.wantTotal)
+			}
+			if tt.wantValid {
+				if result.FromSeq != tt.wantFrom || result.ToSeq != tt.wantTo {
+					t.Errorf("VerifyRange() range = %d..%d, want %d..%d", result.FromSeq, result.ToSeq, tt.wantFrom, tt.wantTo)
+				}
+				return
+			}
+			if result.FirstTamperedIndex == nil || *result.FirstTamperedIndex != tt.wantIndex {
+				t.Errorf("VerifyRange() FirstTamperedIndex = %v, want %d", result.FirstTamperedIndex, tt.wantIndex)
+			}
+			if result.ErrorMessage != tt.wantError {
+				t.Errorf("VerifyRange() ErrorMessage = %q, want %q", result.ErrorMessage, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestVerifier_VerifyRangeBatches(t *testing.T) {
+	q, tenantID := newTestChain(t, 25)
+
+	result, err := NewVerifier(q).WithBatchSize(10).VerifyRange(context.Background(), tenantID, 0, 0)
+	if err != nil || !result.Valid {
+		t.Fatalf("VerifyRange() = %+v, %v", result, err)
+	}
+	if fmt.Sprint(q.batches) != "[10 10 5]" {
+		t.Errorf("batches = %v, want [10 10 5]", q.batches)
+	}
+}
+
+func TestVerifier_VerifyRangeErrors(t *testing.T) {
+	q, tenantID := newTestChain(t, 3)
+
+	if _, err := NewVerifier(q).VerifyRange(context.Background(), tenantID, 5, 2); err == nil {
+		t.Error("VerifyRange() expected error for an inverted range")
+	}
+
+	q.getErr = errors.New("connection reset")
+	result, err := NewVerifier(q).VerifyRange(context.Background(), tenantID, 0, 0)
+	if err == nil || result.Valid || !strings.Contains(result.ErrorMessage, "failed to retrieve audit chain") {
+		t.Errorf("VerifyRange() = %+v, %v, want retrieval error", result, err)
+	}
+}
+
+func TestVerifier_VerifyIncremental(t *testing.T) {
+	ctx := context.Background()
+	q, tenantID := newTestChain(t, 25)
+	verifier := NewVerifier(q).WithBatchSize(10).WithCheckpoints(q, newTestSigner(t, testCheckpointKey))
+
+	result, err := verifier.VerifyIncremental(ctx, tenantID)
+	if err != nil || !result.Valid {
+		t.Fatalf("VerifyIncremental() = %+v, %v", result, err)
+	}
+	if result.CheckpointSeq != 0 || result.NewCheckpointSeq != 25 || result.TotalRecords != 25 {
+		t.Errorf("first run = %+v, want full verification checkpointed at 25", result)
+	}
+
+	// Only records appended since the checkpoint are verified
+	appendTestRecords(t, q, tenantID, 5)
+	result, err = verifier.VerifyIncremental(ctx, tenantID)
+	if err != nil || !result.Valid {
+		t.Fatalf("VerifyIncremental() = %+v, %v", result, err)
+	}
+	if result.CheckpointSeq != 25 || result.FromSeq != 26 || result.TotalRecords != 5 || result.NewCheckpointSeq != 30 {
+		t.Errorf("second run = %+v, want records 26..30 checkpointed at 30", result)
+	}
+
+	// Nothing new leaves the checkpoint where it is
+	result, err = verifier.VerifyIncremental(ctx, tenantID)
+	if err != nil || !result.Valid || result.TotalRecords != 0 || result.NewCheckpointSeq != 0 {
+		t.Errorf("idle run = %+v, %v, want nothing verified", result, err)
+	}
+	if len(q.checkpoints) != 2 {
+		t.Errorf("checkpoints = %d, want 2", len(q.checkpoints))
+	}
+}
+
+func TestVerifier_VerifyIncrementalDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name      string
+		tamper    func(q *rangeQueries)
+		wantIndex int
+		wantError string
+	}{
+		{
+			name:      "forged checkpoint",
+			tamper:    func(q *rangeQueries) { q.checkpoints[0].Seq = 5 },
+			wantIndex: 4, wantError: "checkpoint at seq 5: checkpoint signature invalid",
+		},
+		{
+			name: "chain rewritten up to the checkpoint",
+			tamper: func(q *rangeQueries) {
+				q.audits[9].Hash = []byte("rewritten")
+			},
+			wantIndex: 9, wantError: "record at seq 10 no longer matches its checkpoint",
+		},
+		{
+			name:      "chain truncated",
+			tamper:    func(q *rangeQueries) { q.audits = q.audits[:5] },
+			wantIndex: 9, wantError: "missing record at seq 10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, tenantID := newTestChain(t, 10)
+			verifier := NewVerifier(q).WithCheckpoints(q, newTestSigner(t, testCheckpointKey))
+			if _, err := verifier.VerifyIncremental(context.Background(), tenantID); err != nil {
+				t.Fatalf("VerifyIncremental() unexpected error: %v", err)
+			}
+			tt.tamper(q)
+
+			result, err := verifier.VerifyIncremental(context.Background(), tenantID)
+			if err != nil {
+				t.Fatalf("VerifyIncremental() unexpected error: %v", err)
+			}
+			if result.Valid {
+				t.Fatal("VerifyIncremental() Valid = true, want tampering detected")
+			}
+			if result.FirstTamperedIndex == nil || *result.FirstTamperedIndex != tt.wantIndex {
+				t.Errorf("FirstTamperedIndex = %v, want %d", result.FirstTamperedIndex, tt.wantIndex)
+			}
+			if result.ErrorMessage != tt.wantError {
+				t.Errorf("ErrorMessage = %q, want %q", result.ErrorMessage, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestVerifier_VerifyIncrementalAfterKeyRotation(t *testing.T) {
+	q, tenantID := newTestChain(t, 10)
+	ctx := context.Background()
+	if _, err := NewVerifier(q).WithCheckpoints(q, newTestSigner(t, testCheckpointKey)).VerifyIncremental(ctx, tenantID); err != nil {
+		t.Fatalf("VerifyIncremental() unexpected error: %v", err)
+	}
+
+	rotated := newTestSigner(t, []byte("fedcba9876543210fedcba9876543210"))
+	result, err := NewVerifier(q).WithCheckpoints(q, rotated).VerifyIncremental(ctx, tenantID)
+	if err != nil || !result.Valid {
+		t.Fatalf("VerifyIncremental() = %+v, %v", result, err)
+	}
+	if result.CheckpointSeq != 0 || result.TotalRecords != 10 {
+		t.Errorf("VerifyIncremental() = %+v, want full verification", result)
+	}
+	if got := q.checkpoints[len(q.checkpoints)-1].KeyID; got != rotated.KeyID() {
+		t.Errorf("new checkpoint key ID = %s, want %s", got, rotated.KeyID())
+	}
+}
+
+func TestVerifier_VerifyIncrementalWithoutSigner(t *testing.T) {
+	q, tenantID := newTestChain(t, 1)
+	_, err := NewVerifier(q).VerifyIncremental(context.Background(), tenantID)
+	if !errors.Is(err, ErrCheckpointsDisabled) {
+		t.Errorf("VerifyIncremental() error = %v, want ErrCheckpointsDisabled", err)
+	}
+}
@@ -0,0 +1,259 @@
+package dbpool
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pool roles reported in stats and metrics
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+// lagQuery reports how far a replica's replay is behind the primary. A
+// replica that has replayed everything it received reports zero even when
+// the primary has been idle, and a primary reports zero.
+const lagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END::float8`
+
+// replica is a read pool and its last observed replication state
+type replica struct {
+	name    string
+	db      *pgxpool.Pool
+	probe   func(ctx context.Context) (time.Duration, error)
+	lag     atomic.Int64 // nanoseconds
+	healthy atomic.Bool
+}
+
+// Cluster routes queries between a primary pool and read replicas
+type Cluster struct {
+	config    Config
+	logger    logging.Logger
+	primary   *pgxpool.Pool
+	name      string
+	replicas  []*replica
+	next      atomic.Uint64
+	fallbacks atomic.Int64
+
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// Open creates the primary and replica pools and starts monitoring replica
+// lag. Connections are established lazily, so an unreachable replica does
+// not fail Open; it is treated as unhealthy until a lag check succeeds.
+func Open(ctx context.Context, config *Config, logger logging.Logger) (*Cluster, error) {
+	primary, name, err := newPool(ctx, config.PrimaryURL, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create primary pool: %w", err)
+	}
+
+	c := &Cluster{config: *config, logger: logger, primary: primary, name: name}
+	for i, url := range config.ReplicaURLs {
+		db, name, err := newPool(ctx, url, config)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to create replica pool %d: %w", i, err)
+		}
+		c.replicas = append(c.replicas, &replica{name: name, db: db, probe: queryLag(db)})
+	}
+
+	c.checkReplicas(ctx)
+	c.startMonitor()
+	return c, nil
+}
+
+// newPool builds a pool from url with the configured sizing and statement timeout
+func newPool(ctx context.Context, url string, config *Config) (*pgxpool.Pool, string, error) {
+	poolConfig, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, "", err
+	}
+	if config.MaxConns > 0 {
+		poolConfig.MaxConns = config.MaxConns
+	}
+	poolConfig.MinConns = config.MinConns
+	if config.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = config.MaxConnLifetime
+	}
+	if config.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.MaxConnIdleTime
+	}
+	if config.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(config.StatementTimeout.Milliseconds(), 10)
+	}
+
+	db, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	// host:port identifies the pool without exposing credentials
+	name := fmt.Sprintf("%s:%d", poolConfig.ConnConfig.Host, poolConfig.ConnConfig.Port)
+	return db, name, nil
+}
+
+func queryLag(db *pgxpool.Pool) func(ctx context.Context) (time.Duration, error) {
+	return func(ctx context.Context) (time.Duration, error) {
+		var seconds float64
+		if err := db.QueryRow(ctx, lagQuery).Scan(&seconds); err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+}
+
+// Primary returns the primary pool. Writes, transactions and reads that must
+// see the caller's own writes use it.
+func (c *Cluster) Primary() *pgxpool.Pool {
+	return c.primary
+}
+
+// Reader returns a handle for read-only queries. Each query goes to the next
+// replica within the lag limit, or to the primary when none is.
+func (c *Cluster) Reader() queries.DBTX {
+	return reader{c}
+}
+
+// Fallbacks returns how many reads went to the primary because no replica was usable
+func (c *Cluster) Fallbacks() int64 {
+	return c.fallbacks.Load()
+}
+
+// Close stops lag monitoring and closes all pools
+func (c *Cluster) Close() {
+	if c.stop != nil {
+		c.stop()
+		c.wg.Wait()
+	}
+	for _, r := range c.replicas {
+		r.db.Close()
+	}
+	c.primary.Close()
+}
+
+// route picks the replica for the next read, or nil for the primary
+func (c *Cluster) route() *replica {
+	n := uint64(len(c.replicas))
+	if n == 0 {
+		return nil
+	}
+	start := c.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		r := c.replicas[(start+i)%n]
+		if c.usable(r) {
+			return r
+		}
+	}
+	c.fallbacks.Add(1)
+	return nil
+}
+
+// usable reports whether r is reachable and within the lag limit. A zero
+// MaxReplicaLag disables the limit.
+func (c *Cluster) usable(r *replica) bool {
+	if !r.healthy.Load() {
+		return false
+	}
+	return c.config.MaxReplicaLag == 0 || time.Duration(r.lag.Load()) <= c.config.MaxReplicaLag
+}
+
+func (c *Cluster) readPool() *pgxpool.Pool {
+	if r := c.route(); r != nil {
+		return r.db
+	}
+	return c.primary
+}
+
+// checkReplicas refreshes the lag and health of every replica
+func (c *Cluster) checkReplicas(ctx context.Context) {
+	timeout := c.config.LagCheckInterval
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	for _, r := range c.replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		lag, err := r.probe(checkCtx)
+		cancel()
+
+		if err != nil {
+			if r.healthy.Swap(false) {
+				c.logger.Warn("Read replica unavailable, routing reads to primary",
+					logging.String("replica", r.name),
+					logging.String("error", err.Error()))
+			}
+			continue
+		}
+
+		wasUsable := c.usable(r)
+		r.lag.Store(int64(lag))
+		r.healthy.Store(true)
+
+		switch usable := c.usable(r); {
+		case usable && !wasUsable:
+			c.logger.Info("Read replica available",
+				logging.String("replica", r.name),
+				logging.Float64("lag_seconds", lag.Seconds()))
+		case !usable && wasUsable:
+			c.logger.Warn("Read replica lagging, routing reads to primary",
+				logging.String("replica", r.name),
+				logging.Float64("lag_seconds", lag.Seconds()),
+				logging.Float64("max_lag_seconds", c.config.MaxReplicaLag.Seconds()))
+		}
+	}
+}
+
+// startMonitor checks replica lag every LagCheckInterval until Close
+func (c *Cluster) startMonitor() {
+	if len(c.replicas) == 0 || c.config.LagCheckInterval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.stop = cancel
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(c.config.LagCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.checkReplicas(ctx)
+			}
+		}
+	}()
+}
+
+// reader implements queries.DBTX by routing each call
+type reader struct {
+	c *Cluster
+}
+
+func (r reader) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return r.c.readPool().Exec(ctx, sql, args...)
+}
+
+func (r reader) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return r.c.readPool().Query(ctx, sql, args...)
+}
+
+func (r reader) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return r.c.readPool().QueryRow(ctx, sql, args...)
+}
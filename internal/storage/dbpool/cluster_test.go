@@ -0,0 +1,118 @@
+package dbpool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProbe returns a lag probe whose result the test can change
+type fakeProbe struct {
+	lag time.Duration
+	err error
+}
+
+func (p *fakeProbe) probe(context.Context) (time.Duration, error) {
+	return p.lag, p.err
+}
+
+func newTestCluster(t *testing.T, maxLag time.Duration, probes ...*fakeProbe) (*Cluster, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	c := &Cluster{
+		config: Config{MaxReplicaLag: maxLag, LagCheckInterval: time.Second},
+		logger: logging.NewLoggerWithWriter(&buf),
+		name:   "primary:5432",
+	}
+	for i, p := range probes {
+		c.replicas = append(c.replicas, &replica{name: string(rune('a'+i)) + ":5432", probe: p.probe})
+	}
+	return c, &buf
+}
+
+func TestRouteWithoutReplicas(t *testing.T) {
+	c, _ := newTestCluster(t, time.Second)
+	assert.Nil(t, c.route())
+	assert.Equal(t, int64(0), c.Fallbacks(), "a cluster without replicas is not a fallback")
+}
+
+func TestRouteRoundRobin(t *testing.T) {
+	c, _ := newTestCluster(t, time.Second, &fakeProbe{}, &fakeProbe{})
+	c.checkReplicas(context.Background())
+
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		r := c.route()
+		require.NotNil(t, r)
+		seen[r.name]++
+	}
+	assert.Equal(t, map[string]int{"a:5432": 2, "b:5432": 2}, seen)
+	assert.Equal(t, int64(0), c.Fallbacks())
+}
+
+func TestRouteSkipsLaggingReplica(t *testing.T) {
+	lagging := &fakeProbe{lag: 10 * time.Second}
+	c, buf := newTestCluster(t, time.Second, lagging, &fakeProbe{lag: 100 * time.Millisecond})
+	c.checkReplicas(context.Background())
+
+	for i := 0; i < 3; i++ {
+		r := c.route()
+		require.NotNil(t, r)
+		assert.Equal(t, "b:5432", r.name)
+	}
+
+	// Once the replica catches up it rejoins the rotation
+	lagging.lag = 0
+	c.checkReplicas(context.Background())
+	assert.Contains(t, buf.String(), "Read replica available")
+	names := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		names[c.route().name] = true
+	}
+	assert.Len(t, names, 2)
+}
+
+func TestRouteFallsBackToPrimary(t *testing.T) {
+	probe := &fakeProbe{}
+	c, buf := newTestCluster(t, time.Second, probe)
+	c.checkReplicas(context.Background())
+	require.NotNil(t, c.route())
+
+	probe.lag = 3 * time.Second
+	c.checkReplicas(context.Background())
+	assert.Nil(t, c.route())
+	assert.Contains(t, buf.String(), "Read replica lagging")
+
+	probe.err = errors.New("connection refused")
+	c.checkReplicas(context.Background())
+	assert.Nil(t, c.route())
+	assert.Contains(t, buf.String(), "Read replica unavailable")
+	assert.Equal(t, int64(2), c.Fallbacks())
+}
+
+func TestUsableWithoutLagLimit(t *testing.T) {
+	c, _ := newTestCluster(t, 0, &fakeProbe{lag: time.Hour})
+	assert.Nil(t, c.route(), "replicas are unhealthy until the first successful check")
+
+	c.checkReplicas(context.Background())
+	assert.NotNil(t, c.route())
+}
+
+func TestStats(t *testing.T) {
+	c, _ := newTestCluster(t, time.Second, &fakeProbe{lag: 2 * time.Second}, &fakeProbe{err: errors.New("down")})
+	c.checkReplicas(context.Background())
+
+	stats := c.Stats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, PoolStats{Name: "primary:5432", Role: RolePrimary, Healthy: true}, stats[0])
+	assert.Equal(t, RoleReplica, stats[1].Role)
+	assert.Equal(t, 2*time.Second, stats[1].ReplicationLag)
+	assert.True(t, stats[1].Healthy)
+	assert.False(t, stats[2].Healthy)
+}
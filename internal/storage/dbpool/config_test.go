@@ -0,0 +1,52 @@
+package dbpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLFromEnv(t *testing.T) {
+	t.Setenv("AF_DATABASE_URL", "")
+	t.Setenv("DATABASE_URL", "")
+	assert.Equal(t, DefaultURL, URLFromEnv())
+
+	t.Setenv("DATABASE_URL", "postgres://fallback/db")
+	assert.Equal(t, "postgres://fallback/db", URLFromEnv())
+
+	t.Setenv("AF_DATABASE_URL", "postgres://primary/db")
+	assert.Equal(t, "postgres://primary/db", URLFromEnv())
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	t.Setenv("AF_DATABASE_URL", "postgres://primary/db")
+	t.Setenv("AF_DATABASE_REPLICA_URLS", "postgres://replica1/db, ,postgres://replica2/db")
+	t.Setenv("AF_DB_MAX_CONNS", "25")
+	t.Setenv("AF_DB_MIN_CONNS", "5")
+	t.Setenv("AF_DB_STATEMENT_TIMEOUT", "0s")
+	t.Setenv("AF_DB_MAX_REPLICA_LAG", "2s")
+	t.Setenv("AF_DB_LAG_CHECK_INTERVAL", "500ms")
+
+	config := LoadFromEnv()
+	assert.Equal(t, "postgres://primary/db", config.PrimaryURL)
+	assert.Equal(t, []string{"postgres://replica1/db", "postgres://replica2/db"}, config.ReplicaURLs)
+	assert.Equal(t, int32(25), config.MaxConns)
+	assert.Equal(t, int32(5), config.MinConns)
+	assert.Equal(t, time.Duration(0), config.StatementTimeout)
+	assert.Equal(t, 2*time.Second, config.MaxReplicaLag)
+	assert.Equal(t, 500*time.Millisecond, config.LagCheckInterval)
+	assert.Equal(t, time.Hour, config.MaxConnLifetime)
+}
+
+func TestLoadFromEnvInvalidValues(t *testing.T) {
+	t.Setenv("AF_DB_MAX_CONNS", "-3")
+	t.Setenv("AF_DB_MIN_CONNS", "50")
+	t.Setenv("AF_DB_MAX_REPLICA_LAG", "soon")
+
+	config := LoadFromEnv()
+	defaults := DefaultConfig()
+	assert.Equal(t, defaults.MaxConns, config.MaxConns)
+	assert.Equal(t, defaults.MaxConns, config.MinConns, "MinConns is clamped to MaxConns")
+	assert.Equal(t, defaults.MaxReplicaLag, config.MaxReplicaLag)
+}
@@ -0,0 +1,140 @@
+package dbpool
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// PoolStats is a point-in-time snapshot of one pool
+type PoolStats struct {
+	Name              string        `json:"name"`
+	Role              string        `json:"role"`
+	AcquiredConns     int32         `json:"acquired_conns"`
+	IdleConns         int32         `json:"idle_conns"`
+	TotalConns        int32         `json:"total_conns"`
+	MaxConns          int32         `json:"max_conns"`
+	AcquireCount      int64         `json:"acquire_count"`
+	EmptyAcquireCount int64         `json:"empty_acquire_count"`
+	CanceledAcquires  int64         `json:"canceled_acquire_count"`
+	AcquireDuration   time.Duration `json:"acquire_duration"`
+	ReplicationLag    time.Duration `json:"replication_lag"`
+	Healthy           bool          `json:"healthy"`
+}
+
+// Stats returns a snapshot of the primary pool followed by each replica
+func (c *Cluster) Stats() []PoolStats {
+	stats := []PoolStats{poolStats(c.name, RolePrimary, c.primary)}
+	stats[0].Healthy = true
+	for _, r := range c.replicas {
+		s := poolStats(r.name, RoleReplica, r.db)
+		s.ReplicationLag = time.Duration(r.lag.Load())
+		s.Healthy = r.healthy.Load()
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+func poolStats(name, role string, db *pgxpool.Pool) PoolStats {
+	s := PoolStats{Name: name, Role: role}
+	if db == nil {
+		return s
+	}
+	st := db.Stat()
+	s.AcquiredConns = st.AcquiredConns()
+	s.IdleConns = st.IdleConns()
+	s.TotalConns = st.TotalConns()
+	s.MaxConns = st.MaxConns()
+	s.AcquireCount = st.AcquireCount()
+	s.EmptyAcquireCount = st.EmptyAcquireCount()
+	s.CanceledAcquires = st.CanceledAcquireCount()
+	s.AcquireDuration = st.AcquireDuration()
+	return s
+}
+
+// RegisterMetrics exports pool stats, replica lag and read fallbacks as
+// observable instruments on meter. Every pool series carries db.pool.name and
+// db.pool.role attributes. Unregister the returned registration before Close.
+func (c *Cluster) RegisterMetrics(meter metric.Meter) (metric.Registration, error) {
+	acquired, err := meter.Int64ObservableGauge("db.pool.connections.acquired",
+		metric.WithDescription("Connections currently checked out of the pool"))
+	if err != nil {
+		return nil, err
+	}
+	idle, err := meter.Int64ObservableGauge("db.pool.connections.idle",
+		metric.WithDescription("Idle connections in the pool"))
+	if err != nil {
+		return nil, err
+	}
+	total, err := meter.Int64ObservableGauge("db.pool.connections.total",
+		metric.WithDescription("Open connections in the pool"))
+	if err != nil {
+		return nil, err
+	}
+	maxConns, err := meter.Int64ObservableGauge("db.pool.connections.max",
+		metric.WithDescription("Maximum connections the pool may open"))
+	if err != nil {
+		return nil, err
+	}
+	acquires, err := meter.Int64ObservableCounter("db.pool.acquires",
+		metric.WithDescription("Connection acquires from the pool"))
+	if err != nil {
+		return nil, err
+	}
+	emptyAcquires, err := meter.Int64ObservableCounter("db.pool.acquires.waited",
+		metric.WithDescription("Acquires that waited because the pool had no idle connection"))
+	if err != nil {
+		return nil, err
+	}
+	acquireTime, err := meter.Float64ObservableCounter("db.pool.acquire.duration",
+		metric.WithDescription("Total time spent acquiring connections"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	lag, err := meter.Float64ObservableGauge("db.replica.lag",
+		metric.WithDescription("Replication lag of the read replica"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	healthy, err := meter.Int64ObservableGauge("db.replica.healthy",
+		metric.WithDescription("1 when the last lag check of the read replica succeeded"))
+	if err != nil {
+		return nil, err
+	}
+	fallbacks, err := meter.Int64ObservableCounter("db.reads.primary_fallback",
+		metric.WithDescription("Reads sent to the primary because no replica was within the lag limit"))
+	if err != nil {
+		return nil, err
+	}
+
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, s := range c.Stats() {
+			attrs := metric.WithAttributes(
+				attribute.String("db.pool.name", s.Name),
+				attribute.String("db.pool.role", s.Role))
+			o.ObserveInt64(acquired, int64(s.AcquiredConns), attrs)
+			o.ObserveInt64(idle, int64(s.IdleConns), attrs)
+			o.ObserveInt64(total, int64(s.TotalConns), attrs)
+			o.ObserveInt64(maxConns, int64(s.MaxConns), attrs)
+			o.ObserveInt64(acquires, s.AcquireCount, attrs)
+			o.ObserveInt64(emptyAcquires, s.EmptyAcquireCount, attrs)
+			o.ObserveFloat64(acquireTime, s.AcquireDuration.Seconds(), attrs)
+			if s.Role == RoleReplica {
+				o.ObserveFloat64(lag, s.ReplicationLag.Seconds(), attrs)
+				o.ObserveInt64(healthy, boolToInt64(s.Healthy), attrs)
+			}
+		}
+		o.ObserveInt64(fallbacks, c.Fallbacks())
+		return nil
+	}, acquired, idle, total, maxConns, acquires, emptyAcquires, acquireTime, lag, healthy, fallbacks)
+}
+
+func boolToInt64(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
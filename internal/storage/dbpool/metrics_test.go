@@ -0,0 +1,51 @@
+package dbpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRegisterMetrics(t *testing.T) {
+	c, _ := newTestCluster(t, time.Second, &fakeProbe{lag: 3 * time.Second})
+	c.checkReplicas(context.Background())
+	c.route()
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	reg, err := c.RegisterMetrics(provider.Meter("test"))
+	require.NoError(t, err)
+	defer func() { _ = reg.Unregister() }()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	found := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		found[m.Name] = m.Data
+	}
+
+	lag, ok := found["db.replica.lag"].(metricdata.Gauge[float64])
+	require.True(t, ok)
+	require.Len(t, lag.DataPoints, 1)
+	assert.Equal(t, 3.0, lag.DataPoints[0].Value)
+	role, _ := lag.DataPoints[0].Attributes.Value("db.pool.role")
+	assert.Equal(t, RoleReplica, role.AsString())
+
+	fallbacks, ok := found["db.reads.primary_fallback"].(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, fallbacks.DataPoints, 1)
+	assert.Equal(t, int64(1), fallbacks.DataPoints[0].Value)
+
+	total, ok := found["db.pool.connections.total"].(metricdata.Gauge[int64])
+	require.True(t, ok)
+	assert.Len(t, total.DataPoints, 2, "one series per pool")
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/agentflow/agentflow/internal/storage/dbpool"
 	"github.com/agentflow/agentflow/internal/storage/queries"
 	"github.com/agentflow/agentflow/pkg/messaging"
 )
@@ -24,6 +25,7 @@ type MessageQuerier interface {
 type Service struct {
 	db         *pgxpool.Pool
 	queries    MessageQuerier
+	reader     MessageQuerier
 	serializer *messaging.CanonicalSerializer
 }
 
@@ -34,13 +36,26 @@ func NewService(db *pgxpool.Pool) (*Service, error) {
 		return nil, fmt.Errorf("failed to create canonical serializer: %w", err)
 	}
 
+	q := queries.New(db)
 	return &Service{
 		db:         db,
-		queries:    queries.New(db),
+		queries:    q,
+		reader:     q,
 		serializer: serializer,
 	}, nil
 }
 
+// NewClusterService creates a message service that writes to the cluster
+// primary and lists traces from read replicas when they are within the lag limit
+func NewClusterService(cluster *dbpool.Cluster) (*Service, error) {
+	s, err := NewService(cluster.Primary())
+	if err != nil {
+		return nil, err
+	}
+	s.reader = queries.New(cluster.Reader())
+	return s, nil
+}
+
 // CreateMessage stores a message with envelope hash validation
 func (s *Service) CreateMessage(ctx context.Context, msg *messaging.Message, tenantID uuid.UUID) error {
 	// Validate that envelope_hash is present
@@ -107,7 +122,7 @@ func (s *Service) ValidateMessageIntegrity(ctx context.Context, messageID, tenan
 
 // ListMessagesByTrace retrieves messages for a trace and validates their integrity
 func (s *Service) ListMessagesByTrace(ctx context.Context, tenantID uuid.UUID, traceID string) ([]*messaging.Message, error) {
-	dbMessages, err := s.queries.ListMessagesByTrace(ctx, queries.ListMessagesByTraceParams{
+	dbMessages, err := s.reader.ListMessagesByTrace(ctx, queries.ListMessagesByTraceParams{
 		TenantID: pgtype.UUID{Bytes: tenantID, Valid: true},
 		TraceID:  pgtype.Text{String: traceID, Valid: true},
 	})
@@ -158,6 +158,7 @@ func TestService_ListMessagesByTrace(t *testing.T) {
 	mockQueries := NewMockQueries()
 	service := &Service{
 		queries: mockQueries,
+		reader:  mockQueries,
 	}
 
 	// Initialize serializer
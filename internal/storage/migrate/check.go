@@ -0,0 +1,57 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/migrations"
+)
+
+// Schema check modes used by services at startup
+const (
+	CheckOff     = "off"     // do not inspect the schema
+	CheckWarn    = "warn"    // log problems and continue
+	CheckEnforce = "enforce" // refuse to start on pending, dirty or modified migrations
+)
+
+// Embedded returns the migrations compiled into the binary
+func Embedded() ([]Migration, error) {
+	return Load(migrations.FS)
+}
+
+// CheckSchema inspects the schema at startup according to mode. In enforce
+// mode it returns an error when the schema is not current and clean.
+func CheckSchema(ctx context.Context, conn Conn, logger logging.Logger, mode string) (*Status, error) {
+	switch mode {
+	case CheckOff:
+		return nil, nil
+	case CheckWarn, CheckEnforce:
+	default:
+		return nil, fmt.Errorf("invalid schema check mode %q: must be %s, %s or %s", mode, CheckOff, CheckWarn, CheckEnforce)
+	}
+
+	embedded, err := Embedded()
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := NewRunner(conn, embedded, logger).Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema status: %w", err)
+	}
+
+	if problem := status.Err(); problem != nil {
+		if mode == CheckEnforce {
+			return status, problem
+		}
+		logger.Warn("Database schema is not current",
+			logging.String("problem", problem.Error()),
+			logging.Int("pending", status.Pending))
+		return status, nil
+	}
+
+	logger.Info("Database schema is current",
+		logging.Int("version", int(status.CurrentVersion)))
+	return status, nil
+}
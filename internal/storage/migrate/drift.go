@@ -0,0 +1,205 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// scratchSchema is created inside a rolled back transaction to build the expected schema
+const scratchSchema = "af_drift_check"
+
+// concurrentlyPattern matches CONCURRENTLY, which cannot run inside the drift check transaction
+var concurrentlyPattern = regexp.MustCompile(`(?i)\s+CONCURRENTLY\b`)
+
+// SchemaSnapshot captures the structure of the tables in one schema
+type SchemaSnapshot struct {
+	Columns     map[string]string // "table.column" -> type, nullability and default
+	Indexes     map[string]string // "table.index" -> definition
+	Constraints map[string]string // "table.constraint" -> definition
+}
+
+// DriftItem is a single difference between the expected and actual schema
+type DriftItem struct {
+	Kind     string `json:"kind"` // "column", "index" or "constraint"
+	Object   string `json:"object"`
+	Problem  string `json:"problem"` // "missing", "unexpected" or "changed"
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// DriftReport lists differences between the schema produced by the applied
+// migrations and the live schema
+type DriftReport struct {
+	Schema string      `json:"schema"`
+	Items  []DriftItem `json:"items"`
+}
+
+// HasDrift reports whether the live schema differs from the expected one
+func (d *DriftReport) HasDrift() bool {
+	return len(d.Items) > 0
+}
+
+// Drift compares the live schema with the schema produced by applying the
+// currently applied migrations to an empty scratch schema. The scratch schema
+// lives in a transaction that is always rolled back.
+func (r *Runner) Drift(ctx context.Context, schema string) (*DriftReport, error) {
+	if schema == "" {
+		schema = "public"
+	}
+
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, err := snapshot(ctx, r.conn, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, "CREATE SCHEMA "+scratchSchema); err != nil {
+		return nil, fmt.Errorf("failed to create drift check schema: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "SET LOCAL search_path TO "+scratchSchema+", "+schema); err != nil {
+		return nil, fmt.Errorf("failed to set search_path: %w", err)
+	}
+
+	for _, row := range applied {
+		m := r.find(row.Version)
+		if m == nil {
+			return nil, fmt.Errorf("%w: cannot rebuild version %d", ErrUnknownMigration, row.Version)
+		}
+		for _, stmt := range m.Up {
+			if m.NoTransaction {
+				stmt = concurrentlyPattern.ReplaceAllString(stmt, "")
+			}
+			if _, err := tx.Exec(ctx, stmt); err != nil {
+				return nil, fmt.Errorf("failed to rebuild migration %d_%s: %w", m.Version, m.Name, err)
+			}
+		}
+	}
+
+	expected, err := snapshot(ctx, tx, scratchSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DriftReport{Schema: schema, Items: diffSnapshots(expected, actual)}, nil
+}
+
+// rowsQuerier is satisfied by Conn and pgx.Tx
+type rowsQuerier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// ignoredTables are bookkeeping tables that migrations do not create
+var ignoredTables = map[string]bool{
+	TableName:  true,
+	gooseTable: true,
+}
+
+// snapshot reads the columns, indexes and constraints of schema. Schema
+// qualifiers are stripped from definitions so snapshots of different schemas compare equal.
+func snapshot(ctx context.Context, conn rowsQuerier, schema string) (*SchemaSnapshot, error) {
+	snap := &SchemaSnapshot{
+		Columns:     make(map[string]string),
+		Indexes:     make(map[string]string),
+		Constraints: make(map[string]string),
+	}
+	normalize := func(def string) string {
+		def = strings.ReplaceAll(def, `"`+schema+`".`, "")
+		return strings.ReplaceAll(def, schema+".", "")
+	}
+
+	queries := []struct {
+		target map[string]string
+		sql    string
+	}{
+		{
+			target: snap.Columns,
+			sql: `SELECT c.table_name, c.column_name,
+       c.data_type || COALESCE('(' || c.character_maximum_length || ')', '') ||
+       CASE WHEN c.is_nullable = 'NO' THEN ' NOT NULL' ELSE '' END ||
+       COALESCE(' DEFAULT ' || c.column_default, '')
+FROM information_schema.columns c
+JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE c.table_schema = $1 AND t.table_type = 'BASE TABLE'`,
+		},
+		{
+			target: snap.Indexes,
+			sql:    `SELECT tablename, indexname, indexdef FROM pg_indexes WHERE schemaname = $1`,
+		},
+		{
+			target: snap.Constraints,
+			sql: `SELECT rel.relname, con.conname, pg_get_constraintdef(con.oid)
+FROM pg_constraint con
+JOIN pg_class rel ON rel.oid = con.conrelid
+JOIN pg_namespace ns ON ns.oid = rel.relnamespace
+WHERE ns.nspname = $1`,
+		},
+	}
+
+	for _, q := range queries {
+		rows, err := conn.Query(ctx, q.sql, schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot schema %s: %w", schema, err)
+		}
+		for rows.Next() {
+			var table, name, def string
+			if err := rows.Scan(&table, &name, &def); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to snapshot schema %s: %w", schema, err)
+			}
+			if ignoredTables[table] {
+				continue
+			}
+			q.target[table+"."+name] = normalize(def)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to snapshot schema %s: %w", schema, err)
+		}
+	}
+
+	return snap, nil
+}
+
+// diffSnapshots lists the differences between two snapshots in a stable order
+func diffSnapshots(expected, actual *SchemaSnapshot) []DriftItem {
+	items := []DriftItem{}
+	items = append(items, diffMaps("column", expected.Columns, actual.Columns)...)
+	items = append(items, diffMaps("index", expected.Indexes, actual.Indexes)...)
+	items = append(items, diffMaps("constraint", expected.Constraints, actual.Constraints)...)
+	return items
+}
+
+func diffMaps(kind string, expected, actual map[string]string) []DriftItem {
+	var items []DriftItem
+	for name, want := range expected {
+		got, ok := actual[name]
+		switch {
+		case !ok:
+			items = append(items, DriftItem{Kind: kind, Object: name, Problem: "missing", Expected: want})
+		case got != want:
+			items = append(items, DriftItem{Kind: kind, Object: name, Problem: "changed", Expected: want, Actual: got})
+		}
+	}
+	for name, got := range actual {
+		if _, ok := expected[name]; !ok {
+			items = append(items, DriftItem{Kind: kind, Object: name, Problem: "unexpected", Actual: got})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Object < items[j].Object })
+	return items
+}
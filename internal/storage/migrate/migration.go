@@ -0,0 +1,211 @@
+// Package migrate applies the embedded goose-format SQL migrations and reports
+// schema status, edited migrations and drift
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFilePattern matches YYYYMMDDHHMMSS_description.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d{14})_([a-z0-9_]+)\.sql$`)
+
+// Migration is a single parsed migration file
+type Migration struct {
+	Version  int64
+	Name     string
+	Filename string
+	Checksum string // sha256 of the file contents
+
+	Up   []string
+	Down []string
+
+	// NoTransaction is set by "-- +goose NO TRANSACTION" and applies to both directions
+	NoTransaction bool
+}
+
+// Load reads and parses every migration in fsys, sorted by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int64]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, err := Parse(entry.Name(), content)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[migration.Version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d in %s and %s", migration.Version, other, entry.Name())
+		}
+		seen[migration.Version] = entry.Name()
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Parse parses a goose-format SQL migration. Statements are split on
+// semicolons at the end of a line unless enclosed in
+// "-- +goose StatementBegin" / "-- +goose StatementEnd".
+func Parse(filename string, content []byte) (Migration, error) {
+	match := migrationFilePattern.FindStringSubmatch(filename)
+	if match == nil {
+		return Migration{}, fmt.Errorf("migration %s does not follow naming convention YYYYMMDDHHMMSS_description.sql", filename)
+	}
+	version, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return Migration{}, fmt.Errorf("invalid migration version in %s: %w", filename, err)
+	}
+
+	sum := sha256.Sum256(content)
+	migration := Migration{
+		Version:  version,
+		Name:     match[2],
+		Filename: filename,
+		Checksum: hex.EncodeToString(sum[:]),
+	}
+
+	const (
+		sectionNone = iota
+		sectionUp
+		sectionDown
+	)
+	section := sectionNone
+	inBlock := false
+	var current strings.Builder
+
+	flush := func() {
+		stmt := strings.TrimSpace(current.String())
+		current.Reset()
+		if stmt == "" || isOnlyComments(stmt) {
+			return
+		}
+		switch section {
+		case sectionUp:
+			migration.Up = append(migration.Up, stmt)
+		case sectionDown:
+			migration.Down = append(migration.Down, stmt)
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNo++
+
+		if directive, ok := gooseDirective(line); ok {
+			switch directive {
+			case "up":
+				if section != sectionNone {
+					return Migration{}, fmt.Errorf("%s:%d: unexpected '-- +goose Up'", filename, lineNo)
+				}
+				section = sectionUp
+			case "down":
+				if section != sectionUp || inBlock {
+					return Migration{}, fmt.Errorf("%s:%d: unexpected '-- +goose Down'", filename, lineNo)
+				}
+				flush()
+				section = sectionDown
+			case "statementbegin":
+				if inBlock {
+					return Migration{}, fmt.Errorf("%s:%d: nested StatementBegin", filename, lineNo)
+				}
+				flush()
+				inBlock = true
+			case "statementend":
+				if !inBlock {
+					return Migration{}, fmt.Errorf("%s:%d: StatementEnd without StatementBegin", filename, lineNo)
+				}
+				flush()
+				inBlock = false
+			case "no transaction":
+				migration.NoTransaction = true
+			default:
+				return Migration{}, fmt.Errorf("%s:%d: unknown goose directive %q", filename, lineNo, directive)
+			}
+			continue
+		}
+
+		if section == sectionNone {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteByte('\n')
+		if !inBlock && endsStatement(line) {
+			flush()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Migration{}, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	if inBlock {
+		return Migration{}, fmt.Errorf("%s: missing StatementEnd", filename)
+	}
+	flush()
+
+	if section == sectionNone {
+		return Migration{}, fmt.Errorf("%s: missing '-- +goose Up'", filename)
+	}
+	if len(migration.Up) == 0 {
+		return Migration{}, fmt.Errorf("%s: empty Up section", filename)
+	}
+	return migration, nil
+}
+
+// gooseDirective returns the lowercased directive of a "-- +goose" line
+func gooseDirective(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "--") {
+		return "", false
+	}
+	trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "--"))
+	if !strings.HasPrefix(trimmed, "+goose") {
+		return "", false
+	}
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(trimmed, "+goose"))), true
+}
+
+// endsStatement reports whether a line terminates a statement, ignoring trailing comments
+func endsStatement(line string) bool {
+	if idx := strings.Index(line, "--"); idx >= 0 && !strings.Contains(line[:idx], "'") {
+		line = line[:idx]
+	}
+	return strings.HasSuffix(strings.TrimSpace(line), ";")
+}
+
+// isOnlyComments reports whether a statement consists solely of SQL line comments
+func isOnlyComments(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
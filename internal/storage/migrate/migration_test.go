@@ -0,0 +1,103 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Embedded()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.NotEmpty(t, m.Up, "migration %s has no up statements", m.Filename)
+		assert.NotEmpty(t, m.Down, "migration %s has no down statements", m.Filename)
+		assert.Len(t, m.Checksum, 64)
+		if i > 0 {
+			assert.Greater(t, m.Version, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	content := `-- +goose Up
+-- create the table
+CREATE TABLE t (
+    id INT -- trailing comment;
+);
+CREATE INDEX idx_t ON t(id); -- index
+-- +goose StatementBegin
+CREATE FUNCTION f() RETURNS trigger AS $$
+BEGIN
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION f();
+DROP TABLE t;
+`
+	m, err := Parse("20250101000000_create_t.sql", []byte(content))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(20250101000000), m.Version)
+	assert.Equal(t, "create_t", m.Name)
+	assert.False(t, m.NoTransaction)
+	require.Len(t, m.Up, 3)
+	assert.True(t, strings.HasPrefix(m.Up[0], "-- create the table\nCREATE TABLE t"))
+	assert.Equal(t, "CREATE INDEX idx_t ON t(id); -- index", m.Up[1])
+	assert.Contains(t, m.Up[2], "END;\n$$ LANGUAGE plpgsql;")
+	assert.Equal(t, []string{"DROP FUNCTION f();", "DROP TABLE t;"}, m.Down)
+}
+
+func TestParse_NoTransaction(t *testing.T) {
+	content := "-- +goose NO TRANSACTION\n-- +goose Up\nCREATE INDEX CONCURRENTLY idx ON t(id);\n-- +goose Down\nDROP INDEX idx;\n"
+	m, err := Parse("20250101000001_index.sql", []byte(content))
+	require.NoError(t, err)
+	assert.True(t, m.NoTransaction)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+	}{
+		{"bad filename", "create_t.sql", "-- +goose Up\nSELECT 1;\n"},
+		{"missing up", "20250101000000_t.sql", "SELECT 1;\n"},
+		{"down before up", "20250101000000_t.sql", "-- +goose Down\nSELECT 1;\n-- +goose Up\nSELECT 1;\n"},
+		{"empty up", "20250101000000_t.sql", "-- +goose Up\n-- +goose Down\nSELECT 1;\n"},
+		{"unterminated block", "20250101000000_t.sql", "-- +goose Up\n-- +goose StatementBegin\nSELECT 1;\n"},
+		{"unknown directive", "20250101000000_t.sql", "-- +goose Up\n-- +goose Sideways\nSELECT 1;\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.filename, []byte(tt.content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoad_DuplicateVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20250101000000_a.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+		"20250101000000_b.sql": {Data: []byte("-- +goose Up\nSELECT 2;\n")},
+	}
+	_, err := Load(fsys)
+	assert.ErrorContains(t, err, "duplicate migration version")
+}
+
+func TestChecksumDetectsEdits(t *testing.T) {
+	original, err := Parse("20250101000000_t.sql", []byte("-- +goose Up\nSELECT 1;\n"))
+	require.NoError(t, err)
+	edited, err := Parse("20250101000000_t.sql", []byte("-- +goose Up\nSELECT 2;\n"))
+	require.NoError(t, err)
+	assert.NotEqual(t, original.Checksum, edited.Checksum)
+}
@@ -0,0 +1,380 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// TableName is the bookkeeping table recording applied migrations
+const TableName = "agentflow_schema_migrations"
+
+// gooseTable is the version table written by the goose CLI, adopted on first run
+const gooseTable = "goose_db_version"
+
+// advisoryLockKey serializes migration runners across processes
+const advisoryLockKey int64 = 0x6166_6d69_6772_6174 // "afmigrat"
+
+// Conn is a single database session. Both *pgx.Conn and *pgxpool.Conn satisfy it;
+// a pool cannot be used directly because the advisory lock is session scoped.
+type Conn interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Runner applies and rolls back migrations
+type Runner struct {
+	conn       Conn
+	migrations []Migration
+	logger     logging.Logger
+}
+
+// NewRunner creates a migration runner for the given migrations
+func NewRunner(conn Conn, migrations []Migration, logger logging.Logger) *Runner {
+	return &Runner{
+		conn:       conn,
+		migrations: migrations,
+		logger:     logger,
+	}
+}
+
+// Result describes a migration applied or rolled back by the runner
+type Result struct {
+	Version   int64  `json:"version"`
+	Name      string `json:"name"`
+	Direction string `json:"direction"` // "up" or "down"
+	Duration  string `json:"duration"`
+}
+
+// Status returns the schema status without modifying the database
+func (r *Runner) Status(ctx context.Context) (*Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return computeStatus(r.migrations, applied), nil
+}
+
+// Up applies pending migrations up to and including target. A target of 0
+// applies every pending migration.
+func (r *Runner) Up(ctx context.Context, target int64) ([]Result, error) {
+	var results []Result
+	err := r.withLock(ctx, func() error {
+		status, err := r.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if status.Dirty {
+			return status.Err()
+		}
+		if len(status.Modified) > 0 {
+			return status.Err()
+		}
+
+		for _, ms := range status.Migrations {
+			if ms.State != StatePending || (target > 0 && ms.Version > target) {
+				continue
+			}
+			m := r.find(ms.Version)
+			result, err := r.apply(ctx, m, "up")
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	return results, err
+}
+
+// Down rolls back applied migrations newer than target. A target of -1 rolls
+// back only the most recent migration.
+func (r *Runner) Down(ctx context.Context, target int64) ([]Result, error) {
+	var results []Result
+	err := r.withLock(ctx, func() error {
+		applied, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(applied) - 1; i >= 0; i-- {
+			row := applied[i]
+			if target >= 0 && row.Version <= target {
+				break
+			}
+			m := r.find(row.Version)
+			if m == nil {
+				return fmt.Errorf("%w: cannot roll back version %d", ErrUnknownMigration, row.Version)
+			}
+			result, err := r.apply(ctx, m, "down")
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+			if target < 0 {
+				break
+			}
+		}
+		return nil
+	})
+	return results, err
+}
+
+// Redo rolls back the most recent migration and applies it again
+func (r *Runner) Redo(ctx context.Context) ([]Result, error) {
+	var results []Result
+	err := r.withLock(ctx, func() error {
+		applied, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			return errors.New("no applied migrations to redo")
+		}
+		m := r.find(applied[len(applied)-1].Version)
+		if m == nil {
+			return fmt.Errorf("%w: cannot redo version %d", ErrUnknownMigration, applied[len(applied)-1].Version)
+		}
+
+		for _, direction := range []string{"down", "up"} {
+			result, err := r.apply(ctx, m, direction)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	return results, err
+}
+
+// find returns the embedded migration with the given version
+func (r *Runner) find(version int64) *Migration {
+	for i := range r.migrations {
+		if r.migrations[i].Version == version {
+			return &r.migrations[i]
+		}
+	}
+	return nil
+}
+
+// withLock ensures the bookkeeping table exists and runs fn under the migration advisory lock
+func (r *Runner) withLock(ctx context.Context, fn func() error) error {
+	if _, err := r.conn.Exec(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx was cancelled
+		if _, err := r.conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey); err != nil {
+			r.logger.Error("Failed to release migration lock", err)
+		}
+	}()
+
+	if err := r.ensureTable(ctx); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// ensureTable creates the bookkeeping table and adopts goose history on first use
+func (r *Runner) ensureTable(ctx context.Context) error {
+	_, err := r.conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+TableName+` (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    dirty BOOLEAN NOT NULL DEFAULT FALSE,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    execution_ms BIGINT NOT NULL DEFAULT 0
+)`)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", TableName, err)
+	}
+
+	var count int
+	if err := r.conn.QueryRow(ctx, "SELECT COUNT(*) FROM "+TableName).Scan(&count); err != nil {
+		return fmt.Errorf("failed to read %s: %w", TableName, err)
+	}
+	if count > 0 {
+		return nil
+	}
+	return r.adoptGoose(ctx)
+}
+
+// adoptGoose imports versions applied by the goose CLI so existing databases
+// are not migrated twice. The checksum of the embedded file is recorded since
+// goose does not keep one.
+func (r *Runner) adoptGoose(ctx context.Context) error {
+	var exists bool
+	if err := r.conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", gooseTable).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up %s: %w", gooseTable, err)
+	}
+	if !exists {
+		return nil
+	}
+
+	rows, err := r.conn.Query(ctx, `SELECT DISTINCT ON (version_id) version_id, is_applied, tstamp
+FROM `+gooseTable+`
+WHERE version_id > 0
+ORDER BY version_id, id DESC`)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", gooseTable, err)
+	}
+	type gooseRow struct {
+		version int64
+		applied bool
+		ts      time.Time
+	}
+	var gooseRows []gooseRow
+	for rows.Next() {
+		var row gooseRow
+		if err := rows.Scan(&row.version, &row.applied, &row.ts); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan %s: %w", gooseTable, err)
+		}
+		gooseRows = append(gooseRows, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", gooseTable, err)
+	}
+
+	for _, row := range gooseRows {
+		if !row.applied {
+			continue
+		}
+		name, checksum := "", ""
+		if m := r.find(row.version); m != nil {
+			name, checksum = m.Name, m.Checksum
+		}
+		_, err := r.conn.Exec(ctx, "INSERT INTO "+TableName+" (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)",
+			row.version, name, checksum, row.ts)
+		if err != nil {
+			return fmt.Errorf("failed to adopt goose version %d: %w", row.version, err)
+		}
+		r.logger.Info("Adopted migration applied by goose",
+			logging.Int("version", int(row.version)),
+			logging.String("name", name))
+	}
+	return nil
+}
+
+// applied returns the applied migrations ordered by version. A missing
+// bookkeeping table means nothing has been applied.
+func (r *Runner) applied(ctx context.Context) ([]AppliedMigration, error) {
+	var exists bool
+	if err := r.conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", TableName).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", TableName, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := r.conn.Query(ctx, "SELECT version, name, checksum, dirty, applied_at, execution_ms FROM "+TableName+" ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", TableName, err)
+	}
+	defer rows.Close()
+
+	var applied []AppliedMigration
+	for rows.Next() {
+		var row AppliedMigration
+		if err := rows.Scan(&row.Version, &row.Name, &row.Checksum, &row.Dirty, &row.AppliedAt, &row.ExecutionMS); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", TableName, err)
+		}
+		applied = append(applied, row)
+	}
+	return applied, rows.Err()
+}
+
+// apply runs one direction of a migration and records the outcome
+func (r *Runner) apply(ctx context.Context, m *Migration, direction string) (Result, error) {
+	statements := m.Up
+	if direction == "down" {
+		statements = m.Down
+	}
+
+	r.logger.Info("Applying migration",
+		logging.Int("version", int(m.Version)),
+		logging.String("name", m.Name),
+		logging.String("direction", direction))
+
+	start := time.Now()
+	var err error
+	if m.NoTransaction {
+		err = r.applyWithoutTx(ctx, m, direction, statements, start)
+	} else {
+		err = r.applyInTx(ctx, m, direction, statements, start)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("migration %d_%s %s failed: %w", m.Version, m.Name, direction, err)
+	}
+
+	return Result{
+		Version:   m.Version,
+		Name:      m.Name,
+		Direction: direction,
+		Duration:  time.Since(start).String(),
+	}, nil
+}
+
+// applyInTx runs the statements and the bookkeeping update in a single transaction
+func (r *Runner) applyInTx(ctx context.Context, m *Migration, direction string, statements []string, start time.Time) error {
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	if direction == "up" {
+		_, err = tx.Exec(ctx, "INSERT INTO "+TableName+" (version, name, checksum, execution_ms) VALUES ($1, $2, $3, $4)",
+			m.Version, m.Name, m.Checksum, time.Since(start).Milliseconds())
+	} else {
+		_, err = tx.Exec(ctx, "DELETE FROM "+TableName+" WHERE version = $1", m.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// applyWithoutTx runs statements that cannot run inside a transaction. The
+// version is marked dirty first so a partial failure is visible in Status.
+func (r *Runner) applyWithoutTx(ctx context.Context, m *Migration, direction string, statements []string, start time.Time) error {
+	var err error
+	if direction == "up" {
+		_, err = r.conn.Exec(ctx, "INSERT INTO "+TableName+" (version, name, checksum, dirty) VALUES ($1, $2, $3, TRUE)",
+			m.Version, m.Name, m.Checksum)
+	} else {
+		_, err = r.conn.Exec(ctx, "UPDATE "+TableName+" SET dirty = TRUE WHERE version = $1", m.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range statements {
+		if _, err := r.conn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	if direction == "up" {
+		_, err = r.conn.Exec(ctx, "UPDATE "+TableName+" SET dirty = FALSE, execution_ms = $2 WHERE version = $1",
+			m.Version, time.Since(start).Milliseconds())
+	} else {
+		_, err = r.conn.Exec(ctx, "DELETE FROM "+TableName+" WHERE version = $1", m.Version)
+	}
+	return err
+}
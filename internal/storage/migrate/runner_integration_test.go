@@ -0,0 +1,77 @@
+//go:build integration
+// +build integration
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/agentflow/agentflow/internal/logging"
+)
+
+// TestRunnerIntegration applies the embedded migrations to a throwaway schema
+func TestRunnerIntegration(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, dbURL)
+	if err != nil {
+		t.Skip("Database not available")
+	}
+	defer conn.Close(ctx)
+
+	schema := fmt.Sprintf("af_migrate_test_%d", time.Now().UnixNano())
+	_, err = conn.Exec(ctx, "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+	defer func() { _, _ = conn.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE") }()
+	_, err = conn.Exec(ctx, "SET search_path TO "+schema+", public")
+	require.NoError(t, err)
+
+	embedded, err := Embedded()
+	require.NoError(t, err)
+	runner := NewRunner(conn, embedded, logging.NewLogger())
+
+	status, err := runner.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(embedded), status.Pending)
+
+	results, err := runner.Up(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, results, len(embedded))
+
+	status, err = runner.Status(ctx)
+	require.NoError(t, err)
+	assert.NoError(t, status.Err())
+	assert.Equal(t, embedded[len(embedded)-1].Version, status.CurrentVersion)
+
+	report, err := runner.Drift(ctx, schema)
+	require.NoError(t, err)
+	assert.False(t, report.HasDrift(), "unexpected drift: %+v", report.Items)
+
+	_, err = conn.Exec(ctx, "ALTER TABLE "+schema+".tenants ADD COLUMN drift_probe TEXT")
+	require.NoError(t, err)
+	report, err = runner.Drift(ctx, schema)
+	require.NoError(t, err)
+	assert.Contains(t, report.Items, DriftItem{Kind: "column", Object: "tenants.drift_probe", Problem: "unexpected", Actual: "text"})
+
+	// Keep the first migration so the shared uuid-ossp extension is not dropped
+	results, err = runner.Down(ctx, embedded[0].Version)
+	require.NoError(t, err)
+	assert.Len(t, results, len(embedded)-1)
+
+	status, err = runner.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, embedded[0].Version, status.CurrentVersion)
+	assert.ErrorIs(t, status.Err(), ErrPendingMigrations)
+}
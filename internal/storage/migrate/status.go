@@ -0,0 +1,134 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrPendingMigrations is returned when embedded migrations have not been applied
+	ErrPendingMigrations = errors.New("database schema has pending migrations")
+	// ErrDirtySchema is returned when a migration failed part way through
+	ErrDirtySchema = errors.New("database schema is dirty")
+	// ErrChecksumMismatch is returned when an applied migration was edited afterwards
+	ErrChecksumMismatch = errors.New("applied migration has been modified")
+	// ErrUnknownMigration is returned when the database has a migration this binary does not know
+	ErrUnknownMigration = errors.New("database has migrations unknown to this binary")
+)
+
+// Migration states reported by Status
+const (
+	StateApplied  = "applied"
+	StatePending  = "pending"
+	StateModified = "modified"
+	StateDirty    = "dirty"
+	StateUnknown  = "unknown"
+)
+
+// AppliedMigration is a row of the schema migrations table
+type AppliedMigration struct {
+	Version     int64
+	Name        string
+	Checksum    string
+	Dirty       bool
+	AppliedAt   time.Time
+	ExecutionMS int64
+}
+
+// MigrationStatus describes the state of a single migration
+type MigrationStatus struct {
+	Version         int64      `json:"version"`
+	Name            string     `json:"name"`
+	State           string     `json:"state"`
+	AppliedAt       *time.Time `json:"applied_at,omitempty"`
+	Checksum        string     `json:"checksum,omitempty"`
+	AppliedChecksum string     `json:"applied_checksum,omitempty"`
+}
+
+// Status summarizes the schema state of a database
+type Status struct {
+	CurrentVersion int64             `json:"current_version"`
+	LatestVersion  int64             `json:"latest_version"`
+	Pending        int               `json:"pending"`
+	Dirty          bool              `json:"dirty"`
+	Modified       []int64           `json:"modified,omitempty"`
+	Unknown        []int64           `json:"unknown,omitempty"`
+	Migrations     []MigrationStatus `json:"migrations"`
+	Drift          *DriftReport      `json:"drift,omitempty"`
+}
+
+// Err returns nil when the schema is current and clean, or the most severe problem otherwise
+func (s *Status) Err() error {
+	switch {
+	case s.Dirty:
+		return fmt.Errorf("%w at version %d", ErrDirtySchema, s.CurrentVersion)
+	case len(s.Modified) > 0:
+		return fmt.Errorf("%w: versions %v", ErrChecksumMismatch, s.Modified)
+	case len(s.Unknown) > 0:
+		return fmt.Errorf("%w: versions %v", ErrUnknownMigration, s.Unknown)
+	case s.Pending > 0:
+		return fmt.Errorf("%w: %d pending, database at %d, latest is %d", ErrPendingMigrations, s.Pending, s.CurrentVersion, s.LatestVersion)
+	}
+	return nil
+}
+
+// computeStatus merges the embedded migrations with the applied rows
+func computeStatus(migrations []Migration, applied []AppliedMigration) *Status {
+	status := &Status{}
+	byVersion := make(map[int64]AppliedMigration, len(applied))
+	for _, row := range applied {
+		byVersion[row.Version] = row
+		if row.Version > status.CurrentVersion {
+			status.CurrentVersion = row.Version
+		}
+	}
+
+	known := make(map[int64]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		if m.Version > status.LatestVersion {
+			status.LatestVersion = m.Version
+		}
+
+		ms := MigrationStatus{Version: m.Version, Name: m.Name, Checksum: m.Checksum, State: StatePending}
+		if row, ok := byVersion[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			ms.AppliedAt = &appliedAt
+			ms.AppliedChecksum = row.Checksum
+			switch {
+			case row.Dirty:
+				ms.State = StateDirty
+				status.Dirty = true
+			case row.Checksum != m.Checksum:
+				ms.State = StateModified
+				status.Modified = append(status.Modified, m.Version)
+			default:
+				ms.State = StateApplied
+			}
+		} else {
+			status.Pending++
+		}
+		status.Migrations = append(status.Migrations, ms)
+	}
+
+	for _, row := range applied {
+		if known[row.Version] {
+			continue
+		}
+		appliedAt := row.AppliedAt
+		status.Unknown = append(status.Unknown, row.Version)
+		status.Migrations = append(status.Migrations, MigrationStatus{
+			Version:         row.Version,
+			Name:            row.Name,
+			State:           StateUnknown,
+			AppliedAt:       &appliedAt,
+			AppliedChecksum: row.Checksum,
+		})
+		if row.Dirty {
+			status.Dirty = true
+		}
+	}
+
+	return status
+}
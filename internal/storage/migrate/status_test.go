@@ -0,0 +1,137 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Name: "one", Checksum: "c1"},
+		{Version: 2, Name: "two", Checksum: "c2"},
+		{Version: 3, Name: "three", Checksum: "c3"},
+	}
+}
+
+func TestComputeStatus(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		applied  []AppliedMigration
+		states   []string
+		err      error
+		current  int64
+		pending  int
+		modified []int64
+	}{
+		{
+			name:    "fresh database",
+			states:  []string{StatePending, StatePending, StatePending},
+			err:     ErrPendingMigrations,
+			pending: 3,
+		},
+		{
+			name: "fully applied",
+			applied: []AppliedMigration{
+				{Version: 1, Checksum: "c1", AppliedAt: now},
+				{Version: 2, Checksum: "c2", AppliedAt: now},
+				{Version: 3, Checksum: "c3", AppliedAt: now},
+			},
+			states:  []string{StateApplied, StateApplied, StateApplied},
+			current: 3,
+		},
+		{
+			name: "edited migration",
+			applied: []AppliedMigration{
+				{Version: 1, Checksum: "c1", AppliedAt: now},
+				{Version: 2, Checksum: "edited", AppliedAt: now},
+			},
+			states:   []string{StateApplied, StateModified, StatePending},
+			err:      ErrChecksumMismatch,
+			current:  2,
+			pending:  1,
+			modified: []int64{2},
+		},
+		{
+			name: "dirty migration",
+			applied: []AppliedMigration{
+				{Version: 1, Checksum: "c1", AppliedAt: now},
+				{Version: 2, Checksum: "c2", AppliedAt: now, Dirty: true},
+			},
+			states:  []string{StateApplied, StateDirty, StatePending},
+			err:     ErrDirtySchema,
+			current: 2,
+			pending: 1,
+		},
+		{
+			name: "unknown migration",
+			applied: []AppliedMigration{
+				{Version: 1, Checksum: "c1", AppliedAt: now},
+				{Version: 2, Checksum: "c2", AppliedAt: now},
+				{Version: 3, Checksum: "c3", AppliedAt: now},
+				{Version: 4, Name: "future", Checksum: "c4", AppliedAt: now},
+			},
+			states:  []string{StateApplied, StateApplied, StateApplied, StateUnknown},
+			err:     ErrUnknownMigration,
+			current: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := computeStatus(testMigrations(), tt.applied)
+
+			var states []string
+			for _, ms := range status.Migrations {
+				states = append(states, ms.State)
+			}
+			assert.Equal(t, tt.states, states)
+			assert.Equal(t, tt.current, status.CurrentVersion)
+			assert.Equal(t, int64(3), status.LatestVersion)
+			assert.Equal(t, tt.pending, status.Pending)
+			assert.Equal(t, tt.modified, status.Modified)
+
+			if tt.err == nil {
+				assert.NoError(t, status.Err())
+			} else {
+				assert.True(t, errors.Is(status.Err(), tt.err), "unexpected error: %v", status.Err())
+			}
+		})
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	expected := &SchemaSnapshot{
+		Columns: map[string]string{
+			"users.id":    "uuid NOT NULL DEFAULT uuid_generate_v4()",
+			"users.email": "character varying(255) NOT NULL",
+		},
+		Indexes: map[string]string{
+			"users.idx_users_tenant_id": "CREATE INDEX idx_users_tenant_id ON users USING btree (tenant_id)",
+		},
+		Constraints: map[string]string{},
+	}
+	actual := &SchemaSnapshot{
+		Columns: map[string]string{
+			"users.id":    "uuid NOT NULL DEFAULT uuid_generate_v4()",
+			"users.email": "text",
+			"users.extra": "text",
+		},
+		Indexes:     map[string]string{},
+		Constraints: map[string]string{},
+	}
+
+	items := diffSnapshots(expected, actual)
+	assert.Equal(t, []DriftItem{
+		{Kind: "column", Object: "users.email", Problem: "changed", Expected: "character varying(255) NOT NULL", Actual: "text"},
+		{Kind: "column", Object: "users.extra", Problem: "unexpected", Actual: "text"},
+		{Kind: "index", Object: "users.idx_users_tenant_id", Problem: "missing", Expected: "CREATE INDEX idx_users_tenant_id ON users USING btree (tenant_id)"},
+	}, items)
+
+	report := &DriftReport{Items: diffSnapshots(expected, expected)}
+	assert.False(t, report.HasDrift())
+}
@@ -5,28 +5,75 @@ RETURNING *;
 
 -- name: GetAgent :one
 SELECT * FROM agents
-WHERE id = $1 AND tenant_id = $2;
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL;
 
 -- name: GetAgentByName :one
 SELECT * FROM agents
-WHERE tenant_id = $1 AND name = $2;
+WHERE tenant_id = $1 AND name = $2 AND deleted_at IS NULL;
+
+-- name: GetAgentForUpdate :one
+SELECT * FROM agents
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE;
+
+-- name: CountAgentsByTenant :one
+SELECT COUNT(*) FROM agents
+WHERE tenant_id = $1 AND deleted_at IS NULL;
 
 -- name: ListAgentsByTenant :many
 SELECT * FROM agents
-WHERE tenant_id = $1
+WHERE tenant_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC;
 
 -- name: ListAgentsByType :many
 SELECT * FROM agents
-WHERE tenant_id = $1 AND type = $2
+WHERE tenant_id = $1 AND type = $2 AND deleted_at IS NULL
 ORDER BY created_at DESC;
 
+-- name: ListDeletedAgents :many
+SELECT * FROM agents
+WHERE tenant_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;
+
 -- name: UpdateAgent :one
 UPDATE agents
 SET name = $3, type = $4, role = $5, config_json = $6, policies_json = $7, updated_at = NOW()
-WHERE id = $1 AND tenant_id = $2
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: DeleteAgent :one
+UPDATE agents
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 RETURNING *;
 
--- name: DeleteAgent :exec
-DELETE FROM agents
-WHERE id = $1 AND tenant_id = $2;
\ No newline at end of file
+-- name: RestoreAgent :one
+UPDATE agents
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: CreateAgentRevision :one
+INSERT INTO agent_revisions (tenant_id, agent_id, revision, operation, name, type, role, config_json, policies_json, audit_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetAgentRevision :one
+SELECT * FROM agent_revisions
+WHERE tenant_id = $1 AND agent_id = $2 AND revision = $3;
+
+-- name: GetLatestAgentRevision :one
+SELECT * FROM agent_revisions
+WHERE tenant_id = $1 AND agent_id = $2
+ORDER BY revision DESC
+LIMIT 1;
+
+-- name: ListAgentRevisions :many
+SELECT * FROM agent_revisions
+WHERE tenant_id = $1 AND agent_id = $2
+ORDER BY revision DESC;
+
+-- name: ListAgentsForExport :many
+SELECT * FROM agents
+WHERE tenant_id = $1
+ORDER BY created_at ASC, id ASC;
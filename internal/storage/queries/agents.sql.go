@@ -11,10 +11,22 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countAgentsByTenant = `-- name: CountAgentsByTenant :one
+SELECT COUNT(*) FROM agents
+WHERE tenant_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) CountAgentsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countAgentsByTenant, tenantID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createAgent = `-- name: CreateAgent :one
 INSERT INTO agents (tenant_id, name, type, role, config_json, policies_json)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at
+RETURNING id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at, deleted_at
 `
 
 type CreateAgentParams struct {
@@ -46,13 +58,66 @@ func (q *Queries) CreateAgent(ctx context.Context, arg CreateAgentParams) (Agent
 		&i.PoliciesJson,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const deleteAgent = `-- name: DeleteAgent :exec
-DELETE FROM agents
-WHERE id = $1 AND tenant_id = $2
+const createAgentRevision = `-- name: CreateAgentRevision :one
+INSERT INTO agent_revisions (tenant_id, agent_id, revision, operation, name, type, role, config_json, policies_json, audit_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, tenant_id, agent_id, revision, operation, name, type, role, config_json, policies_json, audit_id, created_at
+`
+
+type CreateAgentRevisionParams struct {
+	TenantID     pgtype.UUID `json:"tenant_id"`
+	AgentID      pgtype.UUID `json:"agent_id"`
+	Revision     int32       `json:"revision"`
+	Operation    string      `json:"operation"`
+	Name         string      `json:"name"`
+	Type         string      `json:"type"`
+	Role         pgtype.Text `json:"role"`
+	ConfigJson   []byte      `json:"config_json"`
+	PoliciesJson []byte      `json:"policies_json"`
+	AuditID      pgtype.UUID `json:"audit_id"`
+}
+
+func (q *Queries) CreateAgentRevision(ctx context.Context, arg CreateAgentRevisionParams) (AgentRevision, error) {
+	row := q.db.QueryRow(ctx, createAgentRevision,
+		arg.TenantID,
+		arg.AgentID,
+		arg.Revision,
+		arg.Operation,
+		arg.Name,
+		arg.Type,
+		arg.Role,
+		arg.ConfigJson,
+		arg.PoliciesJson,
+		arg.AuditID,
+	)
+	var i AgentRevision
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.AgentID,
+		&i.Revision,
+		&i.Operation,
+		&i.Name,
+		&i.Type,
+		&i.Role,
+		&i.ConfigJson,
+		&i.PoliciesJson,
+		&i.AuditID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteAgent = `-- name: DeleteAgent :one
+UPDATE agents
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+RETURNING id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at, deleted_at
 `
 
 type DeleteAgentParams struct {
@@ -60,14 +125,27 @@ type DeleteAgentParams struct {
 	TenantID pgtype.UUID `json:"tenant_id"`
 }
 
-func (q *Queries) DeleteAgent(ctx context.Context, arg DeleteAgentParams) error {
-	_, err := q.db.Exec(ctx, deleteAgent, arg.ID, arg.TenantID)
-	return err
+func (q *Queries) DeleteAgent(ctx context.Context, arg DeleteAgentParams) (Agent, error) {
+	row := q.db.QueryRow(ctx, deleteAgent, arg.ID, arg.TenantID)
+	var i Agent
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Name,
+		&i.Type,
+		&i.Role,
+		&i.ConfigJson,
+		&i.PoliciesJson,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
 }
 
 const getAgent = `-- name: GetAgent :one
-SELECT id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at FROM agents
-WHERE id = $1 AND tenant_id = $2
+SELECT id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at, deleted_at FROM agents
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 `
 
 type GetAgentParams struct {
@@ -88,13 +166,14 @@ func (q *Queries) GetAgent(ctx context.Context, arg GetAgentParams) (Agent, erro
 		&i.PoliciesJson,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getAgentByName = `-- name: GetAgentByName :one
-SELECT id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at FROM agents
-WHERE tenant_id = $1 AND name = $2
+SELECT id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at, deleted_at FROM agents
+WHERE tenant_id = $1 AND name = $2 AND deleted_at IS NULL
 `
 
 type GetAgentByNameParams struct {
@@ -115,13 +194,150 @@ func (q *Queries) GetAgentByName(ctx context.Context, arg GetAgentByNameParams)
 		&i.PoliciesJson,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getAgentForUpdate = `-- name: GetAgentForUpdate :one
+SELECT id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at, deleted_at FROM agents
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE
+`
+
+type GetAgentForUpdateParams struct {
+	ID       pgtype.UUID `json:"id"`
+	TenantID pgtype.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) GetAgentForUpdate(ctx context.Context, arg GetAgentForUpdateParams) (Agent, error) {
+	row := q.db.QueryRow(ctx, getAgentForUpdate, arg.ID, arg.TenantID)
+	var i Agent
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Name,
+		&i.Type,
+		&i.Role,
+		&i.ConfigJson,
+		&i.PoliciesJson,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getAgentRevision = `-- name: GetAgentRevision :one
+SELECT id, tenant_id, agent_id, revision, operation, name, type, role, config_json, policies_json, audit_id, created_at FROM agent_revisions
+WHERE tenant_id = $1 AND agent_id = $2 AND revision = $3
+`
+
+type GetAgentRevisionParams struct {
+	TenantID pgtype.UUID `json:"tenant_id"`
+	AgentID  pgtype.UUID `json:"agent_id"`
+	Revision int32       `json:"revision"`
+}
+
+func (q *Queries) GetAgentRevision(ctx context.Context, arg GetAgentRevisionParams) (AgentRevision, error) {
+	row := q.db.QueryRow(ctx, getAgentRevision, arg.TenantID, arg.AgentID, arg.Revision)
+	var i AgentRevision
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.AgentID,
+		&i.Revision,
+		&i.Operation,
+		&i.Name,
+		&i.Type,
+		&i.Role,
+		&i.ConfigJson,
+		&i.PoliciesJson,
+		&i.AuditID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLatestAgentRevision = `-- name: GetLatestAgentRevision :one
+SELECT id, tenant_id, agent_id, revision, operation, name, type, role, config_json, policies_json, audit_id, created_at FROM agent_revisions
+WHERE tenant_id = $1 AND agent_id = $2
+ORDER BY revision DESC
+LIMIT 1
+`
+
+type GetLatestAgentRevisionParams struct {
+	TenantID pgtype.UUID `json:"tenant_id"`
+	AgentID  pgtype.UUID `json:"agent_id"`
+}
+
+func (q *Queries) GetLatestAgentRevision(ctx context.Context, arg GetLatestAgentRevisionParams) (AgentRevision, error) {
+	row := q.db.QueryRow(ctx, getLatestAgentRevision, arg.TenantID, arg.AgentID)
+	var i AgentRevision
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.AgentID,
+		&i.Revision,
+		&i.Operation,
+		&i.Name,
+		&i.Type,
+		&i.Role,
+		&i.ConfigJson,
+		&i.PoliciesJson,
+		&i.AuditID,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
+const listAgentRevisions = `-- name: ListAgentRevisions :many
+SELECT id, tenant_id, agent_id, revision, operation, name, type, role, config_json, policies_json, audit_id, created_at FROM agent_revisions
+WHERE tenant_id = $1 AND agent_id = $2
+ORDER BY revision DESC
+`
+
+type ListAgentRevisionsParams struct {
+	TenantID pgtype.UUID `json:"tenant_id"`
+	AgentID  pgtype.UUID `json:"agent_id"`
+}
+
+func (q *Queries) ListAgentRevisions(ctx context.Context, arg ListAgentRevisionsParams) ([]AgentRevision, error) {
+	rows, err := q.db.Query(ctx, listAgentRevisions, arg.TenantID, arg.AgentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AgentRevision{}
+	for rows.Next() {
+		var i AgentRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.AgentID,
+			&i.Revision,
+			&i.Operation,
+			&i.Name,
+			&i.Type,
+			&i.Role,
+			&i.ConfigJson,
+			&i.PoliciesJson,
+			&i.AuditID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAgentsByTenant = `-- name: ListAgentsByTenant :many
-SELECT id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at FROM agents
-WHERE tenant_id = $1
+SELECT id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at, deleted_at FROM agents
+WHERE tenant_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC
 `
 
@@ -144,6 +360,7 @@ func (q *Queries) ListAgentsByTenant(ctx context.Context, tenantID pgtype.UUID)
 			&i.PoliciesJson,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -156,8 +373,8 @@ func (q *Queries) ListAgentsByTenant(ctx context.Context, tenantID pgtype.UUID)
 }
 
 const listAgentsByType = `-- name: ListAgentsByType :many
-SELECT id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at FROM agents
-WHERE tenant_id = $1 AND type = $2
+SELECT id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at, deleted_at FROM agents
+WHERE tenant_id = $1 AND type = $2 AND deleted_at IS NULL
 ORDER BY created_at DESC
 `
 
@@ -185,6 +402,7 @@ func (q *Queries) ListAgentsByType(ctx context.Context, arg ListAgentsByTypePara
 			&i.PoliciesJson,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -196,11 +414,115 @@ func (q *Queries) ListAgentsByType(ctx context.Context, arg ListAgentsByTypePara
 	return items, nil
 }
 
+const listAgentsForExport = `-- name: ListAgentsForExport :many
+SELECT id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at, deleted_at FROM agents
+WHERE tenant_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) ListAgentsForExport(ctx context.Context, tenantID pgtype.UUID) ([]Agent, error) {
+	rows, err := q.db.Query(ctx, listAgentsForExport, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Agent{}
+	for rows.Next() {
+		var i Agent
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Name,
+			&i.Type,
+			&i.Role,
+			&i.ConfigJson,
+			&i.PoliciesJson,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeletedAgents = `-- name: ListDeletedAgents :many
+SELECT id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at, deleted_at FROM agents
+WHERE tenant_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC
+`
+
+func (q *Queries) ListDeletedAgents(ctx context.Context, tenantID pgtype.UUID) ([]Agent, error) {
+	rows, err := q.db.Query(ctx, listDeletedAgents, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Agent{}
+	for rows.Next() {
+		var i Agent
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Name,
+			&i.Type,
+			&i.Role,
+			&i.ConfigJson,
+			&i.PoliciesJson,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreAgent = `-- name: RestoreAgent :one
+UPDATE agents
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at, deleted_at
+`
+
+type RestoreAgentParams struct {
+	ID       pgtype.UUID `json:"id"`
+	TenantID pgtype.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) RestoreAgent(ctx context.Context, arg RestoreAgentParams) (Agent, error) {
+	row := q.db.QueryRow(ctx, restoreAgent, arg.ID, arg.TenantID)
+	var i Agent
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Name,
+		&i.Type,
+		&i.Role,
+		&i.ConfigJson,
+		&i.PoliciesJson,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const updateAgent = `-- name: UpdateAgent :one
 UPDATE agents
 SET name = $3, type = $4, role = $5, config_json = $6, policies_json = $7, updated_at = NOW()
-WHERE id = $1 AND tenant_id = $2
-RETURNING id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+RETURNING id, tenant_id, name, type, role, config_json, policies_json, created_at, updated_at, deleted_at
 `
 
 type UpdateAgentParams struct {
@@ -234,6 +556,7 @@ func (q *Queries) UpdateAgent(ctx context.Context, arg UpdateAgentParams) (Agent
 		&i.PoliciesJson,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
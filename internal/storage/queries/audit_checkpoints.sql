@@ -0,0 +1,10 @@
+-- name: CreateAuditCheckpoint :exec
+INSERT INTO audit_checkpoints (tenant_id, seq, hash, record_ts, key_id, signature)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (tenant_id, seq) DO NOTHING;
+
+-- name: GetLatestAuditCheckpoint :one
+SELECT * FROM audit_checkpoints
+WHERE tenant_id = $1
+ORDER BY seq DESC
+LIMIT 1;
@@ -0,0 +1,14 @@
+-- name: CreateAuditTreeHead :exec
+INSERT INTO audit_tree_heads (tenant_id, tree_size, root, signed_at, key_id, signature)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (tenant_id, tree_size) DO NOTHING;
+
+-- name: GetAuditTreeHead :one
+SELECT * FROM audit_tree_heads
+WHERE tenant_id = $1 AND tree_size = $2;
+
+-- name: GetLatestAuditTreeHead :one
+SELECT * FROM audit_tree_heads
+WHERE tenant_id = $1
+ORDER BY tree_size DESC
+LIMIT 1;
@@ -1,12 +1,19 @@
 -- name: CreateAudit :one
-INSERT INTO audits (tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, prev_hash, hash)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+INSERT INTO audits (tenant_id, seq, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING *;
 
+-- name: LockAuditChain :exec
+SELECT pg_advisory_xact_lock(hashtextextended('audits:' || @tenant_id::uuid::text, 0));
+
 -- name: GetAudit :one
 SELECT * FROM audits
 WHERE id = $1 AND tenant_id = $2;
 
+-- name: GetAuditByID :one
+SELECT * FROM audits
+WHERE id = $1;
+
 -- name: ListAuditsByTenant :many
 SELECT * FROM audits
 WHERE tenant_id = $1
@@ -28,10 +35,34 @@ LIMIT $4 OFFSET $5;
 -- name: GetAuditChain :many
 SELECT * FROM audits
 WHERE tenant_id = $1
-ORDER BY ts ASC;
+ORDER BY seq ASC;
 
 -- name: GetLatestAudit :one
 SELECT * FROM audits
 WHERE tenant_id = $1
-ORDER BY ts DESC
-LIMIT 1;
\ No newline at end of file
+ORDER BY seq DESC
+LIMIT 1;
+
+-- name: GetAuditBySeq :one
+SELECT * FROM audits
+WHERE tenant_id = $1 AND seq = $2;
+
+-- name: ListAuditRange :many
+SELECT * FROM audits
+WHERE tenant_id = @tenant_id AND seq > @after_seq AND seq <= @to_seq
+ORDER BY seq ASC
+LIMIT @batch_size;
+
+-- name: SearchAudits :many
+SELECT * FROM audits
+WHERE tenant_id = @tenant_id
+  AND seq > @after_seq
+  AND (@since::timestamptz IS NULL OR ts >= @since::timestamptz)
+  AND (@until::timestamptz IS NULL OR ts < @until::timestamptz)
+  AND (@actor_type::text = '' OR actor_type = @actor_type::text)
+  AND (@actor_id::text = '' OR actor_id = @actor_id::text)
+  AND (@action::text = '' OR action = @action::text)
+  AND (@resource_type::text = '' OR resource_type = @resource_type::text)
+  AND (@resource_id::text = '' OR resource_id = @resource_id::text)
+ORDER BY seq ASC
+LIMIT @max_results;
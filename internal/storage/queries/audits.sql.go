@@ -12,32 +12,36 @@ import (
 )
 
 const createAudit = `-- name: CreateAudit :one
-INSERT INTO audits (tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, prev_hash, hash)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-RETURNING id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash
+INSERT INTO audits (tenant_id, seq, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash, seq
 `
 
 type CreateAuditParams struct {
-	TenantID     pgtype.UUID `json:"tenant_id"`
-	ActorType    string      `json:"actor_type"`
-	ActorID      string      `json:"actor_id"`
-	Action       string      `json:"action"`
-	ResourceType string      `json:"resource_type"`
-	ResourceID   pgtype.Text `json:"resource_id"`
-	Details      []byte      `json:"details"`
-	PrevHash     []byte      `json:"prev_hash"`
-	Hash         []byte      `json:"hash"`
+	TenantID     pgtype.UUID        `json:"tenant_id"`
+	Seq          int64              `json:"seq"`
+	ActorType    string             `json:"actor_type"`
+	ActorID      string             `json:"actor_id"`
+	Action       string             `json:"action"`
+	ResourceType string             `json:"resource_type"`
+	ResourceID   pgtype.Text        `json:"resource_id"`
+	Details      []byte             `json:"details"`
+	Ts           pgtype.Timestamptz `json:"ts"`
+	PrevHash     []byte             `json:"prev_hash"`
+	Hash         []byte             `json:"hash"`
 }
 
 func (q *Queries) CreateAudit(ctx context.Context, arg CreateAuditParams) (Audit, error) {
 	row := q.db.QueryRow(ctx, createAudit,
 		arg.TenantID,
+		arg.Seq,
 		arg.ActorType,
 		arg.ActorID,
 		arg.Action,
 		arg.ResourceType,
 		arg.ResourceID,
 		arg.Details,
+		arg.Ts,
 		arg.PrevHash,
 		arg.Hash,
 	)
@@ -54,12 +58,13 @@ func (q *Queries) CreateAudit(ctx context.Context, arg CreateAuditParams) (Audit
 		&i.Ts,
 		&i.PrevHash,
 		&i.Hash,
+		&i.Seq,
 	)
 	return i, err
 }
 
 const getAudit = `-- name: GetAudit :one
-SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash FROM audits
+SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash, seq FROM audits
 WHERE id = $1 AND tenant_id = $2
 `
 
@@ -83,14 +88,70 @@ func (q *Queries) GetAudit(ctx context.Context, arg GetAuditParams) (Audit, erro
 		&i.Ts,
 		&i.PrevHash,
 		&i.Hash,
+		&i.Seq,
+	)
+	return i, err
+}
+
+const getAuditByID = `-- name: GetAuditByID :one
+SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash, seq FROM audits
+WHERE id = $1
+`
+
+func (q *Queries) GetAuditByID(ctx context.Context, id pgtype.UUID) (Audit, error) {
+	row := q.db.QueryRow(ctx, getAuditByID, id)
+	var i Audit
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.ActorType,
+		&i.ActorID,
+		&i.Action,
+		&i.ResourceType,
+		&i.ResourceID,
+		&i.Details,
+		&i.Ts,
+		&i.PrevHash,
+		&i.Hash,
+		&i.Seq,
+	)
+	return i, err
+}
+
+const getAuditBySeq = `-- name: GetAuditBySeq :one
+SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash, seq FROM audits
+WHERE tenant_id = $1 AND seq = $2
+`
+
+type GetAuditBySeqParams struct {
+	TenantID pgtype.UUID `json:"tenant_id"`
+	Seq      int64       `json:"seq"`
+}
+
+func (q *Queries) GetAuditBySeq(ctx context.Context, arg GetAuditBySeqParams) (Audit, error) {
+	row := q.db.QueryRow(ctx, getAuditBySeq, arg.TenantID, arg.Seq)
+	var i Audit
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.ActorType,
+		&i.ActorID,
+		&i.Action,
+		&i.ResourceType,
+		&i.ResourceID,
+		&i.Details,
+		&i.Ts,
+		&i.PrevHash,
+		&i.Hash,
+		&i.Seq,
 	)
 	return i, err
 }
 
 const getAuditChain = `-- name: GetAuditChain :many
-SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash FROM audits
+SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash, seq FROM audits
 WHERE tenant_id = $1
-ORDER BY ts ASC
+ORDER BY seq ASC
 `
 
 func (q *Queries) GetAuditChain(ctx context.Context, tenantID pgtype.UUID) ([]Audit, error) {
@@ -114,6 +175,7 @@ func (q *Queries) GetAuditChain(ctx context.Context, tenantID pgtype.UUID) ([]Au
 			&i.Ts,
 			&i.PrevHash,
 			&i.Hash,
+			&i.Seq,
 		); err != nil {
 			return nil, err
 		}
@@ -126,9 +188,9 @@ func (q *Queries) GetAuditChain(ctx context.Context, tenantID pgtype.UUID) ([]Au
 }
 
 const getLatestAudit = `-- name: GetLatestAudit :one
-SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash FROM audits
+SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash, seq FROM audits
 WHERE tenant_id = $1
-ORDER BY ts DESC
+ORDER BY seq DESC
 LIMIT 1
 `
 
@@ -147,12 +209,65 @@ func (q *Queries) GetLatestAudit(ctx context.Context, tenantID pgtype.UUID) (Aud
 		&i.Ts,
 		&i.PrevHash,
 		&i.Hash,
+		&i.Seq,
 	)
 	return i, err
 }
 
+const listAuditRange = `-- name: ListAuditRange :many
+SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash, seq FROM audits
+WHERE tenant_id = $1 AND seq > $2 AND seq <= $3
+ORDER BY seq ASC
+LIMIT $4
+`
+
+type ListAuditRangeParams struct {
+	TenantID  pgtype.UUID `json:"tenant_id"`
+	AfterSeq  int64       `json:"after_seq"`
+	ToSeq     int64       `json:"to_seq"`
+	BatchSize int32       `json:"batch_size"`
+}
+
+func (q *Queries) ListAuditRange(ctx context.Context, arg ListAuditRangeParams) ([]Audit, error) {
+	rows, err := q.db.Query(ctx, listAuditRange,
+		arg.TenantID,
+		arg.AfterSeq,
+		arg.ToSeq,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Audit{}
+	for rows.Next() {
+		var i Audit
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.ActorType,
+			&i.ActorID,
+			&i.Action,
+			&i.ResourceType,
+			&i.ResourceID,
+			&i.Details,
+			&i.Ts,
+			&i.PrevHash,
+			&i.Hash,
+			&i.Seq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAuditsByActor = `-- name: ListAuditsByActor :many
-SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash FROM audits
+SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash, seq FROM audits
 WHERE tenant_id = $1 AND actor_type = $2 AND actor_id = $3
 ORDER BY ts DESC
 LIMIT $4 OFFSET $5
@@ -193,6 +308,7 @@ func (q *Queries) ListAuditsByActor(ctx context.Context, arg ListAuditsByActorPa
 			&i.Ts,
 			&i.PrevHash,
 			&i.Hash,
+			&i.Seq,
 		); err != nil {
 			return nil, err
 		}
@@ -205,7 +321,7 @@ func (q *Queries) ListAuditsByActor(ctx context.Context, arg ListAuditsByActorPa
 }
 
 const listAuditsByResource = `-- name: ListAuditsByResource :many
-SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash FROM audits
+SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash, seq FROM audits
 WHERE tenant_id = $1 AND resource_type = $2 AND resource_id = $3
 ORDER BY ts DESC
 LIMIT $4 OFFSET $5
@@ -246,6 +362,7 @@ func (q *Queries) ListAuditsByResource(ctx context.Context, arg ListAuditsByReso
 			&i.Ts,
 			&i.PrevHash,
 			&i.Hash,
+			&i.Seq,
 		); err != nil {
 			return nil, err
 		}
@@ -258,7 +375,7 @@ func (q *Queries) ListAuditsByResource(ctx context.Context, arg ListAuditsByReso
 }
 
 const listAuditsByTenant = `-- name: ListAuditsByTenant :many
-SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash FROM audits
+SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash, seq FROM audits
 WHERE tenant_id = $1
 ORDER BY ts DESC
 LIMIT $2 OFFSET $3
@@ -291,6 +408,88 @@ func (q *Queries) ListAuditsByTenant(ctx context.Context, arg ListAuditsByTenant
 			&i.Ts,
 			&i.PrevHash,
 			&i.Hash,
+			&i.Seq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockAuditChain = `-- name: LockAuditChain :exec
+SELECT pg_advisory_xact_lock(hashtextextended('audits:' || $1::uuid::text, 0))
+`
+
+func (q *Queries) LockAuditChain(ctx context.Context, tenantID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, lockAuditChain, tenantID)
+	return err
+}
+
+const searchAudits = `-- name: SearchAudits :many
+SELECT id, tenant_id, actor_type, actor_id, action, resource_type, resource_id, details, ts, prev_hash, hash, seq FROM audits
+WHERE tenant_id = $1
+  AND seq > $2
+  AND ($3::timestamptz IS NULL OR ts >= $3::timestamptz)
+  AND ($4::timestamptz IS NULL OR ts < $4::timestamptz)
+  AND ($5::text = '' OR actor_type = $5::text)
+  AND ($6::text = '' OR actor_id = $6::text)
+  AND ($7::text = '' OR action = $7::text)
+  AND ($8::text = '' OR resource_type = $8::text)
+  AND ($9::text = '' OR resource_id = $9::text)
+ORDER BY seq ASC
+LIMIT $10
+`
+
+type SearchAuditsParams struct {
+	TenantID     pgtype.UUID        `json:"tenant_id"`
+	AfterSeq     int64              `json:"after_seq"`
+	Since        pgtype.Timestamptz `json:"since"`
+	Until        pgtype.Timestamptz `json:"until"`
+	ActorType    string             `json:"actor_type"`
+	ActorID      string             `json:"actor_id"`
+	Action       string             `json:"action"`
+	ResourceType string             `json:"resource_type"`
+	ResourceID   string             `json:"resource_id"`
+	MaxResults   int32              `json:"max_results"`
+}
+
+func (q *Queries) SearchAudits(ctx context.Context, arg SearchAuditsParams) ([]Audit, error) {
+	rows, err := q.db.Query(ctx, searchAudits,
+		arg.TenantID,
+		arg.AfterSeq,
+		arg.Since,
+		arg.Until,
+		arg.ActorType,
+		arg.ActorID,
+		arg.Action,
+		arg.ResourceType,
+		arg.ResourceID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Audit{}
+	for rows.Next() {
+		var i Audit
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.ActorType,
+			&i.ActorID,
+			&i.Action,
+			&i.ResourceType,
+			&i.ResourceID,
+			&i.Details,
+			&i.Ts,
+			&i.PrevHash,
+			&i.Hash,
+			&i.Seq,
 		); err != nil {
 			return nil, err
 		}
@@ -32,4 +32,10 @@ LIMIT $4 OFFSET $5;
 
 -- name: DeleteMessage :exec
 DELETE FROM messages
-WHERE id = $1 AND tenant_id = $2;
\ No newline at end of file
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListMessagesForExport :many
+SELECT * FROM messages
+WHERE tenant_id = $1
+ORDER BY ts ASC, id ASC
+LIMIT $2 OFFSET $3;
\ No newline at end of file
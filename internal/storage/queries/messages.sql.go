@@ -305,3 +305,49 @@ func (q *Queries) ListMessagesByTrace(ctx context.Context, arg ListMessagesByTra
 	}
 	return items, nil
 }
+
+const listMessagesForExport = `-- name: ListMessagesForExport :many
+SELECT id, tenant_id, trace_id, span_id, from_agent, to_agent, type, payload, metadata, cost, ts, envelope_hash FROM messages
+WHERE tenant_id = $1
+ORDER BY ts ASC, id ASC
+LIMIT $2 OFFSET $3
+`
+
+type ListMessagesForExportParams struct {
+	TenantID pgtype.UUID `json:"tenant_id"`
+	Limit    int32       `json:"limit"`
+	Offset   int32       `json:"offset"`
+}
+
+func (q *Queries) ListMessagesForExport(ctx context.Context, arg ListMessagesForExportParams) ([]Message, error) {
+	rows, err := q.db.Query(ctx, listMessagesForExport, arg.TenantID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Message{}
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.TraceID,
+			&i.SpanID,
+			&i.FromAgent,
+			&i.ToAgent,
+			&i.Type,
+			&i.Payload,
+			&i.Metadata,
+			&i.Cost,
+			&i.Ts,
+			&i.EnvelopeHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
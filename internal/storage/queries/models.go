@@ -11,15 +11,44 @@ import (
 )
 
 type Agent struct {
+	ID           pgtype.UUID        `json:"id"`
+	TenantID     pgtype.UUID        `json:"tenant_id"`
+	Name         string             `json:"name"`
+	Type         string             `json:"type"`
+	Role         pgtype.Text        `json:"role"`
+	ConfigJson   []byte             `json:"config_json"`
+	PoliciesJson []byte             `json:"policies_json"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type AgentRevision struct {
 	ID           pgtype.UUID `json:"id"`
 	TenantID     pgtype.UUID `json:"tenant_id"`
+	AgentID      pgtype.UUID `json:"agent_id"`
+	Revision     int32       `json:"revision"`
+	Operation    string      `json:"operation"`
 	Name         string      `json:"name"`
 	Type         string      `json:"type"`
 	Role         pgtype.Text `json:"role"`
 	ConfigJson   []byte      `json:"config_json"`
 	PoliciesJson []byte      `json:"policies_json"`
+	AuditID      pgtype.UUID `json:"audit_id"`
 	CreatedAt    time.Time   `json:"created_at"`
-	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+type ApiKey struct {
+	ID               pgtype.UUID        `json:"id"`
+	TenantID         pgtype.UUID        `json:"tenant_id"`
+	ServiceAccountID pgtype.UUID        `json:"service_account_id"`
+	Prefix           string             `json:"prefix"`
+	KeyHash          []byte             `json:"key_hash"`
+	Scopes           []string           `json:"scopes"`
+	ExpiresAt        pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt       pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt        pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt        time.Time          `json:"created_at"`
 }
 
 type Audit struct {
@@ -34,6 +63,27 @@ type Audit struct {
 	Ts           pgtype.Timestamptz `json:"ts"`
 	PrevHash     []byte             `json:"prev_hash"`
 	Hash         []byte             `json:"hash"`
+	Seq          int64              `json:"seq"`
+}
+
+type AuditCheckpoint struct {
+	TenantID  pgtype.UUID        `json:"tenant_id"`
+	Seq       int64              `json:"seq"`
+	Hash      []byte             `json:"hash"`
+	RecordTs  pgtype.Timestamptz `json:"record_ts"`
+	KeyID     string             `json:"key_id"`
+	Signature []byte             `json:"signature"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type AuditTreeHead struct {
+	TenantID  pgtype.UUID        `json:"tenant_id"`
+	TreeSize  int64              `json:"tree_size"`
+	Root      []byte             `json:"root"`
+	SignedAt  pgtype.Timestamptz `json:"signed_at"`
+	KeyID     string             `json:"key_id"`
+	Signature []byte             `json:"signature"`
+	CreatedAt time.Time          `json:"created_at"`
 }
 
 type Budget struct {
@@ -75,6 +125,16 @@ type Plan struct {
 	UpdatedAt   time.Time   `json:"updated_at"`
 }
 
+type Policy struct {
+	ID        pgtype.UUID `json:"id"`
+	TenantID  pgtype.UUID `json:"tenant_id"`
+	Name      string      `json:"name"`
+	Document  []byte      `json:"document"`
+	Enabled   bool        `json:"enabled"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
 type RbacBinding struct {
 	ID        pgtype.UUID `json:"id"`
 	TenantID  pgtype.UUID `json:"tenant_id"`
@@ -89,15 +149,49 @@ type RbacRole struct {
 	Name        string      `json:"name"`
 	Permissions []byte      `json:"permissions"`
 	CreatedAt   time.Time   `json:"created_at"`
+	ParentID    pgtype.UUID `json:"parent_id"`
+}
+
+type RefreshToken struct {
+	TokenHash   []byte             `json:"token_hash"`
+	FamilyID    pgtype.UUID        `json:"family_id"`
+	TenantID    pgtype.UUID        `json:"tenant_id"`
+	UserID      string             `json:"user_id"`
+	Roles       []string           `json:"roles"`
+	Permissions []string           `json:"permissions"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	UsedAt      pgtype.Timestamptz `json:"used_at"`
+	RevokedAt   pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+type RevokedToken struct {
+	Jti       string             `json:"jti"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
+}
+
+type ServiceAccount struct {
+	ID          pgtype.UUID        `json:"id"`
+	TenantID    pgtype.UUID        `json:"tenant_id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	DisabledAt  pgtype.Timestamptz `json:"disabled_at"`
+	CreatedAt   time.Time          `json:"created_at"`
 }
 
 type Tenant struct {
-	ID        pgtype.UUID `json:"id"`
-	Name      string      `json:"name"`
-	Tier      string      `json:"tier"`
-	Settings  []byte      `json:"settings"`
-	CreatedAt time.Time   `json:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at"`
+	ID                  pgtype.UUID        `json:"id"`
+	Name                string             `json:"name"`
+	Tier                string             `json:"tier"`
+	Settings            []byte             `json:"settings"`
+	CreatedAt           time.Time          `json:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at"`
+	Status              string             `json:"status"`
+	StatusReason        pgtype.Text        `json:"status_reason"`
+	StatusChangedAt     pgtype.Timestamptz `json:"status_changed_at"`
+	DeletionScheduledAt pgtype.Timestamptz `json:"deletion_scheduled_at"`
+	ErasedAt            pgtype.Timestamptz `json:"erased_at"`
 }
 
 type Tool struct {
@@ -112,23 +206,41 @@ type Tool struct {
 }
 
 type User struct {
-	ID           pgtype.UUID `json:"id"`
-	TenantID     pgtype.UUID `json:"tenant_id"`
-	Email        string      `json:"email"`
-	Role         string      `json:"role"`
-	HashedSecret pgtype.Text `json:"hashed_secret"`
-	CreatedAt    time.Time   `json:"created_at"`
-	UpdatedAt    time.Time   `json:"updated_at"`
+	ID                  pgtype.UUID        `json:"id"`
+	TenantID            pgtype.UUID        `json:"tenant_id"`
+	Email               string             `json:"email"`
+	Role                string             `json:"role"`
+	HashedSecret        pgtype.Text        `json:"hashed_secret"`
+	CreatedAt           time.Time          `json:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at"`
+	FailedLoginAttempts int32              `json:"failed_login_attempts"`
+	LockedUntil         pgtype.Timestamptz `json:"locked_until"`
 }
 
 type Workflow struct {
+	ID                        pgtype.UUID        `json:"id"`
+	TenantID                  pgtype.UUID        `json:"tenant_id"`
+	Name                      string             `json:"name"`
+	Version                   string             `json:"version"`
+	ConfigYaml                string             `json:"config_yaml"`
+	PlannerType               string             `json:"planner_type"`
+	TemplateVersionConstraint pgtype.Text        `json:"template_version_constraint"`
+	CreatedAt                 time.Time          `json:"created_at"`
+	UpdatedAt                 time.Time          `json:"updated_at"`
+	DeletedAt                 pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type WorkflowRevision struct {
 	ID                        pgtype.UUID `json:"id"`
 	TenantID                  pgtype.UUID `json:"tenant_id"`
+	WorkflowID                pgtype.UUID `json:"workflow_id"`
+	Revision                  int32       `json:"revision"`
+	Operation                 string      `json:"operation"`
 	Name                      string      `json:"name"`
 	Version                   string      `json:"version"`
 	ConfigYaml                string      `json:"config_yaml"`
 	PlannerType               string      `json:"planner_type"`
 	TemplateVersionConstraint pgtype.Text `json:"template_version_constraint"`
+	AuditID                   pgtype.UUID `json:"audit_id"`
 	CreatedAt                 time.Time   `json:"created_at"`
-	UpdatedAt                 time.Time   `json:"updated_at"`
 }
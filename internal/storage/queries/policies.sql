@@ -0,0 +1,15 @@
+-- name: UpsertPolicy :one
+INSERT INTO policies (tenant_id, name, document, enabled)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (tenant_id, name)
+DO UPDATE SET document = EXCLUDED.document, enabled = EXCLUDED.enabled, updated_at = NOW()
+RETURNING *;
+
+-- name: ListEnabledPolicies :many
+SELECT * FROM policies
+WHERE tenant_id = $1 AND enabled
+ORDER BY name;
+
+-- name: EraseTenantPolicies :execrows
+DELETE FROM policies
+WHERE tenant_id = $1;
@@ -11,46 +11,114 @@ import (
 )
 
 type Querier interface {
+	CountAgentsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	CountWorkflowsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAgent(ctx context.Context, arg CreateAgentParams) (Agent, error)
+	CreateAgentRevision(ctx context.Context, arg CreateAgentRevisionParams) (AgentRevision, error)
 	CreateAudit(ctx context.Context, arg CreateAuditParams) (Audit, error)
+	CreateAuditCheckpoint(ctx context.Context, arg CreateAuditCheckpointParams) error
+	CreateAuditTreeHead(ctx context.Context, arg CreateAuditTreeHeadParams) error
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error
+	CreateServiceAccount(ctx context.Context, arg CreateServiceAccountParams) (ServiceAccount, error)
 	CreateTenant(ctx context.Context, arg CreateTenantParams) (Tenant, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	CreateWorkflow(ctx context.Context, arg CreateWorkflowParams) (Workflow, error)
-	DeleteAgent(ctx context.Context, arg DeleteAgentParams) error
+	CreateWorkflowRevision(ctx context.Context, arg CreateWorkflowRevisionParams) (WorkflowRevision, error)
+	DeleteAgent(ctx context.Context, arg DeleteAgentParams) (Agent, error)
+	DeleteExpiredRevokedTokens(ctx context.Context) (int64, error)
 	DeleteMessage(ctx context.Context, arg DeleteMessageParams) error
 	DeleteTenant(ctx context.Context, id pgtype.UUID) error
 	DeleteUser(ctx context.Context, arg DeleteUserParams) error
-	DeleteWorkflow(ctx context.Context, arg DeleteWorkflowParams) error
+	DeleteWorkflow(ctx context.Context, arg DeleteWorkflowParams) (Workflow, error)
+	DisableServiceAccount(ctx context.Context, arg DisableServiceAccountParams) (ServiceAccount, error)
+	EraseTenantAgents(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantBudgets(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantMessages(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantPolicies(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantRefreshTokens(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantRoles(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantServiceAccounts(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantTools(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantUsers(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantWorkflows(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	GetAPIKey(ctx context.Context, arg GetAPIKeyParams) (ApiKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
 	GetAgent(ctx context.Context, arg GetAgentParams) (Agent, error)
 	GetAgentByName(ctx context.Context, arg GetAgentByNameParams) (Agent, error)
+	GetAgentForUpdate(ctx context.Context, arg GetAgentForUpdateParams) (Agent, error)
+	GetAgentRevision(ctx context.Context, arg GetAgentRevisionParams) (AgentRevision, error)
 	GetAudit(ctx context.Context, arg GetAuditParams) (Audit, error)
+	GetAuditByID(ctx context.Context, id pgtype.UUID) (Audit, error)
+	GetAuditBySeq(ctx context.Context, arg GetAuditBySeqParams) (Audit, error)
 	GetAuditChain(ctx context.Context, tenantID pgtype.UUID) ([]Audit, error)
+	GetAuditTreeHead(ctx context.Context, arg GetAuditTreeHeadParams) (AuditTreeHead, error)
+	GetLatestAgentRevision(ctx context.Context, arg GetLatestAgentRevisionParams) (AgentRevision, error)
 	GetLatestAudit(ctx context.Context, tenantID pgtype.UUID) (Audit, error)
+	GetLatestAuditCheckpoint(ctx context.Context, tenantID pgtype.UUID) (AuditCheckpoint, error)
+	GetLatestAuditTreeHead(ctx context.Context, tenantID pgtype.UUID) (AuditTreeHead, error)
+	GetLatestWorkflowRevision(ctx context.Context, arg GetLatestWorkflowRevisionParams) (WorkflowRevision, error)
 	GetMessage(ctx context.Context, arg GetMessageParams) (Message, error)
+	GetRefreshToken(ctx context.Context, tokenHash []byte) (RefreshToken, error)
+	GetServiceAccount(ctx context.Context, arg GetServiceAccountParams) (ServiceAccount, error)
 	GetTenant(ctx context.Context, id pgtype.UUID) (Tenant, error)
 	GetTenantByName(ctx context.Context, name string) (Tenant, error)
+	GetTenantForUpdate(ctx context.Context, id pgtype.UUID) (Tenant, error)
+	GetToolByName(ctx context.Context, arg GetToolByNameParams) (Tool, error)
 	GetUser(ctx context.Context, arg GetUserParams) (User, error)
 	GetUserByEmail(ctx context.Context, arg GetUserByEmailParams) (User, error)
 	GetWorkflow(ctx context.Context, arg GetWorkflowParams) (Workflow, error)
 	GetWorkflowByNameVersion(ctx context.Context, arg GetWorkflowByNameVersionParams) (Workflow, error)
+	GetWorkflowForUpdate(ctx context.Context, arg GetWorkflowForUpdateParams) (Workflow, error)
+	GetWorkflowRevision(ctx context.Context, arg GetWorkflowRevisionParams) (WorkflowRevision, error)
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	ListAPIKeys(ctx context.Context, arg ListAPIKeysParams) ([]ApiKey, error)
+	ListAgentRevisions(ctx context.Context, arg ListAgentRevisionsParams) ([]AgentRevision, error)
 	ListAgentsByTenant(ctx context.Context, tenantID pgtype.UUID) ([]Agent, error)
 	ListAgentsByType(ctx context.Context, arg ListAgentsByTypeParams) ([]Agent, error)
+	ListAgentsForExport(ctx context.Context, tenantID pgtype.UUID) ([]Agent, error)
+	ListAuditRange(ctx context.Context, arg ListAuditRangeParams) ([]Audit, error)
 	ListAuditsByActor(ctx context.Context, arg ListAuditsByActorParams) ([]Audit, error)
 	ListAuditsByResource(ctx context.Context, arg ListAuditsByResourceParams) ([]Audit, error)
 	ListAuditsByTenant(ctx context.Context, arg ListAuditsByTenantParams) ([]Audit, error)
+	ListDeletedAgents(ctx context.Context, tenantID pgtype.UUID) ([]Agent, error)
+	ListDeletedWorkflows(ctx context.Context, tenantID pgtype.UUID) ([]Workflow, error)
+	ListEnabledPolicies(ctx context.Context, tenantID pgtype.UUID) ([]Policy, error)
 	ListMessagesByAgent(ctx context.Context, arg ListMessagesByAgentParams) ([]Message, error)
 	ListMessagesByTenant(ctx context.Context, arg ListMessagesByTenantParams) ([]Message, error)
 	ListMessagesByTimeRange(ctx context.Context, arg ListMessagesByTimeRangeParams) ([]Message, error)
 	ListMessagesByTrace(ctx context.Context, arg ListMessagesByTraceParams) ([]Message, error)
+	ListMessagesForExport(ctx context.Context, arg ListMessagesForExportParams) ([]Message, error)
+	ListServiceAccounts(ctx context.Context, tenantID pgtype.UUID) ([]ServiceAccount, error)
+	ListTenantRoles(ctx context.Context, tenantID pgtype.UUID) ([]RbacRole, error)
 	ListTenants(ctx context.Context) ([]Tenant, error)
+	ListTenantsDueForErasure(ctx context.Context, deletionScheduledAt pgtype.Timestamptz) ([]Tenant, error)
+	ListUserRoleIDs(ctx context.Context, arg ListUserRoleIDsParams) ([]pgtype.UUID, error)
 	ListUsersByTenant(ctx context.Context, tenantID pgtype.UUID) ([]User, error)
+	ListWorkflowRevisions(ctx context.Context, arg ListWorkflowRevisionsParams) ([]WorkflowRevision, error)
 	ListWorkflowsByPlanner(ctx context.Context, arg ListWorkflowsByPlannerParams) ([]Workflow, error)
 	ListWorkflowsByTenant(ctx context.Context, tenantID pgtype.UUID) ([]Workflow, error)
+	ListWorkflowsForExport(ctx context.Context, tenantID pgtype.UUID) ([]Workflow, error)
+	LockAuditChain(ctx context.Context, tenantID pgtype.UUID) error
+	MarkTenantErased(ctx context.Context, id pgtype.UUID) (Tenant, error)
+	RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (pgtype.Timestamptz, error)
+	ResetFailedLogins(ctx context.Context, arg ResetFailedLoginsParams) error
+	RestoreAgent(ctx context.Context, arg RestoreAgentParams) (Agent, error)
+	RestoreWorkflow(ctx context.Context, arg RestoreWorkflowParams) (Workflow, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (int64, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID pgtype.UUID) (int64, error)
+	RevokeToken(ctx context.Context, arg RevokeTokenParams) error
+	SearchAudits(ctx context.Context, arg SearchAuditsParams) ([]Audit, error)
+	SetAPIKeyExpiry(ctx context.Context, arg SetAPIKeyExpiryParams) error
+	TouchAPIKey(ctx context.Context, arg TouchAPIKeyParams) error
 	UpdateAgent(ctx context.Context, arg UpdateAgentParams) (Agent, error)
 	UpdateTenant(ctx context.Context, arg UpdateTenantParams) (Tenant, error)
+	UpdateTenantStatus(ctx context.Context, arg UpdateTenantStatusParams) (Tenant, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
 	UpdateWorkflow(ctx context.Context, arg UpdateWorkflowParams) (Workflow, error)
+	UpsertPolicy(ctx context.Context, arg UpsertPolicyParams) (Policy, error)
+	UseRefreshToken(ctx context.Context, tokenHash []byte) (RefreshToken, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,8 @@
+-- name: ListTenantRoles :many
+SELECT * FROM rbac_roles
+WHERE tenant_id = $1
+ORDER BY name;
+
+-- name: ListUserRoleIDs :many
+SELECT role_id FROM rbac_bindings
+WHERE tenant_id = $1 AND user_id = $2;
@@ -0,0 +1,22 @@
+-- name: CreateRefreshToken :exec
+INSERT INTO refresh_tokens (token_hash, family_id, tenant_id, user_id, roles, permissions, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: UseRefreshToken :one
+UPDATE refresh_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: GetRefreshToken :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1;
+
+-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: EraseTenantRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE tenant_id = $1;
@@ -0,0 +1,56 @@
+-- name: CreateServiceAccount :one
+INSERT INTO service_accounts (tenant_id, name, description)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetServiceAccount :one
+SELECT * FROM service_accounts
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListServiceAccounts :many
+SELECT * FROM service_accounts
+WHERE tenant_id = $1
+ORDER BY name;
+
+-- name: DisableServiceAccount :one
+UPDATE service_accounts
+SET disabled_at = COALESCE(disabled_at, NOW())
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: EraseTenantServiceAccounts :execrows
+DELETE FROM service_accounts
+WHERE tenant_id = $1;
+
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (tenant_id, service_account_id, prefix, key_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetAPIKey :one
+SELECT * FROM api_keys
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: GetAPIKeyByPrefix :one
+SELECT * FROM api_keys
+WHERE prefix = $1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys
+WHERE tenant_id = $1 AND service_account_id = $2
+ORDER BY created_at;
+
+-- name: SetAPIKeyExpiry :exec
+UPDATE api_keys
+SET expires_at = $3
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = NOW()
+WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = $2
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2);
@@ -23,4 +23,55 @@ RETURNING *;
 
 -- name: DeleteTenant :exec
 DELETE FROM tenants
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: GetTenantForUpdate :one
+SELECT * FROM tenants
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateTenantStatus :one
+UPDATE tenants
+SET status = $2, status_reason = $3, deletion_scheduled_at = $4, status_changed_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListTenantsDueForErasure :many
+SELECT * FROM tenants
+WHERE status = 'pending_deletion' AND deletion_scheduled_at <= $1
+ORDER BY deletion_scheduled_at ASC;
+
+-- name: MarkTenantErased :one
+UPDATE tenants
+SET status = 'erased', name = 'erased-' || id::text, settings = '{}', status_reason = NULL,
+    deletion_scheduled_at = NULL, status_changed_at = NOW(), erased_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: EraseTenantMessages :execrows
+DELETE FROM messages
+WHERE tenant_id = $1;
+
+-- name: EraseTenantAgents :execrows
+DELETE FROM agents
+WHERE tenant_id = $1;
+
+-- name: EraseTenantWorkflows :execrows
+DELETE FROM workflows
+WHERE tenant_id = $1;
+
+-- name: EraseTenantTools :execrows
+DELETE FROM tools
+WHERE tenant_id = $1;
+
+-- name: EraseTenantBudgets :execrows
+DELETE FROM budgets
+WHERE tenant_id = $1;
+
+-- name: EraseTenantRoles :execrows
+DELETE FROM rbac_roles
+WHERE tenant_id = $1;
+
+-- name: EraseTenantUsers :execrows
+DELETE FROM users
+WHERE tenant_id = $1;
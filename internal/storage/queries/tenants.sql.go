@@ -14,7 +14,7 @@ import (
 const createTenant = `-- name: CreateTenant :one
 INSERT INTO tenants (name, tier, settings)
 VALUES ($1, $2, $3)
-RETURNING id, name, tier, settings, created_at, updated_at
+RETURNING id, name, tier, settings, created_at, updated_at, status, status_reason, status_changed_at, deletion_scheduled_at, erased_at
 `
 
 type CreateTenantParams struct {
@@ -33,6 +33,11 @@ func (q *Queries) CreateTenant(ctx context.Context, arg CreateTenantParams) (Ten
 		&i.Settings,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.DeletionScheduledAt,
+		&i.ErasedAt,
 	)
 	return i, err
 }
@@ -47,8 +52,99 @@ func (q *Queries) DeleteTenant(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const eraseTenantAgents = `-- name: EraseTenantAgents :execrows
+DELETE FROM agents
+WHERE tenant_id = $1
+`
+
+func (q *Queries) EraseTenantAgents(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, eraseTenantAgents, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const eraseTenantBudgets = `-- name: EraseTenantBudgets :execrows
+DELETE FROM budgets
+WHERE tenant_id = $1
+`
+
+func (q *Queries) EraseTenantBudgets(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, eraseTenantBudgets, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const eraseTenantMessages = `-- name: EraseTenantMessages :execrows
+DELETE FROM messages
+WHERE tenant_id = $1
+`
+
+func (q *Queries) EraseTenantMessages(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, eraseTenantMessages, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const eraseTenantRoles = `-- name: EraseTenantRoles :execrows
+DELETE FROM rbac_roles
+WHERE tenant_id = $1
+`
+
+func (q *Queries) EraseTenantRoles(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, eraseTenantRoles, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const eraseTenantTools = `-- name: EraseTenantTools :execrows
+DELETE FROM tools
+WHERE tenant_id = $1
+`
+
+func (q *Queries) EraseTenantTools(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, eraseTenantTools, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const eraseTenantUsers = `-- name: EraseTenantUsers :execrows
+DELETE FROM users
+WHERE tenant_id = $1
+`
+
+func (q *Queries) EraseTenantUsers(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, eraseTenantUsers, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const eraseTenantWorkflows = `-- name: EraseTenantWorkflows :execrows
+DELETE FROM workflows
+WHERE tenant_id = $1
+`
+
+func (q *Queries) EraseTenantWorkflows(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, eraseTenantWorkflows, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getTenant = `-- name: GetTenant :one
-SELECT id, name, tier, settings, created_at, updated_at FROM tenants
+SELECT id, name, tier, settings, created_at, updated_at, status, status_reason, status_changed_at, deletion_scheduled_at, erased_at FROM tenants
 WHERE id = $1
 `
 
@@ -62,12 +158,17 @@ func (q *Queries) GetTenant(ctx context.Context, id pgtype.UUID) (Tenant, error)
 		&i.Settings,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.DeletionScheduledAt,
+		&i.ErasedAt,
 	)
 	return i, err
 }
 
 const getTenantByName = `-- name: GetTenantByName :one
-SELECT id, name, tier, settings, created_at, updated_at FROM tenants
+SELECT id, name, tier, settings, created_at, updated_at, status, status_reason, status_changed_at, deletion_scheduled_at, erased_at FROM tenants
 WHERE name = $1
 `
 
@@ -81,12 +182,42 @@ func (q *Queries) GetTenantByName(ctx context.Context, name string) (Tenant, err
 		&i.Settings,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.DeletionScheduledAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
+const getTenantForUpdate = `-- name: GetTenantForUpdate :one
+SELECT id, name, tier, settings, created_at, updated_at, status, status_reason, status_changed_at, deletion_scheduled_at, erased_at FROM tenants
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetTenantForUpdate(ctx context.Context, id pgtype.UUID) (Tenant, error) {
+	row := q.db.QueryRow(ctx, getTenantForUpdate, id)
+	var i Tenant
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Tier,
+		&i.Settings,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.DeletionScheduledAt,
+		&i.ErasedAt,
 	)
 	return i, err
 }
 
 const listTenants = `-- name: ListTenants :many
-SELECT id, name, tier, settings, created_at, updated_at FROM tenants
+SELECT id, name, tier, settings, created_at, updated_at, status, status_reason, status_changed_at, deletion_scheduled_at, erased_at FROM tenants
 ORDER BY created_at DESC
 `
 
@@ -106,6 +237,49 @@ func (q *Queries) ListTenants(ctx context.Context) ([]Tenant, error) {
 			&i.Settings,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Status,
+			&i.StatusReason,
+			&i.StatusChangedAt,
+			&i.DeletionScheduledAt,
+			&i.ErasedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTenantsDueForErasure = `-- name: ListTenantsDueForErasure :many
+SELECT id, name, tier, settings, created_at, updated_at, status, status_reason, status_changed_at, deletion_scheduled_at, erased_at FROM tenants
+WHERE status = 'pending_deletion' AND deletion_scheduled_at <= $1
+ORDER BY deletion_scheduled_at ASC
+`
+
+func (q *Queries) ListTenantsDueForErasure(ctx context.Context, deletionScheduledAt pgtype.Timestamptz) ([]Tenant, error) {
+	rows, err := q.db.Query(ctx, listTenantsDueForErasure, deletionScheduledAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Tenant{}
+	for rows.Next() {
+		var i Tenant
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Tier,
+			&i.Settings,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Status,
+			&i.StatusReason,
+			&i.StatusChangedAt,
+			&i.DeletionScheduledAt,
+			&i.ErasedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -117,11 +291,38 @@ func (q *Queries) ListTenants(ctx context.Context) ([]Tenant, error) {
 	return items, nil
 }
 
+const markTenantErased = `-- name: MarkTenantErased :one
+UPDATE tenants
+SET status = 'erased', name = 'erased-' || id::text, settings = '{}', status_reason = NULL,
+    deletion_scheduled_at = NULL, status_changed_at = NOW(), erased_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, tier, settings, created_at, updated_at, status, status_reason, status_changed_at, deletion_scheduled_at, erased_at
+`
+
+func (q *Queries) MarkTenantErased(ctx context.Context, id pgtype.UUID) (Tenant, error) {
+	row := q.db.QueryRow(ctx, markTenantErased, id)
+	var i Tenant
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Tier,
+		&i.Settings,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.DeletionScheduledAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
 const updateTenant = `-- name: UpdateTenant :one
 UPDATE tenants
 SET name = $2, tier = $3, settings = $4, updated_at = NOW()
 WHERE id = $1
-RETURNING id, name, tier, settings, created_at, updated_at
+RETURNING id, name, tier, settings, created_at, updated_at, status, status_reason, status_changed_at, deletion_scheduled_at, erased_at
 `
 
 type UpdateTenantParams struct {
@@ -146,6 +347,49 @@ func (q *Queries) UpdateTenant(ctx context.Context, arg UpdateTenantParams) (Ten
 		&i.Settings,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.DeletionScheduledAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
+const updateTenantStatus = `-- name: UpdateTenantStatus :one
+UPDATE tenants
+SET status = $2, status_reason = $3, deletion_scheduled_at = $4, status_changed_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, tier, settings, created_at, updated_at, status, status_reason, status_changed_at, deletion_scheduled_at, erased_at
+`
+
+type UpdateTenantStatusParams struct {
+	ID                  pgtype.UUID        `json:"id"`
+	Status              string             `json:"status"`
+	StatusReason        pgtype.Text        `json:"status_reason"`
+	DeletionScheduledAt pgtype.Timestamptz `json:"deletion_scheduled_at"`
+}
+
+func (q *Queries) UpdateTenantStatus(ctx context.Context, arg UpdateTenantStatusParams) (Tenant, error) {
+	row := q.db.QueryRow(ctx, updateTenantStatus,
+		arg.ID,
+		arg.Status,
+		arg.StatusReason,
+		arg.DeletionScheduledAt,
+	)
+	var i Tenant
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Tier,
+		&i.Settings,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.DeletionScheduledAt,
+		&i.ErasedAt,
 	)
 	return i, err
 }
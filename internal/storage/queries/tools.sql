@@ -0,0 +1,3 @@
+-- name: GetToolByName :one
+SELECT * FROM tools
+WHERE tenant_id = $1 AND name = $2;
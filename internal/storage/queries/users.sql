@@ -24,4 +24,16 @@ RETURNING *;
 
 -- name: DeleteUser :exec
 DELETE FROM users
-WHERE id = $1 AND tenant_id = $2;
\ No newline at end of file
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: RecordFailedLogin :one
+UPDATE users
+SET failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= sqlc.arg(max_attempts)::int THEN 0 ELSE failed_login_attempts + 1 END,
+    locked_until = CASE WHEN failed_login_attempts + 1 >= sqlc.arg(max_attempts)::int THEN sqlc.arg(lock_until)::timestamptz ELSE locked_until END
+WHERE id = $1 AND tenant_id = $2
+RETURNING locked_until;
+
+-- name: ResetFailedLogins :exec
+UPDATE users
+SET failed_login_attempts = 0, locked_until = NULL
+WHERE id = $1 AND tenant_id = $2 AND (failed_login_attempts <> 0 OR locked_until IS NOT NULL);
@@ -14,7 +14,7 @@ import (
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (tenant_id, email, role, hashed_secret)
 VALUES ($1, $2, $3, $4)
-RETURNING id, tenant_id, email, role, hashed_secret, created_at, updated_at
+RETURNING id, tenant_id, email, role, hashed_secret, created_at, updated_at, failed_login_attempts, locked_until
 `
 
 type CreateUserParams struct {
@@ -40,6 +40,8 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.HashedSecret,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
 	)
 	return i, err
 }
@@ -60,7 +62,7 @@ func (q *Queries) DeleteUser(ctx context.Context, arg DeleteUserParams) error {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, tenant_id, email, role, hashed_secret, created_at, updated_at FROM users
+SELECT id, tenant_id, email, role, hashed_secret, created_at, updated_at, failed_login_attempts, locked_until FROM users
 WHERE id = $1 AND tenant_id = $2
 `
 
@@ -80,12 +82,14 @@ func (q *Queries) GetUser(ctx context.Context, arg GetUserParams) (User, error)
 		&i.HashedSecret,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, tenant_id, email, role, hashed_secret, created_at, updated_at FROM users
+SELECT id, tenant_id, email, role, hashed_secret, created_at, updated_at, failed_login_attempts, locked_until FROM users
 WHERE tenant_id = $1 AND email = $2
 `
 
@@ -105,12 +109,14 @@ func (q *Queries) GetUserByEmail(ctx context.Context, arg GetUserByEmailParams)
 		&i.HashedSecret,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
 	)
 	return i, err
 }
 
 const listUsersByTenant = `-- name: ListUsersByTenant :many
-SELECT id, tenant_id, email, role, hashed_secret, created_at, updated_at FROM users
+SELECT id, tenant_id, email, role, hashed_secret, created_at, updated_at, failed_login_attempts, locked_until FROM users
 WHERE tenant_id = $1
 ORDER BY created_at DESC
 `
@@ -132,6 +138,8 @@ func (q *Queries) ListUsersByTenant(ctx context.Context, tenantID pgtype.UUID) (
 			&i.HashedSecret,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.FailedLoginAttempts,
+			&i.LockedUntil,
 		); err != nil {
 			return nil, err
 		}
@@ -143,11 +151,54 @@ func (q *Queries) ListUsersByTenant(ctx context.Context, tenantID pgtype.UUID) (
 	return items, nil
 }
 
+const recordFailedLogin = `-- name: RecordFailedLogin :one
+UPDATE users
+SET failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= $3::int THEN 0 ELSE failed_login_attempts + 1 END,
+    locked_until = CASE WHEN failed_login_attempts + 1 >= $3::int THEN $4::timestamptz ELSE locked_until END
+WHERE id = $1 AND tenant_id = $2
+RETURNING locked_until
+`
+
+type RecordFailedLoginParams struct {
+	ID          pgtype.UUID        `json:"id"`
+	TenantID    pgtype.UUID        `json:"tenant_id"`
+	MaxAttempts int32              `json:"max_attempts"`
+	LockUntil   pgtype.Timestamptz `json:"lock_until"`
+}
+
+func (q *Queries) RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, recordFailedLogin,
+		arg.ID,
+		arg.TenantID,
+		arg.MaxAttempts,
+		arg.LockUntil,
+	)
+	var lockedUntil pgtype.Timestamptz
+	err := row.Scan(&lockedUntil)
+	return lockedUntil, err
+}
+
+const resetFailedLogins = `-- name: ResetFailedLogins :exec
+UPDATE users
+SET failed_login_attempts = 0, locked_until = NULL
+WHERE id = $1 AND tenant_id = $2 AND (failed_login_attempts <> 0 OR locked_until IS NOT NULL)
+`
+
+type ResetFailedLoginsParams struct {
+	ID       pgtype.UUID `json:"id"`
+	TenantID pgtype.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) ResetFailedLogins(ctx context.Context, arg ResetFailedLoginsParams) error {
+	_, err := q.db.Exec(ctx, resetFailedLogins, arg.ID, arg.TenantID)
+	return err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET email = $3, role = $4, hashed_secret = $5, updated_at = NOW()
 WHERE id = $1 AND tenant_id = $2
-RETURNING id, tenant_id, email, role, hashed_secret, created_at, updated_at
+RETURNING id, tenant_id, email, role, hashed_secret, created_at, updated_at, failed_login_attempts, locked_until
 `
 
 type UpdateUserParams struct {
@@ -175,6 +226,8 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.HashedSecret,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
 	)
 	return i, err
 }
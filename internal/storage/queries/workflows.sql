@@ -5,28 +5,75 @@ RETURNING *;
 
 -- name: GetWorkflow :one
 SELECT * FROM workflows
-WHERE id = $1 AND tenant_id = $2;
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL;
 
 -- name: GetWorkflowByNameVersion :one
 SELECT * FROM workflows
-WHERE tenant_id = $1 AND name = $2 AND version = $3;
+WHERE tenant_id = $1 AND name = $2 AND version = $3 AND deleted_at IS NULL;
+
+-- name: GetWorkflowForUpdate :one
+SELECT * FROM workflows
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE;
+
+-- name: CountWorkflowsByTenant :one
+SELECT COUNT(*) FROM workflows
+WHERE tenant_id = $1 AND deleted_at IS NULL;
 
 -- name: ListWorkflowsByTenant :many
 SELECT * FROM workflows
-WHERE tenant_id = $1
+WHERE tenant_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC;
 
 -- name: ListWorkflowsByPlanner :many
 SELECT * FROM workflows
-WHERE tenant_id = $1 AND planner_type = $2
+WHERE tenant_id = $1 AND planner_type = $2 AND deleted_at IS NULL
 ORDER BY created_at DESC;
 
+-- name: ListDeletedWorkflows :many
+SELECT * FROM workflows
+WHERE tenant_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;
+
 -- name: UpdateWorkflow :one
 UPDATE workflows
 SET name = $3, version = $4, config_yaml = $5, planner_type = $6, template_version_constraint = $7, updated_at = NOW()
-WHERE id = $1 AND tenant_id = $2
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: DeleteWorkflow :one
+UPDATE workflows
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 RETURNING *;
 
--- name: DeleteWorkflow :exec
-DELETE FROM workflows
-WHERE id = $1 AND tenant_id = $2;
\ No newline at end of file
+-- name: RestoreWorkflow :one
+UPDATE workflows
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: CreateWorkflowRevision :one
+INSERT INTO workflow_revisions (tenant_id, workflow_id, revision, operation, name, version, config_yaml, planner_type, template_version_constraint, audit_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetWorkflowRevision :one
+SELECT * FROM workflow_revisions
+WHERE tenant_id = $1 AND workflow_id = $2 AND revision = $3;
+
+-- name: GetLatestWorkflowRevision :one
+SELECT * FROM workflow_revisions
+WHERE tenant_id = $1 AND workflow_id = $2
+ORDER BY revision DESC
+LIMIT 1;
+
+-- name: ListWorkflowRevisions :many
+SELECT * FROM workflow_revisions
+WHERE tenant_id = $1 AND workflow_id = $2
+ORDER BY revision DESC;
+
+-- name: ListWorkflowsForExport :many
+SELECT * FROM workflows
+WHERE tenant_id = $1
+ORDER BY created_at ASC, id ASC;
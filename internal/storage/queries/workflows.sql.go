@@ -11,10 +11,22 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countWorkflowsByTenant = `-- name: CountWorkflowsByTenant :one
+SELECT COUNT(*) FROM workflows
+WHERE tenant_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) CountWorkflowsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countWorkflowsByTenant, tenantID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createWorkflow = `-- name: CreateWorkflow :one
 INSERT INTO workflows (tenant_id, name, version, config_yaml, planner_type, template_version_constraint)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at
+RETURNING id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at, deleted_at
 `
 
 type CreateWorkflowParams struct {
@@ -46,13 +58,66 @@ func (q *Queries) CreateWorkflow(ctx context.Context, arg CreateWorkflowParams)
 		&i.TemplateVersionConstraint,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const deleteWorkflow = `-- name: DeleteWorkflow :exec
-DELETE FROM workflows
-WHERE id = $1 AND tenant_id = $2
+const createWorkflowRevision = `-- name: CreateWorkflowRevision :one
+INSERT INTO workflow_revisions (tenant_id, workflow_id, revision, operation, name, version, config_yaml, planner_type, template_version_constraint, audit_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, tenant_id, workflow_id, revision, operation, name, version, config_yaml, planner_type, template_version_constraint, audit_id, created_at
+`
+
+type CreateWorkflowRevisionParams struct {
+	TenantID                  pgtype.UUID `json:"tenant_id"`
+	WorkflowID                pgtype.UUID `json:"workflow_id"`
+	Revision                  int32       `json:"revision"`
+	Operation                 string      `json:"operation"`
+	Name                      string      `json:"name"`
+	Version                   string      `json:"version"`
+	ConfigYaml                string      `json:"config_yaml"`
+	PlannerType               string      `json:"planner_type"`
+	TemplateVersionConstraint pgtype.Text `json:"template_version_constraint"`
+	AuditID                   pgtype.UUID `json:"audit_id"`
+}
+
+func (q *Queries) CreateWorkflowRevision(ctx context.Context, arg CreateWorkflowRevisionParams) (WorkflowRevision, error) {
+	row := q.db.QueryRow(ctx, createWorkflowRevision,
+		arg.TenantID,
+		arg.WorkflowID,
+		arg.Revision,
+		arg.Operation,
+		arg.Name,
+		arg.Version,
+		arg.ConfigYaml,
+		arg.PlannerType,
+		arg.TemplateVersionConstraint,
+		arg.AuditID,
+	)
+	var i WorkflowRevision
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.WorkflowID,
+		&i.Revision,
+		&i.Operation,
+		&i.Name,
+		&i.Version,
+		&i.ConfigYaml,
+		&i.PlannerType,
+		&i.TemplateVersionConstraint,
+		&i.AuditID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteWorkflow = `-- name: DeleteWorkflow :one
+UPDATE workflows
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+RETURNING id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at, deleted_at
 `
 
 type DeleteWorkflowParams struct {
@@ -60,14 +125,59 @@ type DeleteWorkflowParams struct {
 	TenantID pgtype.UUID `json:"tenant_id"`
 }
 
-func (q *Queries) DeleteWorkflow(ctx context.Context, arg DeleteWorkflowParams) error {
-	_, err := q.db.Exec(ctx, deleteWorkflow, arg.ID, arg.TenantID)
-	return err
+func (q *Queries) DeleteWorkflow(ctx context.Context, arg DeleteWorkflowParams) (Workflow, error) {
+	row := q.db.QueryRow(ctx, deleteWorkflow, arg.ID, arg.TenantID)
+	var i Workflow
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Name,
+		&i.Version,
+		&i.ConfigYaml,
+		&i.PlannerType,
+		&i.TemplateVersionConstraint,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getLatestWorkflowRevision = `-- name: GetLatestWorkflowRevision :one
+SELECT id, tenant_id, workflow_id, revision, operation, name, version, config_yaml, planner_type, template_version_constraint, audit_id, created_at FROM workflow_revisions
+WHERE tenant_id = $1 AND workflow_id = $2
+ORDER BY revision DESC
+LIMIT 1
+`
+
+type GetLatestWorkflowRevisionParams struct {
+	TenantID   pgtype.UUID `json:"tenant_id"`
+	WorkflowID pgtype.UUID `json:"workflow_id"`
+}
+
+func (q *Queries) GetLatestWorkflowRevision(ctx context.Context, arg GetLatestWorkflowRevisionParams) (WorkflowRevision, error) {
+	row := q.db.QueryRow(ctx, getLatestWorkflowRevision, arg.TenantID, arg.WorkflowID)
+	var i WorkflowRevision
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.WorkflowID,
+		&i.Revision,
+		&i.Operation,
+		&i.Name,
+		&i.Version,
+		&i.ConfigYaml,
+		&i.PlannerType,
+		&i.TemplateVersionConstraint,
+		&i.AuditID,
+		&i.CreatedAt,
+	)
+	return i, err
 }
 
 const getWorkflow = `-- name: GetWorkflow :one
-SELECT id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at FROM workflows
-WHERE id = $1 AND tenant_id = $2
+SELECT id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at, deleted_at FROM workflows
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 `
 
 type GetWorkflowParams struct {
@@ -88,13 +198,14 @@ func (q *Queries) GetWorkflow(ctx context.Context, arg GetWorkflowParams) (Workf
 		&i.TemplateVersionConstraint,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getWorkflowByNameVersion = `-- name: GetWorkflowByNameVersion :one
-SELECT id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at FROM workflows
-WHERE tenant_id = $1 AND name = $2 AND version = $3
+SELECT id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at, deleted_at FROM workflows
+WHERE tenant_id = $1 AND name = $2 AND version = $3 AND deleted_at IS NULL
 `
 
 type GetWorkflowByNameVersionParams struct {
@@ -116,13 +227,155 @@ func (q *Queries) GetWorkflowByNameVersion(ctx context.Context, arg GetWorkflowB
 		&i.TemplateVersionConstraint,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
+const getWorkflowForUpdate = `-- name: GetWorkflowForUpdate :one
+SELECT id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at, deleted_at FROM workflows
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE
+`
+
+type GetWorkflowForUpdateParams struct {
+	ID       pgtype.UUID `json:"id"`
+	TenantID pgtype.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) GetWorkflowForUpdate(ctx context.Context, arg GetWorkflowForUpdateParams) (Workflow, error) {
+	row := q.db.QueryRow(ctx, getWorkflowForUpdate, arg.ID, arg.TenantID)
+	var i Workflow
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Name,
+		&i.Version,
+		&i.ConfigYaml,
+		&i.PlannerType,
+		&i.TemplateVersionConstraint,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getWorkflowRevision = `-- name: GetWorkflowRevision :one
+SELECT id, tenant_id, workflow_id, revision, operation, name, version, config_yaml, planner_type, template_version_constraint, audit_id, created_at FROM workflow_revisions
+WHERE tenant_id = $1 AND workflow_id = $2 AND revision = $3
+`
+
+type GetWorkflowRevisionParams struct {
+	TenantID   pgtype.UUID `json:"tenant_id"`
+	WorkflowID pgtype.UUID `json:"workflow_id"`
+	Revision   int32       `json:"revision"`
+}
+
+func (q *Queries) GetWorkflowRevision(ctx context.Context, arg GetWorkflowRevisionParams) (WorkflowRevision, error) {
+	row := q.db.QueryRow(ctx, getWorkflowRevision, arg.TenantID, arg.WorkflowID, arg.Revision)
+	var i WorkflowRevision
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.WorkflowID,
+		&i.Revision,
+		&i.Operation,
+		&i.Name,
+		&i.Version,
+		&i.ConfigYaml,
+		&i.PlannerType,
+		&i.TemplateVersionConstraint,
+		&i.AuditID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listDeletedWorkflows = `-- name: ListDeletedWorkflows :many
+SELECT id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at, deleted_at FROM workflows
+WHERE tenant_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC
+`
+
+func (q *Queries) ListDeletedWorkflows(ctx context.Context, tenantID pgtype.UUID) ([]Workflow, error) {
+	rows, err := q.db.Query(ctx, listDeletedWorkflows, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Workflow{}
+	for rows.Next() {
+		var i Workflow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Name,
+			&i.Version,
+			&i.ConfigYaml,
+			&i.PlannerType,
+			&i.TemplateVersionConstraint,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkflowRevisions = `-- name: ListWorkflowRevisions :many
+SELECT id, tenant_id, workflow_id, revision, operation, name, version, config_yaml, planner_type, template_version_constraint, audit_id, created_at FROM workflow_revisions
+WHERE tenant_id = $1 AND workflow_id = $2
+ORDER BY revision DESC
+`
+
+type ListWorkflowRevisionsParams struct {
+	TenantID   pgtype.UUID `json:"tenant_id"`
+	WorkflowID pgtype.UUID `json:"workflow_id"`
+}
+
+func (q *Queries) ListWorkflowRevisions(ctx context.Context, arg ListWorkflowRevisionsParams) ([]WorkflowRevision, error) {
+	rows, err := q.db.Query(ctx, listWorkflowRevisions, arg.TenantID, arg.WorkflowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WorkflowRevision{}
+	for rows.Next() {
+		var i WorkflowRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.WorkflowID,
+			&i.Revision,
+			&i.Operation,
+			&i.Name,
+			&i.Version,
+			&i.ConfigYaml,
+			&i.PlannerType,
+			&i.TemplateVersionConstraint,
+			&i.AuditID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listWorkflowsByPlanner = `-- name: ListWorkflowsByPlanner :many
-SELECT id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at FROM workflows
-WHERE tenant_id = $1 AND planner_type = $2
+SELECT id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at, deleted_at FROM workflows
+WHERE tenant_id = $1 AND planner_type = $2 AND deleted_at IS NULL
 ORDER BY created_at DESC
 `
 
@@ -150,6 +403,7 @@ func (q *Queries) ListWorkflowsByPlanner(ctx context.Context, arg ListWorkflowsB
 			&i.TemplateVersionConstraint,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -162,8 +416,8 @@ func (q *Queries) ListWorkflowsByPlanner(ctx context.Context, arg ListWorkflowsB
 }
 
 const listWorkflowsByTenant = `-- name: ListWorkflowsByTenant :many
-SELECT id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at FROM workflows
-WHERE tenant_id = $1
+SELECT id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at, deleted_at FROM workflows
+WHERE tenant_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC
 `
 
@@ -186,6 +440,7 @@ func (q *Queries) ListWorkflowsByTenant(ctx context.Context, tenantID pgtype.UUI
 			&i.TemplateVersionConstraint,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -197,11 +452,78 @@ func (q *Queries) ListWorkflowsByTenant(ctx context.Context, tenantID pgtype.UUI
 	return items, nil
 }
 
+const listWorkflowsForExport = `-- name: ListWorkflowsForExport :many
+SELECT id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at, deleted_at FROM workflows
+WHERE tenant_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) ListWorkflowsForExport(ctx context.Context, tenantID pgtype.UUID) ([]Workflow, error) {
+	rows, err := q.db.Query(ctx, listWorkflowsForExport, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Workflow{}
+	for rows.Next() {
+		var i Workflow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Name,
+			&i.Version,
+			&i.ConfigYaml,
+			&i.PlannerType,
+			&i.TemplateVersionConstraint,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreWorkflow = `-- name: RestoreWorkflow :one
+UPDATE workflows
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at, deleted_at
+`
+
+type RestoreWorkflowParams struct {
+	ID       pgtype.UUID `json:"id"`
+	TenantID pgtype.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) RestoreWorkflow(ctx context.Context, arg RestoreWorkflowParams) (Workflow, error) {
+	row := q.db.QueryRow(ctx, restoreWorkflow, arg.ID, arg.TenantID)
+	var i Workflow
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Name,
+		&i.Version,
+		&i.ConfigYaml,
+		&i.PlannerType,
+		&i.TemplateVersionConstraint,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const updateWorkflow = `-- name: UpdateWorkflow :one
 UPDATE workflows
 SET name = $3, version = $4, config_yaml = $5, planner_type = $6, template_version_constraint = $7, updated_at = NOW()
-WHERE id = $1 AND tenant_id = $2
-RETURNING id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+RETURNING id, tenant_id, name, version, config_yaml, planner_type, template_version_constraint, created_at, updated_at, deleted_at
 `
 
 type UpdateWorkflowParams struct {
@@ -235,6 +557,7 @@ func (q *Queries) UpdateWorkflow(ctx context.Context, arg UpdateWorkflowParams)
 		&i.TemplateVersionConstraint,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,195 @@
+package revision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CreateAgent creates an agent and records revision 1. It returns a
+// *quota.QuotaError if the tenant has as many agents as its limits allow.
+func (s *Service) CreateAgent(ctx context.Context, actor Actor, arg queries.CreateAgentParams) (*queries.Agent, error) {
+	var agent queries.Agent
+	err := s.withTx(ctx, func(q Querier) error {
+		if err := checkQuota(ctx, q, arg.TenantID, ResourceAgent); err != nil {
+			return err
+		}
+		var err error
+		agent, err = q.CreateAgent(ctx, arg)
+		if err != nil {
+			return fmt.Errorf("failed to create agent: %w", err)
+		}
+		return recordAgentRevision(ctx, q, actor, agent, OperationCreate, 1, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// UpdateAgent overwrites an agent and records the new configuration as a revision
+func (s *Service) UpdateAgent(ctx context.Context, actor Actor, arg queries.UpdateAgentParams) (*queries.Agent, error) {
+	var agent queries.Agent
+	err := s.withTx(ctx, func(q Querier) error {
+		next, err := lockAgent(ctx, q, arg.TenantID, arg.ID, false)
+		if err != nil {
+			return err
+		}
+		agent, err = q.UpdateAgent(ctx, arg)
+		if err != nil {
+			return fmt.Errorf("failed to update agent: %w", err)
+		}
+		return recordAgentRevision(ctx, q, actor, agent, OperationUpdate, next, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// DeleteAgent soft-deletes an agent. The row and its history are kept and
+// the agent can be brought back with RestoreAgent.
+func (s *Service) DeleteAgent(ctx context.Context, actor Actor, tenantID, agentID pgtype.UUID) error {
+	return s.withTx(ctx, func(q Querier) error {
+		next, err := lockAgent(ctx, q, tenantID, agentID, false)
+		if err != nil {
+			return err
+		}
+		agent, err := q.DeleteAgent(ctx, queries.DeleteAgentParams{ID: agentID, TenantID: tenantID})
+		if err != nil {
+			return fmt.Errorf("failed to delete agent: %w", err)
+		}
+		return recordAgentRevision(ctx, q, actor, agent, OperationDelete, next, nil)
+	})
+}
+
+// RestoreAgent undoes a soft delete. Restored agents count against the
+// tenant's limits like new ones.
+func (s *Service) RestoreAgent(ctx context.Context, actor Actor, tenantID, agentID pgtype.UUID) (*queries.Agent, error) {
+	var agent queries.Agent
+	err := s.withTx(ctx, func(q Querier) error {
+		if err := checkQuota(ctx, q, tenantID, ResourceAgent); err != nil {
+			return err
+		}
+		next, err := lockAgent(ctx, q, tenantID, agentID, true)
+		if err != nil {
+			return err
+		}
+		agent, err = q.RestoreAgent(ctx, queries.RestoreAgentParams{ID: agentID, TenantID: tenantID})
+		if err != nil {
+			return fmt.Errorf("failed to restore agent: %w", err)
+		}
+		return recordAgentRevision(ctx, q, actor, agent, OperationRestore, next, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// RevertAgent applies the configuration of an earlier revision as a new revision
+func (s *Service) RevertAgent(ctx context.Context, actor Actor, tenantID, agentID pgtype.UUID, revision int32) (*queries.Agent, error) {
+	var agent queries.Agent
+	err := s.withTx(ctx, func(q Querier) error {
+		next, err := lockAgent(ctx, q, tenantID, agentID, false)
+		if err != nil {
+			return err
+		}
+		target, err := q.GetAgentRevision(ctx, queries.GetAgentRevisionParams{TenantID: tenantID, AgentID: agentID, Revision: revision})
+		if err != nil {
+			return notFound(err, ErrRevisionNotFound)
+		}
+		agent, err = q.UpdateAgent(ctx, queries.UpdateAgentParams{
+			ID:           agentID,
+			TenantID:     tenantID,
+			Name:         target.Name,
+			Type:         target.Type,
+			Role:         target.Role,
+			ConfigJson:   target.ConfigJson,
+			PoliciesJson: target.PoliciesJson,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to revert agent: %w", err)
+		}
+		return recordAgentRevision(ctx, q, actor, agent, OperationRevert, next, map[string]interface{}{"reverted_to": revision})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// ListAgentRevisions returns the revisions of an agent, newest first
+func (s *Service) ListAgentRevisions(ctx context.Context, tenantID, agentID pgtype.UUID) ([]queries.AgentRevision, error) {
+	revisions, err := s.newQuerier(s.db).ListAgentRevisions(ctx, queries.ListAgentRevisionsParams{TenantID: tenantID, AgentID: agentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// DiffAgentRevisions compares two revisions of an agent
+func (s *Service) DiffAgentRevisions(ctx context.Context, tenantID, agentID pgtype.UUID, from, to int32) (*AgentDiff, error) {
+	q := s.newQuerier(s.db)
+	fromRev, err := q.GetAgentRevision(ctx, queries.GetAgentRevisionParams{TenantID: tenantID, AgentID: agentID, Revision: from})
+	if err != nil {
+		return nil, fmt.Errorf("revision %d: %w", from, notFound(err, ErrRevisionNotFound))
+	}
+	toRev, err := q.GetAgentRevision(ctx, queries.GetAgentRevisionParams{TenantID: tenantID, AgentID: agentID, Revision: to})
+	if err != nil {
+		return nil, fmt.Errorf("revision %d: %w", to, notFound(err, ErrRevisionNotFound))
+	}
+	return DiffAgents(fromRev, toRev)
+}
+
+// lockAgent locks the agent row and returns the next revision number. The
+// lock serializes writers so revision numbers are assigned without gaps.
+func lockAgent(ctx context.Context, q Querier, tenantID, agentID pgtype.UUID, wantDeleted bool) (int32, error) {
+	agent, err := q.GetAgentForUpdate(ctx, queries.GetAgentForUpdateParams{ID: agentID, TenantID: tenantID})
+	if err != nil {
+		return 0, notFound(err, ErrNotFound)
+	}
+	deleted := agent.DeletedAt.Valid
+	if deleted && !wantDeleted {
+		return 0, ErrDeleted
+	}
+	if !deleted && wantDeleted {
+		return 0, ErrNotDeleted
+	}
+
+	latest, err := q.GetLatestAgentRevision(ctx, queries.GetLatestAgentRevisionParams{TenantID: tenantID, AgentID: agentID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 1, nil
+		}
+		return 0, fmt.Errorf("failed to get latest agent revision: %w", err)
+	}
+	return latest.Revision + 1, nil
+}
+
+func recordAgentRevision(ctx context.Context, q Querier, actor Actor, agent queries.Agent, operation string, revision int32, details map[string]interface{}) error {
+	auditID, err := recordAudit(ctx, q, agent.TenantID, actor, operation, ResourceAgent, agent.ID, revision, details)
+	if err != nil {
+		return err
+	}
+	_, err = q.CreateAgentRevision(ctx, queries.CreateAgentRevisionParams{
+		TenantID:     agent.TenantID,
+		AgentID:      agent.ID,
+		Revision:     revision,
+		Operation:    operation,
+		Name:         agent.Name,
+		Type:         agent.Type,
+		Role:         agent.Role,
+		ConfigJson:   agent.ConfigJson,
+		PoliciesJson: agent.PoliciesJson,
+		AuditID:      auditID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record agent revision: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package revision
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Change kinds reported by diffs
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// FieldChange is a change to a scalar column
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// JSONChange is a change at a JSON Pointer (RFC 6901) path
+type JSONChange struct {
+	Path string          `json:"path"`
+	Kind string          `json:"kind"`
+	From json.RawMessage `json:"from,omitempty"`
+	To   json.RawMessage `json:"to,omitempty"`
+}
+
+// LineChange is a removed ("-") or added ("+") line. Line numbers are 1-based
+// and refer to the old text for removals and the new text for additions.
+type LineChange struct {
+	Op   string `json:"op"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// AgentDiff describes the changes between two agent revisions
+type AgentDiff struct {
+	FromRevision int32         `json:"from_revision"`
+	ToRevision   int32         `json:"to_revision"`
+	Fields       []FieldChange `json:"fields"`
+	Config       []JSONChange  `json:"config"`
+	Policies     []JSONChange  `json:"policies"`
+}
+
+// WorkflowDiff describes the changes between two workflow revisions
+type WorkflowDiff struct {
+	FromRevision int32         `json:"from_revision"`
+	ToRevision   int32         `json:"to_revision"`
+	Fields       []FieldChange `json:"fields"`
+	Config       []LineChange  `json:"config"`
+}
+
+// Empty reports whether the revisions are identical
+func (d *AgentDiff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.Config) == 0 && len(d.Policies) == 0
+}
+
+// Empty reports whether the revisions are identical
+func (d *WorkflowDiff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.Config) == 0
+}
+
+// DiffAgents compares two agent revisions
+func DiffAgents(from, to queries.AgentRevision) (*AgentDiff, error) {
+	diff := &AgentDiff{FromRevision: from.Revision, ToRevision: to.Revision}
+	diff.Fields = diffFields(
+		[]string{"name", "type", "role"},
+		[]string{from.Name, from.Type, textValue(from.Role)},
+		[]string{to.Name, to.Type, textValue(to.Role)},
+	)
+
+	var err error
+	if diff.Config, err = DiffJSON(from.ConfigJson, to.ConfigJson); err != nil {
+		return nil, fmt.Errorf("failed to diff config_json: %w", err)
+	}
+	if diff.Policies, err = DiffJSON(from.PoliciesJson, to.PoliciesJson); err != nil {
+		return nil, fmt.Errorf("failed to diff policies_json: %w", err)
+	}
+	return diff, nil
+}
+
+// DiffWorkflows compares two workflow revisions
+func DiffWorkflows(from, to queries.WorkflowRevision) *WorkflowDiff {
+	return &WorkflowDiff{
+		FromRevision: from.Revision,
+		ToRevision:   to.Revision,
+		Fields: diffFields(
+			[]string{"name", "version", "planner_type", "template_version_constraint"},
+			[]string{from.Name, from.Version, from.PlannerType, textValue(from.TemplateVersionConstraint)},
+			[]string{to.Name, to.Version, to.PlannerType, textValue(to.TemplateVersionConstraint)},
+		),
+		Config: DiffLines(from.ConfigYaml, to.ConfigYaml),
+	}
+}
+
+// DiffJSON compares two JSON documents and reports changes by path. Objects
+// are compared key by key and arrays element by element.
+func DiffJSON(from, to []byte) ([]JSONChange, error) {
+	var a, b interface{}
+	if err := decodeJSON(from, &a); err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(to, &b); err != nil {
+		return nil, err
+	}
+	changes := []JSONChange{}
+	diffValues("", a, b, &changes)
+	return changes, nil
+}
+
+func decodeJSON(data []byte, v *interface{}) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		*v = nil
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func diffValues(path string, a, b interface{}, changes *[]JSONChange) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(av)+len(bv))
+			for k := range av {
+				keys = append(keys, k)
+			}
+			for k := range bv {
+				if _, ok := av[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				child := path + "/" + escapePointer(k)
+				x, inA := av[k]
+				y, inB := bv[k]
+				switch {
+				case !inB:
+					*changes = append(*changes, JSONChange{Path: child, Kind: ChangeRemoved, From: rawJSON(x)})
+				case !inA:
+					*changes = append(*changes, JSONChange{Path: child, Kind: ChangeAdded, To: rawJSON(y)})
+				default:
+					diffValues(child, x, y, changes)
+				}
+			}
+			return
+		}
+	case json.Number:
+		// 1 and 1.0 are the same value
+		if bv, ok := b.(json.Number); ok && numbersEqual(av, bv) {
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			for i := 0; i < len(av) || i < len(bv); i++ {
+				child := path + "/" + strconv.Itoa(i)
+				switch {
+				case i >= len(bv):
+					*changes = append(*changes, JSONChange{Path: child, Kind: ChangeRemoved, From: rawJSON(av[i])})
+				case i >= len(av):
+					*changes = append(*changes, JSONChange{Path: child, Kind: ChangeAdded, To: rawJSON(bv[i])})
+				default:
+					diffValues(child, av[i], bv[i], changes)
+				}
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, JSONChange{Path: path, Kind: ChangeChanged, From: rawJSON(a), To: rawJSON(b)})
+	}
+}
+
+func numbersEqual(a, b json.Number) bool {
+	if a == b {
+		return true
+	}
+	x, errA := a.Float64()
+	y, errB := b.Float64()
+	return errA == nil && errB == nil && x == y
+}
+
+// DiffLines compares two texts line by line using a longest common subsequence
+func DiffLines(from, to string) []LineChange {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	changes := []LineChange{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			changes = append(changes, LineChange{Op: "-", Line: i + 1, Text: a[i]})
+			i++
+		default:
+			changes = append(changes, LineChange{Op: "+", Line: j + 1, Text: b[j]})
+			j++
+		}
+	}
+	return changes
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func diffFields(names, from, to []string) []FieldChange {
+	changes := []FieldChange{}
+	for i, name := range names {
+		if from[i] != to[i] {
+			changes = append(changes, FieldChange{Field: name, From: from[i], To: to[i]})
+		}
+	}
+	return changes
+}
+
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func rawJSON(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func textValue(t pgtype.Text) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.String
+}
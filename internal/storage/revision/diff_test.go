@@ -0,0 +1,84 @@
+package revision
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected []JSONChange
+	}{
+		{
+			name:     "identical",
+			from:     `{"a":1,"b":{"c":[1,2]}}`,
+			to:       `{"b":{"c":[1,2]},"a":1}`,
+			expected: []JSONChange{},
+		},
+		{
+			name: "nested changes",
+			from: `{"a":1,"b":{"c":true,"d":"x"}}`,
+			to:   `{"a":1.0,"b":{"c":false,"e":null}}`,
+			expected: []JSONChange{
+				{Path: "/b/c", Kind: ChangeChanged, From: json.RawMessage(`true`), To: json.RawMessage(`false`)},
+				{Path: "/b/d", Kind: ChangeRemoved, From: json.RawMessage(`"x"`)},
+				{Path: "/b/e", Kind: ChangeAdded, To: json.RawMessage(`null`)},
+			},
+		},
+		{
+			name: "array shrink and pointer escaping",
+			from: `{"a/b":[1,2,3],"m~n":1}`,
+			to:   `{"a/b":[1],"m~n":2}`,
+			expected: []JSONChange{
+				{Path: "/a~1b/1", Kind: ChangeRemoved, From: json.RawMessage(`2`)},
+				{Path: "/a~1b/2", Kind: ChangeRemoved, From: json.RawMessage(`3`)},
+				{Path: "/m~0n", Kind: ChangeChanged, From: json.RawMessage(`1`), To: json.RawMessage(`2`)},
+			},
+		},
+		{
+			name: "type change",
+			from: `{"a":{"b":1}}`,
+			to:   `{"a":[1]}`,
+			expected: []JSONChange{
+				{Path: "/a", Kind: ChangeChanged, From: json.RawMessage(`{"b":1}`), To: json.RawMessage(`[1]`)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := DiffJSON([]byte(tt.from), []byte(tt.to))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, changes)
+		})
+	}
+}
+
+func TestDiffJSON_Invalid(t *testing.T) {
+	_, err := DiffJSON([]byte(`{`), []byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestDiffLines(t *testing.T) {
+	from := "name: triage\nsteps:\n  - classify\n  - escalate\n"
+	to := "name: triage\nsteps:\n  - classify\n  - route\n  - escalate\ntimeout: 30s\n"
+
+	assert.Equal(t, []LineChange{
+		{Op: "+", Line: 4, Text: "  - route"},
+		{Op: "+", Line: 6, Text: "timeout: 30s"},
+	}, DiffLines(from, to))
+
+	assert.Equal(t, []LineChange{
+		{Op: "-", Line: 1, Text: "a"},
+		{Op: "+", Line: 1, Text: "b"},
+	}, DiffLines("a", "b"))
+
+	assert.Empty(t, DiffLines(from, from))
+	assert.Equal(t, []LineChange{{Op: "+", Line: 1, Text: "x"}}, DiffLines("", "x\n"))
+}
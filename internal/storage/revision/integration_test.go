@@ -0,0 +1,63 @@
+//go:build integration
+// +build integration
+
+package revision
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+)
+
+func TestIntegration(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Skip("Database not available")
+	}
+	defer db.Close()
+
+	q := queries.New(db)
+	tenant, err := q.CreateTenant(ctx, queries.CreateTenantParams{Name: "revision-" + uuid.NewString(), Tier: "free", Settings: []byte(`{}`)})
+	require.NoError(t, err)
+	defer func() { _ = q.DeleteTenant(ctx, tenant.ID) }()
+
+	svc := NewService(db)
+	agent, err := svc.CreateAgent(ctx, testActor, queries.CreateAgentParams{
+		TenantID:     tenant.ID,
+		Name:         "planner",
+		Type:         "llm",
+		ConfigJson:   []byte(`{"model":"a"}`),
+		PoliciesJson: []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.DeleteAgent(ctx, testActor, tenant.ID, agent.ID))
+	_, err = q.GetAgent(ctx, queries.GetAgentParams{ID: agent.ID, TenantID: tenant.ID})
+	assert.Error(t, err, "soft-deleted agent must not be visible")
+
+	// The name is free again while the old agent is deleted
+	_, err = q.CreateAgent(ctx, queries.CreateAgentParams{TenantID: tenant.ID, Name: "planner", Type: "llm", ConfigJson: []byte(`{}`), PoliciesJson: []byte(`{}`)})
+	require.NoError(t, err)
+
+	revisions, err := svc.ListAgentRevisions(ctx, tenant.ID, agent.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+
+	_, err = db.Exec(ctx, "UPDATE agent_revisions SET operation = 'update' WHERE id = $1", revisions[0].ID)
+	assert.ErrorContains(t, err, "append-only")
+	_, err = db.Exec(ctx, "DELETE FROM agent_revisions WHERE id = $1", revisions[0].ID)
+	assert.ErrorContains(t, err, "append-only")
+}
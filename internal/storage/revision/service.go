@@ -0,0 +1,187 @@
+// Package revision provides soft-delete, restore and append-only revision
+// history for agents and workflows. Every change is recorded as a full
+// snapshot linked to the audit record of the actor who made it.
+package revision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/agentflow/agentflow/internal/quota"
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Operations recorded on a revision
+const (
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+	OperationRevert  = "revert"
+)
+
+// Resource types recorded in the audit log
+const (
+	ResourceAgent    = "agent"
+	ResourceWorkflow = "workflow"
+)
+
+var (
+	// ErrNotFound is returned when the agent or workflow does not exist for the tenant
+	ErrNotFound = errors.New("resource not found")
+	// ErrDeleted is returned when modifying a soft-deleted resource
+	ErrDeleted = errors.New("resource is deleted")
+	// ErrNotDeleted is returned when restoring a resource that is not deleted
+	ErrNotDeleted = errors.New("resource is not deleted")
+	// ErrRevisionNotFound is returned when the requested revision does not exist
+	ErrRevisionNotFound = errors.New("revision not found")
+)
+
+// Actor identifies who made a change
+type Actor struct {
+	Type string // "user", "agent", "system"
+	ID   string
+}
+
+// Querier defines the queries used by the revision service
+type Querier interface {
+	audit.AuditQuerier
+
+	GetTenantForUpdate(ctx context.Context, id pgtype.UUID) (queries.Tenant, error)
+
+	CountAgentsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	CreateAgent(ctx context.Context, arg queries.CreateAgentParams) (queries.Agent, error)
+	GetAgentForUpdate(ctx context.Context, arg queries.GetAgentForUpdateParams) (queries.Agent, error)
+	UpdateAgent(ctx context.Context, arg queries.UpdateAgentParams) (queries.Agent, error)
+	DeleteAgent(ctx context.Context, arg queries.DeleteAgentParams) (queries.Agent, error)
+	RestoreAgent(ctx context.Context, arg queries.RestoreAgentParams) (queries.Agent, error)
+	CreateAgentRevision(ctx context.Context, arg queries.CreateAgentRevisionParams) (queries.AgentRevision, error)
+	GetAgentRevision(ctx context.Context, arg queries.GetAgentRevisionParams) (queries.AgentRevision, error)
+	GetLatestAgentRevision(ctx context.Context, arg queries.GetLatestAgentRevisionParams) (queries.AgentRevision, error)
+	ListAgentRevisions(ctx context.Context, arg queries.ListAgentRevisionsParams) ([]queries.AgentRevision, error)
+
+	CountWorkflowsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	CreateWorkflow(ctx context.Context, arg queries.CreateWorkflowParams) (queries.Workflow, error)
+	GetWorkflowForUpdate(ctx context.Context, arg queries.GetWorkflowForUpdateParams) (queries.Workflow, error)
+	UpdateWorkflow(ctx context.Context, arg queries.UpdateWorkflowParams) (queries.Workflow, error)
+	DeleteWorkflow(ctx context.Context, arg queries.DeleteWorkflowParams) (queries.Workflow, error)
+	RestoreWorkflow(ctx context.Context, arg queries.RestoreWorkflowParams) (queries.Workflow, error)
+	CreateWorkflowRevision(ctx context.Context, arg queries.CreateWorkflowRevisionParams) (queries.WorkflowRevision, error)
+	GetWorkflowRevision(ctx context.Context, arg queries.GetWorkflowRevisionParams) (queries.WorkflowRevision, error)
+	GetLatestWorkflowRevision(ctx context.Context, arg queries.GetLatestWorkflowRevisionParams) (queries.WorkflowRevision, error)
+	ListWorkflowRevisions(ctx context.Context, arg queries.ListWorkflowRevisionsParams) ([]queries.WorkflowRevision, error)
+}
+
+// DB is a database handle that can run queries and start transactions.
+// *pgxpool.Pool and *pgx.Conn satisfy it.
+type DB interface {
+	queries.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Service records revisions for agent and workflow changes
+type Service struct {
+	db         DB
+	newQuerier func(db queries.DBTX) Querier
+}
+
+// NewService creates a new revision service
+func NewService(db DB) *Service {
+	return &Service{
+		db: db,
+		newQuerier: func(db queries.DBTX) Querier {
+			return queries.New(db)
+		},
+	}
+}
+
+// withTx runs fn in a transaction. The change, its audit record and its
+// revision are committed together or not at all.
+func (s *Service) withTx(ctx context.Context, fn func(q Querier) error) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := fn(s.newQuerier(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// checkQuota refuses to add an agent or workflow to a tenant that has as
+// many as its limits allow, returning a *quota.QuotaError. It locks the
+// tenant row, so concurrent creates and restores are counted one at a time,
+// and must run before any agent or workflow row is locked.
+func checkQuota(ctx context.Context, q Querier, tenantID pgtype.UUID, resourceType string) error {
+	tenant, err := q.GetTenantForUpdate(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to lock tenant: %w", notFound(err, ErrNotFound))
+	}
+	// Broken settings fall back to the tier's limits rather than none
+	limits, _ := quota.LimitsFromSettings(tenant.Tier, tenant.Settings)
+
+	var used int64
+	var limit int
+	var resource string
+	switch resourceType {
+	case ResourceAgent:
+		limit, resource = limits.MaxAgents, quota.ResourceAgents
+		used, err = q.CountAgentsByTenant(ctx, tenantID)
+	default:
+		limit, resource = limits.MaxWorkflows, quota.ResourceWorkflows
+		used, err = q.CountWorkflowsByTenant(ctx, tenantID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to count %ss: %w", resourceType, err)
+	}
+	return quota.CheckCount(uuidString(tenantID), resource, limit, used)
+}
+
+// recordAudit appends the audit record a revision links to
+func recordAudit(ctx context.Context, q Querier, tenantID pgtype.UUID, actor Actor, operation, resourceType string, resourceID pgtype.UUID, revision int32, details map[string]interface{}) (pgtype.UUID, error) {
+	if details == nil {
+		details = make(map[string]interface{})
+	}
+	details["revision"] = revision
+
+	id := uuidString(resourceID)
+	record, err := audit.NewService(q).CreateAudit(ctx, audit.CreateAuditParams{
+		TenantID:     tenantID,
+		ActorType:    actor.Type,
+		ActorID:      actor.ID,
+		Action:       operation,
+		ResourceType: resourceType,
+		ResourceID:   &id,
+		Details:      details,
+	})
+	if err != nil {
+		return pgtype.UUID{}, err
+	}
+	return record.ID, nil
+}
+
+// notFound maps pgx.ErrNoRows to sentinel
+func notFound(err, sentinel error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sentinel
+	}
+	return err
+}
+
+func uuidString(id pgtype.UUID) string {
+	if !id.Valid {
+		return ""
+	}
+	b := id.Bytes
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
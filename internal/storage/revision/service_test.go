@@ -0,0 +1,614 @@
+package revision
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/quota"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockTx records whether the service committed or rolled back
+type mockTx struct {
+	pgx.Tx
+	committed  bool
+	rolledBack bool
+}
+
+func (t *mockTx) Commit(ctx context.Context) error {
+	t.committed = true
+	return nil
+}
+
+func (t *mockTx) Rollback(ctx context.Context) error {
+	if !t.committed {
+		t.rolledBack = true
+	}
+	return nil
+}
+
+// mockDB hands out mockTx transactions
+type mockDB struct {
+	txs []*mockTx
+}
+
+func (d *mockDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (d *mockDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (d *mockDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return nil
+}
+
+func (d *mockDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx := &mockTx{}
+	d.txs = append(d.txs, tx)
+	return tx, nil
+}
+
+// mockQueries is an in-memory implementation of Querier
+type mockQueries struct {
+	agents            map[pgtype.UUID]*queries.Agent
+	agentRevisions    []queries.AgentRevision
+	workflows         map[pgtype.UUID]*queries.Workflow
+	workflowRevisions []queries.WorkflowRevision
+	audits            []queries.Audit
+	tenants           map[pgtype.UUID]queries.Tenant
+	failRevision      bool
+}
+
+func newMockQueries() *mockQueries {
+	return &mockQueries{
+		agents:    make(map[pgtype.UUID]*queries.Agent),
+		workflows: make(map[pgtype.UUID]*queries.Workflow),
+		tenants:   make(map[pgtype.UUID]queries.Tenant),
+	}
+}
+
+// GetTenantForUpdate returns tenants added to m.tenants, and tenants without
+// limits otherwise
+func (m *mockQueries) GetTenantForUpdate(ctx context.Context, id pgtype.UUID) (queries.Tenant, error) {
+	if tenant, ok := m.tenants[id]; ok {
+		return tenant, nil
+	}
+	return queries.Tenant{ID: id, Tier: quota.TierEnterprise, Settings: []byte(`{}`)}, nil
+}
+
+func (m *mockQueries) CountAgentsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	var n int64
+	for _, agent := range m.agents {
+		if agent.TenantID == tenantID && !agent.DeletedAt.Valid {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockQueries) CountWorkflowsByTenant(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	var n int64
+	for _, workflow := range m.workflows {
+		if workflow.TenantID == tenantID && !workflow.DeletedAt.Valid {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func newID(n int) pgtype.UUID {
+	var b [16]byte
+	b[0] = byte(n >> 8)
+	b[1] = byte(n)
+	b[15] = 0xaf
+	return pgtype.UUID{Bytes: b, Valid: true}
+}
+
+func (m *mockQueries) CreateAudit(ctx context.Context, arg queries.CreateAuditParams) (queries.Audit, error) {
+	audit := queries.Audit{
+		ID:           newID(1000 + len(m.audits)),
+		TenantID:     arg.TenantID,
+		ActorType:    arg.ActorType,
+		ActorID:      arg.ActorID,
+		Action:       arg.Action,
+		ResourceType: arg.ResourceType,
+		ResourceID:   arg.ResourceID,
+		Details:      arg.Details,
+		Ts:           arg.Ts,
+		PrevHash:     arg.PrevHash,
+		Hash:         arg.Hash,
+		Seq:          arg.Seq,
+	}
+	m.audits = append(m.audits, audit)
+	return audit, nil
+}
+
+func (m *mockQueries) GetLatestAudit(ctx context.Context, tenantID pgtype.UUID) (queries.Audit, error) {
+	if len(m.audits) == 0 {
+		return queries.Audit{}, pgx.ErrNoRows
+	}
+	return m.audits[len(m.audits)-1], nil
+}
+
+func (m *mockQueries) LockAuditChain(ctx context.Context, tenantID pgtype.UUID) error {
+	return nil
+}
+
+func (m *mockQueries) GetAuditChain(ctx context.Context, tenantID pgtype.UUID) ([]queries.Audit, error) {
+	return m.audits, nil
+}
+
+func (m *mockQueries) CreateAgent(ctx context.Context, arg queries.CreateAgentParams) (queries.Agent, error) {
+	agent := &queries.Agent{
+		ID:           newID(len(m.agents) + 1),
+		TenantID:     arg.TenantID,
+		Name:         arg.Name,
+		Type:         arg.Type,
+		Role:         arg.Role,
+		ConfigJson:   arg.ConfigJson,
+		PoliciesJson: arg.PoliciesJson,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	m.agents[agent.ID] = agent
+	return *agent, nil
+}
+
+func (m *mockQueries) agent(id, tenantID pgtype.UUID) (*queries.Agent, error) {
+	agent, ok := m.agents[id]
+	if !ok || agent.TenantID != tenantID {
+		return nil, pgx.ErrNoRows
+	}
+	return agent, nil
+}
+
+func (m *mockQueries) GetAgentForUpdate(ctx context.Context, arg queries.GetAgentForUpdateParams) (queries.Agent, error) {
+	agent, err := m.agent(arg.ID, arg.TenantID)
+	if err != nil {
+		return queries.Agent{}, err
+	}
+	return *agent, nil
+}
+
+func (m *mockQueries) UpdateAgent(ctx context.Context, arg queries.UpdateAgentParams) (queries.Agent, error) {
+	agent, err := m.agent(arg.ID, arg.TenantID)
+	if err != nil || agent.DeletedAt.Valid {
+		return queries.Agent{}, pgx.ErrNoRows
+	}
+	agent.Name, agent.Type, agent.Role = arg.Name, arg.Type, arg.Role
+	agent.ConfigJson, agent.PoliciesJson = arg.ConfigJson, arg.PoliciesJson
+	return *agent, nil
+}
+
+func (m *mockQueries) DeleteAgent(ctx context.Context, arg queries.DeleteAgentParams) (queries.Agent, error) {
+	agent, err := m.agent(arg.ID, arg.TenantID)
+	if err != nil || agent.DeletedAt.Valid {
+		return queries.Agent{}, pgx.ErrNoRows
+	}
+	agent.DeletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return *agent, nil
+}
+
+func (m *mockQueries) RestoreAgent(ctx context.Context, arg queries.RestoreAgentParams) (queries.Agent, error) {
+	agent, err := m.agent(arg.ID, arg.TenantID)
+	if err != nil || !agent.DeletedAt.Valid {
+		return queries.Agent{}, pgx.ErrNoRows
+	}
+	agent.DeletedAt = pgtype.Timestamptz{}
+	return *agent, nil
+}
+
+func (m *mockQueries) CreateAgentRevision(ctx context.Context, arg queries.CreateAgentRevisionParams) (queries.AgentRevision, error) {
+	if m.failRevision {
+		return queries.AgentRevision{}, errors.New("insert failed")
+	}
+	rev := queries.AgentRevision{
+		ID:           newID(2000 + len(m.agentRevisions)),
+		TenantID:     arg.TenantID,
+		AgentID:      arg.AgentID,
+		Revision:     arg.Revision,
+		Operation:    arg.Operation,
+		Name:         arg.Name,
+		Type:         arg.Type,
+		Role:         arg.Role,
+		ConfigJson:   arg.ConfigJson,
+		PoliciesJson: arg.PoliciesJson,
+		AuditID:      arg.AuditID,
+		CreatedAt:    time.Now(),
+	}
+	m.agentRevisions = append(m.agentRevisions, rev)
+	return rev, nil
+}
+
+func (m *mockQueries) GetAgentRevision(ctx context.Context, arg queries.GetAgentRevisionParams) (queries.AgentRevision, error) {
+	for _, rev := range m.agentRevisions {
+		if rev.TenantID == arg.TenantID && rev.AgentID == arg.AgentID && rev.Revision == arg.Revision {
+			return rev, nil
+		}
+	}
+	return queries.AgentRevision{}, pgx.ErrNoRows
+}
+
+func (m *mockQueries) GetLatestAgentRevision(ctx context.Context, arg queries.GetLatestAgentRevisionParams) (queries.AgentRevision, error) {
+	revs, _ := m.ListAgentRevisions(ctx, queries.ListAgentRevisionsParams(arg))
+	if len(revs) == 0 {
+		return queries.AgentRevision{}, pgx.ErrNoRows
+	}
+	return revs[0], nil
+}
+
+func (m *mockQueries) ListAgentRevisions(ctx context.Context, arg queries.ListAgentRevisionsParams) ([]queries.AgentRevision, error) {
+	revs := []queries.AgentRevision{}
+	for i := len(m.agentRevisions) - 1; i >= 0; i-- {
+		rev := m.agentRevisions[i]
+		if rev.TenantID == arg.TenantID && rev.AgentID == arg.AgentID {
+			revs = append(revs, rev)
+		}
+	}
+	return revs, nil
+}
+
+func (m *mockQueries) CreateWorkflow(ctx context.Context, arg queries.CreateWorkflowParams) (queries.Workflow, error) {
+	workflow := &queries.Workflow{
+		ID:                        newID(500 + len(m.workflows)),
+		TenantID:                  arg.TenantID,
+		Name:                      arg.Name,
+		Version:                   arg.Version,
+		ConfigYaml:                arg.ConfigYaml,
+		PlannerType:               arg.PlannerType,
+		TemplateVersionConstraint: arg.TemplateVersionConstraint,
+	}
+	m.workflows[workflow.ID] = workflow
+	return *workflow, nil
+}
+
+func (m *mockQueries) workflow(id, tenantID pgtype.UUID) (*queries.Workflow, error) {
+	workflow, ok := m.workflows[id]
+	if !ok || workflow.TenantID != tenantID {
+		return nil, pgx.ErrNoRows
+	}
+	return workflow, nil
+}
+
+func (m *mockQueries) GetWorkflowForUpdate(ctx context.Context, arg queries.GetWorkflowForUpdateParams) (queries.Workflow, error) {
+	workflow, err := m.workflow(arg.ID, arg.TenantID)
+	if err != nil {
+		return queries.Workflow{}, err
+	}
+	return *workflow, nil
+}
+
+func (m *mockQueries) UpdateWorkflow(ctx context.Context, arg queries.UpdateWorkflowParams) (queries.Workflow, error) {
+	workflow, err := m.workflow(arg.ID, arg.TenantID)
+	if err != nil || workflow.DeletedAt.Valid {
+		return queries.Workflow{}, pgx.ErrNoRows
+	}
+	workflow.Name, workflow.Version, workflow.ConfigYaml = arg.Name, arg.Version, arg.ConfigYaml
+	workflow.PlannerType, workflow.TemplateVersionConstraint = arg.PlannerType, arg.TemplateVersionConstraint
+	return *workflow, nil
+}
+
+func (m *mockQueries) DeleteWorkflow(ctx context.Context, arg queries.DeleteWorkflowParams) (queries.Workflow, error) {
+	workflow, err := m.workflow(arg.ID, arg.TenantID)
+	if err != nil || workflow.DeletedAt.Valid {
+		return queries.Workflow{}, pgx.ErrNoRows
+	}
+	workflow.DeletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return *workflow, nil
+}
+
+func (m *mockQueries) RestoreWorkflow(ctx context.Context, arg queries.RestoreWorkflowParams) (queries.Workflow, error) {
+	workflow, err := m.workflow(arg.ID, arg.TenantID)
+	if err != nil || !workflow.DeletedAt.Valid {
+		return queries.Workflow{}, pgx.ErrNoRows
+	}
+	workflow.DeletedAt = pgtype.Timestamptz{}
+	return *workflow, nil
+}
+
+func (m *mockQueries) CreateWorkflowRevision(ctx context.Context, arg queries.CreateWorkflowRevisionParams) (queries.WorkflowRevision, error) {
+	rev := queries.WorkflowRevision{
+		ID:                        newID(3000 + len(m.workflowRevisions)),
+		TenantID:                  arg.TenantID,
+		WorkflowID:                arg.WorkflowID,
+		Revision:                  arg.Revision,
+		Operation:                 arg.Operation,
+		Name:                      arg.Name,
+		Version:                   arg.Version,
+		ConfigYaml:                arg.ConfigYaml,
+		PlannerType:               arg.PlannerType,
+		TemplateVersionConstraint: arg.TemplateVersionConstraint,
+		AuditID:                   arg.AuditID,
+	}
+	m.workflowRevisions = append(m.workflowRevisions, rev)
+	return rev, nil
+}
+
+func (m *mockQueries) GetWorkflowRevision(ctx context.Context, arg queries.GetWorkflowRevisionParams) (queries.WorkflowRevision, error) {
+	for _, rev := range m.workflowRevisions {
+		if rev.TenantID == arg.TenantID && rev.WorkflowID == arg.WorkflowID && rev.Revision == arg.Revision {
+			return rev, nil
+		}
+	}
+	return queries.WorkflowRevision{}, pgx.ErrNoRows
+}
+
+func (m *mockQueries) GetLatestWorkflowRevision(ctx context.Context, arg queries.GetLatestWorkflowRevisionParams) (queries.WorkflowRevision, error) {
+	revs, _ := m.ListWorkflowRevisions(ctx, queries.ListWorkflowRevisionsParams(arg))
+	if len(revs) == 0 {
+		return queries.WorkflowRevision{}, pgx.ErrNoRows
+	}
+	return revs[0], nil
+}
+
+func (m *mockQueries) ListWorkflowRevisions(ctx context.Context, arg queries.ListWorkflowRevisionsParams) ([]queries.WorkflowRevision, error) {
+	revs := []queries.WorkflowRevision{}
+	for i := len(m.workflowRevisions) - 1; i >= 0; i-- {
+		rev := m.workflowRevisions[i]
+		if rev.TenantID == arg.TenantID && rev.WorkflowID == arg.WorkflowID {
+			revs = append(revs, rev)
+		}
+	}
+	return revs, nil
+}
+
+func newTestService() (*Service, *mockDB, *mockQueries) {
+	db := &mockDB{}
+	mock := newMockQueries()
+	svc := NewService(db)
+	svc.newQuerier = func(queries.DBTX) Querier { return mock }
+	return svc, db, mock
+}
+
+var testActor = Actor{Type: "user", ID: "user-1"}
+
+func TestAgentLifecycle(t *testing.T) {
+	ctx := context.Background()
+	svc, db, mock := newTestService()
+	tenantID := newID(42)
+
+	agent, err := svc.CreateAgent(ctx, testActor, queries.CreateAgentParams{
+		TenantID:     tenantID,
+		Name:         "planner",
+		Type:         "llm",
+		ConfigJson:   []byte(`{"model":"gpt-4","temperature":0.2}`),
+		PoliciesJson: []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	_, err = svc.UpdateAgent(ctx, testActor, queries.UpdateAgentParams{
+		ID:           agent.ID,
+		TenantID:     tenantID,
+		Name:         "planner",
+		Type:         "llm",
+		ConfigJson:   []byte(`{"model":"gpt-4o","temperature":0.2}`),
+		PoliciesJson: []byte(`{"max_cost":5}`),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.DeleteAgent(ctx, testActor, tenantID, agent.ID))
+	assert.True(t, mock.agents[agent.ID].DeletedAt.Valid)
+
+	_, err = svc.UpdateAgent(ctx, testActor, queries.UpdateAgentParams{ID: agent.ID, TenantID: tenantID})
+	assert.ErrorIs(t, err, ErrDeleted)
+
+	restored, err := svc.RestoreAgent(ctx, testActor, tenantID, agent.ID)
+	require.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+
+	_, err = svc.RestoreAgent(ctx, testActor, tenantID, agent.ID)
+	assert.ErrorIs(t, err, ErrNotDeleted)
+
+	reverted, err := svc.RevertAgent(ctx, testActor, tenantID, agent.ID, 1)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"model":"gpt-4","temperature":0.2}`, string(reverted.ConfigJson))
+
+	revisions, err := svc.ListAgentRevisions(ctx, tenantID, agent.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 5)
+
+	wantOps := []string{OperationRevert, OperationRestore, OperationDelete, OperationUpdate, OperationCreate}
+	for i, rev := range revisions {
+		assert.Equal(t, int32(5-i), rev.Revision)
+		assert.Equal(t, wantOps[i], rev.Operation)
+
+		// Every revision links to the audit record of the change
+		require.True(t, rev.AuditID.Valid)
+		var linked *queries.Audit
+		for j := range mock.audits {
+			if mock.audits[j].ID == rev.AuditID {
+				linked = &mock.audits[j]
+			}
+		}
+		require.NotNil(t, linked)
+		assert.Equal(t, wantOps[i], linked.Action)
+		assert.Equal(t, ResourceAgent, linked.ResourceType)
+		assert.Equal(t, testActor.ID, linked.ActorID)
+
+		var details map[string]interface{}
+		require.NoError(t, json.Unmarshal(linked.Details, &details))
+		assert.Equal(t, float64(rev.Revision), details["revision"])
+	}
+
+	// Successful changes commit; the rejected update and restore rolled back
+	committed := 0
+	for _, tx := range db.txs {
+		if tx.committed {
+			committed++
+		} else {
+			assert.True(t, tx.rolledBack)
+		}
+	}
+	assert.Equal(t, 5, committed)
+}
+
+func TestAgentNotFound(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService()
+
+	err := svc.DeleteAgent(ctx, testActor, newID(1), newID(99))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = svc.DiffAgentRevisions(ctx, newID(1), newID(99), 1, 2)
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+}
+
+func TestAgentOtherTenant(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService()
+
+	agent, err := svc.CreateAgent(ctx, testActor, queries.CreateAgentParams{TenantID: newID(1), Name: "a", Type: "llm"})
+	require.NoError(t, err)
+
+	err = svc.DeleteAgent(ctx, testActor, newID(2), agent.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestAgentRevisionFailureRollsBack(t *testing.T) {
+	ctx := context.Background()
+	svc, db, mock := newTestService()
+	mock.failRevision = true
+
+	_, err := svc.CreateAgent(ctx, testActor, queries.CreateAgentParams{TenantID: newID(1), Name: "a", Type: "llm"})
+	require.Error(t, err)
+	require.Len(t, db.txs, 1)
+	assert.False(t, db.txs[0].committed)
+	assert.True(t, db.txs[0].rolledBack)
+}
+
+func TestRevertAgentUnknownRevision(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService()
+
+	agent, err := svc.CreateAgent(ctx, testActor, queries.CreateAgentParams{TenantID: newID(1), Name: "a", Type: "llm"})
+	require.NoError(t, err)
+
+	_, err = svc.RevertAgent(ctx, testActor, newID(1), agent.ID, 7)
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+}
+
+func TestDiffAgentRevisions(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService()
+	tenantID := newID(1)
+
+	agent, err := svc.CreateAgent(ctx, testActor, queries.CreateAgentParams{
+		TenantID:     tenantID,
+		Name:         "a",
+		Type:         "llm",
+		ConfigJson:   []byte(`{"model":"gpt-4","tools":["search"]}`),
+		PoliciesJson: []byte(`{}`),
+	})
+	require.NoError(t, err)
+	_, err = svc.UpdateAgent(ctx, testActor, queries.UpdateAgentParams{
+		ID:           agent.ID,
+		TenantID:     tenantID,
+		Name:         "a",
+		Type:         "llm",
+		Role:         pgtype.Text{String: "planner", Valid: true},
+		ConfigJson:   []byte(`{"model":"gpt-4o","tools":["search","browse"]}`),
+		PoliciesJson: []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	diff, err := svc.DiffAgentRevisions(ctx, tenantID, agent.ID, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []FieldChange{{Field: "role", From: "", To: "planner"}}, diff.Fields)
+	assert.Equal(t, []JSONChange{
+		{Path: "/model", Kind: ChangeChanged, From: json.RawMessage(`"gpt-4"`), To: json.RawMessage(`"gpt-4o"`)},
+		{Path: "/tools/1", Kind: ChangeAdded, To: json.RawMessage(`"browse"`)},
+	}, diff.Config)
+	assert.Empty(t, diff.Policies)
+	assert.False(t, diff.Empty())
+}
+
+func TestWorkflowLifecycle(t *testing.T) {
+	ctx := context.Background()
+	svc, _, mock := newTestService()
+	tenantID := newID(7)
+
+	workflow, err := svc.CreateWorkflow(ctx, testActor, queries.CreateWorkflowParams{
+		TenantID:    tenantID,
+		Name:        "triage",
+		Version:     "1.0.0",
+		ConfigYaml:  "steps:\n  - classify\n",
+		PlannerType: "fsm",
+	})
+	require.NoError(t, err)
+
+	_, err = svc.UpdateWorkflow(ctx, testActor, queries.UpdateWorkflowParams{
+		ID:          workflow.ID,
+		TenantID:    tenantID,
+		Name:        "triage",
+		Version:     "1.0.1",
+		ConfigYaml:  "steps:\n  - classify\n  - route\n",
+		PlannerType: "fsm",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.DeleteWorkflow(ctx, testActor, tenantID, workflow.ID))
+	assert.ErrorIs(t, svc.DeleteWorkflow(ctx, testActor, tenantID, workflow.ID), ErrDeleted)
+
+	_, err = svc.RestoreWorkflow(ctx, testActor, tenantID, workflow.ID)
+	require.NoError(t, err)
+	assert.False(t, mock.workflows[workflow.ID].DeletedAt.Valid)
+
+	diff, err := svc.DiffWorkflowRevisions(ctx, tenantID, workflow.ID, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []FieldChange{{Field: "version", From: "1.0.0", To: "1.0.1"}}, diff.Fields)
+	assert.Equal(t, []LineChange{{Op: "+", Line: 3, Text: "  - route"}}, diff.Config)
+
+	revisions, err := svc.ListWorkflowRevisions(ctx, tenantID, workflow.ID)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 4)
+	for _, rev := range revisions {
+		assert.True(t, rev.AuditID.Valid)
+	}
+}
+
+func TestCreateAndRestoreRespectQuotas(t *testing.T) {
+	ctx := context.Background()
+	svc, db, mock := newTestService()
+	tenantID := newID(9)
+	mock.tenants[tenantID] = queries.Tenant{
+		ID:       tenantID,
+		Tier:     quota.TierFree,
+		Settings: []byte(`{"resource_limits": {"max_agents": 1, "max_workflows": 1}}`),
+	}
+	agentParams := queries.CreateAgentParams{TenantID: tenantID, Name: "a", Type: "llm", Role: pgtype.Text{String: "worker", Valid: true}, ConfigJson: []byte(`{}`), PoliciesJson: []byte(`{}`)}
+
+	agent, err := svc.CreateAgent(ctx, testActor, agentParams)
+	require.NoError(t, err)
+	agentParams.Name = "b"
+	_, err = svc.CreateAgent(ctx, testActor, agentParams)
+	var quotaErr *quota.QuotaError
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Equal(t, quota.ResourceAgents, quotaErr.Resource)
+	assert.Equal(t, 1, quotaErr.Limit)
+	assert.True(t, db.txs[len(db.txs)-1].rolledBack)
+
+	// A deleted agent frees its slot, and restoring it takes the slot again
+	require.NoError(t, svc.DeleteAgent(ctx, testActor, tenantID, agent.ID))
+	_, err = svc.CreateAgent(ctx, testActor, agentParams)
+	require.NoError(t, err)
+	_, err = svc.RestoreAgent(ctx, testActor, tenantID, agent.ID)
+	assert.ErrorIs(t, err, quota.ErrQuotaExceeded)
+
+	workflowParams := queries.CreateWorkflowParams{TenantID: tenantID, Name: "w", Version: "1.0.0", PlannerType: "fsm"}
+	_, err = svc.CreateWorkflow(ctx, testActor, workflowParams)
+	require.NoError(t, err)
+	workflowParams.Name = "w2"
+	_, err = svc.CreateWorkflow(ctx, testActor, workflowParams)
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Equal(t, quota.ResourceWorkflows, quotaErr.Resource)
+}
@@ -0,0 +1,195 @@
+package revision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CreateWorkflow creates a workflow and records revision 1. It returns a
+// *quota.QuotaError if the tenant has as many workflows as its limits allow.
+func (s *Service) CreateWorkflow(ctx context.Context, actor Actor, arg queries.CreateWorkflowParams) (*queries.Workflow, error) {
+	var workflow queries.Workflow
+	err := s.withTx(ctx, func(q Querier) error {
+		if err := checkQuota(ctx, q, arg.TenantID, ResourceWorkflow); err != nil {
+			return err
+		}
+		var err error
+		workflow, err = q.CreateWorkflow(ctx, arg)
+		if err != nil {
+			return fmt.Errorf("failed to create workflow: %w", err)
+		}
+		return recordWorkflowRevision(ctx, q, actor, workflow, OperationCreate, 1, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// UpdateWorkflow overwrites a workflow and records the new configuration as a revision
+func (s *Service) UpdateWorkflow(ctx context.Context, actor Actor, arg queries.UpdateWorkflowParams) (*queries.Workflow, error) {
+	var workflow queries.Workflow
+	err := s.withTx(ctx, func(q Querier) error {
+		next, err := lockWorkflow(ctx, q, arg.TenantID, arg.ID, false)
+		if err != nil {
+			return err
+		}
+		workflow, err = q.UpdateWorkflow(ctx, arg)
+		if err != nil {
+			return fmt.Errorf("failed to update workflow: %w", err)
+		}
+		return recordWorkflowRevision(ctx, q, actor, workflow, OperationUpdate, next, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// DeleteWorkflow soft-deletes a workflow. The row and its history are kept and
+// the workflow can be brought back with RestoreWorkflow.
+func (s *Service) DeleteWorkflow(ctx context.Context, actor Actor, tenantID, workflowID pgtype.UUID) error {
+	return s.withTx(ctx, func(q Querier) error {
+		next, err := lockWorkflow(ctx, q, tenantID, workflowID, false)
+		if err != nil {
+			return err
+		}
+		workflow, err := q.DeleteWorkflow(ctx, queries.DeleteWorkflowParams{ID: workflowID, TenantID: tenantID})
+		if err != nil {
+			return fmt.Errorf("failed to delete workflow: %w", err)
+		}
+		return recordWorkflowRevision(ctx, q, actor, workflow, OperationDelete, next, nil)
+	})
+}
+
+// RestoreWorkflow undoes a soft delete. Restored workflows count against
+// the tenant's limits like new ones.
+func (s *Service) RestoreWorkflow(ctx context.Context, actor Actor, tenantID, workflowID pgtype.UUID) (*queries.Workflow, error) {
+	var workflow queries.Workflow
+	err := s.withTx(ctx, func(q Querier) error {
+		if err := checkQuota(ctx, q, tenantID, ResourceWorkflow); err != nil {
+			return err
+		}
+		next, err := lockWorkflow(ctx, q, tenantID, workflowID, true)
+		if err != nil {
+			return err
+		}
+		workflow, err = q.RestoreWorkflow(ctx, queries.RestoreWorkflowParams{ID: workflowID, TenantID: tenantID})
+		if err != nil {
+			return fmt.Errorf("failed to restore workflow: %w", err)
+		}
+		return recordWorkflowRevision(ctx, q, actor, workflow, OperationRestore, next, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// RevertWorkflow applies the configuration of an earlier revision as a new revision
+func (s *Service) RevertWorkflow(ctx context.Context, actor Actor, tenantID, workflowID pgtype.UUID, revision int32) (*queries.Workflow, error) {
+	var workflow queries.Workflow
+	err := s.withTx(ctx, func(q Querier) error {
+		next, err := lockWorkflow(ctx, q, tenantID, workflowID, false)
+		if err != nil {
+			return err
+		}
+		target, err := q.GetWorkflowRevision(ctx, queries.GetWorkflowRevisionParams{TenantID: tenantID, WorkflowID: workflowID, Revision: revision})
+		if err != nil {
+			return notFound(err, ErrRevisionNotFound)
+		}
+		workflow, err = q.UpdateWorkflow(ctx, queries.UpdateWorkflowParams{
+			ID:                        workflowID,
+			TenantID:                  tenantID,
+			Name:                      target.Name,
+			Version:                   target.Version,
+			ConfigYaml:                target.ConfigYaml,
+			PlannerType:               target.PlannerType,
+			TemplateVersionConstraint: target.TemplateVersionConstraint,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to revert workflow: %w", err)
+		}
+		return recordWorkflowRevision(ctx, q, actor, workflow, OperationRevert, next, map[string]interface{}{"reverted_to": revision})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// ListWorkflowRevisions returns the revisions of a workflow, newest first
+func (s *Service) ListWorkflowRevisions(ctx context.Context, tenantID, workflowID pgtype.UUID) ([]queries.WorkflowRevision, error) {
+	revisions, err := s.newQuerier(s.db).ListWorkflowRevisions(ctx, queries.ListWorkflowRevisionsParams{TenantID: tenantID, WorkflowID: workflowID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// DiffWorkflowRevisions compares two revisions of a workflow
+func (s *Service) DiffWorkflowRevisions(ctx context.Context, tenantID, workflowID pgtype.UUID, from, to int32) (*WorkflowDiff, error) {
+	q := s.newQuerier(s.db)
+	fromRev, err := q.GetWorkflowRevision(ctx, queries.GetWorkflowRevisionParams{TenantID: tenantID, WorkflowID: workflowID, Revision: from})
+	if err != nil {
+		return nil, fmt.Errorf("revision %d: %w", from, notFound(err, ErrRevisionNotFound))
+	}
+	toRev, err := q.GetWorkflowRevision(ctx, queries.GetWorkflowRevisionParams{TenantID: tenantID, WorkflowID: workflowID, Revision: to})
+	if err != nil {
+		return nil, fmt.Errorf("revision %d: %w", to, notFound(err, ErrRevisionNotFound))
+	}
+	return DiffWorkflows(fromRev, toRev), nil
+}
+
+// lockWorkflow locks the workflow row and returns the next revision number. The
+// lock serializes writers so revision numbers are assigned without gaps.
+func lockWorkflow(ctx context.Context, q Querier, tenantID, workflowID pgtype.UUID, wantDeleted bool) (int32, error) {
+	workflow, err := q.GetWorkflowForUpdate(ctx, queries.GetWorkflowForUpdateParams{ID: workflowID, TenantID: tenantID})
+	if err != nil {
+		return 0, notFound(err, ErrNotFound)
+	}
+	deleted := workflow.DeletedAt.Valid
+	if deleted && !wantDeleted {
+		return 0, ErrDeleted
+	}
+	if !deleted && wantDeleted {
+		return 0, ErrNotDeleted
+	}
+
+	latest, err := q.GetLatestWorkflowRevision(ctx, queries.GetLatestWorkflowRevisionParams{TenantID: tenantID, WorkflowID: workflowID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 1, nil
+		}
+		return 0, fmt.Errorf("failed to get latest workflow revision: %w", err)
+	}
+	return latest.Revision + 1, nil
+}
+
+func recordWorkflowRevision(ctx context.Context, q Querier, actor Actor, workflow queries.Workflow, operation string, revision int32, details map[string]interface{}) error {
+	auditID, err := recordAudit(ctx, q, workflow.TenantID, actor, operation, ResourceWorkflow, workflow.ID, revision, details)
+	if err != nil {
+		return err
+	}
+	_, err = q.CreateWorkflowRevision(ctx, queries.CreateWorkflowRevisionParams{
+		TenantID:                  workflow.TenantID,
+		WorkflowID:                workflow.ID,
+		Revision:                  revision,
+		Operation:                 operation,
+		Name:                      workflow.Name,
+		Version:                   workflow.Version,
+		ConfigYaml:                workflow.ConfigYaml,
+		PlannerType:               workflow.PlannerType,
+		TemplateVersionConstraint: workflow.TemplateVersionConstraint,
+		AuditID:                   auditID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record workflow revision: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sqlTokenKind classifies a lexical token of the PostgreSQL dialect
+type sqlTokenKind int
+
+const (
+	tokIdent sqlTokenKind = iota
+	tokQuotedIdent
+	tokString
+	tokNumber
+	tokParam
+	tokOperator
+	tokLParen
+	tokRParen
+	tokComma
+	tokDot
+	tokSemicolon
+	tokPunct
+)
+
+// sqlToken is a single lexical token with its byte range in the original query
+type sqlToken struct {
+	kind  sqlTokenKind
+	text  string // raw text as written in the query
+	value string // lowercased identifier, unescaped quoted identifier or string contents
+	start int
+	end   int
+}
+
+// isKeyword reports whether the token is the given unquoted keyword
+func (t sqlToken) isKeyword(keywords ...string) bool {
+	if t.kind != tokIdent {
+		return false
+	}
+	for _, kw := range keywords {
+		if t.value == kw {
+			return true
+		}
+	}
+	return false
+}
+
+// isName reports whether the token can be used as an identifier
+func (t sqlToken) isName() bool {
+	return t.kind == tokIdent || t.kind == tokQuotedIdent
+}
+
+// paramIndex returns the positional parameter number of a $n token
+func (t sqlToken) paramIndex() int {
+	if t.kind != tokParam {
+		return 0
+	}
+	n, err := strconv.Atoi(t.text[1:])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// sqlTokens is a lexed query with precomputed parenthesis matching
+type sqlTokens struct {
+	query  string
+	tokens []sqlToken
+	match  []int // index of the matching parenthesis, -1 for other tokens
+}
+
+// lexSQL splits a PostgreSQL query into tokens, dropping whitespace and comments.
+// String literals, dollar-quoted strings and quoted identifiers are kept intact
+// so keywords inside them are never mistaken for clauses.
+func lexSQL(query string) (*sqlTokens, error) {
+	var tokens []sqlToken
+	i := 0
+	n := len(query)
+
+	for i < n {
+		c := query[i]
+		start := i
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+			continue
+
+		case c == '-' && i+1 < n && query[i+1] == '-':
+			for i < n && query[i] != '\n' {
+				i++
+			}
+			continue
+
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			depth := 0
+			for i < n {
+				if i+1 < n && query[i] == '/' && query[i+1] == '*' {
+					depth++
+					i += 2
+					continue
+				}
+				if i+1 < n && query[i] == '*' && query[i+1] == '/' {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+					continue
+				}
+				i++
+			}
+			if depth != 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", start)
+			}
+			continue
+
+		case c == '\'':
+			end, value, err := scanQuoted(query, i, '\'', false)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{kind: tokString, text: query[start:end], value: value, start: start, end: end})
+			i = end
+			continue
+
+		case (c == 'e' || c == 'E') && i+1 < n && query[i+1] == '\'':
+			end, value, err := scanQuoted(query, i+1, '\'', true)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{kind: tokString, text: query[start:end], value: value, start: start, end: end})
+			i = end
+			continue
+
+		case (c == 'b' || c == 'B' || c == 'x' || c == 'X' || c == 'n' || c == 'N') && i+1 < n && query[i+1] == '\'':
+			end, value, err := scanQuoted(query, i+1, '\'', false)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{kind: tokString, text: query[start:end], value: value, start: start, end: end})
+			i = end
+			continue
+
+		case c == '"':
+			end, value, err := scanQuoted(query, i, '"', false)
+			if err != nil {
+				return nil, err
+			}
+			if value == "" {
+				return nil, fmt.Errorf("zero-length quoted identifier at offset %d", start)
+			}
+			tokens = append(tokens, sqlToken{kind: tokQuotedIdent, text: query[start:end], value: value, start: start, end: end})
+			i = end
+			continue
+
+		case c == '$':
+			if i+1 < n && isDigit(query[i+1]) {
+				i++
+				for i < n && isDigit(query[i]) {
+					i++
+				}
+				tokens = append(tokens, sqlToken{kind: tokParam, text: query[start:i], value: query[start:i], start: start, end: i})
+				continue
+			}
+			end, value, ok, err := scanDollarQuoted(query, i)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				tokens = append(tokens, sqlToken{kind: tokString, text: query[start:end], value: value, start: start, end: end})
+				i = end
+				continue
+			}
+			i++
+			tokens = append(tokens, sqlToken{kind: tokPunct, text: "$", value: "$", start: start, end: i})
+			continue
+
+		case isDigit(c) || (c == '.' && i+1 < n && isDigit(query[i+1])):
+			i = scanNumber(query, i)
+			tokens = append(tokens, sqlToken{kind: tokNumber, text: query[start:i], value: query[start:i], start: start, end: i})
+			continue
+
+		case isIdentStart(c):
+			for i < n && isIdentPart(query[i]) {
+				i++
+			}
+			text := query[start:i]
+			tokens = append(tokens, sqlToken{kind: tokIdent, text: text, value: strings.ToLower(text), start: start, end: i})
+			continue
+
+		case c == '(':
+			i++
+			tokens = append(tokens, sqlToken{kind: tokLParen, text: "(", value: "(", start: start, end: i})
+			continue
+
+		case c == ')':
+			i++
+			tokens = append(tokens, sqlToken{kind: tokRParen, text: ")", value: ")", start: start, end: i})
+			continue
+
+		case c == ',':
+			i++
+			tokens = append(tokens, sqlToken{kind: tokComma, text: ",", value: ",", start: start, end: i})
+			continue
+
+		case c == '.':
+			i++
+			tokens = append(tokens, sqlToken{kind: tokDot, text: ".", value: ".", start: start, end: i})
+			continue
+
+		case c == ';':
+			i++
+			tokens = append(tokens, sqlToken{kind: tokSemicolon, text: ";", value: ";", start: start, end: i})
+			continue
+
+		case c == ':':
+			i++
+			if i < n && query[i] == ':' {
+				i++
+				tokens = append(tokens, sqlToken{kind: tokOperator, text: "::", value: "::", start: start, end: i})
+				continue
+			}
+			tokens = append(tokens, sqlToken{kind: tokPunct, text: ":", value: ":", start: start, end: i})
+			continue
+
+		case isOperatorChar(c):
+			for i < n && isOperatorChar(query[i]) {
+				// Comments terminate an operator
+				if i+1 < n && ((query[i] == '-' && query[i+1] == '-') || (query[i] == '/' && query[i+1] == '*')) && i > start {
+					break
+				}
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokOperator, text: query[start:i], value: query[start:i], start: start, end: i})
+			continue
+
+		default:
+			i++
+			tokens = append(tokens, sqlToken{kind: tokPunct, text: query[start:i], value: query[start:i], start: start, end: i})
+			continue
+		}
+	}
+
+	match := make([]int, len(tokens))
+	var stack []int
+	for idx, tok := range tokens {
+		match[idx] = -1
+		switch tok.kind {
+		case tokLParen:
+			stack = append(stack, idx)
+		case tokRParen:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unbalanced parenthesis at offset %d", tok.start)
+			}
+			open := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			match[open] = idx
+			match[idx] = open
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unbalanced parenthesis at offset %d", tokens[stack[len(stack)-1]].start)
+	}
+
+	return &sqlTokens{query: query, tokens: tokens, match: match}, nil
+}
+
+// scanQuoted scans a quote-delimited literal starting at the opening quote and
+// returns the offset just past the closing quote together with the unescaped contents
+func scanQuoted(query string, open int, quote byte, backslashEscapes bool) (int, string, error) {
+	var sb strings.Builder
+	i := open + 1
+	for i < len(query) {
+		c := query[i]
+		if backslashEscapes && c == '\\' && i+1 < len(query) {
+			sb.WriteByte(query[i+1])
+			i += 2
+			continue
+		}
+		if c == quote {
+			if i+1 < len(query) && query[i+1] == quote {
+				sb.WriteByte(quote)
+				i += 2
+				continue
+			}
+			return i + 1, sb.String(), nil
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	return 0, "", fmt.Errorf("unterminated quoted literal at offset %d", open)
+}
+
+// scanDollarQuoted scans a $tag$...$tag$ string. ok is false when the '$' at
+// start does not open a dollar-quoted string.
+func scanDollarQuoted(query string, start int) (end int, value string, ok bool, err error) {
+	i := start + 1
+	for i < len(query) && query[i] != '$' {
+		if !isIdentPart(query[i]) || query[i] == '$' {
+			return 0, "", false, nil
+		}
+		i++
+	}
+	if i >= len(query) {
+		return 0, "", false, nil
+	}
+	if i > start+1 && isDigit(query[start+1]) {
+		return 0, "", false, nil
+	}
+
+	delimiter := query[start : i+1]
+	bodyStart := i + 1
+	closeIdx := strings.Index(query[bodyStart:], delimiter)
+	if closeIdx == -1 {
+		return 0, "", false, fmt.Errorf("unterminated dollar-quoted string at offset %d", start)
+	}
+	return bodyStart + closeIdx + len(delimiter), query[bodyStart : bodyStart+closeIdx], true, nil
+}
+
+// scanNumber scans a numeric literal including fraction and exponent
+func scanNumber(query string, i int) int {
+	n := len(query)
+	for i < n && isDigit(query[i]) {
+		i++
+	}
+	if i < n && query[i] == '.' && !(i+1 < n && query[i+1] == '.') {
+		i++
+		for i < n && isDigit(query[i]) {
+			i++
+		}
+	}
+	if i < n && (query[i] == 'e' || query[i] == 'E') {
+		j := i + 1
+		if j < n && (query[j] == '+' || query[j] == '-') {
+			j++
+		}
+		if j < n && isDigit(query[j]) {
+			i = j
+			for i < n && isDigit(query[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '$'
+}
+
+func isOperatorChar(c byte) bool {
+	return strings.IndexByte("+-*/<>=~!@#%^&|`?", c) >= 0
+}
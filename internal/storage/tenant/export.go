@@ -0,0 +1,232 @@
+package tenant
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ExportFormat identifies the archive layout
+const ExportFormat = "agentflow.tenant-export/v1"
+
+// exportPageSize bounds how many messages are held in memory at once
+const exportPageSize = 1000
+
+// ExportManifest summarizes an export archive
+type ExportManifest struct {
+	Format     string         `json:"format"`
+	TenantID   string         `json:"tenant_id"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Counts     map[string]int `json:"counts"`
+	// AuditHead is the hash of the newest audit record; the audits in the
+	// archive can be re-verified against it
+	AuditHead string `json:"audit_head,omitempty"`
+}
+
+// exportTenant and friends replace the raw JSONB columns so they are written
+// as JSON instead of base64
+type exportTenant struct {
+	queries.Tenant
+	Settings json.RawMessage `json:"settings"`
+}
+
+type exportAgent struct {
+	queries.Agent
+	ConfigJson   json.RawMessage `json:"config_json"`
+	PoliciesJson json.RawMessage `json:"policies_json"`
+}
+
+type exportMessage struct {
+	queries.Message
+	Payload  json.RawMessage `json:"payload"`
+	Metadata json.RawMessage `json:"metadata"`
+	Cost     json.RawMessage `json:"cost"`
+}
+
+type exportAudit struct {
+	queries.Audit
+	Details  json.RawMessage `json:"details"`
+	PrevHash string          `json:"prev_hash"`
+	Hash     string          `json:"hash"`
+}
+
+// Export writes every agent, workflow, message and audit record of a tenant
+// to w as a single JSON document. Deleted agents and workflows are included.
+// All reads run in one repeatable-read transaction so the archive is a
+// consistent snapshot.
+func (s *Service) Export(ctx context.Context, tenantID pgtype.UUID, w io.Writer) (*ExportManifest, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	q := s.newQuerier(tx)
+
+	t, err := q.GetTenant(ctx, tenantID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if t.Status == StatusErased {
+		return nil, ErrErased
+	}
+
+	manifest := &ExportManifest{
+		Format:     ExportFormat,
+		TenantID:   uuidString(tenantID),
+		ExportedAt: s.now().UTC(),
+		Counts:     make(map[string]int),
+	}
+
+	aw := &archiveWriter{w: w}
+	aw.begin()
+	aw.field("format", manifest.Format)
+	aw.field("exported_at", manifest.ExportedAt)
+	aw.field("tenant", exportTenant{Tenant: t, Settings: rawJSON(t.Settings)})
+
+	agents, err := q.ListAgentsForExport(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export agents: %w", err)
+	}
+	aw.beginArray("agents")
+	for _, a := range agents {
+		aw.item(exportAgent{Agent: a, ConfigJson: rawJSON(a.ConfigJson), PoliciesJson: rawJSON(a.PoliciesJson)})
+	}
+	aw.endArray()
+	manifest.Counts["agents"] = len(agents)
+
+	workflows, err := q.ListWorkflowsForExport(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export workflows: %w", err)
+	}
+	aw.field("workflows", workflows)
+	manifest.Counts["workflows"] = len(workflows)
+
+	aw.beginArray("messages")
+	for offset := int32(0); ; offset += exportPageSize {
+		page, err := q.ListMessagesForExport(ctx, queries.ListMessagesForExportParams{
+			TenantID: tenantID,
+			Limit:    exportPageSize,
+			Offset:   offset,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to export messages: %w", err)
+		}
+		for _, m := range page {
+			aw.item(exportMessage{Message: m, Payload: rawJSON(m.Payload), Metadata: rawJSON(m.Metadata), Cost: rawJSON(m.Cost)})
+		}
+		manifest.Counts["messages"] += len(page)
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+	aw.endArray()
+
+	audits, err := q.GetAuditChain(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export audits: %w", err)
+	}
+	aw.beginArray("audits")
+	for _, a := range audits {
+		aw.item(exportAudit{
+			Audit:    a,
+			Details:  rawJSON(a.Details),
+			PrevHash: hex.EncodeToString(a.PrevHash),
+			Hash:     hex.EncodeToString(a.Hash),
+		})
+	}
+	aw.endArray()
+	manifest.Counts["audits"] = len(audits)
+	if len(audits) > 0 {
+		manifest.AuditHead = hex.EncodeToString(audits[len(audits)-1].Hash)
+	}
+
+	aw.field("manifest", manifest)
+	aw.end()
+	if aw.err != nil {
+		return nil, fmt.Errorf("failed to write export: %w", aw.err)
+	}
+	return manifest, nil
+}
+
+// archiveWriter streams a JSON object whose arrays may be too large to
+// marshal in one go. The first write error sticks and later calls are no-ops.
+type archiveWriter struct {
+	w      io.Writer
+	err    error
+	fields int
+	items  int
+}
+
+func (a *archiveWriter) write(s string) {
+	if a.err == nil {
+		_, a.err = io.WriteString(a.w, s)
+	}
+}
+
+func (a *archiveWriter) value(v interface{}) {
+	if a.err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		a.err = err
+		return
+	}
+	_, a.err = a.w.Write(data)
+}
+
+func (a *archiveWriter) key(name string) {
+	if a.fields > 0 {
+		a.write(",")
+	}
+	a.fields++
+	a.write("\n")
+	a.value(name)
+	a.write(":")
+}
+
+func (a *archiveWriter) begin() { a.write("{") }
+
+func (a *archiveWriter) end() { a.write("\n}\n") }
+
+func (a *archiveWriter) field(name string, v interface{}) {
+	a.key(name)
+	a.value(v)
+}
+
+func (a *archiveWriter) beginArray(name string) {
+	a.key(name)
+	a.write("[")
+	a.items = 0
+}
+
+func (a *archiveWriter) item(v interface{}) {
+	if a.items > 0 {
+		a.write(",")
+	}
+	a.items++
+	a.write("\n")
+	a.value(v)
+}
+
+func (a *archiveWriter) endArray() {
+	if a.items > 0 {
+		a.write("\n")
+	}
+	a.write("]")
+}
+
+// rawJSON returns nil for empty columns, which marshals as null
+func rawJSON(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,69 @@
+//go:build integration
+// +build integration
+
+package tenant
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/agentflow/agentflow/internal/storage/revision"
+)
+
+func TestIntegration(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Skip("Database not available")
+	}
+	defer db.Close()
+
+	q := queries.New(db)
+	created, err := q.CreateTenant(ctx, queries.CreateTenantParams{Name: "lifecycle-" + uuid.NewString(), Tier: "free", Settings: []byte(`{}`)})
+	require.NoError(t, err)
+	defer func() { _ = q.DeleteTenant(ctx, created.ID) }()
+	assert.Equal(t, StatusActive, created.Status)
+
+	// Agents carry append-only revisions that must still go with the tenant data
+	_, err = revision.NewService(db).CreateAgent(ctx, revision.Actor{Type: "user", ID: "u1"}, queries.CreateAgentParams{
+		TenantID: created.ID, Name: "planner", Type: "llm", ConfigJson: []byte(`{}`), PoliciesJson: []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	svc := NewService(db)
+	_, err = svc.Suspend(ctx, testActor, created.ID, "integration")
+	require.NoError(t, err)
+	_, err = svc.ScheduleDeletion(ctx, testActor, created.ID, "", 0)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	manifest, err := svc.Export(ctx, created.ID, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, manifest.Counts["agents"])
+
+	report, err := svc.Erase(ctx, testActor, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Deleted["agents"])
+
+	erased, err := svc.Get(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusErased, erased.Status)
+
+	result, err := audit.NewService(q).VerifyChainIntegrity(ctx, created.ID)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.ErrorMessage)
+}
@@ -0,0 +1,279 @@
+// Package tenant manages the tenant lifecycle: suspension, scheduled deletion
+// with a grace period, full data export and erasure. Every transition is
+// recorded in the tenant's audit hash chain, and erasure ends the chain with
+// a tombstone record instead of removing it.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/audit"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Lifecycle states stored in tenants.status
+const (
+	StatusActive          = "active"
+	StatusSuspended       = "suspended"
+	StatusPendingDeletion = "pending_deletion"
+	StatusErased          = "erased"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionSuspend        = "suspend"
+	ActionResume         = "resume"
+	ActionScheduleDelete = "schedule_deletion"
+	ActionCancelDelete   = "cancel_deletion"
+	ActionErase          = "erase"
+)
+
+// ResourceTenant is the audit resource type for lifecycle records
+const ResourceTenant = "tenant"
+
+// DefaultGracePeriod is how long a tenant stays pending deletion before it is erased
+const DefaultGracePeriod = 30 * 24 * time.Hour
+
+var (
+	// ErrNotFound is returned when the tenant does not exist
+	ErrNotFound = errors.New("tenant not found")
+	// ErrSuspended is returned for writes to a suspended tenant
+	ErrSuspended = errors.New("tenant is suspended")
+	// ErrPendingDeletion is returned for writes to a tenant scheduled for deletion
+	ErrPendingDeletion = errors.New("tenant is pending deletion")
+	// ErrErased is returned for any access to an erased tenant
+	ErrErased = errors.New("tenant has been erased")
+	// ErrInvalidTransition is returned when the tenant is not in a state the operation applies to
+	ErrInvalidTransition = errors.New("invalid tenant state transition")
+	// ErrGracePeriod is returned when erasing a tenant before its grace period ends
+	ErrGracePeriod = errors.New("tenant deletion grace period has not ended")
+)
+
+// CheckAccess reports whether a request with the given HTTP method may
+// proceed for a tenant in status. Suspended and pending-deletion tenants are
+// read-only so their data can still be exported; erased tenants are closed.
+func CheckAccess(status, method string) error {
+	switch status {
+	case StatusActive, "":
+		return nil
+	case StatusErased:
+		return ErrErased
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+	if status == StatusPendingDeletion {
+		return ErrPendingDeletion
+	}
+	return ErrSuspended
+}
+
+// Actor identifies who changed the tenant
+type Actor struct {
+	Type string // "user", "system"
+	ID   string
+}
+
+// Querier defines the queries used by the lifecycle service
+type Querier interface {
+	audit.AuditQuerier
+
+	GetTenant(ctx context.Context, id pgtype.UUID) (queries.Tenant, error)
+	GetTenantForUpdate(ctx context.Context, id pgtype.UUID) (queries.Tenant, error)
+	UpdateTenantStatus(ctx context.Context, arg queries.UpdateTenantStatusParams) (queries.Tenant, error)
+	ListTenantsDueForErasure(ctx context.Context, deletionScheduledAt pgtype.Timestamptz) ([]queries.Tenant, error)
+	MarkTenantErased(ctx context.Context, id pgtype.UUID) (queries.Tenant, error)
+
+	EraseTenantMessages(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantAgents(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantWorkflows(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantTools(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantBudgets(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantRoles(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantUsers(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantRefreshTokens(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantPolicies(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+	EraseTenantServiceAccounts(ctx context.Context, tenantID pgtype.UUID) (int64, error)
+
+	ListAgentsForExport(ctx context.Context, tenantID pgtype.UUID) ([]queries.Agent, error)
+	ListWorkflowsForExport(ctx context.Context, tenantID pgtype.UUID) ([]queries.Workflow, error)
+	ListMessagesForExport(ctx context.Context, arg queries.ListMessagesForExportParams) ([]queries.Message, error)
+}
+
+// DB is a database handle that can run queries and start transactions.
+// *pgxpool.Pool and *pgx.Conn satisfy it.
+type DB interface {
+	queries.DBTX
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// Service drives tenant lifecycle transitions
+type Service struct {
+	db         DB
+	newQuerier func(db queries.DBTX) Querier
+	now        func() time.Time
+}
+
+// NewService creates a new tenant lifecycle service
+func NewService(db DB) *Service {
+	return &Service{
+		db: db,
+		newQuerier: func(db queries.DBTX) Querier {
+			return queries.New(db)
+		},
+		now: time.Now,
+	}
+}
+
+// Get returns a tenant in any state
+func (s *Service) Get(ctx context.Context, tenantID pgtype.UUID) (*queries.Tenant, error) {
+	t, err := s.newQuerier(s.db).GetTenant(ctx, tenantID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &t, nil
+}
+
+// Suspend makes an active tenant read-only
+func (s *Service) Suspend(ctx context.Context, actor Actor, tenantID pgtype.UUID, reason string) (*queries.Tenant, error) {
+	return s.transition(ctx, actor, tenantID, ActionSuspend, []string{StatusActive}, queries.UpdateTenantStatusParams{
+		Status:       StatusSuspended,
+		StatusReason: text(reason),
+	})
+}
+
+// Resume reactivates a suspended tenant
+func (s *Service) Resume(ctx context.Context, actor Actor, tenantID pgtype.UUID) (*queries.Tenant, error) {
+	return s.transition(ctx, actor, tenantID, ActionResume, []string{StatusSuspended}, queries.UpdateTenantStatusParams{
+		Status: StatusActive,
+	})
+}
+
+// ScheduleDeletion marks a tenant for erasure once grace has passed. The
+// tenant is read-only in the meantime and the deletion can be cancelled.
+func (s *Service) ScheduleDeletion(ctx context.Context, actor Actor, tenantID pgtype.UUID, reason string, grace time.Duration) (*queries.Tenant, error) {
+	if grace < 0 {
+		return nil, fmt.Errorf("grace period must not be negative: %s", grace)
+	}
+	return s.transition(ctx, actor, tenantID, ActionScheduleDelete, []string{StatusActive, StatusSuspended}, queries.UpdateTenantStatusParams{
+		Status:              StatusPendingDeletion,
+		StatusReason:        text(reason),
+		DeletionScheduledAt: pgtype.Timestamptz{Time: s.now().Add(grace).UTC(), Valid: true},
+	})
+}
+
+// CancelDeletion returns a tenant pending deletion to active
+func (s *Service) CancelDeletion(ctx context.Context, actor Actor, tenantID pgtype.UUID) (*queries.Tenant, error) {
+	return s.transition(ctx, actor, tenantID, ActionCancelDelete, []string{StatusPendingDeletion}, queries.UpdateTenantStatusParams{
+		Status: StatusActive,
+	})
+}
+
+// transition moves a tenant between states under a row lock and records the
+// change in the audit log in the same transaction
+func (s *Service) transition(ctx context.Context, actor Actor, tenantID pgtype.UUID, action string, from []string, arg queries.UpdateTenantStatusParams) (*queries.Tenant, error) {
+	var updated queries.Tenant
+	err := s.withTx(ctx, func(q Querier) error {
+		current, err := q.GetTenantForUpdate(ctx, tenantID)
+		if err != nil {
+			return notFound(err)
+		}
+		if !contains(from, current.Status) {
+			return fmt.Errorf("%w: cannot %s a tenant that is %s", ErrInvalidTransition, action, current.Status)
+		}
+
+		arg.ID = tenantID
+		updated, err = q.UpdateTenantStatus(ctx, arg)
+		if err != nil {
+			return fmt.Errorf("failed to update tenant status: %w", err)
+		}
+
+		details := map[string]interface{}{
+			"from": current.Status,
+			"to":   updated.Status,
+		}
+		if arg.StatusReason.Valid {
+			details["reason"] = arg.StatusReason.String
+		}
+		if arg.DeletionScheduledAt.Valid {
+			details["deletion_scheduled_at"] = arg.DeletionScheduledAt.Time.Format(time.RFC3339)
+		}
+		_, err = recordAudit(ctx, q, tenantID, actor, action, details)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// withTx runs fn in a transaction
+func (s *Service) withTx(ctx context.Context, fn func(q Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := fn(s.newQuerier(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func recordAudit(ctx context.Context, q Querier, tenantID pgtype.UUID, actor Actor, action string, details map[string]interface{}) (*queries.Audit, error) {
+	id := uuidString(tenantID)
+	record, err := audit.NewService(q).CreateAudit(ctx, audit.CreateAuditParams{
+		TenantID:     tenantID,
+		ActorType:    actor.Type,
+		ActorID:      actor.ID,
+		Action:       action,
+		ResourceType: ResourceTenant,
+		ResourceID:   &id,
+		Details:      details,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record tenant audit: %w", err)
+	}
+	return record, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func text(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
+
+func uuidString(id pgtype.UUID) string {
+	if !id.Valid {
+		return ""
+	}
+	b := id.Bytes
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,485 @@
+package tenant
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testActor = Actor{Type: "user", ID: "admin-1"}
+
+// mockTx records whether the service committed or rolled back
+type mockTx struct {
+	pgx.Tx
+	opts       pgx.TxOptions
+	committed  bool
+	rolledBack bool
+}
+
+func (t *mockTx) Commit(ctx context.Context) error {
+	t.committed = true
+	return nil
+}
+
+func (t *mockTx) Rollback(ctx context.Context) error {
+	if !t.committed {
+		t.rolledBack = true
+	}
+	return nil
+}
+
+// mockDB hands out mockTx transactions
+type mockDB struct {
+	txs []*mockTx
+}
+
+func (d *mockDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (d *mockDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (d *mockDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return nil
+}
+
+func (d *mockDB) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	tx := &mockTx{opts: opts}
+	d.txs = append(d.txs, tx)
+	return tx, nil
+}
+
+// mockQueries is an in-memory implementation of Querier
+type mockQueries struct {
+	tenants   map[pgtype.UUID]*queries.Tenant
+	agents    []queries.Agent
+	workflows []queries.Workflow
+	messages  []queries.Message
+	rows      map[string]int64
+	audits    []queries.Audit
+	failErase bool
+}
+
+func newMockQueries() *mockQueries {
+	return &mockQueries{
+		tenants: make(map[pgtype.UUID]*queries.Tenant),
+		rows:    make(map[string]int64),
+	}
+}
+
+func newID(n int) pgtype.UUID {
+	var b [16]byte
+	b[0] = byte(n >> 8)
+	b[1] = byte(n)
+	b[15] = 0x7e
+	return pgtype.UUID{Bytes: b, Valid: true}
+}
+
+func (m *mockQueries) addTenant(n int, status string) pgtype.UUID {
+	id := newID(n)
+	m.tenants[id] = &queries.Tenant{ID: id, Name: "acme", Tier: "pro", Settings: []byte(`{"region":"eu"}`), Status: status}
+	return id
+}
+
+func (m *mockQueries) CreateAudit(ctx context.Context, arg queries.CreateAuditParams) (queries.Audit, error) {
+	audit := queries.Audit{
+		ID:           newID(1000 + len(m.audits)),
+		TenantID:     arg.TenantID,
+		ActorType:    arg.ActorType,
+		ActorID:      arg.ActorID,
+		Action:       arg.Action,
+		ResourceType: arg.ResourceType,
+		ResourceID:   arg.ResourceID,
+		Details:      arg.Details,
+		Ts:           arg.Ts,
+		PrevHash:     arg.PrevHash,
+		Hash:         arg.Hash,
+		Seq:          arg.Seq,
+	}
+	m.audits = append(m.audits, audit)
+	return audit, nil
+}
+
+func (m *mockQueries) GetLatestAudit(ctx context.Context, tenantID pgtype.UUID) (queries.Audit, error) {
+	if len(m.audits) == 0 {
+		return queries.Audit{}, pgx.ErrNoRows
+	}
+	return m.audits[len(m.audits)-1], nil
+}
+
+func (m *mockQueries) LockAuditChain(ctx context.Context, tenantID pgtype.UUID) error {
+	return nil
+}
+
+func (m *mockQueries) GetAuditChain(ctx context.Context, tenantID pgtype.UUID) ([]queries.Audit, error) {
+	return m.audits, nil
+}
+
+func (m *mockQueries) GetTenant(ctx context.Context, id pgtype.UUID) (queries.Tenant, error) {
+	t, ok := m.tenants[id]
+	if !ok {
+		return queries.Tenant{}, pgx.ErrNoRows
+	}
+	return *t, nil
+}
+
+func (m *mockQueries) GetTenantForUpdate(ctx context.Context, id pgtype.UUID) (queries.Tenant, error) {
+	return m.GetTenant(ctx, id)
+}
+
+func (m *mockQueries) UpdateTenantStatus(ctx context.Context, arg queries.UpdateTenantStatusParams) (queries.Tenant, error) {
+	t, ok := m.tenants[arg.ID]
+	if !ok {
+		return queries.Tenant{}, pgx.ErrNoRows
+	}
+	t.Status = arg.Status
+	t.StatusReason = arg.StatusReason
+	t.DeletionScheduledAt = arg.DeletionScheduledAt
+	return *t, nil
+}
+
+func (m *mockQueries) ListTenantsDueForErasure(ctx context.Context, due pgtype.Timestamptz) ([]queries.Tenant, error) {
+	var out []queries.Tenant
+	for _, t := range m.tenants {
+		if t.Status == StatusPendingDeletion && !t.DeletionScheduledAt.Time.After(due.Time) {
+			out = append(out, *t)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockQueries) MarkTenantErased(ctx context.Context, id pgtype.UUID) (queries.Tenant, error) {
+	t := m.tenants[id]
+	t.Status = StatusErased
+	t.Name = "erased-" + uuidString(id)
+	t.Settings = []byte(`{}`)
+	t.DeletionScheduledAt = pgtype.Timestamptz{}
+	t.ErasedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return *t, nil
+}
+
+func (m *mockQueries) erase(table string) (int64, error) {
+	if m.failErase && table == "users" {
+		return 0, errors.New("boom")
+	}
+	n := m.rows[table]
+	m.rows[table] = 0
+	return n, nil
+}
+
+func (m *mockQueries) EraseTenantMessages(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return m.erase("messages")
+}
+
+func (m *mockQueries) EraseTenantAgents(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return m.erase("agents")
+}
+
+func (m *mockQueries) EraseTenantWorkflows(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return m.erase("workflows")
+}
+
+func (m *mockQueries) EraseTenantTools(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return m.erase("tools")
+}
+
+func (m *mockQueries) EraseTenantBudgets(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return m.erase("budgets")
+}
+
+func (m *mockQueries) EraseTenantRoles(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return m.erase("rbac_roles")
+}
+
+func (m *mockQueries) EraseTenantUsers(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return m.erase("users")
+}
+
+func (m *mockQueries) EraseTenantRefreshTokens(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return m.erase("refresh_tokens")
+}
+
+func (m *mockQueries) EraseTenantPolicies(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return m.erase("policies")
+}
+
+func (m *mockQueries) EraseTenantServiceAccounts(ctx context.Context, tenantID pgtype.UUID) (int64, error) {
+	return m.erase("service_accounts")
+}
+
+func (m *mockQueries) ListAgentsForExport(ctx context.Context, tenantID pgtype.UUID) ([]queries.Agent, error) {
+	return m.agents, nil
+}
+
+func (m *mockQueries) ListWorkflowsForExport(ctx context.Context, tenantID pgtype.UUID) ([]queries.Workflow, error) {
+	return m.workflows, nil
+}
+
+func (m *mockQueries) ListMessagesForExport(ctx context.Context, arg queries.ListMessagesForExportParams) ([]queries.Message, error) {
+	start := int(arg.Offset)
+	if start > len(m.messages) {
+		start = len(m.messages)
+	}
+	end := start + int(arg.Limit)
+	if end > len(m.messages) {
+		end = len(m.messages)
+	}
+	return m.messages[start:end], nil
+}
+
+// clock is a settable time source
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newTestService(q *mockQueries) (*Service, *mockDB, *clock) {
+	db := &mockDB{}
+	c := &clock{t: time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)}
+	return &Service{
+		db:         db,
+		newQuerier: func(queries.DBTX) Querier { return q },
+		now:        c.now,
+	}, db, c
+}
+
+func TestCheckAccess(t *testing.T) {
+	tests := []struct {
+		status string
+		method string
+		want   error
+	}{
+		{StatusActive, http.MethodPost, nil},
+		{"", http.MethodDelete, nil},
+		{StatusSuspended, http.MethodGet, nil},
+		{StatusSuspended, http.MethodPost, ErrSuspended},
+		{StatusPendingDeletion, http.MethodHead, nil},
+		{StatusPendingDeletion, http.MethodPut, ErrPendingDeletion},
+		{StatusErased, http.MethodGet, ErrErased},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status+" "+tt.method, func(t *testing.T) {
+			assert.Equal(t, tt.want, CheckAccess(tt.status, tt.method))
+		})
+	}
+}
+
+func TestLifecycleTransitions(t *testing.T) {
+	ctx := context.Background()
+	q := newMockQueries()
+	id := q.addTenant(1, StatusActive)
+	svc, db, c := newTestService(q)
+
+	tenant, err := svc.Suspend(ctx, testActor, id, "unpaid invoice")
+	require.NoError(t, err)
+	assert.Equal(t, StatusSuspended, tenant.Status)
+	assert.Equal(t, "unpaid invoice", tenant.StatusReason.String)
+
+	_, err = svc.Suspend(ctx, testActor, id, "again")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	assert.True(t, db.txs[len(db.txs)-1].rolledBack)
+
+	tenant, err = svc.Resume(ctx, testActor, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusActive, tenant.Status)
+	assert.False(t, tenant.StatusReason.Valid)
+
+	tenant, err = svc.ScheduleDeletion(ctx, testActor, id, "customer request", 7*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPendingDeletion, tenant.Status)
+	assert.Equal(t, c.t.Add(7*24*time.Hour), tenant.DeletionScheduledAt.Time)
+
+	_, err = svc.Resume(ctx, testActor, id)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	tenant, err = svc.CancelDeletion(ctx, testActor, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusActive, tenant.Status)
+	assert.False(t, tenant.DeletionScheduledAt.Valid)
+
+	// Each successful transition is audited and chained to the previous record
+	require.Len(t, q.audits, 4)
+	actions := make([]string, len(q.audits))
+	for i, a := range q.audits {
+		actions[i] = a.Action
+		assert.Equal(t, ResourceTenant, a.ResourceType)
+		if i > 0 {
+			assert.Equal(t, q.audits[i-1].Hash, a.PrevHash)
+		}
+	}
+	assert.Equal(t, []string{ActionSuspend, ActionResume, ActionScheduleDelete, ActionCancelDelete}, actions)
+
+	var details map[string]interface{}
+	require.NoError(t, json.Unmarshal(q.audits[2].Details, &details))
+	assert.Equal(t, "active", details["from"])
+	assert.Equal(t, "pending_deletion", details["to"])
+	assert.Equal(t, "2025-09-08T12:00:00Z", details["deletion_scheduled_at"])
+}
+
+func TestLifecycle_Errors(t *testing.T) {
+	ctx := context.Background()
+	q := newMockQueries()
+	id := q.addTenant(1, StatusActive)
+	svc, _, _ := newTestService(q)
+
+	_, err := svc.Suspend(ctx, testActor, newID(99), "")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = svc.ScheduleDeletion(ctx, testActor, id, "", -time.Hour)
+	assert.Error(t, err)
+
+	_, err = svc.Get(ctx, newID(99))
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Empty(t, q.audits)
+}
+
+func TestErase(t *testing.T) {
+	ctx := context.Background()
+	q := newMockQueries()
+	id := q.addTenant(1, StatusActive)
+	q.rows = map[string]int64{"messages": 40, "agents": 3, "workflows": 2, "users": 5}
+	svc, db, c := newTestService(q)
+
+	_, err := svc.Erase(ctx, testActor, id)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	_, err = svc.ScheduleDeletion(ctx, testActor, id, "", 24*time.Hour)
+	require.NoError(t, err)
+
+	_, err = svc.Erase(ctx, testActor, id)
+	assert.ErrorIs(t, err, ErrGracePeriod)
+	assert.Equal(t, int64(40), q.rows["messages"], "nothing is erased during the grace period")
+
+	c.t = c.t.Add(25 * time.Hour)
+	report, err := svc.Erase(ctx, testActor, id)
+	require.NoError(t, err)
+	assert.True(t, db.txs[len(db.txs)-1].committed)
+
+	assert.Equal(t, int64(40), report.Deleted["messages"])
+	assert.Equal(t, int64(5), report.Deleted["users"])
+	assert.Equal(t, int64(0), report.Deleted["tools"])
+
+	// The tombstone extends the chain rather than replacing it
+	tombstone := q.audits[len(q.audits)-1]
+	assert.Equal(t, ActionErase, tombstone.Action)
+	assert.Equal(t, q.audits[len(q.audits)-2].Hash, tombstone.PrevHash)
+	assert.Equal(t, hex.EncodeToString(tombstone.Hash), report.ChainHead)
+	assert.Equal(t, uuidString(tombstone.ID), report.TombstoneID)
+
+	tenant, err := svc.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusErased, tenant.Status)
+	assert.NotEqual(t, "acme", tenant.Name)
+
+	_, err = svc.Erase(ctx, testActor, id)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+}
+
+func TestErase_RollsBackOnFailure(t *testing.T) {
+	ctx := context.Background()
+	q := newMockQueries()
+	id := q.addTenant(1, StatusPendingDeletion)
+	q.tenants[id].DeletionScheduledAt = pgtype.Timestamptz{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	q.failErase = true
+	svc, db, _ := newTestService(q)
+
+	_, err := svc.Erase(ctx, testActor, id)
+	assert.ErrorContains(t, err, "failed to erase users")
+	assert.True(t, db.txs[0].rolledBack)
+	assert.Empty(t, q.audits)
+}
+
+func TestEraseDue(t *testing.T) {
+	ctx := context.Background()
+	q := newMockQueries()
+	past := pgtype.Timestamptz{Time: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	future := pgtype.Timestamptz{Time: time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+
+	due := q.addTenant(1, StatusPendingDeletion)
+	q.tenants[due].DeletionScheduledAt = past
+	notDue := q.addTenant(2, StatusPendingDeletion)
+	q.tenants[notDue].DeletionScheduledAt = future
+	q.addTenant(3, StatusActive)
+	svc, _, _ := newTestService(q)
+
+	reports, err := svc.EraseDue(ctx, Actor{Type: "system", ID: "erasure-job"})
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, uuidString(due), reports[0].TenantID)
+	assert.Equal(t, StatusErased, q.tenants[due].Status)
+	assert.Equal(t, StatusPendingDeletion, q.tenants[notDue].Status)
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	q := newMockQueries()
+	id := q.addTenant(1, StatusSuspended)
+	q.agents = []queries.Agent{
+		{ID: newID(10), TenantID: id, Name: "planner", Type: "llm", ConfigJson: []byte(`{"model":"a"}`), PoliciesJson: []byte(`{}`)},
+		{ID: newID(11), TenantID: id, Name: "old", Type: "llm", ConfigJson: []byte(`{}`), DeletedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}},
+	}
+	q.workflows = []queries.Workflow{{ID: newID(20), TenantID: id, Name: "triage", Version: "1.0.0", ConfigYaml: "steps: []\n"}}
+	for i := 0; i < exportPageSize+5; i++ {
+		q.messages = append(q.messages, queries.Message{ID: newID(100 + i), TenantID: id, Type: "event", Payload: []byte(`{"n":1}`)})
+	}
+	svc, db, _ := newTestService(q)
+	q.audits = []queries.Audit{{ID: newID(500), TenantID: id, Action: "create", Details: []byte(`{"a":1}`), Hash: []byte{0xab, 0xcd}}}
+
+	var buf bytes.Buffer
+	manifest, err := svc.Export(ctx, id, &buf)
+	require.NoError(t, err)
+	tx := db.txs[len(db.txs)-1]
+	assert.Equal(t, pgx.RepeatableRead, tx.opts.IsoLevel)
+	assert.Equal(t, pgx.ReadOnly, tx.opts.AccessMode)
+
+	assert.Equal(t, map[string]int{"agents": 2, "workflows": 1, "messages": exportPageSize + 5, "audits": 1}, manifest.Counts)
+	assert.Equal(t, "abcd", manifest.AuditHead)
+
+	var archive struct {
+		Format string                   `json:"format"`
+		Tenant map[string]interface{}   `json:"tenant"`
+		Agents []map[string]interface{} `json:"agents"`
+		Flows  []map[string]interface{} `json:"workflows"`
+		Msgs   []map[string]interface{} `json:"messages"`
+		Audits []map[string]interface{} `json:"audits"`
+		Mfst   ExportManifest           `json:"manifest"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &archive))
+	assert.Equal(t, ExportFormat, archive.Format)
+	assert.Equal(t, map[string]interface{}{"region": "eu"}, archive.Tenant["settings"])
+	require.Len(t, archive.Agents, 2)
+	assert.Equal(t, map[string]interface{}{"model": "a"}, archive.Agents[0]["config_json"])
+	assert.Nil(t, archive.Agents[1]["policies_json"])
+	assert.NotNil(t, archive.Agents[1]["deleted_at"])
+	assert.Len(t, archive.Flows, 1)
+	assert.Len(t, archive.Msgs, exportPageSize+5)
+	assert.Equal(t, "abcd", archive.Audits[0]["hash"])
+	assert.Equal(t, "", archive.Audits[0]["prev_hash"])
+	assert.Equal(t, map[string]interface{}{"a": float64(1)}, archive.Audits[0]["details"])
+	assert.Equal(t, manifest.Counts, archive.Mfst.Counts)
+}
+
+func TestExport_Erased(t *testing.T) {
+	q := newMockQueries()
+	id := q.addTenant(1, StatusErased)
+	svc, _, _ := newTestService(q)
+
+	var buf bytes.Buffer
+	_, err := svc.Export(context.Background(), id, &buf)
+	assert.ErrorIs(t, err, ErrErased)
+	assert.Zero(t, buf.Len())
+}
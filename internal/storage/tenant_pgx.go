@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/agentflow/agentflow/internal/storage/queries"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrMissingTenant is returned when a tenant-scoped query runs without a tenant in context
+var ErrMissingTenant = errors.New("tenant ID not found in context")
+
+// TenantScopedConn wraps a pgx pool, connection or transaction so that every
+// statement is rewritten with tenant conditions before execution. It satisfies
+// queries.DBTX and can be passed directly to queries.New.
+type TenantScopedConn struct {
+	conn     queries.DBTX
+	rewriter *TenantRewriter
+	logger   logging.Logger
+}
+
+// NewTenantScopedConn creates a tenant-scoped wrapper around a pgx querier
+func NewTenantScopedConn(conn queries.DBTX, logger logging.Logger) *TenantScopedConn {
+	return &TenantScopedConn{
+		conn:     conn,
+		rewriter: NewTenantRewriter(),
+		logger:   logger,
+	}
+}
+
+// Exec executes a tenant-scoped statement
+func (c *TenantScopedConn) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	scopedSQL, scopedArgs, err := c.scope(ctx, sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return c.conn.Exec(ctx, scopedSQL, scopedArgs...)
+}
+
+// Query executes a tenant-scoped query
+func (c *TenantScopedConn) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	scopedSQL, scopedArgs, err := c.scope(ctx, sql, args)
+	if err != nil {
+		return nil, err
+	}
+	return c.conn.Query(ctx, scopedSQL, scopedArgs...)
+}
+
+// QueryRow executes a tenant-scoped query that returns a single row
+func (c *TenantScopedConn) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	scopedSQL, scopedArgs, err := c.scope(ctx, sql, args)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return c.conn.QueryRow(ctx, scopedSQL, scopedArgs...)
+}
+
+// scope rewrites sql for the tenant found in ctx
+func (c *TenantScopedConn) scope(ctx context.Context, sql string, args []interface{}) (string, []interface{}, error) {
+	tenantID, ok := GetTenantIDFromContext(ctx)
+	if !ok {
+		return "", nil, ErrMissingTenant
+	}
+
+	// Query options and rewriters change how pgx interprets the remaining
+	// arguments, so positional parameters cannot be appended safely
+	for _, arg := range args {
+		switch arg.(type) {
+		case pgx.QueryRewriter, pgx.QueryExecMode, pgx.QueryResultFormats, pgx.QueryResultFormatsByOID:
+			return "", nil, fmt.Errorf("%w: unsupported pgx query option %T", ErrUnscopableQuery, arg)
+		}
+	}
+
+	scopedSQL, scopedArgs, err := c.rewriter.Rewrite(sql, tenantID, args)
+	if err != nil {
+		c.logger.Warn("Rejected tenant-scoped query",
+			logging.String("tenant_id", tenantID),
+			logging.String("query", sql),
+			logging.String("error", err.Error()))
+		return "", nil, fmt.Errorf("failed to inject tenant scoping: %w", err)
+	}
+
+	c.logger.Debug("Executing tenant-scoped query",
+		logging.String("tenant_id", tenantID),
+		logging.String("original_query", sql),
+		logging.String("scoped_query", scopedSQL),
+		logging.Int("arg_count", len(scopedArgs)))
+
+	return scopedSQL, scopedArgs, nil
+}
+
+// errRow is a pgx.Row that reports a scoping error on Scan
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
+
+// TenantQueryTracer is a pgx.QueryTracer that reports statements touching
+// multi-tenant tables without tenant conditions. Install it on the pool's
+// ConnConfig.Tracer to detect code paths that bypass TenantScopedConn.
+type TenantQueryTracer struct {
+	rewriter *TenantRewriter
+	logger   logging.Logger
+	next     pgx.QueryTracer
+}
+
+// NewTenantQueryTracer creates a tracer that logs unscoped queries. next may
+// be nil or another tracer (for example OpenTelemetry) to chain to.
+func NewTenantQueryTracer(logger logging.Logger, next pgx.QueryTracer) *TenantQueryTracer {
+	return &TenantQueryTracer{
+		rewriter: NewTenantRewriter(),
+		logger:   logger,
+		next:     next,
+	}
+}
+
+// TraceQueryStart inspects the statement before it is sent to the server
+func (t *TenantQueryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	tables, err := t.rewriter.UnscopedTables(data.SQL)
+	switch {
+	case err != nil:
+		t.logger.Warn("Unable to analyze query for tenant scoping",
+			logging.String("query", data.SQL),
+			logging.String("error", err.Error()))
+	case len(tables) > 0:
+		t.logger.Warn("Query on multi-tenant tables without tenant scoping",
+			logging.String("query", data.SQL),
+			logging.Any("tables", tables))
+	}
+
+	if t.next != nil {
+		return t.next.TraceQueryStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+// TraceQueryEnd forwards to the chained tracer
+func (t *TenantQueryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	if t.next != nil {
+		t.next.TraceQueryEnd(ctx, conn, data)
+	}
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDBTX captures the statements sent through it
+type recordingDBTX struct {
+	sql  string
+	args []interface{}
+}
+
+func (r *recordingDBTX) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	r.sql, r.args = sql, args
+	return pgconn.NewCommandTag("DELETE 1"), nil
+}
+
+func (r *recordingDBTX) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	r.sql, r.args = sql, args
+	return nil, nil
+}
+
+func (r *recordingDBTX) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	r.sql, r.args = sql, args
+	return nil
+}
+
+func TestTenantScopedConn(t *testing.T) {
+	inner := &recordingDBTX{}
+	conn := NewTenantScopedConn(inner, logging.NewLogger())
+	ctx := context.WithValue(context.Background(), "tenant_id", testTenant)
+
+	_, err := conn.Exec(ctx, "DELETE FROM agents WHERE id = $1", "agent-1")
+	require.NoError(t, err)
+	assert.Equal(t, "DELETE FROM agents WHERE agents.tenant_id = $2 AND id = $1", inner.sql)
+	assert.Equal(t, []interface{}{"agent-1", testTenant}, inner.args)
+
+	_, err = conn.Query(ctx, "SELECT * FROM workflows")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM workflows WHERE workflows.tenant_id = $1", inner.sql)
+
+	conn.QueryRow(ctx, "SELECT * FROM tenants WHERE id = $1", testTenant)
+	assert.Equal(t, "SELECT * FROM tenants WHERE id = $1", inner.sql)
+}
+
+func TestTenantScopedConn_Errors(t *testing.T) {
+	inner := &recordingDBTX{}
+	conn := NewTenantScopedConn(inner, logging.NewLogger())
+
+	_, err := conn.Exec(context.Background(), "DELETE FROM agents")
+	assert.True(t, errors.Is(err, ErrMissingTenant))
+
+	ctx := context.WithValue(context.Background(), "tenant_id", testTenant)
+	row := conn.QueryRow(ctx, "SELECT * FROM users WHERE tenant_id = $1", "22222222-2222-2222-2222-222222222222")
+	assert.True(t, errors.Is(row.Scan(), ErrCrossTenantQuery))
+
+	_, err = conn.Query(ctx, "SELECT * FROM users", pgx.QueryExecModeSimpleProtocol)
+	assert.True(t, errors.Is(err, ErrUnscopableQuery))
+	assert.Empty(t, inner.sql, "rejected statements must not reach the database")
+}
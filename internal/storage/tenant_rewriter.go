@@ -0,0 +1,1205 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrCrossTenantQuery is returned when a query explicitly filters on a tenant other than the caller's
+	ErrCrossTenantQuery = errors.New("query references a different tenant")
+	// ErrUnscopableQuery is returned when a tenant table is referenced in a way the rewriter cannot scope
+	ErrUnscopableQuery = errors.New("query cannot be tenant scoped")
+)
+
+// DefaultMultiTenantTables lists the tables that carry a tenant_id column
+var DefaultMultiTenantTables = []string{
+	"users",
+	"agents",
+	"workflows",
+	"messages",
+	"tools",
+	"audits",
+	"budgets",
+	"rbac_roles",
+	"rbac_bindings",
+	"agent_revisions",
+	"workflow_revisions",
+	"audit_checkpoints",
+	"audit_tree_heads",
+	"refresh_tokens",
+	"policies",
+	"service_accounts",
+	"api_keys",
+}
+
+// TenantRewriter rewrites PostgreSQL statements so that every reference to a
+// multi-tenant table is restricted to a single tenant. It understands SELECT,
+// INSERT, UPDATE and DELETE including CTEs, joins, subqueries and set operations.
+type TenantRewriter struct {
+	tables map[string]bool
+}
+
+// NewTenantRewriter creates a rewriter for the given multi-tenant tables.
+// When no tables are given DefaultMultiTenantTables is used.
+func NewTenantRewriter(tables ...string) *TenantRewriter {
+	if len(tables) == 0 {
+		tables = DefaultMultiTenantTables
+	}
+	r := &TenantRewriter{tables: make(map[string]bool, len(tables))}
+	for _, table := range tables {
+		r.tables[table] = true
+	}
+	return r
+}
+
+// IsMultiTenantTable reports whether the table carries a tenant_id column
+func (r *TenantRewriter) IsMultiTenantTable(table string) bool {
+	return r.tables[table]
+}
+
+// Rewrite returns the query with tenant conditions injected for tenantID. The
+// tenant ID is appended to args as a new positional parameter when needed.
+// Existing tenant_id conditions are validated against tenantID instead of duplicated.
+func (r *TenantRewriter) Rewrite(query, tenantID string, args []interface{}) (string, []interface{}, error) {
+	res, err := r.rewrite(query, tenantID, args)
+	if err != nil {
+		return query, args, err
+	}
+	return res.query, res.args, nil
+}
+
+// UnscopedTables returns the multi-tenant tables referenced by query without a
+// tenant condition. It is used to detect queries that bypassed the rewriter.
+func (r *TenantRewriter) UnscopedTables(query string) ([]string, error) {
+	res, err := r.rewrite(query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.scoped, nil
+}
+
+// rewriteResult holds the outcome of a rewrite pass
+type rewriteResult struct {
+	query  string
+	args   []interface{}
+	scoped []string
+}
+
+// textEdit is an insertion into the original query text
+type textEdit struct {
+	offset int
+	text   string
+}
+
+// tableRef is a reference to a multi-tenant table within a statement
+type tableRef struct {
+	table     string // normalized table name
+	qualifier string // alias or table name as written, used to qualify tenant_id
+	match     string // normalized qualifier for detecting existing conditions
+}
+
+// rewriteState carries the state of a single rewrite pass
+type rewriteState struct {
+	r        *TenantRewriter
+	toks     *sqlTokens
+	tenantID string
+	args     []interface{}
+	param    string
+	edits    []textEdit
+	scoped   []string
+}
+
+func (r *TenantRewriter) rewrite(query, tenantID string, args []interface{}) (*rewriteResult, error) {
+	toks, err := lexSQL(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnscopableQuery, err)
+	}
+
+	maxParam := 0
+	for _, tok := range toks.tokens {
+		if idx := tok.paramIndex(); idx > maxParam {
+			maxParam = idx
+		}
+	}
+	if tenantID != "" && maxParam > len(args) {
+		return nil, fmt.Errorf("query references $%d but only %d arguments were supplied", maxParam, len(args))
+	}
+
+	next := len(args) + 1
+	if next <= maxParam {
+		next = maxParam + 1
+	}
+
+	st := &rewriteState{
+		r:        r,
+		toks:     toks,
+		tenantID: tenantID,
+		args:     args,
+		param:    fmt.Sprintf("$%d", next),
+	}
+
+	// Process each statement separately
+	lo := 0
+	for i := 0; i <= len(toks.tokens); i++ {
+		if i == len(toks.tokens) || toks.tokens[i].kind == tokSemicolon {
+			if i > lo {
+				if err := st.statement(lo, i, nil); err != nil {
+					return nil, err
+				}
+			}
+			lo = i + 1
+		}
+	}
+
+	res := &rewriteResult{query: query, args: args, scoped: st.scoped}
+	if len(st.edits) == 0 {
+		return res, nil
+	}
+
+	sort.SliceStable(st.edits, func(a, b int) bool { return st.edits[a].offset < st.edits[b].offset })
+	var sb strings.Builder
+	prev := 0
+	for _, edit := range st.edits {
+		sb.WriteString(query[prev:edit.offset])
+		sb.WriteString(edit.text)
+		prev = edit.offset
+	}
+	sb.WriteString(query[prev:])
+
+	res.query = sb.String()
+	res.args = append(append([]interface{}{}, args...), tenantID)
+	return res, nil
+}
+
+func (st *rewriteState) tok(i int) sqlToken {
+	return st.toks.tokens[i]
+}
+
+// insert records a text insertion at the given byte offset
+func (st *rewriteState) insert(offset int, text string) {
+	st.edits = append(st.edits, textEdit{offset: offset, text: text})
+}
+
+// skip returns the index after token i, jumping over parenthesized groups
+func (st *rewriteState) skip(i int) int {
+	if st.tok(i).kind == tokLParen {
+		return st.toks.match[i] + 1
+	}
+	return i + 1
+}
+
+// find returns the first depth-0 index in [lo, hi) whose token is one of keywords, or hi
+func (st *rewriteState) find(lo, hi int, keywords ...string) int {
+	for i := lo; i < hi; i = st.skip(i) {
+		if st.tok(i).isKeyword(keywords...) {
+			return i
+		}
+	}
+	return hi
+}
+
+// firstWord returns the first token in [lo, hi) that is not an opening parenthesis
+func (st *rewriteState) firstWord(lo, hi int) (sqlToken, bool) {
+	for i := lo; i < hi; i++ {
+		if st.tok(i).kind != tokLParen {
+			return st.tok(i), true
+		}
+	}
+	return sqlToken{}, false
+}
+
+// isQueryStart reports whether the group [lo, hi) begins a nested statement
+func (st *rewriteState) isQueryStart(lo, hi int) bool {
+	first, ok := st.firstWord(lo, hi)
+	return ok && first.isKeyword("select", "with", "values", "table", "insert", "update", "delete")
+}
+
+// statement scopes a single statement in [lo, hi)
+func (st *rewriteState) statement(lo, hi int, ctes map[string]bool) error {
+	if lo >= hi {
+		return nil
+	}
+
+	// Unwrap fully parenthesized statements
+	if st.tok(lo).kind == tokLParen && st.toks.match[lo] == hi-1 {
+		return st.statement(lo+1, hi-1, ctes)
+	}
+
+	if st.tok(lo).isKeyword("with") {
+		next, inner, err := st.withClause(lo, hi, ctes)
+		if err != nil {
+			return err
+		}
+		lo, ctes = next, inner
+		if lo >= hi {
+			return fmt.Errorf("%w: WITH clause without a statement", ErrUnscopableQuery)
+		}
+	}
+
+	first := st.tok(lo)
+	switch {
+	case first.kind == tokLParen || first.isKeyword("select", "values", "table"):
+		return st.selectStatement(lo, hi, ctes)
+	case first.isKeyword("insert"):
+		return st.insertStatement(lo, hi, ctes)
+	case first.isKeyword("update"):
+		return st.updateStatement(lo, hi, ctes)
+	case first.isKeyword("delete"):
+		return st.deleteStatement(lo, hi, ctes)
+	case first.isKeyword("explain"):
+		// EXPLAIN ANALYZE executes the statement, so it is scoped like any other
+		i := lo + 1
+		for i < hi && (st.tok(i).kind == tokLParen || st.tok(i).isKeyword("analyze", "analyse", "verbose")) {
+			i = st.skip(i)
+		}
+		return st.statement(i, hi, ctes)
+	default:
+		// DDL and session statements are not tenant scoped, but utility
+		// statements such as TRUNCATE, COPY or LOCK would act on every tenant
+		return st.utilityStatement(lo, hi)
+	}
+}
+
+// utilityStatement rejects statements outside SELECT, INSERT, UPDATE and
+// DELETE that name a multi-tenant table, other than DDL
+func (st *rewriteState) utilityStatement(lo, hi int) error {
+	if st.tok(lo).isKeyword("create", "alter", "drop", "comment", "grant", "revoke") {
+		return nil
+	}
+	for i := lo; i < hi; i++ {
+		tok := st.tok(i)
+		if !tok.isName() || !st.r.tables[tok.value] {
+			continue
+		}
+		// Only the last part of a qualified name is the table
+		if i+1 < hi && st.tok(i+1).kind == tokDot {
+			continue
+		}
+		return fmt.Errorf("%w: %s on tenant table %s", ErrUnscopableQuery, strings.ToUpper(st.tok(lo).value), tok.value)
+	}
+	return nil
+}
+
+// withClause scopes the CTE bodies of a WITH clause and returns the index of
+// the main statement together with the CTE names visible to it
+func (st *rewriteState) withClause(lo, hi int, ctes map[string]bool) (int, map[string]bool, error) {
+	inner := make(map[string]bool, len(ctes))
+	for name := range ctes {
+		inner[name] = true
+	}
+
+	i := lo + 1
+	if i < hi && st.tok(i).isKeyword("recursive") {
+		i++
+	}
+
+	for {
+		if i >= hi || !st.tok(i).isName() {
+			return 0, nil, fmt.Errorf("%w: malformed WITH clause", ErrUnscopableQuery)
+		}
+		inner[st.tok(i).value] = true
+		i++
+		if i < hi && st.tok(i).kind == tokLParen {
+			i = st.skip(i)
+		}
+		if i >= hi || !st.tok(i).isKeyword("as") {
+			return 0, nil, fmt.Errorf("%w: malformed WITH clause", ErrUnscopableQuery)
+		}
+		i++
+		for i < hi && st.tok(i).isKeyword("not", "materialized") {
+			i++
+		}
+		if i >= hi || st.tok(i).kind != tokLParen {
+			return 0, nil, fmt.Errorf("%w: malformed WITH clause", ErrUnscopableQuery)
+		}
+		if err := st.statement(i+1, st.toks.match[i], inner); err != nil {
+			return 0, nil, err
+		}
+		i = st.skip(i)
+
+		// SEARCH and CYCLE clauses of recursive CTEs
+		for i < hi && st.tok(i).isKeyword("search", "cycle") {
+			for i < hi && st.tok(i).kind != tokComma && !st.isQueryStart(i, hi) {
+				i = st.skip(i)
+			}
+		}
+
+		if i < hi && st.tok(i).kind == tokComma {
+			i++
+			continue
+		}
+		return i, inner, nil
+	}
+}
+
+// selectStatement scopes a SELECT, possibly combined with set operations
+func (st *rewriteState) selectStatement(lo, hi int, ctes map[string]bool) error {
+	armStart := lo
+	for i := lo; i <= hi; {
+		if i < hi && !st.tok(i).isKeyword("union", "intersect", "except") {
+			i = st.skip(i)
+			continue
+		}
+		if err := st.selectArm(armStart, i, ctes); err != nil {
+			return err
+		}
+		if i >= hi {
+			break
+		}
+		i++
+		if i < hi && st.tok(i).isKeyword("all", "distinct") {
+			i++
+		}
+		armStart = i
+	}
+	return nil
+}
+
+// selectArm scopes a single SELECT without set operations
+func (st *rewriteState) selectArm(lo, hi int, ctes map[string]bool) error {
+	if lo >= hi {
+		return fmt.Errorf("%w: empty query in set operation", ErrUnscopableQuery)
+	}
+
+	first := st.tok(lo)
+	switch {
+	case first.kind == tokLParen:
+		closeIdx := st.toks.match[lo]
+		if err := st.statement(lo+1, closeIdx, ctes); err != nil {
+			return err
+		}
+		return st.subqueries(closeIdx+1, hi, ctes)
+	case first.isKeyword("values"):
+		return st.subqueries(lo+1, hi, ctes)
+	case first.isKeyword("table"):
+		return fmt.Errorf("%w: TABLE statements are not supported", ErrUnscopableQuery)
+	}
+
+	fromIdx := st.findFrom(lo+1, hi)
+	if fromIdx == hi {
+		return st.subqueries(lo+1, hi, ctes)
+	}
+	if err := st.subqueries(lo+1, fromIdx, ctes); err != nil {
+		return err
+	}
+
+	fromEnd := st.find(fromIdx+1, hi, "where", "group", "having", "window", "order", "limit", "offset", "fetch", "for")
+	refs, err := st.fromList(fromIdx+1, fromEnd, ctes)
+	if err != nil {
+		return err
+	}
+
+	whereIdx, whereEnd := -1, fromEnd
+	if fromEnd < hi && st.tok(fromEnd).isKeyword("where") {
+		whereIdx = fromEnd
+		whereEnd = st.find(fromEnd+1, hi, "group", "having", "window", "order", "limit", "offset", "fetch", "for")
+	}
+
+	if err := st.subqueries(fromEnd, hi, ctes); err != nil {
+		return err
+	}
+	return st.addConditions(refs, whereIdx, whereEnd, fromEnd-1, "WHERE", len(refs) == 1)
+}
+
+// findFrom locates the FROM keyword of a SELECT, ignoring IS [NOT] DISTINCT FROM
+func (st *rewriteState) findFrom(lo, hi int) int {
+	for i := lo; i < hi; i = st.skip(i) {
+		if !st.tok(i).isKeyword("from") {
+			continue
+		}
+		if i-2 >= lo && st.tok(i-1).isKeyword("distinct") && st.tok(i-2).isKeyword("is", "not") {
+			continue
+		}
+		return i
+	}
+	return hi
+}
+
+// fromList parses a FROM list in [lo, hi). Tables joined with an ON clause are
+// scoped inside that clause; all other references are returned so the caller can
+// scope them in its WHERE clause.
+func (st *rewriteState) fromList(lo, hi int, ctes map[string]bool) ([]tableRef, error) {
+	var whereRefs []tableRef
+	i := lo
+
+	refs, next, err := st.fromItem(i, hi, ctes)
+	if err != nil {
+		return nil, err
+	}
+	whereRefs = append(whereRefs, refs...)
+	i = next
+
+	for i < hi {
+		tok := st.tok(i)
+		if tok.kind == tokComma {
+			refs, next, err := st.fromItem(i+1, hi, ctes)
+			if err != nil {
+				return nil, err
+			}
+			whereRefs = append(whereRefs, refs...)
+			i = next
+			continue
+		}
+
+		if !st.isJoinKeyword(i, hi) {
+			return nil, fmt.Errorf("%w: unexpected %q in FROM clause", ErrUnscopableQuery, tok.text)
+		}
+		for i < hi && !st.tok(i).isKeyword("join") {
+			i++
+		}
+		if i >= hi {
+			return nil, fmt.Errorf("%w: incomplete JOIN", ErrUnscopableQuery)
+		}
+
+		refs, next, err := st.fromItem(i+1, hi, ctes)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+
+		switch {
+		case i < hi && st.tok(i).isKeyword("on"):
+			onEnd := i + 1
+			for onEnd < hi && st.tok(onEnd).kind != tokComma && !st.isJoinKeyword(onEnd, hi) {
+				onEnd = st.skip(onEnd)
+			}
+			if err := st.subqueries(i+1, onEnd, ctes); err != nil {
+				return nil, err
+			}
+			if err := st.addConditions(refs, i, onEnd, -1, "ON", false); err != nil {
+				return nil, err
+			}
+			i = onEnd
+		case i < hi && st.tok(i).isKeyword("using"):
+			i++
+			if i < hi && st.tok(i).kind == tokLParen {
+				i = st.skip(i)
+			}
+			if i < hi && st.tok(i).isKeyword("as") {
+				i += 2
+			}
+			whereRefs = append(whereRefs, refs...)
+		default:
+			whereRefs = append(whereRefs, refs...)
+		}
+	}
+
+	return whereRefs, nil
+}
+
+// isJoinKeyword reports whether token i starts a join clause
+func (st *rewriteState) isJoinKeyword(i, hi int) bool {
+	tok := st.tok(i)
+	if !tok.isKeyword("join", "inner", "left", "right", "full", "cross", "natural") {
+		return false
+	}
+	// left(...) and right(...) are string functions
+	return !(i+1 < hi && st.tok(i+1).kind == tokLParen)
+}
+
+// reservedAfterTable lists keywords that can follow a table reference and
+// therefore cannot be an implicit alias. It contains the PostgreSQL reserved
+// keywords plus the join and clause keywords that are only reserved in context.
+var reservedAfterTable = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true,
+	"as": true, "asc": true, "asymmetric": true, "both": true, "case": true, "cast": true,
+	"check": true, "collate": true, "column": true, "constraint": true, "create": true,
+	"current_catalog": true, "current_date": true, "current_role": true, "current_time": true,
+	"current_timestamp": true, "current_user": true, "default": true, "deferrable": true,
+	"desc": true, "distinct": true, "do": true, "else": true, "end": true, "except": true,
+	"false": true, "fetch": true, "for": true, "foreign": true, "from": true, "grant": true,
+	"group": true, "having": true, "in": true, "initially": true, "intersect": true,
+	"into": true, "lateral": true, "leading": true, "limit": true, "localtime": true,
+	"localtimestamp": true, "not": true, "null": true, "offset": true, "on": true,
+	"only": true, "or": true, "order": true, "placing": true, "primary": true,
+	"references": true, "returning": true, "select": true, "session_user": true,
+	"some": true, "symmetric": true, "table": true, "then": true, "to": true,
+	"trailing": true, "true": true, "union": true, "unique": true, "user": true,
+	"using": true, "variadic": true, "when": true, "where": true, "window": true,
+	"with": true, "join": true, "inner": true, "left": true, "right": true, "full": true,
+	"outer": true, "cross": true, "natural": true, "tablesample": true, "set": true,
+	"values": true, "overriding": true, "is": true, "isnull": true, "notnull": true,
+	"like": true, "ilike": true, "similar": true, "between": true, "overlaps": true,
+}
+
+// fromItem parses a single FROM item starting at lo and returns the tenant
+// table references it introduces and the index following it
+func (st *rewriteState) fromItem(lo, hi int, ctes map[string]bool) ([]tableRef, int, error) {
+	i := lo
+	if i < hi && st.tok(i).isKeyword("lateral") {
+		i++
+	}
+	if i >= hi {
+		return nil, 0, fmt.Errorf("%w: missing FROM item", ErrUnscopableQuery)
+	}
+
+	if st.tok(i).kind == tokLParen {
+		closeIdx := st.toks.match[i]
+		var refs []tableRef
+		if st.isQueryStart(i+1, closeIdx) {
+			if err := st.statement(i+1, closeIdx, ctes); err != nil {
+				return nil, 0, err
+			}
+		} else {
+			// Parenthesized join tree
+			inner, err := st.fromList(i+1, closeIdx, ctes)
+			if err != nil {
+				return nil, 0, err
+			}
+			refs = inner
+		}
+		_, next := st.alias(closeIdx+1, hi)
+		return refs, next, nil
+	}
+
+	if st.tok(i).isKeyword("only") {
+		i++
+	}
+
+	var parts []sqlToken
+	for i < hi && st.tok(i).isName() {
+		parts = append(parts, st.tok(i))
+		i++
+		if i < hi && st.tok(i).kind == tokDot {
+			i++
+			continue
+		}
+		break
+	}
+	if len(parts) == 0 {
+		return nil, 0, fmt.Errorf("%w: unexpected %q in FROM clause", ErrUnscopableQuery, st.tok(i).text)
+	}
+
+	// Table functions such as unnest(...) or generate_series(...)
+	if i < hi && st.tok(i).kind == tokLParen {
+		closeIdx := st.toks.match[i]
+		if err := st.subqueries(i+1, closeIdx, ctes); err != nil {
+			return nil, 0, err
+		}
+		i = closeIdx + 1
+		if i < hi && st.tok(i).isKeyword("with") && i+1 < hi && st.tok(i+1).isKeyword("ordinality") {
+			i += 2
+		}
+		_, next := st.alias(i, hi)
+		return nil, next, nil
+	}
+
+	if i < hi && st.tok(i).kind == tokOperator && st.tok(i).text == "*" {
+		i++
+	}
+
+	alias, next := st.alias(i, hi)
+	i = next
+	if i < hi && st.tok(i).isKeyword("tablesample") {
+		i++
+		for i < hi && (st.tok(i).isName() && !reservedAfterTable[st.tok(i).value] || st.tok(i).kind == tokLParen) {
+			i = st.skip(i)
+		}
+	}
+
+	table := parts[len(parts)-1]
+	if len(parts) == 1 && ctes[table.value] {
+		return nil, i, nil
+	}
+	ref, ok := st.ref(table, alias)
+	if !ok {
+		return nil, i, nil
+	}
+	return []tableRef{ref}, i, nil
+}
+
+// alias parses an optional [AS] alias [(columns)] starting at lo
+func (st *rewriteState) alias(lo, hi int) (*sqlToken, int) {
+	i := lo
+	explicit := false
+	if i < hi && st.tok(i).isKeyword("as") {
+		explicit = true
+		i++
+	}
+	if i >= hi || !st.tok(i).isName() {
+		return nil, lo
+	}
+	tok := st.tok(i)
+	if !explicit && tok.kind == tokIdent && reservedAfterTable[tok.value] {
+		return nil, lo
+	}
+	i++
+	if i < hi && st.tok(i).kind == tokLParen {
+		i = st.skip(i)
+	}
+	return &tok, i
+}
+
+// ref builds a table reference if the table is multi-tenant
+func (st *rewriteState) ref(table sqlToken, alias *sqlToken) (tableRef, bool) {
+	if !st.r.tables[table.value] {
+		return tableRef{}, false
+	}
+	qualifier := table
+	if alias != nil {
+		qualifier = *alias
+	}
+	return tableRef{table: table.value, qualifier: qualifier.text, match: qualifier.value}, true
+}
+
+// target parses the target table of INSERT, UPDATE or DELETE starting at lo
+func (st *rewriteState) target(lo, hi int, allowAlias bool) (sqlToken, *sqlToken, int, error) {
+	i := lo
+	if i < hi && st.tok(i).isKeyword("only") {
+		i++
+	}
+	var table sqlToken
+	found := false
+	for i < hi && st.tok(i).isName() {
+		table = st.tok(i)
+		found = true
+		i++
+		if i < hi && st.tok(i).kind == tokDot {
+			i++
+			continue
+		}
+		break
+	}
+	if !found {
+		return sqlToken{}, nil, 0, fmt.Errorf("%w: missing target table", ErrUnscopableQuery)
+	}
+	if i < hi && st.tok(i).kind == tokOperator && st.tok(i).text == "*" {
+		i++
+	}
+	if !allowAlias {
+		return table, nil, i, nil
+	}
+	alias, next := st.alias(i, hi)
+	return table, alias, next, nil
+}
+
+// updateStatement scopes UPDATE ... SET ... [FROM ...] [WHERE ...] [RETURNING ...]
+func (st *rewriteState) updateStatement(lo, hi int, ctes map[string]bool) error {
+	table, alias, i, err := st.target(lo+1, hi, true)
+	if err != nil {
+		return err
+	}
+	if i >= hi || !st.tok(i).isKeyword("set") {
+		return fmt.Errorf("%w: UPDATE without SET", ErrUnscopableQuery)
+	}
+
+	var refs []tableRef
+	if ref, ok := st.ref(table, alias); ok {
+		refs = append(refs, ref)
+	}
+
+	returningIdx := st.find(i, hi, "returning")
+	whereIdx := st.find(i, returningIdx, "where")
+	fromIdx := st.find(i, whereIdx, "from")
+
+	if err := st.subqueries(i+1, fromIdx, ctes); err != nil {
+		return err
+	}
+	if len(refs) == 1 {
+		if err := st.checkAssignments(refs[0], i+1, fromIdx, false); err != nil {
+			return err
+		}
+	}
+	if fromIdx < whereIdx {
+		fromRefs, err := st.fromList(fromIdx+1, whereIdx, ctes)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, fromRefs...)
+	}
+	if err := st.subqueries(whereIdx, hi, ctes); err != nil {
+		return err
+	}
+
+	if whereIdx == returningIdx {
+		whereIdx = -1
+	} else if whereIdx+1 < returningIdx && st.tok(whereIdx+1).isKeyword("current") {
+		return fmt.Errorf("%w: WHERE CURRENT OF is not supported", ErrUnscopableQuery)
+	}
+	return st.addConditions(refs, whereIdx, returningIdx, returningIdx-1, "WHERE", len(refs) == 1 && fromIdx == whereIdx)
+}
+
+// deleteStatement scopes DELETE FROM ... [USING ...] [WHERE ...] [RETURNING ...]
+func (st *rewriteState) deleteStatement(lo, hi int, ctes map[string]bool) error {
+	if lo+1 >= hi || !st.tok(lo+1).isKeyword("from") {
+		return fmt.Errorf("%w: DELETE without FROM", ErrUnscopableQuery)
+	}
+	table, alias, i, err := st.target(lo+2, hi, true)
+	if err != nil {
+		return err
+	}
+
+	var refs []tableRef
+	if ref, ok := st.ref(table, alias); ok {
+		refs = append(refs, ref)
+	}
+
+	returningIdx := st.find(i, hi, "returning")
+	whereIdx := st.find(i, returningIdx, "where")
+
+	usingScoped := false
+	if i < whereIdx && st.tok(i).isKeyword("using") {
+		usingRefs, err := st.fromList(i+1, whereIdx, ctes)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, usingRefs...)
+		usingScoped = true
+	} else if i < whereIdx {
+		return fmt.Errorf("%w: unexpected %q in DELETE", ErrUnscopableQuery, st.tok(i).text)
+	}
+	if err := st.subqueries(whereIdx, hi, ctes); err != nil {
+		return err
+	}
+
+	if whereIdx == returningIdx {
+		whereIdx = -1
+	} else if whereIdx+1 < returningIdx && st.tok(whereIdx+1).isKeyword("current") {
+		return fmt.Errorf("%w: WHERE CURRENT OF is not supported", ErrUnscopableQuery)
+	}
+	return st.addConditions(refs, whereIdx, returningIdx, returningIdx-1, "WHERE", len(refs) == 1 && !usingScoped)
+}
+
+// insertStatement scopes INSERT INTO ... [(columns)] VALUES | query [ON CONFLICT ...] [RETURNING ...]
+func (st *rewriteState) insertStatement(lo, hi int, ctes map[string]bool) error {
+	if lo+1 >= hi || !st.tok(lo+1).isKeyword("into") {
+		return fmt.Errorf("%w: INSERT without INTO", ErrUnscopableQuery)
+	}
+	table, _, i, err := st.target(lo+2, hi, false)
+	if err != nil {
+		return err
+	}
+	var alias *sqlToken
+	if i+1 < hi && st.tok(i).isKeyword("as") && st.tok(i+1).isName() {
+		tok := st.tok(i + 1)
+		alias = &tok
+		i += 2
+	}
+	ref, tenantTable := st.ref(table, alias)
+
+	// Column list
+	colsOpen, colsClose := -1, -1
+	tenantCol := -1
+	if i < hi && st.tok(i).kind == tokLParen && !st.isQueryStart(i+1, st.toks.match[i]) {
+		colsOpen, colsClose = i, st.toks.match[i]
+		col := 0
+		for j := colsOpen + 1; j < colsClose; j = st.skip(j) {
+			if st.tok(j).kind == tokComma {
+				col++
+				continue
+			}
+			if st.tok(j).isName() && st.tok(j).value == "tenant_id" {
+				tenantCol = col
+			}
+		}
+		i = colsClose + 1
+	}
+	if i < hi && st.tok(i).isKeyword("overriding") {
+		i += 3
+	}
+
+	bodyEnd := i
+	for bodyEnd < hi {
+		tok := st.tok(bodyEnd)
+		if tok.isKeyword("returning") || (tok.isKeyword("on") && bodyEnd+1 < hi && st.tok(bodyEnd+1).isKeyword("conflict")) {
+			break
+		}
+		bodyEnd = st.skip(bodyEnd)
+	}
+	if i >= bodyEnd {
+		return fmt.Errorf("%w: INSERT without values", ErrUnscopableQuery)
+	}
+
+	body := st.tok(i)
+	switch {
+	case body.isKeyword("default"):
+		if tenantTable {
+			return fmt.Errorf("%w: DEFAULT VALUES into tenant table %s", ErrUnscopableQuery, table.value)
+		}
+	case body.isKeyword("values"):
+		if err := st.subqueries(i+1, bodyEnd, ctes); err != nil {
+			return err
+		}
+		if tenantTable {
+			if err := st.insertValues(ref, i+1, bodyEnd, colsOpen, colsClose, tenantCol); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := st.statement(i, bodyEnd, ctes); err != nil {
+			return err
+		}
+		if tenantTable && tenantCol == -1 {
+			if err := st.insertQueryColumn(ref, i, bodyEnd, colsOpen, colsClose); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := st.subqueries(bodyEnd, hi, ctes); err != nil {
+		return err
+	}
+
+	// An upsert must never update a row owned by another tenant
+	if tenantTable && bodyEnd < hi && st.tok(bodyEnd).isKeyword("on") {
+		returningIdx := st.find(bodyEnd, hi, "returning")
+		doIdx := st.find(bodyEnd, returningIdx, "do")
+		if doIdx+1 < returningIdx && st.tok(doIdx+1).isKeyword("update") {
+			whereIdx := st.find(doIdx+2, returningIdx, "where")
+			if doIdx+2 < whereIdx && st.tok(doIdx+2).isKeyword("set") {
+				if err := st.checkAssignments(ref, doIdx+3, whereIdx, true); err != nil {
+					return err
+				}
+			}
+			if whereIdx == returningIdx {
+				whereIdx = -1
+			}
+			return st.addConditions([]tableRef{ref}, whereIdx, returningIdx, returningIdx-1, "WHERE", false)
+		}
+	}
+	return nil
+}
+
+// checkAssignments validates the SET list in [lo, hi) of an UPDATE or upsert
+// on ref so that a row cannot be moved to another tenant. tenant_id may only
+// be assigned the current tenant, or EXCLUDED.tenant_id in an upsert.
+func (st *rewriteState) checkAssignments(ref tableRef, lo, hi int, upsert bool) error {
+	start := lo
+	for i := lo; i <= hi; {
+		if i < hi && st.tok(i).kind != tokComma {
+			i = st.skip(i)
+			continue
+		}
+		if err := st.checkAssignment(ref, start, i, upsert); err != nil {
+			return err
+		}
+		start = i + 1
+		i++
+	}
+	return nil
+}
+
+// checkAssignment validates a single "column = value" or "(columns) = ..."
+// assignment in [lo, hi)
+func (st *rewriteState) checkAssignment(ref tableRef, lo, hi int, upsert bool) error {
+	if lo >= hi {
+		return nil
+	}
+	if st.tok(lo).kind == tokLParen {
+		for j := lo + 1; j < st.toks.match[lo]; j++ {
+			if st.tok(j).isName() && st.tok(j).value == "tenant_id" {
+				return fmt.Errorf("%w: tenant_id of %s assigned from a row", ErrUnscopableQuery, ref.table)
+			}
+		}
+		return nil
+	}
+	if !st.tok(lo).isName() || st.tok(lo).value != "tenant_id" {
+		return nil
+	}
+
+	valueLo := lo + 2
+	if lo+1 >= hi || st.tok(lo+1).text != "=" || valueLo >= hi {
+		return fmt.Errorf("%w: malformed tenant_id assignment", ErrUnscopableQuery)
+	}
+	if upsert && hi-valueLo == 3 && st.tok(valueLo).isName() && st.tok(valueLo).value == "excluded" &&
+		st.tok(valueLo+1).kind == tokDot && st.tok(valueLo+2).value == "tenant_id" {
+		// The inserted row was already scoped
+		return nil
+	}
+	value, ok := st.valueAt(valueLo, hi, 0)
+	if !ok || (value.kind != tokParam && value.kind != tokString) {
+		return fmt.Errorf("%w: tenant_id of %s assigned a computed value", ErrUnscopableQuery, ref.table)
+	}
+	_, err := st.matchesTenant(value)
+	return err
+}
+
+// insertValues adds or validates the tenant_id column of an INSERT ... VALUES
+func (st *rewriteState) insertValues(ref tableRef, lo, hi, colsOpen, colsClose, tenantCol int) error {
+	if colsOpen == -1 {
+		return fmt.Errorf("%w: INSERT into %s requires an explicit column list", ErrUnscopableQuery, ref.table)
+	}
+
+	var rows [][2]int
+	for i := lo; i < hi; i = st.skip(i) {
+		tok := st.tok(i)
+		switch tok.kind {
+		case tokLParen:
+			rows = append(rows, [2]int{i, st.toks.match[i]})
+		case tokComma:
+		default:
+			return fmt.Errorf("%w: unexpected %q in VALUES", ErrUnscopableQuery, tok.text)
+		}
+	}
+
+	if tenantCol >= 0 {
+		for _, row := range rows {
+			value, ok := st.valueAt(row[0]+1, row[1], tenantCol)
+			if !ok {
+				return fmt.Errorf("%w: VALUES row does not provide tenant_id", ErrUnscopableQuery)
+			}
+			if _, err := st.matchesTenant(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	st.insert(st.tok(colsClose).start, ", tenant_id")
+	for _, row := range rows {
+		st.insert(st.tok(row[1]).start, ", "+st.param)
+	}
+	st.scoped = append(st.scoped, ref.table)
+	return nil
+}
+
+// valueAt returns the single token of the n-th comma separated value in [lo, hi)
+func (st *rewriteState) valueAt(lo, hi, n int) (sqlToken, bool) {
+	col := 0
+	start := lo
+	for i := lo; i <= hi; {
+		if i == hi || st.tok(i).kind == tokComma {
+			if col == n {
+				end := i
+				// Allow an explicit cast such as $1::uuid
+				if end-start == 3 && st.tok(start+1).text == "::" {
+					end = start + 1
+				}
+				if end-start != 1 {
+					return sqlToken{}, false
+				}
+				return st.tok(start), true
+			}
+			col++
+			start = i + 1
+			if i == hi {
+				break
+			}
+			i++
+			continue
+		}
+		i = st.skip(i)
+	}
+	return sqlToken{}, false
+}
+
+// insertQueryColumn appends tenant_id to the column list and the select list of INSERT ... SELECT
+func (st *rewriteState) insertQueryColumn(ref tableRef, lo, hi, colsOpen, colsClose int) error {
+	if colsOpen == -1 || !st.tok(lo).isKeyword("select") {
+		return fmt.Errorf("%w: INSERT INTO %s ... SELECT must list tenant_id", ErrUnscopableQuery, ref.table)
+	}
+	if st.find(lo, hi, "union", "intersect", "except") != hi {
+		return fmt.Errorf("%w: INSERT INTO %s with set operations must list tenant_id", ErrUnscopableQuery, ref.table)
+	}
+	listEnd := st.findFrom(lo+1, hi)
+	if listEnd == hi {
+		listEnd = st.find(lo+1, hi, "where", "group", "having", "window", "order", "limit", "offset", "fetch", "for")
+	}
+	if listEnd == lo+1 {
+		return fmt.Errorf("%w: empty select list", ErrUnscopableQuery)
+	}
+
+	st.insert(st.tok(colsClose).start, ", tenant_id")
+	st.insert(st.tok(listEnd-1).end, ", "+st.param)
+	st.scoped = append(st.scoped, ref.table)
+	return nil
+}
+
+// subqueries scopes every nested statement found in [lo, hi)
+func (st *rewriteState) subqueries(lo, hi int, ctes map[string]bool) error {
+	for i := lo; i < hi; i++ {
+		if st.tok(i).kind != tokLParen {
+			continue
+		}
+		closeIdx := st.toks.match[i]
+		if st.isQueryStart(i+1, closeIdx) {
+			if err := st.statement(i+1, closeIdx, ctes); err != nil {
+				return err
+			}
+			i = closeIdx
+		}
+	}
+	return nil
+}
+
+// addConditions injects tenant conditions for refs into the clause introduced
+// by the keyword at kwIdx, whose expression ends at exprEnd. When kwIdx is -1
+// a new clause is appended after token insertAfter.
+func (st *rewriteState) addConditions(refs []tableRef, kwIdx, exprEnd, insertAfter int, keyword string, unqualified bool) error {
+	if len(refs) == 0 {
+		return nil
+	}
+
+	exprStart := kwIdx + 1
+	if kwIdx >= 0 && exprStart >= exprEnd {
+		return fmt.Errorf("%w: empty %s clause", ErrUnscopableQuery, keyword)
+	}
+
+	hasOr := false
+	if kwIdx >= 0 {
+		hasOr = st.find(exprStart, exprEnd, "or") != exprEnd
+	}
+
+	var conds []string
+	for _, ref := range refs {
+		if kwIdx >= 0 && !hasOr {
+			found, err := st.hasTenantCondition(exprStart, exprEnd, ref, unqualified)
+			if err != nil {
+				return err
+			}
+			if found {
+				continue
+			}
+		}
+		conds = append(conds, ref.qualifier+".tenant_id = "+st.param)
+		st.scoped = append(st.scoped, ref.table)
+	}
+	if len(conds) == 0 {
+		return nil
+	}
+	cond := strings.Join(conds, " AND ")
+
+	if kwIdx < 0 {
+		st.insert(st.tok(insertAfter).end, " "+keyword+" "+cond)
+		return nil
+	}
+
+	prefix := ""
+	if st.tok(kwIdx).end == st.tok(exprStart).start {
+		prefix = " "
+	}
+	if hasOr {
+		st.insert(st.tok(exprStart).start, prefix+cond+" AND (")
+		st.insert(st.tok(exprEnd-1).end, ")")
+	} else {
+		st.insert(st.tok(exprStart).start, prefix+cond+" AND ")
+	}
+	return nil
+}
+
+// hasTenantCondition looks for a top-level conjunct "<ref>.tenant_id = <value>"
+// in [lo, hi) and validates that the value belongs to the current tenant. The
+// comparison only counts when nothing but WHERE/ON or AND precedes it, so
+// NOT, CASE and enclosing operators cannot invert it
+func (st *rewriteState) hasTenantCondition(lo, hi int, ref tableRef, unqualified bool) (bool, error) {
+	conjunct := true
+	between, caseDepth := 0, 0
+	for i := lo; i < hi; i = st.skip(i) {
+		start := conjunct
+		conjunct = false
+		tok := st.tok(i)
+		switch {
+		case tok.isKeyword("case"):
+			caseDepth++
+			continue
+		case tok.isKeyword("end") && caseDepth > 0:
+			caseDepth--
+			continue
+		case tok.isKeyword("between"):
+			between++
+			continue
+		case tok.isKeyword("and"):
+			if between > 0 {
+				between--
+			} else {
+				conjunct = caseDepth == 0
+			}
+			continue
+		}
+		if !start {
+			continue
+		}
+
+		j := i
+		switch {
+		case tok.isName() && tok.value == ref.match && j+2 < hi && st.tok(j+1).kind == tokDot:
+			j += 2
+		case unqualified:
+		default:
+			continue
+		}
+
+		if !st.tok(j).isName() || st.tok(j).value != "tenant_id" {
+			continue
+		}
+		if j+1 >= hi || st.tok(j+1).text != "=" {
+			continue
+		}
+		j += 2
+		if j >= hi {
+			continue
+		}
+		value := st.tok(j)
+		if value.kind != tokParam && value.kind != tokString {
+			continue
+		}
+		// The comparison must be a complete predicate, optionally cast
+		after := j + 1
+		if after+1 < hi && st.tok(after).text == "::" && st.tok(after+1).isName() {
+			after += 2
+		}
+		if after < hi && !st.tok(after).isKeyword("and") {
+			continue
+		}
+
+		return st.matchesTenant(value)
+	}
+	return false, nil
+}
+
+// matchesTenant checks that a literal or parameter equals the current tenant
+func (st *rewriteState) matchesTenant(value sqlToken) (bool, error) {
+	if st.tenantID == "" {
+		return true, nil
+	}
+
+	var actual string
+	switch value.kind {
+	case tokString:
+		actual = value.value
+	case tokParam:
+		idx := value.paramIndex()
+		if idx < 1 || idx > len(st.args) {
+			return false, fmt.Errorf("%w: no argument for $%d", ErrUnscopableQuery, idx)
+		}
+		s, ok := argString(st.args[idx-1])
+		if !ok {
+			return false, fmt.Errorf("%w: cannot compare $%d of type %T with the tenant", ErrUnscopableQuery, idx, st.args[idx-1])
+		}
+		actual = s
+	default:
+		return false, nil
+	}
+
+	if !strings.EqualFold(actual, st.tenantID) {
+		return false, fmt.Errorf("%w: expected tenant %s", ErrCrossTenantQuery, st.tenantID)
+	}
+	return true, nil
+}
+
+// argString converts a query argument to its string form when it has one
+func argString(arg interface{}) (string, bool) {
+	if valuer, ok := arg.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", false
+		}
+		arg = v
+	}
+	switch v := arg.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case [16]byte:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16]), true
+	case fmt.Stringer:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,110 @@
+-- +goose Up
+-- Soft-delete and append-only revision history for agents and workflows
+
+-- ============================================================================
+-- SOFT DELETE
+-- ============================================================================
+
+ALTER TABLE agents ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE workflows ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Names stay unique among live rows so a deleted name can be reused
+ALTER TABLE agents DROP CONSTRAINT agents_tenant_id_name_key;
+CREATE UNIQUE INDEX idx_agents_tenant_name_live ON agents(tenant_id, name) WHERE deleted_at IS NULL;
+ALTER TABLE workflows DROP CONSTRAINT workflows_tenant_id_name_version_key;
+CREATE UNIQUE INDEX idx_workflows_tenant_name_version_live ON workflows(tenant_id, name, version) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_agents_deleted_at ON agents(tenant_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_workflows_deleted_at ON workflows(tenant_id, deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- ============================================================================
+-- REVISION TABLES
+-- ============================================================================
+
+-- Agent revisions - full snapshot of an agent after each change
+CREATE TABLE agent_revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    agent_id UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    operation VARCHAR(20) NOT NULL CHECK (operation IN ('create', 'update', 'delete', 'restore', 'revert')),
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    role VARCHAR(100),
+    config_json JSONB NOT NULL,
+    policies_json JSONB NOT NULL,
+    audit_id UUID REFERENCES audits(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(agent_id, revision)
+);
+
+-- Workflow revisions - full snapshot of a workflow after each change
+CREATE TABLE workflow_revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    workflow_id UUID NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    operation VARCHAR(20) NOT NULL CHECK (operation IN ('create', 'update', 'delete', 'restore', 'revert')),
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    config_yaml TEXT NOT NULL,
+    planner_type VARCHAR(50) NOT NULL,
+    template_version_constraint VARCHAR(100),
+    audit_id UUID REFERENCES audits(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(workflow_id, revision)
+);
+
+CREATE INDEX idx_agent_revisions_tenant_id ON agent_revisions(tenant_id);
+CREATE INDEX idx_agent_revisions_audit_id ON agent_revisions(audit_id);
+CREATE INDEX idx_workflow_revisions_tenant_id ON workflow_revisions(tenant_id);
+CREATE INDEX idx_workflow_revisions_audit_id ON workflow_revisions(audit_id);
+
+-- Revisions are append-only. Deletes are only allowed when cascading from
+-- the parent row or tenant, where the trigger runs nested in the RI trigger.
+-- +goose StatementBegin
+CREATE FUNCTION reject_revision_mutation() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND pg_trigger_depth() > 1 THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER agent_revisions_append_only
+    BEFORE UPDATE OR DELETE ON agent_revisions
+    FOR EACH ROW EXECUTE FUNCTION reject_revision_mutation();
+
+CREATE TRIGGER workflow_revisions_append_only
+    BEFORE UPDATE OR DELETE ON workflow_revisions
+    FOR EACH ROW EXECUTE FUNCTION reject_revision_mutation();
+
+-- Seed revision 1 for rows that existed before history was recorded
+INSERT INTO agent_revisions (tenant_id, agent_id, revision, operation, name, type, role, config_json, policies_json, created_at)
+SELECT tenant_id, id, 1, 'create', name, type, role, config_json, policies_json, COALESCE(updated_at, created_at, NOW())
+FROM agents;
+
+INSERT INTO workflow_revisions (tenant_id, workflow_id, revision, operation, name, version, config_yaml, planner_type, template_version_constraint, created_at)
+SELECT tenant_id, id, 1, 'create', name, version, config_yaml, planner_type, template_version_constraint, COALESCE(updated_at, created_at, NOW())
+FROM workflows;
+
+-- +goose Down
+DROP TABLE IF EXISTS workflow_revisions;
+DROP TABLE IF EXISTS agent_revisions;
+DROP FUNCTION IF EXISTS reject_revision_mutation();
+
+-- Soft-deleted rows would violate the restored unique constraints
+DELETE FROM workflows WHERE deleted_at IS NOT NULL;
+DELETE FROM agents WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_workflows_deleted_at;
+DROP INDEX IF EXISTS idx_agents_deleted_at;
+DROP INDEX IF EXISTS idx_workflows_tenant_name_version_live;
+DROP INDEX IF EXISTS idx_agents_tenant_name_live;
+ALTER TABLE workflows ADD CONSTRAINT workflows_tenant_id_name_version_key UNIQUE (tenant_id, name, version);
+ALTER TABLE agents ADD CONSTRAINT agents_tenant_id_name_key UNIQUE (tenant_id, name);
+
+ALTER TABLE workflows DROP COLUMN deleted_at;
+ALTER TABLE agents DROP COLUMN deleted_at;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- Tenant lifecycle states, deletion grace period and erasure tombstones
+
+ALTER TABLE tenants ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'suspended', 'pending_deletion', 'erased'));
+ALTER TABLE tenants ADD COLUMN status_reason TEXT;
+ALTER TABLE tenants ADD COLUMN status_changed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE tenants ADD COLUMN deletion_scheduled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE tenants ADD COLUMN erased_at TIMESTAMP WITH TIME ZONE;
+
+-- A tenant pending deletion always has an erasure date
+ALTER TABLE tenants ADD CONSTRAINT tenants_deletion_scheduled_check
+    CHECK (status <> 'pending_deletion' OR deletion_scheduled_at IS NOT NULL);
+
+-- Erasure job scan
+CREATE INDEX idx_tenants_deletion_due ON tenants(deletion_scheduled_at) WHERE status = 'pending_deletion';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_tenants_deletion_due;
+ALTER TABLE tenants DROP CONSTRAINT IF EXISTS tenants_deletion_scheduled_check;
+ALTER TABLE tenants DROP COLUMN erased_at;
+ALTER TABLE tenants DROP COLUMN deletion_scheduled_at;
+ALTER TABLE tenants DROP COLUMN status_changed_at;
+ALTER TABLE tenants DROP COLUMN status_reason;
+ALTER TABLE tenants DROP COLUMN status;
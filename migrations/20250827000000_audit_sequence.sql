@@ -0,0 +1,22 @@
+-- +goose Up
+-- Per-tenant audit sequence numbers; the unique index rejects forked chain heads
+
+ALTER TABLE audits ADD COLUMN seq BIGINT;
+
+-- Number existing chains in the order they were verified
+UPDATE audits a SET seq = numbered.seq
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY tenant_id ORDER BY ts, id) AS seq
+    FROM audits
+) numbered
+WHERE a.id = numbered.id;
+
+ALTER TABLE audits ALTER COLUMN seq SET NOT NULL;
+ALTER TABLE audits ALTER COLUMN ts SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_audits_tenant_seq ON audits(tenant_id, seq);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_audits_tenant_seq;
+ALTER TABLE audits ALTER COLUMN ts DROP NOT NULL;
+ALTER TABLE audits DROP COLUMN seq;
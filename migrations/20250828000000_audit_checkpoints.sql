@@ -0,0 +1,16 @@
+-- +goose Up
+-- Signed audit verification checkpoints; verification resumes after the latest one
+
+CREATE TABLE audit_checkpoints (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL,
+    hash BYTEA NOT NULL,
+    record_ts TIMESTAMP WITH TIME ZONE NOT NULL,
+    key_id VARCHAR(64) NOT NULL,
+    signature BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, seq)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_checkpoints;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Signed Merkle tree heads over each tenant's audit records; inclusion proofs are issued against them
+
+CREATE TABLE audit_tree_heads (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    tree_size BIGINT NOT NULL,
+    root BYTEA NOT NULL,
+    signed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    key_id VARCHAR(64) NOT NULL,
+    signature BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, tree_size)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_tree_heads;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Refresh tokens, stored as SHA-256 hashes. Every rotation adds a token to the
+-- family of the one it replaces, so reuse of a rotated token can revoke them all.
+
+CREATE TABLE refresh_tokens (
+    token_hash BYTEA PRIMARY KEY,
+    family_id UUID NOT NULL,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    roles TEXT[] NOT NULL DEFAULT '{}',
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_tenant ON refresh_tokens(tenant_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Revoked access tokens, keyed by JWT ID. A row is only needed until the
+-- token would have expired anyway.
+
+CREATE TABLE revoked_tokens (
+    jti VARCHAR(255) PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_revoked_tokens_expires ON revoked_tokens(expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS revoked_tokens;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- A role inherits the permissions of its parent role, and of the parent's
+-- parents in turn. Deleting a parent leaves its children without one.
+
+ALTER TABLE rbac_roles
+    ADD COLUMN parent_id UUID REFERENCES rbac_roles(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_rbac_roles_parent_id ON rbac_roles(parent_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_rbac_roles_parent_id;
+ALTER TABLE rbac_roles DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Tenant-wide policies evaluated by the policy engine, alongside the policies
+-- in agents.policies_json and tools.permissions. Disabled policies are kept
+-- but not evaluated.
+
+CREATE TABLE policies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    document JSONB NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(tenant_id, name)
+);
+
+CREATE INDEX idx_policies_tenant_enabled ON policies(tenant_id) WHERE enabled;
+
+-- +goose Down
+DROP TABLE IF EXISTS policies;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- Service accounts are tenant-scoped principals for machine clients. They
+-- authenticate with API keys, which are stored as SHA-256 hashes and looked
+-- up by their public prefix.
+
+CREATE TABLE service_accounts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    disabled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(tenant_id, name)
+);
+
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    service_account_id UUID NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    key_hash BYTEA NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_keys_service_account ON api_keys(service_account_id);
+CREATE INDEX idx_api_keys_tenant ON api_keys(tenant_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS service_accounts;
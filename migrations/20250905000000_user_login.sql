@@ -0,0 +1,11 @@
+-- +goose Up
+-- Failed password logins per user, for locking accounts after repeated
+-- failures. The counter restarts when an account is locked and after a
+-- successful login.
+
+ALTER TABLE users ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP WITH TIME ZONE;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN locked_until;
+ALTER TABLE users DROP COLUMN failed_login_attempts;
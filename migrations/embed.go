@@ -0,0 +1,10 @@
+// Package migrations embeds the goose-format SQL migrations so they ship with
+// the binaries and can be applied without external tooling
+package migrations
+
+import "embed"
+
+// FS contains every migration file in this directory
+//
+//go:embed *.sql
+var FS embed.FS
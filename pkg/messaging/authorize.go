@@ -0,0 +1,28 @@
+package messaging
+
+import "context"
+
+// MessageAuthorizer decides whether a message may be published to subject
+type MessageAuthorizer interface {
+	AuthorizeMessage(ctx context.Context, subject string, msg *Message) error
+}
+
+// authorizedBus checks every message with an authorizer before publishing it
+type authorizedBus struct {
+	MessageBus
+	authorizer MessageAuthorizer
+}
+
+// NewAuthorizedBus wraps bus so messages the authorizer refuses are not
+// published. Publish returns the authorizer's error for a refused message.
+func NewAuthorizedBus(bus MessageBus, authorizer MessageAuthorizer) MessageBus {
+	return &authorizedBus{MessageBus: bus, authorizer: authorizer}
+}
+
+// Publish publishes msg if the authorizer allows it
+func (b *authorizedBus) Publish(ctx context.Context, subject string, msg *Message) error {
+	if err := b.authorizer.AuthorizeMessage(ctx, subject, msg); err != nil {
+		return err
+	}
+	return b.MessageBus.Publish(ctx, subject, msg)
+}
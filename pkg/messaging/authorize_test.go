@@ -0,0 +1,45 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// recordingBus keeps published messages in memory
+type recordingBus struct {
+	MessageBus
+	published []*Message
+}
+
+func (b *recordingBus) Publish(ctx context.Context, subject string, msg *Message) error {
+	b.published = append(b.published, msg)
+	return nil
+}
+
+type authorizerFunc func(ctx context.Context, subject string, msg *Message) error
+
+func (f authorizerFunc) AuthorizeMessage(ctx context.Context, subject string, msg *Message) error {
+	return f(ctx, subject, msg)
+}
+
+func TestAuthorizedBus(t *testing.T) {
+	errDenied := errors.New("denied")
+	inner := &recordingBus{}
+	bus := NewAuthorizedBus(inner, authorizerFunc(func(ctx context.Context, subject string, msg *Message) error {
+		if msg.To == "restricted" {
+			return errDenied
+		}
+		return nil
+	}))
+
+	if err := bus.Publish(context.Background(), "agents.worker.in", NewMessage("m1", "planner", "worker", MessageTypeRequest)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := bus.Publish(context.Background(), "agents.restricted.in", NewMessage("m2", "planner", "restricted", MessageTypeRequest)); !errors.Is(err, errDenied) {
+		t.Errorf("Publish() error = %v, want %v", err, errDenied)
+	}
+	if len(inner.published) != 1 || inner.published[0].ID != "m1" {
+		t.Errorf("published %d messages, want only m1", len(inner.published))
+	}
+}
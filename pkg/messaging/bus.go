@@ -49,6 +49,16 @@ type BusConfig struct {
 	MaxInFlight    int           `env:"AF_BUS_MAX_IN_FLIGHT"`
 	ConnectTimeout time.Duration `env:"AF_BUS_CONNECT_TIMEOUT"`
 	RequestTimeout time.Duration `env:"AF_BUS_REQUEST_TIMEOUT"`
+	// TLS client certificate and CA bundle. The files are read again on
+	// every reconnect, so renewed certificates are picked up.
+	TLSCertPath string `env:"AF_BUS_TLS_CERT_PATH"`
+	TLSKeyPath  string `env:"AF_BUS_TLS_KEY_PATH"`
+	TLSCAPath   string `env:"AF_BUS_TLS_CA_PATH"`
+	// Credentials: a NATS creds file, a token, or a user and password
+	CredsFile string `env:"AF_BUS_CREDS_FILE"`
+	Token     string `env:"AF_BUS_TOKEN"`
+	User      string `env:"AF_BUS_USER"`
+	Password  string `env:"AF_BUS_PASSWORD"`
 }
 
 // DefaultBusConfig returns default configuration values
@@ -0,0 +1,107 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// LoadBusConfigFromEnv returns the default bus configuration with the
+// AF_BUS_* environment variables applied
+func LoadBusConfigFromEnv() (*BusConfig, error) {
+	config := DefaultBusConfig()
+	if err := applyEnvConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// applyEnvConfig overrides config with the AF_BUS_* environment variables
+// that are set
+func applyEnvConfig(config *BusConfig) error {
+	texts := map[string]*string{
+		"AF_BUS_URL":           &config.URL,
+		"AF_BUS_TLS_CERT_PATH": &config.TLSCertPath,
+		"AF_BUS_TLS_KEY_PATH":  &config.TLSKeyPath,
+		"AF_BUS_TLS_CA_PATH":   &config.TLSCAPath,
+		"AF_BUS_CREDS_FILE":    &config.CredsFile,
+		"AF_BUS_TOKEN":         &config.Token,
+		"AF_BUS_USER":          &config.User,
+		"AF_BUS_PASSWORD":      &config.Password,
+	}
+	for name, field := range texts {
+		if val := os.Getenv(name); val != "" {
+			*field = val
+		}
+	}
+
+	ints := map[string]*int{
+		"AF_BUS_MAX_RECONNECT": &config.MaxReconnect,
+		"AF_BUS_MAX_IN_FLIGHT": &config.MaxInFlight,
+	}
+	for name, field := range ints {
+		if val := os.Getenv(name); val != "" {
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*field = n
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"AF_BUS_RECONNECT_WAIT":  &config.ReconnectWait,
+		"AF_BUS_ACK_WAIT":        &config.AckWait,
+		"AF_BUS_CONNECT_TIMEOUT": &config.ConnectTimeout,
+		"AF_BUS_REQUEST_TIMEOUT": &config.RequestTimeout,
+	}
+	for name, field := range durations {
+		if val := os.Getenv(name); val != "" {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*field = d
+		}
+	}
+	return nil
+}
+
+// NATSOptions returns the connection options for the configured TLS
+// certificates and credentials. Connections use TLS when a certificate or
+// CA bundle is set.
+func (c *BusConfig) NATSOptions() ([]nats.Option, error) {
+	var opts []nats.Option
+
+	if (c.TLSCertPath == "") != (c.TLSKeyPath == "") {
+		return nil, errors.New("bus TLS certificate and key paths must be set together")
+	}
+	if c.TLSCertPath != "" {
+		opts = append(opts, nats.ClientCert(c.TLSCertPath, c.TLSKeyPath))
+	}
+	if c.TLSCAPath != "" {
+		opts = append(opts, nats.RootCAs(c.TLSCAPath))
+	}
+
+	credentials := 0
+	if c.CredsFile != "" {
+		credentials++
+		opts = append(opts, nats.UserCredentials(c.CredsFile))
+	}
+	if c.Token != "" {
+		credentials++
+		opts = append(opts, nats.Token(c.Token))
+	}
+	if c.User != "" {
+		credentials++
+		opts = append(opts, nats.UserInfo(c.User, c.Password))
+	}
+	if credentials > 1 {
+		return nil, errors.New("only one of a bus creds file, token or user may be set")
+	}
+	return opts, nil
+}
@@ -0,0 +1,84 @@
+package messaging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/security/certs"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBusConfigFromEnv(t *testing.T) {
+	t.Setenv("AF_BUS_URL", "tls://nats:4222")
+	t.Setenv("AF_BUS_MAX_RECONNECT", "3")
+	t.Setenv("AF_BUS_CONNECT_TIMEOUT", "2s")
+	t.Setenv("AF_BUS_TLS_CA_PATH", "/etc/agentflow/ca.pem")
+	t.Setenv("AF_BUS_TOKEN", "s3cret")
+
+	config, err := LoadBusConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "tls://nats:4222", config.URL)
+	assert.Equal(t, 3, config.MaxReconnect)
+	assert.Equal(t, 2*time.Second, config.ConnectTimeout)
+	assert.Equal(t, "/etc/agentflow/ca.pem", config.TLSCAPath)
+	assert.Equal(t, "s3cret", config.Token)
+	assert.Equal(t, DefaultBusConfig().AckWait, config.AckWait, "unset variables keep defaults")
+
+	t.Setenv("AF_BUS_ACK_WAIT", "soon")
+	_, err = LoadBusConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestBusConfig_NATSOptions(t *testing.T) {
+	apply := func(config *BusConfig) (nats.Options, error) {
+		o := nats.GetDefaultOptions()
+		opts, err := config.NATSOptions()
+		if err != nil {
+			return o, err
+		}
+		for _, opt := range opts {
+			if err := opt(&o); err != nil {
+				return o, err
+			}
+		}
+		return o, nil
+	}
+
+	o, err := apply(DefaultBusConfig())
+	require.NoError(t, err)
+	assert.False(t, o.Secure)
+
+	dir := t.TempDir()
+	ca, err := certs.NewCA("test CA", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, certs.WriteCA(dir, ca))
+	certPEM, keyPEM, err := ca.Issue(certs.IssueRequest{Kind: certs.KindWorker, Name: "w1"})
+	require.NoError(t, err)
+	certPath, keyPath := filepath.Join(dir, "w1.pem"), filepath.Join(dir, "w1-key.pem")
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0o644))
+	require.NoError(t, os.WriteFile(keyPath, keyPEM, 0o600))
+
+	config := DefaultBusConfig()
+	config.TLSCertPath, config.TLSKeyPath = certPath, keyPath
+	config.TLSCAPath = filepath.Join(dir, certs.CACertFile)
+	config.User, config.Password = "worker", "pw"
+	o, err = apply(config)
+	require.NoError(t, err)
+	assert.True(t, o.Secure)
+	assert.NotNil(t, o.TLSCertCB)
+	assert.NotNil(t, o.RootCAsCB)
+	assert.Equal(t, "worker", o.User)
+
+	config.Token = "s3cret"
+	_, err = apply(config)
+	assert.Error(t, err, "only one kind of credential")
+
+	config = DefaultBusConfig()
+	config.TLSCertPath = certPath
+	_, err = apply(config)
+	assert.Error(t, err, "certificate without key")
+}
@@ -105,8 +105,13 @@ func connectWithRetry(config *BusConfig) (*nats.Conn, error) {
 		}),
 	}
 
+	security, err := config.NATSOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, security...)
+
 	var conn *nats.Conn
-	var err error
 
 	// Retry with exponential backoff
 	for attempt := 0; attempt < config.MaxReconnect; attempt++ {
@@ -500,11 +505,3 @@ func (nb *natsBus) getStreamForSubject(subject string) string {
 	}
 	return ""
 }
-
-// applyEnvConfig applies environment variable configuration
-func applyEnvConfig(config *BusConfig) error {
-	// This is a simplified version - in a real implementation,
-	// you would use a proper environment variable parsing library
-	// For now, we'll use the defaults
-	return nil
-}
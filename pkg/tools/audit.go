@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/agentflow/agentflow/internal/storage/audit"
+)
+
+// AuditActionExecute is the audit action recorded for a tool execution
+const AuditActionExecute = "tool.execute"
+
+// ErrNoInvocation is returned when an audited tool runs without an Invocation
+var ErrNoInvocation = errors.New("tool execution has no invocation to audit")
+
+// Invocation identifies the tenant and actor a tool is executed for
+type Invocation struct {
+	TenantID  string
+	ActorType string
+	ActorID   string
+}
+
+type invocationKey struct{}
+
+// WithInvocation returns a context that executes tools for inv
+func WithInvocation(ctx context.Context, inv Invocation) context.Context {
+	return context.WithValue(ctx, invocationKey{}, inv)
+}
+
+// InvocationFromContext returns the invocation set by WithInvocation
+func InvocationFromContext(ctx context.Context) (Invocation, bool) {
+	inv, ok := ctx.Value(invocationKey{}).(Invocation)
+	return inv, ok
+}
+
+// AuditedTool records every execution of the tool it wraps in the audit
+// trail of the invoking tenant
+type AuditedTool struct {
+	Tool
+	recorder audit.Recorder
+}
+
+// NewAuditedTool wraps tool so its executions are recorded with recorder
+func NewAuditedTool(tool Tool, recorder audit.Recorder) *AuditedTool {
+	return &AuditedTool{Tool: tool, recorder: recorder}
+}
+
+// Execute runs the tool and records the outcome. Executions that cannot be
+// attributed to a tenant are refused. Parameter names are recorded but not
+// their values, which may hold credentials.
+func (t *AuditedTool) Execute(ctx context.Context, input ToolInput) (ToolOutput, error) {
+	inv, ok := InvocationFromContext(ctx)
+	if !ok || inv.TenantID == "" {
+		return ToolOutput{}, ErrNoInvocation
+	}
+
+	start := time.Now()
+	output, err := t.Tool.Execute(ctx, input)
+
+	params := make([]string, 0, len(input.Parameters))
+	for name := range input.Parameters {
+		params = append(params, name)
+	}
+	sort.Strings(params)
+
+	event := audit.Event{
+		TenantID:     inv.TenantID,
+		ActorType:    inv.ActorType,
+		ActorID:      inv.ActorID,
+		Action:       AuditActionExecute,
+		ResourceType: "tool",
+		ResourceID:   t.Name(),
+		Outcome:      audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"parameters":  params,
+			"duration_ms": time.Since(start).Milliseconds(),
+		},
+	}
+	failure := err
+	if failure == nil {
+		failure = output.Error
+	}
+	if failure != nil {
+		event.Outcome = audit.OutcomeFailure
+		event.Details["error"] = failure.Error()
+	}
+	_ = t.recorder.Record(ctx, event)
+
+	return output, err
+}
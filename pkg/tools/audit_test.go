@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agentflow/agentflow/internal/storage/audit"
+)
+
+// echoTool returns its parameters, or fails with err
+type echoTool struct {
+	err error
+}
+
+func (t *echoTool) Name() string        { return "echo" }
+func (t *echoTool) Description() string { return "Returns its parameters" }
+
+func (t *echoTool) Execute(ctx context.Context, input ToolInput) (ToolOutput, error) {
+	return ToolOutput{Result: input.Parameters}, t.err
+}
+
+func TestAuditedTool(t *testing.T) {
+	recorder := audit.NewMemoryRecorder()
+	tool := &echoTool{}
+	audited := NewAuditedTool(tool, recorder)
+	input := ToolInput{Parameters: map[string]interface{}{"url": "https://example.com", "api_key": "secret"}}
+
+	if _, err := audited.Execute(context.Background(), input); !errors.Is(err, ErrNoInvocation) {
+		t.Errorf("Execute() without invocation error = %v, want %v", err, ErrNoInvocation)
+	}
+
+	ctx := WithInvocation(context.Background(), Invocation{TenantID: "tenant123", ActorType: "agent", ActorID: "agent-1"})
+	output, err := audited.Execute(ctx, input)
+	if err != nil || output.Result["url"] != "https://example.com" {
+		t.Fatalf("Execute() = %+v, %v, want the tool's output", output, err)
+	}
+	tool.err = errors.New("timeout")
+	if _, err := audited.Execute(ctx, input); err != tool.err {
+		t.Errorf("Execute() error = %v, want the tool's error", err)
+	}
+
+	if len(recorder.Events()) != 2 {
+		t.Fatalf("recorded %d events, want 2", len(recorder.Events()))
+	}
+	event := recorder.Events()[0]
+	if event.TenantID != "tenant123" || event.ActorID != "agent-1" || event.Action != AuditActionExecute ||
+		event.ResourceID != "echo" || event.Outcome != audit.OutcomeSuccess {
+		t.Errorf("recorded event = %+v, want a successful execution of echo by agent-1", event)
+	}
+	if params, ok := event.Details["parameters"].([]string); !ok || len(params) != 2 || params[0] != "api_key" {
+		t.Errorf("recorded parameters = %v, want sorted names", event.Details["parameters"])
+	}
+	for _, v := range event.Details {
+		if v == "secret" {
+			t.Error("recorded event holds a parameter value")
+		}
+	}
+	if failed := recorder.Events()[1]; failed.Outcome != audit.OutcomeFailure || failed.Details["error"] != "timeout" {
+		t.Errorf("recorded event = %+v, want a failure with the tool's error", failed)
+	}
+}